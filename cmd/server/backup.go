@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/backup"
+	"github.com/codingminions/Whatsapp-Lite/internal/bootstrap"
+	"github.com/codingminions/Whatsapp-Lite/internal/storage"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+)
+
+// runBackup writes a logical backup of the database, or restores one into
+// a freshly migrated database:
+//
+//	backup -out chat.backup.gz
+//	backup -restore chat.backup.gz
+//
+// Attachment files are not part of the database. The backup lists them in
+// its media manifest, and they must be copied from attachment storage
+// separately. Restoring checks that every listed file is present.
+func runBackup(args []string) error {
+	var opts bootstrap.Options
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	opts.RegisterFlags(fs)
+	out := fs.String("out", "", "file to write the backup to (default backup-<time>.gz)")
+	restore := fs.String("restore", "", "backup file to restore into an empty database")
+	fs.Parse(args)
+
+	env, err := bootstrap.New(opts)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	log := env.Logger
+	ctx := context.Background()
+
+	if *restore != "" {
+		file, err := os.Open(*restore)
+		if err != nil {
+			return fmt.Errorf("failed to open backup: %w", err)
+		}
+		defer file.Close()
+
+		summary, err := backup.Restore(ctx, env.DB, file)
+		if err != nil {
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+		log.Info("Backup restored", "file", *restore, "schema_version", summary.SchemaVersion, "rows", summary.Rows)
+
+		checkMedia(ctx, env.Config.Attachments.Storage, summary.Media, log)
+		return nil
+	}
+
+	path := *out
+	if path == "" {
+		path = fmt.Sprintf("backup-%s.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	// Write to a temporary file so a failed backup never looks complete
+	tmp := path + ".partial"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+
+	summary, err := backup.Write(ctx, env.DB, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to finish backup file: %w", err)
+	}
+
+	log.Info("Backup written", "file", path, "schema_version", summary.SchemaVersion, "rows", summary.Rows, "media_files", len(summary.Media))
+	return nil
+}
+
+// checkMedia reports attachment files listed in a restored backup that are
+// missing from attachment storage
+func checkMedia(ctx context.Context, config configs.StorageConfig, media []backup.MediaEntry, log logger.Logger) {
+	if len(media) == 0 {
+		return
+	}
+
+	store, err := storage.New(config)
+	if err != nil {
+		log.Error("Failed to open attachment storage, skipping media check", "error", err)
+		return
+	}
+
+	missing := 0
+	for _, entry := range media {
+		body, err := store.Get(ctx, entry.StorageKey)
+		if err != nil {
+			missing++
+			log.Warn("Attachment file missing from storage", "storage_key", entry.StorageKey)
+			continue
+		}
+		body.Close()
+	}
+
+	if missing > 0 {
+		log.Warn("Copy the missing attachment files into storage", "missing", missing, "total", len(media))
+		return
+	}
+	log.Info("All attachment files present", "count", len(media))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/bootstrap"
+	"github.com/codingminions/Whatsapp-Lite/pkg/migrate"
+)
+
+// runMigrate applies or reverts database migrations:
+//
+//	migrate up        apply all pending migrations
+//	migrate down [n]  revert the last n migrations (default 1)
+//	migrate version   print the current migration version
+func runMigrate(args []string) error {
+	var opts bootstrap.Options
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	opts.RegisterFlags(fs)
+	dir := fs.String("dir", "./migrations", "directory holding the migration files")
+	fs.Parse(args)
+
+	action := fs.Arg(0)
+	if action == "" {
+		return fmt.Errorf("migrate needs an action: up, down or version")
+	}
+
+	env, err := bootstrap.New(opts)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	log := env.Logger
+	migrator := migrate.New(env.DB, *dir)
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("Migrations applied", "count", applied)
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			steps, err = strconv.Atoi(fs.Arg(1))
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations to revert: %q", fs.Arg(1))
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Info("Migrations reverted", "count", reverted)
+	case "version":
+		version, dirty, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version %d (dirty: %t)\n", version, dirty)
+	default:
+		return fmt.Errorf("unknown migrate action %q", action)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/app"
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/bootstrap"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/google/uuid"
+)
+
+// runSeed fills a development database with demo users who have exchanged
+// a few messages. Running it again reuses the existing demo users.
+func runSeed(args []string) error {
+	var opts bootstrap.Options
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	opts.RegisterFlags(fs)
+	users := fs.Int("users", 5, "number of demo users")
+	password := fs.String("password", "password123", "password of the demo users")
+	messages := fs.Int("messages", 3, "messages exchanged between each pair of neighbouring users")
+	fs.Parse(args)
+
+	env, err := bootstrap.New(opts)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	log := env.Logger
+	a, err := app.New(env)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx := context.Background()
+
+	userIDs := make([]uuid.UUID, 0, *users)
+	for i := 1; i <= *users; i++ {
+		req := &models.RegisterRequest{
+			Username: fmt.Sprintf("demo%d", i),
+			Email:    fmt.Sprintf("demo%d@example.com", i),
+			Password: *password,
+		}
+
+		resp, err := a.AuthService.Register(ctx, req)
+		if err == nil {
+			userIDs = append(userIDs, resp.ID)
+			continue
+		}
+		if !errors.Is(err, auth.ErrUserAlreadyExists) {
+			return fmt.Errorf("failed to create %s: %w", req.Username, err)
+		}
+
+		existing, err := a.AuthRepo.GetUserByEmail(ctx, req.Email)
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", req.Username, err)
+		}
+		userIDs = append(userIDs, existing.ID)
+	}
+
+	seeded := 0
+	for i := 1; i < len(userIDs); i++ {
+		sender, recipient := userIDs[i-1], userIDs[i]
+
+		// Leave conversations from earlier runs alone
+		conversationID, err := a.ConvRepo.GetOrCreateConversation(ctx, sender, recipient)
+		if err != nil {
+			return err
+		}
+		exists, err := a.ConvRepo.ConversationExists(ctx, conversationID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		for j := 0; j < *messages; j++ {
+			err := a.ConvService.SaveMessage(ctx, &models.DirectMessage{
+				ID:          uuid.New(),
+				SenderID:    sender,
+				RecipientID: recipient,
+				Content:     fmt.Sprintf("Demo message %d", j+1),
+				Format:      models.FormatPlain,
+				CreatedAt:   time.Now(),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to seed conversation %s: %w", conversationID, err)
+			}
+			sender, recipient = recipient, sender
+			seeded++
+		}
+	}
+
+	log.Info("Database seeded", "users", len(userIDs), "messages", seeded, "password", *password)
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/app"
+	"github.com/codingminions/Whatsapp-Lite/internal/bootstrap"
+)
+
+// runServe runs the HTTP and WebSocket server
+func runServe(args []string) error {
+	var opts bootstrap.Options
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	opts.RegisterFlags(fs)
+	runJobs := fs.Bool("jobs", true, "also run background jobs; disable when a separate worker runs them")
+	fs.Parse(args)
+
+	config, log, err := opts.Load()
+	if err != nil {
+		return err
+	}
+	log.Info("Starting chat application server")
+
+	a, err := app.Build(config, log)
+	if err != nil {
+		return err
+	}
+	a.DisableJobs = !*runJobs
+
+	if err := a.Start(); err != nil {
+		a.Stop(context.Background())
+		return err
+	}
+
+	// Listen for signals
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+
+	// Block until we receive a signal or an error
+	select {
+	case err := <-a.Err():
+		log.Error("Server error", "error", err)
+	case <-shutdown:
+		log.Info("Shutting down server")
+	}
+
+	// Create context with timeout for graceful shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), config.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := a.Stop(ctx); err != nil {
+		log.Error("Shutdown error", "error", err)
+	}
+	return nil
+}
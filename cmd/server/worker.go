@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/app"
+	"github.com/codingminions/Whatsapp-Lite/internal/bootstrap"
+)
+
+// runWorker runs the background jobs without serving HTTP, for deployments
+// that run the server with -jobs=false
+func runWorker(args []string) error {
+	var opts bootstrap.Options
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	opts.RegisterFlags(fs)
+	fs.Parse(args)
+
+	env, err := bootstrap.New(opts)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	return app.RunWorker(env)
+}
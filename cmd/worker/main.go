@@ -0,0 +1,32 @@
+// Command worker runs only the background jobs (session cleanup, message
+// retention, attachment expiry, account erasure) against the same database
+// as the web nodes, so workers can be scaled and deployed separately. Run
+// the web nodes with "server serve -jobs=false" so jobs are not run twice.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/app"
+	"github.com/codingminions/Whatsapp-Lite/internal/bootstrap"
+)
+
+func main() {
+	var opts bootstrap.Options
+	opts.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	env, err := bootstrap.New(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "worker: %v\n", err)
+		os.Exit(1)
+	}
+	defer env.Close()
+
+	if err := app.RunWorker(env); err != nil {
+		fmt.Fprintf(os.Stderr, "worker: %v\n", err)
+		os.Exit(1)
+	}
+}
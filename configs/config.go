@@ -11,10 +11,26 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	Auth     AuthConfig     `yaml:"auth"`
+	Server      ServerConfig      `yaml:"server"`
+	Log         LogConfig         `yaml:"log"`
+	Database    DatabaseConfig    `yaml:"database"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Events      EventsConfig      `yaml:"events"`
+	Jobs        JobsConfig        `yaml:"jobs"`
+	Messages    MessagesConfig    `yaml:"messages"`
+	Features    FeaturesConfig    `yaml:"features"`
+	Attachments AttachmentsConfig `yaml:"attachments"`
+	Contacts    ContactsConfig    `yaml:"contacts"`
+	Exports     ExportsConfig     `yaml:"exports"`
+	Accounts    AccountsConfig    `yaml:"accounts"`
+	Calls       CallsConfig       `yaml:"calls"`
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+	WebSocket   WebSocketConfig   `yaml:"websocket"`
+	SSO         SSOConfig         `yaml:"sso"`
+	SMS         SMSConfig         `yaml:"sms"`
+	Email       EmailConfig       `yaml:"email"`
+	Inbox       InboxConfig       `yaml:"inbox"`
 }
 
 // ServerConfig holds server-related configuration
@@ -23,6 +39,56 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `yaml:"read_timeout"`
 	WriteTimeout    time.Duration `yaml:"write_timeout"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	WebDir          string        `yaml:"web_dir"` // serves web assets from disk instead of the embedded copy
+	SPA             SPAConfig     `yaml:"spa"`
+
+	// MetricsAddr is the address of the internal listener serving
+	// Prometheus metrics at /metrics, kept off the public port. Empty
+	// disables metrics.
+	MetricsAddr string `yaml:"metrics_addr"`
+}
+
+// SPAConfig holds the settings for serving a prebuilt single-page app in
+// place of the bundled web pages
+type SPAConfig struct {
+	Dir               string   `yaml:"dir"`                // empty serves the bundled pages
+	ImmutablePrefixes []string `yaml:"immutable_prefixes"` // paths of fingerprinted assets
+}
+
+// LogConfig holds logging configuration. Empty fields keep the defaults of
+// the development or production logger.
+type LogConfig struct {
+	Level    string   `yaml:"level"`    // debug, info, warn or error
+	Encoding string   `yaml:"encoding"` // json or console
+	Outputs  []string `yaml:"outputs"`  // stdout, stderr or file paths
+
+	File      LogFileConfig `yaml:"file"`       // every entry
+	ErrorFile LogFileConfig `yaml:"error_file"` // errors only
+
+	// IncludeContent logs message content, which is otherwise redacted. It
+	// only takes effect in development mode.
+	IncludeContent bool `yaml:"include_content"`
+
+	Redaction LogRedactionConfig `yaml:"redaction"`
+}
+
+// LogRedactionConfig holds the settings of the filter that masks emails,
+// hashes IP addresses and drops tokens and message bodies, so logs can be
+// shipped to third-party aggregators
+type LogRedactionConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Allow   []string `yaml:"allow"`    // field keys logged as is
+	HashKey string   `yaml:"hash_key"` // key of the HMAC that hashes IP addresses; random if empty
+}
+
+// LogFileConfig holds the settings of a rotating log file. An empty path
+// disables the file. A zero size rotates at 100 MB, and zero limits on
+// rotated files keep them forever.
+type LogFileConfig struct {
+	Path       string        `yaml:"path"`
+	MaxSizeMB  int           `yaml:"max_size_mb"`
+	MaxAge     time.Duration `yaml:"max_age"`
+	MaxBackups int           `yaml:"max_backups"`
 }
 
 // DatabaseConfig holds database-related configuration
@@ -33,6 +99,20 @@ type DatabaseConfig struct {
 	Password string `yaml:"password"`
 	DBName   string `yaml:"dbname"`
 	SSLMode  string `yaml:"sslmode"`
+
+	QueryTimeout       time.Duration            `yaml:"query_timeout"`        // 0 disables the timeout
+	SlowQueryThreshold time.Duration            `yaml:"slow_query_threshold"` // 0 disables the slow query log
+	Resilience         DatabaseResilienceConfig `yaml:"resilience"`
+}
+
+// DatabaseResilienceConfig holds the retry policy and circuit breaker
+// settings for database calls
+type DatabaseResilienceConfig struct {
+	MaxAttempts      int           `yaml:"max_attempts"` // 1 disables retries
+	RetryBaseDelay   time.Duration `yaml:"retry_base_delay"`
+	RetryMaxDelay    time.Duration `yaml:"retry_max_delay"`
+	BreakerThreshold int           `yaml:"breaker_threshold"` // 0 disables the breaker
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
 }
 
 // JWTConfig holds JWT-related configuration
@@ -40,11 +120,285 @@ type JWTConfig struct {
 	SecretKey     string        `yaml:"secret_key"`
 	AccessExpiry  time.Duration `yaml:"access_expiry"`
 	RefreshExpiry time.Duration `yaml:"refresh_expiry"`
+
+	// Issuer and Audience identify the deployment in tokens, so tokens
+	// minted by other environments are rejected. Leeway tolerates clock
+	// drift when checking expiry.
+	Issuer   string        `yaml:"issuer"`
+	Audience string        `yaml:"audience"`
+	Leeway   time.Duration `yaml:"leeway"`
+
+	// AcceptLegacy accepts tokens with the claims used before the standard
+	// sub, iat and exp claims, until they have all expired
+	AcceptLegacy bool `yaml:"accept_legacy"`
 }
 
 // AuthConfig holds authentication-related configuration
 type AuthConfig struct {
-	PasswordMinLength int `yaml:"password_min_length"`
+	PasswordMinLength        int                  `yaml:"password_min_length"`
+	PasswordRequireUppercase bool                 `yaml:"password_require_uppercase"`
+	PasswordRequireLowercase bool                 `yaml:"password_require_lowercase"`
+	PasswordRequireDigit     bool                 `yaml:"password_require_digit"`
+	PasswordRequireSymbol    bool                 `yaml:"password_require_symbol"`
+	PasswordBreachCheck      PasswordBreachConfig `yaml:"password_breach_check"`
+	PasswordHashing          PasswordHashConfig   `yaml:"password_hashing"`
+	GeoIPDatabase            string               `yaml:"geoip_database"`       // IP range to country CSV; empty skips country checks
+	MaxSessions              int                  `yaml:"max_sessions"`         // active sessions per user; 0 is unlimited
+	SessionLimitPolicy       string               `yaml:"session_limit_policy"` // reject or evict_oldest
+	Backend                  string               `yaml:"backend"`              // local or ldap
+	LDAP                     LDAPConfig           `yaml:"ldap"`
+}
+
+// LDAPConfig holds the directory used to check credentials when the auth
+// backend is ldap. Users are found with the service account, then their
+// password is checked by binding as them. Accounts are created on first
+// login and display names follow the directory.
+type LDAPConfig struct {
+	URL                  string        `yaml:"url"` // ldap:// or ldaps://
+	StartTLS             bool          `yaml:"start_tls"`
+	BindDN               string        `yaml:"bind_dn"`
+	BindPassword         string        `yaml:"bind_password"`
+	BaseDN               string        `yaml:"base_dn"`
+	UserFilter           string        `yaml:"user_filter"` // %s is replaced by the login email
+	UsernameAttribute    string        `yaml:"username_attribute"`
+	DisplayNameAttribute string        `yaml:"display_name_attribute"`
+	Timeout              time.Duration `yaml:"timeout"`
+}
+
+// PasswordBreachConfig holds the breached password check configuration
+type PasswordBreachConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	URL     string        `yaml:"url"` // Pwned Passwords range API; empty uses the public one
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// PasswordHashConfig holds the password hashing configuration. Hashes made
+// with another algorithm, cost or pepper are replaced when their users next
+// log in.
+type PasswordHashConfig struct {
+	Algorithm  string       `yaml:"algorithm"` // argon2id or bcrypt
+	BcryptCost int          `yaml:"bcrypt_cost"`
+	Argon2     Argon2Config `yaml:"argon2"`
+	Pepper     PepperConfig `yaml:"pepper"`
+}
+
+// PepperConfig holds the secret peppers mixed into passwords before
+// hashing. Files maps each pepper ID to a file holding the secret, such as a
+// mounted secret. To rotate, add a pepper and make it current; hashes are
+// moved to it as users log in, and the old pepper can be removed once none
+// use it.
+type PepperConfig struct {
+	Current string            `yaml:"current"` // empty leaves new hashes unpeppered
+	Files   map[string]string `yaml:"files"`
+}
+
+// Argon2Config holds the argon2id cost parameters
+type Argon2Config struct {
+	Memory      uint32 `yaml:"memory"` // in KiB
+	Iterations  uint32 `yaml:"iterations"`
+	Parallelism uint8  `yaml:"parallelism"`
+	SaltLength  uint32 `yaml:"salt_length"`
+	KeyLength   uint32 `yaml:"key_length"`
+}
+
+// EventsConfig holds domain event publishing configuration
+type EventsConfig struct {
+	Driver string      `yaml:"driver"` // none, log, nats or kafka
+	Prefix string      `yaml:"prefix"`
+	NATS   NATSConfig  `yaml:"nats"`
+	Kafka  KafkaConfig `yaml:"kafka"`
+}
+
+// NATSConfig holds NATS connection configuration
+type NATSConfig struct {
+	URL string `yaml:"url"`
+}
+
+// KafkaConfig holds Kafka producer configuration
+type KafkaConfig struct {
+	Brokers      []string      `yaml:"brokers"`
+	BatchTimeout time.Duration `yaml:"batch_timeout"`
+}
+
+// JobsConfig holds background job configuration
+type JobsConfig struct {
+	Workers                int           `yaml:"workers"`
+	QueueSize              int           `yaml:"queue_size"`
+	SessionCleanupInterval time.Duration `yaml:"session_cleanup_interval"`
+	RetentionInterval      time.Duration `yaml:"retention_interval"`
+	MessageRetention       time.Duration `yaml:"message_retention"` // 0 keeps messages forever
+	DisappearingInterval   time.Duration `yaml:"disappearing_interval"`
+	AccountErasureInterval time.Duration `yaml:"account_erasure_interval"`
+	ThumbnailInterval      time.Duration `yaml:"thumbnail_interval"`
+}
+
+// MessagesConfig holds message content configuration
+type MessagesConfig struct {
+	MaxLength      int           `yaml:"max_length"`      // in characters
+	SanitizePolicy string        `yaml:"sanitize_policy"` // strip, escape or none
+	SendTimeout    time.Duration `yaml:"send_timeout"`    // deadline for saving a WebSocket message
+
+	Buffer MessageBufferConfig `yaml:"buffer"`
+}
+
+// MessageBufferConfig holds the settings for buffering messages on local
+// disk while the database is unavailable
+type MessageBufferConfig struct {
+	Path          string        `yaml:"path"` // empty disables buffering
+	MaxMessages   int           `yaml:"max_messages"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// FeaturesConfig holds feature flag configuration
+type FeaturesConfig struct {
+	RefreshInterval time.Duration                `yaml:"refresh_interval"`
+	Flags           map[string]FeatureFlagConfig `yaml:"flags"`
+}
+
+// FeatureFlagConfig holds the default state of a single feature flag
+type FeatureFlagConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	Percentage *int     `yaml:"percentage"` // defaults to 100
+	Users      []string `yaml:"users"`      // user IDs that always get the feature
+}
+
+// AttachmentsConfig holds attachment upload and download configuration
+type AttachmentsConfig struct {
+	MaxSize    int64         `yaml:"max_size"`    // in bytes
+	URLExpiry  time.Duration `yaml:"url_expiry"`  // lifetime of signed download URLs
+	SigningKey string        `yaml:"signing_key"` // defaults to the JWT secret key
+	// StripMetadata re-encodes uploaded images to drop EXIF and GPS data
+	StripMetadata bool          `yaml:"strip_metadata"`
+	Storage       StorageConfig `yaml:"storage"`
+}
+
+// StorageConfig holds attachment storage backend configuration
+type StorageConfig struct {
+	Driver        string             `yaml:"driver"`         // local, s3 or gcs
+	StorageClass  string             `yaml:"storage_class"`  // object storage class, e.g. STANDARD_IA or NEARLINE
+	Expiry        time.Duration      `yaml:"expiry"`         // 0 keeps attachments forever
+	CleanupPeriod time.Duration      `yaml:"cleanup_period"` // how often expired attachments are deleted
+	Local         LocalStorageConfig `yaml:"local"`
+	S3            S3Config           `yaml:"s3"`
+	GCS           GCSConfig          `yaml:"gcs"`
+}
+
+// LocalStorageConfig holds local disk storage configuration
+type LocalStorageConfig struct {
+	Dir string `yaml:"dir"`
+}
+
+// S3Config holds S3 or S3-compatible object storage configuration
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint"` // defaults to AWS for the region
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	PathStyle       bool   `yaml:"path_style"` // required by most S3-compatible servers
+}
+
+// GCSConfig holds Google Cloud Storage configuration, authenticated with
+// HMAC keys
+type GCSConfig struct {
+	Endpoint        string `yaml:"endpoint"` // defaults to https://storage.googleapis.com
+	Bucket          string `yaml:"bucket"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+}
+
+// ContactsConfig holds contact request configuration
+type ContactsConfig struct {
+	// StrictMode only lets users message contacts who accepted their request
+	StrictMode bool `yaml:"strict_mode"`
+}
+
+// ExportsConfig holds conversation transcript export configuration
+type ExportsConfig struct {
+	MaxMessages int           `yaml:"max_messages"` // larger conversations cannot be exported
+	Interval    time.Duration `yaml:"interval"`     // minimum time between exports by the same user
+}
+
+// AccountsConfig holds account data export and deletion configuration
+type AccountsConfig struct {
+	// DeletionGracePeriod is how long users can cancel a deletion request
+	// before their account is erased
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period"`
+}
+
+// CallsConfig holds voice and video call configuration
+type CallsConfig struct {
+	STUNURLs      []string      `yaml:"stun_urls"`
+	TURNURLs      []string      `yaml:"turn_urls"`
+	TURNSecret    string        `yaml:"turn_secret"`    // coturn static-auth-secret; empty leaves out the TURN servers
+	CredentialTTL time.Duration `yaml:"credential_ttl"` // how long issued TURN credentials work
+}
+
+// MaintenanceConfig holds the maintenance mode state at startup. Admins can
+// change it at runtime.
+type MaintenanceConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Message     string `yaml:"message"`      // shown to clients; empty uses a generic message
+	AllowAdmins bool   `yaml:"allow_admins"` // let admin users through while enabled
+}
+
+// WebSocketConfig holds WebSocket connection configuration
+type WebSocketConfig struct {
+	// AllowedOrigins lists the web origins other than the server's own that
+	// may open connections, such as https://app.example.com. A host of
+	// *.example.com allows every subdomain and "*" allows any origin.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+
+	// MinAppVersions maps client platforms, such as ios or android, to the
+	// oldest app version the server supports. Older clients are told to
+	// upgrade and disconnected.
+	MinAppVersions map[string]string `yaml:"min_app_versions"`
+}
+
+// SSOConfig holds enterprise single sign-on configuration
+type SSOConfig struct {
+	Providers []SSOProviderConfig `yaml:"providers"`
+	Timeout   time.Duration       `yaml:"timeout"` // for requests to identity providers
+}
+
+// SSOProviderConfig holds the configuration of one identity provider.
+// Users signing in through it are matched to accounts by the provider's
+// subject identifier, then by email if LinkByEmail is set and the provider
+// says the email is verified.
+type SSOProviderConfig struct {
+	Name        string            `yaml:"name"`          // used in the provider's URLs
+	Type        string            `yaml:"type"`          // oidc or saml
+	Provision   bool              `yaml:"provision"`     // create accounts for unknown users on first sign-in
+	LinkByEmail bool              `yaml:"link_by_email"` // trust the provider's emails to link existing accounts
+	GroupsClaim string            `yaml:"groups_claim"`  // claim or attribute listing the user's groups
+	RoleMapping map[string]string `yaml:"role_mapping"`  // group to role; when set, roles follow the groups on every sign-in
+	OIDC        OIDCConfig        `yaml:"oidc"`
+	SAML        SAMLConfig        `yaml:"saml"`
+}
+
+// OIDCConfig holds an OpenID Connect provider's settings. The endpoints are
+// found through the issuer's discovery document.
+type OIDCConfig struct {
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"` // this server's callback URL for the provider
+	Scopes       []string `yaml:"scopes"`       // empty requests openid, email and profile
+}
+
+// SAMLConfig holds a SAML identity provider's settings. Assertions are
+// accepted at the provider's ACS URL when signed with the certificate.
+type SAMLConfig struct {
+	IdPEntityID       string `yaml:"idp_entity_id"`
+	IdPCertificate    string `yaml:"idp_certificate"` // PEM file
+	EntityID          string `yaml:"entity_id"`       // this service's entity ID, the audience of assertions
+	ACSURL            string `yaml:"acs_url"`         // checked against the assertion's recipient when set
+	EmailAttribute    string `yaml:"email_attribute"` // empty uses the NameID
+	UsernameAttribute string `yaml:"username_attribute"`
+
+	// EmailVerifiedAttribute names an attribute whose value "true" marks the
+	// email as verified. Empty treats emails as unverified.
+	EmailVerifiedAttribute string `yaml:"email_verified_attribute"`
 }
 
 // LoadConfig loads the configuration from a file
@@ -62,3 +416,64 @@ func LoadConfig(configPath string) (*Config, error) {
 
 	return &config, nil
 }
+
+// SMSConfig holds SMS configuration for phone number verification and
+// notifications about unread messages
+type SMSConfig struct {
+	Provider   string        `yaml:"provider"`    // twilio or log; empty disables SMS
+	Timeout    time.Duration `yaml:"timeout"`     // for requests to the provider
+	CodeExpiry time.Duration `yaml:"code_expiry"` // lifetime of phone verification codes
+	// FallbackDelay is how long a direct message stays unread before its
+	// recipient is sent an SMS, if they opted in
+	FallbackDelay time.Duration `yaml:"fallback_delay"`
+	DailyCap      int           `yaml:"daily_cap"` // SMS notifications per user in 24 hours
+	Interval      time.Duration `yaml:"interval"`  // how often unread messages are checked
+	// PhoneLogin lets users sign up and log in with a code texted to their
+	// phone number instead of an email and password. It needs a provider
+	// and cannot be used with the ldap auth backend.
+	PhoneLogin bool         `yaml:"phone_login"`
+	Twilio     TwilioConfig `yaml:"twilio"`
+}
+
+// TwilioConfig holds Twilio Programmable Messaging credentials
+type TwilioConfig struct {
+	AccountSID          string `yaml:"account_sid"`
+	AuthToken           string `yaml:"auth_token"`
+	From                string `yaml:"from"`                  // sending phone number
+	MessagingServiceSID string `yaml:"messaging_service_sid"` // used instead of from when set
+}
+
+// EmailConfig holds configuration for notification emails and replies to
+// them
+type EmailConfig struct {
+	From string     `yaml:"from"` // sender address; empty disables email notifications
+	SMTP SMTPConfig `yaml:"smtp"`
+	// ReplyDomain receives replies to notification emails. Its mail must be
+	// forwarded by the mail provider to POST /email/inbound. Empty leaves
+	// reply addresses out of notifications.
+	ReplyDomain string `yaml:"reply_domain"`
+	// WebhookSecret authenticates the mail provider's inbound requests,
+	// sent in the X-Webhook-Secret header
+	WebhookSecret string `yaml:"webhook_secret"`
+	// DigestInterval is how often users who opted in are emailed a summary
+	// of their unread messages. 0 disables digests.
+	DigestInterval time.Duration `yaml:"digest_interval"`
+}
+
+// SMTPConfig holds the SMTP server that sends email. Port 465 uses implicit
+// TLS; other ports upgrade with STARTTLS when the server offers it.
+type SMTPConfig struct {
+	Host     string        `yaml:"host"`
+	Port     int           `yaml:"port"`
+	Username string        `yaml:"username"`
+	Password string        `yaml:"password"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// InboxConfig holds configuration for keeping the durable events sent to
+// users until their devices acknowledge them
+type InboxConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	Retention       time.Duration `yaml:"retention"`        // unacknowledged events older than this are deleted
+	CleanupInterval time.Duration `yaml:"cleanup_interval"` // how often expired events are deleted
+}
@@ -0,0 +1,46 @@
+package account
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+)
+
+// WriteArchive writes an account export as a zip archive holding one JSON
+// file per kind of data
+func WriteArchive(w io.Writer, export *models.AccountExport) error {
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"sessions.json", export.Sessions},
+		{"contacts.json", export.Contacts},
+		{"direct_messages.json", export.DirectMessages},
+		{"group_messages.json", export.GroupMessages},
+		{"attachments.json", export.Attachments},
+		{"audit_log.json", export.AuditLog},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,141 @@
+package account
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/google/uuid"
+)
+
+// Handler handles account data export and deletion HTTP requests
+type Handler struct {
+	service Service
+	logger  logger.Logger
+}
+
+// NewHandler creates a new account handler
+func NewHandler(service Service, logger logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Export handles requests to download an archive of the user's data
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	export, err := h.service.Export(r.Context(), userID, auth.ClientIP(r))
+	if err != nil {
+		h.sendServiceError(w, r, err, "account.export_failed")
+		return
+	}
+
+	// Stream the archive as a download
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="account-export.zip"`)
+	w.WriteHeader(http.StatusOK)
+
+	if err := WriteArchive(w, export); err != nil {
+		// Headers are already sent, so the client sees a truncated download
+		h.logger.WithContext(r.Context()).Error("Failed to write account archive", "error", err)
+	}
+}
+
+// RequestDeletion handles requests to delete the user's account
+func (h *Handler) RequestDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	resp, err := h.service.RequestDeletion(r.Context(), userID, auth.ClientIP(r))
+	if err != nil {
+		h.sendServiceError(w, r, err, "account.deletion_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusAccepted, resp)
+}
+
+// CancelDeletion handles requests to cancel a scheduled account deletion
+func (h *Handler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	if err := h.service.CancelDeletion(r.Context(), userID, auth.ClientIP(r)); err != nil {
+		h.sendServiceError(w, r, err, "account.cancel_deletion_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing or malformed
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// sendServiceError maps a service error to an HTTP error response, using
+// key for unexpected errors
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error, key string) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+	case errors.Is(err, ErrDeletionNotScheduled):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "account.deletion_not_scheduled"))
+	default:
+		h.logger.WithContext(r.Context()).Error(i18n.English.T(key), "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, key))
+	}
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
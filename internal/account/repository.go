@@ -0,0 +1,307 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+)
+
+// ErrUserNotFound is returned when the user does not exist or was erased
+var ErrUserNotFound = errors.New("user not found")
+
+// Repository interface for account data export and erasure operations
+type Repository interface {
+	GetProfile(ctx context.Context, userID uuid.UUID) (*models.AccountProfile, error)
+	GetSessions(ctx context.Context, userID uuid.UUID) ([]models.AccountSession, error)
+	GetContacts(ctx context.Context, userID uuid.UUID) ([]models.AccountContact, error)
+	GetDirectMessages(ctx context.Context, userID uuid.UUID) ([]models.DirectMessage, error)
+	GetGroupMessages(ctx context.Context, userID uuid.UUID) ([]models.AccountGroupMessage, error)
+	GetAttachments(ctx context.Context, userID uuid.UUID) ([]models.Attachment, error)
+	GetAuditLog(ctx context.Context, userID uuid.UUID) ([]models.AccountAuditEntry, error)
+	AddAuditEntry(ctx context.Context, userID uuid.UUID, action, clientIP string) error
+	ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) (time.Time, error)
+	CancelDeletion(ctx context.Context, userID uuid.UUID) (bool, error)
+	GetDueDeletions(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+	EraseUser(ctx context.Context, userID uuid.UUID) ([]string, error)
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// GetProfile retrieves the profile of a user who has not been erased
+func (r *PostgresRepository) GetProfile(ctx context.Context, userID uuid.UUID) (*models.AccountProfile, error) {
+	query := `
+        SELECT id, username, COALESCE(email, '') as email, COALESCE(phone_number, '') as phone_number, role, status_text, status_emoji, message_privacy, created_at, deletion_scheduled_at
+        FROM users
+        WHERE id = $1 AND erased_at IS NULL
+    `
+
+	var profile models.AccountProfile
+	err := r.conn(ctx).GetContext(ctx, &profile, query, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+// GetSessions retrieves a user's sessions, newest first
+func (r *PostgresRepository) GetSessions(ctx context.Context, userID uuid.UUID) ([]models.AccountSession, error) {
+	query := `
+        SELECT id, user_agent, client_ip, created_at, last_active_at, expires_at
+        FROM sessions
+        WHERE user_id = $1
+        ORDER BY created_at DESC
+    `
+
+	var sessions []models.AccountSession
+	err := r.conn(ctx).SelectContext(ctx, &sessions, query, userID)
+	return sessions, err
+}
+
+// GetContacts retrieves a user's accepted contacts
+func (r *PostgresRepository) GetContacts(ctx context.Context, userID uuid.UUID) ([]models.AccountContact, error) {
+	query := `
+        SELECT c.contact_id, u.username, c.created_at
+        FROM contacts c
+        JOIN users u ON u.id = c.contact_id
+        WHERE c.user_id = $1
+        ORDER BY c.created_at ASC
+    `
+
+	var contacts []models.AccountContact
+	err := r.conn(ctx).SelectContext(ctx, &contacts, query, userID)
+	return contacts, err
+}
+
+// GetDirectMessages retrieves every direct message a user sent or
+// received, oldest first
+func (r *PostgresRepository) GetDirectMessages(ctx context.Context, userID uuid.UUID) ([]models.DirectMessage, error) {
+	query := `
+        SELECT dm.id, dm.type, dm.data, dm.sender_id, dm.recipient_id, dm.content, dm.format, COALESCE(dm.rendered_content, '') as rendered_content,
+               dm.delivered,
+               (rc.user_id IS NOT NULL AND (dm.created_at, dm.id) <= (rc.last_read_message_at, rc.last_read_message_id)) as read,
+               dm.created_at
+        FROM direct_messages dm
+        LEFT JOIN read_cursors rc
+            ON rc.user_id = dm.recipient_id
+           AND rc.conversation_id = LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text
+        WHERE dm.sender_id = $1 OR dm.recipient_id = $1
+        ORDER BY dm.created_at ASC, dm.id ASC
+    `
+
+	var messages []models.DirectMessage
+	err := r.conn(ctx).SelectContext(ctx, &messages, query, userID)
+	return messages, err
+}
+
+// GetGroupMessages retrieves every group message a user sent, oldest first
+func (r *PostgresRepository) GetGroupMessages(ctx context.Context, userID uuid.UUID) ([]models.AccountGroupMessage, error) {
+	query := `
+        SELECT id, group_id, type, content, created_at
+        FROM group_messages
+        WHERE sender_id = $1
+        ORDER BY created_at ASC, id ASC
+    `
+
+	var messages []models.AccountGroupMessage
+	err := r.conn(ctx).SelectContext(ctx, &messages, query, userID)
+	return messages, err
+}
+
+// GetAttachments retrieves the attachments a user uploaded, oldest first
+func (r *PostgresRepository) GetAttachments(ctx context.Context, userID uuid.UUID) ([]models.Attachment, error) {
+	query := `
+        SELECT id, conversation_id, uploader_id, filename, content_type, size, storage_key, created_at
+        FROM attachments
+        WHERE uploader_id = $1
+        ORDER BY created_at ASC, id ASC
+    `
+
+	var attachments []models.Attachment
+	err := r.conn(ctx).SelectContext(ctx, &attachments, query, userID)
+	return attachments, err
+}
+
+// GetAuditLog retrieves a user's audit entries, oldest first
+func (r *PostgresRepository) GetAuditLog(ctx context.Context, userID uuid.UUID) ([]models.AccountAuditEntry, error) {
+	query := `
+        SELECT action, client_ip, created_at
+        FROM account_audit_log
+        WHERE user_id = $1
+        ORDER BY created_at ASC
+    `
+
+	var entries []models.AccountAuditEntry
+	err := r.conn(ctx).SelectContext(ctx, &entries, query, userID)
+	return entries, err
+}
+
+// AddAuditEntry records an action on a user's account
+func (r *PostgresRepository) AddAuditEntry(ctx context.Context, userID uuid.UUID, action, clientIP string) error {
+	query := `
+        INSERT INTO account_audit_log (user_id, action, client_ip)
+        VALUES ($1, $2, $3)
+    `
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, action, clientIP)
+	return err
+}
+
+// ScheduleDeletion schedules a user's account for erasure at the given
+// time and returns the scheduled time. An earlier request keeps its time.
+func (r *PostgresRepository) ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) (time.Time, error) {
+	query := `
+        UPDATE users
+        SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, $2)
+        WHERE id = $1 AND erased_at IS NULL
+        RETURNING deletion_scheduled_at
+    `
+
+	var scheduledAt time.Time
+	err := r.conn(ctx).GetContext(ctx, &scheduledAt, query, userID, at)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrUserNotFound
+		}
+		return time.Time{}, err
+	}
+
+	return scheduledAt, nil
+}
+
+// CancelDeletion cancels a user's scheduled erasure, reporting whether one
+// was scheduled
+func (r *PostgresRepository) CancelDeletion(ctx context.Context, userID uuid.UUID) (bool, error) {
+	query := `
+        UPDATE users
+        SET deletion_scheduled_at = NULL
+        WHERE id = $1 AND deletion_scheduled_at IS NOT NULL AND erased_at IS NULL
+    `
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// GetDueDeletions retrieves up to limit users whose grace period has passed
+func (r *PostgresRepository) GetDueDeletions(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+        SELECT id
+        FROM users
+        WHERE deletion_scheduled_at <= $1 AND erased_at IS NULL
+        ORDER BY deletion_scheduled_at ASC
+        LIMIT $2
+    `
+
+	var userIDs []uuid.UUID
+	err := r.conn(ctx).SelectContext(ctx, &userIDs, query, now, limit)
+	return userIDs, err
+}
+
+// erasureStatements remove or anonymize a user's data in every table.
+// Messages sent to others are kept with their content removed, so the
+// other participants' histories keep their shape.
+var erasureStatements = []string{
+	"DELETE FROM sessions WHERE user_id = $1",
+	"DELETE FROM login_history WHERE user_id = $1",
+	"DELETE FROM drafts WHERE user_id = $1",
+	"DELETE FROM read_cursors WHERE user_id = $1",
+	"DELETE FROM mentions WHERE user_id = $1",
+	"DELETE FROM contacts WHERE user_id = $1 OR contact_id = $1",
+	"DELETE FROM contact_requests WHERE requester_id = $1 OR addressee_id = $1",
+	"DELETE FROM notification_preferences WHERE user_id = $1",
+	"DELETE FROM conversation_notification_overrides WHERE user_id = $1",
+	"DELETE FROM conversation_settings WHERE user_id = $1",
+	"DELETE FROM phone_verifications WHERE user_id = $1",
+	"DELETE FROM phone_login_codes WHERE phone_number = (SELECT phone_number FROM users WHERE id = $1)",
+	"DELETE FROM sms_notifications WHERE user_id = $1",
+	"DELETE FROM email_reply_tokens WHERE user_id = $1",
+	"DELETE FROM user_events WHERE user_id = $1",
+	"DELETE FROM user_event_offsets WHERE user_id = $1",
+	"DELETE FROM conversation_visibility WHERE user_id = $1",
+	"DELETE FROM group_join_requests WHERE user_id = $1",
+	"DELETE FROM group_invites WHERE created_by = $1",
+	"DELETE FROM message_delivery_status WHERE user_id = $1",
+	"DELETE FROM group_members WHERE user_id = $1",
+	"DELETE FROM user_identities WHERE user_id = $1",
+	"DELETE FROM workspace_members WHERE user_id = $1",
+	"UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL",
+	`WITH blanked AS (
+        UPDATE direct_messages SET content = '', rendered_content = NULL
+        WHERE sender_id = $1
+        RETURNING sender_id, recipient_id
+    )
+    UPDATE conversation_summaries
+    SET version = version + 1
+    WHERE conversation_id IN (
+        SELECT DISTINCT LEAST(sender_id, recipient_id)::text || '-' || GREATEST(sender_id, recipient_id)::text
+        FROM blanked
+    )`,
+	"UPDATE group_messages SET content = '' WHERE sender_id = $1",
+	`UPDATE users
+        SET username = 'deleted-' || replace(id::text, '-', ''),
+            display_name = '',
+            email = id::text || '@deleted.invalid',
+            password_hash = '',
+            status = 'offline',
+            status_text = '',
+            status_emoji = '',
+            status_expires_at = NULL,
+            phone_number = NULL,
+            phone_verified_at = NULL,
+            deletion_scheduled_at = NULL,
+            erased_at = NOW(),
+            updated_at = NOW()
+        WHERE id = $1`,
+}
+
+// EraseUser removes or anonymizes a user's data and returns the storage
+// keys of the attachments they uploaded and their thumbnails, which the
+// caller must delete
+func (r *PostgresRepository) EraseUser(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var storageKeys []string
+	err := r.conn(ctx).SelectContext(ctx, &storageKeys, `
+        WITH deleted AS (
+            DELETE FROM attachments WHERE uploader_id = $1
+            RETURNING storage_key, thumbnail_key
+        )
+        SELECT storage_key FROM deleted
+        UNION ALL
+        SELECT thumbnail_key FROM deleted WHERE thumbnail_key IS NOT NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, statement := range erasureStatements {
+		if _, err := r.conn(ctx).ExecContext(ctx, statement, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return storageKeys, nil
+}
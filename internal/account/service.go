@@ -0,0 +1,185 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// ErrDeletionNotScheduled is returned when cancelling a deletion that was
+// never requested
+var ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+
+// erasureBatchSize is the most accounts erased in a single run
+const erasureBatchSize = 100
+
+// Service handles account data export and erasure business logic
+type Service interface {
+	Export(ctx context.Context, userID uuid.UUID, clientIP string) (*models.AccountExport, error)
+	RequestDeletion(ctx context.Context, userID uuid.UUID, clientIP string) (*models.AccountDeletionResponse, error)
+	CancelDeletion(ctx context.Context, userID uuid.UUID, clientIP string) error
+	EraseDue(ctx context.Context) (int, error)
+}
+
+// FileStore deletes stored attachment files
+type FileStore interface {
+	Delete(ctx context.Context, key string) error
+}
+
+// AccountService implements Service interface
+type AccountService struct {
+	repo   Repository
+	uow    database.UnitOfWork
+	files  FileStore
+	config configs.AccountsConfig
+	logger logger.Logger
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(repo Repository, uow database.UnitOfWork, files FileStore, config configs.AccountsConfig, logger logger.Logger) *AccountService {
+	return &AccountService{
+		repo:   repo,
+		uow:    uow,
+		files:  files,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Export collects the personal data held about a user
+func (s *AccountService) Export(ctx context.Context, userID uuid.UUID, clientIP string) (*models.AccountExport, error) {
+	// Record the export first so it appears in its own audit log
+	if err := s.repo.AddAuditEntry(ctx, userID, models.AuditExportRequested, clientIP); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to record account export", "error", err)
+		return nil, err
+	}
+
+	export := &models.AccountExport{ExportedAt: time.Now()}
+
+	profile, err := s.repo.GetProfile(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to get profile for export", "error", err)
+		}
+		return nil, err
+	}
+	export.Profile = *profile
+
+	if export.Sessions, err = s.repo.GetSessions(ctx, userID); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get sessions for export", "error", err)
+		return nil, err
+	}
+	if export.Contacts, err = s.repo.GetContacts(ctx, userID); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get contacts for export", "error", err)
+		return nil, err
+	}
+	if export.DirectMessages, err = s.repo.GetDirectMessages(ctx, userID); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get direct messages for export", "error", err)
+		return nil, err
+	}
+	if export.GroupMessages, err = s.repo.GetGroupMessages(ctx, userID); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group messages for export", "error", err)
+		return nil, err
+	}
+	if export.Attachments, err = s.repo.GetAttachments(ctx, userID); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get attachments for export", "error", err)
+		return nil, err
+	}
+	if export.AuditLog, err = s.repo.GetAuditLog(ctx, userID); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get audit log for export", "error", err)
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// RequestDeletion schedules the user's account for erasure once the grace
+// period passes. Requesting again keeps the original schedule.
+func (s *AccountService) RequestDeletion(ctx context.Context, userID uuid.UUID, clientIP string) (*models.AccountDeletionResponse, error) {
+	var scheduledAt time.Time
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		scheduledAt, err = s.repo.ScheduleDeletion(ctx, userID, time.Now().Add(s.config.DeletionGracePeriod))
+		if err != nil {
+			return err
+		}
+		return s.repo.AddAuditEntry(ctx, userID, models.AuditDeletionRequested, clientIP)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to schedule account deletion", "error", err)
+		}
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Account deletion scheduled", "user_id", userID, "deletion_scheduled_at", scheduledAt)
+
+	return &models.AccountDeletionResponse{DeletionScheduledAt: scheduledAt}, nil
+}
+
+// CancelDeletion cancels the user's scheduled account erasure
+func (s *AccountService) CancelDeletion(ctx context.Context, userID uuid.UUID, clientIP string) error {
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		cancelled, err := s.repo.CancelDeletion(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !cancelled {
+			return ErrDeletionNotScheduled
+		}
+		return s.repo.AddAuditEntry(ctx, userID, models.AuditDeletionCancelled, clientIP)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrDeletionNotScheduled) {
+			s.logger.WithContext(ctx).Error("Failed to cancel account deletion", "error", err)
+		}
+		return err
+	}
+
+	s.logger.WithContext(ctx).Info("Account deletion cancelled", "user_id", userID)
+	return nil
+}
+
+// EraseDue erases the accounts whose grace period has passed and returns
+// how many were erased
+func (s *AccountService) EraseDue(ctx context.Context) (int, error) {
+	userIDs, err := s.repo.GetDueDeletions(ctx, time.Now(), erasureBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	erased := 0
+	for _, userID := range userIDs {
+		var storageKeys []string
+		err := s.uow.Do(ctx, func(ctx context.Context) error {
+			var err error
+			storageKeys, err = s.repo.EraseUser(ctx, userID)
+			if err != nil {
+				return err
+			}
+			return s.repo.AddAuditEntry(ctx, userID, models.AuditAccountErased, "")
+		})
+		if err != nil {
+			return erased, err
+		}
+		erased++
+
+		// Files are deleted after the commit, so a failure here only
+		// leaves unreferenced files behind
+		for _, key := range storageKeys {
+			if err := s.files.Delete(ctx, key); err != nil {
+				s.logger.WithContext(ctx).Error("Failed to delete attachment of erased account", "error", err, "user_id", userID)
+			}
+		}
+
+		s.logger.WithContext(ctx).Info("Account erased", "user_id", userID, "attachments", len(storageKeys))
+	}
+
+	return erased, nil
+}
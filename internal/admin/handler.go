@@ -0,0 +1,211 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Page sizes of the connection list
+const (
+	connectionListLimit    = 100
+	maxConnectionListLimit = 1000
+)
+
+// Handler handles admin HTTP requests
+type Handler struct {
+	service   Service
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new admin handler
+func NewHandler(service Service, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		service:   service,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// GetStats handles requests for usage statistics
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+	if days <= 0 || days > 90 {
+		days = 7 // Default window
+	}
+
+	// Call service
+	resp, err := h.service.GetStats(r.Context(), days)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get stats", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "admin.stats_failed"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// ListConnections handles requests to list the open WebSocket connections
+func (h *Handler) ListConnections(w http.ResponseWriter, r *http.Request) {
+	limit := pagination.ParseLimit(r.URL.Query().Get("limit"), connectionListLimit, maxConnectionListLimit)
+
+	// Call service
+	resp := h.service.ListConnections(r.Context(), limit)
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// ListJobs handles requests for the status of the background jobs
+func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	// Call service
+	resp := h.service.ListJobs(r.Context())
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// GetUserConnections handles requests for a user's connection state
+func (h *Handler) GetUserConnections(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	resp := h.service.GetUserConnections(r.Context(), userID)
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// DisconnectUser handles requests to close a user's live connections
+func (h *Handler) DisconnectUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	resp := h.service.DisconnectUser(r.Context(), userID)
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// BanUser handles requests to ban a user
+func (h *Handler) BanUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	adminIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return
+	}
+	adminID, err := uuid.Parse(adminIDStr)
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Parse and validate request
+	var req models.BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode ban request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	resp, err := h.service.BanUser(r.Context(), adminID, userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrBanSelf):
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "admin.ban_self"))
+		case errors.Is(err, ErrBanEnded):
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "admin.ban_ended"))
+		case errors.Is(err, ErrUserNotFound):
+			sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+		default:
+			sendError(w, r, errcode.Internal, i18n.T(r, "admin.ban_failed"))
+		}
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// UnbanUser handles requests to lift a user's ban
+func (h *Handler) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	if err := h.service.UnbanUser(r.Context(), userID); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+			return
+		}
+		sendError(w, r, errcode.Internal, i18n.T(r, "admin.ban_failed"))
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+)
+
+// ErrUserNotFound is returned when a user does not exist
+var ErrUserNotFound = errors.New("user not found")
+
+// Repository interface for admin statistics and moderation
+type Repository interface {
+	GetDailyActiveUsers(ctx context.Context, since time.Time) ([]models.DailyCount, error)
+	GetMessagesPerDay(ctx context.Context, since time.Time) ([]models.DailyCount, error)
+	GetTopConversations(ctx context.Context, since time.Time, limit int) ([]models.ConversationStat, error)
+	BanUser(ctx context.Context, userID uuid.UUID, ban *models.Ban) error
+	UnbanUser(ctx context.Context, userID uuid.UUID) error
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// GetDailyActiveUsers counts distinct users who sent a message or used a session each day
+func (r *PostgresRepository) GetDailyActiveUsers(ctx context.Context, since time.Time) ([]models.DailyCount, error) {
+	query := `
+        WITH activity AS (
+            SELECT sender_id as user_id, created_at as active_at
+            FROM direct_messages
+            WHERE created_at >= $1
+            UNION ALL
+            SELECT user_id, last_active_at as active_at
+            FROM sessions
+            WHERE last_active_at >= $1
+        )
+        SELECT
+            date_trunc('day', active_at) as day,
+            COUNT(DISTINCT user_id) as count
+        FROM activity
+        GROUP BY day
+        ORDER BY day ASC
+    `
+
+	var counts []models.DailyCount
+	if err := r.conn(ctx).SelectContext(ctx, &counts, query, since); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetMessagesPerDay counts messages sent each day
+func (r *PostgresRepository) GetMessagesPerDay(ctx context.Context, since time.Time) ([]models.DailyCount, error) {
+	query := `
+        SELECT
+            date_trunc('day', created_at) as day,
+            COUNT(*) as count
+        FROM direct_messages
+        WHERE created_at >= $1
+        GROUP BY day
+        ORDER BY day ASC
+    `
+
+	var counts []models.DailyCount
+	if err := r.conn(ctx).SelectContext(ctx, &counts, query, since); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// GetTopConversations returns the conversations with the most messages since a time
+func (r *PostgresRepository) GetTopConversations(ctx context.Context, since time.Time, limit int) ([]models.ConversationStat, error) {
+	query := `
+        SELECT
+            LEAST(sender_id, recipient_id)::text || '-' || GREATEST(sender_id, recipient_id)::text as conversation_id,
+            COUNT(*) as message_count
+        FROM direct_messages
+        WHERE created_at >= $1
+        GROUP BY LEAST(sender_id, recipient_id), GREATEST(sender_id, recipient_id)
+        ORDER BY message_count DESC
+        LIMIT $2
+    `
+
+	var stats []models.ConversationStat
+	if err := r.conn(ctx).SelectContext(ctx, &stats, query, since, limit); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// BanUser records a ban on a user, replacing any earlier ban
+func (r *PostgresRepository) BanUser(ctx context.Context, userID uuid.UUID, ban *models.Ban) error {
+	query := `
+        UPDATE users
+        SET banned_at = $1, banned_until = $2, ban_reason = $3
+        WHERE id = $4
+    `
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, ban.BannedAt, ban.BannedUntil, ban.BanReason, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+// UnbanUser lifts a user's ban
+func (r *PostgresRepository) UnbanUser(ctx context.Context, userID uuid.UUID) error {
+	query := `
+        UPDATE users
+        SET banned_at = NULL, banned_until = NULL, ban_reason = ''
+        WHERE id = $1
+    `
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+// requireRow returns ErrUserNotFound if an update matched no user
+func requireRow(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// topConversationsLimit is the number of conversations returned in statistics
+const topConversationsLimit = 10
+
+// Service errors
+var (
+	ErrBanEnded = errors.New("ban must end in the future")
+	ErrBanSelf  = errors.New("admins cannot ban themselves")
+)
+
+// Reasons given to clients whose connections are closed
+const (
+	disconnectReasonAdmin  = "disconnected by an administrator"
+	disconnectReasonBanned = "account banned"
+)
+
+// Connections reports, inspects and force-closes live WebSocket connections
+type Connections interface {
+	GetConnectedUserCount() int
+	GetConnectionCount() int
+	Connections() []models.ConnectionInfo
+	UserConnections(userID uuid.UUID) ([]models.ConnectionInfo, int)
+	DisconnectUser(userID uuid.UUID, reason string) int
+}
+
+// Jobs reports the status of the background jobs run by this server
+type Jobs interface {
+	Statuses() []models.JobStatus
+}
+
+// Service handles admin business logic
+type Service interface {
+	GetStats(ctx context.Context, days int) (*models.StatsResponse, error)
+	ListConnections(ctx context.Context, limit int) *models.ConnectionListResponse
+	GetUserConnections(ctx context.Context, userID uuid.UUID) *models.UserConnectionsResponse
+	DisconnectUser(ctx context.Context, userID uuid.UUID) *models.DisconnectResponse
+	BanUser(ctx context.Context, adminID, userID uuid.UUID, req *models.BanRequest) (*models.BanResponse, error)
+	UnbanUser(ctx context.Context, userID uuid.UUID) error
+	ListJobs(ctx context.Context) *models.JobListResponse
+}
+
+// AdminService implements Service interface
+type AdminService struct {
+	repo        Repository
+	connections Connections
+	jobs        Jobs
+	logger      logger.Logger
+}
+
+// NewAdminService creates a new admin service
+func NewAdminService(repo Repository, connections Connections, jobs Jobs, logger logger.Logger) *AdminService {
+	return &AdminService{
+		repo:        repo,
+		connections: connections,
+		jobs:        jobs,
+		logger:      logger,
+	}
+}
+
+// GetStats returns usage statistics for the last number of days
+func (s *AdminService) GetStats(ctx context.Context, days int) (*models.StatsResponse, error) {
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(days - 1))
+
+	dau, err := s.repo.GetDailyActiveUsers(ctx, since)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get daily active users", "error", err)
+		return nil, err
+	}
+
+	messages, err := s.repo.GetMessagesPerDay(ctx, since)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get messages per day", "error", err)
+		return nil, err
+	}
+
+	top, err := s.repo.GetTopConversations(ctx, since, topConversationsLimit)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get top conversations", "error", err)
+		return nil, err
+	}
+	if top == nil {
+		top = []models.ConversationStat{}
+	}
+
+	return &models.StatsResponse{
+		GeneratedAt:      now,
+		Days:             days,
+		DailyActiveUsers: nonNilCounts(dau),
+		MessagesPerDay:   nonNilCounts(messages),
+		Connections: models.ConnectionStats{
+			ConnectedUsers: s.connections.GetConnectedUserCount(),
+			Connections:    s.connections.GetConnectionCount(),
+		},
+		TopConversations: top,
+	}, nil
+}
+
+// nonNilCounts returns an empty slice instead of nil so it encodes as []
+func nonNilCounts(counts []models.DailyCount) []models.DailyCount {
+	if counts == nil {
+		return []models.DailyCount{}
+	}
+	return counts
+}
+
+// ListConnections returns up to limit of the open connections on this
+// server, oldest first
+func (s *AdminService) ListConnections(ctx context.Context, limit int) *models.ConnectionListResponse {
+	connections := s.connections.Connections()
+	total := len(connections)
+	if len(connections) > limit {
+		connections = connections[:limit]
+	}
+
+	return &models.ConnectionListResponse{
+		Connections: connections,
+		Total:       total,
+	}
+}
+
+// ListJobs returns the status of the background jobs this server has run
+func (s *AdminService) ListJobs(ctx context.Context) *models.JobListResponse {
+	return &models.JobListResponse{Jobs: s.jobs.Statuses()}
+}
+
+// GetUserConnections returns a user's open connections on this server and
+// the messages waiting to be retried for them
+func (s *AdminService) GetUserConnections(ctx context.Context, userID uuid.UUID) *models.UserConnectionsResponse {
+	connections, pending := s.connections.UserConnections(userID)
+
+	return &models.UserConnectionsResponse{
+		UserID:         userID.String(),
+		Connected:      len(connections) > 0,
+		Connections:    connections,
+		PendingRetries: pending,
+	}
+}
+
+// DisconnectUser closes a user's live connections. They may reconnect
+// straight away; ban them to keep them out.
+func (s *AdminService) DisconnectUser(ctx context.Context, userID uuid.UUID) *models.DisconnectResponse {
+	closed := s.connections.DisconnectUser(userID, disconnectReasonAdmin)
+	s.logger.WithContext(ctx).Info("Disconnected user", "user_id", userID, "connections_closed", closed)
+
+	return &models.DisconnectResponse{
+		UserID:            userID.String(),
+		ConnectionsClosed: closed,
+	}
+}
+
+// BanUser bans a user until a time, or indefinitely, and closes their live
+// connections. Their tokens are rejected while the ban lasts, so they cannot
+// reconnect.
+func (s *AdminService) BanUser(ctx context.Context, adminID, userID uuid.UUID, req *models.BanRequest) (*models.BanResponse, error) {
+	if adminID == userID {
+		return nil, ErrBanSelf
+	}
+
+	now := time.Now()
+	if req.Until != nil && !req.Until.After(now) {
+		return nil, ErrBanEnded
+	}
+
+	ban := models.Ban{
+		BannedAt:    &now,
+		BannedUntil: req.Until,
+		BanReason:   req.Reason,
+	}
+	if err := s.repo.BanUser(ctx, userID, &ban); err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to ban user", "error", err)
+		}
+		return nil, err
+	}
+
+	closed := s.connections.DisconnectUser(userID, disconnectReasonBanned)
+	s.logger.WithContext(ctx).Info("Banned user", "user_id", userID, "admin_id", adminID, "until", req.Until, "connections_closed", closed)
+
+	return &models.BanResponse{
+		UserID:            userID.String(),
+		Ban:               ban,
+		ConnectionsClosed: closed,
+	}, nil
+}
+
+// UnbanUser lifts a user's ban
+func (s *AdminService) UnbanUser(ctx context.Context, userID uuid.UUID) error {
+	if err := s.repo.UnbanUser(ctx, userID); err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to unban user", "error", err)
+		}
+		return err
+	}
+
+	s.logger.WithContext(ctx).Info("Unbanned user", "user_id", userID)
+	return nil
+}
@@ -0,0 +1,102 @@
+package announcement
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+)
+
+// Handler handles announcement HTTP requests
+type Handler struct {
+	service   Service
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new announcement handler
+func NewHandler(service Service, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		service:   service,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// Announce handles requests to broadcast an announcement
+func (h *Handler) Announce(w http.ResponseWriter, r *http.Request) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return
+	}
+
+	adminID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Parse and validate request
+	var req models.AnnouncementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode announcement request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	resp, err := h.service.Announce(r.Context(), adminID, &req)
+	if err != nil {
+		if errors.Is(err, ErrExpired) {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "announcement.expired"))
+			return
+		}
+		sendError(w, r, errcode.Internal, i18n.T(r, "announcement.failed"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusCreated, resp)
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
@@ -0,0 +1,76 @@
+package announcement
+
+import (
+	"context"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+)
+
+// Repository interface for announcement operations
+type Repository interface {
+	CreateAnnouncement(ctx context.Context, announcement *models.Announcement) error
+	GetUnseenAnnouncements(ctx context.Context, userID uuid.UUID, now time.Time) ([]models.Announcement, error)
+	MarkAnnouncementsSeen(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// CreateAnnouncement saves an announcement, filling in its ID and creation time
+func (r *PostgresRepository) CreateAnnouncement(ctx context.Context, announcement *models.Announcement) error {
+	query := `
+        INSERT INTO announcements (message, created_by, expires_at)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at
+    `
+
+	return r.conn(ctx).QueryRowContext(ctx, query, announcement.Message, announcement.CreatedBy, announcement.ExpiresAt).
+		Scan(&announcement.ID, &announcement.CreatedAt)
+}
+
+// GetUnseenAnnouncements retrieves the unexpired announcements made since
+// the user last had announcements delivered, oldest first
+func (r *PostgresRepository) GetUnseenAnnouncements(ctx context.Context, userID uuid.UUID, now time.Time) ([]models.Announcement, error) {
+	query := `
+        SELECT a.id, a.message, a.created_by, a.created_at, a.expires_at
+        FROM announcements a
+        JOIN users u ON u.id = $1
+        WHERE a.expires_at > $2
+            AND (u.announcements_seen_at IS NULL OR a.created_at > u.announcements_seen_at)
+        ORDER BY a.created_at ASC
+    `
+
+	var announcements []models.Announcement
+	if err := r.conn(ctx).SelectContext(ctx, &announcements, query, userID, now); err != nil {
+		return nil, err
+	}
+	return announcements, nil
+}
+
+// MarkAnnouncementsSeen records that announcements made up to a time have
+// been delivered to the user
+func (r *PostgresRepository) MarkAnnouncementsSeen(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	query := `
+        UPDATE users
+        SET announcements_seen_at = $1
+        WHERE id = $2
+    `
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, at, userID)
+	return err
+}
@@ -0,0 +1,114 @@
+// Package announcement broadcasts system-wide notices from admins, such as
+// maintenance windows, to every connected client and delivers them to
+// offline users when they next connect.
+package announcement
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// DefaultLifetime is how long an announcement without an expiry is
+// delivered to users who reconnect
+const DefaultLifetime = 24 * time.Hour
+
+// ErrExpired is returned for an announcement that expires in the past
+var ErrExpired = errors.New("announcement must expire in the future")
+
+// Broadcaster sends messages to connected clients
+type Broadcaster interface {
+	Broadcast(message *models.WebSocketMessage) int
+	SendToUser(userID uuid.UUID, message *models.WebSocketMessage) bool
+}
+
+// Service handles announcement business logic
+type Service interface {
+	Announce(ctx context.Context, adminID uuid.UUID, req *models.AnnouncementRequest) (*models.AnnouncementResponse, error)
+	DeliverPending(ctx context.Context, userID uuid.UUID)
+	MarkSeen(ctx context.Context, userID uuid.UUID)
+}
+
+// AnnouncementService implements Service interface
+type AnnouncementService struct {
+	repo        Repository
+	broadcaster Broadcaster
+	logger      logger.Logger
+}
+
+// NewAnnouncementService creates a new announcement service
+func NewAnnouncementService(repo Repository, broadcaster Broadcaster, logger logger.Logger) *AnnouncementService {
+	return &AnnouncementService{
+		repo:        repo,
+		broadcaster: broadcaster,
+		logger:      logger,
+	}
+}
+
+// Announce saves an announcement and broadcasts it to every connected client
+func (s *AnnouncementService) Announce(ctx context.Context, adminID uuid.UUID, req *models.AnnouncementRequest) (*models.AnnouncementResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(DefaultLifetime)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return nil, ErrExpired
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	announcement := models.Announcement{
+		Message:   req.Message,
+		CreatedBy: &adminID,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.CreateAnnouncement(ctx, &announcement); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to create announcement", "error", err)
+		return nil, err
+	}
+
+	recipients := s.broadcaster.Broadcast(&models.WebSocketMessage{
+		Type: protocol.TypeSystemAnnouncement,
+		Data: announcement,
+	})
+	s.logger.WithContext(ctx).Info("Announcement broadcast", "announcement_id", announcement.ID, "admin_id", adminID, "recipients", recipients)
+
+	return &models.AnnouncementResponse{
+		Announcement: announcement,
+		Recipients:   recipients,
+	}, nil
+}
+
+// DeliverPending sends a user who has just connected the announcements made
+// while they were offline
+func (s *AnnouncementService) DeliverPending(ctx context.Context, userID uuid.UUID) {
+	now := time.Now()
+	announcements, err := s.repo.GetUnseenAnnouncements(ctx, userID, now)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get unseen announcements", "error", err, "user_id", userID)
+		return
+	}
+
+	for _, announcement := range announcements {
+		s.broadcaster.SendToUser(userID, &models.WebSocketMessage{
+			Type: protocol.TypeSystemAnnouncement,
+			Data: announcement,
+		})
+	}
+
+	if err := s.repo.MarkAnnouncementsSeen(ctx, userID, now); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to mark announcements seen", "error", err, "user_id", userID)
+	}
+}
+
+// MarkSeen records that a user who is going offline received every
+// announcement made while they were connected
+func (s *AnnouncementService) MarkSeen(ctx context.Context, userID uuid.UUID) {
+	if err := s.repo.MarkAnnouncementsSeen(ctx, userID, time.Now()); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to mark announcements seen", "error", err, "user_id", userID)
+	}
+}
@@ -0,0 +1,153 @@
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Handler handles API key admin HTTP requests
+type Handler struct {
+	service   *Service
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new API key handler
+func NewHandler(service *Service, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		service:   service,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// CreateKey handles requests to issue an API key
+func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode API key request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	resp, err := h.service.CreateKey(r.Context(), adminID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrBotUserRequired):
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "apikey.user_required"))
+		case errors.Is(err, ErrUserNotFound):
+			sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+		default:
+			sendError(w, r, errcode.Internal, i18n.T(r, "apikey.create_failed"))
+		}
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusCreated, resp)
+}
+
+// ListKeys handles requests to list API keys
+func (h *Handler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	// Call service
+	resp, err := h.service.GetKeys(r.Context())
+	if err != nil {
+		sendError(w, r, errcode.Internal, i18n.T(r, "apikey.list_failed"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// RevokeKey handles requests to revoke an API key
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(mux.Vars(r)["key_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "apikey.invalid_id"))
+		return
+	}
+
+	// Call service
+	if err := h.service.RevokeKey(r.Context(), adminID, keyID); err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			sendError(w, r, errcode.NotFound, i18n.T(r, "apikey.not_found"))
+			return
+		}
+		sendError(w, r, errcode.Internal, i18n.T(r, "apikey.revoke_failed"))
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing
+func currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
@@ -0,0 +1,82 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+)
+
+// HeaderName is the request header that carries an API key
+const HeaderName = "X-API-Key"
+
+// contextKey is a custom type for context keys to avoid collisions
+type contextKey string
+
+// keyContextKey is the key for the authenticated API key in context
+const keyContextKey contextKey = "api_key"
+
+// Middleware authenticates requests from integrations by API key. It is
+// independent of user access tokens.
+type Middleware struct {
+	service *Service
+	logger  logger.Logger
+}
+
+// NewMiddleware creates a new API key middleware
+func NewMiddleware(service *Service, logger logger.Logger) *Middleware {
+	return &Middleware{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Require restricts a handler to requests with an active API key that has
+// the scope. Keys that act as a bot account put it in the context the way
+// user authentication does, so user handlers can serve them.
+func (m *Middleware) Require(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get(HeaderName)
+		if secret == "" {
+			sendError(w, r, errcode.Unauthenticated, i18n.T(r, "apikey.required"))
+			return
+		}
+
+		key, err := m.service.Authenticate(r.Context(), secret)
+		if err != nil {
+			if errors.Is(err, ErrInvalidKey) {
+				m.logger.WithContext(r.Context()).Info("API key authentication failed")
+				sendError(w, r, errcode.Unauthenticated, i18n.T(r, "apikey.invalid"))
+				return
+			}
+			m.logger.WithContext(r.Context()).Error("Failed to verify API key", "error", err)
+			sendError(w, r, errcode.Internal, i18n.T(r, "apikey.verify_failed"))
+			return
+		}
+
+		if !key.HasScope(scope) {
+			m.logger.WithContext(r.Context()).Info("API key scope denied", "key_id", key.ID, "scope", scope)
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "apikey.scope_denied", scope))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), keyContextKey, key)
+		if key.UserID != nil {
+			ctx = context.WithValue(ctx, auth.UserIDKey, key.UserID.String())
+			ctx = context.WithValue(ctx, auth.UsernameKey, key.Username)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext returns the API key that authenticated the request, if any
+func FromContext(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(keyContextKey).(*models.APIKey)
+	return key, ok
+}
@@ -0,0 +1,199 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Repository errors
+var (
+	ErrKeyNotFound  = errors.New("API key not found")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// foreignKeyViolation is the PostgreSQL error code for a missing referenced row
+const foreignKeyViolation = "23503"
+
+// Repository interface for API key operations
+type Repository interface {
+	CreateKey(ctx context.Context, key *models.APIKey, keyHash string) error
+	GetKeys(ctx context.Context) ([]models.APIKey, error)
+	GetKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	RevokeKey(ctx context.Context, keyID uuid.UUID, revokedAt time.Time) error
+	TouchKey(ctx context.Context, keyID uuid.UUID, usedAt time.Time) error
+	GetUserStatus(ctx context.Context, userID uuid.UUID) (*UserStatus, error)
+}
+
+// UserStatus is the account state of a key's bot account that decides
+// whether the key is accepted
+type UserStatus struct {
+	ErasedAt *time.Time `db:"erased_at"`
+	models.Ban
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// keyColumns selects an API key joined with its bot account's username
+const keyColumns = `
+	k.id, k.name, k.prefix, k.scopes, k.user_id, COALESCE(u.username, ''),
+	k.created_by, k.created_at, k.last_used_at, k.revoked_at
+`
+
+// scanKey scans a row selected with keyColumns
+func scanKey(row interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Scopes),
+		&key.UserID,
+		&key.Username,
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// CreateKey saves a new API key with the hash of its secret
+func (r *PostgresRepository) CreateKey(ctx context.Context, key *models.APIKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (id, name, prefix, key_hash, scopes, user_id, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		key.ID,
+		key.Name,
+		key.Prefix,
+		keyHash,
+		pq.Array(key.Scopes),
+		key.UserID,
+		key.CreatedBy,
+		key.CreatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetKeys retrieves all API keys, newest first
+func (r *PostgresRepository) GetKeys(ctx context.Context) ([]models.APIKey, error) {
+	query := `
+		SELECT ` + keyColumns + `
+		FROM api_keys k
+		LEFT JOIN users u ON u.id = k.user_id
+		ORDER BY k.created_at DESC
+	`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetKeyByHash retrieves the API key whose secret has the hash
+func (r *PostgresRepository) GetKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `
+		SELECT ` + keyColumns + `
+		FROM api_keys k
+		LEFT JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1
+	`
+
+	key, err := scanKey(r.conn(ctx).QueryRowContext(ctx, query, keyHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// RevokeKey marks an API key as revoked. Revoking a revoked key returns
+// ErrKeyNotFound.
+func (r *PostgresRepository) RevokeKey(ctx context.Context, keyID uuid.UUID, revokedAt time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, keyID, revokedAt)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// GetUserStatus retrieves the account state of a key's bot account
+func (r *PostgresRepository) GetUserStatus(ctx context.Context, userID uuid.UUID) (*UserStatus, error) {
+	query := "SELECT erased_at, banned_at, banned_until, ban_reason FROM users WHERE id = $1"
+
+	var status UserStatus
+	if err := r.conn(ctx).GetContext(ctx, &status, query, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &status, nil
+}
+
+// TouchKey records when an API key was last used
+func (r *PostgresRepository) TouchKey(ctx context.Context, keyID uuid.UUID, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, keyID, usedAt)
+	return err
+}
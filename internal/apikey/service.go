@@ -0,0 +1,182 @@
+// Package apikey issues and verifies API keys for trusted backend
+// integrations. Keys are separate from user sessions: they carry scopes
+// instead of a role, and keys that send messages act as a bot account.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// keyPrefix marks API keys so they are recognizable in config files and
+// secret scanners
+const keyPrefix = "wlk_"
+
+// keySecretBytes is the number of random bytes in a key
+const keySecretBytes = 32
+
+// displayPrefixLength is the number of leading characters of a key kept to
+// identify it in listings
+const displayPrefixLength = len(keyPrefix) + 8
+
+// touchInterval limits how often a key's last use is written
+const touchInterval = time.Minute
+
+// Service errors
+var (
+	ErrInvalidKey      = errors.New("invalid or revoked API key")
+	ErrBotUserRequired = errors.New("keys with the messages:send scope must act as a user")
+)
+
+// Service manages API keys
+type Service struct {
+	repo   Repository
+	logger logger.Logger
+}
+
+// NewService creates a new API key service
+func NewService(repo Repository, logger logger.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateKey issues an API key. The returned response is the only place the
+// key appears; only its hash is stored.
+func (s *Service) CreateKey(ctx context.Context, adminID uuid.UUID, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	key := models.APIKey{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Scopes:    dedupe(req.Scopes),
+		CreatedBy: &adminID,
+		CreatedAt: time.Now(),
+	}
+	if req.UserID != "" {
+		userID, err := uuid.Parse(req.UserID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		key.UserID = &userID
+	}
+	if key.HasScope(models.ScopeMessagesSend) && key.UserID == nil {
+		return nil, ErrBotUserRequired
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	key.Prefix = secret[:displayPrefixLength]
+
+	if err := s.repo.CreateKey(ctx, &key, hashKey(secret)); err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to create API key", "error", err)
+		}
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("API key created", "key_id", key.ID, "scopes", key.Scopes, "admin_id", adminID)
+	return &models.CreateAPIKeyResponse{APIKey: key, Key: secret}, nil
+}
+
+// GetKeys lists all API keys, including revoked ones
+func (s *Service) GetKeys(ctx context.Context) (*models.APIKeyListResponse, error) {
+	keys, err := s.repo.GetKeys(ctx)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get API keys", "error", err)
+		return nil, err
+	}
+	return &models.APIKeyListResponse{Keys: keys}, nil
+}
+
+// RevokeKey revokes an API key. Requests with it are refused immediately.
+func (s *Service) RevokeKey(ctx context.Context, adminID, keyID uuid.UUID) error {
+	if err := s.repo.RevokeKey(ctx, keyID, time.Now()); err != nil {
+		if !errors.Is(err, ErrKeyNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to revoke API key", "error", err, "key_id", keyID)
+		}
+		return err
+	}
+
+	s.logger.WithContext(ctx).Info("API key revoked", "key_id", keyID, "admin_id", adminID)
+	return nil
+}
+
+// Authenticate returns the active key for a secret
+func (s *Service) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	if !strings.HasPrefix(secret, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := s.repo.GetKeyByHash(ctx, hashKey(secret))
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidKey
+	}
+	if key.UserID != nil {
+		status, err := s.repo.GetUserStatus(ctx, *key.UserID)
+		if err != nil {
+			if errors.Is(err, ErrUserNotFound) {
+				return nil, ErrInvalidKey
+			}
+			return nil, err
+		}
+		// A banned or erased bot account cannot act through its keys
+		if status.ErasedAt != nil || status.Ban.Active(time.Now()) {
+			return nil, ErrInvalidKey
+		}
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > touchInterval {
+		if err := s.repo.TouchKey(ctx, key.ID, now); err != nil {
+			s.logger.WithContext(ctx).Error("Failed to record API key use", "error", err, "key_id", key.ID)
+		}
+	}
+	return key, nil
+}
+
+// newSecret generates a random API key
+func newSecret() (string, error) {
+	b := make([]byte, keySecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashKey hashes a key for storage. Keys are random, so a fast hash is
+// enough to make a leaked table useless.
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// dedupe removes repeated scopes, keeping their order
+func dedupe(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	out := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			out = append(out, scope)
+		}
+	}
+	return out
+}
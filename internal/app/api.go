@@ -0,0 +1,208 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/sso"
+	"github.com/codingminions/Whatsapp-Lite/pkg/apiversion"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/openapi"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+)
+
+// legacyAPI serves the unversioned API paths that predate /api/v1. Clients
+// choose a version with the API-Version header, v1 by default, and are
+// told the paths are deprecated in favor of the versioned ones.
+func legacyAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := apiversion.V1
+		if value := r.Header.Get(apiversion.Header); value != "" {
+			var err error
+			if version, err = apiversion.Parse(value); err != nil {
+				resp := models.NewErrorResponse(errcode.InvalidRequest, i18n.T(r, "api.unsupported_version"))
+				resp.RequestID = requestid.FromContext(r.Context())
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(errcode.InvalidRequest.HTTPStatus())
+				json.NewEncoder(w).Encode(resp)
+				return
+			}
+		}
+
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+version.Prefix()+r.URL.Path+">; rel=\"successor-version\"")
+		w.Header().Set(apiversion.Header, strconv.Itoa(int(version)))
+		next.ServeHTTP(w, r.WithContext(apiversion.NewContext(r.Context(), version)))
+	})
+}
+
+// routeAuth is the authentication a route requires
+type routeAuth int
+
+const (
+	authNone   routeAuth = iota
+	authUser             // access token
+	authAdmin            // access token of an admin
+	authAPIKey           // API key with the route's scope
+)
+
+// apiRoute is a REST API route and the metadata it is documented with
+type apiRoute struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	auth    routeAuth
+	scope   string
+	doc     openapi.Operation
+}
+
+// Query parameters shared by several routes
+var (
+	limitParam  = openapi.Param{Name: "limit", Type: "integer", Description: "Maximum number of results"}
+	beforeParam = openapi.Param{Name: "before", Description: "Cursor of the page to continue before"}
+)
+
+// apiRouteTable lists the REST API routes
+func (a *App) apiRouteTable() []apiRoute {
+	return []apiRoute{
+		// Auth
+		{"POST", "/auth/register", a.authHandler.Register, authNone, "", openapi.Operation{Summary: "Register an account", Tag: "auth", Request: models.RegisterRequest{}, Status: http.StatusCreated, Response: models.UserResponse{}}},
+		{"POST", "/auth/login", a.authHandler.Login, authNone, "", openapi.Operation{Summary: "Log in with an email and password", Tag: "auth", Request: models.LoginRequest{}, Response: models.LoginResponse{}}},
+		{"POST", "/auth/refresh", a.authHandler.Refresh, authNone, "", openapi.Operation{Summary: "Exchange a refresh token for new tokens", Tag: "auth", Request: models.RefreshRequest{}, Response: models.RefreshResponse{}}},
+		{"POST", "/auth/logout", a.authHandler.Logout, authUser, "", openapi.Operation{Summary: "Log out of the current session", Tag: "auth", Status: http.StatusNoContent}},
+		{"POST", "/auth/logout-all", a.authHandler.LogoutAll, authUser, "", openapi.Operation{Summary: "Log out of every session", Tag: "auth", Status: http.StatusNoContent}},
+		{"PUT", "/auth/password", a.authHandler.ChangePassword, authUser, "", openapi.Operation{Summary: "Change the password", Tag: "auth", Request: models.ChangePasswordRequest{}, Status: http.StatusNoContent}},
+		{"GET", "/auth/sso", a.ssoHandler.GetProviders, authNone, "", openapi.Operation{Summary: "List single sign-on providers", Tag: "auth", Response: sso.ProviderListResponse{}}},
+
+		// Phone number sign-up and login
+		{"POST", "/auth/phone/code", a.smsHandler.SendLoginCode, authNone, "", openapi.Operation{Summary: "Text a login code to a phone number", Tag: "auth", Request: models.PhoneNumberRequest{}, Status: http.StatusAccepted}},
+		{"POST", "/auth/phone/register", a.smsHandler.Register, authNone, "", openapi.Operation{Summary: "Register with a phone number", Tag: "auth", Request: models.PhoneRegisterRequest{}, Status: http.StatusCreated, Response: models.LoginResponse{}}},
+		{"POST", "/auth/phone/login", a.smsHandler.Login, authNone, "", openapi.Operation{Summary: "Log in with a texted code", Tag: "auth", Request: models.PhoneLoginRequest{}, Response: models.LoginResponse{}}},
+
+		// Users
+		{"GET", "/users", a.userHandler.GetUsers, authUser, "", openapi.Operation{Summary: "List users", Tag: "users", Query: []openapi.Param{{Name: "cursor", Description: "Cursor of the next page"}, limitParam, {Name: "search", Description: "Filter by username"}}, Response: models.UserListResponse{}}},
+		{"GET", "/users/online", a.userHandler.GetOnlineUsers, authUser, "", openapi.Operation{Summary: "List online contacts", Tag: "users", Response: models.OnlineUsersResponse{}}},
+		{"GET", "/users/search", a.userHandler.SearchUsers, authUser, "", openapi.Operation{Summary: "Search users", Tag: "users", Query: []openapi.Param{{Name: "q", Description: "Search query"}, limitParam}, Response: models.UserSearchResponse{}}},
+		{"DELETE", "/users/me", a.accountHandler.RequestDeletion, authUser, "", openapi.Operation{Summary: "Schedule the account for deletion", Tag: "account", Status: http.StatusAccepted, Response: models.AccountDeletionResponse{}}},
+		{"DELETE", "/users/me/deletion", a.accountHandler.CancelDeletion, authUser, "", openapi.Operation{Summary: "Cancel a scheduled account deletion", Tag: "account", Status: http.StatusNoContent}},
+		{"GET", "/users/me/logins", a.authHandler.GetLogins, authUser, "", openapi.Operation{Summary: "List recent logins", Tag: "account", Query: []openapi.Param{limitParam}, Response: models.LoginHistoryResponse{}}},
+		{"POST", "/users/me/export", a.accountHandler.Export, authUser, "", openapi.Operation{Summary: "Download an archive of the account's data", Tag: "account", ContentType: "application/zip"}},
+		{"GET", "/users/me/privacy", a.userHandler.GetPrivacySettings, authUser, "", openapi.Operation{Summary: "Get privacy settings", Tag: "account", Response: models.PrivacySettings{}}},
+		{"PUT", "/users/me/privacy", a.userHandler.UpdatePrivacySettings, authUser, "", openapi.Operation{Summary: "Update privacy settings", Tag: "account", Request: models.PrivacySettings{}, Response: models.PrivacySettings{}}},
+		{"PUT", "/users/me/status", a.userHandler.SetCustomStatus, authUser, "", openapi.Operation{Summary: "Set a custom status", Tag: "account", Request: models.CustomStatusRequest{}, Response: models.CustomStatus{}}},
+		{"DELETE", "/users/me/status", a.userHandler.ClearCustomStatus, authUser, "", openapi.Operation{Summary: "Clear the custom status", Tag: "account", Status: http.StatusNoContent}},
+		{"GET", "/users/me/phone", a.smsHandler.GetPhone, authUser, "", openapi.Operation{Summary: "Get the verified phone number", Tag: "account", Response: models.PhoneNumber{}}},
+		{"PUT", "/users/me/phone", a.smsHandler.SetPhone, authUser, "", openapi.Operation{Summary: "Text a verification code to a new phone number", Tag: "account", Request: models.PhoneNumberRequest{}, Status: http.StatusAccepted}},
+		{"DELETE", "/users/me/phone", a.smsHandler.RemovePhone, authUser, "", openapi.Operation{Summary: "Remove the phone number", Tag: "account", Status: http.StatusNoContent}},
+		{"POST", "/users/me/phone/verify", a.smsHandler.VerifyPhone, authUser, "", openapi.Operation{Summary: "Verify a new phone number", Tag: "account", Request: models.VerifyPhoneRequest{}, Response: models.PhoneNumber{}}},
+
+		// Notification preferences
+		{"GET", "/notifications/preferences", a.notificationHandler.GetPreferences, authUser, "", openapi.Operation{Summary: "Get notification preferences", Tag: "notifications", Response: models.NotificationPreferences{}}},
+		{"PUT", "/notifications/preferences", a.notificationHandler.UpdatePreferences, authUser, "", openapi.Operation{Summary: "Update notification preferences", Tag: "notifications", Request: models.UpdateNotificationPreferencesRequest{}, Response: models.NotificationPreferences{}}},
+
+		// Contacts
+		{"GET", "/contacts", a.contactHandler.GetContacts, authUser, "", openapi.Operation{Summary: "List contacts", Tag: "contacts", Response: models.ContactListResponse{}}},
+		{"GET", "/contacts/requests", a.contactHandler.GetRequests, authUser, "", openapi.Operation{Summary: "List pending contact requests", Tag: "contacts", Query: []openapi.Param{{Name: "direction", Description: "incoming (default) or outgoing"}}, Response: models.ContactRequestListResponse{}}},
+		{"POST", "/contacts/requests", a.contactHandler.SendRequest, authUser, "", openapi.Operation{Summary: "Send a contact request", Tag: "contacts", Request: models.CreateContactRequest{}, Status: http.StatusCreated, Response: models.ContactRequest{}}},
+		{"POST", "/contacts/requests/{request_id}/accept", a.contactHandler.AcceptRequest, authUser, "", openapi.Operation{Summary: "Accept a contact request", Tag: "contacts", Response: models.ContactRequest{}}},
+		{"POST", "/contacts/requests/{request_id}/decline", a.contactHandler.DeclineRequest, authUser, "", openapi.Operation{Summary: "Decline a contact request", Tag: "contacts", Response: models.ContactRequest{}}},
+		{"DELETE", "/contacts/{user_id}", a.contactHandler.RemoveContact, authUser, "", openapi.Operation{Summary: "Remove a contact", Tag: "contacts", Status: http.StatusNoContent}},
+
+		// Workspaces
+		{"GET", "/workspaces", a.workspaceHandler.GetWorkspaces, authUser, "", openapi.Operation{Summary: "List workspaces", Tag: "workspaces", Response: models.WorkspaceListResponse{}}},
+		{"POST", "/workspaces", a.workspaceHandler.CreateWorkspace, authUser, "", openapi.Operation{Summary: "Create a workspace", Tag: "workspaces", Request: models.CreateWorkspaceRequest{}, Status: http.StatusCreated, Response: models.Workspace{}}},
+		{"GET", "/workspaces/{workspace_id}", a.workspaceHandler.GetWorkspace, authUser, "", openapi.Operation{Summary: "Get a workspace", Tag: "workspaces", Response: models.Workspace{}}},
+		{"GET", "/workspaces/{workspace_id}/members", a.workspaceHandler.GetMembers, authUser, "", openapi.Operation{Summary: "List workspace members", Tag: "workspaces", Response: models.WorkspaceMemberListResponse{}}},
+		{"PUT", "/workspaces/{workspace_id}/members/{user_id}", a.workspaceHandler.SetMember, authUser, "", openapi.Operation{Summary: "Add a workspace member or change their role", Tag: "workspaces", Request: models.WorkspaceMemberRequest{}, Response: models.WorkspaceMemberListResponse{}}},
+		{"DELETE", "/workspaces/{workspace_id}/members/{user_id}", a.workspaceHandler.RemoveMember, authUser, "", openapi.Operation{Summary: "Remove a workspace member", Tag: "workspaces", Status: http.StatusNoContent}},
+
+		// Groups
+		{"POST", "/groups", a.groupHandler.CreateGroup, authUser, "", openapi.Operation{Summary: "Create a group", Tag: "groups", Request: models.CreateGroupRequest{}, Status: http.StatusCreated, Response: models.Group{}}},
+		{"GET", "/groups/{group_id}", a.groupHandler.GetGroup, authUser, "", openapi.Operation{Summary: "Get a group", Tag: "groups", Response: models.Group{}}},
+		{"POST", "/groups/{group_id}/members", a.groupHandler.AddMember, authUser, "", openapi.Operation{Summary: "Add a group member", Tag: "groups", Request: models.AddGroupMemberRequest{}, Response: models.Group{}}},
+		{"DELETE", "/groups/{group_id}/members/{user_id}", a.groupHandler.RemoveMember, authUser, "", openapi.Operation{Summary: "Remove a group member", Tag: "groups", Status: http.StatusNoContent}},
+		{"POST", "/groups/{group_id}/leave", a.groupHandler.LeaveGroup, authUser, "", openapi.Operation{Summary: "Leave a group", Tag: "groups", Status: http.StatusNoContent}},
+		{"GET", "/groups/{group_id}/messages", a.groupHandler.GetMessages, authUser, "", openapi.Operation{Summary: "List group messages", Tag: "groups", Query: []openapi.Param{beforeParam, limitParam}, Response: models.GroupMessageListResponse{}}},
+		{"POST", "/groups/{group_id}/messages", a.groupHandler.SendMessage, authUser, "", openapi.Operation{Summary: "Send a group message", Tag: "groups", Request: models.SendGroupMessageRequest{}, Status: http.StatusCreated, Response: models.GroupMessage{}}},
+		{"GET", "/groups/{group_id}/messages/{message_id}/info", a.groupHandler.GetMessageInfo, authUser, "", openapi.Operation{Summary: "Get the delivery and read status of a group message", Tag: "groups", Response: models.GroupMessageInfoResponse{}}},
+		{"GET", "/groups/{group_id}/invites", a.groupHandler.GetInvites, authUser, "", openapi.Operation{Summary: "List group invite links", Tag: "groups", Response: models.GroupInviteListResponse{}}},
+		{"POST", "/groups/{group_id}/invites", a.groupHandler.CreateInvite, authUser, "", openapi.Operation{Summary: "Create a group invite link", Tag: "groups", Request: models.CreateGroupInviteRequest{}, Status: http.StatusCreated, Response: models.GroupInvite{}}},
+		{"DELETE", "/groups/{group_id}/invites/{invite_id}", a.groupHandler.RevokeInvite, authUser, "", openapi.Operation{Summary: "Revoke a group invite link", Tag: "groups", Response: models.GroupInvite{}}},
+		{"GET", "/groups/{group_id}/join-requests", a.groupHandler.GetJoinRequests, authUser, "", openapi.Operation{Summary: "List pending join requests", Tag: "groups", Response: models.GroupJoinRequestListResponse{}}},
+		{"POST", "/groups/{group_id}/join-requests/{request_id}/approve", a.groupHandler.ApproveJoinRequest, authUser, "", openapi.Operation{Summary: "Approve a join request", Tag: "groups", Response: models.GroupJoinRequest{}}},
+		{"POST", "/groups/{group_id}/join-requests/{request_id}/decline", a.groupHandler.DeclineJoinRequest, authUser, "", openapi.Operation{Summary: "Decline a join request", Tag: "groups", Response: models.GroupJoinRequest{}}},
+		{"POST", "/invites/{code}/join", a.groupHandler.JoinByInvite, authUser, "", openapi.Operation{Summary: "Ask to join a group with an invite link", Tag: "groups", Status: http.StatusAccepted, Response: models.GroupJoinRequest{}}},
+
+		// Conversations
+		{"GET", "/conversations", a.convHandler.GetConversations, authUser, "", openapi.Operation{Summary: "List conversations", Tag: "conversations", Response: models.ConversationListResponse{}}},
+		{"POST", "/conversations/messages:batchGet", a.convHandler.BatchGetMessages, authUser, "", openapi.Operation{Summary: "List the latest messages of several conversations", Tag: "conversations", Request: models.BatchGetMessagesRequest{}, Response: models.BatchGetMessagesResponse{}}},
+		{"GET", "/conversations/{conversation_id}/messages", a.convHandler.GetMessages, authUser, "", openapi.Operation{Summary: "List messages", Tag: "conversations", Query: []openapi.Param{beforeParam, limitParam, {Name: "around_date", Description: "Return the messages around a date instead"}}, Response: models.MessageListResponse{}}},
+		{"POST", "/conversations/{conversation_id}/messages", a.convHandler.SendMessage, authUser, "", openapi.Operation{Summary: "Send a message", Tag: "conversations", Request: models.SendMessageRequest{}, Status: http.StatusCreated, Response: models.SendMessageResponse{}}},
+		{"DELETE", "/conversations/{conversation_id}", a.convHandler.DeleteConversation, authUser, "", openapi.Operation{Summary: "Delete a conversation", Tag: "conversations", Status: http.StatusNoContent}},
+		{"DELETE", "/conversations/{conversation_id}/messages", a.convHandler.ClearHistory, authUser, "", openapi.Operation{Summary: "Clear a conversation's history", Tag: "conversations", Status: http.StatusNoContent}},
+		{"GET", "/conversations/{conversation_id}/messages/{message_id}/context", a.convHandler.GetMessageContext, authUser, "", openapi.Operation{Summary: "List the messages around a message", Tag: "conversations", Query: []openapi.Param{{Name: "before", Type: "integer", Description: "Number of earlier messages"}, {Name: "after", Type: "integer", Description: "Number of later messages"}}, Response: models.MessageContextResponse{}}},
+		{"GET", "/conversations/{conversation_id}/export", a.convHandler.ExportConversation, authUser, "", openapi.Operation{Summary: "Export a conversation", Tag: "conversations", Query: []openapi.Param{{Name: "format", Description: "json (default) or text"}, {Name: "media", Type: "boolean", Description: "Include attachments"}}, Response: models.ConversationExport{}}},
+		{"GET", "/conversations/{conversation_id}/draft", a.convHandler.GetDraft, authUser, "", openapi.Operation{Summary: "Get the draft", Tag: "conversations", Response: models.Draft{}}},
+		{"POST", "/conversations/{conversation_id}/read", a.convHandler.MarkRead, authUser, "", openapi.Operation{Summary: "Mark the conversation read up to a message", Tag: "conversations", Request: models.MarkReadRequest{}, Status: http.StatusNoContent}},
+		{"GET", "/conversations/{conversation_id}/settings", a.convHandler.GetSettings, authUser, "", openapi.Operation{Summary: "Get the conversation's settings", Tag: "conversations", Response: models.ConversationSettings{}}},
+		{"PUT", "/conversations/{conversation_id}/settings", a.convHandler.UpdateSettings, authUser, "", openapi.Operation{Summary: "Update the conversation's settings", Tag: "conversations", Request: models.ConversationSettingsRequest{}, Response: models.ConversationSettings{}}},
+		{"PUT", "/conversations/{conversation_id}/draft", a.convHandler.SaveDraft, authUser, "", openapi.Operation{Summary: "Save the draft", Tag: "conversations", Request: models.DraftRequest{}, Response: models.Draft{}}},
+		{"POST", "/conversations/{conversation_id}/attachments", a.attachmentHandler.Upload, authUser, "", openapi.Operation{Summary: "Upload an attachment", Tag: "conversations", FileField: "file", Status: http.StatusCreated, Response: models.AttachmentResponse{}}},
+		{"GET", "/conversations/{conversation_id}/attachments/{attachment_id}", a.attachmentHandler.GetDownloadURL, authUser, "", openapi.Operation{Summary: "Get an attachment's download URL", Tag: "conversations", Response: models.AttachmentResponse{}}},
+		{"PUT", "/conversations/{conversation_id}/notifications", a.notificationHandler.SetConversationOverride, authUser, "", openapi.Operation{Summary: "Override notifications for a conversation", Tag: "notifications", Request: models.ConversationNotificationRequest{}, Response: models.NotificationPreferences{}}},
+		{"DELETE", "/conversations/{conversation_id}/notifications", a.notificationHandler.DeleteConversationOverride, authUser, "", openapi.Operation{Summary: "Remove a conversation's notification override", Tag: "notifications", Status: http.StatusNoContent}},
+		{"GET", "/mentions", a.convHandler.GetMentions, authUser, "", openapi.Operation{Summary: "List mentions of the user", Tag: "conversations", Query: []openapi.Param{beforeParam, limitParam}, Response: models.MentionListResponse{}}},
+
+		// Inbox
+		{"GET", "/inbox", a.inboxHandler.GetEvents, authUser, "", openapi.Operation{Summary: "List events missed while offline", Tag: "inbox", Query: []openapi.Param{{Name: "after", Type: "integer", Description: "Sequence number to list events after"}, {Name: "device_id", Description: "Device syncing the events, registered so its events are kept until it acknowledges them"}, limitParam}, Response: models.InboxResponse{}}},
+		{"POST", "/inbox/ack", a.inboxHandler.Ack, authUser, "", openapi.Operation{Summary: "Acknowledge events up to a sequence number", Tag: "inbox", Request: models.AckInboxRequest{}, Status: http.StatusNoContent}},
+
+		// Calls
+		{"GET", "/calls/ice-servers", a.callHandler.GetICEServers, authUser, "", openapi.Operation{Summary: "Get ICE servers for calls", Tag: "calls", Response: models.ICEServersResponse{}}},
+
+		// Admin
+		{"GET", "/admin/stats", a.adminHandler.GetStats, authAdmin, "", openapi.Operation{Summary: "Get usage statistics", Tag: "admin", Query: []openapi.Param{{Name: "days", Type: "integer", Description: "Length of the window in days"}}, Response: models.StatsResponse{}}},
+		{"GET", "/admin/connections", a.adminHandler.ListConnections, authAdmin, "", openapi.Operation{Summary: "List open WebSocket connections", Tag: "admin", Query: []openapi.Param{limitParam}, Response: models.ConnectionListResponse{}}},
+		{"GET", "/admin/users/{user_id}/connections", a.adminHandler.GetUserConnections, authAdmin, "", openapi.Operation{Summary: "Get a user's WebSocket connection state", Tag: "admin", Response: models.UserConnectionsResponse{}}},
+		{"POST", "/admin/users/{user_id}/disconnect", a.adminHandler.DisconnectUser, authAdmin, "", openapi.Operation{Summary: "Disconnect a user's WebSocket connections", Tag: "admin", Response: models.DisconnectResponse{}}},
+		{"PUT", "/admin/users/{user_id}/ban", a.adminHandler.BanUser, authAdmin, "", openapi.Operation{Summary: "Ban a user", Tag: "admin", Request: models.BanRequest{}, Response: models.BanResponse{}}},
+		{"DELETE", "/admin/users/{user_id}/ban", a.adminHandler.UnbanUser, authAdmin, "", openapi.Operation{Summary: "Lift a user's ban", Tag: "admin", Status: http.StatusNoContent}},
+		{"POST", "/admin/announcements", a.announcementHandler.Announce, authAdmin, "", openapi.Operation{Summary: "Send a system announcement", Tag: "admin", Request: models.AnnouncementRequest{}, Status: http.StatusCreated, Response: models.AnnouncementResponse{}}},
+		{"GET", "/admin/jobs", a.adminHandler.ListJobs, authAdmin, "", openapi.Operation{Summary: "List the status of this server's background jobs", Tag: "admin", Response: models.JobListResponse{}}},
+		{"GET", "/admin/maintenance", a.maintenanceHandler.GetState, authAdmin, "", openapi.Operation{Summary: "Get the maintenance mode", Tag: "admin", Response: models.MaintenanceState{}}},
+		{"PUT", "/admin/maintenance", a.maintenanceHandler.UpdateState, authAdmin, "", openapi.Operation{Summary: "Turn maintenance mode on or off", Tag: "admin", Request: models.MaintenanceRequest{}, Response: models.MaintenanceState{}}},
+		{"GET", "/admin/log-levels", a.loggingHandler.GetLevels, authAdmin, "", openapi.Operation{Summary: "List log levels", Tag: "admin", Response: models.LogLevelListResponse{}}},
+		{"PUT", "/admin/log-levels/{module}", a.loggingHandler.SetLevel, authAdmin, "", openapi.Operation{Summary: "Change the log level of a module", Tag: "admin", Request: models.LogLevelRequest{}, Response: models.LogLevelListResponse{}}},
+		{"GET", "/admin/features", a.featureHandler.ListFlags, authAdmin, "", openapi.Operation{Summary: "List feature flags", Tag: "admin", Response: models.FeatureFlagListResponse{}}},
+		{"PUT", "/admin/features/{name}", a.featureHandler.UpdateFlag, authAdmin, "", openapi.Operation{Summary: "Update a feature flag", Tag: "admin", Request: models.FeatureFlagRequest{}, Response: models.FeatureFlag{}}},
+		{"POST", "/admin/api-keys", a.apiKeyHandler.CreateKey, authAdmin, "", openapi.Operation{Summary: "Create an API key", Tag: "admin", Request: models.CreateAPIKeyRequest{}, Status: http.StatusCreated, Response: models.CreateAPIKeyResponse{}}},
+		{"GET", "/admin/api-keys", a.apiKeyHandler.ListKeys, authAdmin, "", openapi.Operation{Summary: "List API keys", Tag: "admin", Response: models.APIKeyListResponse{}}},
+		{"DELETE", "/admin/api-keys/{key_id}", a.apiKeyHandler.RevokeKey, authAdmin, "", openapi.Operation{Summary: "Revoke an API key", Tag: "admin", Status: http.StatusNoContent}},
+
+		// Integrations
+		{"GET", "/integrations/stats", a.adminHandler.GetStats, authAPIKey, models.ScopeStatsRead, openapi.Operation{Summary: "Get usage statistics", Tag: "integrations", Query: []openapi.Param{{Name: "days", Type: "integer", Description: "Length of the window in days"}}, Response: models.StatsResponse{}}},
+		{"POST", "/integrations/conversations/{conversation_id}/messages", a.convHandler.SendMessage, authAPIKey, models.ScopeMessagesSend, openapi.Operation{Summary: "Send a message as a bot", Tag: "integrations", Request: models.SendMessageRequest{}, Status: http.StatusCreated, Response: models.SendMessageResponse{}}},
+		{"POST", "/integrations/groups/{group_id}/messages", a.groupHandler.SendMessage, authAPIKey, models.ScopeMessagesSend, openapi.Operation{Summary: "Send a group message as a bot", Tag: "integrations", Request: models.SendGroupMessageRequest{}, Status: http.StatusCreated, Response: models.GroupMessage{}}},
+	}
+}
+
+// openAPIDocument describes the routes of the latest API version
+func (a *App) openAPIDocument() *openapi.Document {
+	routes := a.apiRouteTable()
+	docs := make([]openapi.Route, len(routes))
+	for i, route := range routes {
+		docs[i] = openapi.Route{Method: route.method, Path: route.path, Operation: route.doc}
+		switch route.auth {
+		case authUser, authAdmin:
+			docs[i].Operation.Security = openapi.BearerAuth
+		case authAPIKey:
+			docs[i].Operation.Security = openapi.APIKeyAuth
+		}
+	}
+
+	info := openapi.Info{Title: "Whatsapp-Lite API", Version: apiversion.Latest.String()}
+	return openapi.Build(info, apiversion.Latest.Prefix(), docs, models.ErrorResponse{})
+}
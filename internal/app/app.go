@@ -0,0 +1,513 @@
+// Package app wires the application's components together so every
+// entrypoint, and integration tests, build the same stack. Build creates an
+// App from a configuration; Start and Stop run and shut down its HTTP
+// server, WebSocket hub and background jobs.
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/account"
+	"github.com/codingminions/Whatsapp-Lite/internal/admin"
+	"github.com/codingminions/Whatsapp-Lite/internal/announcement"
+	"github.com/codingminions/Whatsapp-Lite/internal/apikey"
+	"github.com/codingminions/Whatsapp-Lite/internal/attachment"
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/bootstrap"
+	"github.com/codingminions/Whatsapp-Lite/internal/calls"
+	"github.com/codingminions/Whatsapp-Lite/internal/contact"
+	"github.com/codingminions/Whatsapp-Lite/internal/conversation"
+	"github.com/codingminions/Whatsapp-Lite/internal/email"
+	"github.com/codingminions/Whatsapp-Lite/internal/events"
+	"github.com/codingminions/Whatsapp-Lite/internal/features"
+	"github.com/codingminions/Whatsapp-Lite/internal/group"
+	"github.com/codingminions/Whatsapp-Lite/internal/inbox"
+	"github.com/codingminions/Whatsapp-Lite/internal/jobs"
+	"github.com/codingminions/Whatsapp-Lite/internal/logging"
+	"github.com/codingminions/Whatsapp-Lite/internal/maintenance"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/notification"
+	"github.com/codingminions/Whatsapp-Lite/internal/pages"
+	"github.com/codingminions/Whatsapp-Lite/internal/sms"
+	"github.com/codingminions/Whatsapp-Lite/internal/sso"
+	"github.com/codingminions/Whatsapp-Lite/internal/storage"
+	"github.com/codingminions/Whatsapp-Lite/internal/user"
+	"github.com/codingminions/Whatsapp-Lite/internal/websocket"
+	"github.com/codingminions/Whatsapp-Lite/internal/workspace"
+	"github.com/codingminions/Whatsapp-Lite/pkg/apiversion"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/geoip"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/password"
+	"github.com/codingminions/Whatsapp-Lite/pkg/sanitize"
+	"github.com/codingminions/Whatsapp-Lite/pkg/signedurl"
+	"github.com/codingminions/Whatsapp-Lite/pkg/token"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/codingminions/Whatsapp-Lite/web"
+)
+
+// App holds the application's wired components
+type App struct {
+	// DisableJobs leaves the background jobs to a separate worker
+	DisableJobs bool
+
+	env       *bootstrap.Env
+	ownsDB    bool
+	publisher events.Publisher
+
+	messageBuffer *conversation.MessageBuffer
+
+	listener  net.Listener
+	server    *http.Server
+	metrics   *http.Server // nil unless metrics are enabled
+	scheduler *jobs.Scheduler
+	errs      chan error
+
+	tokenMaker token.Maker
+	Hub        *websocket.Hub
+	assets     fs.FS
+	pages      *pages.Renderer
+	spa        http.Handler // nil unless a single-page app replaces the pages
+
+	AuthRepo          *auth.PostgresRepository
+	AuthService       *auth.AuthService
+	FeatureManager    *features.Manager
+	Maintenance       *maintenance.Mode
+	ConvRepo          *conversation.PostgresRepository
+	ConvService       *conversation.ConversationService
+	AttachmentService *attachment.AttachmentService
+	AccountService    *account.AccountService
+	SMSService        *sms.Service
+	DigestService     *email.Digests // nil unless email is configured
+	InboxService      *inbox.Service
+
+	authHandler         *auth.Handler
+	authMiddleware      *auth.AuthMiddleware
+	featureHandler      *features.Handler
+	userHandler         *user.Handler
+	notificationHandler *notification.Handler
+	smsHandler          *sms.Handler
+	emailHandler        *email.Handler
+	contactHandler      *contact.Handler
+	groupHandler        *group.Handler
+	workspaceHandler    *workspace.Handler
+	ssoHandler          *sso.Handler
+	convHandler         *conversation.Handler
+	wsHandler           *websocket.Handler
+	attachmentHandler   *attachment.Handler
+	adminHandler        *admin.Handler
+	apiKeyHandler       *apikey.Handler
+	apiKeyMiddleware    *apikey.Middleware
+	announcementHandler *announcement.Handler
+	maintenanceHandler  *maintenance.Handler
+	loggingHandler      *logging.Handler
+	accountHandler      *account.Handler
+	callHandler         *calls.Handler
+	inboxHandler        *inbox.Handler
+}
+
+// Build connects to the database and wires the application. The App owns
+// the connection and closes it in Stop.
+func Build(config *configs.Config, log logger.Logger) (*App, error) {
+	db, err := bootstrap.Connect(config, log)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := New(&bootstrap.Env{Config: config, Logger: log, DB: db})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	a.ownsDB = true
+
+	return a, nil
+}
+
+// New wires the application's components on an existing environment, whose
+// database connection stays owned by the caller
+func New(env *bootstrap.Env) (*App, error) {
+	config := env.Config
+	log := env.Logger
+
+	// Modules whose log level can be set apart from the rest
+	authLog := log.Module("auth")
+	convLog := log.Module("conversation")
+	dbLog := log.Module("database")
+	wsLog := log.Module("websocket")
+
+	// Bound and log database queries, retry transient errors and stop
+	// calling a database that is down
+	resilience := config.Database.Resilience
+	db := database.NewDB(env.DB, database.Config{
+		QueryTimeout:       config.Database.QueryTimeout,
+		SlowQueryThreshold: config.Database.SlowQueryThreshold,
+		Resilience: database.ResilienceConfig{
+			MaxAttempts:      resilience.MaxAttempts,
+			RetryBaseDelay:   resilience.RetryBaseDelay,
+			RetryMaxDelay:    resilience.RetryMaxDelay,
+			BreakerThreshold: resilience.BreakerThreshold,
+			BreakerCooldown:  resilience.BreakerCooldown,
+		},
+	}, dbLog)
+
+	// Initialize domain event publisher
+	publisher, err := events.NewPublisher(config.Events, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event publisher: %w", err)
+	}
+
+	// Initialize unit of work for multi-repository transactions
+	uow := database.NewUnitOfWork(db)
+
+	// Initialize validators
+	sanitizePolicy, err := sanitize.ParsePolicy(config.Messages.SanitizePolicy)
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("invalid message configuration: %w", err)
+	}
+	sanitizer := sanitize.New(sanitizePolicy)
+	messageValidator := validator.NewMessageValidator(config.Messages.MaxLength, sanitizer)
+	validate := validator.NewCustomValidator()
+	validate.SetMessageValidator(messageValidator)
+	validate.RegisterStructRules(models.StructRules...)
+
+	// Initialize JWT token maker
+	tokenMaker, err := token.NewJWTMaker(config.JWT.SecretKey, token.Options{
+		Issuer:       config.JWT.Issuer,
+		Audience:     config.JWT.Audience,
+		Leeway:       config.JWT.Leeway,
+		AcceptLegacy: config.JWT.AcceptLegacy,
+	})
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("failed to create token maker: %w", err)
+	}
+
+	a := &App{env: env, publisher: publisher, tokenMaker: tokenMaker, assets: web.Assets(config.Server.WebDir)}
+
+	// Initialize WebSocket hub, keeping durable messages in users' inboxes
+	// if enabled
+	var eventInbox websocket.EventInbox
+	if config.Inbox.Enabled {
+		a.InboxService = inbox.NewService(inbox.NewPostgresRepository(db), log)
+		eventInbox = a.InboxService
+	}
+	a.Hub = websocket.NewHub(wsLog, publisher, eventInbox)
+	a.inboxHandler = inbox.NewHandler(a.InboxService, log, validate)
+
+	a.AuthRepo = auth.NewPostgresRepository(db)
+
+	// Initialize notification components. Email is the only delivery
+	// channel so far; without it the dispatcher drops offline notifications.
+	var senders []notification.Sender
+	var replyAddresses *email.ReplyAddresses
+	emailRepo := email.NewPostgresRepository(db)
+	notificationRepo := notification.NewPostgresRepository(db)
+	if config.Email.ReplyDomain != "" {
+		replyAddresses = email.NewReplyAddresses(emailRepo, config.Email.ReplyDomain)
+	}
+	if config.Email.From != "" {
+		mailer, err := email.NewSMTPMailer(config.Email.SMTP, config.Email.From)
+		if err != nil {
+			publisher.Close()
+			return nil, fmt.Errorf("invalid email configuration: %w", err)
+		}
+		senders = append(senders, email.NewSender(mailer, a.AuthRepo, replyAddresses, log))
+		a.DigestService = email.NewDigests(emailRepo, mailer, notificationRepo, log)
+	}
+	notificationDispatcher := notification.NewDispatcher(notificationRepo, log, senders...)
+
+	// Initialize SMS components for phone verification and unread message
+	// notifications
+	smsProvider, err := sms.NewProvider(config.SMS, log)
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("invalid sms configuration: %w", err)
+	}
+	if config.SMS.PhoneLogin && (smsProvider == nil || config.Auth.Backend == auth.BackendLDAP) {
+		publisher.Close()
+		return nil, fmt.Errorf("invalid sms configuration: phone_login needs a provider and a local auth backend")
+	}
+	smsRepo := sms.NewPostgresRepository(db)
+	a.SMSService = sms.NewService(smsRepo, uow, smsProvider, notificationRepo, config.SMS, log)
+
+	// Initialize workspace components
+	workspaceRepo := workspace.NewPostgresRepository(db)
+	workspaceService := workspace.NewWorkspaceService(workspaceRepo, uow, log)
+	a.workspaceHandler = workspace.NewHandler(workspaceService, log, validate)
+
+	// Initialize the GeoIP table used to spot logins from new countries
+	var geo auth.GeoLocator
+	if config.Auth.GeoIPDatabase != "" {
+		table, err := geoip.Load(config.Auth.GeoIPDatabase)
+		if err != nil {
+			publisher.Close()
+			return nil, fmt.Errorf("failed to load GeoIP database: %w", err)
+		}
+		geo = table
+	}
+
+	// Initialize the password policy, hasher and peppers
+	passwordPolicy := &password.Policy{
+		MinLength:        config.Auth.PasswordMinLength,
+		RequireUppercase: config.Auth.PasswordRequireUppercase,
+		RequireLowercase: config.Auth.PasswordRequireLowercase,
+		RequireDigit:     config.Auth.PasswordRequireDigit,
+		RequireSymbol:    config.Auth.PasswordRequireSymbol,
+	}
+	if breach := config.Auth.PasswordBreachCheck; breach.Enabled {
+		passwordPolicy.Breaches = password.NewPwnedChecker(breach.URL, breach.Timeout)
+	}
+	hashing := config.Auth.PasswordHashing
+	argon2 := hashing.Argon2
+	passwordHasher, err := password.NewHasher(hashing.Algorithm, hashing.BcryptCost, password.Argon2Params{
+		Memory:      argon2.Memory,
+		Iterations:  argon2.Iterations,
+		Parallelism: argon2.Parallelism,
+		SaltLength:  argon2.SaltLength,
+		KeyLength:   argon2.KeyLength,
+	})
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("invalid auth configuration: %w", err)
+	}
+	peppers, err := password.LoadPeppers(hashing.Pepper.Current, hashing.Pepper.Files)
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("failed to load password peppers: %w", err)
+	}
+
+	// Initialize auth components
+	if _, err := auth.ParseSessionLimitPolicy(config.Auth.SessionLimitPolicy); err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("invalid auth configuration: %w", err)
+	}
+	directory, err := auth.NewDirectory(config.Auth)
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("invalid auth configuration: %w", err)
+	}
+	a.AuthService = auth.NewAuthService(
+		a.AuthRepo,
+		uow,
+		tokenMaker,
+		publisher,
+		a.Hub,
+		a.Hub,
+		notificationDispatcher,
+		workspaceRepo,
+		geo,
+		directory,
+		passwordPolicy,
+		passwordHasher,
+		peppers,
+		config.Auth,
+		authLog,
+		config.JWT.AccessExpiry,
+		config.JWT.RefreshExpiry,
+	)
+	a.authHandler = auth.NewHandler(a.AuthService, authLog, validate)
+	a.authMiddleware = auth.NewAuthMiddleware(tokenMaker, a.AuthRepo, authLog)
+
+	// Initialize single sign-on components
+	ssoService, err := sso.NewService(config.SSO, sso.NewPostgresRepository(db), uow, a.AuthRepo, a.AuthService, log)
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("invalid sso configuration: %w", err)
+	}
+	a.ssoHandler = sso.NewHandler(ssoService, log)
+
+	// Initialize phone number handlers, with sign-up and login by texted
+	// code if enabled
+	var phoneLogin *sms.LoginService
+	if config.SMS.PhoneLogin {
+		phoneLogin = sms.NewLoginService(smsRepo, uow, smsProvider, a.AuthService, config.SMS, log)
+	}
+	a.smsHandler = sms.NewHandler(a.SMSService, phoneLogin, log, validate)
+
+	// Initialize feature flags
+	featureRepo := features.NewPostgresRepository(db)
+	a.FeatureManager = features.NewManager(featureRepo, config.Features, log)
+	if err := a.FeatureManager.Refresh(context.Background()); err != nil {
+		log.Error("Failed to load feature flags, using defaults", "error", err)
+	}
+	a.featureHandler = features.NewHandler(a.FeatureManager, log, validate)
+
+	// Initialize the web client's pages
+	a.pages = pages.NewRenderer(a.assets, config.Server.WebDir != "", apiversion.Latest.Prefix(), "/ws", tokenMaker, a.FeatureManager, log)
+	if dir := config.Server.SPA.Dir; dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			publisher.Close()
+			return nil, fmt.Errorf("invalid server configuration: SPA directory %q not found", dir)
+		}
+		a.spa = pages.SPA(os.DirFS(dir), config.Server.SPA.ImmutablePrefixes)
+	}
+
+	// Initialize maintenance mode
+	a.Maintenance = maintenance.NewMode(config.Maintenance, log)
+	a.maintenanceHandler = maintenance.NewHandler(a.Maintenance, log, validate)
+
+	// Initialize runtime log levels, which need the zap logger
+	var logLevels *logger.Levels
+	if zapLogger, ok := log.(*logger.ZapLogger); ok {
+		logLevels = zapLogger.Levels()
+	}
+	a.loggingHandler = logging.NewHandler(logLevels, log, validate)
+
+	// Initialize user components
+	userRepo := user.NewPostgresRepository(db)
+	userService := user.NewUserService(userRepo, a.Hub, a.Hub, log)
+	a.userHandler = user.NewHandler(userService, log, validate)
+
+	// Initialize contact components
+	contactRepo := contact.NewPostgresRepository(db)
+	contactService := contact.NewContactService(contactRepo, uow, a.Hub, notificationDispatcher, config.Contacts, log)
+	a.contactHandler = contact.NewHandler(contactService, log, validate)
+
+	// Initialize group components
+	groupRepo := group.NewPostgresRepository(db)
+	groupService := group.NewGroupService(groupRepo, uow, a.Hub, workspaceRepo, log)
+	a.groupHandler = group.NewHandler(groupService, log, validate, messageValidator)
+
+	// Initialize the buffer for messages sent while the database is down
+	if config.Messages.Buffer.Path != "" {
+		a.messageBuffer, err = conversation.OpenMessageBuffer(config.Messages.Buffer.Path, config.Messages.Buffer.MaxMessages)
+		if err != nil {
+			publisher.Close()
+			return nil, fmt.Errorf("failed to open message buffer: %w", err)
+		}
+	}
+
+	// Initialize conversation components
+	a.ConvRepo = conversation.NewPostgresRepository(db, convLog)
+	a.ConvService = conversation.NewConversationService(a.ConvRepo, uow, publisher, a.Hub, notificationDispatcher, a.FeatureManager, contactService, workspaceRepo, notificationRepo, sanitizer, config.Exports, a.messageBuffer, convLog)
+	a.convHandler = conversation.NewHandler(a.ConvService, convLog, validate, messageValidator)
+	if a.InboxService != nil {
+		a.InboxService.TrackDeliveries(a.ConvService)
+	}
+
+	// Initialize replies to notification emails
+	var inboundService *email.InboundService
+	if replyAddresses != nil {
+		inboundService = email.NewInboundService(replyAddresses, a.AuthRepo, a.ConvService, messageValidator, log)
+	}
+	a.emailHandler = email.NewHandler(inboundService, config.Email.WebhookSecret, log)
+
+	notificationService := notification.NewPreferenceService(notificationRepo, a.ConvRepo, log)
+	a.notificationHandler = notification.NewHandler(notificationService, log, validate)
+
+	// Initialize announcement components
+	announcementRepo := announcement.NewPostgresRepository(db)
+	announcementService := announcement.NewAnnouncementService(announcementRepo, a.Hub, log)
+	a.announcementHandler = announcement.NewHandler(announcementService, log, validate)
+
+	a.Hub.InitRouter(a.ConvService, announcementService, messageValidator, a.FeatureManager, config.Messages.SendTimeout) // Initialize the router after hub is created
+	wsOrigins, err := websocket.NewOriginPolicy(config.WebSocket.AllowedOrigins)
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("invalid websocket configuration: %w", err)
+	}
+	wsVersions, err := websocket.NewVersionPolicy(config.WebSocket.MinAppVersions)
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("invalid websocket configuration: %w", err)
+	}
+	a.wsHandler = websocket.NewHandler(a.Hub, a.authMiddleware, wsOrigins, wsVersions, wsLog)
+
+	// Initialize attachment components
+	signingKey := config.Attachments.SigningKey
+	if signingKey == "" {
+		signingKey = config.JWT.SecretKey
+	}
+	attachmentStorage, err := storage.New(config.Attachments.Storage)
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("failed to create attachment storage: %w", err)
+	}
+	attachmentRepo := attachment.NewPostgresRepository(db)
+	a.AttachmentService = attachment.NewAttachmentService(
+		attachmentRepo,
+		a.ConvRepo,
+		attachmentStorage,
+		a.Hub,
+		notificationDispatcher,
+		signedurl.NewSigner(signingKey, config.Attachments.URLExpiry),
+		config.Attachments,
+		log,
+	)
+	a.attachmentHandler = attachment.NewHandler(a.AttachmentService, log)
+
+	// Initialize admin components
+	adminRepo := admin.NewPostgresRepository(db)
+	// The scheduler is started with the server, or by the worker
+	a.scheduler = jobs.NewScheduler(config.Jobs.Workers, config.Jobs.QueueSize, log)
+	adminService := admin.NewAdminService(adminRepo, a.Hub, a.scheduler, log)
+	a.adminHandler = admin.NewHandler(adminService, log, validate)
+
+	// Initialize API key components
+	apiKeyService := apikey.NewService(apikey.NewPostgresRepository(db), log)
+	a.apiKeyHandler = apikey.NewHandler(apiKeyService, log, validate)
+	a.apiKeyMiddleware = apikey.NewMiddleware(apiKeyService, log)
+
+	// Initialize account components
+	accountRepo := account.NewPostgresRepository(db)
+	a.AccountService = account.NewAccountService(accountRepo, uow, attachmentStorage, config.Accounts, log)
+	a.accountHandler = account.NewHandler(a.AccountService, log)
+
+	// Initialize call components
+	callService := calls.NewCallService(config.Calls, a.FeatureManager, log)
+	a.callHandler = calls.NewHandler(callService, log)
+
+	return a, nil
+}
+
+// ScheduleJobs registers the recurring background jobs
+func (a *App) ScheduleJobs(scheduler *jobs.Scheduler) {
+	config := a.env.Config
+	log := a.env.Logger
+
+	scheduler.Every(config.Jobs.SessionCleanupInterval, jobs.SessionCleanup(a.AuthRepo, log))
+	if config.Jobs.MessageRetention > 0 {
+		scheduler.Every(config.Jobs.RetentionInterval, jobs.RetentionEnforcement(a.ConvRepo, config.Jobs.MessageRetention, log))
+	}
+	scheduler.Every(config.Jobs.DisappearingInterval, jobs.DisappearingMessages(a.ConvRepo, log))
+	if config.Attachments.Storage.Expiry > 0 {
+		scheduler.Every(config.Attachments.Storage.CleanupPeriod, jobs.AttachmentExpiry(a.AttachmentService, config.Attachments.Storage.Expiry, log))
+	}
+	scheduler.Every(config.Jobs.ThumbnailInterval, jobs.ThumbnailGeneration(a.AttachmentService, log))
+	scheduler.Every(config.Jobs.AccountErasureInterval, jobs.AccountErasure(a.AccountService, log))
+	scheduler.Every(config.Features.RefreshInterval, jobs.FeatureFlagRefresh(a.FeatureManager))
+	if config.SMS.Provider != "" {
+		scheduler.Every(config.SMS.Interval, jobs.SMSNotifications(a.SMSService, log))
+	}
+	if a.DigestService != nil {
+		scheduler.Every(config.Email.DigestInterval, jobs.EmailDigests(a.DigestService, log))
+	}
+	if a.InboxService != nil && config.Inbox.Retention > 0 {
+		scheduler.Every(config.Inbox.CleanupInterval, jobs.InboxCleanup(a.InboxService, config.Inbox.Retention, log))
+	}
+}
+
+// Close releases the components' resources, including the database
+// connection if the App opened it
+func (a *App) Close() error {
+	err := a.publisher.Close()
+	if a.messageBuffer != nil {
+		if bufErr := a.messageBuffer.Close(); err == nil {
+			err = bufErr
+		}
+	}
+	if a.ownsDB {
+		if dbErr := a.env.DB.Close(); err == nil {
+			err = dbErr
+		}
+	}
+	return err
+}
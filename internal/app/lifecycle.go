@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/jobs"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Start starts the WebSocket hub, the background jobs unless DisableJobs is
+// set, and the HTTP server. It returns once the server is listening; errors
+// the server hits afterwards are delivered on Err.
+func (a *App) Start() error {
+	config := a.env.Config
+	log := a.env.Logger
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.Server.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	a.listener = listener
+
+	if config.Server.MetricsAddr != "" {
+		if err := a.startMetrics(config.Server.MetricsAddr); err != nil {
+			listener.Close()
+			return err
+		}
+	}
+
+	// Start WebSocket hub
+	go a.Hub.Run()
+
+	// Start background jobs
+	if !a.DisableJobs {
+		a.ScheduleJobs(a.scheduler)
+	}
+	// The message buffer is on this node's disk, so this node flushes it
+	// even when a separate worker runs the other jobs
+	if a.messageBuffer != nil {
+		a.scheduler.Every(config.Messages.Buffer.FlushInterval, jobs.MessageBufferFlush(a.ConvService, log))
+	}
+	a.scheduler.Start(context.Background())
+
+	// Configure CORS if needed
+	// Uncomment and configure if needed for frontend development
+	/*
+		corsMiddleware := cors.New(cors.Options{
+			AllowedOrigins:   []string{"*"},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+			AllowCredentials: true,
+		})
+
+		// Apply CORS middleware
+		routerWithMiddleware := corsMiddleware.Handler(router)
+	*/
+
+	// Create server
+	a.server = &http.Server{
+		Handler:      a.Routes(), // Wrap with corsMiddleware.Handler if using CORS
+		ReadTimeout:  config.Server.ReadTimeout,
+		WriteTimeout: config.Server.WriteTimeout,
+		IdleTimeout:  120 * time.Second,
+	}
+
+	// Start server in a goroutine
+	a.errs = make(chan error, 1)
+	go func() {
+		log.Info("Server listening", "addr", listener.Addr().String())
+		if err := a.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.errs <- err
+		}
+	}()
+
+	return nil
+}
+
+// startMetrics serves Prometheus metrics on an internal listener, apart
+// from the public routes and their maintenance mode
+func (a *App) startMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	a.metrics = &http.Server{
+		Handler:     mux,
+		ReadTimeout: a.env.Config.Server.ReadTimeout,
+	}
+
+	log := a.env.Logger
+	go func() {
+		log.Info("Metrics listening", "addr", listener.Addr().String())
+		if err := a.metrics.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Metrics server stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the HTTP server listens on, which tells tests
+// the port chosen when the configured port is 0
+func (a *App) Addr() string {
+	if a.listener == nil {
+		return ""
+	}
+	return a.listener.Addr().String()
+}
+
+// Err delivers an error if the HTTP server stops unexpectedly
+func (a *App) Err() <-chan error {
+	return a.errs
+}
+
+// Stop drains the WebSocket hub, shuts down the HTTP server, waits for
+// running background jobs and releases the App's resources. It is safe to
+// call if Start failed or was never called.
+func (a *App) Stop(ctx context.Context) error {
+	log := a.env.Logger
+	var errs []error
+
+	// Close WebSocket connections with a reconnect hint. The server does
+	// not track hijacked connections, so Shutdown would leave them open.
+	if a.Hub != nil {
+		a.Hub.Drain()
+	}
+
+	// Shut down server
+	if a.server != nil {
+		if err := a.server.Shutdown(ctx); err != nil {
+			a.server.Close()
+			errs = append(errs, fmt.Errorf("server shutdown: %w", err))
+		}
+	} else if a.listener != nil {
+		a.listener.Close()
+	}
+	if a.metrics != nil {
+		if err := a.metrics.Shutdown(ctx); err != nil {
+			a.metrics.Close()
+			errs = append(errs, fmt.Errorf("metrics server shutdown: %w", err))
+		}
+	}
+
+	// Wait for running background jobs
+	if a.scheduler != nil {
+		if err := a.scheduler.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("job scheduler shutdown: %w", err))
+		}
+	}
+
+	if err := a.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	log.Info("Server stopped")
+	return errors.Join(errs...)
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"io/fs"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/apiversion"
+	"github.com/codingminions/Whatsapp-Lite/pkg/openapi"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/gorilla/mux"
+)
+
+// Routes registers the HTTP routes
+func (a *App) Routes() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(requestid.Middleware)
+	router.Use(a.Maintenance.Middleware(a.authMiddleware))
+
+	// Static files and public pages, unless a single-page app replaces them
+	if a.spa == nil {
+		static, _ := fs.Sub(a.assets, "static")
+		router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+
+		router.HandleFunc("/", a.pages.Page("index.html", false)).Methods("GET")
+		router.HandleFunc("/login", a.pages.Page("login.html", false)).Methods("GET")
+		router.HandleFunc("/register", a.pages.Page("register.html", false)).Methods("GET")
+		router.HandleFunc("/chat", a.pages.Page("chat.html", true)).Methods("GET")
+	}
+
+	// Single sign-on flows stay unversioned, since identity providers are
+	// configured with their callback URLs
+	router.HandleFunc("/auth/sso/{provider}/login", a.ssoHandler.Login).Methods("GET")
+	router.HandleFunc("/auth/sso/{provider}/callback", a.ssoHandler.Callback).Methods("GET")
+	router.HandleFunc("/auth/sso/{provider}/acs", a.ssoHandler.ACS).Methods("POST")
+
+	// Attachment downloads are authorized by the signed URL
+	router.HandleFunc("/attachments/{attachment_id}/download", a.attachmentHandler.Download).Methods("GET")
+	router.HandleFunc("/attachments/{attachment_id}/thumbnail", a.attachmentHandler.Thumbnail).Methods("GET")
+
+	// Replies to notification emails, forwarded by the mail provider and
+	// authorized by the webhook secret
+	router.HandleFunc("/email/inbound", a.emailHandler.Inbound).Methods("POST")
+
+	// WebSocket route
+	router.HandleFunc("/ws", a.wsHandler.ServeWS)
+
+	// API description and explorer
+	router.Handle("/api/openapi.json", a.openAPIDocument().Handler()).Methods("GET")
+	router.Handle("/api/docs", openapi.UIHandler("Whatsapp-Lite API", "/api/openapi.json")).Methods("GET")
+
+	// REST API, served under /api/v1 and, for clients that predate it, at
+	// the unversioned legacy paths
+	a.apiRoutes(router.PathPrefix(apiversion.V1.Prefix()).Subrouter(), apiversion.V1)
+	legacy := router.NewRoute().Subrouter()
+	legacy.Use(legacyAPI)
+	a.apiRoutes(legacy, 0)
+
+	// The single-page app gets every other GET, so its client-side routes
+	// load directly. Routes shadowed by the legacy API paths must be avoided.
+	if a.spa != nil {
+		router.PathPrefix("/").Handler(a.spa).Methods("GET", "HEAD")
+	}
+
+	return router
+}
+
+// apiRoutes registers the REST API routes on r. Requests are marked as made
+// for version v, or for the version negotiated by earlier middleware if v
+// is 0.
+func (a *App) apiRoutes(r *mux.Router, v apiversion.Version) {
+	if v != 0 {
+		r.Use(apiversion.Middleware(v))
+	}
+
+	for _, route := range a.apiRouteTable() {
+		r.Handle(route.path, a.authorize(route)).Methods(route.method)
+	}
+}
+
+// authorize wraps a route's handler in the authentication it requires
+func (a *App) authorize(route apiRoute) http.Handler {
+	switch route.auth {
+	case authUser:
+		return a.authMiddleware.Authenticate(route.handler)
+	case authAdmin:
+		return a.authMiddleware.Authenticate(a.authMiddleware.RequireAdmin(route.handler))
+	case authAPIKey:
+		return a.apiKeyMiddleware.Require(route.scope, route.handler)
+	default:
+		return route.handler
+	}
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/bootstrap"
+)
+
+// RunWorker runs the background jobs without serving HTTP until the process
+// is interrupted. Web nodes then run with -jobs=false, so each side can be
+// scaled and deployed on its own.
+func RunWorker(env *bootstrap.Env) error {
+	config := env.Config
+	log := env.Logger
+	log.Info("Starting background job worker")
+
+	a, err := New(env)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	a.ScheduleJobs(a.scheduler)
+	a.scheduler.Start(context.Background())
+
+	// Block until we receive a signal
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+	<-shutdown
+	log.Info("Shutting down worker")
+
+	// Wait for running background jobs
+	ctx, cancel := context.WithTimeout(context.Background(), config.Server.ShutdownTimeout)
+	defer cancel()
+	if err := a.scheduler.Stop(ctx); err != nil {
+		log.Error("Job scheduler shutdown error", "error", err)
+	}
+
+	log.Info("Worker stopped")
+	return nil
+}
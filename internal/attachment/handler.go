@@ -0,0 +1,240 @@
+package attachment
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/conversation"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/signedurl"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// multipartOverhead allows for form boundaries and headers around the file
+const multipartOverhead = 1 << 20
+
+// Handler handles attachment HTTP requests
+type Handler struct {
+	service Service
+	logger  logger.Logger
+}
+
+// NewHandler creates a new attachment handler
+func NewHandler(service Service, logger logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Upload handles multipart uploads of a single file to a conversation
+func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Stream the file part instead of buffering the whole form
+	r.Body = http.MaxBytesReader(w, r.Body, h.service.MaxSize()+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "attachment.missing_file"))
+			return
+		}
+		if err != nil {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+			return
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		// Call service
+		resp, err := h.service.Upload(r.Context(), conversationID, userID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			h.sendServiceError(w, r, err, "attachment.upload_failed")
+			return
+		}
+
+		// Send response
+		sendJSON(w, http.StatusCreated, resp)
+		return
+	}
+}
+
+// GetDownloadURL handles requests for a fresh signed download URL
+func (h *Handler) GetDownloadURL(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	attachmentID, err := uuid.Parse(vars["attachment_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "attachment.invalid_id"))
+		return
+	}
+
+	// Call service
+	resp, err := h.service.GetDownloadURL(r.Context(), vars["conversation_id"], attachmentID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "attachment.download_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// Download serves an attachment to the holder of a valid signed URL. It is
+// not behind the auth middleware; the signature is the credential.
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	attachmentID, err := uuid.Parse(mux.Vars(r)["attachment_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "attachment.invalid_id"))
+		return
+	}
+
+	attachment, body, err := h.service.Open(r.Context(), attachmentID, r.URL.Path, r.URL.Query())
+	if err != nil {
+		h.sendServiceError(w, r, err, "attachment.download_failed")
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	h.serveContent(w, r, attachment, attachment.Size, body)
+}
+
+// Thumbnail serves the thumbnail of an image attachment to the holder of a
+// valid signed URL, like Download
+func (h *Handler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	attachmentID, err := uuid.Parse(mux.Vars(r)["attachment_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "attachment.invalid_id"))
+		return
+	}
+
+	attachment, body, err := h.service.OpenThumbnail(r.Context(), attachmentID, r.URL.Path, r.URL.Query())
+	if err != nil {
+		h.sendServiceError(w, r, err, "attachment.download_failed")
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", thumbnailContentType)
+	w.Header().Set("Content-Disposition", "inline")
+	h.serveContent(w, r, attachment, *attachment.ThumbnailSize, body)
+}
+
+// serveContent writes the contents of an attachment or its thumbnail,
+// which must be size bytes long
+func (h *Handler) serveContent(w http.ResponseWriter, r *http.Request, attachment *models.Attachment, size int64, body io.Reader) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	// Seekable contents support range requests
+	if content, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", attachment.CreatedAt, content)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, body); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to stream attachment", "attachment_id", attachment.ID, "error", err)
+	}
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing or malformed
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// sendServiceError maps a service error to an HTTP error response, using
+// the message key for unexpected errors
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error, key string) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, conversation.ErrInvalidConversationID):
+		sendError(w, r, errcode.InvalidConversation, i18n.T(r, "conversation.invalid_id"))
+	case errors.Is(err, ErrUnauthorized):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "conversation.not_participant"))
+	case errors.Is(err, ErrAttachmentNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "attachment.not_found"))
+	case errors.Is(err, ErrTooLarge), errors.As(err, &maxBytesErr):
+		sendError(w, r, errcode.PayloadTooLarge, i18n.T(r, "attachment.too_large", h.service.MaxSize()))
+	case errors.Is(err, ErrImageTooLarge):
+		sendError(w, r, errcode.PayloadTooLarge, i18n.T(r, "attachment.image_too_large", MaxImagePixels))
+	case errors.Is(err, ErrUnsupportedImage):
+		sendError(w, r, errcode.InvalidContent, i18n.T(r, "attachment.unsupported_image"))
+	case errors.Is(err, ErrEmptyUpload):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "attachment.empty"))
+	case errors.Is(err, ErrInvalidUpload):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+	case errors.Is(err, signedurl.ErrExpired):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "attachment.url_expired"))
+	case errors.Is(err, signedurl.ErrInvalidSignature):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "attachment.invalid_signature"))
+	default:
+		h.logger.WithContext(r.Context()).Error(i18n.English.T(key), "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, key))
+	}
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
@@ -0,0 +1,247 @@
+package attachment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	// Register the decoders for formats that are re-encoded as PNG
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+// Image errors
+var (
+	// ErrUnsupportedImage is returned for images that cannot be re-encoded,
+	// so their metadata cannot be stripped
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	ErrImageTooLarge    = errors.New("image dimensions are too large")
+)
+
+// MaxImagePixels guards against decompression bombs
+const MaxImagePixels = 50_000_000
+
+// jpegQuality is the quality re-encoded JPEGs are written with
+const jpegQuality = 90
+
+// Thumbnails are JPEGs that fit in a square of thumbnailSize pixels
+const (
+	thumbnailSize        = 320
+	thumbnailQuality     = 80
+	thumbnailContentType = "image/jpeg"
+)
+
+// normalizeImage decodes an image and writes a fresh encoding of it to w,
+// returning the content type it was written as. The encoders write pixel
+// data only, so EXIF, GPS, XMP and comment metadata are dropped. The EXIF
+// orientation of JPEGs is applied to the pixels first so photos keep
+// displaying upright. WebP and BMP have no encoder and are written as PNG.
+func normalizeImage(r io.ReadSeeker, contentType string, w io.Writer) (string, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return "", ErrImageTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	switch contentType {
+	case "image/jpeg":
+		orientation := jpegOrientation(r)
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		img, err := jpeg.Decode(r)
+		if err != nil {
+			return "", err
+		}
+		return contentType, jpeg.Encode(w, orient(img, orientation), &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		img, err := png.Decode(r)
+		if err != nil {
+			return "", err
+		}
+		return contentType, png.Encode(w, img)
+	case "image/gif":
+		// Keep every frame so animations survive
+		img, err := gif.DecodeAll(r)
+		if err != nil {
+			return "", err
+		}
+		return contentType, gif.EncodeAll(w, img)
+	case "image/webp", "image/bmp":
+		img, _, err := image.Decode(r)
+		if err != nil {
+			return "", err
+		}
+		return "image/png", png.Encode(w, img)
+	default:
+		return "", ErrUnsupportedImage
+	}
+}
+
+// makeThumbnail decodes an image and writes a JPEG of it scaled down to fit
+// in a square of thumbnailSize pixels. Transparent areas are filled with
+// white, and the EXIF orientation of JPEGs is applied first.
+func makeThumbnail(data []byte, contentType string, w io.Writer) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return ErrUnsupportedImage
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ErrUnsupportedImage
+	}
+	if contentType == "image/jpeg" {
+		img = orient(img, jpegOrientation(bytes.NewReader(data)))
+	}
+
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width > thumbnailSize || height > thumbnailSize {
+		if width >= height {
+			width, height = thumbnailSize, height*thumbnailSize/width
+		} else {
+			width, height = width*thumbnailSize/height, thumbnailSize
+		}
+		// Keep very thin images at least a pixel across
+		if width < 1 {
+			width = 1
+		}
+		if height < 1 {
+			height = 1
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return jpeg.Encode(w, dst, &jpeg.Options{Quality: thumbnailQuality})
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or 1 if it has none
+func jpegOrientation(r io.Reader) int {
+	var marker [4]byte
+	if _, err := io.ReadFull(r, marker[:2]); err != nil || marker[0] != 0xFF || marker[1] != 0xD8 {
+		return 1
+	}
+
+	for {
+		if _, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xFF {
+			return 1
+		}
+		// Start of scan: no more metadata segments follow
+		if marker[1] == 0xDA {
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if length < 0 {
+			return 1
+		}
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return 1
+		}
+
+		if marker[1] == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+	}
+}
+
+// exifOrientation reads the orientation tag from the first IFD of a TIFF
+// structure, returning 1 if it is missing or malformed
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orient transforms an image according to an EXIF orientation value
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	// Orientations 5-8 swap the width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			si := src.PixOffset(x, y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,32 @@
+package attachment
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Image processing outcomes
+const (
+	imageStatusSuccess     = "success"
+	imageStatusFailure     = "failure"
+	imageStatusUnsupported = "unsupported"
+)
+
+// Attachment metrics exported on the metrics endpoint
+var (
+	imagesProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_attachments_images_processed_total",
+		Help: "Number of uploaded images re-encoded to strip metadata, by content type and outcome.",
+	}, []string{"content_type", "status"})
+
+	thumbnailsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_attachments_thumbnails_total",
+		Help: "Number of images the thumbnail job handled, by content type and outcome.",
+	}, []string{"content_type", "status"})
+
+	imageProcessingDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "chat_attachments_image_processing_seconds",
+		Help:    "Duration of uploaded image re-encoding.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 3, 8),
+	})
+)
@@ -0,0 +1,155 @@
+package attachment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+)
+
+// ErrAttachmentNotFound is returned when an attachment does not exist
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// Repository interface for attachment operations
+type Repository interface {
+	SaveAttachment(ctx context.Context, attachment *models.Attachment) error
+	GetAttachment(ctx context.Context, id uuid.UUID) (*models.Attachment, error)
+	GetAttachmentsBefore(ctx context.Context, before time.Time, limit int) ([]*models.Attachment, error)
+	DeleteAttachment(ctx context.Context, id uuid.UUID) error
+	GetUnthumbnailedImages(ctx context.Context, limit int) ([]*models.Attachment, error)
+	SetThumbnail(ctx context.Context, id uuid.UUID, thumbnailKey string, thumbnailSize int64) error
+	SkipThumbnail(ctx context.Context, id uuid.UUID) error
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// SaveAttachment saves an attachment's metadata
+func (r *PostgresRepository) SaveAttachment(ctx context.Context, attachment *models.Attachment) error {
+	query := `
+		INSERT INTO attachments (id, conversation_id, uploader_id, filename, content_type, size, storage_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		attachment.ID,
+		attachment.ConversationID,
+		attachment.UploaderID,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.StorageKey,
+		attachment.CreatedAt,
+	)
+	return err
+}
+
+// GetAttachment retrieves an attachment's metadata by ID
+func (r *PostgresRepository) GetAttachment(ctx context.Context, id uuid.UUID) (*models.Attachment, error) {
+	query := `
+		SELECT id, conversation_id, uploader_id, filename, content_type, size, storage_key, created_at,
+		       thumbnail_key, thumbnail_size
+		FROM attachments
+		WHERE id = $1
+	`
+
+	var attachment models.Attachment
+	if err := r.conn(ctx).GetContext(ctx, &attachment, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// GetAttachmentsBefore retrieves the oldest attachments created before a cutoff
+func (r *PostgresRepository) GetAttachmentsBefore(ctx context.Context, before time.Time, limit int) ([]*models.Attachment, error) {
+	query := `
+		SELECT id, conversation_id, uploader_id, filename, content_type, size, storage_key, created_at,
+		       thumbnail_key, thumbnail_size
+		FROM attachments
+		WHERE created_at < $1
+		ORDER BY created_at
+		LIMIT $2
+	`
+
+	var attachments []*models.Attachment
+	if err := r.conn(ctx).SelectContext(ctx, &attachments, query, before, limit); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// DeleteAttachment deletes an attachment's metadata
+func (r *PostgresRepository) DeleteAttachment(ctx context.Context, id uuid.UUID) error {
+	_, err := r.conn(ctx).ExecContext(ctx, "DELETE FROM attachments WHERE id = $1", id)
+	return err
+}
+
+// GetUnthumbnailedImages retrieves the oldest images the thumbnail job has
+// not handled yet
+func (r *PostgresRepository) GetUnthumbnailedImages(ctx context.Context, limit int) ([]*models.Attachment, error) {
+	query := `
+		SELECT id, conversation_id, uploader_id, filename, content_type, size, storage_key, created_at
+		FROM attachments
+		WHERE thumbnailed_at IS NULL AND content_type LIKE 'image/%'
+		ORDER BY created_at
+		LIMIT $1
+	`
+
+	var attachments []*models.Attachment
+	if err := r.conn(ctx).SelectContext(ctx, &attachments, query, limit); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// SetThumbnail saves the storage key and size of an image's thumbnail. It
+// returns ErrAttachmentNotFound if the image was deleted meanwhile.
+func (r *PostgresRepository) SetThumbnail(ctx context.Context, id uuid.UUID, thumbnailKey string, thumbnailSize int64) error {
+	query := `
+		UPDATE attachments
+		SET thumbnail_key = $2, thumbnail_size = $3, thumbnailed_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, id, thumbnailKey, thumbnailSize)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrAttachmentNotFound
+	}
+	return nil
+}
+
+// SkipThumbnail records that no thumbnail can be made of an image, so the
+// thumbnail job does not try it again
+func (r *PostgresRepository) SkipThumbnail(ctx context.Context, id uuid.UUID) error {
+	_, err := r.conn(ctx).ExecContext(ctx, "UPDATE attachments SET thumbnailed_at = NOW() WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,520 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/internal/storage"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/signedurl"
+	"github.com/google/uuid"
+)
+
+// Service errors
+var (
+	ErrUnauthorized  = errors.New("user not authorized to access this attachment")
+	ErrTooLarge      = errors.New("attachment is too large")
+	ErrEmptyUpload   = errors.New("attachment is empty")
+	ErrInvalidUpload = errors.New("invalid attachment upload")
+)
+
+// DefaultMaxSize is used when no maximum attachment size is configured
+const DefaultMaxSize = 10 << 20
+
+// expiryBatchSize is the number of expired attachments deleted per query
+const expiryBatchSize = 100
+
+// thumbnailBatchSize is the number of images thumbnailed per query
+const thumbnailBatchSize = 20
+
+// ParticipantChecker reports whether a user takes part in a conversation
+type ParticipantChecker interface {
+	IsUserInConversation(ctx context.Context, conversationID string, userID uuid.UUID) (bool, error)
+}
+
+// Notifier pushes real-time events to a user's connected clients
+type Notifier interface {
+	SendToUser(userID uuid.UUID, message *models.WebSocketMessage) bool
+}
+
+// OfflineNotifier notifies users who are not connected through channels
+// such as push or email, subject to their notification preferences
+type OfflineNotifier interface {
+	Dispatch(ctx context.Context, notification *models.Notification)
+}
+
+// Service handles attachment business logic
+type Service interface {
+	Upload(ctx context.Context, conversationID string, userID uuid.UUID, filename string, content io.Reader) (*models.AttachmentResponse, error)
+	GetDownloadURL(ctx context.Context, conversationID string, attachmentID, userID uuid.UUID) (*models.AttachmentResponse, error)
+	Open(ctx context.Context, attachmentID uuid.UUID, path string, query url.Values) (*models.Attachment, io.ReadCloser, error)
+	OpenThumbnail(ctx context.Context, attachmentID uuid.UUID, path string, query url.Values) (*models.Attachment, io.ReadCloser, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+	GenerateThumbnails(ctx context.Context) (int, error)
+	MaxSize() int64
+}
+
+// AttachmentService implements Service interface
+type AttachmentService struct {
+	repo          Repository
+	participants  ParticipantChecker
+	storage       storage.Storage
+	notifier      Notifier
+	offline       OfflineNotifier
+	signer        *signedurl.Signer
+	maxSize       int64
+	stripMetadata bool
+	logger        logger.Logger
+}
+
+// NewAttachmentService creates a new attachment service
+func NewAttachmentService(repo Repository, participants ParticipantChecker, store storage.Storage, notifier Notifier, offline OfflineNotifier, signer *signedurl.Signer, config configs.AttachmentsConfig, logger logger.Logger) *AttachmentService {
+	maxSize := config.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+
+	return &AttachmentService{
+		repo:          repo,
+		participants:  participants,
+		storage:       store,
+		notifier:      notifier,
+		offline:       offline,
+		signer:        signer,
+		maxSize:       maxSize,
+		stripMetadata: config.StripMetadata,
+		logger:        logger,
+	}
+}
+
+// MaxSize returns the maximum attachment size in bytes
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Upload stores a file for a conversation and returns it with a download URL
+func (s *AttachmentService) Upload(ctx context.Context, conversationID string, userID uuid.UUID, filename string, content io.Reader) (*models.AttachmentResponse, error) {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		ID:             uuid.New(),
+		ConversationID: conversationID,
+		UploaderID:     userID,
+		Filename:       cleanFilename(filename),
+		CreatedAt:      time.Now(),
+	}
+	attachment.StorageKey = attachment.ID.String()
+
+	// Spool to a temporary file to learn the size and process images
+	tmp, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// Sniff the content type rather than trusting the client
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, ErrInvalidUpload
+	}
+	if n == 0 {
+		return nil, ErrEmptyUpload
+	}
+	attachment.ContentType = http.DetectContentType(head[:n])
+
+	written, err := io.Copy(tmp, io.LimitReader(io.MultiReader(bytes.NewReader(head[:n]), content), s.maxSize+1))
+	if err != nil {
+		return nil, ErrInvalidUpload
+	}
+	if written > s.maxSize {
+		return nil, ErrTooLarge
+	}
+	attachment.Size = written
+
+	stored := tmp
+	if s.stripMetadata && strings.HasPrefix(attachment.ContentType, "image/") {
+		normalized, err := s.normalizeImage(ctx, tmp, attachment)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(normalized.Name())
+		defer normalized.Close()
+		stored = normalized
+	}
+
+	if _, err := stored.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Put(ctx, attachment.StorageKey, stored, attachment.Size, attachment.ContentType); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to store attachment", "error", err)
+		return nil, err
+	}
+
+	if err := s.repo.SaveAttachment(ctx, attachment); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save attachment", "error", err)
+		if err := s.storage.Delete(ctx, attachment.StorageKey); err != nil {
+			s.logger.WithContext(ctx).Error("Failed to delete orphaned attachment", "error", err)
+		}
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Attachment uploaded",
+		"attachment_id", attachment.ID,
+		"conversation_id", conversationID,
+		"size", attachment.Size)
+
+	s.notifyUploaded(ctx, attachment)
+
+	return s.withDownloadURL(attachment), nil
+}
+
+// notifyUploaded tells the other participant about a new attachment, as a
+// media notification if they are not connected
+func (s *AttachmentService) notifyUploaded(ctx context.Context, attachment *models.Attachment) {
+	recipientID, err := otherParticipant(attachment.ConversationID, attachment.UploaderID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to find attachment recipient", "error", err, "conversation_id", attachment.ConversationID)
+		return
+	}
+
+	delivered := s.notifier.SendToUser(recipientID, &models.WebSocketMessage{
+		Type: protocol.TypeAttachmentUploaded,
+		Data: *attachment,
+	})
+	if !delivered {
+		s.offline.Dispatch(ctx, &models.Notification{
+			UserID:         recipientID,
+			Type:           models.NotificationMedia,
+			ConversationID: attachment.ConversationID,
+			SenderID:       attachment.UploaderID.String(),
+			Body:           attachment.Filename,
+		})
+	}
+}
+
+// normalizeImage re-encodes an uploaded image into a new temporary file,
+// updating the attachment's size and content type. Images that cannot be re-encoded are
+// rejected rather than stored with their metadata.
+func (s *AttachmentService) normalizeImage(ctx context.Context, upload *os.File, attachment *models.Attachment) (*os.File, error) {
+	start := time.Now()
+	defer func() {
+		imageProcessingDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	out, err := os.CreateTemp("", "normalized-*")
+	if err != nil {
+		return nil, err
+	}
+
+	fail := func(status string, err error) (*os.File, error) {
+		out.Close()
+		os.Remove(out.Name())
+		imagesProcessed.WithLabelValues(attachment.ContentType, status).Inc()
+		return nil, err
+	}
+
+	if _, err := upload.Seek(0, io.SeekStart); err != nil {
+		return fail(imageStatusFailure, err)
+	}
+	contentType, err := normalizeImage(upload, attachment.ContentType, out)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedImage) {
+			return fail(imageStatusUnsupported, err)
+		}
+		s.logger.WithContext(ctx).Error("Failed to normalize image",
+			"content_type", attachment.ContentType,
+			"error", err)
+		if errors.Is(err, ErrImageTooLarge) {
+			return fail(imageStatusFailure, err)
+		}
+		return fail(imageStatusFailure, ErrInvalidUpload)
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		return fail(imageStatusFailure, err)
+	}
+	if info.Size() > s.maxSize {
+		return fail(imageStatusFailure, ErrTooLarge)
+	}
+	attachment.Size = info.Size()
+
+	imagesProcessed.WithLabelValues(attachment.ContentType, imageStatusSuccess).Inc()
+	attachment.ContentType = contentType
+	return out, nil
+}
+
+// GetDownloadURL returns an attachment with a fresh download URL
+func (s *AttachmentService) GetDownloadURL(ctx context.Context, conversationID string, attachmentID, userID uuid.UUID) (*models.AttachmentResponse, error) {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.repo.GetAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The attachment must belong to the conversation the user is part of
+	if attachment.ConversationID != conversationID {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return s.withDownloadURL(attachment), nil
+}
+
+// Open verifies a signed download URL and opens the attachment's contents.
+// The caller must close the returned reader.
+func (s *AttachmentService) Open(ctx context.Context, attachmentID uuid.UUID, path string, query url.Values) (*models.Attachment, io.ReadCloser, error) {
+	if err := s.signer.Verify(path, query); err != nil {
+		return nil, nil, err
+	}
+
+	attachment, err := s.repo.GetAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := s.storage.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			s.logger.WithContext(ctx).Error("Attachment contents are missing", "attachment_id", attachmentID)
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+
+	return attachment, body, nil
+}
+
+// OpenThumbnail verifies a signed thumbnail URL and opens the thumbnail of
+// an image attachment. The caller must close the returned reader.
+func (s *AttachmentService) OpenThumbnail(ctx context.Context, attachmentID uuid.UUID, path string, query url.Values) (*models.Attachment, io.ReadCloser, error) {
+	if err := s.signer.Verify(path, query); err != nil {
+		return nil, nil, err
+	}
+
+	attachment, err := s.repo.GetAttachment(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment.ThumbnailKey == nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+
+	body, err := s.storage.Get(ctx, *attachment.ThumbnailKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			s.logger.WithContext(ctx).Error("Attachment thumbnail is missing", "attachment_id", attachmentID)
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+
+	return attachment, body, nil
+}
+
+// GenerateThumbnails makes thumbnails of the images uploaded since the
+// last run, returning how many were made. Images that cannot be decoded
+// are skipped for good; storage errors leave the rest to the next run.
+func (s *AttachmentService) GenerateThumbnails(ctx context.Context) (int, error) {
+	generated := 0
+	for {
+		attachments, err := s.repo.GetUnthumbnailedImages(ctx, thumbnailBatchSize)
+		if err != nil {
+			return generated, err
+		}
+
+		for _, attachment := range attachments {
+			ok, err := s.generateThumbnail(ctx, attachment)
+			if err != nil {
+				return generated, err
+			}
+			if ok {
+				generated++
+			}
+		}
+
+		if len(attachments) < thumbnailBatchSize {
+			return generated, nil
+		}
+	}
+}
+
+// generateThumbnail makes and stores the thumbnail of an image, reporting
+// whether one was made
+func (s *AttachmentService) generateThumbnail(ctx context.Context, attachment *models.Attachment) (bool, error) {
+	skip := func(status string) (bool, error) {
+		thumbnailsProcessed.WithLabelValues(attachment.ContentType, status).Inc()
+		return false, s.repo.SkipThumbnail(ctx, attachment.ID)
+	}
+
+	body, err := s.storage.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return skip(imageStatusFailure)
+		}
+		return false, err
+	}
+	data, err := io.ReadAll(io.LimitReader(body, attachment.Size+1))
+	body.Close()
+	if err != nil {
+		return false, err
+	}
+
+	var thumbnail bytes.Buffer
+	if err := makeThumbnail(data, attachment.ContentType, &thumbnail); err != nil {
+		if errors.Is(err, ErrUnsupportedImage) {
+			return skip(imageStatusUnsupported)
+		}
+		s.logger.WithContext(ctx).Error("Failed to make thumbnail",
+			"attachment_id", attachment.ID,
+			"content_type", attachment.ContentType,
+			"error", err)
+		return skip(imageStatusFailure)
+	}
+
+	thumbnailKey := attachment.StorageKey + ".thumbnail"
+	size := int64(thumbnail.Len())
+	if err := s.storage.Put(ctx, thumbnailKey, &thumbnail, size, thumbnailContentType); err != nil {
+		return false, err
+	}
+	if err := s.repo.SetThumbnail(ctx, attachment.ID, thumbnailKey, size); err != nil {
+		if errors.Is(err, ErrAttachmentNotFound) {
+			// The image expired or was erased while the thumbnail was made
+			return false, s.storage.Delete(ctx, thumbnailKey)
+		}
+		return false, err
+	}
+
+	thumbnailsProcessed.WithLabelValues(attachment.ContentType, imageStatusSuccess).Inc()
+	return true, nil
+}
+
+// DeleteExpired deletes attachments created before a cutoff from storage
+// and the database, returning how many were deleted
+func (s *AttachmentService) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	deleted := 0
+	for {
+		attachments, err := s.repo.GetAttachmentsBefore(ctx, before, expiryBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+
+		for _, attachment := range attachments {
+			// Delete the contents first so a failure leaves the row to retry
+			if err := s.storage.Delete(ctx, attachment.StorageKey); err != nil {
+				return deleted, err
+			}
+			if attachment.ThumbnailKey != nil {
+				if err := s.storage.Delete(ctx, *attachment.ThumbnailKey); err != nil {
+					return deleted, err
+				}
+			}
+			if err := s.repo.DeleteAttachment(ctx, attachment.ID); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+
+		if len(attachments) < expiryBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// withDownloadURL signs a download URL for an attachment, and a thumbnail
+// URL if it has a thumbnail
+func (s *AttachmentService) withDownloadURL(attachment *models.Attachment) *models.AttachmentResponse {
+	downloadURL, expiresAt := s.signer.Sign(DownloadPath(attachment.ID))
+	resp := &models.AttachmentResponse{
+		Attachment:  *attachment,
+		DownloadURL: downloadURL,
+		ExpiresAt:   expiresAt,
+	}
+	if attachment.ThumbnailKey != nil {
+		resp.ThumbnailURL, _ = s.signer.Sign(ThumbnailPath(attachment.ID))
+	}
+	return resp
+}
+
+// checkParticipant returns ErrUnauthorized unless the user is in the conversation
+func (s *AttachmentService) checkParticipant(ctx context.Context, conversationID string, userID uuid.UUID) error {
+	isParticipant, err := s.participants.IsUserInConversation(ctx, conversationID, userID)
+	if err != nil {
+		return err
+	}
+	if !isParticipant {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// otherParticipant returns the participant of a direct conversation who is
+// not userID. Conversation IDs join the two user IDs with a hyphen.
+func otherParticipant(conversationID string, userID uuid.UUID) (uuid.UUID, error) {
+	if len(conversationID) != 73 { // 36 + 1 + 36
+		return uuid.Nil, errors.New("invalid conversation ID")
+	}
+	first, err := uuid.Parse(conversationID[:36])
+	if err != nil {
+		return uuid.Nil, err
+	}
+	second, err := uuid.Parse(conversationID[37:])
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if first == userID {
+		return second, nil
+	}
+	return first, nil
+}
+
+// DownloadPath returns the URL path an attachment is downloaded from
+func DownloadPath(attachmentID uuid.UUID) string {
+	return "/attachments/" + attachmentID.String() + "/download"
+}
+
+// ThumbnailPath returns the URL path an attachment's thumbnail is
+// downloaded from
+func ThumbnailPath(attachmentID uuid.UUID) string {
+	return "/attachments/" + attachmentID.String() + "/thumbnail"
+}
+
+// cleanFilename reduces a client-supplied filename to a safe display name
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/ldap"
+)
+
+// Auth backends
+const (
+	BackendLocal = "local"
+	BackendLDAP  = "ldap"
+)
+
+// ErrDirectoryManaged is returned for registrations and password changes
+// when accounts are managed by a directory
+var ErrDirectoryManaged = errors.New("accounts are managed by the directory")
+
+// DirectoryUser is a user found in an external directory
+type DirectoryUser struct {
+	Email       string
+	Username    string
+	DisplayName string
+}
+
+// Directory checks credentials against an external directory. It returns
+// ErrInvalidCredentials if the user is unknown or the password is wrong.
+type Directory interface {
+	Authenticate(ctx context.Context, email, password string) (*DirectoryUser, error)
+}
+
+// LDAPDirectory checks credentials against an LDAP directory such as
+// OpenLDAP or Active Directory
+type LDAPDirectory struct {
+	config configs.LDAPConfig
+}
+
+// NewDirectory returns the directory for an auth backend, or nil for local
+// accounts
+func NewDirectory(config configs.AuthConfig) (Directory, error) {
+	switch config.Backend {
+	case "", BackendLocal:
+		return nil, nil
+	case BackendLDAP:
+		directory, err := NewLDAPDirectory(config.LDAP)
+		if err != nil {
+			return nil, err
+		}
+		return directory, nil
+	default:
+		return nil, fmt.Errorf("unknown auth backend %q", config.Backend)
+	}
+}
+
+// NewLDAPDirectory creates an LDAP directory
+func NewLDAPDirectory(config configs.LDAPConfig) (*LDAPDirectory, error) {
+	if config.URL == "" || config.BaseDN == "" {
+		return nil, errors.New("ldap: url and base_dn are required")
+	}
+	if !strings.Contains(config.UserFilter, "%s") {
+		return nil, errors.New("ldap: user_filter must contain %s")
+	}
+	return &LDAPDirectory{config: config}, nil
+}
+
+// Authenticate finds the user with the service account, then checks the
+// password by binding as them
+func (d *LDAPDirectory) Authenticate(ctx context.Context, email, password string) (*DirectoryUser, error) {
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := ldap.Dial(ctx, d.config.URL, d.config.StartTLS, nil, d.config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: connect: %w", err)
+	}
+	defer conn.Close()
+
+	if d.config.BindDN != "" {
+		if err := conn.Bind(d.config.BindDN, d.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: service account bind: %v", err)
+		}
+	}
+
+	// Exactly one entry must match
+	filter := strings.ReplaceAll(d.config.UserFilter, "%s", ldap.EscapeFilter(email))
+	attributes := []string{"mail", d.config.UsernameAttribute, d.config.DisplayNameAttribute}
+	entries, err := conn.Search(d.config.BaseDN, filter, attributes, 2)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: search: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	if len(entries) > 1 {
+		return nil, fmt.Errorf("ldap: %d entries match %s", len(entries), filter)
+	}
+	entry := entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap: user bind: %w", err)
+	}
+
+	user := &DirectoryUser{
+		Email:       entry.Get("mail"),
+		Username:    entry.Get(d.config.UsernameAttribute),
+		DisplayName: entry.Get(d.config.DisplayNameAttribute),
+	}
+	if user.Email == "" {
+		user.Email = email
+	}
+	return user, nil
+}
+
+// directoryLogin checks a user's credentials against the directory and
+// returns their account, creating it on their first login and updating
+// their display name from the directory
+func (s *AuthService) directoryLogin(ctx context.Context, req *models.LoginRequest) (*models.User, error) {
+	entry, err := s.directory.Authenticate(ctx, req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.logger.WithContext(ctx).Info("Invalid directory credentials", "email", req.Email)
+			return nil, ErrInvalidCredentials
+		}
+		s.logger.WithContext(ctx).Error("Failed to check directory credentials", "error", err)
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, entry.Email)
+	if errors.Is(err, ErrUserNotFound) {
+		user, err = s.ProvisionUser(ctx, entry.Email, entry.Username)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.DisplayName != user.DisplayName {
+		if err := s.repo.UpdateDisplayName(ctx, user.ID, entry.DisplayName); err != nil {
+			s.logger.WithContext(ctx).Error("Failed to update display name", "error", err, "user_id", user.ID)
+		} else {
+			user.DisplayName = entry.DisplayName
+		}
+	}
+
+	return user, nil
+}
@@ -3,13 +3,18 @@ package auth
 import (
 	"encoding/json"
 	"errors"
-	"net"
 	"net/http"
 	"strings"
 
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
+	"github.com/codingminions/Whatsapp-Lite/pkg/password"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
 	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
 )
 
 // Handler handles auth-related HTTP requests
@@ -33,39 +38,47 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	// Parse and validate request
 	var req models.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode register request", "error", err)
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: "Invalid request format",
-		})
+		h.logger.WithContext(r.Context()).Error("Failed to decode register request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
 		return
 	}
 
 	// Validate request
 	if err := h.validator.Validate(req); err != nil {
-		h.logger.Info("Invalid register request", "error", err)
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: err.Error(),
-		})
+		h.logger.WithContext(r.Context()).Info("Invalid register request", "error", err)
+		sendValidationError(w, r, err)
 		return
 	}
 
 	// Call service
 	resp, err := h.service.Register(r.Context(), &req)
 	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			sendValidationError(w, r, err)
+			return
+		}
+		if errors.Is(err, ErrEmailTaken) {
+			sendError(w, r, errcode.Conflict, i18n.T(r, "auth.email_taken"))
+			return
+		}
+		if errors.Is(err, ErrUsernameTaken) {
+			sendError(w, r, errcode.Conflict, i18n.T(r, "auth.username_taken"))
+			return
+		}
 		if errors.Is(err, ErrUserAlreadyExists) {
-			sendJSON(w, http.StatusConflict, models.ErrorResponse{
-				Code:    1000,
-				Message: "Email or username already exists",
-			})
+			sendError(w, r, errcode.Conflict, i18n.T(r, "auth.user_exists"))
+			return
+		}
+		if errors.Is(err, ErrDirectoryManaged) {
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.directory_managed"))
+			return
+		}
+		if sendConstraintError(w, r, err) {
 			return
 		}
-		h.logger.Error("Failed to register user", "error", err)
-		sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Code:    1009,
-			Message: "Failed to register user",
-		})
+		h.logger.WithContext(r.Context()).Error("Failed to register user", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "auth.register_failed"))
 		return
 	}
 
@@ -78,47 +91,47 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	// Parse request
 	var req models.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode login request", "error", err)
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: "Invalid request format",
-		})
+		h.logger.WithContext(r.Context()).Error("Failed to decode login request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
 		return
 	}
 
 	// Validate request
 	if err := h.validator.Validate(req); err != nil {
-		h.logger.Info("Invalid login request", "error", err)
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: err.Error(),
-		})
+		h.logger.WithContext(r.Context()).Info("Invalid login request", "error", err)
+		sendValidationError(w, r, err)
 		return
 	}
 
 	// Get client IP and user agent
 	userAgent := r.UserAgent()
-	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		clientIP = r.RemoteAddr
-	}
+	clientIP := ClientIP(r)
 
 	// Call service
 	resp, err := h.service.Login(r.Context(), &req, userAgent, clientIP)
 	if err != nil {
 		if errors.Is(err, ErrInvalidCredentials) {
-			h.logger.Info("Invalid credentials", "email", req.Email)
-			sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-				Code:    1008,
-				Message: "Invalid email or password",
-			})
+			h.logger.WithContext(r.Context()).Info("Invalid credentials", "email", req.Email)
+			sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.invalid_credentials"))
+			return
+		}
+		if errors.Is(err, ErrUserBanned) {
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.banned"))
 			return
 		}
-		h.logger.Error("Failed to login user", "error", err)
-		sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Code:    1009,
-			Message: "Failed to login user",
-		})
+		if errors.Is(err, ErrNotWorkspaceMember) {
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "workspace.not_member"))
+			return
+		}
+		if errors.Is(err, ErrTooManySessions) {
+			sendError(w, r, errcode.Conflict, i18n.T(r, "auth.too_many_sessions"))
+			return
+		}
+		if sendConstraintError(w, r, err) {
+			return
+		}
+		h.logger.WithContext(r.Context()).Error("Failed to login user", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "auth.login_failed"))
 		return
 	}
 
@@ -131,46 +144,46 @@ func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
 	// Parse request
 	var req models.RefreshRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode refresh request", "error", err)
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: "Invalid request format",
-		})
+		h.logger.WithContext(r.Context()).Error("Failed to decode refresh request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
 		return
 	}
 
 	// Validate request
 	if err := h.validator.Validate(req); err != nil {
-		h.logger.Info("Invalid refresh request", "error", err)
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: err.Error(),
-		})
+		h.logger.WithContext(r.Context()).Info("Invalid refresh request", "error", err)
+		sendValidationError(w, r, err)
 		return
 	}
 
 	// Get client IP and user agent
 	userAgent := r.UserAgent()
-	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		clientIP = r.RemoteAddr
-	}
+	clientIP := ClientIP(r)
 
 	// Call service
 	resp, err := h.service.Refresh(r.Context(), &req, userAgent, clientIP)
 	if err != nil {
-		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenExpired) {
-			sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-				Code:    1008,
-				Message: err.Error(),
-			})
+		if errors.Is(err, ErrTokenExpired) {
+			sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.token_expired"))
+			return
+		}
+		if errors.Is(err, ErrInvalidToken) {
+			sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.invalid_token"))
+			return
+		}
+		if errors.Is(err, ErrUserBanned) {
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.banned"))
 			return
 		}
-		h.logger.Error("Failed to refresh token", "error", err)
-		sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Code:    1009,
-			Message: "Failed to refresh token",
-		})
+		if errors.Is(err, ErrNotWorkspaceMember) {
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "workspace.not_member"))
+			return
+		}
+		if sendConstraintError(w, r, err) {
+			return
+		}
+		h.logger.WithContext(r.Context()).Error("Failed to refresh token", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "auth.refresh_failed"))
 		return
 	}
 
@@ -183,20 +196,14 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	// Extract token from Authorization header
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-			Code:    1008,
-			Message: "Authentication required",
-		})
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
 		return
 	}
 
 	// Check header format
 	fields := strings.Fields(authHeader)
 	if len(fields) != 2 || fields[0] != "Bearer" {
-		sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-			Code:    1008,
-			Message: "Invalid authorization header format",
-		})
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.invalid_header"))
 		return
 	}
 
@@ -204,20 +211,161 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	err := h.service.Logout(r.Context(), fields[1])
 	if err != nil {
 		if errors.Is(err, ErrInvalidToken) {
-			sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-				Code:    1008,
-				Message: "Invalid token",
-			})
+			sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.invalid_token"))
+			return
+		}
+		h.logger.WithContext(r.Context()).Error("Failed to logout user", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "auth.logout_failed"))
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll handles requests to log out of every device
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userIDStr, err := GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	err = h.service.LogoutAll(r.Context(), userID)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to logout user from all devices", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "auth.logout_all_failed"))
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePassword handles requests to change the user's password
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userIDStr, err := GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Parse and validate request
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode change password request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		h.logger.WithContext(r.Context()).Info("Invalid change password request", "error", err)
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	err = h.service.ChangePassword(r.Context(), userID, &req)
+	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			sendValidationError(w, r, err)
+			return
+		}
+		if errors.Is(err, ErrWrongPassword) {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "auth.wrong_password"))
+			return
+		}
+		if errors.Is(err, ErrDirectoryManaged) {
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.directory_managed"))
 			return
 		}
-		h.logger.Error("Failed to logout user", "error", err)
-		sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Code:    1009,
-			Message: "Failed to logout user",
-		})
+		if sendConstraintError(w, r, err) {
+			return
+		}
+		h.logger.WithContext(r.Context()).Error("Failed to change password", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "auth.password_change_failed"))
 		return
 	}
 
 	// Send response
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetLogins handles requests to review the user's recent logins
+func (h *Handler) GetLogins(w http.ResponseWriter, r *http.Request) {
+	userIDStr, err := GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Parse query parameters
+	limit := pagination.ParseLimit(r.URL.Query().Get("limit"), DefaultLoginHistoryLimit, MaxLoginHistoryLimit)
+
+	// Call service
+	resp, err := h.service.GetLoginHistory(r.Context(), userID, limit)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get login history", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "auth.logins_failed"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
+
+// sendConstraintError responds to the errors the repository maps from
+// constraint violations, reporting whether err was one of them
+func sendConstraintError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+	case errors.Is(err, ErrInvalidData):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_value"))
+	default:
+		return false
+	}
+	return true
+}
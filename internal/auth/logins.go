@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/google/uuid"
+)
+
+// Login history limits
+const (
+	DefaultLoginHistoryLimit = 20
+	MaxLoginHistoryLimit     = 100
+)
+
+// Notifier pushes real-time events to connected users
+type Notifier interface {
+	SendToUser(userID uuid.UUID, message *models.WebSocketMessage) bool
+}
+
+// OfflineNotifier notifies users through channels such as email
+type OfflineNotifier interface {
+	Dispatch(ctx context.Context, notification *models.Notification)
+}
+
+// GeoLocator finds the country an IP address is in
+type GeoLocator interface {
+	Country(ip string) string
+}
+
+// recordLogin adds a successful login to the user's history and alerts the
+// user if it came from a device, IP address or country they have not logged
+// in from before. A user's first login is never treated as suspicious.
+// Failures are logged rather than failing the login.
+func (s *AuthService) recordLogin(ctx context.Context, user *models.User, userAgent, clientIP string) {
+	login := &models.LoginRecord{
+		UserID:    user.ID,
+		UserAgent: userAgent,
+		ClientIP:  clientIP,
+	}
+	if s.geo != nil {
+		login.Country = s.geo.Country(clientIP)
+	}
+
+	familiarity, err := s.repo.GetLoginFamiliarity(ctx, user.ID, userAgent, clientIP, login.Country)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check login history", "error", err, "user_id", user.ID)
+		return
+	}
+	if familiarity.PreviousLogins > 0 {
+		login.NewDevice = !familiarity.KnownDevice
+		login.NewIP = !familiarity.KnownIP
+		login.NewCountry = login.Country != "" && !familiarity.KnownCountry
+	}
+
+	if err := s.repo.RecordLogin(ctx, login); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to record login", "error", err, "user_id", user.ID)
+		return
+	}
+
+	if login.Suspicious() {
+		s.sendSecurityAlert(ctx, user, login)
+	}
+}
+
+// sendSecurityAlert tells the user about a login from somewhere new, both
+// on their open connections and by email, since an attacker holding the
+// account may be the only one connected
+func (s *AuthService) sendSecurityAlert(ctx context.Context, user *models.User, login *models.LoginRecord) {
+	reason := loginAlertReason(login)
+	s.logger.WithContext(ctx).Warn("Login from a new location", "user_id", user.ID, "reason", reason, "client_ip", login.ClientIP)
+
+	if s.notifier != nil {
+		s.notifier.SendToUser(user.ID, &models.WebSocketMessage{
+			Type: protocol.TypeSecurityAlert,
+			Data: models.SecurityAlertData{
+				Reason: reason,
+				Login:  *login,
+			},
+		})
+	}
+	if s.offline != nil {
+		s.offline.Dispatch(ctx, &models.Notification{
+			UserID: user.ID,
+			Type:   models.NotificationSecurityAlert,
+			Body:   "New login to your account from " + reason,
+		})
+	}
+}
+
+// loginAlertReason describes what was new about a login
+func loginAlertReason(login *models.LoginRecord) string {
+	var reasons []string
+	if login.NewDevice {
+		reasons = append(reasons, "a new device")
+	}
+	if login.NewIP {
+		reasons = append(reasons, "a new IP address")
+	}
+	if login.NewCountry {
+		reasons = append(reasons, "a new country ("+login.Country+")")
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// GetLoginHistory returns the user's most recent logins, newest first
+func (s *AuthService) GetLoginHistory(ctx context.Context, userID uuid.UUID, limit int) (*models.LoginHistoryResponse, error) {
+	logins, err := s.repo.GetLoginHistory(ctx, userID, limit)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get login history", "error", err)
+		return nil, err
+	}
+
+	if logins == nil {
+		logins = []models.LoginRecord{}
+	}
+
+	return &models.LoginHistoryResponse{Logins: logins}, nil
+}
@@ -5,12 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
 	"github.com/codingminions/Whatsapp-Lite/pkg/token"
+	"github.com/google/uuid"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -22,16 +27,26 @@ const UserIDKey contextKey = "user_id"
 // UsernameKey is the key for username in context
 const UsernameKey contextKey = "username"
 
+// WorkspaceIDKey is the key for the workspace ID in context, present only
+// for tokens issued for a workspace
+const WorkspaceIDKey contextKey = "workspace_id"
+
+// ClaimsKey is the key for the claims of the request's access token in
+// context
+const ClaimsKey contextKey = "claims"
+
 // AuthMiddleware struct holds dependencies for the auth middleware
 type AuthMiddleware struct {
 	tokenMaker token.Maker
+	repo       Repository
 	logger     logger.Logger
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(tokenMaker token.Maker, logger logger.Logger) *AuthMiddleware {
+func NewAuthMiddleware(tokenMaker token.Maker, repo Repository, logger logger.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
 		tokenMaker: tokenMaker,
+		repo:       repo,
 		logger:     logger,
 	}
 }
@@ -42,53 +57,167 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		// Get token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-				Code:    1008,
-				Message: "Authentication required",
-			})
-			m.logger.Info("Authentication failed: no token provided")
+			sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+			m.logger.WithContext(r.Context()).Info("Authentication failed: no token provided")
 			return
 		}
 
 		// Check if the header starts with "Bearer "
 		fields := strings.Fields(authHeader)
 		if len(fields) != 2 || fields[0] != "Bearer" {
-			sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-				Code:    1008,
-				Message: "Invalid authorization header format",
-			})
-			m.logger.Info("Authentication failed: invalid header format")
+			sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.invalid_header"))
+			m.logger.WithContext(r.Context()).Info("Authentication failed: invalid header format")
 			return
 		}
 
 		// Verify token
-		payload, err := m.tokenMaker.VerifyToken(fields[1])
+		payload, err := m.VerifyToken(r.Context(), fields[1])
 		if err != nil {
-			var vErr token.ValidationError
-			if errors.As(err, &vErr) {
-				sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-					Code:    1008,
-					Message: vErr.Error(),
-				})
-			} else {
-				sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-					Code:    1008,
-					Message: "Invalid token",
-				})
+			switch {
+			case errors.Is(err, token.ErrExpiredToken):
+				sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.token_expired"))
+			case errors.Is(err, ErrTokenRevoked):
+				sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.token_revoked"))
+			case errors.Is(err, ErrUserBanned):
+				sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.banned"))
+			case errors.Is(err, ErrInvalidToken), errors.As(err, new(token.ValidationError)):
+				sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.invalid_token"))
+			default:
+				m.logger.WithContext(r.Context()).Error("Failed to verify token", "error", err)
+				sendError(w, r, errcode.Internal, i18n.T(r, "auth.verify_failed"))
+				return
 			}
-			m.logger.Info("Authentication failed: invalid token", "error", err)
+			m.logger.WithContext(r.Context()).Info("Authentication failed: invalid token", "error", err)
+			return
+		}
+		if !payload.HasScope(token.ScopeAPI) {
+			m.logger.WithContext(r.Context()).Info("Authentication failed: token lacks the API scope", "user_id", payload.UserID)
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.insufficient_scope"))
 			return
 		}
 
 		// Add user info to context
 		ctx := context.WithValue(r.Context(), UserIDKey, payload.UserID)
 		ctx = context.WithValue(ctx, UsernameKey, payload.Username)
+		if payload.WorkspaceID != "" {
+			ctx = context.WithValue(ctx, WorkspaceIDKey, payload.WorkspaceID)
+		}
+		ctx = context.WithValue(ctx, ClaimsKey, payload.Claims)
 
 		// Call the next handler with the updated context
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// VerifyToken checks an access token's signature and expiry, that it was
+// issued after the user's tokens were last revoked, that the session it was
+// issued for has not ended and that the user is not banned. Token timestamps have second precision, so a token issued in the
+// same second as a revocation is rejected too.
+func (m *AuthMiddleware) VerifyToken(ctx context.Context, tokenStr string) (*token.Payload, error) {
+	payload, err := m.tokenMaker.VerifyToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.Parse(payload.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if payload.WorkspaceID != "" {
+		if _, err := uuid.Parse(payload.WorkspaceID); err != nil {
+			return nil, ErrInvalidToken
+		}
+	}
+	var sessionID uuid.UUID
+	if payload.SessionID != "" {
+		if sessionID, err = uuid.Parse(payload.SessionID); err != nil {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	status, err := m.repo.GetTokenStatus(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if status.TokensRevokedAt != nil && !payload.IssuedAt.After(*status.TokensRevokedAt) {
+		return nil, ErrTokenRevoked
+	}
+	if status.Ban.Active(time.Now()) {
+		return nil, ErrUserBanned
+	}
+
+	// Tokens that predate session claims are only ended by revocation
+	if payload.SessionID != "" {
+		active, err := m.repo.SessionExists(ctx, userID, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	return payload, nil
+}
+
+// RequireAdmin middleware restricts a handler to admin users. It must be
+// wrapped by Authenticate.
+func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userIDStr, err := GetUserID(r.Context())
+		if err != nil {
+			sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+			return
+		}
+
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			sendError(w, r, errcode.Unauthenticated, i18n.T(r, "user.invalid_id"))
+			return
+		}
+
+		// Tokens issued without the admin role are turned away without a
+		// lookup. Tokens that predate role claims carry no roles.
+		if claims, ok := GetClaims(r.Context()); ok && len(claims.Roles) > 0 && !claims.HasRole(models.RoleAdmin) {
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.admin_required"))
+			return
+		}
+
+		// Roles are looked up on every request so revoking admin takes effect immediately
+		user, err := m.repo.GetUserByID(r.Context(), userID)
+		if err != nil || user.Role != models.RoleAdmin {
+			m.logger.WithContext(r.Context()).Info("Admin access denied", "user_id", userIDStr)
+			sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.admin_required"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsAdminToken reports whether an access token is valid and belongs to an
+// admin user
+func (m *AuthMiddleware) IsAdminToken(ctx context.Context, tokenStr string) bool {
+	payload, err := m.VerifyToken(ctx, tokenStr)
+	if err != nil {
+		return false
+	}
+	if len(payload.Roles) > 0 && !payload.HasRole(models.RoleAdmin) {
+		return false
+	}
+
+	userID, err := uuid.Parse(payload.UserID)
+	if err != nil {
+		return false
+	}
+
+	user, err := m.repo.GetUserByID(ctx, userID)
+	return err == nil && user.Role == models.RoleAdmin
+}
+
 // GetUserID extracts the user ID from the request context
 func GetUserID(ctx context.Context) (string, error) {
 	userID, ok := ctx.Value(UserIDKey).(string)
@@ -98,6 +227,32 @@ func GetUserID(ctx context.Context) (string, error) {
 	return userID, nil
 }
 
+// ClientIP returns the IP address a request came from. Every handler that
+// records or shows client addresses uses it, so how they are found is
+// decided in one place.
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// GetClaims extracts the claims of the access token from the request
+// context
+func GetClaims(ctx context.Context) (token.Claims, bool) {
+	claims, ok := ctx.Value(ClaimsKey).(token.Claims)
+	return claims, ok
+}
+
+// GetSessionID extracts the ID of the session the access token was issued
+// for from the request context. It is empty for tokens that predate
+// session claims.
+func GetSessionID(ctx context.Context) string {
+	claims, _ := GetClaims(ctx)
+	return claims.SessionID
+}
+
 // GetUsername extracts the username from the request context
 func GetUsername(ctx context.Context) (string, error) {
 	username, ok := ctx.Value(UsernameKey).(string)
@@ -107,6 +262,17 @@ func GetUsername(ctx context.Context) (string, error) {
 	return username, nil
 }
 
+// GetWorkspaceID extracts the workspace ID from the request context,
+// returning uuid.Nil for tokens issued outside workspaces
+func GetWorkspaceID(ctx context.Context) uuid.UUID {
+	workspaceID, _ := ctx.Value(WorkspaceIDKey).(string)
+	id, err := uuid.Parse(workspaceID)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
 // sendJSON sends a JSON response
 func sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
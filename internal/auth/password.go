@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/password"
+)
+
+// checkPassword checks a new password against the password policy. If the
+// breached password lookup fails the password is accepted, so an outage of
+// the lookup service does not block sign-ups.
+func (s *AuthService) checkPassword(ctx context.Context, field, pw string) error {
+	err := s.passwords.Validate(ctx, field, pw)
+	if err == nil {
+		return nil
+	}
+
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		s.logger.WithContext(ctx).Info("Password rejected by policy", "rules", policyErr.Rules)
+		return err
+	}
+
+	s.logger.WithContext(ctx).Warn("Skipped breached password check", "error", err)
+	return nil
+}
+
+// ChangePassword replaces the user's password after checking the current one
+func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, req *models.ChangePasswordRequest) error {
+	if s.directory != nil {
+		return ErrDirectoryManaged
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get user by ID", "error", err)
+		return err
+	}
+
+	// Check the current password
+	match, _, err := s.verifyPassword(user, req.CurrentPassword)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to verify password", "error", err, "user_id", userID)
+		return err
+	}
+	if !match {
+		s.logger.WithContext(ctx).Info("Wrong current password during password change", "user_id", userID)
+		return ErrWrongPassword
+	}
+
+	// Check the new password against the policy
+	if err := s.checkPassword(ctx, "new_password", req.NewPassword); err != nil {
+		return err
+	}
+
+	if err := s.hashPassword(user, req.NewPassword); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to hash password", "error", err)
+		return err
+	}
+
+	err = s.repo.UpdatePassword(ctx, user)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to update password", "error", err)
+		return err
+	}
+
+	s.logger.WithContext(ctx).Info("Password changed", "user_id", userID)
+	return nil
+}
+
+// hashPassword peppers and hashes a new password, setting the user's hash
+// and the algorithm and pepper it was made with
+func (s *AuthService) hashPassword(user *models.User, pw string) error {
+	peppered, err := s.peppers.Apply(s.peppers.Current(), pw)
+	if err != nil {
+		return err
+	}
+
+	hash, err := s.hasher.Hash(peppered)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+	user.PasswordAlgorithm = s.hasher.Algorithm()
+	user.PasswordPepperID = s.peppers.Current()
+	return nil
+}
+
+// verifyPassword reports whether pw is the user's password and, if so,
+// whether the hash is out of date and should be replaced
+func (s *AuthService) verifyPassword(user *models.User, pw string) (match, rehash bool, err error) {
+	peppered, err := s.peppers.Apply(user.PasswordPepperID, pw)
+	if err != nil {
+		return false, false, err
+	}
+
+	match, rehash, err = s.hasher.Verify(peppered, user.PasswordHash, user.PasswordAlgorithm)
+	if err != nil || !match {
+		return false, false, err
+	}
+
+	return true, rehash || user.PasswordPepperID != s.peppers.Current(), nil
+}
+
+// rehashPassword replaces a hash made with an outdated algorithm, parameters
+// or pepper after the user logged in with the password. Failures are
+// logged and the old hash is kept until the next login.
+func (s *AuthService) rehashPassword(ctx context.Context, user *models.User, pw string) {
+	if err := s.hashPassword(user, pw); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to rehash password", "error", err, "user_id", user.ID)
+		return
+	}
+
+	err := s.repo.UpdatePassword(ctx, user)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save rehashed password", "error", err, "user_id", user.ID)
+		return
+	}
+
+	s.logger.WithContext(ctx).Info("Rehashed password", "user_id", user.ID, "algorithm", user.PasswordAlgorithm, "pepper_id", user.PasswordPepperID)
+}
@@ -2,22 +2,44 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
 )
 
-// Repository errors
+// Repository errors. ErrEmailTaken and ErrUsernameTaken wrap
+// ErrUserAlreadyExists, so callers that do not care which field conflicts
+// can check for that.
 var (
 	ErrUserNotFound      = errors.New("user not found")
 	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrEmailTaken        = fmt.Errorf("%w: email is already registered", ErrUserAlreadyExists)
+	ErrUsernameTaken     = fmt.Errorf("%w: username is already taken", ErrUserAlreadyExists)
 	ErrSessionNotFound   = errors.New("session not found")
+	ErrInvalidData       = errors.New("value is not allowed")
 )
 
+// uniqueViolation is the PostgreSQL error code for unique constraint
+// violations
+const uniqueViolation = "23505"
+
+// Unique constraints on the users table
+const (
+	usersEmailKey    = "users_email_key"
+	usersUsernameKey = "users_username_key"
+)
+
+// sessionsWorkspaceKey is the foreign key from sessions to the workspace
+// they were opened in
+const sessionsWorkspaceKey = "sessions_workspace_id_fkey"
+
 // Repository interface for auth operations
 type Repository interface {
 	CreateUser(ctx context.Context, user *models.User) error
@@ -27,44 +49,72 @@ type Repository interface {
 	GetSessionByRefreshToken(ctx context.Context, refreshToken string) (*models.Session, error)
 	DeleteSession(ctx context.Context, refreshToken string) error
 	DeleteUserSessions(ctx context.Context, userID uuid.UUID) error
+	DeleteUserSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+	SessionExists(ctx context.Context, userID, sessionID uuid.UUID) (bool, error)
+	LockUser(ctx context.Context, userID uuid.UUID) error
+	CountActiveSessions(ctx context.Context, userID uuid.UUID) (int, error)
+	DeleteOldestSessions(ctx context.Context, userID uuid.UUID, keep int) ([]uuid.UUID, error)
 	UpdateUserStatus(ctx context.Context, userID uuid.UUID, status string) error
+	UpdateDisplayName(ctx context.Context, userID uuid.UUID, displayName string) error
+	UpdatePassword(ctx context.Context, user *models.User) error
+	RevokeTokens(ctx context.Context, userID uuid.UUID, at time.Time) error
+	GetTokenStatus(ctx context.Context, userID uuid.UUID) (*models.TokenStatus, error)
+	GetLoginFamiliarity(ctx context.Context, userID uuid.UUID, userAgent, clientIP, country string) (*LoginFamiliarity, error)
+	RecordLogin(ctx context.Context, login *models.LoginRecord) error
+	GetLoginHistory(ctx context.Context, userID uuid.UUID, limit int) ([]models.LoginRecord, error)
 }
 
 // PostgresRepository implements Repository interface with PostgreSQL
 type PostgresRepository struct {
-	db *sqlx.DB
+	db *database.DB
 }
 
 // NewPostgresRepository creates a new PostgreSQL repository
-func NewPostgresRepository(db *sqlx.DB) *PostgresRepository {
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
 	return &PostgresRepository{db: db}
 }
 
-// CreateUser creates a new user in the database
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// CreateUser creates a new user in the database. Users who signed up with
+// a phone number have no email.
 func (r *PostgresRepository) CreateUser(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (username, email, password_hash, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (username, email, password_hash, password_algorithm, password_pepper_id, status, created_at, updated_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8)
 		RETURNING id 
 		`
 
-	err := r.db.QueryRowContext(
+	err := r.conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		user.Username,
 		user.Email,
 		user.PasswordHash,
+		user.PasswordAlgorithm,
+		user.PasswordPepperID,
 		user.Status,
 		user.CreatedAt,
 		user.UpdatedAt,
 	).Scan(&user.ID)
 
 	if err != nil {
-		// Check if the error is due to unique constraint violation
-		if err.Error() == "pq: duplicate key value violates unique constraint" {
+		// Map unique constraint violations to the field that conflicts
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			switch pqErr.Constraint {
+			case usersEmailKey:
+				return ErrEmailTaken
+			case usersUsernameKey:
+				return ErrUsernameTaken
+			}
 			return ErrUserAlreadyExists
 		}
-		return err
+		return mapConstraintError(err)
 	}
 
 	return nil
@@ -73,13 +123,14 @@ func (r *PostgresRepository) CreateUser(ctx context.Context, user *models.User)
 // GetUserByEmail retrieves a user by email
 func (r *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, status, created_at, updated_at
+		SELECT id, username, display_name, COALESCE(email, '') AS email, password_hash, password_algorithm, password_pepper_id, status, role,
+		       banned_at, banned_until, ban_reason, created_at, updated_at
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND erased_at IS NULL
 	`
 
 	var user models.User
-	err := r.db.GetContext(ctx, &user, query, email)
+	err := r.conn(ctx).GetContext(ctx, &user, query, email)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -90,13 +141,14 @@ func (r *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (
 // GetUserByID retrieves a user by ID
 func (r *PostgresRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, status, created_at, updated_at
+		SELECT id, username, display_name, COALESCE(email, '') AS email, password_hash, password_algorithm, password_pepper_id, status, role,
+		       banned_at, banned_until, ban_reason, created_at, updated_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND erased_at IS NULL
 	`
 
 	var user models.User
-	err := r.db.GetContext(ctx, &user, query, id)
+	err := r.conn(ctx).GetContext(ctx, &user, query, id)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
@@ -107,12 +159,12 @@ func (r *PostgresRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*mo
 // CreateSession creates a new session in the database
 func (r *PostgresRepository) CreateSession(ctx context.Context, session *models.Session) error {
 	query := `
-		INSERT INTO sessions (user_id, refresh_token, user_agent, client_ip, expires_at, created_at, last_active_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO sessions (user_id, refresh_token, user_agent, client_ip, expires_at, created_at, last_active_at, workspace_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id
 	`
 
-	err := r.db.QueryRowContext(
+	err := r.conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		session.UserID,
@@ -122,10 +174,11 @@ func (r *PostgresRepository) CreateSession(ctx context.Context, session *models.
 		session.ExpiresAt,
 		session.CreatedAt,
 		session.LastActiveAt,
+		session.WorkspaceID,
 	).Scan(&session.ID)
 
 	if err != nil {
-		return err
+		return mapConstraintError(err)
 	}
 
 	return nil
@@ -134,13 +187,13 @@ func (r *PostgresRepository) CreateSession(ctx context.Context, session *models.
 // GetSessionByRefreshToken retrieves a session by refresh token
 func (r *PostgresRepository) GetSessionByRefreshToken(ctx context.Context, refreshToken string) (*models.Session, error) {
 	query := `
-		SELECT id, user_id, refresh_token, user_agent, client_ip, expires_at, created_at, last_active_at
+		SELECT id, user_id, refresh_token, user_agent, client_ip, expires_at, created_at, last_active_at, workspace_id
 		FROM sessions
 		WHERE refresh_token = $1
 	`
 
 	var session models.Session
-	err := r.db.GetContext(ctx, &session, query, refreshToken)
+	err := r.conn(ctx).GetContext(ctx, &session, query, refreshToken)
 	if err != nil {
 		return nil, ErrSessionNotFound
 	}
@@ -155,7 +208,7 @@ func (r *PostgresRepository) DeleteSession(ctx context.Context, refreshToken str
 		WHERE refresh_token = $1
 	`
 
-	_, err := r.db.ExecContext(ctx, query, refreshToken)
+	_, err := r.conn(ctx).ExecContext(ctx, query, refreshToken)
 	return err
 }
 
@@ -166,10 +219,89 @@ func (r *PostgresRepository) DeleteUserSessions(ctx context.Context, userID uuid
 		WHERE user_id = $1
 	`
 
-	_, err := r.db.ExecContext(ctx, query, userID)
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID)
+	return err
+}
+
+// DeleteUserSession deletes one of a user's sessions
+func (r *PostgresRepository) DeleteUserSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	query := `
+		DELETE FROM sessions
+		WHERE user_id = $1 AND id = $2
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, sessionID)
+	return err
+}
+
+// DeleteExpiredSessions deletes all sessions whose refresh token has expired
+func (r *PostgresRepository) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM sessions
+		WHERE expires_at < NOW()
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SessionExists reports whether one of a user's sessions has not been
+// ended
+func (r *PostgresRepository) SessionExists(ctx context.Context, userID, sessionID uuid.UUID) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM sessions WHERE id = $1 AND user_id = $2)"
+
+	var exists bool
+	err := r.conn(ctx).GetContext(ctx, &exists, query, sessionID, userID)
+	return exists, err
+}
+
+// LockUser locks a user's row until the end of the transaction bound to
+// ctx, serializing changes to the user's sessions
+func (r *PostgresRepository) LockUser(ctx context.Context, userID uuid.UUID) error {
+	var id uuid.UUID
+	err := r.conn(ctx).GetContext(ctx, &id, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
 	return err
 }
 
+// CountActiveSessions returns the number of a user's unexpired sessions
+func (r *PostgresRepository) CountActiveSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+	`
+
+	var count int
+	err := r.conn(ctx).GetContext(ctx, &count, query, userID)
+	return count, err
+}
+
+// DeleteOldestSessions deletes a user's unexpired sessions except the keep
+// most recently created, and returns the IDs of those deleted
+func (r *PostgresRepository) DeleteOldestSessions(ctx context.Context, userID uuid.UUID, keep int) ([]uuid.UUID, error) {
+	query := `
+		DELETE FROM sessions
+		WHERE id IN (
+			SELECT id
+			FROM sessions
+			WHERE user_id = $1 AND expires_at > NOW()
+			ORDER BY created_at DESC
+			OFFSET $2
+		)
+		RETURNING id
+	`
+
+	var evicted []uuid.UUID
+	err := r.conn(ctx).SelectContext(ctx, &evicted, query, userID, keep)
+	return evicted, err
+}
+
 // UpdateUserStatus updates a user's status
 func (r *PostgresRepository) UpdateUserStatus(ctx context.Context, userID uuid.UUID, status string) error {
 	query := `
@@ -178,6 +310,152 @@ func (r *PostgresRepository) UpdateUserStatus(ctx context.Context, userID uuid.U
 		WHERE id = $3
 	`
 
-	_, err := r.db.ExecContext(ctx, query, status, time.Now(), userID)
+	_, err := r.conn(ctx).ExecContext(ctx, query, status, time.Now(), userID)
+	return mapConstraintError(err)
+}
+
+// UpdateDisplayName sets a user's display name
+func (r *PostgresRepository) UpdateDisplayName(ctx context.Context, userID uuid.UUID, displayName string) error {
+	query := `
+		UPDATE users
+		SET display_name = $1, updated_at = $2
+		WHERE id = $3
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, displayName, time.Now(), userID)
+	return mapConstraintError(err)
+}
+
+// UpdatePassword saves a user's password hash and the algorithm and pepper
+// it was made with
+func (r *PostgresRepository) UpdatePassword(ctx context.Context, user *models.User) error {
+	query := `
+		UPDATE users
+		SET password_hash = $1, password_algorithm = $2, password_pepper_id = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, user.PasswordHash, user.PasswordAlgorithm, user.PasswordPepperID, time.Now(), user.ID)
+	return mapConstraintError(err)
+}
+
+// RevokeTokens rejects the user's access tokens issued at or before a time
+func (r *PostgresRepository) RevokeTokens(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE users
+		SET tokens_revoked_at = $1, updated_at = $1
+		WHERE id = $2
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, at, userID)
 	return err
 }
+
+// GetTokenStatus returns when the user's access tokens were last revoked
+// and whether they are banned
+func (r *PostgresRepository) GetTokenStatus(ctx context.Context, userID uuid.UUID) (*models.TokenStatus, error) {
+	query := `
+		SELECT tokens_revoked_at, banned_at, banned_until, ban_reason
+		FROM users
+		WHERE id = $1
+	`
+
+	var status models.TokenStatus
+	err := r.conn(ctx).GetContext(ctx, &status, query, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// LoginFamiliarity describes how a login compares with the user's earlier logins
+type LoginFamiliarity struct {
+	PreviousLogins int  `db:"previous_logins"`
+	KnownDevice    bool `db:"known_device"`
+	KnownIP        bool `db:"known_ip"`
+	KnownCountry   bool `db:"known_country"`
+}
+
+// GetLoginFamiliarity reports whether a user has logged in before from the
+// same device, IP address and country
+func (r *PostgresRepository) GetLoginFamiliarity(ctx context.Context, userID uuid.UUID, userAgent, clientIP, country string) (*LoginFamiliarity, error) {
+	query := `
+		SELECT
+			COUNT(*) AS previous_logins,
+			COALESCE(BOOL_OR(user_agent = $2), FALSE) AS known_device,
+			COALESCE(BOOL_OR(client_ip = $3), FALSE) AS known_ip,
+			COALESCE(BOOL_OR(country = $4), FALSE) AS known_country
+		FROM login_history
+		WHERE user_id = $1
+	`
+
+	var familiarity LoginFamiliarity
+	if err := r.conn(ctx).GetContext(ctx, &familiarity, query, userID, userAgent, clientIP, country); err != nil {
+		return nil, err
+	}
+
+	return &familiarity, nil
+}
+
+// RecordLogin adds a login to the user's history
+func (r *PostgresRepository) RecordLogin(ctx context.Context, login *models.LoginRecord) error {
+	query := `
+		INSERT INTO login_history (user_id, user_agent, client_ip, country, new_device, new_ip, new_country)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err := r.conn(ctx).GetContext(ctx, login, query,
+		login.UserID,
+		login.UserAgent,
+		login.ClientIP,
+		login.Country,
+		login.NewDevice,
+		login.NewIP,
+		login.NewCountry,
+	)
+	return mapConstraintError(err)
+}
+
+// mapConstraintError maps a statement rejected by a table constraint to the
+// domain error behind it: a user or workspace that no longer exists, or a
+// value outside a column's rules. Other errors are returned unchanged.
+func mapConstraintError(err error) error {
+	pqErr := database.ConstraintViolation(err)
+	if pqErr == nil {
+		return err
+	}
+
+	switch pqErr.Code {
+	case database.ForeignKeyViolation:
+		if pqErr.Constraint == sessionsWorkspaceKey {
+			return ErrNotWorkspaceMember
+		}
+		return ErrUserNotFound
+	case database.NotNullViolation, database.CheckViolation:
+		return fmt.Errorf("%w: %s", ErrInvalidData, database.ConstraintField(pqErr))
+	}
+	return err
+}
+
+// GetLoginHistory returns a user's most recent logins, newest first
+func (r *PostgresRepository) GetLoginHistory(ctx context.Context, userID uuid.UUID, limit int) ([]models.LoginRecord, error) {
+	query := `
+		SELECT id, user_id, user_agent, client_ip, country, new_device, new_ip, new_country, created_at
+		FROM login_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	var logins []models.LoginRecord
+	if err := r.conn(ctx).SelectContext(ctx, &logins, query, userID, limit); err != nil {
+		return nil, err
+	}
+
+	return logins, nil
+}
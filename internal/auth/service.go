@@ -2,14 +2,22 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/events"
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/password"
 	"github.com/codingminions/Whatsapp-Lite/pkg/token"
 )
 
@@ -18,31 +26,67 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrTokenExpired       = errors.New("token expired")
+	ErrTokenRevoked       = errors.New("token revoked")
+	ErrUserBanned         = errors.New("user is banned")
+	ErrWrongPassword      = errors.New("current password is incorrect")
+	ErrNotWorkspaceMember = errors.New("user is not a member of the workspace")
 )
 
+// WorkspaceMembership reports whether a user belongs to a workspace
+type WorkspaceMembership interface {
+	IsMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error)
+}
+
 // Service handles auth business logic
 type Service interface {
 	Register(ctx context.Context, req *models.RegisterRequest) (*models.UserResponse, error)
 	Login(ctx context.Context, req *models.LoginRequest, userAgent, clientIP string) (*models.LoginResponse, error)
 	Refresh(ctx context.Context, req *models.RefreshRequest, userAgent, clientIP string) (*models.RefreshResponse, error)
 	Logout(ctx context.Context, token string) error
+	LogoutAll(ctx context.Context, userID uuid.UUID) error
+	ChangePassword(ctx context.Context, userID uuid.UUID, req *models.ChangePasswordRequest) error
 	UpdateStatus(ctx context.Context, userID uuid.UUID, status string) error
+	GetLoginHistory(ctx context.Context, userID uuid.UUID, limit int) (*models.LoginHistoryResponse, error)
 }
 
 // AuthService implements Service interface
 type AuthService struct {
 	repo            Repository
+	uow             database.UnitOfWork
 	tokenMaker      token.Maker
+	events          events.Publisher
+	notifier        Notifier
+	connections     ConnectionCloser
+	offline         OfflineNotifier
+	workspaces      WorkspaceMembership
+	geo             GeoLocator // nil when no GeoIP database is configured
+	directory       Directory  // nil when accounts use local passwords
+	passwords       *password.Policy
+	hasher          *password.Hasher
+	peppers         *password.Peppers
+	config          configs.AuthConfig
 	logger          logger.Logger
 	accessDuration  time.Duration
 	refreshDuration time.Duration
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(repo Repository, tokenMaker token.Maker, logger logger.Logger, accessDuration, refreshDuration time.Duration) *AuthService {
+func NewAuthService(repo Repository, uow database.UnitOfWork, tokenMaker token.Maker, publisher events.Publisher, notifier Notifier, connections ConnectionCloser, offline OfflineNotifier, workspaces WorkspaceMembership, geo GeoLocator, directory Directory, passwords *password.Policy, hasher *password.Hasher, peppers *password.Peppers, config configs.AuthConfig, logger logger.Logger, accessDuration, refreshDuration time.Duration) *AuthService {
 	return &AuthService{
 		repo:            repo,
+		uow:             uow,
 		tokenMaker:      tokenMaker,
+		events:          publisher,
+		notifier:        notifier,
+		connections:     connections,
+		offline:         offline,
+		workspaces:      workspaces,
+		geo:             geo,
+		directory:       directory,
+		passwords:       passwords,
+		hasher:          hasher,
+		peppers:         peppers,
+		config:          config,
 		logger:          logger,
 		accessDuration:  accessDuration,
 		refreshDuration: refreshDuration,
@@ -51,35 +95,54 @@ func NewAuthService(repo Repository, tokenMaker token.Maker, logger logger.Logge
 
 // Register handles user registration
 func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest) (*models.UserResponse, error) {
-	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		s.logger.Error("Failed to hash password", "error", err)
+	if s.directory != nil {
+		return nil, ErrDirectoryManaged
+	}
+
+	// Check the password against the policy
+	if err := s.checkPassword(ctx, "password", req.Password); err != nil {
 		return nil, err
 	}
 
 	// Create user
 	now := time.Now()
 	user := &models.User{
-		Username:     req.Username,
-		Email:        req.Email,
-		PasswordHash: string(hashedPassword),
-		Status:       "offline",
-		CreatedAt:    now,
-		UpdatedAt:    now,
+		Username:  req.Username,
+		Email:     req.Email,
+		Status:    "offline",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// Hash the password
+	if err := s.hashPassword(user, req.Password); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to hash password", "error", err)
+		return nil, err
 	}
 
 	// Save to database
-	err = s.repo.CreateUser(ctx, user)
+	err := s.repo.CreateUser(ctx, user)
 	if err != nil {
 		if errors.Is(err, ErrUserAlreadyExists) {
-			s.logger.Info("User already exists", "email", req.Email)
-			return nil, ErrUserAlreadyExists
+			s.logger.WithContext(ctx).Info("User already exists", "email", req.Email, "error", err)
+			return nil, err
 		}
-		s.logger.Error("Failed to create user", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to create user", "error", err)
 		return nil, err
 	}
 
+	// Publish domain event
+	err = s.events.Publish(ctx, events.New(events.TypeUserRegistered, events.UserRegisteredData{
+		UserID:    user.ID.String(),
+		Username:  user.Username,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+	}))
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to publish user registered event", "error", err)
+		// Continue anyway, the user has been created
+	}
+
 	// Return user response
 	return &models.UserResponse{
 		ID:        user.ID,
@@ -89,61 +152,284 @@ func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest)
 	}, nil
 }
 
-// Login handles user login
+// Login handles user login, checking credentials against the directory if
+// one is configured
 func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, userAgent, clientIP string) (*models.LoginResponse, error) {
+	var user *models.User
+	var err error
+	if s.directory != nil {
+		user, err = s.directoryLogin(ctx, req)
+	} else {
+		user, err = s.passwordLogin(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Ban.Active(time.Now()) {
+		s.logger.WithContext(ctx).Info("Banned user tried to log in", "user_id", user.ID)
+		return nil, ErrUserBanned
+	}
+	if err := s.checkWorkspace(ctx, user.ID, req.WorkspaceID); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, user, req.WorkspaceID, userAgent, clientIP)
+}
+
+// passwordLogin checks a user's credentials against their stored password
+// hash and returns their account
+func (s *AuthService) passwordLogin(ctx context.Context, req *models.LoginRequest) (*models.User, error) {
 	// Find user
 	user, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, ErrUserNotFound) {
-			s.logger.Info("User not found during login", "email", req.Email)
+			s.logger.WithContext(ctx).Info("User not found during login", "email", req.Email)
 			return nil, ErrInvalidCredentials
 		}
-		s.logger.Error("Failed to get user by email", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to get user by email", "error", err)
 		return nil, err
 	}
 
 	// Check password
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
+	match, rehash, err := s.verifyPassword(user, req.Password)
 	if err != nil {
-		s.logger.Info("Invalid password", "email", req.Email)
+		s.logger.WithContext(ctx).Error("Failed to verify password", "error", err, "user_id", user.ID)
+		return nil, ErrInvalidCredentials
+	}
+	if !match {
+		s.logger.WithContext(ctx).Info("Invalid password", "email", req.Email)
 		return nil, ErrInvalidCredentials
 	}
+	if rehash {
+		s.rehashPassword(ctx, user, req.Password)
+	}
+
+	return user, nil
+}
 
-	// Create access token
-	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.ID.String(), user.Username, s.accessDuration)
+// LoginUser starts a session for a user who signed in with an external
+// identity provider rather than a password
+func (s *AuthService) LoginUser(ctx context.Context, userID uuid.UUID, userAgent, clientIP string) (*models.LoginResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to create access token", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to get user by ID", "error", err, "user_id", userID)
 		return nil, err
 	}
+	if user.Ban.Active(time.Now()) {
+		s.logger.WithContext(ctx).Info("Banned user tried to log in", "user_id", user.ID)
+		return nil, ErrUserBanned
+	}
 
-	// Create refresh token
-	refreshToken, err := s.createRefreshToken(ctx, user.ID, userAgent, clientIP)
+	return s.startSession(ctx, user, nil, userAgent, clientIP)
+}
+
+// ProvisionUser creates an account for a user signing in with an external
+// identity provider or directory for the first time. The account gets a
+// random password, so it can only be used through the provider. The
+// username is derived from the preferred one, or the email if there is
+// none, and a number is added to it if it is taken.
+func (s *AuthService) ProvisionUser(ctx context.Context, email, username string) (*models.User, error) {
+	username = provisionedUsername(username, email)
+
+	user, err := s.passwordlessUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		user.Username = username
+		if attempt > 0 {
+			user.Username = fmt.Sprintf("%s%d", username, mathrand.Intn(10000))
+		}
+		err = s.repo.CreateUser(ctx, user)
+		if !errors.Is(err, ErrUsernameTaken) {
+			break
+		}
+	}
+	if err != nil {
+		if !errors.Is(err, ErrUserAlreadyExists) {
+			s.logger.WithContext(ctx).Error("Failed to provision user", "error", err)
+		}
+		return nil, err
+	}
+
+	err = s.events.Publish(ctx, events.New(events.TypeUserRegistered, events.UserRegisteredData{
+		UserID:    user.ID.String(),
+		Username:  user.Username,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+	}))
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to publish user registered event", "error", err)
+	}
+
+	return user, nil
+}
+
+// RegisterPhoneUser creates an account for a user signing up with a phone
+// number. The account has no email and gets a random password, so it can
+// only be used by logging in with a code texted to the phone.
+func (s *AuthService) RegisterPhoneUser(ctx context.Context, username string) (*models.User, error) {
+	if s.directory != nil {
+		return nil, ErrDirectoryManaged
+	}
+
+	user, err := s.passwordlessUser(ctx, "")
 	if err != nil {
-		s.logger.Error("Failed to create refresh token", "error", err)
+		return nil, err
+	}
+	user.Username = username
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		if !errors.Is(err, ErrUsernameTaken) {
+			s.logger.WithContext(ctx).Error("Failed to create user", "error", err)
+		}
+		return nil, err
+	}
+
+	err = s.events.Publish(ctx, events.New(events.TypeUserRegistered, events.UserRegisteredData{
+		UserID:    user.ID.String(),
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+	}))
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to publish user registered event", "error", err)
+	}
+
+	return user, nil
+}
+
+// passwordlessUser returns a new user with a random password, for accounts
+// that are not used with a password
+func (s *AuthService) passwordlessUser(ctx context.Context, email string) (*models.User, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Email:     email,
+		Status:    "offline",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.hashPassword(user, hex.EncodeToString(secret)); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to hash password", "error", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// provisionedUsername returns a valid username made from the preferred
+// one, or the local part of the email if there is none
+func provisionedUsername(preferred, email string) string {
+	name := preferred
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	var sb strings.Builder
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			sb.WriteRune(r)
+		}
+	}
+	name = sb.String()
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	for len(name) < 3 {
+		name += "_"
+	}
+	return name
+}
+
+// startSession issues tokens to an authenticated user
+func (s *AuthService) startSession(ctx context.Context, user *models.User, workspaceID *uuid.UUID, userAgent, clientIP string) (*models.LoginResponse, error) {
+	// Make room for the new session and create it in one unit of work
+	var session *models.Session
+	var evicted []uuid.UUID
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		evicted, err = s.enforceSessionLimit(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		// Create refresh token
+		session, err = s.createRefreshToken(ctx, user.ID, workspaceID, userAgent, clientIP)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("Failed to create refresh token", "error", err)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.closeEvictedSessions(ctx, user.ID, evicted)
+
+	// Create access token for the session
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(accessClaims(user, workspaceID, session), s.accessDuration)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to create access token", "error", err)
 		return nil, err
 	}
 
 	// Update user status to online
 	err = s.repo.UpdateUserStatus(ctx, user.ID, "online")
 	if err != nil {
-		s.logger.Error("Failed to update user status", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to update user status", "error", err)
 		// Continue anyway, this shouldn't fail the login process
 	}
 
+	s.recordLogin(ctx, user, userAgent, clientIP)
+
 	return &models.LoginResponse{
 		UserID:       user.ID,
 		Username:     user.Username,
+		DisplayName:  user.DisplayName,
 		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		RefreshToken: session.RefreshToken,
 		ExpiresAt:    accessPayload.ExpiredAt,
+		WorkspaceID:  workspaceID,
 	}, nil
 }
 
-// createRefreshToken creates a new refresh token
-func (s *AuthService) createRefreshToken(ctx context.Context, userID uuid.UUID, userAgent, clientIP string) (string, error) {
+// checkWorkspace returns ErrNotWorkspaceMember unless workspaceID is nil or
+// a workspace the user belongs to
+func (s *AuthService) checkWorkspace(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID) error {
+	if workspaceID == nil {
+		return nil
+	}
+
+	isMember, err := s.workspaces.IsMember(ctx, *workspaceID, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check workspace membership", "error", err)
+		return err
+	}
+	if !isMember {
+		s.logger.WithContext(ctx).Info("User is not a member of the workspace", "user_id", userID, "workspace_id", *workspaceID)
+		return ErrNotWorkspaceMember
+	}
+	return nil
+}
+
+// workspaceClaim returns the workspace claim for tokens issued for a
+// workspace, empty outside workspaces
+func workspaceClaim(workspaceID *uuid.UUID) string {
+	if workspaceID == nil {
+		return ""
+	}
+	return workspaceID.String()
+}
+
+// createRefreshToken creates a new refresh token for a session in a
+// workspace, or outside workspaces if workspaceID is nil
+func (s *AuthService) createRefreshToken(ctx context.Context, userID uuid.UUID, workspaceID *uuid.UUID, userAgent, clientIP string) (*models.Session, error) {
 	refreshToken, err := token.GenerateRandomString(32)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Save session
@@ -155,14 +441,28 @@ func (s *AuthService) createRefreshToken(ctx context.Context, userID uuid.UUID,
 		ExpiresAt:    time.Now().Add(s.refreshDuration),
 		CreatedAt:    time.Now(),
 		LastActiveAt: time.Now(),
+		WorkspaceID:  workspaceID,
 	}
 
 	err = s.repo.CreateSession(ctx, session)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return refreshToken, nil
+	return session, nil
+}
+
+// accessClaims returns the claims of an access token issued to a user for
+// a session
+func accessClaims(user *models.User, workspaceID *uuid.UUID, session *models.Session) token.Claims {
+	return token.Claims{
+		UserID:      user.ID.String(),
+		Username:    user.Username,
+		WorkspaceID: workspaceClaim(workspaceID),
+		Roles:       []string{user.Role},
+		Scopes:      []string{token.ScopeAPI, token.ScopeWebSocket},
+		SessionID:   session.ID.String(),
+	}
 }
 
 // Refresh handles token refresh
@@ -171,51 +471,66 @@ func (s *AuthService) Refresh(ctx context.Context, req *models.RefreshRequest, u
 	session, err := s.repo.GetSessionByRefreshToken(ctx, req.RefreshToken)
 	if err != nil {
 		if errors.Is(err, ErrSessionNotFound) {
-			s.logger.Info("Session not found during refresh", "refresh_token", req.RefreshToken)
+			s.logger.WithContext(ctx).Info("Session not found during refresh", "refresh_token", req.RefreshToken)
 			return nil, ErrInvalidToken
 		}
-		s.logger.Error("Failed to get session by refresh token", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to get session by refresh token", "error", err)
 		return nil, err
 	}
 
 	// Check if expired
 	if time.Now().After(session.ExpiresAt) {
-		s.logger.Info("Refresh token expired", "user_id", session.UserID)
+		s.logger.WithContext(ctx).Info("Refresh token expired", "user_id", session.UserID)
 		return nil, ErrTokenExpired
 	}
 
 	// Get user
 	user, err := s.repo.GetUserByID(ctx, session.UserID)
 	if err != nil {
-		s.logger.Error("Failed to get user by ID", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to get user by ID", "error", err)
 		return nil, err
 	}
+	if user.Ban.Active(time.Now()) {
+		s.logger.WithContext(ctx).Info("Banned user tried to refresh", "user_id", user.ID)
+		return nil, ErrUserBanned
+	}
 
-	// Create new access token
-	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.ID.String(), user.Username, s.accessDuration)
-	if err != nil {
-		s.logger.Error("Failed to create new access token", "error", err)
+	// Keep the session's workspace unless switching to another. Membership
+	// is checked again so removed members lose access at their next refresh.
+	workspaceID := session.WorkspaceID
+	if req.WorkspaceID != nil {
+		workspaceID = req.WorkspaceID
+	}
+	if err := s.checkWorkspace(ctx, user.ID, workspaceID); err != nil {
 		return nil, err
 	}
 
 	// Delete old session
 	err = s.repo.DeleteSession(ctx, req.RefreshToken)
 	if err != nil {
-		s.logger.Error("Failed to delete old session", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to delete old session", "error", err)
 		// Continue anyway
 	}
 
 	// Create new refresh token
-	refreshToken, err := s.createRefreshToken(ctx, user.ID, userAgent, clientIP)
+	newSession, err := s.createRefreshToken(ctx, user.ID, workspaceID, userAgent, clientIP)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to create new refresh token", "error", err)
+		return nil, err
+	}
+
+	// Create new access token for the new session
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(accessClaims(user, workspaceID, newSession), s.accessDuration)
 	if err != nil {
-		s.logger.Error("Failed to create new refresh token", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to create new access token", "error", err)
 		return nil, err
 	}
 
 	return &models.RefreshResponse{
 		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		RefreshToken: newSession.RefreshToken,
 		ExpiresAt:    accessPayload.ExpiredAt,
+		WorkspaceID:  workspaceID,
 	}, nil
 }
 
@@ -224,28 +539,42 @@ func (s *AuthService) Logout(ctx context.Context, tokenStr string) error {
 	// Verify token
 	payload, err := s.tokenMaker.VerifyToken(tokenStr)
 	if err != nil {
-		s.logger.Info("Invalid token during logout", "error", err)
+		s.logger.WithContext(ctx).Info("Invalid token during logout", "error", err)
 		return ErrInvalidToken
 	}
 
 	// Parse user ID
 	userID, err := uuid.Parse(payload.UserID)
 	if err != nil {
-		s.logger.Error("Failed to parse user ID from token", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to parse user ID from token", "error", err)
 		return err
 	}
 
 	// Update user status to offline
 	err = s.repo.UpdateUserStatus(ctx, userID, "offline")
 	if err != nil {
-		s.logger.Error("Failed to update user status", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to update user status", "error", err)
 		// Continue anyway
 	}
 
+	// End the session the token was issued for, leaving the user's other
+	// devices signed in. Tokens that predate session claims end them all.
+	if payload.SessionID != "" {
+		sessionID, err := uuid.Parse(payload.SessionID)
+		if err != nil {
+			return ErrInvalidToken
+		}
+		if err := s.repo.DeleteUserSession(ctx, userID, sessionID); err != nil {
+			s.logger.WithContext(ctx).Error("Failed to delete session", "error", err)
+			return err
+		}
+		return nil
+	}
+
 	// Delete all user sessions
 	err = s.repo.DeleteUserSessions(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to delete user sessions", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to delete user sessions", "error", err)
 		return err
 	}
 
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrTooManySessions is returned when a login would exceed the session limit
+var ErrTooManySessions = errors.New("user has too many active sessions")
+
+// ConnectionCloser force-closes a user's live connections
+type ConnectionCloser interface {
+	DisconnectUser(userID uuid.UUID, reason string) int
+	DisconnectSession(userID uuid.UUID, sessionID, reason string) int
+}
+
+// SessionLimitPolicy decides what happens when a login would exceed the
+// maximum number of active sessions
+type SessionLimitPolicy string
+
+// Session limit policies
+const (
+	// SessionLimitReject refuses the new login
+	SessionLimitReject SessionLimitPolicy = "reject"
+	// SessionLimitEvictOldest ends the user's oldest sessions to make room
+	SessionLimitEvictOldest SessionLimitPolicy = "evict_oldest"
+)
+
+// ParseSessionLimitPolicy parses a policy name, defaulting to SessionLimitReject
+func ParseSessionLimitPolicy(name string) (SessionLimitPolicy, error) {
+	switch SessionLimitPolicy(name) {
+	case "", SessionLimitReject:
+		return SessionLimitReject, nil
+	case SessionLimitEvictOldest:
+		return SessionLimitEvictOldest, nil
+	default:
+		return "", fmt.Errorf("unknown session limit policy: %q", name)
+	}
+}
+
+// enforceSessionLimit makes room for a new session under the configured
+// limit, either by rejecting the login or by ending the oldest sessions,
+// and returns the IDs of the sessions it ended. It must run in the unit of
+// work that creates the session: the user's row stays locked until it
+// ends, so concurrent logins are counted one after another.
+func (s *AuthService) enforceSessionLimit(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	if s.config.MaxSessions <= 0 {
+		return nil, nil
+	}
+
+	if err := s.repo.LockUser(ctx, userID); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to lock user", "error", err)
+		return nil, err
+	}
+
+	if SessionLimitPolicy(s.config.SessionLimitPolicy) == SessionLimitEvictOldest {
+		evicted, err := s.repo.DeleteOldestSessions(ctx, userID, s.config.MaxSessions-1)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("Failed to evict sessions", "error", err)
+			return nil, err
+		}
+		return evicted, nil
+	}
+
+	active, err := s.repo.CountActiveSessions(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to count sessions", "error", err)
+		return nil, err
+	}
+	if active >= s.config.MaxSessions {
+		return nil, ErrTooManySessions
+	}
+	return nil, nil
+}
+
+// closeEvictedSessions closes the open connections of sessions ended to
+// make room for a new one. Their access tokens stop working with the
+// sessions, so the devices cannot reconnect.
+func (s *AuthService) closeEvictedSessions(ctx context.Context, userID uuid.UUID, evicted []uuid.UUID) {
+	if len(evicted) == 0 {
+		return
+	}
+
+	closed := 0
+	for _, sessionID := range evicted {
+		closed += s.connections.DisconnectSession(userID, sessionID.String(), "session limit reached")
+	}
+	s.logger.WithContext(ctx).Info("Evicted oldest sessions", "user_id", userID, "count", len(evicted), "connections_closed", closed)
+}
+
+// LogoutAll signs the user out of every device. Their sessions are deleted
+// so no refresh token can be used, access tokens issued so far are revoked
+// and their open WebSocket connections are closed.
+func (s *AuthService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	// Revoke access tokens first so closed connections cannot reconnect
+	err := s.repo.RevokeTokens(ctx, userID, time.Now())
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to revoke access tokens", "error", err)
+		return err
+	}
+
+	err = s.repo.DeleteUserSessions(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to delete user sessions", "error", err)
+		return err
+	}
+
+	err = s.repo.UpdateUserStatus(ctx, userID, "offline")
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to update user status", "error", err)
+		// Continue anyway
+	}
+
+	closed := s.connections.DisconnectUser(userID, "logged out")
+	s.logger.WithContext(ctx).Info("Logged out of all devices", "user_id", userID, "connections_closed", closed)
+
+	return nil
+}
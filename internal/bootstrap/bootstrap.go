@@ -0,0 +1,113 @@
+// Package bootstrap sets up the configuration, logger and database
+// connection shared by the server's subcommands
+package bootstrap
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// Options are the command-line options common to every subcommand
+type Options struct {
+	ConfigPath string
+	Dev        bool
+}
+
+// RegisterFlags adds the common options to a subcommand's flag set
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.ConfigPath, "config", "./configs/config.yaml", "path to config file")
+	fs.BoolVar(&o.Dev, "dev", false, "run in development mode")
+}
+
+// Env holds the dependencies every subcommand starts from
+type Env struct {
+	Config *configs.Config
+	Logger logger.Logger
+	DB     *sqlx.DB
+}
+
+// New loads the configuration and connects to the database
+func New(opts Options) (*Env, error) {
+	config, log, err := opts.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := Connect(config, log)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Env{
+		Config: config,
+		Logger: log,
+		DB:     db,
+	}, nil
+}
+
+// Load loads the configuration file and creates the logger it describes
+func (o Options) Load() (*configs.Config, logger.Logger, error) {
+	config, err := configs.LoadConfig(o.ConfigPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	log, err := logger.New(logger.Config{
+		Development: o.Dev,
+		Level:       config.Log.Level,
+		Encoding:    config.Log.Encoding,
+		Outputs:     config.Log.Outputs,
+		File:        logFile(config.Log.File),
+		ErrorFile:   logFile(config.Log.ErrorFile),
+		// Message content is never logged in production
+		IncludeContent: o.Dev && config.Log.IncludeContent,
+		Redaction: logger.Redaction{
+			Enabled: config.Log.Redaction.Enabled,
+			Allow:   config.Log.Redaction.Allow,
+			HashKey: config.Log.Redaction.HashKey,
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid log configuration: %w", err)
+	}
+
+	return config, log, nil
+}
+
+// logFile converts a rotating log file's configuration
+func logFile(config configs.LogFileConfig) logger.FileConfig {
+	return logger.FileConfig{
+		Path:       config.Path,
+		MaxSizeMB:  config.MaxSizeMB,
+		MaxAge:     config.MaxAge,
+		MaxBackups: config.MaxBackups,
+	}
+}
+
+// Connect opens the database connection described by the configuration
+func Connect(config *configs.Config, log logger.Logger) (*sqlx.DB, error) {
+	db, err := database.ConnectPostgres(database.PostgresConfig{
+		Host:     config.Database.Host,
+		Port:     config.Database.Port,
+		User:     config.Database.User,
+		Password: config.Database.Password,
+		DBName:   config.Database.DBName,
+		SSLMode:  config.Database.SSLMode,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	log.Info("Connected to database")
+
+	return db, nil
+}
+
+// Close releases the environment's resources
+func (e *Env) Close() error {
+	return e.DB.Close()
+}
@@ -0,0 +1,83 @@
+package calls
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+)
+
+// Handler handles call-related HTTP requests
+type Handler struct {
+	service Service
+	logger  logger.Logger
+}
+
+// NewHandler creates a new call handler
+func NewHandler(service Service, logger logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetICEServers handles requests for the ICE servers to use in a call
+func (h *Handler) GetICEServers(w http.ResponseWriter, r *http.Request) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	resp, err := h.service.GetICEServers(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, ErrFeatureDisabled) {
+			sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "feature.disabled"))
+			return
+		}
+		h.logger.WithContext(r.Context()).Error("Failed to get ICE servers", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "call.ice_servers_failed"))
+		return
+	}
+
+	// The TURN credentials are per user and short-lived
+	w.Header().Set("Cache-Control", "no-store")
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
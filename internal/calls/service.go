@@ -0,0 +1,80 @@
+package calls
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/features"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+)
+
+// ErrFeatureDisabled is returned when calls are turned off for the user
+var ErrFeatureDisabled = errors.New("calls are not enabled for this user")
+
+// FeatureFlags reports whether a feature is enabled for a user
+type FeatureFlags interface {
+	Enabled(name string, userID uuid.UUID) bool
+}
+
+// Service handles call business logic
+type Service interface {
+	GetICEServers(ctx context.Context, userID uuid.UUID) (*models.ICEServersResponse, error)
+}
+
+// CallService implements Service interface
+type CallService struct {
+	config configs.CallsConfig
+	flags  FeatureFlags
+	logger logger.Logger
+}
+
+// NewCallService creates a new call service
+func NewCallService(config configs.CallsConfig, flags FeatureFlags, logger logger.Logger) *CallService {
+	return &CallService{
+		config: config,
+		flags:  flags,
+		logger: logger,
+	}
+}
+
+// GetICEServers returns the STUN and TURN servers for a user's calls. TURN
+// credentials are minted with coturn's shared secret scheme: the username
+// is the expiry time and user ID, and the password is an HMAC of the
+// username keyed with the secret the TURN server also holds, so no
+// credentials are stored and each set stops working when it expires.
+func (s *CallService) GetICEServers(ctx context.Context, userID uuid.UUID) (*models.ICEServersResponse, error) {
+	if !s.flags.Enabled(features.Calls, userID) {
+		return nil, ErrFeatureDisabled
+	}
+
+	resp := &models.ICEServersResponse{ICEServers: []models.ICEServer{}}
+	if len(s.config.STUNURLs) > 0 {
+		resp.ICEServers = append(resp.ICEServers, models.ICEServer{URLs: s.config.STUNURLs})
+	}
+
+	if len(s.config.TURNURLs) > 0 && s.config.TURNSecret != "" {
+		expiresAt := time.Now().Add(s.config.CredentialTTL).Truncate(time.Second)
+		username := strconv.FormatInt(expiresAt.Unix(), 10) + ":" + userID.String()
+
+		mac := hmac.New(sha1.New, []byte(s.config.TURNSecret))
+		mac.Write([]byte(username))
+
+		resp.ICEServers = append(resp.ICEServers, models.ICEServer{
+			URLs:       s.config.TURNURLs,
+			Username:   username,
+			Credential: base64.StdEncoding.EncodeToString(mac.Sum(nil)),
+		})
+		resp.ExpiresAt = &expiresAt
+	}
+
+	return resp, nil
+}
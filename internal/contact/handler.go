@@ -0,0 +1,252 @@
+package contact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Handler handles contact HTTP requests
+type Handler struct {
+	service   Service
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new contact handler
+func NewHandler(service Service, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		service:   service,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// GetContacts handles requests to list the user's contacts
+func (h *Handler) GetContacts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	resp, err := h.service.GetContacts(r.Context(), userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "contact.list_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// RemoveContact handles requests to remove a contact
+func (h *Handler) RemoveContact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	if err := h.service.RemoveContact(r.Context(), userID, contactID); err != nil {
+		h.sendServiceError(w, r, err, "contact.remove_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendRequest handles requests to send a contact request
+func (h *Handler) SendRequest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.CreateContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode contact request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	addresseeID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	request, err := h.service.SendRequest(r.Context(), userID, addresseeID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "contact.request_failed")
+		return
+	}
+
+	// A mutual request is accepted right away
+	status := http.StatusCreated
+	if request.Status == models.ContactRequestAccepted {
+		status = http.StatusOK
+	}
+
+	// Send response
+	sendJSON(w, status, request)
+}
+
+// GetRequests handles requests to list pending contact requests. The
+// direction query parameter selects incoming (default) or outgoing ones.
+func (h *Handler) GetRequests(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var incoming bool
+	switch r.URL.Query().Get("direction") {
+	case "", "incoming":
+		incoming = true
+	case "outgoing":
+		incoming = false
+	default:
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "contact.invalid_direction"))
+		return
+	}
+
+	// Call service
+	resp, err := h.service.GetPendingRequests(r.Context(), userID, incoming)
+	if err != nil {
+		h.sendServiceError(w, r, err, "contact.list_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// AcceptRequest handles requests to accept a contact request
+func (h *Handler) AcceptRequest(w http.ResponseWriter, r *http.Request) {
+	h.answerRequest(w, r, h.service.AcceptRequest)
+}
+
+// DeclineRequest handles requests to decline a contact request
+func (h *Handler) DeclineRequest(w http.ResponseWriter, r *http.Request) {
+	h.answerRequest(w, r, h.service.DeclineRequest)
+}
+
+// answerRequest parses the request ID and answers the request with answer
+func (h *Handler) answerRequest(w http.ResponseWriter, r *http.Request, answer func(ctx context.Context, requestID, userID uuid.UUID) (*models.ContactRequest, error)) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	requestID, err := uuid.Parse(mux.Vars(r)["request_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "contact.invalid_request_id"))
+		return
+	}
+
+	// Call service
+	request, err := answer(r.Context(), requestID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "contact.answer_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, request)
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing or malformed
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// sendServiceError maps a service error to an HTTP error response, using
+// the message key for unexpected errors
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error, key string) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+	case errors.Is(err, ErrSelfRequest):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "contact.self_request"))
+	case errors.Is(err, ErrAlreadyContacts):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "contact.already_contacts"))
+	case errors.Is(err, ErrRequestExists):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "contact.request_exists"))
+	case errors.Is(err, ErrRequestNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "contact.request_not_found"))
+	case errors.Is(err, ErrRequestNotPending):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "contact.request_not_pending"))
+	case errors.Is(err, ErrNotContacts):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "contact.not_found"))
+	default:
+		h.logger.WithContext(r.Context()).Error(i18n.English.T(key), "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, key))
+	}
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
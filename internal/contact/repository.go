@@ -0,0 +1,261 @@
+package contact
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Repository errors
+var (
+	ErrRequestNotFound   = errors.New("contact request not found")
+	ErrRequestExists     = errors.New("contact request already pending")
+	ErrRequestNotPending = errors.New("contact request has already been answered")
+	ErrUserNotFound      = errors.New("user not found")
+)
+
+// uniqueViolation is the PostgreSQL error code for unique constraint violations
+const uniqueViolation = "23505"
+
+// Repository interface for contact operations
+type Repository interface {
+	UserExists(ctx context.Context, userID uuid.UUID) (bool, error)
+	CreateRequest(ctx context.Context, request *models.ContactRequest) error
+	GetRequest(ctx context.Context, requestID uuid.UUID) (*models.ContactRequest, error)
+	GetPendingRequest(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.ContactRequest, error)
+	GetPendingRequests(ctx context.Context, userID uuid.UUID, incoming bool) ([]models.ContactRequest, error)
+	UpdateRequestStatus(ctx context.Context, requestID uuid.UUID, status string, respondedAt time.Time) error
+	AddContact(ctx context.Context, userID, contactID uuid.UUID) error
+	RemoveContact(ctx context.Context, userID, contactID uuid.UUID) (bool, error)
+	AreContacts(ctx context.Context, userID, contactID uuid.UUID) (bool, error)
+	GetContacts(ctx context.Context, userID uuid.UUID) ([]models.UserInfo, error)
+	GetMessagePrivacy(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// requestQuery selects contact requests with both users' usernames.
+// Callers append conditions and ordering.
+const requestQuery = `
+        SELECT
+            cr.id,
+            cr.requester_id,
+            requester.username as requester_username,
+            cr.addressee_id,
+            addressee.username as addressee_username,
+            cr.status,
+            cr.created_at,
+            cr.responded_at
+        FROM contact_requests cr
+        JOIN users requester ON requester.id = cr.requester_id
+        JOIN users addressee ON addressee.id = cr.addressee_id
+`
+
+// UserExists checks whether a user exists
+func (r *PostgresRepository) UserExists(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.conn(ctx).GetContext(ctx, &exists, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID)
+	return exists, err
+}
+
+// CreateRequest saves a new pending contact request
+func (r *PostgresRepository) CreateRequest(ctx context.Context, request *models.ContactRequest) error {
+	query := `
+		INSERT INTO contact_requests (id, requester_id, addressee_id, status, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		request.ID,
+		request.RequesterID,
+		request.AddresseeID,
+		request.Status,
+		request.CreatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrRequestExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetRequest retrieves a contact request by ID
+func (r *PostgresRepository) GetRequest(ctx context.Context, requestID uuid.UUID) (*models.ContactRequest, error) {
+	var request models.ContactRequest
+	if err := r.conn(ctx).GetContext(ctx, &request, requestQuery+" WHERE cr.id = $1", requestID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetPendingRequest retrieves the pending request from one user to another
+func (r *PostgresRepository) GetPendingRequest(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.ContactRequest, error) {
+	query := requestQuery + " WHERE cr.requester_id = $1 AND cr.addressee_id = $2 AND cr.status = $3"
+
+	var request models.ContactRequest
+	if err := r.conn(ctx).GetContext(ctx, &request, query, requesterID, addresseeID, models.ContactRequestPending); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetPendingRequests retrieves a user's pending requests, newest first.
+// Incoming requests are those addressed to the user, outgoing ones those
+// the user sent.
+func (r *PostgresRepository) GetPendingRequests(ctx context.Context, userID uuid.UUID, incoming bool) ([]models.ContactRequest, error) {
+	column := "cr.requester_id"
+	if incoming {
+		column = "cr.addressee_id"
+	}
+	query := requestQuery + " WHERE " + column + " = $1 AND cr.status = $2 ORDER BY cr.created_at DESC"
+
+	var requests []models.ContactRequest
+	if err := r.conn(ctx).SelectContext(ctx, &requests, query, userID, models.ContactRequestPending); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// UpdateRequestStatus answers a pending contact request
+func (r *PostgresRepository) UpdateRequestStatus(ctx context.Context, requestID uuid.UUID, status string, respondedAt time.Time) error {
+	query := `
+		UPDATE contact_requests
+		SET status = $1, responded_at = $2
+		WHERE id = $3 AND status = $4
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, status, respondedAt, requestID, models.ContactRequestPending)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRequestNotPending
+	}
+	return nil
+}
+
+// AddContact makes two users contacts of each other
+func (r *PostgresRepository) AddContact(ctx context.Context, userID, contactID uuid.UUID) error {
+	query := `
+		INSERT INTO contacts (user_id, contact_id)
+		VALUES ($1, $2), ($2, $1)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, contactID)
+	return err
+}
+
+// RemoveContact removes two users from each other's contacts, reporting
+// whether they were contacts
+func (r *PostgresRepository) RemoveContact(ctx context.Context, userID, contactID uuid.UUID) (bool, error) {
+	query := `
+		DELETE FROM contacts
+		WHERE (user_id = $1 AND contact_id = $2) OR (user_id = $2 AND contact_id = $1)
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, userID, contactID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// AreContacts checks whether two users are contacts
+func (r *PostgresRepository) AreContacts(ctx context.Context, userID, contactID uuid.UUID) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM contacts WHERE user_id = $1 AND contact_id = $2)"
+
+	var exists bool
+	err := r.conn(ctx).GetContext(ctx, &exists, query, userID, contactID)
+	return exists, err
+}
+
+// GetContacts retrieves a user's contacts ordered by username
+func (r *PostgresRepository) GetContacts(ctx context.Context, userID uuid.UUID) ([]models.UserInfo, error) {
+	query := `
+		SELECT u.id, u.username, u.status, u.status_text, u.status_emoji, u.status_expires_at, u.updated_at
+		FROM contacts c
+		JOIN users u ON u.id = c.contact_id
+		WHERE c.user_id = $1
+		ORDER BY u.username ASC
+	`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	var contacts []models.UserInfo
+	for rows.Next() {
+		var contact models.UserInfo
+		var statusText, statusEmoji string
+		var statusExpiresAt *time.Time
+		err := rows.Scan(&contact.ID, &contact.Username, &contact.Status, &statusText, &statusEmoji, &statusExpiresAt, &contact.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+
+		contact.OnlineStatus = contact.Status == "online"
+		contact.CustomStatus = models.NewCustomStatus(statusText, statusEmoji, statusExpiresAt, now)
+		contacts = append(contacts, contact)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return contacts, nil
+}
+
+// GetMessagePrivacy retrieves who may start a conversation with a user
+func (r *PostgresRepository) GetMessagePrivacy(ctx context.Context, userID uuid.UUID) (string, error) {
+	var privacy string
+	err := r.conn(ctx).GetContext(ctx, &privacy, "SELECT message_privacy FROM users WHERE id = $1", userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	return privacy, nil
+}
@@ -0,0 +1,282 @@
+package contact
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Service errors
+var (
+	ErrSelfRequest     = errors.New("cannot send a contact request to yourself")
+	ErrAlreadyContacts = errors.New("users are already contacts")
+	ErrNotContacts     = errors.New("users are not contacts")
+)
+
+// Service handles contact business logic
+type Service interface {
+	SendRequest(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.ContactRequest, error)
+	GetPendingRequests(ctx context.Context, userID uuid.UUID, incoming bool) (*models.ContactRequestListResponse, error)
+	AcceptRequest(ctx context.Context, requestID, userID uuid.UUID) (*models.ContactRequest, error)
+	DeclineRequest(ctx context.Context, requestID, userID uuid.UUID) (*models.ContactRequest, error)
+	GetContacts(ctx context.Context, userID uuid.UUID) (*models.ContactListResponse, error)
+	RemoveContact(ctx context.Context, userID, contactID uuid.UUID) error
+	CanMessage(ctx context.Context, senderID, recipientID uuid.UUID) (bool, error)
+	AcceptsConversation(ctx context.Context, senderID, recipientID uuid.UUID) (bool, error)
+}
+
+// Notifier pushes real-time events to a user's connected clients
+type Notifier interface {
+	SendToUser(userID uuid.UUID, message *models.WebSocketMessage) bool
+}
+
+// OfflineNotifier notifies users who are not connected through channels
+// such as push or email, subject to their notification preferences
+type OfflineNotifier interface {
+	Dispatch(ctx context.Context, notification *models.Notification)
+}
+
+// ContactService implements Service interface
+type ContactService struct {
+	repo     Repository
+	uow      database.UnitOfWork
+	notifier Notifier
+	offline  OfflineNotifier
+	strict   bool
+	logger   logger.Logger
+}
+
+// NewContactService creates a new contact service. In strict mode users
+// can only message their contacts.
+func NewContactService(repo Repository, uow database.UnitOfWork, notifier Notifier, offline OfflineNotifier, config configs.ContactsConfig, logger logger.Logger) *ContactService {
+	return &ContactService{
+		repo:     repo,
+		uow:      uow,
+		notifier: notifier,
+		offline:  offline,
+		strict:   config.StrictMode,
+		logger:   logger,
+	}
+}
+
+// SendRequest sends a contact request. If the addressee already asked the
+// requester, that request is accepted instead.
+func (s *ContactService) SendRequest(ctx context.Context, requesterID, addresseeID uuid.UUID) (*models.ContactRequest, error) {
+	if requesterID == addresseeID {
+		return nil, ErrSelfRequest
+	}
+
+	exists, err := s.repo.UserExists(ctx, addresseeID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check if user exists", "error", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	contacts, err := s.repo.AreContacts(ctx, requesterID, addresseeID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check contacts", "error", err)
+		return nil, err
+	}
+	if contacts {
+		return nil, ErrAlreadyContacts
+	}
+
+	// Both users want to connect, so the reverse request counts as consent
+	reverse, err := s.repo.GetPendingRequest(ctx, addresseeID, requesterID)
+	if err == nil {
+		return s.AcceptRequest(ctx, reverse.ID, requesterID)
+	}
+	if !errors.Is(err, ErrRequestNotFound) {
+		s.logger.WithContext(ctx).Error("Failed to get pending contact request", "error", err)
+		return nil, err
+	}
+
+	request := &models.ContactRequest{
+		ID:          uuid.New(),
+		RequesterID: requesterID,
+		AddresseeID: addresseeID,
+		Status:      models.ContactRequestPending,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.CreateRequest(ctx, request); err != nil {
+		if !errors.Is(err, ErrRequestExists) {
+			s.logger.WithContext(ctx).Error("Failed to create contact request", "error", err)
+		}
+		return nil, err
+	}
+
+	// Reload to fill in usernames
+	request, err = s.repo.GetRequest(ctx, request.ID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get contact request", "error", err)
+		return nil, err
+	}
+
+	s.notify(protocol.TypeContactRequest, request, request.RequesterID)
+	delivered := s.notifier.SendToUser(request.AddresseeID, &models.WebSocketMessage{
+		Type: protocol.TypeContactRequest,
+		Data: *request,
+	})
+	if !delivered {
+		s.offline.Dispatch(ctx, &models.Notification{
+			UserID:         request.AddresseeID,
+			Type:           models.NotificationContactRequest,
+			SenderID:       request.RequesterID.String(),
+			SenderUsername: request.RequesterUsername,
+		})
+	}
+	return request, nil
+}
+
+// GetPendingRequests returns a user's incoming or outgoing pending requests
+func (s *ContactService) GetPendingRequests(ctx context.Context, userID uuid.UUID, incoming bool) (*models.ContactRequestListResponse, error) {
+	requests, err := s.repo.GetPendingRequests(ctx, userID, incoming)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get contact requests", "error", err)
+		return nil, err
+	}
+	if requests == nil {
+		requests = []models.ContactRequest{}
+	}
+
+	return &models.ContactRequestListResponse{Requests: requests}, nil
+}
+
+// AcceptRequest accepts a request addressed to the user, making both users
+// contacts
+func (s *ContactService) AcceptRequest(ctx context.Context, requestID, userID uuid.UUID) (*models.ContactRequest, error) {
+	request, err := s.answer(ctx, requestID, userID, models.ContactRequestAccepted, func(ctx context.Context, request *models.ContactRequest) error {
+		return s.repo.AddContact(ctx, request.RequesterID, request.AddresseeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(protocol.TypeContactRequestUpdated, request, request.RequesterID, request.AddresseeID)
+	return request, nil
+}
+
+// DeclineRequest declines a request addressed to the user. The requester
+// is not told, so their request simply stays unanswered from their side.
+func (s *ContactService) DeclineRequest(ctx context.Context, requestID, userID uuid.UUID) (*models.ContactRequest, error) {
+	request, err := s.answer(ctx, requestID, userID, models.ContactRequestDeclined, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(protocol.TypeContactRequestUpdated, request, request.AddresseeID)
+	return request, nil
+}
+
+// answer sets the status of a pending request addressed to the user,
+// running then in the same transaction
+func (s *ContactService) answer(ctx context.Context, requestID, userID uuid.UUID, status string, then func(ctx context.Context, request *models.ContactRequest) error) (*models.ContactRequest, error) {
+	request, err := s.repo.GetRequest(ctx, requestID)
+	if err != nil {
+		if !errors.Is(err, ErrRequestNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to get contact request", "error", err)
+		}
+		return nil, err
+	}
+
+	// Hide requests addressed to other users
+	if request.AddresseeID != userID {
+		return nil, ErrRequestNotFound
+	}
+
+	respondedAt := time.Now()
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateRequestStatus(ctx, requestID, status, respondedAt); err != nil {
+			return err
+		}
+		if then != nil {
+			return then(ctx, request)
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrRequestNotPending) {
+			s.logger.WithContext(ctx).Error("Failed to answer contact request", "error", err, "request_id", requestID)
+		}
+		return nil, err
+	}
+
+	request.Status = status
+	request.RespondedAt = &respondedAt
+	return request, nil
+}
+
+// GetContacts returns a user's contacts
+func (s *ContactService) GetContacts(ctx context.Context, userID uuid.UUID) (*models.ContactListResponse, error) {
+	contacts, err := s.repo.GetContacts(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get contacts", "error", err)
+		return nil, err
+	}
+	if contacts == nil {
+		contacts = []models.UserInfo{}
+	}
+
+	return &models.ContactListResponse{Contacts: contacts}, nil
+}
+
+// RemoveContact removes a contact for both users
+func (s *ContactService) RemoveContact(ctx context.Context, userID, contactID uuid.UUID) error {
+	removed, err := s.repo.RemoveContact(ctx, userID, contactID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to remove contact", "error", err)
+		return err
+	}
+	if !removed {
+		return ErrNotContacts
+	}
+	return nil
+}
+
+// CanMessage reports whether the sender may message the recipient. Outside
+// strict mode anyone may message anyone.
+func (s *ContactService) CanMessage(ctx context.Context, senderID, recipientID uuid.UUID) (bool, error) {
+	if !s.strict {
+		return true, nil
+	}
+	return s.repo.AreContacts(ctx, senderID, recipientID)
+}
+
+// AcceptsConversation reports whether the recipient's privacy settings let
+// the sender start a new conversation with them
+func (s *ContactService) AcceptsConversation(ctx context.Context, senderID, recipientID uuid.UUID) (bool, error) {
+	privacy, err := s.repo.GetMessagePrivacy(ctx, recipientID)
+	if err != nil {
+		return false, err
+	}
+
+	switch privacy {
+	case models.PrivacyNobody:
+		return false, nil
+	case models.PrivacyContacts:
+		return s.repo.AreContacts(ctx, recipientID, senderID)
+	default:
+		return true, nil
+	}
+}
+
+// notify sends a contact request event to users
+func (s *ContactService) notify(messageType protocol.MessageType, request *models.ContactRequest, userIDs ...uuid.UUID) {
+	message := &models.WebSocketMessage{
+		Type: messageType,
+		Data: *request,
+	}
+	for _, userID := range userIDs {
+		s.notifier.SendToUser(userID, message)
+	}
+}
@@ -0,0 +1,281 @@
+package conversation
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+)
+
+// Buffer errors
+var (
+	ErrMessagePending = errors.New("message buffered until the database recovers")
+	ErrBufferFull     = errors.New("message buffer is full")
+)
+
+// BufferedMessage is a direct message waiting in the buffer
+type BufferedMessage struct {
+	Message        models.DirectMessage `json:"message"`
+	SenderUsername string               `json:"sender_username"`
+	BufferedAt     time.Time            `json:"buffered_at"`
+}
+
+// MessageBuffer is a write-ahead log on local disk that holds direct
+// messages sent while the database is unavailable. Each append is synced
+// before the sender is told the message is pending, so buffered messages
+// survive a restart of the node.
+type MessageBuffer struct {
+	path        string
+	maxMessages int
+
+	// flushMu keeps flushes from overlapping
+	flushMu sync.Mutex
+
+	mu      sync.Mutex
+	file    *os.File
+	pending []BufferedMessage
+}
+
+// OpenMessageBuffer opens the buffer file at path, creating it if needed,
+// and loads the messages left in it
+func OpenMessageBuffer(path string, maxMessages int) (*MessageBuffer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create buffer directory: %w", err)
+	}
+
+	pending, err := readBuffer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message buffer: %w", err)
+	}
+
+	return &MessageBuffer{
+		path:        path,
+		maxMessages: maxMessages,
+		file:        file,
+		pending:     pending,
+	}, nil
+}
+
+// readBuffer loads the messages in a buffer file. A partial last line left
+// by a crash during an append is ignored, since that append was never
+// acknowledged.
+func readBuffer(path string) ([]BufferedMessage, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message buffer: %w", err)
+	}
+	defer file.Close()
+
+	var pending []BufferedMessage
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry BufferedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			break
+		}
+		pending = append(pending, entry)
+	}
+
+	return pending, scanner.Err()
+}
+
+// Append adds a message to the buffer and syncs it to disk
+func (b *MessageBuffer) Append(entry BufferedMessage) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxMessages > 0 && len(b.pending) >= b.maxMessages {
+		return ErrBufferFull
+	}
+
+	if _, err := b.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write message buffer: %w", err)
+	}
+	if err := b.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync message buffer: %w", err)
+	}
+
+	b.pending = append(b.pending, entry)
+	return nil
+}
+
+// Pending returns the buffered messages, oldest first
+func (b *MessageBuffer) Pending() []BufferedMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]BufferedMessage(nil), b.pending...)
+}
+
+// Len returns the number of buffered messages
+func (b *MessageBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.pending)
+}
+
+// Remove drops flushed messages from the buffer. The remaining messages
+// are written to a new file that replaces the old one, so a crash leaves
+// either the old or the new buffer intact.
+func (b *MessageBuffer) Remove(ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	removed := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		removed[id] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var remaining []BufferedMessage
+	for _, entry := range b.pending {
+		if !removed[entry.Message.ID] {
+			remaining = append(remaining, entry)
+		}
+	}
+
+	tmpPath := b.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite message buffer: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, entry := range remaining {
+		if err := enc.Encode(entry); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, b.path); err != nil {
+		return fmt.Errorf("failed to replace message buffer: %w", err)
+	}
+
+	// Keep appending to the new file
+	file, err := os.OpenFile(b.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to reopen message buffer: %w", err)
+	}
+	b.file.Close()
+	b.file = file
+	b.pending = remaining
+
+	return nil
+}
+
+// Close closes the buffer file
+func (b *MessageBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.file.Close()
+}
+
+// isUnavailable reports whether err means the database could not be
+// reached, as opposed to the message being rejected
+func isUnavailable(err error) bool {
+	return errors.Is(err, database.ErrCircuitOpen) || database.IsTransient(err)
+}
+
+// bufferMessage stores a message that could not be saved because the
+// database is unavailable
+func (s *ConversationService) bufferMessage(ctx context.Context, message *models.DirectMessage, senderUsername string) error {
+	err := s.buffer.Append(BufferedMessage{
+		Message:        *message,
+		SenderUsername: senderUsername,
+		BufferedAt:     time.Now(),
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to buffer message", "error", err, "message_id", message.ID)
+		return err
+	}
+
+	s.logger.WithContext(ctx).Warn("Database unavailable, message buffered", "message_id", message.ID)
+	return ErrMessagePending
+}
+
+// FlushBuffer saves the buffered messages in the order they were sent and
+// tells each sender the outcome. Flushing stops at the first message that
+// still cannot reach the database; the rest wait for the next flush.
+func (s *ConversationService) FlushBuffer(ctx context.Context) (int, error) {
+	if s.buffer == nil {
+		return 0, nil
+	}
+
+	s.buffer.flushMu.Lock()
+	defer s.buffer.flushMu.Unlock()
+
+	var flushed []uuid.UUID
+	var flushErr error
+	for _, entry := range s.buffer.Pending() {
+		message := entry.Message
+		_, err := s.deliverMessage(ctx, &message, entry.SenderUsername)
+		if err != nil && isUnavailable(err) {
+			flushErr = err
+			break
+		}
+
+		status := message.DeliveryState
+		if err != nil {
+			// The message was rejected, for example because the sender was
+			// blocked while it waited
+			s.logger.WithContext(ctx).Warn("Dropped buffered message", "error", err, "message_id", message.ID)
+			status = models.DeliveryFailed
+		}
+		s.notifier.SendToUser(message.SenderID, &models.WebSocketMessage{
+			Type: protocol.TypeMessageAck,
+			Data: models.MessageAckData{
+				ServerMessageID: message.ID.String(),
+				Status:          status,
+				Timestamp:       message.CreatedAt, // set by the database once saved
+			},
+		})
+		flushed = append(flushed, message.ID)
+	}
+
+	if err := s.buffer.Remove(flushed); err != nil {
+		return 0, err
+	}
+
+	return len(flushed), flushErr
+}
@@ -0,0 +1,162 @@
+package conversation
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/google/uuid"
+)
+
+// Export errors
+var (
+	ErrExportTooLarge    = errors.New("conversation has too many messages to export")
+	ErrExportRateLimited = errors.New("user exported a conversation too recently")
+)
+
+// ExportConversation returns a transcript of the conversation as the user
+// sees it. Each user may start one export per configured interval.
+func (s *ConversationService) ExportConversation(ctx context.Context, conversationID string, userID uuid.UUID, includeMedia bool) (*models.ConversationExport, error) {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !s.exportLimiter.Allow(userID, now) {
+		return nil, ErrExportRateLimited
+	}
+
+	// Get one extra message to check if the conversation is over the limit
+	messages, err := s.repo.GetTranscript(ctx, conversationID, userID, s.exports.MaxMessages+1)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get transcript", "error", err)
+		return nil, err
+	}
+
+	if len(messages) > s.exports.MaxMessages {
+		return nil, ErrExportTooLarge
+	}
+
+	if messages == nil {
+		messages = []models.Message{}
+	}
+
+	// Sanitize messages stored before sanitization was enabled
+	for i := range messages {
+		messages[i].Content = s.sanitizer.CleanStored(messages[i].Content)
+	}
+
+	export := &models.ConversationExport{
+		ConversationID: conversationID,
+		ExportedAt:     now,
+		Messages:       messages,
+	}
+
+	if includeMedia {
+		export.Attachments, err = s.repo.GetTranscriptAttachments(ctx, conversationID, userID)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("Failed to get transcript attachments", "error", err)
+			return nil, err
+		}
+	}
+
+	return export, nil
+}
+
+// WriteTranscriptText writes an export as a plain-text transcript, one
+// line per message followed by any attachments
+func WriteTranscriptText(w io.Writer, export *models.ConversationExport) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "Conversation %s\n", export.ConversationID)
+	fmt.Fprintf(bw, "Exported at %s\n\n", export.ExportedAt.UTC().Format(time.RFC3339))
+
+	usernames := make(map[string]string)
+	for _, msg := range export.Messages {
+		usernames[msg.SenderID] = msg.SenderUsername
+		if msg.Type != "" && msg.Type != models.DirectMessageText {
+			fmt.Fprintf(bw, "[%s] * %s\n", formatTranscriptTime(msg.Timestamp), describeSystemMessage(msg))
+			continue
+		}
+		fmt.Fprintf(bw, "[%s] %s: %s\n", formatTranscriptTime(msg.Timestamp), msg.SenderUsername, msg.Content)
+	}
+
+	if len(export.Attachments) > 0 {
+		fmt.Fprintf(bw, "\nAttachments\n")
+	}
+	for _, attachment := range export.Attachments {
+		uploader := attachment.UploaderID.String()
+		if username, ok := usernames[uploader]; ok {
+			uploader = username
+		}
+		fmt.Fprintf(bw, "[%s] %s: %s (%s, %d bytes, %s)\n",
+			formatTranscriptTime(attachment.CreatedAt), uploader, attachment.Filename,
+			attachment.ContentType, attachment.Size, attachment.ID)
+	}
+
+	return bw.Flush()
+}
+
+// describeSystemMessage describes the event a system message records
+func describeSystemMessage(msg models.Message) string {
+	switch msg.Type {
+	case models.DirectMessageCallMissed:
+		if msg.Data["media"] == models.CallVideo {
+			return "Missed video call from " + msg.SenderUsername
+		}
+		return "Missed voice call from " + msg.SenderUsername
+	case models.DirectMessageDisappearingTimer:
+		if msg.Data["seconds"] == "0" {
+			return msg.SenderUsername + " turned off disappearing messages"
+		}
+		return msg.SenderUsername + " turned on disappearing messages"
+	}
+	return msg.Type + " by " + msg.SenderUsername
+}
+
+// formatTranscriptTime formats a timestamp for plain-text transcripts
+func formatTranscriptTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+// exportLimiter allows each user one export per interval
+type exportLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[uuid.UUID]time.Time
+}
+
+// newExportLimiter creates an export limiter
+func newExportLimiter(interval time.Duration) *exportLimiter {
+	return &exportLimiter{
+		interval: interval,
+		last:     make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Allow records an export by the user at now, unless their previous export
+// was less than an interval ago
+func (l *exportLimiter) Allow(userID uuid.UUID, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if last, ok := l.last[userID]; ok && now.Sub(last) < l.interval {
+		return false
+	}
+
+	// Forget users whose interval has passed so the map stays small
+	for id, last := range l.last {
+		if now.Sub(last) >= l.interval {
+			delete(l.last, id)
+		}
+	}
+
+	l.last[userID] = now
+	return true
+}
@@ -2,27 +2,44 @@ package conversation
 
 import (
 	"encoding/json"
+	"errors"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/codingminions/Whatsapp-Lite/internal/auth"
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/httpcache"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
+// batchMessageLimit is the number of messages returned per conversation by
+// a batch fetch that does not set a limit
+const batchMessageLimit = 20
+
 // Handler handles conversation-related HTTP requests
 type Handler struct {
-	service Service
-	logger  logger.Logger
+	service          Service
+	logger           logger.Logger
+	validator        validator.Validator
+	messageValidator *validator.MessageValidator
 }
 
 // NewHandler creates a new conversation handler
-func NewHandler(service Service, logger logger.Logger) *Handler {
+func NewHandler(service Service, logger logger.Logger, validator validator.Validator, messageValidator *validator.MessageValidator) *Handler {
 	return &Handler{
-		service: service,
-		logger:  logger,
+		service:          service,
+		logger:           logger,
+		validator:        validator,
+		messageValidator: messageValidator,
 	}
 }
 
@@ -31,59 +48,46 @@ func (h *Handler) GetConversations(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
 	userIDStr, err := auth.GetUserID(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get user ID from context", "error", err)
-		sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-			Code:    1008,
-			Message: "Authentication required",
-		})
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		h.logger.Error("Invalid user ID format", "error", err)
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: "Invalid user ID format",
-		})
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
 		return
 	}
 
 	// Call service
-	resp, err := h.service.GetConversations(r.Context(), userID)
+	resp, err := h.service.GetConversations(r.Context(), userID, auth.GetWorkspaceID(r.Context()))
 	if err != nil {
-		h.logger.Error("Failed to get conversations", "error", err)
-		sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Code:    1009,
-			Message: "Failed to get conversations",
-		})
+		h.logger.WithContext(r.Context()).Error("Failed to get conversations", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "conversation.list_failed"))
 		return
 	}
 
-	// Send response
-	sendJSON(w, http.StatusOK, resp)
+	// Send response, or 304 if the client has this version of the list
+	httpcache.WriteJSON(w, r, http.StatusOK, resp)
 }
 
-// GetMessages handles requests to get messages in a conversation
+// GetMessages handles requests to get messages in a conversation. Pages are
+// tagged with the conversation's version, so clients polling with
+// If-None-Match get a 304 while nothing has changed.
 func (h *Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
 	userIDStr, err := auth.GetUserID(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get user ID from context", "error", err)
-		sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-			Code:    1008,
-			Message: "Authentication required",
-		})
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		h.logger.Error("Invalid user ID format", "error", err)
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: "Invalid user ID format",
-		})
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
 		return
 	}
 
@@ -91,37 +95,558 @@ func (h *Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	conversationID := vars["conversation_id"]
 	if conversationID == "" {
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: "Missing conversation ID",
-		})
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "conversation.missing_id"))
+		return
+	}
+
+	// Answer with 304 if the client's page is still current
+	version, err := h.service.MessagesVersion(r.Context(), conversationID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "conversation.messages_failed")
+		return
+	}
+	if httpcache.NotModified(w, r, httpcache.ETag(version, r.URL.RawQuery)) {
 		return
 	}
 
 	// Parse query parameters
 	query := r.URL.Query()
-	before := query.Get("before") // Cursor for pagination
+	limit := pagination.ParseLimit(query.Get("limit"), 50, pagination.MaxLimit)
+
+	// Jump to a date instead of paging back from the newest message
+	if aroundDate := query.Get("around_date"); aroundDate != "" {
+		at, err := parseDate(aroundDate)
+		if err != nil {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_date"))
+			return
+		}
 
-	limit, _ := strconv.Atoi(query.Get("limit"))
-	if limit <= 0 {
-		limit = 50 // Default limit
+		resp, err := h.service.GetMessagesAround(r.Context(), conversationID, userID, at, limit)
+		if err != nil {
+			h.sendServiceError(w, r, err, "conversation.messages_failed")
+			return
+		}
+
+		sendJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	before, err := pagination.Decode(query.Get("before"))
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "pagination.invalid_cursor"))
+		return
 	}
 
 	// Call service
 	resp, err := h.service.GetMessages(r.Context(), conversationID, userID, before, limit)
 	if err != nil {
-		h.logger.Error("Failed to get messages", "error", err)
-		sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Code:    1009,
-			Message: "Failed to get messages",
+		h.sendServiceError(w, r, err, "conversation.messages_failed")
+		return
+	}
+
+	// Reading the page moved the user's read cursor, so tag it with the
+	// version after reading
+	if version, err := h.service.MessagesVersion(r.Context(), conversationID, userID); err == nil {
+		w.Header().Set("ETag", httpcache.ETag(version, r.URL.RawQuery))
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// GetMessageContext handles requests to get the messages around a message
+func (h *Handler) GetMessageContext(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Get conversation and message IDs from URL
+	vars := mux.Vars(r)
+	conversationID := vars["conversation_id"]
+	messageID, err := uuid.Parse(vars["message_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "message.invalid_id"))
+		return
+	}
+
+	// Parse query parameters
+	query := r.URL.Query()
+	before := pagination.ParseLimit(query.Get("before"), pagination.DefaultLimit, pagination.MaxLimit)
+	after := pagination.ParseLimit(query.Get("after"), pagination.DefaultLimit, pagination.MaxLimit)
+
+	// Call service
+	resp, err := h.service.GetMessageContext(r.Context(), conversationID, userID, messageID, before, after)
+	if err != nil {
+		h.sendServiceError(w, r, err, "conversation.messages_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// SendMessage handles requests to send a message without a WebSocket connection
+func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	username, err := auth.GetUsername(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get username from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Parse and validate request
+	var req models.SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode send message request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	content, err := h.messageValidator.Normalize(req.Content)
+	if err != nil {
+		sendError(w, r, errcode.InvalidContent, i18n.Error(r, err))
+		return
+	}
+
+	format, err := h.messageValidator.Format(req.Format)
+	if err != nil {
+		sendError(w, r, errcode.InvalidContent, i18n.Error(r, err))
+		return
+	}
+
+	recipientID, err := h.service.GetRecipient(r.Context(), conversationID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "message.send_failed")
+		return
+	}
+
+	// Create message
+	msg := &models.DirectMessage{
+		ID:          uuid.New(),
+		SenderID:    userID,
+		RecipientID: recipientID,
+		Content:     content,
+		Format:      format,
+		Delivered:   false,
+		Read:        false,
+		CreatedAt:   time.Now(),
+	}
+	if workspaceID := auth.GetWorkspaceID(r.Context()); workspaceID != uuid.Nil {
+		msg.WorkspaceID = &workspaceID
+	}
+
+	// Call service
+	data, err := h.service.SendMessage(r.Context(), msg, username)
+	if errors.Is(err, ErrMessagePending) {
+		// The message is saved once the database recovers
+		sendJSON(w, http.StatusAccepted, models.SendMessageResponse{
+			Ack: models.MessageAckData{
+				ClientMessageID: req.ClientMessageID,
+				ServerMessageID: msg.ID.String(),
+				Status:          models.DeliveryQueued,
+				Timestamp:       time.Now(),
+			},
 		})
 		return
 	}
+	if err != nil {
+		h.sendServiceError(w, r, err, "message.send_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusCreated, models.SendMessageResponse{
+		Ack: models.MessageAckData{
+			ClientMessageID: req.ClientMessageID,
+			ServerMessageID: data.MessageID,
+			Status:          data.DeliveryState,
+			Timestamp:       data.Timestamp,
+		},
+		Message: data,
+	})
+}
+
+// GetDraft handles requests to get the user's draft for a conversation
+func (h *Handler) GetDraft(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Call service
+	draft, err := h.service.GetDraft(r.Context(), conversationID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "draft.get_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, draft)
+}
+
+// SaveDraft handles requests to store the user's draft for a conversation
+func (h *Handler) SaveDraft(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Parse and validate request
+	var req models.DraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode draft request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	draft, err := h.service.SaveDraft(r.Context(), conversationID, userID, req.Content)
+	if err != nil {
+		h.sendServiceError(w, r, err, "draft.save_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, draft)
+}
+
+// MarkRead handles requests to mark a conversation read up to a message
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Parse and validate request
+	var req models.MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode read request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service. The session that read the messages is left out of the
+	// read sync.
+	username, _ := auth.GetUsername(r.Context())
+	err := h.service.MarkRead(r.Context(), conversationID, userID, username, auth.GetSessionID(r.Context()), req.LastReadMessageID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "conversation.read_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BatchGetMessages handles requests for the latest messages of several
+// conversations at once
+func (h *Handler) BatchGetMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.BatchGetMessagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode batch messages request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = batchMessageLimit
+	}
+
+	// Call service
+	resp, err := h.service.BatchGetMessages(r.Context(), userID, req.ConversationIDs, limit)
+	if err != nil {
+		h.sendServiceError(w, r, err, "conversation.messages_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// GetSettings handles requests for the user's settings for a conversation
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Call service
+	settings, err := h.service.GetSettings(r.Context(), conversationID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "settings.get_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings handles requests to change the user's settings for a
+// conversation
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Parse and validate request
+	var req models.ConversationSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode settings request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	settings, err := h.service.UpdateSettings(r.Context(), conversationID, userID, req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "settings.update_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, settings)
+}
+
+// ClearHistory handles requests to clear a conversation's history for the user
+func (h *Handler) ClearHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Call service
+	if err := h.service.ClearHistory(r.Context(), conversationID, userID); err != nil {
+		h.sendServiceError(w, r, err, "conversation.clear_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteConversation handles requests to delete a conversation for the user
+func (h *Handler) DeleteConversation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Call service
+	if err := h.service.DeleteConversation(r.Context(), conversationID, userID); err != nil {
+		h.sendServiceError(w, r, err, "conversation.delete_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExportConversation handles requests to download a conversation transcript
+func (h *Handler) ExportConversation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	conversationID := mux.Vars(r)["conversation_id"]
+
+	// Parse query parameters
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = models.ExportFormatJSON
+	}
+	if format != models.ExportFormatJSON && format != models.ExportFormatText {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "export.invalid_format"))
+		return
+	}
+
+	includeMedia := false
+	if media := query.Get("media"); media != "" {
+		var err error
+		includeMedia, err = strconv.ParseBool(media)
+		if err != nil {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+			return
+		}
+	}
+
+	// Call service
+	export, err := h.service.ExportConversation(r.Context(), conversationID, userID, includeMedia)
+	if err != nil {
+		h.sendServiceError(w, r, err, "export.failed")
+		return
+	}
+
+	// Stream the transcript as a download
+	filename := "conversation-" + conversationID + ".json"
+	contentType := "application/json"
+	if format == models.ExportFormatText {
+		filename = "conversation-" + conversationID + ".txt"
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+
+	if format == models.ExportFormatText {
+		err = WriteTranscriptText(w, export)
+	} else {
+		err = json.NewEncoder(w).Encode(export)
+	}
+	if err != nil {
+		// Headers are already sent, so the client sees a truncated download
+		h.logger.WithContext(r.Context()).Error("Failed to write transcript", "error", err)
+	}
+}
+
+// GetMentions handles requests to list messages that mention the user
+func (h *Handler) GetMentions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse query parameters
+	query := r.URL.Query()
+	before, err := pagination.Decode(query.Get("before"))
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "pagination.invalid_cursor"))
+		return
+	}
+
+	limit := pagination.ParseLimit(query.Get("limit"), 50, pagination.MaxLimit)
+
+	// Call service
+	resp, err := h.service.GetMentions(r.Context(), userID, before, limit)
+	if err != nil {
+		h.sendServiceError(w, r, err, "mention.list_failed")
+		return
+	}
 
 	// Send response
 	sendJSON(w, http.StatusOK, resp)
 }
 
+// currentUserID extracts the authenticated user ID, writing an error response if it is missing
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// parseDate parses an RFC 3339 timestamp or a plain date, which is taken
+// as midnight UTC
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// sendServiceError maps a service error to an HTTP error response, using
+// the message key for unexpected errors
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error, key string) {
+	switch {
+	case errors.Is(err, ErrInvalidConversationID):
+		sendError(w, r, errcode.InvalidConversation, i18n.T(r, "conversation.invalid_id"))
+	case errors.Is(err, ErrUnauthorized):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "conversation.not_participant"))
+	case errors.Is(err, ErrMessageNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "message.not_found"))
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "pagination.invalid_cursor"))
+	case errors.Is(err, ErrUserNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+	case errors.Is(err, ErrInvalidData):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_value"))
+	case errors.Is(err, ErrRecipientNotFound):
+		sendError(w, r, errcode.RecipientNotFound, i18n.T(r, "conversation.recipient_not_found"))
+	case errors.Is(err, ErrNotAccepting):
+		sendError(w, r, errcode.RecipientNotAccepting, i18n.T(r, "conversation.recipient_not_accepting"))
+	case errors.Is(err, ErrNotContact):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "conversation.not_contact"))
+	case errors.Is(err, ErrNotInWorkspace):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "conversation.not_in_workspace"))
+	case errors.Is(err, ErrFeatureDisabled):
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "feature.disabled"))
+	case errors.Is(err, ErrExportTooLarge):
+		sendError(w, r, errcode.PayloadTooLarge, i18n.T(r, "export.too_large"))
+	case errors.Is(err, ErrExportRateLimited):
+		sendError(w, r, errcode.RateLimited, i18n.T(r, "export.rate_limited"))
+	default:
+		h.logger.WithContext(r.Context()).Error(i18n.English.T(key), "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, key))
+	}
+}
+
 // sendJSON sends a JSON response
 func sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -132,3 +657,18 @@ func sendJSON(w http.ResponseWriter, status int, data interface{}) {
 		}
 	}
 }
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
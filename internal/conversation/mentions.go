@@ -0,0 +1,38 @@
+package conversation
+
+import (
+	"regexp"
+)
+
+// mentionPattern matches @username tokens that are not part of an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.\-]{3,50})`)
+
+// ParseMentions returns the unique usernames mentioned in content, in order of appearance
+func ParseMentions(content string) []string {
+	matches := mentionPattern.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(matches))
+	usernames := make([]string, 0, len(matches))
+	for _, m := range matches {
+		// Trailing punctuation is not part of the username
+		username := trimTrailingPunctuation(m[1])
+		if len(username) < 3 || seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+
+	return usernames
+}
+
+// trimTrailingPunctuation strips sentence punctuation captured after a username
+func trimTrailingPunctuation(s string) string {
+	for len(s) > 0 && (s[len(s)-1] == '.' || s[len(s)-1] == '-') {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -2,43 +2,93 @@ package conversation
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Repository errors
+var (
+	ErrInvalidConversationID = errors.New("invalid conversation ID")
+	ErrDraftNotFound         = errors.New("draft not found")
+	ErrMessageNotFound       = errors.New("message not found")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrInvalidData           = errors.New("value is not allowed")
+)
+
+// Foreign keys that reference something other than a user's own account
+const (
+	directMessagesRecipientKey = "direct_messages_recipient_id_fkey"
+	mentionsMessageKey         = "mentions_message_id_fkey"
+	summariesLastMessageKey    = "conversation_summaries_last_message_id_fkey"
 )
 
 // Repository interface for conversation operations
 type Repository interface {
-	GetConversations(ctx context.Context, userID uuid.UUID) ([]models.Conversation, error)
-	GetMessages(ctx context.Context, conversationID string, before string, limit int) ([]models.Message, bool, string, error)
+	GetConversations(ctx context.Context, userID, workspaceID uuid.UUID) ([]models.Conversation, error)
+	GetMessages(ctx context.Context, conversationID string, userID uuid.UUID, before *pagination.Cursor, limit int) ([]models.Message, bool, string, error)
 	IsUserInConversation(ctx context.Context, conversationID string, userID uuid.UUID) (bool, error)
-	MarkMessagesAsRead(ctx context.Context, conversationID string, userID uuid.UUID, lastReadMessageID string) error
+	ConversationExists(ctx context.Context, conversationID string) (bool, error)
+	MarkMessagesAsRead(ctx context.Context, conversationID string, userID uuid.UUID, lastReadMessageID string) (bool, error)
+	GetMessagesVersion(ctx context.Context, conversationID string, userID uuid.UUID) (string, error)
 	SaveMessage(ctx context.Context, message *models.DirectMessage) error
 	GetOrCreateConversation(ctx context.Context, userID1, userID2 uuid.UUID) (string, error)
+	UpdateConversationSummary(ctx context.Context, conversationID string, message *models.DirectMessage) error
+	DeleteMessagesBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	GetDraft(ctx context.Context, conversationID string, userID uuid.UUID) (*models.Draft, error)
+	SaveDraft(ctx context.Context, userID uuid.UUID, draft *models.Draft) error
+	DeleteDraft(ctx context.Context, conversationID string, userID uuid.UUID) error
+	GetUserIDsByUsernames(ctx context.Context, usernames []string) (map[string]uuid.UUID, error)
+	SaveMentions(ctx context.Context, messageID uuid.UUID, userIDs []uuid.UUID) error
+	GetMentions(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, limit int) ([]models.Mention, bool, string, error)
+	GetMessageContext(ctx context.Context, conversationID string, userID, messageID uuid.UUID, before, after int) ([]models.Message, bool, bool, error)
+	GetMessagesAround(ctx context.Context, conversationID string, userID uuid.UUID, t time.Time, before, after int) ([]models.Message, bool, bool, error)
+	GetConversationSummary(ctx context.Context, conversationID string, userID uuid.UUID) (*models.Conversation, int64, error)
+	GetConversationIDs(ctx context.Context, userID uuid.UUID) ([]string, error)
+	GetUserInfo(ctx context.Context, userID uuid.UUID) (*models.UserInfo, error)
+	UserExists(ctx context.Context, userID uuid.UUID) (bool, error)
+	GetPresenceVisible(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error)
+	GetSettings(ctx context.Context, conversationID string, userID uuid.UUID) (*models.ConversationSettings, error)
+	SaveSettings(ctx context.Context, userID uuid.UUID, settings *models.ConversationSettings) error
+	DeleteExpiredMessages(ctx context.Context, now time.Time) (int64, error)
+	MarkMessagesDelivered(ctx context.Context, recipientID uuid.UUID, messageIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error)
+	MarkMessagesReadUpTo(ctx context.Context, conversationID string, userID uuid.UUID) ([]uuid.UUID, error)
+	ClearHistory(ctx context.Context, conversationID string, userID uuid.UUID, clearedAt time.Time) error
+	GetTranscript(ctx context.Context, conversationID string, userID uuid.UUID, limit int) ([]models.Message, error)
+	GetTranscriptAttachments(ctx context.Context, conversationID string, userID uuid.UUID) ([]models.Attachment, error)
 }
 
 // PostgresRepository implements Repository interface with PostgreSQL
 type PostgresRepository struct {
-	db     *sqlx.DB
+	db     *database.DB
 	logger logger.Logger
 }
 
 // NewPostgresRepository creates a new PostgreSQL repository
-func NewPostgresRepository(db *sqlx.DB, logger logger.Logger) *PostgresRepository {
+func NewPostgresRepository(db *database.DB, logger logger.Logger) *PostgresRepository {
 	return &PostgresRepository{
 		db:     db,
 		logger: logger,
 	}
 }
 
-// GetConversations retrieves a list of conversations for a user
-func (r *PostgresRepository) GetConversations(ctx context.Context, userID uuid.UUID) ([]models.Conversation, error) {
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// GetConversations retrieves a list of conversations for a user, limited to
+// conversations with members of the workspace unless workspaceID is uuid.Nil
+func (r *PostgresRepository) GetConversations(ctx context.Context, userID, workspaceID uuid.UUID) ([]models.Conversation, error) {
 	// First check if the user has any messages at all
 	checkQuery := `
         SELECT COUNT(*)
@@ -47,7 +97,7 @@ func (r *PostgresRepository) GetConversations(ctx context.Context, userID uuid.U
     `
 
 	var count int
-	err := r.db.GetContext(ctx, &count, checkQuery, userID)
+	err := r.conn(ctx).GetContext(ctx, &count, checkQuery, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -58,14 +108,32 @@ func (r *PostgresRepository) GetConversations(ctx context.Context, userID uuid.U
 	}
 
 	query := `
-        WITH direct_conversations AS (
-            -- Get all direct messages where user is sender or recipient
+        WITH visible_messages AS (
+            -- Direct messages where user is sender or recipient, except
+            -- those the user cleared, and whether the recipient read them
+            SELECT dm.*,
+                   (rc.user_id IS NOT NULL AND (dm.created_at, dm.id) <= (rc.last_read_message_at, rc.last_read_message_id)) AS read
+            FROM direct_messages dm
+            LEFT JOIN conversation_visibility cv
+                ON cv.user_id = $1
+               AND cv.conversation_id = LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text
+            LEFT JOIN read_cursors rc
+                ON rc.user_id = dm.recipient_id
+               AND rc.conversation_id = LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text
+            WHERE (dm.sender_id = $1 OR dm.recipient_id = $1)
+              AND (cv.cleared_at IS NULL OR dm.created_at > cv.cleared_at)
+              AND (dm.expires_at IS NULL OR dm.expires_at > NOW())
+        ),
+        direct_conversations AS (
+            -- Get all visible messages where user is sender or recipient
             SELECT
                 CASE 
                     WHEN sender_id = $1 THEN recipient_id
                     WHEN recipient_id = $1 THEN sender_id
                 END as other_user_id,
                 id as last_message_id,
+                type as last_message_type,
+                data as last_message_data,
                 content as last_message_content,
                 created_at,
                 CASE 
@@ -84,16 +152,16 @@ func (r *PostgresRepository) GetConversations(ctx context.Context, userID uuid.U
                         END
                     ORDER BY created_at DESC
                 ) as row_num
-            FROM direct_messages
-            WHERE sender_id = $1 OR recipient_id = $1
+            FROM visible_messages
         ),
         unread_counts AS (
-            -- Count unread messages for each conversation
+            -- Count the messages after the user's read cursor in each
+            -- conversation. Notes to self are never unread.
             SELECT 
                 sender_id as other_user_id, 
                 COUNT(*) as unread_count
-            FROM direct_messages
-            WHERE recipient_id = $1 AND read = FALSE
+            FROM visible_messages
+            WHERE recipient_id = $1 AND sender_id <> $1 AND NOT read
             GROUP BY sender_id
         )
         -- Join with users to get usernames
@@ -102,32 +170,56 @@ func (r *PostgresRepository) GetConversations(ctx context.Context, userID uuid.U
             dc.other_user_id as user_id, 
             u.username, 
             u.status,
+            u.status_text,
+            u.status_emoji,
+            u.status_expires_at,
             u.updated_at as last_seen,
             dc.last_message_id as message_id,
+            dc.last_message_type as type,
+            dc.last_message_data as data,
             dc.last_message_content as content,
             dc.created_at as timestamp,
             dc.delivered,
             dc.read,
-            COALESCE(uc.unread_count, 0) as unread_count
+            COALESCE(uc.unread_count, 0) as unread_count,
+            COALESCE(o.mute = 'all', FALSE) as muted,
+            COALESCE(cs.pinned, FALSE) as pinned
         FROM direct_conversations dc
         JOIN users u ON dc.other_user_id = u.id
         LEFT JOIN unread_counts uc ON dc.other_user_id = uc.other_user_id
+        LEFT JOIN conversation_notification_overrides o
+            ON o.user_id = $1
+           AND o.conversation_id = LEAST(dc.other_user_id, $1)::text || '-' || GREATEST(dc.other_user_id, $1)::text
+        LEFT JOIN conversation_settings cs
+            ON cs.user_id = $1
+           AND cs.conversation_id = LEAST(dc.other_user_id, $1)::text || '-' || GREATEST(dc.other_user_id, $1)::text
         WHERE dc.row_num = 1
+          AND ($2::uuid IS NULL OR EXISTS (
+              SELECT 1 FROM workspace_members wm
+              WHERE wm.workspace_id = $2 AND wm.user_id = dc.other_user_id
+          ))
         ORDER BY dc.created_at DESC
     `
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	var workspace *uuid.UUID
+	if workspaceID != uuid.Nil {
+		workspace = &workspaceID
+	}
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, userID, workspace)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	now := time.Now()
 	var conversations []models.Conversation
 	for rows.Next() {
 		var conversation models.Conversation
 		var otherUser models.UserInfo
 		var lastMessage models.Message
-		var status string
+		var status, statusText, statusEmoji string
+		var statusExpiresAt *time.Time
 		var lastSeen time.Time
 
 		err := rows.Scan(
@@ -135,13 +227,20 @@ func (r *PostgresRepository) GetConversations(ctx context.Context, userID uuid.U
 			&otherUser.ID,
 			&otherUser.Username,
 			&status,
+			&statusText,
+			&statusEmoji,
+			&statusExpiresAt,
 			&lastSeen,
 			&lastMessage.ID,
+			&lastMessage.Type,
+			&lastMessage.Data,
 			&lastMessage.Content,
 			&lastMessage.Timestamp,
 			&lastMessage.DeliveryStatus.Delivered,
 			&lastMessage.DeliveryStatus.Read,
 			&conversation.UnreadCount,
+			&conversation.Muted,
+			&conversation.Pinned,
 		)
 		if err != nil {
 			return nil, err
@@ -152,6 +251,7 @@ func (r *PostgresRepository) GetConversations(ctx context.Context, userID uuid.U
 
 		// Set online status based on user status field
 		otherUser.OnlineStatus = status == "online"
+		otherUser.CustomStatus = models.NewCustomStatus(statusText, statusEmoji, statusExpiresAt, now)
 		otherUser.LastSeen = lastSeen
 
 		// Populate the conversation struct
@@ -168,50 +268,183 @@ func (r *PostgresRepository) GetConversations(ctx context.Context, userID uuid.U
 	return conversations, nil
 }
 
-// GetMessages retrieves messages for a conversation with pagination
-func (r *PostgresRepository) GetMessages(ctx context.Context, conversationID string, before string, limit int) ([]models.Message, bool, string, error) {
-	// Parse conversationID to get user IDs
-	user1ID, user2ID, err := splitConversationID(conversationID)
-	if err != nil {
-		return nil, false, "", err
-	}
-
-	// Build query for direct messages
-	query := `
+// messageQuery selects API messages exchanged between the users $1 and $2
+// sent after $3, the time the viewing user cleared the conversation.
+// Callers append further conditions, ordering and limits.
+const messageQuery = `
         SELECT 
             dm.id as message_id,
+            dm.type,
+            dm.data,
             dm.content,
+            dm.format,
+            COALESCE(dm.rendered_content, '') as rendered_content,
             dm.sender_id,
             u.username as sender_username,
             dm.created_at as timestamp,
+            dm.expires_at,
+            dm.delivery_state,
             dm.delivered,
-            dm.read
+            (rc.user_id IS NOT NULL AND (dm.created_at, dm.id) <= (rc.last_read_message_at, rc.last_read_message_id)) as read
         FROM direct_messages dm
         JOIN users u ON dm.sender_id = u.id
-        WHERE (dm.sender_id = $1 AND dm.recipient_id = $2)
-           OR (dm.sender_id = $2 AND dm.recipient_id = $1)
+        LEFT JOIN read_cursors rc
+            ON rc.user_id = dm.recipient_id
+           AND rc.conversation_id = LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text
+        WHERE ((dm.sender_id = $1 AND dm.recipient_id = $2)
+           OR (dm.sender_id = $2 AND dm.recipient_id = $1))
+          AND dm.created_at > $3
+          AND (dm.expires_at IS NULL OR dm.expires_at > NOW())
     `
 
-	args := []interface{}{user1ID, user2ID}
+// GetMessages retrieves messages for a conversation with pagination
+func (r *PostgresRepository) GetMessages(ctx context.Context, conversationID string, userID uuid.UUID, before *pagination.Cursor, limit int) ([]models.Message, bool, string, error) {
+	// Parse conversationID to get user IDs
+	user1ID, user2ID, err := splitConversationID(conversationID)
+	if err != nil {
+		return nil, false, "", err
+	}
+
+	clearedAt, err := r.clearedAt(ctx, conversationID, userID)
+	if err != nil {
+		return nil, false, "", err
+	}
+
+	query := messageQuery
+	args := []interface{}{user1ID, user2ID, clearedAt}
 
 	// Add cursor condition if provided
-	if before != "" {
-		beforeID, err := uuid.Parse(before)
+	if before != nil {
+		beforeTime, err := before.Time()
 		if err != nil {
-			return nil, false, "", errors.New("invalid before cursor")
+			return nil, false, "", err
 		}
-		query += " AND dm.id < $3"
-		args = append(args, beforeID)
+		query += " AND (dm.created_at, dm.id) < ($4, $5)"
+		args = append(args, beforeTime, before.ID)
 	}
 
 	// Add ordering and limit
-	query += " ORDER BY dm.created_at DESC LIMIT $" + strconv.Itoa(len(args)+1)
+	query += " ORDER BY dm.created_at DESC, dm.id DESC LIMIT $" + strconv.Itoa(len(args)+1)
 	args = append(args, limit+1) // Get one extra message to check if there are more
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	messages, err := r.selectMessages(ctx, query, args...)
 	if err != nil {
 		return nil, false, "", err
 	}
+
+	// Check if there are more messages
+	messages, hasMore, nextCursor := pagination.Trim(messages, limit, func(m models.Message) pagination.Cursor {
+		return pagination.TimeCursor(m.Timestamp, m.ID)
+	})
+
+	return messages, hasMore, nextCursor, nil
+}
+
+// GetMessageContext retrieves a message together with up to before older
+// and after newer messages in the same conversation, newest first
+func (r *PostgresRepository) GetMessageContext(ctx context.Context, conversationID string, userID, messageID uuid.UUID, before, after int) ([]models.Message, bool, bool, error) {
+	user1ID, user2ID, err := splitConversationID(conversationID)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	clearedAt, err := r.clearedAt(ctx, conversationID, userID)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	// Find the target message, which must belong to the conversation
+	target, err := r.selectMessages(ctx, messageQuery+" AND dm.id = $4", user1ID, user2ID, clearedAt, messageID)
+	if err != nil {
+		return nil, false, false, err
+	}
+	if len(target) == 0 {
+		return nil, false, false, ErrMessageNotFound
+	}
+	pivot := target[0]
+
+	older, newer, hasMoreBefore, hasMoreAfter, err := r.messagesAround(ctx, user1ID, user2ID, clearedAt, pivot.Timestamp, pivot.ID, before, after)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	// Assemble newest first
+	messages := make([]models.Message, 0, len(newer)+1+len(older))
+	for i := len(newer) - 1; i >= 0; i-- {
+		messages = append(messages, newer[i])
+	}
+	messages = append(messages, pivot)
+	messages = append(messages, older...)
+
+	return messages, hasMoreBefore, hasMoreAfter, nil
+}
+
+// GetMessagesAround retrieves up to before messages sent before t and up to
+// after messages sent at or after t, newest first
+func (r *PostgresRepository) GetMessagesAround(ctx context.Context, conversationID string, userID uuid.UUID, t time.Time, before, after int) ([]models.Message, bool, bool, error) {
+	user1ID, user2ID, err := splitConversationID(conversationID)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	clearedAt, err := r.clearedAt(ctx, conversationID, userID)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	// No message has the nil ID, so messages sent exactly at t sort after it
+	older, newer, hasMoreBefore, hasMoreAfter, err := r.messagesAround(ctx, user1ID, user2ID, clearedAt, t, uuid.Nil, before, after)
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	// Assemble newest first
+	messages := make([]models.Message, 0, len(newer)+len(older))
+	for i := len(newer) - 1; i >= 0; i-- {
+		messages = append(messages, newer[i])
+	}
+	messages = append(messages, older...)
+
+	return messages, hasMoreBefore, hasMoreAfter, nil
+}
+
+// messagesAround retrieves the messages sent after clearedAt on either side
+// of the position (t, id). Older messages are returned newest first, newer
+// ones oldest first.
+func (r *PostgresRepository) messagesAround(ctx context.Context, user1ID, user2ID uuid.UUID, clearedAt, t time.Time, id uuid.UUID, before, after int) ([]models.Message, []models.Message, bool, bool, error) {
+	// Get one extra message on each side to check if there are more
+	older, err := r.selectMessages(ctx,
+		messageQuery+" AND (dm.created_at, dm.id) < ($4, $5) ORDER BY dm.created_at DESC, dm.id DESC LIMIT $6",
+		user1ID, user2ID, clearedAt, t, id, before+1)
+	if err != nil {
+		return nil, nil, false, false, err
+	}
+
+	newer, err := r.selectMessages(ctx,
+		messageQuery+" AND (dm.created_at, dm.id) > ($4, $5) ORDER BY dm.created_at ASC, dm.id ASC LIMIT $6",
+		user1ID, user2ID, clearedAt, t, id, after+1)
+	if err != nil {
+		return nil, nil, false, false, err
+	}
+
+	hasMoreBefore := len(older) > before
+	if hasMoreBefore {
+		older = older[:before]
+	}
+	hasMoreAfter := len(newer) > after
+	if hasMoreAfter {
+		newer = newer[:after]
+	}
+
+	return older, newer, hasMoreBefore, hasMoreAfter, nil
+}
+
+// selectMessages runs a query built on messageQuery and scans the results
+func (r *PostgresRepository) selectMessages(ctx context.Context, query string, args ...interface{}) ([]models.Message, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var messages []models.Message
@@ -221,15 +454,21 @@ func (r *PostgresRepository) GetMessages(ctx context.Context, conversationID str
 
 		err := rows.Scan(
 			&msg.ID,
+			&msg.Type,
+			&msg.Data,
 			&msg.Content,
+			&msg.Format,
+			&msg.RenderedContent,
 			&msg.SenderID,
 			&msg.SenderUsername,
 			&msg.Timestamp,
+			&msg.ExpiresAt,
+			&deliveryStatus.State,
 			&deliveryStatus.Delivered,
 			&deliveryStatus.Read,
 		)
 		if err != nil {
-			return nil, false, "", err
+			return nil, err
 		}
 
 		msg.DeliveryStatus = deliveryStatus
@@ -237,20 +476,10 @@ func (r *PostgresRepository) GetMessages(ctx context.Context, conversationID str
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, false, "", err
-	}
-
-	// Check if there are more messages
-	hasMore := len(messages) > limit
-	var nextCursor string
-
-	if hasMore {
-		// Remove the extra message
-		nextCursor = messages[limit].ID.String()
-		messages = messages[:limit]
+		return nil, err
 	}
 
-	return messages, hasMore, nextCursor, nil
+	return messages, nil
 }
 
 // IsUserInConversation checks if a user is part of a conversation
@@ -264,12 +493,15 @@ func (r *PostgresRepository) IsUserInConversation(ctx context.Context, conversat
 	return userID == user1ID || userID == user2ID, nil
 }
 
-// MarkMessagesAsRead marks messages in a conversation as read
-func (r *PostgresRepository) MarkMessagesAsRead(ctx context.Context, conversationID string, userID uuid.UUID, lastReadMessageID string) error {
+// MarkMessagesAsRead moves the user's read cursor in a conversation to a
+// message, marking it and every earlier message read. The cursor never
+// moves back, and messages from other conversations are ignored. It
+// reports whether the cursor moved.
+func (r *PostgresRepository) MarkMessagesAsRead(ctx context.Context, conversationID string, userID uuid.UUID, lastReadMessageID string) (bool, error) {
 	// Parse conversationID to get user IDs
 	user1ID, user2ID, err := splitConversationID(conversationID)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Determine the other user ID
@@ -279,51 +511,115 @@ func (r *PostgresRepository) MarkMessagesAsRead(ctx context.Context, conversatio
 	} else if userID == user2ID {
 		otherUserID = user1ID
 	} else {
-		return errors.New("user is not part of this conversation")
+		return false, errors.New("user is not part of this conversation")
+	}
+
+	messageID, err := uuid.Parse(lastReadMessageID)
+	if err != nil {
+		return false, ErrMessageNotFound
 	}
 
-	// Update read status for messages from the other user
 	query := `
-        UPDATE direct_messages
-        SET read = TRUE
-        WHERE sender_id = $1 AND recipient_id = $2 AND read = FALSE
+        INSERT INTO read_cursors (user_id, conversation_id, last_read_message_id, last_read_message_at, read_at)
+        SELECT $1, $2, dm.id, dm.created_at, NOW()
+        FROM direct_messages dm
+        WHERE dm.id = $3
+          AND ((dm.sender_id = $1 AND dm.recipient_id = $4) OR (dm.sender_id = $4 AND dm.recipient_id = $1))
+        ON CONFLICT (user_id, conversation_id) DO UPDATE
+        SET last_read_message_id = EXCLUDED.last_read_message_id,
+            last_read_message_at = EXCLUDED.last_read_message_at,
+            read_at = EXCLUDED.read_at
+        WHERE (read_cursors.last_read_message_at, read_cursors.last_read_message_id)
+            < (EXCLUDED.last_read_message_at, EXCLUDED.last_read_message_id)
     `
 
-	_, err = r.db.ExecContext(ctx, query, otherUserID, userID)
-	return err
+	result, err := r.conn(ctx).ExecContext(ctx, query, userID, conversationID, messageID, otherUserID)
+	if err != nil {
+		return false, mapConstraintError(err)
+	}
+	moved, err := result.RowsAffected()
+	return moved > 0, err
+}
+
+// GetMessagesVersion returns what a conversation's messages as seen by a
+// user depend on: the summary version, which changes with every message,
+// when the user cleared the conversation and both read cursors
+func (r *PostgresRepository) GetMessagesVersion(ctx context.Context, conversationID string, userID uuid.UUID) (string, error) {
+	query := `
+        SELECT
+            COALESCE((SELECT version FROM conversation_summaries WHERE conversation_id = $1), 0)::text
+            || ':' || COALESCE((SELECT cleared_at::text FROM conversation_visibility WHERE conversation_id = $1 AND user_id = $2), '')
+            || ':' || COALESCE((
+                SELECT string_agg(user_id::text || '@' || last_read_message_id::text, ',' ORDER BY user_id)
+                FROM read_cursors
+                WHERE conversation_id = $1
+            ), '')
+    `
+
+	var version string
+	err := r.conn(ctx).GetContext(ctx, &version, query, conversationID, userID)
+	return version, err
 }
 
-// SaveMessage saves a direct message to the database
+// SaveMessage saves a direct message to the database and sets its
+// CreatedAt from the database clock, so messages saved by different
+// servers are ordered by one clock. The time is kept after the
+// conversation's last message, locking its summary row until the
+// transaction ends, so a conversation's history never reorders. Text
+// messages expire after the sender's disappearing timer, if they set one.
 func (r *PostgresRepository) SaveMessage(ctx context.Context, message *models.DirectMessage) error {
 	query := `
-        INSERT INTO direct_messages (id, sender_id, recipient_id, content, delivered, read, created_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        INSERT INTO direct_messages (id, type, data, sender_id, recipient_id, content, format, rendered_content, delivered, created_at, expires_at)
+        VALUES ($1, $8, $9, $2, $3, $4, $5, NULLIF($6, ''), $7, GREATEST(
+            clock_timestamp(),
+            (
+                SELECT last_message_at + INTERVAL '1 microsecond'
+                FROM conversation_summaries
+                WHERE conversation_id = LEAST($2::uuid, $3::uuid)::text || '-' || GREATEST($2::uuid, $3::uuid)::text
+                FOR UPDATE
+            )
+        ), (
+            SELECT clock_timestamp() + disappearing_timer * INTERVAL '1 second'
+            FROM conversation_settings
+            WHERE user_id = $2
+              AND conversation_id = LEAST($2::uuid, $3::uuid)::text || '-' || GREATEST($2::uuid, $3::uuid)::text
+              AND disappearing_timer > 0
+              AND $8 = 'text'
+        ))
+        RETURNING created_at, expires_at, delivery_state
     `
 
-	// Log what we're trying to insert
-	fmt.Println("Saving message to database",
+	if message.Type == "" {
+		message.Type = models.DirectMessageText
+	}
+
+	r.logger.WithContext(ctx).Debug("Saving message",
 		"message_id", message.ID,
+		"type", message.Type,
 		"sender_id", message.SenderID,
-		"recipient_id", message.RecipientID)
+		"recipient_id", message.RecipientID,
+		"content", logger.Sensitive(message.Content))
 
-	_, err := r.db.ExecContext(
+	err := r.conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		message.ID,
 		message.SenderID,
 		message.RecipientID,
 		message.Content,
+		message.Format,
+		message.RenderedContent,
 		message.Delivered,
-		message.Read,
-		message.CreatedAt,
-	)
+		message.Type,
+		message.Data,
+	).Scan(&message.CreatedAt, &message.ExpiresAt, &message.DeliveryState)
 
 	if err != nil {
-		r.logger.Error("Failed to save message", "error", err)
-		return err
+		r.logger.WithContext(ctx).Error("Failed to save message", "error", err)
+		return mapConstraintError(err)
 	}
 
-	r.logger.Info("Message saved successfully", "message_id", message.ID)
+	r.logger.WithContext(ctx).Debug("Message saved", "message_id", message.ID)
 	return nil
 }
 
@@ -342,13 +638,583 @@ func (r *PostgresRepository) GetOrCreateConversation(ctx context.Context, userID
 	return smaller.String() + "-" + larger.String(), nil
 }
 
+// ConversationExists checks whether any message has been exchanged in a conversation
+func (r *PostgresRepository) ConversationExists(ctx context.Context, conversationID string) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM conversation_summaries WHERE conversation_id = $1)"
+
+	var exists bool
+	err := r.conn(ctx).GetContext(ctx, &exists, query, conversationID)
+	return exists, err
+}
+
+// UpdateConversationSummary records a new message in the conversation summary
+func (r *PostgresRepository) UpdateConversationSummary(ctx context.Context, conversationID string, message *models.DirectMessage) error {
+	query := `
+        INSERT INTO conversation_summaries (conversation_id, last_message_id, last_message_at, message_count, version, updated_at)
+        VALUES ($1, $2, $3, 1, 1, NOW())
+        ON CONFLICT (conversation_id) DO UPDATE
+        SET last_message_id = EXCLUDED.last_message_id,
+            last_message_at = EXCLUDED.last_message_at,
+            message_count = conversation_summaries.message_count + 1,
+            version = conversation_summaries.version + 1,
+            updated_at = NOW()
+    `
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, conversationID, message.ID, message.CreatedAt)
+	return mapConstraintError(err)
+}
+
+// GetConversationSummary retrieves a single conversation as seen by userID,
+// along with the summary version so clients can discard stale updates
+func (r *PostgresRepository) GetConversationSummary(ctx context.Context, conversationID string, userID uuid.UUID) (*models.Conversation, int64, error) {
+	user1ID, user2ID, err := splitConversationID(conversationID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	otherUserID := user1ID
+	if otherUserID == userID {
+		otherUserID = user2ID
+	}
+
+	query := `
+        SELECT
+            u.username,
+            u.status,
+            u.status_text,
+            u.status_emoji,
+            u.status_expires_at,
+            u.updated_at as last_seen,
+            dm.id as message_id,
+            dm.type,
+            dm.data,
+            dm.content,
+            dm.format,
+            COALESCE(dm.rendered_content, '') as rendered_content,
+            dm.sender_id,
+            dm.created_at as timestamp,
+            dm.delivered,
+            (rrc.user_id IS NOT NULL AND (dm.created_at, dm.id) <= (rrc.last_read_message_at, rrc.last_read_message_id)) as read,
+            (
+                SELECT COUNT(*)
+                FROM direct_messages unread
+                WHERE unread.sender_id = $3 AND unread.recipient_id = $2 AND $2 <> $3
+                  AND (rc.user_id IS NULL OR (unread.created_at, unread.id) > (rc.last_read_message_at, rc.last_read_message_id))
+                  AND unread.created_at > COALESCE(cv.cleared_at, '-infinity')
+            ) as unread_count,
+            COALESCE(o.mute = 'all', FALSE) as muted,
+            COALESCE(cs.pinned, FALSE) as pinned,
+            s.version
+        FROM conversation_summaries s
+        JOIN users u ON u.id = $3
+        JOIN direct_messages dm ON dm.id = s.last_message_id
+        LEFT JOIN conversation_visibility cv ON cv.user_id = $2 AND cv.conversation_id = s.conversation_id
+        LEFT JOIN conversation_notification_overrides o ON o.user_id = $2 AND o.conversation_id = s.conversation_id
+        LEFT JOIN conversation_settings cs ON cs.user_id = $2 AND cs.conversation_id = s.conversation_id
+        -- The user's own cursor counts their unread messages; the last
+        -- message's recipient's cursor says whether it was read
+        LEFT JOIN read_cursors rc ON rc.user_id = $2 AND rc.conversation_id = s.conversation_id
+        LEFT JOIN read_cursors rrc ON rrc.user_id = dm.recipient_id AND rrc.conversation_id = s.conversation_id
+        WHERE s.conversation_id = $1
+          -- Conversations the user cleared stay hidden until a new message
+          AND (cv.cleared_at IS NULL OR dm.created_at > cv.cleared_at)
+    `
+
+	conversation := models.Conversation{ConversationID: conversationID}
+	var status, statusText, statusEmoji string
+	var statusExpiresAt *time.Time
+	var senderID uuid.UUID
+	var version int64
+
+	err = r.conn(ctx).QueryRowContext(ctx, query, conversationID, userID, otherUserID).Scan(
+		&conversation.OtherUser.Username,
+		&status,
+		&statusText,
+		&statusEmoji,
+		&statusExpiresAt,
+		&conversation.OtherUser.LastSeen,
+		&conversation.LastMessage.ID,
+		&conversation.LastMessage.Type,
+		&conversation.LastMessage.Data,
+		&conversation.LastMessage.Content,
+		&conversation.LastMessage.Format,
+		&conversation.LastMessage.RenderedContent,
+		&senderID,
+		&conversation.LastMessage.Timestamp,
+		&conversation.LastMessage.DeliveryStatus.Delivered,
+		&conversation.LastMessage.DeliveryStatus.Read,
+		&conversation.UnreadCount,
+		&conversation.Muted,
+		&conversation.Pinned,
+		&version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, ErrConversationNotFound
+		}
+		return nil, 0, err
+	}
+
+	conversation.OtherUser.ID = otherUserID
+	conversation.OtherUser.OnlineStatus = status == "online"
+	conversation.OtherUser.CustomStatus = models.NewCustomStatus(statusText, statusEmoji, statusExpiresAt, time.Now())
+	conversation.LastMessage.SenderID = senderID.String()
+
+	return &conversation, version, nil
+}
+
+// GetConversationIDs retrieves the IDs of all conversations a user takes part in
+func (r *PostgresRepository) GetConversationIDs(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	query := `
+        SELECT conversation_id
+        FROM conversation_summaries
+        WHERE conversation_id LIKE $1 || '-%' OR conversation_id LIKE '%-' || $1
+    `
+
+	var ids []string
+	if err := r.conn(ctx).SelectContext(ctx, &ids, query, userID.String()); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetSettings retrieves a user's settings for a conversation, with the
+// defaults for settings the user never changed
+func (r *PostgresRepository) GetSettings(ctx context.Context, conversationID string, userID uuid.UUID) (*models.ConversationSettings, error) {
+	query := `
+        SELECT
+            $2::text as conversation_id,
+            COALESCE(o.mute, 'none') as mute,
+            COALESCE(s.pinned, FALSE) as pinned,
+            COALESCE(s.notification_sound, '') as notification_sound,
+            COALESCE(s.disappearing_timer, 0) as disappearing_timer,
+            s.updated_at
+        FROM (SELECT $1::uuid as user_id) k
+        LEFT JOIN conversation_settings s ON s.user_id = k.user_id AND s.conversation_id = $2
+        LEFT JOIN conversation_notification_overrides o ON o.user_id = k.user_id AND o.conversation_id = $2
+    `
+
+	var settings models.ConversationSettings
+	if err := r.conn(ctx).GetContext(ctx, &settings, query, userID, conversationID); err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// SaveSettings creates or replaces a user's settings for a conversation,
+// except the mute level, which is stored with the notification overrides
+func (r *PostgresRepository) SaveSettings(ctx context.Context, userID uuid.UUID, settings *models.ConversationSettings) error {
+	query := `
+        INSERT INTO conversation_settings (user_id, conversation_id, pinned, notification_sound, disappearing_timer, updated_at)
+        VALUES ($1, $2, $3, $4, $5, NOW())
+        ON CONFLICT (user_id, conversation_id) DO UPDATE
+        SET pinned = EXCLUDED.pinned,
+            notification_sound = EXCLUDED.notification_sound,
+            disappearing_timer = EXCLUDED.disappearing_timer,
+            updated_at = NOW()
+        RETURNING updated_at
+    `
+
+	err := r.conn(ctx).QueryRowContext(ctx, query, userID, settings.ConversationID, settings.Pinned,
+		settings.NotificationSound, settings.DisappearingTimer).Scan(&settings.UpdatedAt)
+	return mapConstraintError(err)
+}
+
+// DeleteExpiredMessages deletes the disappearing messages that expired by
+// now. The summary versions of their conversations are bumped in the same
+// statement, so cached message lists are revalidated.
+func (r *PostgresRepository) DeleteExpiredMessages(ctx context.Context, now time.Time) (int64, error) {
+	return r.deleteMessages(ctx, "expires_at <= $1", now)
+}
+
+// MarkMessagesDelivered moves the stored messages among messageIDs that
+// were sent to the recipient to delivered, returning the IDs of those that
+// moved by sender. The summary versions of their conversations are bumped
+// in the same statement, so cached message lists are revalidated.
+func (r *PostgresRepository) MarkMessagesDelivered(ctx context.Context, recipientID uuid.UUID, messageIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	query := `
+        WITH moved AS (
+            UPDATE direct_messages
+            SET delivery_state = $3, delivered = TRUE, delivery_updated_at = NOW()
+            WHERE id = ANY($1) AND recipient_id = $2 AND delivery_state = ANY($4)
+            RETURNING id, sender_id, recipient_id
+        ), bumped AS (
+            UPDATE conversation_summaries
+            SET version = version + 1
+            WHERE conversation_id IN (
+                SELECT LEAST(sender_id, recipient_id)::text || '-' || GREATEST(sender_id, recipient_id)::text
+                FROM moved
+            )
+        )
+        SELECT id, sender_id FROM moved
+    `
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, pq.Array(messageIDs), recipientID, models.DeliveryDelivered,
+		pq.Array(models.DeliverySources(models.DeliveryDelivered)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	moved := make(map[uuid.UUID][]uuid.UUID)
+	for rows.Next() {
+		var id, senderID uuid.UUID
+		if err := rows.Scan(&id, &senderID); err != nil {
+			return nil, err
+		}
+		moved[senderID] = append(moved[senderID], id)
+	}
+
+	return moved, rows.Err()
+}
+
+// MarkMessagesReadUpTo moves the messages the user received in a
+// conversation up to their read cursor to read, returning the IDs of the
+// messages that moved
+func (r *PostgresRepository) MarkMessagesReadUpTo(ctx context.Context, conversationID string, userID uuid.UUID) ([]uuid.UUID, error) {
+	user1ID, user2ID, err := splitConversationID(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	senderID := user1ID
+	if senderID == userID {
+		senderID = user2ID
+	}
+
+	query := `
+        UPDATE direct_messages dm
+        SET delivery_state = $3, delivered = TRUE, delivery_updated_at = NOW()
+        FROM read_cursors rc
+        WHERE rc.user_id = $1
+          AND rc.conversation_id = $2
+          AND dm.sender_id = $5
+          AND dm.recipient_id = $1
+          AND (dm.created_at, dm.id) <= (rc.last_read_message_at, rc.last_read_message_id)
+          AND dm.delivery_state = ANY($4)
+        RETURNING dm.id
+    `
+
+	var ids []uuid.UUID
+	err = r.conn(ctx).SelectContext(ctx, &ids, query, userID, conversationID, models.DeliveryRead,
+		pq.Array(models.DeliverySources(models.DeliveryRead)), senderID)
+	return ids, err
+}
+
+// GetUserInfo retrieves a user's public profile
+func (r *PostgresRepository) GetUserInfo(ctx context.Context, userID uuid.UUID) (*models.UserInfo, error) {
+	query := `
+        SELECT id, username, display_name, status, updated_at
+        FROM users
+        WHERE id = $1
+    `
+
+	var user models.UserInfo
+	if err := r.conn(ctx).GetContext(ctx, &user, query, userID); err != nil {
+		return nil, err
+	}
+	user.OnlineStatus = user.Status == "online"
+
+	return &user, nil
+}
+
+// UserExists reports whether a user exists and has not been erased
+func (r *PostgresRepository) UserExists(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.conn(ctx).GetContext(ctx, &exists,
+		"SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND erased_at IS NULL)", userID)
+	return exists, err
+}
+
+// GetPresenceVisible returns the users among userIDs who let the viewer
+// see when they are online: they show their online status and would
+// accept a conversation from the viewer
+func (r *PostgresRepository) GetPresenceVisible(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+        SELECT u.id
+        FROM users u
+        WHERE u.id = ANY($2) AND u.erased_at IS NULL AND u.show_online_status
+          AND (u.message_privacy = $3
+               OR (u.message_privacy = $4 AND EXISTS (
+                   SELECT 1 FROM contacts c WHERE c.user_id = u.id AND c.contact_id = $1
+               )))
+    `
+
+	var visible []uuid.UUID
+	err := r.conn(ctx).SelectContext(ctx, &visible, query, viewerID, pq.Array(userIDs), models.PrivacyEveryone, models.PrivacyContacts)
+	return visible, err
+}
+
+// DeleteMessagesBefore deletes all direct messages created before the
+// cutoff, bumping the summary versions of their conversations
+func (r *PostgresRepository) DeleteMessagesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return r.deleteMessages(ctx, "created_at < $1", cutoff)
+}
+
+// deleteMessages deletes the direct messages matching condition and bumps
+// the summary versions of their conversations in the same statement,
+// returning the number of messages deleted
+func (r *PostgresRepository) deleteMessages(ctx context.Context, condition string, args ...interface{}) (int64, error) {
+	query := `
+        WITH deleted AS (
+            DELETE FROM direct_messages
+            WHERE ` + condition + `
+            RETURNING sender_id, recipient_id
+        ), bumped AS (
+            UPDATE conversation_summaries
+            SET version = version + 1
+            WHERE conversation_id IN (
+                SELECT DISTINCT LEAST(sender_id, recipient_id)::text || '-' || GREATEST(sender_id, recipient_id)::text
+                FROM deleted
+            )
+        )
+        SELECT COUNT(*) FROM deleted
+    `
+
+	var deleted int64
+	err := r.conn(ctx).GetContext(ctx, &deleted, query, args...)
+	return deleted, err
+}
+
+// GetDraft retrieves a user's draft for a conversation
+func (r *PostgresRepository) GetDraft(ctx context.Context, conversationID string, userID uuid.UUID) (*models.Draft, error) {
+	query := `
+        SELECT conversation_id, content, updated_at
+        FROM drafts
+        WHERE user_id = $1 AND conversation_id = $2
+    `
+
+	var draft models.Draft
+	err := r.conn(ctx).GetContext(ctx, &draft, query, userID, conversationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDraftNotFound
+		}
+		return nil, err
+	}
+
+	return &draft, nil
+}
+
+// SaveDraft creates or replaces a user's draft for a conversation
+func (r *PostgresRepository) SaveDraft(ctx context.Context, userID uuid.UUID, draft *models.Draft) error {
+	query := `
+        INSERT INTO drafts (user_id, conversation_id, content, updated_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id, conversation_id) DO UPDATE
+        SET content = EXCLUDED.content,
+            updated_at = EXCLUDED.updated_at
+    `
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, draft.ConversationID, draft.Content, draft.UpdatedAt)
+	return mapConstraintError(err)
+}
+
+// DeleteDraft removes a user's draft for a conversation
+func (r *PostgresRepository) DeleteDraft(ctx context.Context, conversationID string, userID uuid.UUID) error {
+	query := `
+        DELETE FROM drafts
+        WHERE user_id = $1 AND conversation_id = $2
+    `
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, conversationID)
+	return err
+}
+
+// GetUserIDsByUsernames resolves usernames to user IDs, skipping unknown usernames
+func (r *PostgresRepository) GetUserIDsByUsernames(ctx context.Context, usernames []string) (map[string]uuid.UUID, error) {
+	query := `
+        SELECT id, username
+        FROM users
+        WHERE username = ANY($1)
+    `
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]uuid.UUID, len(usernames))
+	for rows.Next() {
+		var id uuid.UUID
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+		ids[username] = id
+	}
+
+	return ids, rows.Err()
+}
+
+// SaveMentions records the users mentioned in a message
+func (r *PostgresRepository) SaveMentions(ctx context.Context, messageID uuid.UUID, userIDs []uuid.UUID) error {
+	query := `
+        INSERT INTO mentions (message_id, user_id, created_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (message_id, user_id) DO NOTHING
+    `
+
+	for _, userID := range userIDs {
+		if _, err := r.conn(ctx).ExecContext(ctx, query, messageID, userID); err != nil {
+			return mapConstraintError(err)
+		}
+	}
+
+	return nil
+}
+
+// GetMentions retrieves messages that mention a user with pagination
+func (r *PostgresRepository) GetMentions(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, limit int) ([]models.Mention, bool, string, error) {
+	query := `
+        SELECT
+            dm.id as message_id,
+            LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text as conversation_id,
+            dm.sender_id,
+            u.username as sender_username,
+            dm.content,
+            dm.created_at as timestamp
+        FROM mentions m
+        JOIN direct_messages dm ON m.message_id = dm.id
+        JOIN users u ON dm.sender_id = u.id
+        LEFT JOIN conversation_visibility cv
+            ON cv.user_id = m.user_id
+           AND cv.conversation_id = LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text
+        WHERE m.user_id = $1
+          AND (cv.cleared_at IS NULL OR dm.created_at > cv.cleared_at)
+    `
+
+	args := []interface{}{userID}
+
+	// Add cursor condition if provided
+	if before != nil {
+		beforeTime, err := before.Time()
+		if err != nil {
+			return nil, false, "", err
+		}
+		query += " AND (dm.created_at, dm.id) < ($2, $3)"
+		args = append(args, beforeTime, before.ID)
+	}
+
+	query += " ORDER BY dm.created_at DESC, dm.id DESC LIMIT $" + strconv.Itoa(len(args)+1)
+	args = append(args, limit+1) // Get one extra row to check if there are more
+
+	var mentions []models.Mention
+	if err := r.conn(ctx).SelectContext(ctx, &mentions, query, args...); err != nil {
+		return nil, false, "", err
+	}
+
+	mentions, hasMore, nextCursor := pagination.Trim(mentions, limit, func(m models.Mention) pagination.Cursor {
+		return pagination.TimeCursor(m.Timestamp, m.MessageID)
+	})
+
+	return mentions, hasMore, nextCursor, nil
+}
+
+// ClearHistory hides a conversation's messages sent up to clearedAt from
+// the user. Clearing again only ever moves the marker forward.
+func (r *PostgresRepository) ClearHistory(ctx context.Context, conversationID string, userID uuid.UUID, clearedAt time.Time) error {
+	query := `
+        INSERT INTO conversation_visibility (user_id, conversation_id, cleared_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (user_id, conversation_id) DO UPDATE
+        SET cleared_at = GREATEST(conversation_visibility.cleared_at, EXCLUDED.cleared_at)
+    `
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, conversationID, clearedAt)
+	return mapConstraintError(err)
+}
+
+// GetTranscript retrieves up to limit of the messages in a conversation
+// visible to the user, oldest first
+func (r *PostgresRepository) GetTranscript(ctx context.Context, conversationID string, userID uuid.UUID, limit int) ([]models.Message, error) {
+	user1ID, user2ID, err := splitConversationID(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	clearedAt, err := r.clearedAt(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.selectMessages(ctx,
+		messageQuery+" ORDER BY dm.created_at ASC, dm.id ASC LIMIT $4",
+		user1ID, user2ID, clearedAt, limit)
+}
+
+// GetTranscriptAttachments retrieves the attachments uploaded to a
+// conversation since the user last cleared it, oldest first
+func (r *PostgresRepository) GetTranscriptAttachments(ctx context.Context, conversationID string, userID uuid.UUID) ([]models.Attachment, error) {
+	clearedAt, err := r.clearedAt(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT id, conversation_id, uploader_id, filename, content_type, size, storage_key, created_at
+        FROM attachments
+        WHERE conversation_id = $1 AND created_at > $2
+        ORDER BY created_at ASC, id ASC
+    `
+
+	var attachments []models.Attachment
+	err = r.conn(ctx).SelectContext(ctx, &attachments, query, conversationID, clearedAt)
+	return attachments, err
+}
+
+// clearedAt returns when the user last cleared a conversation, or the zero
+// time if they never did
+func (r *PostgresRepository) clearedAt(ctx context.Context, conversationID string, userID uuid.UUID) (time.Time, error) {
+	query := `
+        SELECT cleared_at
+        FROM conversation_visibility
+        WHERE user_id = $1 AND conversation_id = $2
+    `
+
+	var clearedAt time.Time
+	err := r.conn(ctx).GetContext(ctx, &clearedAt, query, userID, conversationID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+	return clearedAt, nil
+}
+
 // Helper functions
 
+// mapConstraintError maps a statement rejected by a table constraint to the
+// domain error behind it: a reference to a user or message that does not
+// exist, or a value outside a column's rules. Other errors are returned
+// unchanged.
+func mapConstraintError(err error) error {
+	pqErr := database.ConstraintViolation(err)
+	if pqErr == nil {
+		return err
+	}
+
+	switch pqErr.Code {
+	case database.ForeignKeyViolation:
+		switch pqErr.Constraint {
+		case directMessagesRecipientKey:
+			return ErrRecipientNotFound
+		case mentionsMessageKey, summariesLastMessageKey:
+			return ErrMessageNotFound
+		}
+		// Every other foreign key references users
+		return ErrUserNotFound
+	case database.NotNullViolation, database.CheckViolation:
+		return fmt.Errorf("%w: %s", ErrInvalidData, database.ConstraintField(pqErr))
+	}
+	return err
+}
+
 // splitConversationID splits a conversation ID into its component UUID parts
 func splitConversationID(conversationID string) (uuid.UUID, uuid.UUID, error) {
 	// A standard UUID is 36 characters (including hyphens)
 	if len(conversationID) < 73 { // 36 + 1 + 36 = 73
-		return uuid.Nil, uuid.Nil, errors.New("invalid conversation ID format: too short")
+		return uuid.Nil, uuid.Nil, fmt.Errorf("%w: too short", ErrInvalidConversationID)
 	}
 
 	// Extract the two UUIDs
@@ -358,12 +1224,12 @@ func splitConversationID(conversationID string) (uuid.UUID, uuid.UUID, error) {
 	// Parse the UUID strings
 	firstUuid, err := uuid.Parse(firstUuidStr)
 	if err != nil {
-		return uuid.Nil, uuid.Nil, errors.New("invalid first UUID in conversation ID")
+		return uuid.Nil, uuid.Nil, fmt.Errorf("%w: invalid first UUID", ErrInvalidConversationID)
 	}
 
 	secondUuid, err := uuid.Parse(secondUuidStr)
 	if err != nil {
-		return uuid.Nil, uuid.Nil, errors.New("invalid second UUID in conversation ID")
+		return uuid.Nil, uuid.Nil, fmt.Errorf("%w: invalid second UUID", ErrInvalidConversationID)
 	}
 
 	return firstUuid, secondUuid, nil
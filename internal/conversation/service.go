@@ -3,9 +3,20 @@ package conversation
 import (
 	"context"
 	"errors"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/events"
+	"github.com/codingminions/Whatsapp-Lite/internal/features"
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/markdown"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
+	"github.com/codingminions/Whatsapp-Lite/pkg/sanitize"
 	"github.com/google/uuid"
 )
 
@@ -13,71 +24,185 @@ import (
 var (
 	ErrConversationNotFound = errors.New("conversation not found")
 	ErrUnauthorized         = errors.New("user not authorized to access this conversation")
+	ErrFeatureDisabled      = errors.New("feature is not enabled for this user")
+	ErrNotContact           = errors.New("recipient has not accepted the sender as a contact")
+	ErrNotAccepting         = errors.New("recipient is not accepting new conversations from the sender")
+	ErrNotInWorkspace       = errors.New("recipient is not a member of the workspace")
+	ErrRecipientNotFound    = errors.New("recipient does not exist")
 )
 
 // Service handles conversation business logic
 type Service interface {
-	GetConversations(ctx context.Context, userID uuid.UUID) (*models.ConversationListResponse, error)
-	GetMessages(ctx context.Context, conversationID string, userID uuid.UUID, before string, limit int) (*models.MessageListResponse, error)
+	GetConversations(ctx context.Context, userID, workspaceID uuid.UUID) (*models.ConversationListResponse, error)
+	GetMessages(ctx context.Context, conversationID string, userID uuid.UUID, before *pagination.Cursor, limit int) (*models.MessageListResponse, error)
+	BatchGetMessages(ctx context.Context, userID uuid.UUID, conversationIDs []string, limit int) (*models.BatchGetMessagesResponse, error)
+	GetMessagesAround(ctx context.Context, conversationID string, userID uuid.UUID, at time.Time, limit int) (*models.MessageListResponse, error)
+	MessagesVersion(ctx context.Context, conversationID string, userID uuid.UUID) (string, error)
+	GetMessageContext(ctx context.Context, conversationID string, userID, messageID uuid.UUID, before, after int) (*models.MessageContextResponse, error)
+	SaveMessage(ctx context.Context, message *models.DirectMessage) error
+	SendMessage(ctx context.Context, message *models.DirectMessage, senderUsername string) (*models.DirectMessageData, error)
+	RecordSystemMessage(ctx context.Context, messageType string, senderID, recipientID uuid.UUID, data models.SystemData) error
+	GetRecipient(ctx context.Context, conversationID string, userID uuid.UUID) (uuid.UUID, error)
+	MarkRead(ctx context.Context, conversationID string, userID uuid.UUID, username, sessionID, lastReadMessageID string) error
+	GetDraft(ctx context.Context, conversationID string, userID uuid.UUID) (*models.Draft, error)
+	SaveDraft(ctx context.Context, conversationID string, userID uuid.UUID, content string) (*models.Draft, error)
+	GetMentions(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, limit int) (*models.MentionListResponse, error)
+	ClearHistory(ctx context.Context, conversationID string, userID uuid.UUID) error
+	DeleteConversation(ctx context.Context, conversationID string, userID uuid.UUID) error
+	ExportConversation(ctx context.Context, conversationID string, userID uuid.UUID, includeMedia bool) (*models.ConversationExport, error)
+	NotifyPresenceChanged(ctx context.Context, userID uuid.UUID)
+	VisiblePresence(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error)
+	DeliverOffline(ctx context.Context, userID uuid.UUID, message *models.WebSocketMessage)
+	GetSettings(ctx context.Context, conversationID string, userID uuid.UUID) (*models.ConversationSettings, error)
+	UpdateSettings(ctx context.Context, conversationID string, userID uuid.UUID, req models.ConversationSettingsRequest) (*models.ConversationSettings, error)
+}
+
+// Notifier pushes real-time events to a user's connected clients
+type Notifier interface {
+	SendToUser(userID uuid.UUID, message *models.WebSocketMessage) bool
+	// SendToOtherSessions leaves out the clients of one of the user's
+	// sessions, such as the device that caused the event
+	SendToOtherSessions(userID uuid.UUID, sessionID string, message *models.WebSocketMessage) bool
+}
+
+// OfflineNotifier notifies users who are not connected through channels
+// such as push or email, subject to their notification preferences
+type OfflineNotifier interface {
+	Dispatch(ctx context.Context, notification *models.Notification)
+}
+
+// WorkspaceMembership reports whether a user belongs to a workspace
+type WorkspaceMembership interface {
+	IsMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error)
+}
+
+// NotificationOverrides stores the notification overrides that mute
+// conversations
+type NotificationOverrides interface {
+	SetConversationOverride(ctx context.Context, userID uuid.UUID, override models.ConversationNotificationOverride) error
+}
+
+// FeatureFlags reports whether a feature is enabled for a user
+type FeatureFlags interface {
+	Enabled(name string, userID uuid.UUID) bool
+}
+
+// ContactPolicy reports whether a user may message another
+type ContactPolicy interface {
+	CanMessage(ctx context.Context, senderID, recipientID uuid.UUID) (bool, error)
+	AcceptsConversation(ctx context.Context, senderID, recipientID uuid.UUID) (bool, error)
 }
 
 // ConversationService implements Service interface
 type ConversationService struct {
-	repo   Repository
-	logger logger.Logger
+	repo       Repository
+	uow        database.UnitOfWork
+	events     events.Publisher
+	notifier   Notifier
+	offline    OfflineNotifier
+	flags      FeatureFlags
+	contacts   ContactPolicy
+	workspaces WorkspaceMembership
+	overrides  NotificationOverrides
+	sanitizer  *sanitize.Sanitizer
+	exports    configs.ExportsConfig
+	buffer     *MessageBuffer // nil when buffering is disabled
+	logger     logger.Logger
+
+	exportLimiter *exportLimiter
 }
 
 // NewConversationService creates a new conversation service
-func NewConversationService(repo Repository, logger logger.Logger) *ConversationService {
+func NewConversationService(repo Repository, uow database.UnitOfWork, publisher events.Publisher, notifier Notifier, offline OfflineNotifier, flags FeatureFlags, contacts ContactPolicy, workspaces WorkspaceMembership, overrides NotificationOverrides, sanitizer *sanitize.Sanitizer, exports configs.ExportsConfig, buffer *MessageBuffer, logger logger.Logger) *ConversationService {
 	return &ConversationService{
-		repo:   repo,
-		logger: logger,
+		repo:       repo,
+		uow:        uow,
+		events:     publisher,
+		notifier:   notifier,
+		offline:    offline,
+		flags:      flags,
+		contacts:   contacts,
+		workspaces: workspaces,
+		overrides:  overrides,
+		sanitizer:  sanitizer,
+		exports:    exports,
+		buffer:     buffer,
+		logger:     logger,
+
+		exportLimiter: newExportLimiter(exports.Interval),
 	}
 }
 
-// GetConversations returns a list of conversations for a user
-func (s *ConversationService) GetConversations(ctx context.Context, userID uuid.UUID) (*models.ConversationListResponse, error) {
-	conversations, err := s.repo.GetConversations(ctx, userID)
+// GetConversations returns a list of conversations for a user. In a
+// workspace only conversations with its members are listed; workspaceID is
+// uuid.Nil outside workspaces.
+func (s *ConversationService) GetConversations(ctx context.Context, userID, workspaceID uuid.UUID) (*models.ConversationListResponse, error) {
+	conversations, err := s.repo.GetConversations(ctx, userID, workspaceID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get conversations", "error", err)
+		return nil, err
+	}
+
+	// Sanitize previews of messages stored before sanitization was enabled,
+	// and count unread messages for the badge
+	unreadTotal := 0
+	for i := range conversations {
+		conversations[i].LastMessage.Content = s.sanitizer.CleanStored(conversations[i].LastMessage.Content)
+		if !conversations[i].Muted {
+			unreadTotal += conversations[i].UnreadCount
+		}
+	}
+
+	// Pinned conversations come first, below the notes to self
+	sort.SliceStable(conversations, func(i, j int) bool {
+		return conversations[i].Pinned && !conversations[j].Pinned
+	})
+	conversations, err = s.pinNotes(ctx, conversations, userID)
 	if err != nil {
-		s.logger.Error("Failed to get conversations", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to get notes to self", "error", err)
 		return nil, err
 	}
 
 	return &models.ConversationListResponse{
 		Conversations: conversations,
+		UnreadTotal:   unreadTotal,
 	}, nil
 }
 
 // GetMessages returns messages in a conversation
-func (s *ConversationService) GetMessages(ctx context.Context, conversationID string, userID uuid.UUID, before string, limit int) (*models.MessageListResponse, error) {
+func (s *ConversationService) GetMessages(ctx context.Context, conversationID string, userID uuid.UUID, before *pagination.Cursor, limit int) (*models.MessageListResponse, error) {
 	// Check if user is part of the conversation
 	isParticipant, err := s.repo.IsUserInConversation(ctx, conversationID, userID)
 	if err != nil {
-		s.logger.Error("Failed to check if user is in conversation", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to check if user is in conversation", "error", err)
 		return nil, err
 	}
 
 	if !isParticipant {
-		s.logger.Info("User attempted to access unauthorized conversation", "user_id", userID, "conversation_id", conversationID)
+		s.logger.WithContext(ctx).Info("User attempted to access unauthorized conversation", "user_id", userID, "conversation_id", conversationID)
 		return nil, ErrUnauthorized
 	}
 
 	// Get messages
-	messages, hasMore, nextCursor, err := s.repo.GetMessages(ctx, conversationID, before, limit)
+	messages, hasMore, nextCursor, err := s.repo.GetMessages(ctx, conversationID, userID, before, limit)
 	if err != nil {
 		if errors.Is(err, ErrConversationNotFound) {
 			return nil, ErrConversationNotFound
 		}
-		s.logger.Error("Failed to get messages", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to get messages", "error", err)
 		return nil, err
 	}
 
+	// Sanitize messages stored before sanitization was enabled
+	for i := range messages {
+		messages[i].Content = s.sanitizer.CleanStored(messages[i].Content)
+	}
+
 	// Update read status for messages
 	if len(messages) > 0 {
 		lastMsgID := messages[0].ID.String() // Messages should be sorted newest first
-		err = s.repo.MarkMessagesAsRead(ctx, conversationID, userID, lastMsgID)
-		if err != nil {
-			s.logger.Error("Failed to mark messages as read", "error", err)
+		if err := s.markRead(ctx, conversationID, userID, "", "", lastMsgID); err != nil {
+			s.logger.WithContext(ctx).Error("Failed to mark messages as read", "error", err)
 			// Continue anyway, this shouldn't fail the main request
 		}
 	}
@@ -89,3 +214,1049 @@ func (s *ConversationService) GetMessages(ctx context.Context, conversationID st
 		NextCursor:     nextCursor,
 	}, nil
 }
+
+// BatchGetMessages returns the latest messages of each conversation, so
+// clients can fill in their conversation list in one request. Unlike
+// GetMessages it leaves read cursors alone, since the user has not opened
+// the conversations.
+func (s *ConversationService) BatchGetMessages(ctx context.Context, userID uuid.UUID, conversationIDs []string, limit int) (*models.BatchGetMessagesResponse, error) {
+	resp := &models.BatchGetMessagesResponse{Conversations: []models.MessageListResponse{}}
+	seen := make(map[string]bool, len(conversationIDs))
+	for _, conversationID := range conversationIDs {
+		if seen[conversationID] {
+			continue
+		}
+		seen[conversationID] = true
+
+		isParticipant, err := s.repo.IsUserInConversation(ctx, conversationID, userID)
+		if errors.Is(err, ErrInvalidConversationID) || (err == nil && !isParticipant) {
+			resp.Missing = append(resp.Missing, conversationID)
+			continue
+		}
+		if err != nil {
+			s.logger.WithContext(ctx).Error("Failed to check if user is in conversation", "error", err)
+			return nil, err
+		}
+
+		messages, hasMore, nextCursor, err := s.repo.GetMessages(ctx, conversationID, userID, nil, limit)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("Failed to get messages", "error", err, "conversation_id", conversationID)
+			return nil, err
+		}
+		if messages == nil {
+			messages = []models.Message{}
+		}
+
+		// Sanitize messages stored before sanitization was enabled
+		for i := range messages {
+			messages[i].Content = s.sanitizer.CleanStored(messages[i].Content)
+		}
+
+		// The messages reached one of the user's devices, though unread
+		s.markFetchedDelivered(ctx, userID, messages)
+
+		resp.Conversations = append(resp.Conversations, models.MessageListResponse{
+			ConversationID: conversationID,
+			Messages:       messages,
+			HasMore:        hasMore,
+			NextCursor:     nextCursor,
+		})
+	}
+
+	return resp, nil
+}
+
+// MessagesVersion identifies the state of a conversation's messages as the
+// user sees them, so unchanged pages can be answered without loading them.
+// Changes that do not send a message, clear the conversation or move a read
+// cursor, such as a participant renaming themselves, are picked up with the
+// next message.
+func (s *ConversationService) MessagesVersion(ctx context.Context, conversationID string, userID uuid.UUID) (string, error) {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return "", err
+	}
+
+	version, err := s.repo.GetMessagesVersion(ctx, conversationID, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get messages version", "error", err)
+		return "", err
+	}
+	return version, nil
+}
+
+// GetMessagesAround returns the page of messages nearest to a point in
+// time, split evenly between messages sent before and after it
+func (s *ConversationService) GetMessagesAround(ctx context.Context, conversationID string, userID uuid.UUID, at time.Time, limit int) (*models.MessageListResponse, error) {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	// Take at least one older message, so a cursor to older pages can be
+	// built from the last message on the page
+	before := limit / 2
+	if before < 1 {
+		before = 1
+	}
+	messages, hasMoreBefore, hasMoreAfter, err := s.repo.GetMessagesAround(ctx, conversationID, userID, at, before, limit-before)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get messages around date", "error", err)
+		return nil, err
+	}
+
+	if messages == nil {
+		messages = []models.Message{}
+	}
+
+	// Sanitize messages stored before sanitization was enabled
+	for i := range messages {
+		messages[i].Content = s.sanitizer.CleanStored(messages[i].Content)
+	}
+
+	resp := &models.MessageListResponse{
+		ConversationID: conversationID,
+		Messages:       messages,
+		HasMore:        hasMoreBefore,
+		HasMoreAfter:   hasMoreAfter,
+	}
+	if hasMoreBefore && len(messages) > 0 {
+		oldest := messages[len(messages)-1]
+		resp.NextCursor = pagination.TimeCursor(oldest.Timestamp, oldest.ID).Encode()
+	}
+
+	return resp, nil
+}
+
+// GetMessageContext returns a message with the messages around it, so it
+// can be shown in place in the conversation history
+func (s *ConversationService) GetMessageContext(ctx context.Context, conversationID string, userID, messageID uuid.UUID, before, after int) (*models.MessageContextResponse, error) {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	messages, hasMoreBefore, hasMoreAfter, err := s.repo.GetMessageContext(ctx, conversationID, userID, messageID, before, after)
+	if err != nil {
+		if !errors.Is(err, ErrMessageNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to get message context", "error", err)
+		}
+		return nil, err
+	}
+
+	// Sanitize messages stored before sanitization was enabled
+	for i := range messages {
+		messages[i].Content = s.sanitizer.CleanStored(messages[i].Content)
+	}
+
+	resp := &models.MessageContextResponse{
+		ConversationID: conversationID,
+		MessageID:      messageID.String(),
+		Messages:       messages,
+		HasMoreBefore:  hasMoreBefore,
+		HasMoreAfter:   hasMoreAfter,
+	}
+	if hasMoreBefore {
+		oldest := messages[len(messages)-1]
+		resp.NextCursor = pagination.TimeCursor(oldest.Timestamp, oldest.ID).Encode()
+	}
+
+	return resp, nil
+}
+
+// SaveMessage persists a direct message and updates the conversation summary atomically
+func (s *ConversationService) SaveMessage(ctx context.Context, message *models.DirectMessage) error {
+	if err := s.checkWorkspace(ctx, message); err != nil {
+		return err
+	}
+	if err := s.checkCanMessage(ctx, message.SenderID, message.RecipientID); err != nil {
+		return err
+	}
+
+	// Render formatted messages once so clients never handle unsanitized markup
+	if message.Format == "" || !s.flags.Enabled(features.Markdown, message.SenderID) {
+		message.Format = models.FormatPlain
+	}
+	if message.Format == models.FormatMarkdown {
+		message.RenderedContent = markdown.Render(message.Content)
+	}
+
+	conversationID, err := s.repo.GetOrCreateConversation(ctx, message.SenderID, message.RecipientID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to resolve conversation", "error", err)
+		return err
+	}
+
+	var mentioned []uuid.UUID
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.SaveMessage(ctx, message); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateConversationSummary(ctx, conversationID, message); err != nil {
+			return err
+		}
+
+		if !s.flags.Enabled(features.Mentions, message.SenderID) {
+			return nil
+		}
+		mentioned, err = s.saveMentions(ctx, message)
+		return err
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save message", "error", err, "message_id", message.ID)
+		return err
+	}
+
+	// Notify mentioned users
+	for _, userID := range mentioned {
+		delivered := s.notifier.SendToUser(userID, &models.WebSocketMessage{
+			Type: protocol.TypeMention,
+			Data: models.MentionData{
+				MessageID:      message.ID.String(),
+				ConversationID: conversationID,
+				SenderID:       message.SenderID.String(),
+				Content:        message.Content,
+				Timestamp:      message.CreatedAt,
+			},
+		})
+		if !delivered {
+			s.offline.Dispatch(ctx, &models.Notification{
+				UserID:         userID,
+				Type:           models.NotificationMention,
+				ConversationID: conversationID,
+				SenderID:       message.SenderID.String(),
+				Body:           message.Content,
+			})
+		}
+	}
+
+	// Refresh both participants' conversation lists
+	participants := []uuid.UUID{message.SenderID}
+	if message.RecipientID != message.SenderID {
+		participants = append(participants, message.RecipientID)
+	}
+	s.notifyConversationUpdated(ctx, conversationID, models.ConversationUpdateNewMessage, participants...)
+
+	// Publish domain event
+	err = s.events.Publish(ctx, events.New(events.TypeMessageCreated, events.MessageCreatedData{
+		MessageID:      message.ID.String(),
+		ConversationID: conversationID,
+		SenderID:       message.SenderID.String(),
+		RecipientID:    message.RecipientID.String(),
+		Content:        message.Content,
+		CreatedAt:      message.CreatedAt,
+	}))
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to publish message created event", "error", err)
+		// Continue anyway, the message has been saved
+	}
+
+	return nil
+}
+
+// SendMessage saves a direct message and forwards it to the recipient's
+// connected clients. It is the shared send path for WebSocket and REST
+// clients; content must already be validated. While the database is
+// unavailable the message is buffered and ErrMessagePending is returned.
+func (s *ConversationService) SendMessage(ctx context.Context, message *models.DirectMessage, senderUsername string) (*models.DirectMessageData, error) {
+	data, err := s.deliverMessage(ctx, message, senderUsername)
+	if err != nil && s.buffer != nil && isUnavailable(err) {
+		return nil, s.bufferMessage(ctx, message, senderUsername)
+	}
+	return data, err
+}
+
+// deliverMessage saves a message and forwards it to the recipient
+func (s *ConversationService) deliverMessage(ctx context.Context, message *models.DirectMessage, senderUsername string) (*models.DirectMessageData, error) {
+	if err := s.SaveMessage(ctx, message); err != nil {
+		return nil, err
+	}
+
+	conversationID, err := s.repo.GetOrCreateConversation(ctx, message.SenderID, message.RecipientID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &models.DirectMessageData{
+		MessageID:       message.ID.String(),
+		ConversationID:  conversationID,
+		SenderID:        message.SenderID.String(),
+		SenderUsername:  senderUsername,
+		Content:         message.Content,
+		Format:          message.Format,
+		RenderedContent: message.RenderedContent,
+		Timestamp:       message.CreatedAt,
+		ExpiresAt:       message.ExpiresAt,
+	}
+
+	// Forward the message to the recipient if they're online, otherwise
+	// notify them through their other channels. Notes to self are only
+	// synced to the sender's other connected clients.
+	delivered := s.notifier.SendToUser(message.RecipientID, &models.WebSocketMessage{
+		Type: protocol.TypeDirectMessage,
+		Data: *data,
+	})
+	if delivered {
+		s.markDelivered(ctx, message, conversationID)
+	}
+	data.DeliveryState = message.DeliveryState
+	if !delivered && message.RecipientID != message.SenderID {
+		s.offline.Dispatch(ctx, &models.Notification{
+			UserID:         message.RecipientID,
+			Type:           models.NotificationDirectMessage,
+			ConversationID: conversationID,
+			SenderID:       data.SenderID,
+			SenderUsername: senderUsername,
+			Body:           message.Content,
+		})
+	}
+
+	return data, nil
+}
+
+// markDelivered moves a message that reached one of the recipient's
+// devices to delivered and tells the sender. A failure only leaves the
+// message stored.
+func (s *ConversationService) markDelivered(ctx context.Context, message *models.DirectMessage, conversationID string) {
+	moved, err := s.repo.MarkMessagesDelivered(ctx, message.RecipientID, []uuid.UUID{message.ID})
+	if err != nil {
+		s.logger.WithContext(ctx).Warn("Failed to mark message delivered", "error", err, "message_id", message.ID)
+		return
+	}
+	if len(moved) == 0 {
+		return
+	}
+
+	message.Delivered = true
+	message.DeliveryState = models.DeliveryDelivered
+	s.sendMessageStatus(message.SenderID, conversationID, []uuid.UUID{message.ID}, models.DeliveryDelivered)
+}
+
+// MarkDelivered moves messages a recipient's device caught up on after
+// being offline, such as through an inbox sync, from stored to delivered
+// and tells their senders. It returns the IDs of the messages that moved;
+// a failure only leaves the messages stored.
+func (s *ConversationService) MarkDelivered(ctx context.Context, recipientID uuid.UUID, messageIDs []uuid.UUID) map[uuid.UUID]bool {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	moved, err := s.repo.MarkMessagesDelivered(ctx, recipientID, messageIDs)
+	if err != nil {
+		s.logger.WithContext(ctx).Warn("Failed to mark messages delivered", "error", err, "user_id", recipientID)
+		return nil
+	}
+
+	delivered := make(map[uuid.UUID]bool)
+	for senderID, ids := range moved {
+		for _, id := range ids {
+			delivered[id] = true
+		}
+		conversationID, err := s.repo.GetOrCreateConversation(ctx, senderID, recipientID)
+		if err != nil {
+			continue
+		}
+		s.sendMessageStatus(senderID, conversationID, ids, models.DeliveryDelivered)
+	}
+	return delivered
+}
+
+// markFetchedDelivered moves the stored messages the user received among
+// those fetched to delivered, updating their status in place
+func (s *ConversationService) markFetchedDelivered(ctx context.Context, userID uuid.UUID, messages []models.Message) {
+	var ids []uuid.UUID
+	for _, message := range messages {
+		if message.SenderID != userID.String() && message.DeliveryStatus.State == models.DeliveryStored {
+			ids = append(ids, message.ID)
+		}
+	}
+
+	delivered := s.MarkDelivered(ctx, userID, ids)
+	for i := range messages {
+		if delivered[messages[i].ID] {
+			messages[i].DeliveryStatus.State = models.DeliveryDelivered
+			messages[i].DeliveryStatus.Delivered = true
+		}
+	}
+}
+
+// sendMessageStatus tells a sender's sessions that their messages moved to
+// a delivery state
+func (s *ConversationService) sendMessageStatus(senderID uuid.UUID, conversationID string, messageIDs []uuid.UUID, state string) {
+	ids := make([]string, len(messageIDs))
+	for i, id := range messageIDs {
+		ids[i] = id.String()
+	}
+
+	s.notifier.SendToUser(senderID, &models.WebSocketMessage{
+		Type: protocol.TypeMessageStatus,
+		Data: models.MessageStatusData{
+			ConversationID: conversationID,
+			MessageIDs:     ids,
+			State:          state,
+			Timestamp:      time.Now(),
+		},
+	})
+}
+
+// RecordSystemMessage adds a system message recording an event, such as a
+// missed call, to the timeline of the conversation between the user who
+// caused it and the other participant, and sends it to both. The event
+// already happened, so contact and privacy settings do not apply.
+func (s *ConversationService) RecordSystemMessage(ctx context.Context, messageType string, senderID, recipientID uuid.UUID, data models.SystemData) error {
+	message := &models.DirectMessage{
+		ID:          uuid.New(),
+		Type:        messageType,
+		Data:        data,
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		Format:      models.FormatPlain,
+	}
+
+	conversationID, err := s.repo.GetOrCreateConversation(ctx, senderID, recipientID)
+	if err != nil {
+		return err
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.SaveMessage(ctx, message); err != nil {
+			return err
+		}
+		return s.repo.UpdateConversationSummary(ctx, conversationID, message)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save system message", "error", err, "type", messageType)
+		return err
+	}
+
+	participants := []uuid.UUID{senderID}
+	if recipientID != senderID {
+		participants = append(participants, recipientID)
+	}
+	for _, userID := range participants {
+		s.notifier.SendToUser(userID, &models.WebSocketMessage{
+			Type: protocol.TypeSystemMessage,
+			Data: models.SystemMessageData{
+				MessageID:      message.ID.String(),
+				ConversationID: conversationID,
+				Type:           message.Type,
+				SenderID:       senderID.String(),
+				Data:           message.Data,
+				Timestamp:      message.CreatedAt,
+			},
+		})
+	}
+	s.notifyConversationUpdated(ctx, conversationID, models.ConversationUpdateNewMessage, participants...)
+
+	return nil
+}
+
+// checkCanMessage returns an error unless the sender may message the
+// recipient. Privacy settings only restrict starting new conversations, and
+// users may always write notes to themselves.
+func (s *ConversationService) checkCanMessage(ctx context.Context, senderID, recipientID uuid.UUID) error {
+	if senderID == recipientID {
+		return nil
+	}
+
+	exists, err := s.repo.UserExists(ctx, recipientID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check recipient", "error", err)
+		return err
+	}
+	if !exists {
+		return ErrRecipientNotFound
+	}
+
+	allowed, err := s.contacts.CanMessage(ctx, senderID, recipientID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check contacts", "error", err)
+		return err
+	}
+	if !allowed {
+		return ErrNotContact
+	}
+
+	conversationID, err := s.repo.GetOrCreateConversation(ctx, senderID, recipientID)
+	if err != nil {
+		return err
+	}
+	exists, err = s.repo.ConversationExists(ctx, conversationID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check if conversation exists", "error", err)
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	accepting, err := s.contacts.AcceptsConversation(ctx, senderID, recipientID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check privacy settings", "error", err)
+		return err
+	}
+	if !accepting {
+		return ErrNotAccepting
+	}
+	return nil
+}
+
+// checkWorkspace returns ErrNotInWorkspace if a message sent in a workspace
+// is addressed to someone outside it
+func (s *ConversationService) checkWorkspace(ctx context.Context, message *models.DirectMessage) error {
+	if message.WorkspaceID == nil {
+		return nil
+	}
+
+	isMember, err := s.workspaces.IsMember(ctx, *message.WorkspaceID, message.RecipientID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check workspace membership", "error", err)
+		return err
+	}
+	if !isMember {
+		return ErrNotInWorkspace
+	}
+	return nil
+}
+
+// CanCall returns an error unless the caller may call the callee. Calls
+// follow the same contact and privacy rules as messages.
+func (s *ConversationService) CanCall(ctx context.Context, callerID, calleeID uuid.UUID) error {
+	return s.checkCanMessage(ctx, callerID, calleeID)
+}
+
+// CanType returns the ID of the conversation between the sender and the
+// recipient, or an error unless the sender may show the recipient that
+// they are typing: the two must already have a conversation, and the
+// sender must still be allowed to message the recipient. Nobody else sees
+// notes to self being written.
+func (s *ConversationService) CanType(ctx context.Context, senderID, recipientID uuid.UUID) (string, error) {
+	if senderID == recipientID {
+		return "", ErrConversationNotFound
+	}
+
+	conversationID, err := s.repo.GetOrCreateConversation(ctx, senderID, recipientID)
+	if err != nil {
+		return "", err
+	}
+	exists, err := s.repo.ConversationExists(ctx, conversationID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check if conversation exists", "error", err)
+		return "", err
+	}
+	if !exists {
+		return "", ErrConversationNotFound
+	}
+
+	allowed, err := s.contacts.CanMessage(ctx, senderID, recipientID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check contacts", "error", err)
+		return "", err
+	}
+	if !allowed {
+		return "", ErrNotContact
+	}
+	return conversationID, nil
+}
+
+// MarkRead moves the user's read cursor in a conversation to a message read
+// on one of their devices. Read cursors are shared by the user's devices,
+// so the user's other sessions are sent a read_sync to keep their unread
+// badges consistent, and the other participant a read receipt.
+func (s *ConversationService) MarkRead(ctx context.Context, conversationID string, userID uuid.UUID, username, sessionID, lastReadMessageID string) error {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	err := s.markRead(ctx, conversationID, userID, username, sessionID, lastReadMessageID)
+	if err != nil && !errors.Is(err, ErrMessageNotFound) {
+		s.logger.WithContext(ctx).Error("Failed to mark messages as read", "error", err)
+	}
+	return err
+}
+
+// markRead moves the read cursor and notifies the participants if it moved.
+// The session that read the messages, if known, is left out of the
+// read_sync.
+func (s *ConversationService) markRead(ctx context.Context, conversationID string, userID uuid.UUID, username, sessionID, lastReadMessageID string) error {
+	moved, err := s.repo.MarkMessagesAsRead(ctx, conversationID, userID, lastReadMessageID)
+	if err != nil || !moved {
+		return err
+	}
+
+	now := time.Now()
+	s.notifier.SendToOtherSessions(userID, sessionID, &models.WebSocketMessage{
+		Type: protocol.TypeReadSync,
+		Data: models.ReadSyncData{
+			ConversationID:    conversationID,
+			LastReadMessageID: lastReadMessageID,
+			ReadAt:            now,
+		},
+	})
+
+	user1ID, user2ID, err := splitConversationID(conversationID)
+	if err != nil {
+		return err
+	}
+	otherUserID := user1ID
+	if otherUserID == userID {
+		otherUserID = user2ID
+	}
+
+	// The cursor already moved, so a failure here only leaves the
+	// messages' delivery states behind it
+	readIDs, err := s.repo.MarkMessagesReadUpTo(ctx, conversationID, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Warn("Failed to mark messages read", "error", err, "conversation_id", conversationID)
+	}
+
+	if otherUserID != userID {
+		if len(readIDs) > 0 {
+			s.sendMessageStatus(otherUserID, conversationID, readIDs, models.DeliveryRead)
+		}
+		s.notifier.SendToUser(otherUserID, &models.WebSocketMessage{
+			Type: protocol.TypeReadReceipt,
+			Data: models.ReadReceiptData{
+				UserID:            userID.String(),
+				Username:          username,
+				ConversationID:    conversationID,
+				LastReadMessageID: lastReadMessageID,
+				Timestamp:         now,
+			},
+		})
+	}
+
+	s.notifyConversationUpdated(ctx, conversationID, models.ConversationUpdateRead, userID)
+	return nil
+}
+
+// GetRecipient returns the other participant of a direct conversation
+func (s *ConversationService) GetRecipient(ctx context.Context, conversationID string, userID uuid.UUID) (uuid.UUID, error) {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return uuid.Nil, err
+	}
+
+	user1ID, user2ID, err := splitConversationID(conversationID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if user1ID == userID {
+		return user2ID, nil
+	}
+	return user1ID, nil
+}
+
+// NotifyPresenceChanged refreshes the conversation lists of everyone who
+// has a conversation with userID after their presence changes
+func (s *ConversationService) NotifyPresenceChanged(ctx context.Context, userID uuid.UUID) {
+	conversationIDs, err := s.repo.GetConversationIDs(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get conversations for presence update", "error", err, "user_id", userID)
+		return
+	}
+
+	for _, conversationID := range conversationIDs {
+		user1ID, user2ID, err := splitConversationID(conversationID)
+		if err != nil {
+			continue
+		}
+
+		otherUserID := user1ID
+		if otherUserID == userID {
+			otherUserID = user2ID
+		}
+		if otherUserID == userID {
+			// Notes to self show no presence
+			continue
+		}
+		s.notifyConversationUpdated(ctx, conversationID, models.ConversationUpdatePresence, otherUserID)
+	}
+}
+
+// VisiblePresence returns the users among userIDs whose presence the
+// viewer may follow. Users who hide their online status, or who would not
+// accept a conversation from the viewer, are left out; the viewer may
+// always follow themselves.
+func (s *ConversationService) VisiblePresence(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	visible, err := s.repo.GetPresenceVisible(ctx, viewerID, userIDs)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check presence visibility", "error", err)
+		return nil, err
+	}
+
+	allowed := make(map[uuid.UUID]bool, len(visible))
+	for _, userID := range visible {
+		allowed[userID] = true
+	}
+	filtered := make([]uuid.UUID, 0, len(visible))
+	for _, userID := range userIDs {
+		if allowed[userID] || userID == viewerID {
+			filtered = append(filtered, userID)
+		}
+	}
+	return filtered, nil
+}
+
+// DeliverOffline notifies a user through their other channels of a message
+// their connections could not be sent. Only direct messages and mentions
+// have an offline form; the user catches up on other events when they
+// reconnect.
+func (s *ConversationService) DeliverOffline(ctx context.Context, userID uuid.UUID, message *models.WebSocketMessage) {
+	switch data := message.Data.(type) {
+	case models.DirectMessageData:
+		if data.SenderID == userID.String() {
+			// Notes to self
+			return
+		}
+		s.offline.Dispatch(ctx, &models.Notification{
+			UserID:         userID,
+			Type:           models.NotificationDirectMessage,
+			ConversationID: data.ConversationID,
+			SenderID:       data.SenderID,
+			SenderUsername: data.SenderUsername,
+			Body:           data.Content,
+		})
+	case models.MentionData:
+		s.offline.Dispatch(ctx, &models.Notification{
+			UserID:         userID,
+			Type:           models.NotificationMention,
+			ConversationID: data.ConversationID,
+			SenderID:       data.SenderID,
+			Body:           data.Content,
+		})
+	}
+}
+
+// notifyConversationUpdated pushes the current state of a conversation to
+// each user's connected clients, as it appears in that user's list
+func (s *ConversationService) notifyConversationUpdated(ctx context.Context, conversationID, reason string, userIDs ...uuid.UUID) {
+	for _, userID := range userIDs {
+		conversation, version, err := s.repo.GetConversationSummary(ctx, conversationID, userID)
+		if errors.Is(err, ErrConversationNotFound) {
+			// Hidden from the user since they cleared it
+			continue
+		}
+		if err != nil {
+			s.logger.WithContext(ctx).Error("Failed to get conversation summary", "error", err, "conversation_id", conversationID)
+			continue
+		}
+
+		conversation.LastMessage.Content = s.sanitizer.CleanStored(conversation.LastMessage.Content)
+		if conversation.OtherUser.ID == userID {
+			markNotes(conversation)
+		}
+
+		s.notifier.SendToUser(userID, &models.WebSocketMessage{
+			Type: protocol.TypeConversationUpdated,
+			Data: models.ConversationUpdatedData{
+				Reason:       reason,
+				Version:      version,
+				Conversation: *conversation,
+			},
+		})
+	}
+}
+
+// pinNotes moves the user's notes to self to the top of their conversation
+// list, adding an empty entry if they have not written any yet
+func (s *ConversationService) pinNotes(ctx context.Context, conversations []models.Conversation, userID uuid.UUID) ([]models.Conversation, error) {
+	for i := range conversations {
+		if conversations[i].OtherUser.ID != userID {
+			continue
+		}
+		markNotes(&conversations[i])
+		notes := conversations[i]
+		copy(conversations[1:i+1], conversations[:i])
+		conversations[0] = notes
+		return conversations, nil
+	}
+
+	user, err := s.repo.GetUserInfo(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	conversationID, err := s.repo.GetOrCreateConversation(ctx, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	notes := models.Conversation{
+		ConversationID: conversationID,
+		OtherUser:      *user,
+		Notes:          true,
+	}
+	return append([]models.Conversation{notes}, conversations...), nil
+}
+
+// markNotes marks a conversation as the user's notes to self, which are
+// read as soon as they are written
+func markNotes(conversation *models.Conversation) {
+	conversation.Notes = true
+	conversation.UnreadCount = 0
+	conversation.LastMessage.DeliveryStatus = models.MessageDeliveryStatus{Delivered: true, Read: true}
+}
+
+// GetSettings returns the user's settings for a conversation
+func (s *ConversationService) GetSettings(ctx context.Context, conversationID string, userID uuid.UUID) (*models.ConversationSettings, error) {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.repo.GetSettings(ctx, conversationID, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get conversation settings", "error", err)
+		return nil, err
+	}
+	return settings, nil
+}
+
+// UpdateSettings changes the user's settings for a conversation and syncs
+// them to the user's sessions. Changes to the disappearing timer are
+// recorded in the conversation's timeline, since they affect what the other
+// participant sees.
+func (s *ConversationService) UpdateSettings(ctx context.Context, conversationID string, userID uuid.UUID, req models.ConversationSettingsRequest) (*models.ConversationSettings, error) {
+	current, err := s.GetSettings(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := *current
+	if req.Mute != nil {
+		settings.Mute = *req.Mute
+	}
+	if req.Pinned != nil {
+		settings.Pinned = *req.Pinned
+	}
+	if req.NotificationSound != nil {
+		settings.NotificationSound = *req.NotificationSound
+	}
+	if req.DisappearingTimer != nil {
+		settings.DisappearingTimer = *req.DisappearingTimer
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if settings.Mute != current.Mute {
+			override := models.ConversationNotificationRequest{Mute: settings.Mute}.Override(conversationID)
+			if err := s.overrides.SetConversationOverride(ctx, userID, override); err != nil {
+				return err
+			}
+		}
+		return s.repo.SaveSettings(ctx, userID, &settings)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save conversation settings", "error", err)
+		return nil, err
+	}
+
+	if settings.DisappearingTimer != current.DisappearingTimer {
+		user1ID, user2ID, err := splitConversationID(conversationID)
+		if err != nil {
+			return nil, err
+		}
+		otherUserID := user1ID
+		if otherUserID == userID {
+			otherUserID = user2ID
+		}
+		// The settings are saved either way; a failure is logged
+		_ = s.RecordSystemMessage(ctx, models.DirectMessageDisappearingTimer, userID, otherUserID, models.SystemData{
+			"seconds": strconv.Itoa(settings.DisappearingTimer),
+		})
+	}
+
+	s.notifier.SendToUser(userID, &models.WebSocketMessage{
+		Type: protocol.TypeConversationSettingsUpdated,
+		Data: settings,
+	})
+	if settings.Mute != current.Mute || settings.Pinned != current.Pinned {
+		s.notifyConversationUpdated(ctx, conversationID, models.ConversationUpdateSettings, userID)
+	}
+
+	return &settings, nil
+}
+
+// saveMentions records the conversation participants mentioned in a message
+// and returns their IDs. Users outside the conversation cannot see the
+// message, so mentioning them has no effect.
+func (s *ConversationService) saveMentions(ctx context.Context, message *models.DirectMessage) ([]uuid.UUID, error) {
+	usernames := ParseMentions(message.Content)
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	ids, err := s.repo.GetUserIDsByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	var mentioned []uuid.UUID
+	for _, username := range usernames {
+		id, ok := ids[username]
+		if ok && id == message.RecipientID && id != message.SenderID {
+			mentioned = append(mentioned, id)
+		}
+	}
+
+	if len(mentioned) == 0 {
+		return nil, nil
+	}
+
+	if err := s.repo.SaveMentions(ctx, message.ID, mentioned); err != nil {
+		return nil, err
+	}
+
+	return mentioned, nil
+}
+
+// GetMentions returns messages that mention the user, newest first
+func (s *ConversationService) GetMentions(ctx context.Context, userID uuid.UUID, before *pagination.Cursor, limit int) (*models.MentionListResponse, error) {
+	mentions, hasMore, nextCursor, err := s.repo.GetMentions(ctx, userID, before, limit)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get mentions", "error", err)
+		return nil, err
+	}
+
+	if mentions == nil {
+		mentions = []models.Mention{}
+	}
+
+	// Sanitize messages stored before sanitization was enabled
+	for i := range mentions {
+		mentions[i].Content = s.sanitizer.CleanStored(mentions[i].Content)
+	}
+
+	return &models.MentionListResponse{
+		Mentions:   mentions,
+		HasMore:    hasMore,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// GetDraft returns the user's draft for a conversation, empty if none is stored
+func (s *ConversationService) GetDraft(ctx context.Context, conversationID string, userID uuid.UUID) (*models.Draft, error) {
+	if !s.flags.Enabled(features.Drafts, userID) {
+		return nil, ErrFeatureDisabled
+	}
+
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	draft, err := s.repo.GetDraft(ctx, conversationID, userID)
+	if err != nil {
+		if errors.Is(err, ErrDraftNotFound) {
+			return &models.Draft{ConversationID: conversationID}, nil
+		}
+		s.logger.WithContext(ctx).Error("Failed to get draft", "error", err)
+		return nil, err
+	}
+
+	return draft, nil
+}
+
+// SaveDraft stores the user's draft for a conversation and syncs it to the
+// user's connected sessions. An empty draft clears the stored one.
+func (s *ConversationService) SaveDraft(ctx context.Context, conversationID string, userID uuid.UUID, content string) (*models.Draft, error) {
+	if !s.flags.Enabled(features.Drafts, userID) {
+		return nil, ErrFeatureDisabled
+	}
+
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	draft := &models.Draft{
+		ConversationID: conversationID,
+		Content:        content,
+		UpdatedAt:      time.Now(),
+	}
+
+	var err error
+	if content == "" {
+		err = s.repo.DeleteDraft(ctx, conversationID, userID)
+	} else {
+		err = s.repo.SaveDraft(ctx, userID, draft)
+	}
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save draft", "error", err)
+		return nil, err
+	}
+
+	// Sync the draft to the user's sessions
+	s.notifier.SendToUser(userID, &models.WebSocketMessage{
+		Type: protocol.TypeDraftUpdated,
+		Data: models.DraftUpdatedData{
+			ConversationID: draft.ConversationID,
+			Content:        draft.Content,
+			UpdatedAt:      draft.UpdatedAt,
+		},
+	})
+
+	return draft, nil
+}
+
+// ClearHistory hides the conversation's current messages from the user.
+// The other participant's history is untouched.
+func (s *ConversationService) ClearHistory(ctx context.Context, conversationID string, userID uuid.UUID) error {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	clearedAt := time.Now()
+	if err := s.repo.ClearHistory(ctx, conversationID, userID, clearedAt); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to clear conversation history", "error", err)
+		return err
+	}
+
+	s.notifyConversationCleared(conversationID, userID, clearedAt, false)
+	return nil
+}
+
+// DeleteConversation clears the conversation's history and draft for the
+// user, removing it from their conversation list until a new message
+// arrives. The other participant's history is untouched.
+func (s *ConversationService) DeleteConversation(ctx context.Context, conversationID string, userID uuid.UUID) error {
+	if err := s.checkParticipant(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	clearedAt := time.Now()
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.ClearHistory(ctx, conversationID, userID, clearedAt); err != nil {
+			return err
+		}
+		return s.repo.DeleteDraft(ctx, conversationID, userID)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to delete conversation", "error", err)
+		return err
+	}
+
+	s.notifyConversationCleared(conversationID, userID, clearedAt, true)
+	return nil
+}
+
+// notifyConversationCleared syncs a cleared or deleted conversation to the
+// user's sessions
+func (s *ConversationService) notifyConversationCleared(conversationID string, userID uuid.UUID, clearedAt time.Time, deleted bool) {
+	s.notifier.SendToUser(userID, &models.WebSocketMessage{
+		Type: protocol.TypeConversationCleared,
+		Data: models.ConversationClearedData{
+			ConversationID: conversationID,
+			ClearedAt:      clearedAt,
+			Deleted:        deleted,
+		},
+	})
+}
+
+// checkParticipant returns ErrUnauthorized if the user is not part of the conversation
+func (s *ConversationService) checkParticipant(ctx context.Context, conversationID string, userID uuid.UUID) error {
+	isParticipant, err := s.repo.IsUserInConversation(ctx, conversationID, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to check if user is in conversation", "error", err)
+		return err
+	}
+
+	if !isParticipant {
+		s.logger.WithContext(ctx).Info("User attempted to access unauthorized conversation", "user_id", userID, "conversation_id", conversationID)
+		return ErrUnauthorized
+	}
+
+	return nil
+}
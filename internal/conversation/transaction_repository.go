@@ -5,21 +5,25 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 )
 
-// TransactionRepository provides a simplified repository implementation focused on transactions
+// TransactionRepository saves messages through a unit of work, so the message
+// and its conversation summary are committed together
 type TransactionRepository struct {
-	db     *sqlx.DB
+	repo   *PostgresRepository
+	uow    database.UnitOfWork
 	logger logger.Logger
 }
 
 // NewTransactionRepository creates a new transaction-focused repository
-func NewTransactionRepository(db *sqlx.DB, logger logger.Logger) *TransactionRepository {
+func NewTransactionRepository(db *database.DB, logger logger.Logger) *TransactionRepository {
 	return &TransactionRepository{
-		db:     db,
+		repo:   NewPostgresRepository(db, logger),
+		uow:    database.NewUnitOfWork(db),
 		logger: logger,
 	}
 }
@@ -29,60 +33,37 @@ func (r *TransactionRepository) SaveMessageDirect(senderID, recipientID uuid.UUI
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Begin transaction
-	tx, err := r.db.BeginTxx(ctx, nil)
-	if err != nil {
-		r.logger.Error("Failed to begin transaction", "error", err)
-		return err
+	message := &models.DirectMessage{
+		ID:          uuid.New(),
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		Content:     content,
+		Delivered:   false,
+		Read:        false,
+		CreatedAt:   time.Now(),
 	}
 
-	// Ensure transaction is rolled back if there's an error
-	defer func() {
-		if err != nil {
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				r.logger.Error("Failed to rollback transaction", "error", rollbackErr)
-			}
-		}
-	}()
-
-	// Create message
-	messageID := uuid.New()
-	now := time.Now()
-
 	r.logger.Info("Saving message with transaction",
-		"message_id", messageID,
+		"message_id", message.ID,
 		"sender_id", senderID,
 		"recipient_id", recipientID)
 
-	// Insert the message
-	query := `
-		INSERT INTO direct_messages (id, sender_id, recipient_id, content, delivered, read, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
-
-	_, err = tx.ExecContext(
-		ctx,
-		query,
-		messageID,
-		senderID,
-		recipientID,
-		content,
-		false, // delivered
-		false, // read
-		now,   // created_at
-	)
-
+	conversationID, err := r.repo.GetOrCreateConversation(ctx, senderID, recipientID)
 	if err != nil {
-		r.logger.Error("Failed to insert message in transaction", "error", err)
-		return fmt.Errorf("failed to insert message: %w", err)
+		return err
 	}
 
-	// Commit the transaction
-	if err = tx.Commit(); err != nil {
-		r.logger.Error("Failed to commit transaction", "error", err)
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	err = r.uow.Do(ctx, func(ctx context.Context) error {
+		if err := r.repo.SaveMessage(ctx, message); err != nil {
+			return fmt.Errorf("failed to insert message: %w", err)
+		}
+		return r.repo.UpdateConversationSummary(ctx, conversationID, message)
+	})
+	if err != nil {
+		r.logger.Error("Failed to save message with transaction", "error", err)
+		return err
 	}
 
-	r.logger.Info("Message saved successfully with transaction", "message_id", messageID)
+	r.logger.Info("Message saved successfully with transaction", "message_id", message.ID)
 	return nil
 }
@@ -0,0 +1,127 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// maxDigestAge bounds how far back a digest looks for unread messages, so
+// users who just opted in are not sent their whole history
+const maxDigestAge = 7 * 24 * time.Hour
+
+// PreferenceStore looks up users' notification preferences
+type PreferenceStore interface {
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error)
+}
+
+// Digests emails users who opted in a summary of the direct messages they
+// have left unread
+type Digests struct {
+	repo        Repository
+	mailer      Mailer
+	preferences PreferenceStore
+	logger      logger.Logger
+}
+
+// NewDigests creates an email digest sender
+func NewDigests(repo Repository, mailer Mailer, preferences PreferenceStore, logger logger.Logger) *Digests {
+	return &Digests{
+		repo:        repo,
+		mailer:      mailer,
+		preferences: preferences,
+		logger:      logger,
+	}
+}
+
+// SendDigests emails each user who opted in a summary of the direct
+// messages they received since their last digest and have not read. It
+// returns the number of emails sent.
+func (d *Digests) SendDigests(ctx context.Context) (int, error) {
+	now := time.Now()
+	unread, err := d.repo.GetUnreadMessages(ctx, now.Add(-maxDigestAge), now)
+	if err != nil {
+		return 0, err
+	}
+
+	// Rows are grouped by recipient
+	sent := 0
+	for start := 0; start < len(unread); {
+		end := start + 1
+		for end < len(unread) && unread[end].UserID == unread[start].UserID {
+			end++
+		}
+		ok, err := d.sendDigest(ctx, unread[start:end])
+		if err != nil {
+			return sent, err
+		}
+		if ok {
+			sent++
+		}
+		start = end
+	}
+	return sent, nil
+}
+
+// sendDigest emails a user about their unread conversations that their
+// preferences allow digests for, and reports whether an email was sent.
+// Send failures leave the messages to be retried in the next digest.
+func (d *Digests) sendDigest(ctx context.Context, unread []UnreadMessages) (bool, error) {
+	userID := unread[0].UserID
+	through := unread[0].LastSentAt
+	for _, u := range unread {
+		if u.LastSentAt.After(through) {
+			through = u.LastSentAt
+		}
+	}
+
+	preferences, err := d.preferences.GetPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	var allowed []UnreadMessages
+	for _, u := range unread {
+		if preferences.Allows(models.ChannelEmailDigest, models.NotificationDirectMessage, u.ConversationID) {
+			allowed = append(allowed, u)
+		}
+	}
+
+	sent := false
+	if len(allowed) > 0 {
+		subject, body := digestSummary(allowed)
+		if err := d.mailer.Send(ctx, &Message{To: unread[0].Email, Subject: subject, Body: body}); err != nil {
+			d.logger.WithContext(ctx).Error("Failed to send email digest", "error", err, "user_id", userID)
+			return false, nil
+		}
+		sent = true
+	}
+
+	return sent, d.repo.SetDigestThrough(ctx, userID, through)
+}
+
+// digestSummary returns the subject and body of a digest email
+func digestSummary(unread []UnreadMessages) (string, string) {
+	var body strings.Builder
+	body.WriteString("You have unread messages on Whatsapp-Lite:\n\n")
+
+	total := 0
+	for _, u := range unread {
+		total += u.Count
+		fmt.Fprintf(&body, "- %s: %d unread %s\n", u.SenderUsername, u.Count, messageNoun(u.Count))
+	}
+
+	return fmt.Sprintf("You have %d unread %s on Whatsapp-Lite", total, messageNoun(total)), body.String()
+}
+
+// messageNoun returns the noun for a number of messages
+func messageNoun(count int) string {
+	if count == 1 {
+		return "message"
+	}
+	return "messages"
+}
@@ -0,0 +1,149 @@
+package email
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/conversation"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+)
+
+// WebhookSecretHeader carries the shared secret on inbound email requests
+const WebhookSecretHeader = "X-Webhook-Secret"
+
+// maxInboundSize bounds inbound email requests, which may include
+// attachments
+const maxInboundSize = 25 << 20
+
+// inboundRequest is an inbound email posted as JSON
+type inboundRequest struct {
+	To   string `json:"to"`
+	From string `json:"from"`
+	Text string `json:"text"`
+}
+
+// Handler handles inbound email webhook requests
+type Handler struct {
+	service *InboundService
+	secret  string
+	logger  logger.Logger
+}
+
+// NewHandler creates a new inbound email handler. Requests must carry the
+// secret in the X-Webhook-Secret header.
+func NewHandler(service *InboundService, secret string, logger logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		secret:  secret,
+		logger:  logger,
+	}
+}
+
+// Inbound handles an email forwarded by the mail provider. It accepts JSON
+// with to, from and text fields, or the form fields posted by Mailgun and
+// SendGrid inbound routes.
+func (h *Handler) Inbound(w http.ResponseWriter, r *http.Request) {
+	if h.service == nil || h.secret == "" {
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "email.replies_disabled"))
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(WebhookSecretHeader)), []byte(h.secret)) != 1 {
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "email.invalid_secret"))
+		return
+	}
+
+	// Parse request
+	r.Body = http.MaxBytesReader(w, r.Body, maxInboundSize)
+	var email InboundEmail
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var req inboundRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+			return
+		}
+		email = InboundEmail{To: req.To, From: req.From, Text: req.Text}
+	} else {
+		if err := r.ParseMultipartForm(maxInboundSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+			return
+		}
+		email = InboundEmail{
+			To:   firstValue(r, "recipient", "to"),
+			From: firstValue(r, "sender", "from"),
+			Text: firstValue(r, "stripped-text", "text", "body-plain"),
+		}
+	}
+
+	// Call service
+	msg, err := h.service.HandleReply(r.Context(), &email)
+	if err != nil {
+		h.sendServiceError(w, r, err)
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, map[string]string{"message_id": msg.ID.String()})
+}
+
+// firstValue returns the first non-empty form field among names
+func firstValue(r *http.Request, names ...string) string {
+	for _, name := range names {
+		if v := r.FormValue(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// sendServiceError maps a service error to an error response. Mail
+// providers retry on server errors only, so rejected emails get client
+// errors.
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrUnknownAddress):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "email.unknown_address"))
+	case errors.Is(err, ErrSenderMismatch):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "email.sender_mismatch"))
+	case errors.Is(err, ErrUserBanned):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.banned"))
+	case errors.Is(err, ErrEmptyReply):
+		sendError(w, r, errcode.InvalidContent, i18n.T(r, "email.empty_reply"))
+	case errors.Is(err, validator.ErrEmptyMessage), errors.Is(err, validator.ErrMessageTooLong), errors.Is(err, validator.ErrInvalidEncoding):
+		sendError(w, r, errcode.InvalidContent, i18n.Error(r, err))
+	case errors.Is(err, conversation.ErrUnauthorized):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "conversation.not_participant"))
+	case errors.Is(err, conversation.ErrNotAccepting):
+		sendError(w, r, errcode.RecipientNotAccepting, i18n.T(r, "conversation.recipient_not_accepting"))
+	case errors.Is(err, conversation.ErrNotContact):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "conversation.not_contact"))
+	default:
+		h.logger.WithContext(r.Context()).Error("Failed to handle email reply", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "message.send_failed"))
+	}
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
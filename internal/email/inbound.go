@@ -0,0 +1,146 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/conversation"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+)
+
+// Inbound errors
+var (
+	ErrUnknownAddress = errors.New("email was not sent to a known reply address")
+	ErrSenderMismatch = errors.New("email sender does not own the reply address")
+	ErrEmptyReply     = errors.New("email reply has no text")
+	ErrUserBanned     = errors.New("user is banned")
+)
+
+// InboundEmail is an email received by the mail provider
+type InboundEmail struct {
+	To   string // recipient addresses
+	From string
+	Text string // plain text body, possibly with the quoted original
+}
+
+// Conversations sends direct messages
+type Conversations interface {
+	GetRecipient(ctx context.Context, conversationID string, userID uuid.UUID) (uuid.UUID, error)
+	SendMessage(ctx context.Context, message *models.DirectMessage, senderUsername string) (*models.DirectMessageData, error)
+}
+
+// InboundService turns replies to notification emails into chat messages
+type InboundService struct {
+	replies       *ReplyAddresses
+	users         Users
+	conversations Conversations
+	messages      *validator.MessageValidator
+	logger        logger.Logger
+}
+
+// NewInboundService creates a new inbound email service
+func NewInboundService(replies *ReplyAddresses, users Users, conversations Conversations, messages *validator.MessageValidator, logger logger.Logger) *InboundService {
+	return &InboundService{
+		replies:       replies,
+		users:         users,
+		conversations: conversations,
+		messages:      messages,
+		logger:        logger,
+	}
+}
+
+// HandleReply sends the text of a reply as a message from the owner of the
+// reply address it was sent to. The sender address must be the owner's
+// email; the mail provider is trusted to reject mail that fails SPF and
+// DKIM checks.
+func (s *InboundService) HandleReply(ctx context.Context, email *InboundEmail) (*models.DirectMessage, error) {
+	userID, conversationID, err := s.replies.Resolve(ctx, email.To)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil, ErrUnknownAddress
+		}
+		return nil, err
+	}
+
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := mail.ParseAddress(email.From)
+	if err != nil || !strings.EqualFold(from.Address, user.Email) {
+		s.logger.WithContext(ctx).Info("Rejected email reply from another sender", "user_id", userID)
+		return nil, ErrSenderMismatch
+	}
+	if user.Ban.Active(time.Now()) {
+		return nil, ErrUserBanned
+	}
+
+	text := StripQuotedReply(email.Text)
+	if text == "" {
+		return nil, ErrEmptyReply
+	}
+	content, err := s.messages.Normalize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	recipientID, err := s.conversations.GetRecipient(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &models.DirectMessage{
+		ID:          uuid.New(),
+		SenderID:    userID,
+		RecipientID: recipientID,
+		Content:     content,
+		Format:      "plain",
+		CreatedAt:   time.Now(),
+	}
+	if _, err := s.conversations.SendMessage(ctx, msg, user.Username); err != nil && !errors.Is(err, conversation.ErrMessagePending) {
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Email reply sent as message", "user_id", userID, "message_id", msg.ID)
+	return msg, nil
+}
+
+// StripQuotedReply returns the new text of an email reply, without the
+// quoted original message or the signature that mail clients add below it
+func StripQuotedReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var kept []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if isReplyHeader(trimmed) || line == "-- " {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// isReplyHeader reports whether a line introduces the quoted original
+// message, as written by common mail clients
+func isReplyHeader(line string) bool {
+	switch {
+	case strings.HasPrefix(line, "On ") && strings.HasSuffix(line, "wrote:"):
+		return true
+	case strings.HasPrefix(line, "-----Original Message-----"):
+		return true
+	case strings.HasPrefix(line, "________________________________"):
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,157 @@
+// Package email sends notification emails with per-conversation reply
+// addresses and turns the replies into chat messages
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	ReplyTo string // optional
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// SMTPMailer sends email through an SMTP server
+type SMTPMailer struct {
+	config configs.SMTPConfig
+	from   *mail.Address
+}
+
+// NewSMTPMailer creates a mailer sending from an address
+func NewSMTPMailer(config configs.SMTPConfig, from string) (*SMTPMailer, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+	if config.Host == "" || config.Port == 0 {
+		return nil, errors.New("smtp host and port are required")
+	}
+	return &SMTPMailer{config: config, from: address}, nil
+}
+
+// Send delivers a message to its recipient
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := m.format(msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if m.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial connects to the server, using TLS when available
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	dialer := &net.Dialer{Timeout: m.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if m.config.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(m.config.Timeout))
+	}
+
+	tlsConfig := &tls.Config{ServerName: m.config.Host}
+	if m.config.Port == 465 {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok && m.config.Port != 465 {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// format encodes a message with its headers
+func (m *SMTPMailer) format(msg *Message) ([]byte, error) {
+	if strings.ContainsAny(msg.To+msg.ReplyTo, "\r\n") {
+		return nil, errors.New("invalid address")
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := m.from.Address[strings.LastIndexByte(m.from.Address, '@')+1:]
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	if msg.ReplyTo != "" {
+		fmt.Fprintf(&buf, "Reply-To: %s\r\n", msg.ReplyTo)
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("Auto-Submitted: auto-generated\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
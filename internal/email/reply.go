@@ -0,0 +1,67 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"net/mail"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// replyPrefix starts the local part of reply addresses
+const replyPrefix = "reply+"
+
+// replyTokenBytes is the number of random bytes in a reply token
+const replyTokenBytes = 20
+
+// tokenEncoding encodes reply tokens in lowercase, since mail servers may
+// not preserve the case of local parts
+var tokenEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// ReplyAddresses issues and resolves the addresses that route replies to
+// notification emails back into a conversation. Each user gets one address
+// per conversation.
+type ReplyAddresses struct {
+	repo   Repository
+	domain string
+}
+
+// NewReplyAddresses creates reply addresses at a domain
+func NewReplyAddresses(repo Repository, domain string) *ReplyAddresses {
+	return &ReplyAddresses{repo: repo, domain: strings.ToLower(domain)}
+}
+
+// Address returns the user's reply address for a conversation
+func (a *ReplyAddresses) Address(ctx context.Context, userID uuid.UUID, conversationID string) (string, error) {
+	b := make([]byte, replyTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	token, err := a.repo.GetOrCreateReplyToken(ctx, userID, conversationID, tokenEncoding.EncodeToString(b))
+	if err != nil {
+		return "", err
+	}
+	return replyPrefix + token + "@" + a.domain, nil
+}
+
+// Resolve returns the user and conversation of the first reply address
+// among a list of recipients
+func (a *ReplyAddresses) Resolve(ctx context.Context, recipients string) (uuid.UUID, string, error) {
+	addresses, err := mail.ParseAddressList(recipients)
+	if err != nil {
+		return uuid.Nil, "", ErrTokenNotFound
+	}
+
+	for _, address := range addresses {
+		at := strings.LastIndexByte(address.Address, '@')
+		local, domain := strings.ToLower(address.Address[:at]), strings.ToLower(address.Address[at+1:])
+		if domain != a.domain || !strings.HasPrefix(local, replyPrefix) {
+			continue
+		}
+		return a.repo.GetReplyTarget(ctx, strings.TrimPrefix(local, replyPrefix))
+	}
+	return uuid.Nil, "", ErrTokenNotFound
+}
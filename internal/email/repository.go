@@ -0,0 +1,124 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+)
+
+// ErrTokenNotFound is returned when a reply address is unknown
+var ErrTokenNotFound = errors.New("reply token not found")
+
+// UnreadMessages counts the unread direct messages from one sender in a
+// conversation, for an email digest
+type UnreadMessages struct {
+	UserID         uuid.UUID `db:"user_id"`
+	Email          string    `db:"email"`
+	ConversationID string    `db:"conversation_id"`
+	SenderUsername string    `db:"sender_username"`
+	Count          int       `db:"count"`
+	LastSentAt     time.Time `db:"last_sent_at"`
+}
+
+// Repository interface for reply address and email digest operations
+type Repository interface {
+	GetOrCreateReplyToken(ctx context.Context, userID uuid.UUID, conversationID, token string) (string, error)
+	GetReplyTarget(ctx context.Context, token string) (uuid.UUID, string, error)
+	GetUnreadMessages(ctx context.Context, sentAfter, sentBefore time.Time) ([]UnreadMessages, error)
+	SetDigestThrough(ctx context.Context, userID uuid.UUID, through time.Time) error
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// GetOrCreateReplyToken returns the user's reply token for a conversation,
+// saving token as it if they have none
+func (r *PostgresRepository) GetOrCreateReplyToken(ctx context.Context, userID uuid.UUID, conversationID, token string) (string, error) {
+	query := `
+		INSERT INTO email_reply_tokens (token, user_id, conversation_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, conversation_id) DO UPDATE
+		SET user_id = EXCLUDED.user_id
+		RETURNING token
+	`
+
+	var saved string
+	err := r.conn(ctx).GetContext(ctx, &saved, query, token, userID, conversationID)
+	return saved, err
+}
+
+// GetReplyTarget returns the user and conversation a reply token belongs to
+func (r *PostgresRepository) GetReplyTarget(ctx context.Context, token string) (uuid.UUID, string, error) {
+	query := "SELECT user_id, conversation_id FROM email_reply_tokens WHERE token = $1"
+
+	var userID uuid.UUID
+	var conversationID string
+	err := r.conn(ctx).QueryRowContext(ctx, query, token).Scan(&userID, &conversationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, "", ErrTokenNotFound
+		}
+		return uuid.Nil, "", err
+	}
+	return userID, conversationID, nil
+}
+
+// GetUnreadMessages summarizes, per recipient and conversation, the unread
+// direct messages sent in a window to users who opted in to email digests
+// and have an email address. Messages up to a recipient's
+// email_digest_through are left out.
+func (r *PostgresRepository) GetUnreadMessages(ctx context.Context, sentAfter, sentBefore time.Time) ([]UnreadMessages, error) {
+	query := `
+		SELECT
+			dm.recipient_id AS user_id,
+			u.email,
+			LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text AS conversation_id,
+			s.username AS sender_username,
+			COUNT(*) AS count,
+			MAX(dm.created_at) AS last_sent_at
+		FROM direct_messages dm
+		JOIN notification_preferences np ON np.user_id = dm.recipient_id AND np.email_digest_enabled
+		JOIN users u ON u.id = dm.recipient_id AND u.email IS NOT NULL AND u.email <> '' AND u.erased_at IS NULL
+		JOIN users s ON s.id = dm.sender_id
+		LEFT JOIN read_cursors rc
+			ON rc.user_id = dm.recipient_id
+			AND rc.conversation_id = LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text
+		WHERE (rc.user_id IS NULL OR (dm.created_at, dm.id) > (rc.last_read_message_at, rc.last_read_message_id))
+		  AND dm.created_at > $1
+		  AND dm.created_at <= $2
+		  AND (np.email_digest_through IS NULL OR dm.created_at > np.email_digest_through)
+		GROUP BY dm.recipient_id, u.email, conversation_id, s.username
+		ORDER BY dm.recipient_id, last_sent_at DESC
+	`
+
+	var unread []UnreadMessages
+	if err := r.conn(ctx).SelectContext(ctx, &unread, query, sentAfter, sentBefore); err != nil {
+		return nil, err
+	}
+	return unread, nil
+}
+
+// SetDigestThrough records the newest unread message that was considered
+// for a user's email digest, so later digests skip it
+func (r *PostgresRepository) SetDigestThrough(ctx context.Context, userID uuid.UUID, through time.Time) error {
+	query := "UPDATE notification_preferences SET email_digest_through = $2 WHERE user_id = $1"
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, through)
+	return err
+}
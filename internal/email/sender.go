@@ -0,0 +1,88 @@
+package email
+
+import (
+	"context"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Users looks up accounts
+type Users interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// Sender delivers notifications by email. Notifications about a
+// conversation carry a reply address when replies are configured.
+type Sender struct {
+	mailer  Mailer
+	users   Users
+	replies *ReplyAddresses // nil leaves reply addresses out
+	logger  logger.Logger
+}
+
+// NewSender creates an email notification sender
+func NewSender(mailer Mailer, users Users, replies *ReplyAddresses, logger logger.Logger) *Sender {
+	return &Sender{
+		mailer:  mailer,
+		users:   users,
+		replies: replies,
+		logger:  logger,
+	}
+}
+
+// Channel returns the email channel
+func (s *Sender) Channel() string {
+	return models.ChannelEmail
+}
+
+// Send emails a notification to its user. Users who signed up with a phone
+// number and have no email are skipped.
+func (s *Sender) Send(ctx context.Context, notification *models.Notification) error {
+	user, err := s.users.GetUserByID(ctx, notification.UserID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+
+	subject, body := describe(notification)
+	msg := &Message{To: user.Email, Subject: subject, Body: body}
+
+	if s.replies != nil && notification.ConversationID != "" {
+		replyTo, err := s.replies.Address(ctx, user.ID, notification.ConversationID)
+		if err != nil {
+			s.logger.WithContext(ctx).Error("Failed to create reply address", "error", err, "user_id", user.ID)
+		} else {
+			msg.ReplyTo = replyTo
+			msg.Body += "\n\nReply to this email to answer in the chat."
+		}
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// describe returns the subject and body of a notification email
+func describe(n *models.Notification) (string, string) {
+	from := n.SenderUsername
+	if from == "" {
+		from = "Someone"
+	}
+
+	switch n.Type {
+	case models.NotificationDirectMessage:
+		return "New message from " + from, from + ": " + n.Body
+	case models.NotificationMention:
+		return from + " mentioned you", from + ": " + n.Body
+	case models.NotificationMedia:
+		return "New file from " + from, from + " sent you " + n.Body
+	case models.NotificationContactRequest:
+		return from + " wants to add you as a contact", from + " sent you a contact request."
+	case models.NotificationSecurityAlert:
+		return "New login to your account", n.Body + "\n\nIf this wasn't you, change your password and log out of all devices."
+	default:
+		return "New notification", n.Body
+	}
+}
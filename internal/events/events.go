@@ -0,0 +1,142 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Event types published to the message broker
+const (
+	TypeMessageCreated  = "message_created"
+	TypeUserRegistered  = "user_registered"
+	TypePresenceChanged = "presence_changed"
+)
+
+// Event is a domain event published to the message broker
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// New creates an event of the given type with a fresh ID
+func New(eventType string, data interface{}) Event {
+	return Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		Data:       data,
+	}
+}
+
+// Key returns the partitioning key for the event, keeping events about the
+// same entity in order on brokers that support it
+func (e Event) Key() string {
+	if k, ok := e.Data.(interface{ EventKey() string }); ok {
+		return k.EventKey()
+	}
+	return e.ID
+}
+
+// MessageCreatedData is the payload of a message_created event
+type MessageCreatedData struct {
+	MessageID      string    `json:"message_id"`
+	ConversationID string    `json:"conversation_id"`
+	SenderID       string    `json:"sender_id"`
+	RecipientID    string    `json:"recipient_id"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// EventKey implements keyed partitioning by conversation
+func (d MessageCreatedData) EventKey() string { return d.ConversationID }
+
+// UserRegisteredData is the payload of a user_registered event
+type UserRegisteredData struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email,omitempty"` // empty for phone signups
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventKey implements keyed partitioning by user
+func (d UserRegisteredData) EventKey() string { return d.UserID }
+
+// PresenceChangedData is the payload of a presence_changed event
+type PresenceChangedData struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Status   string `json:"status"`
+}
+
+// EventKey implements keyed partitioning by user
+func (d PresenceChangedData) EventKey() string { return d.UserID }
+
+// Publisher publishes domain events to a message broker
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+// NewPublisher creates the publisher selected by the events configuration
+func NewPublisher(config configs.EventsConfig, logger logger.Logger) (Publisher, error) {
+	switch config.Driver {
+	case "", "none":
+		return NopPublisher{}, nil
+	case "log":
+		return NewLogPublisher(logger), nil
+	case "nats":
+		return NewNATSPublisher(config.NATS, config.Prefix, logger)
+	case "kafka":
+		return NewKafkaPublisher(config.Kafka, config.Prefix, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown events driver: %q", config.Driver)
+	}
+}
+
+// topic returns the broker topic or subject for an event type
+func topic(prefix, eventType string) string {
+	if prefix == "" {
+		return eventType
+	}
+	return prefix + "." + eventType
+}
+
+// NopPublisher discards all events
+type NopPublisher struct{}
+
+// Publish discards the event
+func (NopPublisher) Publish(ctx context.Context, event Event) error { return nil }
+
+// Close does nothing
+func (NopPublisher) Close() error { return nil }
+
+// LogPublisher writes events to the application log, useful in development
+type LogPublisher struct {
+	logger logger.Logger
+}
+
+// NewLogPublisher creates a new log publisher
+func NewLogPublisher(logger logger.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+// Publish logs the event
+func (p *LogPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	p.logger.Info("Domain event published", "type", event.Type, "event", string(body))
+	return nil
+}
+
+// Close does nothing
+func (p *LogPublisher) Close() error { return nil }
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher publishes events to Kafka topics named <prefix>.<event type>
+type KafkaPublisher struct {
+	writer *kafka.Writer
+	prefix string
+	logger logger.Logger
+}
+
+// NewKafkaPublisher creates a new Kafka publisher
+func NewKafkaPublisher(config configs.KafkaConfig, prefix string, logger logger.Logger) *KafkaPublisher {
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(config.Brokers...),
+		Balancer:               &kafka.Hash{},
+		BatchTimeout:           config.BatchTimeout,
+		AllowAutoTopicCreation: true,
+		// Writes are asynchronous so publishing never blocks request handling
+		Async: true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				logger.Error("Failed to publish events to Kafka", "error", err, "count", len(messages))
+			}
+		},
+	}
+
+	return &KafkaPublisher{
+		writer: writer,
+		prefix: prefix,
+		logger: logger,
+	}
+}
+
+// Publish publishes an event to Kafka
+func (p *KafkaPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Topic: topic(p.prefix, event.Type),
+		Key:   []byte(event.Key()),
+		Value: body,
+	})
+}
+
+// Close flushes pending events and closes the writer
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes events to NATS subjects named <prefix>.<event type>
+type NATSPublisher struct {
+	conn   *nats.Conn
+	prefix string
+	logger logger.Logger
+}
+
+// NewNATSPublisher connects to NATS and creates a new publisher
+func NewNATSPublisher(config configs.NATSConfig, prefix string, logger logger.Logger) (*NATSPublisher, error) {
+	opts := []nats.Option{
+		nats.Name("whatsapp-lite"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("Disconnected from NATS", "error", err)
+			}
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			logger.Info("Reconnected to NATS", "url", c.ConnectedUrl())
+		}),
+	}
+
+	conn, err := nats.Connect(config.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	return &NATSPublisher{
+		conn:   conn,
+		prefix: prefix,
+		logger: logger,
+	}, nil
+}
+
+// Publish publishes an event to NATS
+func (p *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.conn.Publish(topic(p.prefix, event.Type), body)
+}
+
+// Close flushes pending events and closes the connection
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
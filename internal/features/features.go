@@ -0,0 +1,192 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Known feature flags
+const (
+	Markdown         = "markdown"
+	Mentions         = "mentions"
+	Drafts           = "drafts"
+	TypingIndicators = "typing_indicators"
+	Calls            = "calls"
+)
+
+// defaultFlags lists every known flag with its state when neither the
+// config file nor the database overrides it
+var defaultFlags = []models.FeatureFlag{
+	{Name: Markdown, Enabled: true, Percentage: 100},
+	{Name: Mentions, Enabled: true, Percentage: 100},
+	{Name: Drafts, Enabled: true, Percentage: 100},
+	{Name: TypingIndicators, Enabled: true, Percentage: 100},
+	{Name: Calls, Enabled: true, Percentage: 100},
+}
+
+// ErrUnknownFlag is returned when updating a flag that does not exist
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// Manager evaluates feature flags from an in-memory snapshot that combines
+// config defaults with database overrides
+type Manager struct {
+	repo     Repository
+	defaults map[string]models.FeatureFlag
+	logger   logger.Logger
+
+	mu    sync.RWMutex
+	flags map[string]models.FeatureFlag
+}
+
+// NewManager creates a new feature flag manager
+func NewManager(repo Repository, config configs.FeaturesConfig, logger logger.Logger) *Manager {
+	defaults := make(map[string]models.FeatureFlag, len(defaultFlags))
+	for _, flag := range defaultFlags {
+		defaults[flag.Name] = flag
+	}
+
+	// Apply config file overrides
+	for name, fc := range config.Flags {
+		if _, ok := defaults[name]; !ok {
+			logger.Warn("Ignoring unknown feature flag in config", "flag", name)
+			continue
+		}
+		flag := models.FeatureFlag{
+			Name:       name,
+			Enabled:    fc.Enabled,
+			Percentage: 100,
+			UserIDs:    fc.Users,
+		}
+		if fc.Percentage != nil {
+			flag.Percentage = *fc.Percentage
+		}
+		defaults[name] = flag
+	}
+
+	m := &Manager{
+		repo:     repo,
+		defaults: defaults,
+		logger:   logger,
+		flags:    make(map[string]models.FeatureFlag, len(defaults)),
+	}
+	for name, flag := range defaults {
+		m.flags[name] = flag
+	}
+
+	return m
+}
+
+// Enabled reports whether a feature is enabled for a user. Users on the
+// flag's allowlist always get the feature; everyone else is included when
+// their stable bucket falls within the rollout percentage.
+func (m *Manager) Enabled(name string, userID uuid.UUID) bool {
+	m.mu.RLock()
+	flag, ok := m.flags[name]
+	m.mu.RUnlock()
+
+	if !ok || !flag.Enabled {
+		return false
+	}
+
+	for _, id := range flag.UserIDs {
+		if id == userID.String() {
+			return true
+		}
+	}
+
+	if flag.Percentage >= 100 {
+		return true
+	}
+	return bucket(name, userID) < flag.Percentage
+}
+
+// List returns all known flags sorted by name
+func (m *Manager) List() []models.FeatureFlag {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	flags := make([]models.FeatureFlag, 0, len(m.flags))
+	for _, flag := range m.flags {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// Set updates a flag at runtime and persists the override
+func (m *Manager) Set(ctx context.Context, name string, req *models.FeatureFlagRequest) (*models.FeatureFlag, error) {
+	m.mu.RLock()
+	current, ok := m.flags[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownFlag
+	}
+
+	flag := models.FeatureFlag{
+		Name:       name,
+		Enabled:    req.Enabled,
+		Percentage: current.Percentage,
+		UserIDs:    req.UserIDs,
+		UpdatedAt:  time.Now(),
+	}
+	if req.Percentage != nil {
+		flag.Percentage = *req.Percentage
+	}
+	if flag.UserIDs == nil {
+		flag.UserIDs = []string{}
+	}
+
+	if err := m.repo.SaveFlag(ctx, &flag); err != nil {
+		m.logger.WithContext(ctx).Error("Failed to save feature flag", "flag", name, "error", err)
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.flags[name] = flag
+	m.mu.Unlock()
+
+	m.logger.WithContext(ctx).Info("Feature flag updated", "flag", name, "enabled", flag.Enabled, "percentage", flag.Percentage)
+	return &flag, nil
+}
+
+// Refresh reloads database overrides, picking up changes made by other instances
+func (m *Manager) Refresh(ctx context.Context) error {
+	overrides, err := m.repo.GetFlags(ctx)
+	if err != nil {
+		return err
+	}
+
+	flags := make(map[string]models.FeatureFlag, len(m.defaults))
+	for name, flag := range m.defaults {
+		flags[name] = flag
+	}
+	for _, flag := range overrides {
+		if _, ok := flags[flag.Name]; ok {
+			flags[flag.Name] = flag
+		}
+	}
+
+	m.mu.Lock()
+	m.flags = flags
+	m.mu.Unlock()
+
+	return nil
+}
+
+// bucket maps a user to a stable value in [0, 100) for a flag, so each
+// flag rolls out to a different subset of users
+func bucket(name string, userID uuid.UUID) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write(userID[:])
+	return int(h.Sum32() % 100)
+}
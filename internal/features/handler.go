@@ -0,0 +1,96 @@
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/gorilla/mux"
+)
+
+// Handler handles feature flag admin HTTP requests
+type Handler struct {
+	manager   *Manager
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new feature flag handler
+func NewHandler(manager *Manager, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		manager:   manager,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// ListFlags handles requests to list all feature flags
+func (h *Handler) ListFlags(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, http.StatusOK, models.FeatureFlagListResponse{
+		Flags: h.manager.List(),
+	})
+}
+
+// UpdateFlag handles requests to toggle a feature flag at runtime
+func (h *Handler) UpdateFlag(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	// Parse and validate request
+	var req models.FeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode feature flag request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call manager
+	flag, err := h.manager.Set(r.Context(), name, &req)
+	if err != nil {
+		if errors.Is(err, ErrUnknownFlag) {
+			sendError(w, r, errcode.NotFound, i18n.T(r, "feature.unknown"))
+			return
+		}
+		sendError(w, r, errcode.Internal, i18n.T(r, "feature.update_failed"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, flag)
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
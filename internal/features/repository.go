@@ -0,0 +1,72 @@
+package features
+
+import (
+	"context"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/lib/pq"
+)
+
+// Repository interface for feature flag overrides
+type Repository interface {
+	GetFlags(ctx context.Context) ([]models.FeatureFlag, error)
+	SaveFlag(ctx context.Context, flag *models.FeatureFlag) error
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// GetFlags retrieves all feature flag overrides
+func (r *PostgresRepository) GetFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	query := `
+		SELECT name, enabled, percentage, user_ids::text[], updated_at
+		FROM feature_flags
+	`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []models.FeatureFlag
+	for rows.Next() {
+		var flag models.FeatureFlag
+		err := rows.Scan(&flag.Name, &flag.Enabled, &flag.Percentage, pq.Array(&flag.UserIDs), &flag.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags, rows.Err()
+}
+
+// SaveFlag creates or replaces a feature flag override
+func (r *PostgresRepository) SaveFlag(ctx context.Context, flag *models.FeatureFlag) error {
+	query := `
+		INSERT INTO feature_flags (name, enabled, percentage, user_ids, updated_at)
+		VALUES ($1, $2, $3, $4::uuid[], $5)
+		ON CONFLICT (name) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+			percentage = EXCLUDED.percentage,
+			user_ids = EXCLUDED.user_ids,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, flag.Name, flag.Enabled, flag.Percentage, pq.Array(flag.UserIDs), flag.UpdatedAt)
+	return err
+}
@@ -0,0 +1,506 @@
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Handler handles group HTTP requests
+type Handler struct {
+	service          Service
+	logger           logger.Logger
+	validator        validator.Validator
+	messageValidator *validator.MessageValidator
+}
+
+// NewHandler creates a new group handler
+func NewHandler(service Service, logger logger.Logger, validator validator.Validator, messageValidator *validator.MessageValidator) *Handler {
+	return &Handler{
+		service:          service,
+		logger:           logger,
+		validator:        validator,
+		messageValidator: messageValidator,
+	}
+}
+
+// CreateGroup handles requests to create a group
+func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.CreateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode create group request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	group, err := h.service.CreateGroup(r.Context(), userID, auth.GetWorkspaceID(r.Context()), req.Name)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.create_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusCreated, group)
+}
+
+// GetGroup handles requests to get a group and its members
+func (h *Handler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	group, err := h.service.GetGroup(r.Context(), groupID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.get_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, group)
+}
+
+// AddMember handles requests to add a member to a group
+func (h *Handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.AddGroupMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode add member request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	memberID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	group, err := h.service.AddMember(r.Context(), groupID, userID, memberID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.add_member_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, group)
+}
+
+// RemoveMember handles requests to remove a member from a group
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	if err := h.service.RemoveMember(r.Context(), groupID, userID, memberID); err != nil {
+		h.sendServiceError(w, r, err, "group.remove_member_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LeaveGroup handles requests to leave a group
+func (h *Handler) LeaveGroup(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	if err := h.service.LeaveGroup(r.Context(), groupID, userID); err != nil {
+		h.sendServiceError(w, r, err, "group.leave_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMessages handles requests for a group's message history, including
+// system messages recording membership changes
+func (h *Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse query parameters
+	query := r.URL.Query()
+	before, err := pagination.Decode(query.Get("before"))
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "pagination.invalid_cursor"))
+		return
+	}
+	limit := pagination.ParseLimit(query.Get("limit"), 50, pagination.MaxLimit)
+
+	// Call service
+	resp, err := h.service.GetMessages(r.Context(), groupID, userID, before, limit)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.messages_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// SendMessage handles requests to send a message to a group
+func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.SendGroupMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode send group message request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	content, err := h.messageValidator.Normalize(req.Content)
+	if err != nil {
+		sendError(w, r, errcode.InvalidContent, i18n.Error(r, err))
+		return
+	}
+
+	// Call service
+	message, err := h.service.SendMessage(r.Context(), groupID, userID, content)
+	if err != nil {
+		h.sendServiceError(w, r, err, "message.send_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusCreated, message)
+}
+
+// GetMessageInfo handles requests for who a group message was delivered
+// to and read by
+func (h *Handler) GetMessageInfo(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	messageID, err := uuid.Parse(mux.Vars(r)["message_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "message.invalid_id"))
+		return
+	}
+
+	// Call service
+	info, err := h.service.GetMessageInfo(r.Context(), groupID, messageID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.message_info_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, info)
+}
+
+// CreateInvite handles requests to create a group invite link
+func (h *Handler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request. An empty body creates an invite without
+	// limits.
+	var req models.CreateGroupInviteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.WithContext(r.Context()).Error("Failed to decode create invite request", "error", err)
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+			return
+		}
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	invite, err := h.service.CreateInvite(r.Context(), groupID, userID, &req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.invite_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusCreated, invite)
+}
+
+// GetInvites handles requests to list a group's invite links
+func (h *Handler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	resp, err := h.service.GetInvites(r.Context(), groupID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.invite_list_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// RevokeInvite handles requests to revoke a group invite link
+func (h *Handler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	inviteID, err := uuid.Parse(mux.Vars(r)["invite_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "group.invalid_invite_id"))
+		return
+	}
+
+	// Call service
+	invite, err := h.service.RevokeInvite(r.Context(), groupID, inviteID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.invite_revoke_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, invite)
+}
+
+// JoinByInvite handles requests to join a group through an invite link.
+// The join request waits for an admin to approve it.
+func (h *Handler) JoinByInvite(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	request, err := h.service.JoinByInvite(r.Context(), mux.Vars(r)["code"], userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.join_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusAccepted, request)
+}
+
+// GetJoinRequests handles requests to list a group's pending join requests
+func (h *Handler) GetJoinRequests(w http.ResponseWriter, r *http.Request) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	resp, err := h.service.GetJoinRequests(r.Context(), groupID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.join_request_list_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// ApproveJoinRequest handles requests to approve a join request
+func (h *Handler) ApproveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	h.answerJoinRequest(w, r, h.service.ApproveJoinRequest)
+}
+
+// DeclineJoinRequest handles requests to decline a join request
+func (h *Handler) DeclineJoinRequest(w http.ResponseWriter, r *http.Request) {
+	h.answerJoinRequest(w, r, h.service.DeclineJoinRequest)
+}
+
+// answerJoinRequest parses the group and request IDs and answers the
+// request with answer
+func (h *Handler) answerJoinRequest(w http.ResponseWriter, r *http.Request, answer func(ctx context.Context, groupID, requestID, userID uuid.UUID) (*models.GroupJoinRequest, error)) {
+	userID, groupID, ok := h.groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	requestID, err := uuid.Parse(mux.Vars(r)["request_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "group.invalid_join_request_id"))
+		return
+	}
+
+	// Call service
+	request, err := answer(r.Context(), groupID, requestID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "group.join_request_answer_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, request)
+}
+
+// groupRequest returns the authenticated user's ID and the group ID from
+// the path, sending an error response if either is invalid
+func (h *Handler) groupRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	groupID, err := uuid.Parse(mux.Vars(r)["group_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "group.invalid_id"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, groupID, true
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing or malformed
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// sendServiceError maps a service error to an HTTP error response, using
+// the message key for unexpected errors
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error, key string) {
+	switch {
+	case errors.Is(err, ErrGroupNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "group.not_found"))
+	case errors.Is(err, ErrNotMember):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "group.not_member"))
+	case errors.Is(err, ErrNotAdmin):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "group.admin_required"))
+	case errors.Is(err, ErrUserNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+	case errors.Is(err, ErrMemberNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "group.member_not_found"))
+	case errors.Is(err, ErrMessageNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "message.not_found"))
+	case errors.Is(err, ErrNotSender):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "group.not_sender"))
+	case errors.Is(err, ErrNotInWorkspace):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "group.not_in_workspace"))
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "pagination.invalid_cursor"))
+	case errors.Is(err, ErrAlreadyMember):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "group.already_member"))
+	case errors.Is(err, ErrInvalidExpiry):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "group.invalid_invite_expiry"))
+	case errors.Is(err, ErrInviteNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "group.invite_not_found"))
+	case errors.Is(err, ErrInviteUnusable):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "group.invite_unusable"))
+	case errors.Is(err, ErrJoinRequestExists):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "group.join_request_exists"))
+	case errors.Is(err, ErrJoinRequestNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "group.join_request_not_found"))
+	case errors.Is(err, ErrJoinRequestNotPending):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "group.join_request_not_pending"))
+	default:
+		h.logger.WithContext(r.Context()).Error(i18n.English.T(key), "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, key))
+	}
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
@@ -0,0 +1,593 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Repository errors
+var (
+	ErrGroupNotFound         = errors.New("group not found")
+	ErrNotMember             = errors.New("user is not a member of the group")
+	ErrInviteNotFound        = errors.New("group invite not found")
+	ErrInviteUnusable        = errors.New("group invite is revoked, expired or used up")
+	ErrJoinRequestExists     = errors.New("join request already pending")
+	ErrJoinRequestNotFound   = errors.New("join request not found")
+	ErrJoinRequestNotPending = errors.New("join request has already been answered")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrMessageNotFound       = errors.New("group message not found")
+)
+
+// uniqueViolation is the PostgreSQL error code for unique constraint violations
+const uniqueViolation = "23505"
+
+// Repository interface for group operations
+type Repository interface {
+	CreateGroup(ctx context.Context, group *models.Group) error
+	GetGroup(ctx context.Context, groupID uuid.UUID) (*models.Group, error)
+	GetMembers(ctx context.Context, groupID uuid.UUID) ([]models.GroupMember, error)
+	GetMemberRole(ctx context.Context, groupID, userID uuid.UUID) (string, error)
+	AddMember(ctx context.Context, groupID, userID uuid.UUID, role string) (bool, error)
+	RemoveMember(ctx context.Context, groupID, userID uuid.UUID) (bool, error)
+	EnsureAdmin(ctx context.Context, groupID uuid.UUID) error
+	GetUsername(ctx context.Context, userID uuid.UUID) (string, error)
+	CreateMessage(ctx context.Context, message *models.GroupMessage) error
+	GetMessages(ctx context.Context, groupID uuid.UUID, before *pagination.Cursor, limit int) ([]models.GroupMessage, bool, string, error)
+	GetMessage(ctx context.Context, groupID, messageID uuid.UUID) (*models.GroupMessage, error)
+	CreateReceipts(ctx context.Context, message *models.GroupMessage) error
+	MarkDelivered(ctx context.Context, messageID uuid.UUID, userIDs []uuid.UUID, deliveredAt time.Time) error
+	MarkRead(ctx context.Context, groupID, userID uuid.UUID, readAt time.Time) ([]ReadMessage, error)
+	GetDeliveryStatuses(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID]string, error)
+	GetReceipts(ctx context.Context, messageID uuid.UUID) ([]models.GroupMessageReceipt, error)
+	CreateInvite(ctx context.Context, invite *models.GroupInvite) error
+	GetInvites(ctx context.Context, groupID uuid.UUID) ([]models.GroupInvite, error)
+	GetInviteByCode(ctx context.Context, code string) (*models.GroupInvite, error)
+	UseInvite(ctx context.Context, inviteID uuid.UUID, now time.Time) error
+	RevokeInvite(ctx context.Context, groupID, inviteID uuid.UUID, revokedAt time.Time) (*models.GroupInvite, error)
+	CreateJoinRequest(ctx context.Context, request *models.GroupJoinRequest) error
+	GetJoinRequest(ctx context.Context, requestID uuid.UUID) (*models.GroupJoinRequest, error)
+	GetPendingJoinRequests(ctx context.Context, groupID uuid.UUID) ([]models.GroupJoinRequest, error)
+	UpdateJoinRequestStatus(ctx context.Context, requestID uuid.UUID, status string, respondedBy uuid.UUID, respondedAt time.Time) error
+}
+
+// ReadMessage identifies a message a member has just read
+type ReadMessage struct {
+	MessageID uuid.UUID `db:"id"`
+	SenderID  uuid.UUID `db:"sender_id"`
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// inviteColumns are the columns selected for group invites
+const inviteColumns = "id, group_id, code, created_by, expires_at, max_uses, uses, revoked_at, created_at"
+
+// joinRequestQuery selects join requests with the group name and the
+// requesting user's username. Callers append conditions and ordering.
+const joinRequestQuery = `
+        SELECT
+            jr.id,
+            jr.group_id,
+            g.name as group_name,
+            jr.user_id,
+            u.username,
+            jr.invite_id,
+            jr.status,
+            jr.created_at,
+            jr.responded_at,
+            jr.responded_by
+        FROM group_join_requests jr
+        JOIN groups g ON g.id = jr.group_id
+        JOIN users u ON u.id = jr.user_id
+`
+
+// deliveryStatusExpr aggregates the receipts of the text message gm from
+// the group's current members: read once all of them read it, delivered
+// once it reached all of them, sent otherwise or while nobody else is in
+// the group. System messages get an empty status.
+const deliveryStatusExpr = `
+            CASE
+                WHEN gm.type <> 'text' THEN ''
+                WHEN NOT EXISTS (
+                    SELECT 1 FROM message_delivery_status mds
+                    JOIN group_members m ON m.group_id = gm.group_id AND m.user_id = mds.user_id
+                    WHERE mds.message_id = gm.id
+                ) THEN 'sent'
+                WHEN NOT EXISTS (
+                    SELECT 1 FROM message_delivery_status mds
+                    JOIN group_members m ON m.group_id = gm.group_id AND m.user_id = mds.user_id
+                    WHERE mds.message_id = gm.id AND NOT mds.read
+                ) THEN 'read'
+                WHEN NOT EXISTS (
+                    SELECT 1 FROM message_delivery_status mds
+                    JOIN group_members m ON m.group_id = gm.group_id AND m.user_id = mds.user_id
+                    WHERE mds.message_id = gm.id AND NOT mds.delivered
+                ) THEN 'delivered'
+                ELSE 'sent'
+            END`
+
+// messageQuery selects a group's messages with the sender's and subject's
+// usernames and the aggregated delivery status. Callers append conditions
+// and ordering.
+const messageQuery = `
+        SELECT
+            gm.id,
+            gm.group_id,
+            gm.type,
+            gm.sender_id,
+            sender.username as sender_username,
+            gm.subject_id,
+            subject.username as subject_username,
+            gm.content,
+            gm.created_at,` + deliveryStatusExpr + ` as delivery_status
+        FROM group_messages gm
+        JOIN users sender ON sender.id = gm.sender_id
+        LEFT JOIN users subject ON subject.id = gm.subject_id
+        WHERE gm.group_id = $1
+`
+
+// CreateGroup saves a new group
+func (r *PostgresRepository) CreateGroup(ctx context.Context, group *models.Group) error {
+	query := `
+		INSERT INTO groups (id, name, created_by, created_at, updated_at, workspace_id)
+		VALUES ($1, $2, $3, $4, $4, $5)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, group.ID, group.Name, group.CreatedBy, group.CreatedAt, group.WorkspaceID)
+	return err
+}
+
+// GetGroup retrieves a group by ID, without its members
+func (r *PostgresRepository) GetGroup(ctx context.Context, groupID uuid.UUID) (*models.Group, error) {
+	var group models.Group
+	query := "SELECT id, name, created_by, created_at, workspace_id FROM groups WHERE id = $1"
+	if err := r.conn(ctx).GetContext(ctx, &group, query, groupID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetMembers retrieves a group's members in the order they joined
+func (r *PostgresRepository) GetMembers(ctx context.Context, groupID uuid.UUID) ([]models.GroupMember, error) {
+	query := `
+		SELECT gm.user_id, u.username, gm.role, gm.joined_at
+		FROM group_members gm
+		JOIN users u ON u.id = gm.user_id
+		WHERE gm.group_id = $1
+		ORDER BY gm.joined_at ASC
+	`
+
+	var members []models.GroupMember
+	if err := r.conn(ctx).SelectContext(ctx, &members, query, groupID); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetMemberRole retrieves a member's role in a group
+func (r *PostgresRepository) GetMemberRole(ctx context.Context, groupID, userID uuid.UUID) (string, error) {
+	query := "SELECT role FROM group_members WHERE group_id = $1 AND user_id = $2"
+
+	var role string
+	if err := r.conn(ctx).GetContext(ctx, &role, query, groupID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotMember
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// AddMember adds a user to a group, reporting whether they were added.
+// Existing members keep their role.
+func (r *PostgresRepository) AddMember(ctx context.Context, groupID, userID uuid.UUID, role string) (bool, error) {
+	query := `
+		INSERT INTO group_members (group_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (group_id, user_id) DO NOTHING
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, groupID, userID, role)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// RemoveMember removes a user from a group, reporting whether they were a
+// member
+func (r *PostgresRepository) RemoveMember(ctx context.Context, groupID, userID uuid.UUID) (bool, error) {
+	result, err := r.conn(ctx).ExecContext(ctx, "DELETE FROM group_members WHERE group_id = $1 AND user_id = $2", groupID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// EnsureAdmin makes the longest-standing member an admin if the group has
+// members but no admins left
+func (r *PostgresRepository) EnsureAdmin(ctx context.Context, groupID uuid.UUID) error {
+	query := `
+		UPDATE group_members
+		SET role = $2
+		WHERE id = (
+			SELECT id FROM group_members
+			WHERE group_id = $1
+			ORDER BY joined_at ASC, id ASC
+			LIMIT 1
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM group_members WHERE group_id = $1 AND role = $2
+		)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, groupID, models.GroupRoleAdmin)
+	return err
+}
+
+// GetUsername retrieves a user's username
+func (r *PostgresRepository) GetUsername(ctx context.Context, userID uuid.UUID) (string, error) {
+	var username string
+	if err := r.conn(ctx).GetContext(ctx, &username, "SELECT username FROM users WHERE id = $1", userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	return username, nil
+}
+
+// CreateMessage saves a message to a group's timeline
+func (r *PostgresRepository) CreateMessage(ctx context.Context, message *models.GroupMessage) error {
+	query := `
+		INSERT INTO group_messages (id, group_id, type, sender_id, subject_id, content, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		message.ID,
+		message.GroupID,
+		message.Type,
+		message.SenderID,
+		message.SubjectID,
+		message.Content,
+		message.Timestamp,
+	)
+	return err
+}
+
+// GetMessages retrieves a page of a group's messages, newest first
+func (r *PostgresRepository) GetMessages(ctx context.Context, groupID uuid.UUID, before *pagination.Cursor, limit int) ([]models.GroupMessage, bool, string, error) {
+	query := messageQuery
+	args := []interface{}{groupID}
+
+	// Add cursor condition if provided
+	if before != nil {
+		beforeTime, err := before.Time()
+		if err != nil {
+			return nil, false, "", err
+		}
+		query += " AND (gm.created_at, gm.id) < ($2, $3)"
+		args = append(args, beforeTime, before.ID)
+	}
+
+	// Add ordering and limit
+	query += " ORDER BY gm.created_at DESC, gm.id DESC LIMIT $" + strconv.Itoa(len(args)+1)
+	args = append(args, limit+1) // Get one extra message to check if there are more
+
+	var messages []models.GroupMessage
+	if err := r.conn(ctx).SelectContext(ctx, &messages, query, args...); err != nil {
+		return nil, false, "", err
+	}
+
+	messages, hasMore, nextCursor := pagination.Trim(messages, limit, func(m models.GroupMessage) pagination.Cursor {
+		return pagination.TimeCursor(m.Timestamp, m.ID)
+	})
+
+	return messages, hasMore, nextCursor, nil
+}
+
+// CreateInvite saves a new group invite
+func (r *PostgresRepository) CreateInvite(ctx context.Context, invite *models.GroupInvite) error {
+	query := `
+		INSERT INTO group_invites (id, group_id, code, created_by, expires_at, max_uses, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		invite.ID,
+		invite.GroupID,
+		invite.Code,
+		invite.CreatedBy,
+		invite.ExpiresAt,
+		invite.MaxUses,
+		invite.CreatedAt,
+	)
+	return err
+}
+
+// GetInvites retrieves a group's invites, newest first
+func (r *PostgresRepository) GetInvites(ctx context.Context, groupID uuid.UUID) ([]models.GroupInvite, error) {
+	query := "SELECT " + inviteColumns + " FROM group_invites WHERE group_id = $1 ORDER BY created_at DESC"
+
+	var invites []models.GroupInvite
+	if err := r.conn(ctx).SelectContext(ctx, &invites, query, groupID); err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// GetInviteByCode retrieves a group invite by its code
+func (r *PostgresRepository) GetInviteByCode(ctx context.Context, code string) (*models.GroupInvite, error) {
+	query := "SELECT " + inviteColumns + " FROM group_invites WHERE code = $1"
+
+	var invite models.GroupInvite
+	if err := r.conn(ctx).GetContext(ctx, &invite, query, code); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInviteNotFound
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// UseInvite counts a use of an invite if it is still usable at now. The
+// check and the increment are a single statement so concurrent joins
+// cannot exceed the usage limit.
+func (r *PostgresRepository) UseInvite(ctx context.Context, inviteID uuid.UUID, now time.Time) error {
+	query := `
+		UPDATE group_invites
+		SET uses = uses + 1
+		WHERE id = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > $2)
+		  AND (max_uses IS NULL OR uses < max_uses)
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, inviteID, now)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInviteUnusable
+	}
+	return nil
+}
+
+// RevokeInvite revokes a group's invite. Revoking a revoked invite keeps
+// the original revocation time.
+func (r *PostgresRepository) RevokeInvite(ctx context.Context, groupID, inviteID uuid.UUID, revokedAt time.Time) (*models.GroupInvite, error) {
+	query := `
+		UPDATE group_invites
+		SET revoked_at = COALESCE(revoked_at, $3)
+		WHERE id = $1 AND group_id = $2
+		RETURNING ` + inviteColumns
+
+	var invite models.GroupInvite
+	if err := r.conn(ctx).GetContext(ctx, &invite, query, inviteID, groupID, revokedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInviteNotFound
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// CreateJoinRequest saves a new pending join request
+func (r *PostgresRepository) CreateJoinRequest(ctx context.Context, request *models.GroupJoinRequest) error {
+	query := `
+		INSERT INTO group_join_requests (id, group_id, user_id, invite_id, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		request.ID,
+		request.GroupID,
+		request.UserID,
+		request.InviteID,
+		request.Status,
+		request.CreatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrJoinRequestExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetJoinRequest retrieves a join request by ID
+func (r *PostgresRepository) GetJoinRequest(ctx context.Context, requestID uuid.UUID) (*models.GroupJoinRequest, error) {
+	var request models.GroupJoinRequest
+	if err := r.conn(ctx).GetContext(ctx, &request, joinRequestQuery+" WHERE jr.id = $1", requestID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJoinRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetPendingJoinRequests retrieves a group's pending join requests, oldest
+// first
+func (r *PostgresRepository) GetPendingJoinRequests(ctx context.Context, groupID uuid.UUID) ([]models.GroupJoinRequest, error) {
+	query := joinRequestQuery + " WHERE jr.group_id = $1 AND jr.status = $2 ORDER BY jr.created_at ASC"
+
+	var requests []models.GroupJoinRequest
+	if err := r.conn(ctx).SelectContext(ctx, &requests, query, groupID, models.JoinRequestPending); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// UpdateJoinRequestStatus answers a pending join request
+func (r *PostgresRepository) UpdateJoinRequestStatus(ctx context.Context, requestID uuid.UUID, status string, respondedBy uuid.UUID, respondedAt time.Time) error {
+	query := `
+		UPDATE group_join_requests
+		SET status = $1, responded_by = $2, responded_at = $3
+		WHERE id = $4 AND status = $5
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, status, respondedBy, respondedAt, requestID, models.JoinRequestPending)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrJoinRequestNotPending
+	}
+	return nil
+}
+
+// GetMessage retrieves one of a group's messages
+func (r *PostgresRepository) GetMessage(ctx context.Context, groupID, messageID uuid.UUID) (*models.GroupMessage, error) {
+	var message models.GroupMessage
+	if err := r.conn(ctx).GetContext(ctx, &message, messageQuery+" AND gm.id = $2", groupID, messageID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// CreateReceipts creates an undelivered receipt for a message for every
+// member of its group except the sender
+func (r *PostgresRepository) CreateReceipts(ctx context.Context, message *models.GroupMessage) error {
+	query := `
+		INSERT INTO message_delivery_status (message_id, user_id)
+		SELECT $1, user_id FROM group_members
+		WHERE group_id = $2 AND user_id <> $3
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, message.ID, message.GroupID, message.SenderID)
+	return err
+}
+
+// MarkDelivered marks a message as delivered to the users
+func (r *PostgresRepository) MarkDelivered(ctx context.Context, messageID uuid.UUID, userIDs []uuid.UUID, deliveredAt time.Time) error {
+	query := `
+		UPDATE message_delivery_status
+		SET delivered = TRUE, delivered_at = $3
+		WHERE message_id = $1 AND user_id = ANY($2) AND NOT delivered
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, messageID, pq.Array(userIDs), deliveredAt)
+	return err
+}
+
+// MarkRead marks all of a member's unread messages in a group as read,
+// and as delivered if they were not yet, returning the messages marked
+func (r *PostgresRepository) MarkRead(ctx context.Context, groupID, userID uuid.UUID, readAt time.Time) ([]ReadMessage, error) {
+	query := `
+		UPDATE message_delivery_status mds
+		SET read = TRUE,
+		    read_at = $3,
+		    delivered = TRUE,
+		    delivered_at = COALESCE(mds.delivered_at, $3)
+		FROM group_messages gm
+		WHERE gm.id = mds.message_id
+		  AND gm.group_id = $1
+		  AND mds.user_id = $2
+		  AND NOT mds.read
+		RETURNING gm.id, gm.sender_id
+	`
+
+	var messages []ReadMessage
+	if err := r.conn(ctx).SelectContext(ctx, &messages, query, groupID, userID, readAt); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetDeliveryStatuses retrieves the aggregated delivery status of messages
+func (r *PostgresRepository) GetDeliveryStatuses(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	query := "SELECT gm.id," + deliveryStatusExpr + " FROM group_messages gm WHERE gm.id = ANY($1)"
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, pq.Array(messageIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := make(map[uuid.UUID]string, len(messageIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var status string
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, err
+		}
+		statuses[id] = status
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// GetReceipts retrieves a message's receipts from the group's current
+// members, ordered by username
+func (r *PostgresRepository) GetReceipts(ctx context.Context, messageID uuid.UUID) ([]models.GroupMessageReceipt, error) {
+	query := `
+		SELECT mds.user_id, u.username, mds.delivered_at, mds.read_at
+		FROM message_delivery_status mds
+		JOIN group_messages gm ON gm.id = mds.message_id
+		JOIN group_members m ON m.group_id = gm.group_id AND m.user_id = mds.user_id
+		JOIN users u ON u.id = mds.user_id
+		WHERE mds.message_id = $1
+		ORDER BY u.username ASC
+	`
+
+	var receipts []models.GroupMessageReceipt
+	if err := r.conn(ctx).SelectContext(ctx, &receipts, query, messageID); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
@@ -0,0 +1,813 @@
+package group
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
+	"github.com/google/uuid"
+)
+
+// Service errors
+var (
+	ErrNotAdmin       = errors.New("group admin access required")
+	ErrAlreadyMember  = errors.New("user is already a member of the group")
+	ErrInvalidExpiry  = errors.New("invite expiry must be in the future")
+	ErrMemberNotFound = errors.New("group member not found")
+	ErrNotSender      = errors.New("only the sender can view message info")
+	ErrNotInWorkspace = errors.New("user is not a member of the group's workspace")
+)
+
+// inviteCodeBytes is the number of random bytes in an invite code
+const inviteCodeBytes = 12
+
+// Service handles group business logic
+type Service interface {
+	CreateGroup(ctx context.Context, userID, workspaceID uuid.UUID, name string) (*models.Group, error)
+	GetGroup(ctx context.Context, groupID, userID uuid.UUID) (*models.Group, error)
+	AddMember(ctx context.Context, groupID, userID, memberID uuid.UUID) (*models.Group, error)
+	RemoveMember(ctx context.Context, groupID, userID, memberID uuid.UUID) error
+	LeaveGroup(ctx context.Context, groupID, userID uuid.UUID) error
+	GetMessages(ctx context.Context, groupID, userID uuid.UUID, before *pagination.Cursor, limit int) (*models.GroupMessageListResponse, error)
+	SendMessage(ctx context.Context, groupID, userID uuid.UUID, content string) (*models.GroupMessage, error)
+	GetMessageInfo(ctx context.Context, groupID, messageID, userID uuid.UUID) (*models.GroupMessageInfoResponse, error)
+	CreateInvite(ctx context.Context, groupID, userID uuid.UUID, req *models.CreateGroupInviteRequest) (*models.GroupInvite, error)
+	GetInvites(ctx context.Context, groupID, userID uuid.UUID) (*models.GroupInviteListResponse, error)
+	RevokeInvite(ctx context.Context, groupID, inviteID, userID uuid.UUID) (*models.GroupInvite, error)
+	JoinByInvite(ctx context.Context, code string, userID uuid.UUID) (*models.GroupJoinRequest, error)
+	GetJoinRequests(ctx context.Context, groupID, userID uuid.UUID) (*models.GroupJoinRequestListResponse, error)
+	ApproveJoinRequest(ctx context.Context, groupID, requestID, userID uuid.UUID) (*models.GroupJoinRequest, error)
+	DeclineJoinRequest(ctx context.Context, groupID, requestID, userID uuid.UUID) (*models.GroupJoinRequest, error)
+}
+
+// Notifier pushes real-time events to a user's connected clients
+type Notifier interface {
+	SendToUser(userID uuid.UUID, message *models.WebSocketMessage) bool
+}
+
+// WorkspaceMembership reports whether a user belongs to a workspace
+type WorkspaceMembership interface {
+	IsMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error)
+}
+
+// GroupService implements Service interface
+type GroupService struct {
+	repo       Repository
+	uow        database.UnitOfWork
+	notifier   Notifier
+	workspaces WorkspaceMembership
+	logger     logger.Logger
+}
+
+// NewGroupService creates a new group service
+func NewGroupService(repo Repository, uow database.UnitOfWork, notifier Notifier, workspaces WorkspaceMembership, logger logger.Logger) *GroupService {
+	return &GroupService{
+		repo:       repo,
+		uow:        uow,
+		notifier:   notifier,
+		workspaces: workspaces,
+		logger:     logger,
+	}
+}
+
+// CreateGroup creates a group with the user as its only member and admin.
+// A group created in a workspace only admits the workspace's members;
+// workspaceID is uuid.Nil outside workspaces.
+func (s *GroupService) CreateGroup(ctx context.Context, userID, workspaceID uuid.UUID, name string) (*models.Group, error) {
+	group := &models.Group{
+		ID:        uuid.New(),
+		Name:      name,
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+	}
+	if workspaceID != uuid.Nil {
+		group.WorkspaceID = &workspaceID
+	}
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateGroup(ctx, group); err != nil {
+			return err
+		}
+		_, err := s.repo.AddMember(ctx, group.ID, userID, models.GroupRoleAdmin)
+		return err
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to create group", "error", err)
+		return nil, err
+	}
+
+	return s.withMembers(ctx, group)
+}
+
+// GetGroup returns a group and its members to one of its members
+func (s *GroupService) GetGroup(ctx context.Context, groupID, userID uuid.UUID) (*models.Group, error) {
+	group, err := s.repo.GetGroup(ctx, groupID)
+	if err != nil {
+		if !errors.Is(err, ErrGroupNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to get group", "error", err, "group_id", groupID)
+		}
+		return nil, err
+	}
+
+	if _, err := s.memberRole(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.withMembers(ctx, group)
+}
+
+// AddMember adds a user to the group. Only admins can add members.
+func (s *GroupService) AddMember(ctx context.Context, groupID, userID, memberID uuid.UUID) (*models.Group, error) {
+	if err := s.requireAdmin(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	username, err := s.repo.GetUsername(ctx, memberID)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to get username", "error", err)
+		}
+		return nil, err
+	}
+
+	var added *models.GroupMessage
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		added, err = s.addMember(ctx, groupID, memberID, username, userID)
+		if err == nil && added == nil {
+			return ErrAlreadyMember
+		}
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, ErrAlreadyMember) && !errors.Is(err, ErrNotInWorkspace) {
+			s.logger.WithContext(ctx).Error("Failed to add group member", "error", err, "group_id", groupID)
+		}
+		return nil, err
+	}
+
+	s.announce(ctx, added)
+
+	group, err := s.repo.GetGroup(ctx, groupID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group", "error", err, "group_id", groupID)
+		return nil, err
+	}
+	return s.withMembers(ctx, group)
+}
+
+// RemoveMember removes a member from the group. Only admins can remove
+// other members; removing yourself leaves the group.
+func (s *GroupService) RemoveMember(ctx context.Context, groupID, userID, memberID uuid.UUID) error {
+	if memberID == userID {
+		return s.LeaveGroup(ctx, groupID, userID)
+	}
+
+	if err := s.requireAdmin(ctx, groupID, userID); err != nil {
+		return err
+	}
+
+	removed, err := s.removeMember(ctx, groupID, memberID, userID, models.GroupMessageMemberRemoved)
+	if err != nil {
+		if errors.Is(err, ErrNotMember) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+
+	// The removed member no longer gets group events, so tell them directly
+	s.announce(ctx, removed, memberID)
+	return nil
+}
+
+// LeaveGroup removes the user from the group
+func (s *GroupService) LeaveGroup(ctx context.Context, groupID, userID uuid.UUID) error {
+	left, err := s.removeMember(ctx, groupID, userID, userID, models.GroupMessageMemberLeft)
+	if err != nil {
+		return err
+	}
+
+	// Let the user's other devices know too
+	s.announce(ctx, left, userID)
+	return nil
+}
+
+// GetMessages returns a page of the group's timeline to one of its members
+func (s *GroupService) GetMessages(ctx context.Context, groupID, userID uuid.UUID, before *pagination.Cursor, limit int) (*models.GroupMessageListResponse, error) {
+	if _, err := s.memberRole(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	messages, hasMore, nextCursor, err := s.repo.GetMessages(ctx, groupID, before, limit)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group messages", "error", err, "group_id", groupID)
+		return nil, err
+	}
+	if messages == nil {
+		messages = []models.GroupMessage{}
+	}
+
+	// Fetching the timeline reads every message in it
+	read, err := s.repo.MarkRead(ctx, groupID, userID, time.Now())
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to mark group messages as read", "error", err, "group_id", groupID)
+		// Continue anyway, this shouldn't fail the main request
+	} else {
+		s.notifyStatusChanges(ctx, groupID, read)
+	}
+
+	return &models.GroupMessageListResponse{
+		GroupID:    groupID.String(),
+		Messages:   messages,
+		HasMore:    hasMore,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// SendMessage sends a text message to the group, delivering it to the
+// members who are online
+func (s *GroupService) SendMessage(ctx context.Context, groupID, userID uuid.UUID, content string) (*models.GroupMessage, error) {
+	if _, err := s.memberRole(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	username, err := s.repo.GetUsername(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get username", "error", err)
+		return nil, err
+	}
+
+	message := &models.GroupMessage{
+		ID:             uuid.New(),
+		GroupID:        groupID,
+		Type:           models.GroupMessageText,
+		SenderID:       userID,
+		SenderUsername: username,
+		Content:        content,
+		Timestamp:      time.Now(),
+		DeliveryStatus: models.GroupDeliverySent,
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateMessage(ctx, message); err != nil {
+			return err
+		}
+		return s.repo.CreateReceipts(ctx, message)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save group message", "error", err, "group_id", groupID)
+		return nil, err
+	}
+
+	members, err := s.repo.GetMembers(ctx, groupID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group members", "error", err, "group_id", groupID)
+		return message, nil
+	}
+
+	// Forward the message to members who are online
+	event := &models.WebSocketMessage{
+		Type: protocol.TypeGroupMessage,
+		Data: *message,
+	}
+	var delivered []uuid.UUID
+	for _, member := range members {
+		if member.UserID != userID && s.notifier.SendToUser(member.UserID, event) {
+			delivered = append(delivered, member.UserID)
+		}
+	}
+	if len(delivered) == 0 {
+		return message, nil
+	}
+
+	if err := s.repo.MarkDelivered(ctx, message.ID, delivered, time.Now()); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to mark group message as delivered", "error", err, "message_id", message.ID)
+		return message, nil
+	}
+
+	statuses, err := s.repo.GetDeliveryStatuses(ctx, []uuid.UUID{message.ID})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group message status", "error", err, "message_id", message.ID)
+		return message, nil
+	}
+	if status, ok := statuses[message.ID]; ok {
+		message.DeliveryStatus = status
+	}
+
+	return message, nil
+}
+
+// GetMessageInfo returns who a text message was delivered to and read by.
+// Only the sender can view it.
+func (s *GroupService) GetMessageInfo(ctx context.Context, groupID, messageID, userID uuid.UUID) (*models.GroupMessageInfoResponse, error) {
+	if _, err := s.memberRole(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	message, err := s.repo.GetMessage(ctx, groupID, messageID)
+	if err != nil {
+		if !errors.Is(err, ErrMessageNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to get group message", "error", err, "message_id", messageID)
+		}
+		return nil, err
+	}
+
+	// System messages have no receipts
+	if message.Type != models.GroupMessageText {
+		return nil, ErrMessageNotFound
+	}
+	if message.SenderID != userID {
+		return nil, ErrNotSender
+	}
+
+	receipts, err := s.repo.GetReceipts(ctx, messageID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group message receipts", "error", err, "message_id", messageID)
+		return nil, err
+	}
+	if receipts == nil {
+		receipts = []models.GroupMessageReceipt{}
+	}
+
+	info := &models.GroupMessageInfoResponse{
+		MessageID:      messageID.String(),
+		GroupID:        groupID.String(),
+		DeliveryStatus: message.DeliveryStatus,
+		Receipts:       receipts,
+	}
+	for _, receipt := range receipts {
+		if receipt.DeliveredAt != nil {
+			info.DeliveredCount++
+		}
+		if receipt.ReadAt != nil {
+			info.ReadCount++
+		}
+	}
+
+	return info, nil
+}
+
+// CreateInvite creates an invite link for the group. Only admins can
+// create invites.
+func (s *GroupService) CreateInvite(ctx context.Context, groupID, userID uuid.UUID, req *models.CreateGroupInviteRequest) (*models.GroupInvite, error) {
+	if err := s.requireAdmin(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, ErrInvalidExpiry
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to generate invite code", "error", err)
+		return nil, err
+	}
+
+	invite := &models.GroupInvite{
+		ID:        uuid.New(),
+		GroupID:   groupID,
+		Code:      code,
+		CreatedBy: userID,
+		ExpiresAt: req.ExpiresAt,
+		MaxUses:   req.MaxUses,
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateInvite(ctx, invite); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to create group invite", "error", err, "group_id", groupID)
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// GetInvites returns the group's invites to an admin
+func (s *GroupService) GetInvites(ctx context.Context, groupID, userID uuid.UUID) (*models.GroupInviteListResponse, error) {
+	if err := s.requireAdmin(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	invites, err := s.repo.GetInvites(ctx, groupID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group invites", "error", err, "group_id", groupID)
+		return nil, err
+	}
+	if invites == nil {
+		invites = []models.GroupInvite{}
+	}
+
+	return &models.GroupInviteListResponse{Invites: invites}, nil
+}
+
+// RevokeInvite revokes one of the group's invites. Pending join requests
+// made with it stay in the queue.
+func (s *GroupService) RevokeInvite(ctx context.Context, groupID, inviteID, userID uuid.UUID) (*models.GroupInvite, error) {
+	if err := s.requireAdmin(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	invite, err := s.repo.RevokeInvite(ctx, groupID, inviteID, time.Now())
+	if err != nil {
+		if !errors.Is(err, ErrInviteNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to revoke group invite", "error", err, "invite_id", inviteID)
+		}
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// JoinByInvite uses an invite to queue a request to join its group for
+// the admins to approve
+func (s *GroupService) JoinByInvite(ctx context.Context, code string, userID uuid.UUID) (*models.GroupJoinRequest, error) {
+	invite, err := s.repo.GetInviteByCode(ctx, code)
+	if err != nil {
+		if !errors.Is(err, ErrInviteNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to get group invite", "error", err)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !invite.Usable(now) {
+		return nil, ErrInviteUnusable
+	}
+
+	_, err = s.repo.GetMemberRole(ctx, invite.GroupID, userID)
+	if err == nil {
+		return nil, ErrAlreadyMember
+	}
+	if !errors.Is(err, ErrNotMember) {
+		s.logger.WithContext(ctx).Error("Failed to check group membership", "error", err)
+		return nil, err
+	}
+	if err := s.checkWorkspace(ctx, invite.GroupID, userID); err != nil {
+		return nil, err
+	}
+
+	request := &models.GroupJoinRequest{
+		ID:        uuid.New(),
+		GroupID:   invite.GroupID,
+		UserID:    userID,
+		InviteID:  &invite.ID,
+		Status:    models.JoinRequestPending,
+		CreatedAt: now,
+	}
+
+	// Only count the use if the request is queued
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.UseInvite(ctx, invite.ID, now); err != nil {
+			return err
+		}
+		return s.repo.CreateJoinRequest(ctx, request)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrInviteUnusable) && !errors.Is(err, ErrJoinRequestExists) {
+			s.logger.WithContext(ctx).Error("Failed to create join request", "error", err, "group_id", invite.GroupID)
+		}
+		return nil, err
+	}
+
+	// Reload to fill in the group name and username
+	request, err = s.repo.GetJoinRequest(ctx, request.ID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get join request", "error", err)
+		return nil, err
+	}
+
+	s.notifyAdmins(ctx, request.GroupID, &models.WebSocketMessage{
+		Type: protocol.TypeGroupJoinRequest,
+		Data: *request,
+	})
+	return request, nil
+}
+
+// GetJoinRequests returns the group's pending join requests to an admin
+func (s *GroupService) GetJoinRequests(ctx context.Context, groupID, userID uuid.UUID) (*models.GroupJoinRequestListResponse, error) {
+	if err := s.requireAdmin(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.repo.GetPendingJoinRequests(ctx, groupID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get join requests", "error", err, "group_id", groupID)
+		return nil, err
+	}
+	if requests == nil {
+		requests = []models.GroupJoinRequest{}
+	}
+
+	return &models.GroupJoinRequestListResponse{Requests: requests}, nil
+}
+
+// ApproveJoinRequest approves a pending join request, adding the user to
+// the group and announcing the new member to everyone in it
+func (s *GroupService) ApproveJoinRequest(ctx context.Context, groupID, requestID, userID uuid.UUID) (*models.GroupJoinRequest, error) {
+	var added *models.GroupMessage
+	request, err := s.answer(ctx, groupID, requestID, userID, models.JoinRequestApproved, func(ctx context.Context, request *models.GroupJoinRequest) error {
+		var err error
+		added, err = s.addMember(ctx, request.GroupID, request.UserID, request.Username, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing to announce if the user was added some other way meanwhile
+	if added != nil {
+		s.announce(ctx, added)
+	}
+	return request, nil
+}
+
+// DeclineJoinRequest declines a pending join request
+func (s *GroupService) DeclineJoinRequest(ctx context.Context, groupID, requestID, userID uuid.UUID) (*models.GroupJoinRequest, error) {
+	request, err := s.answer(ctx, groupID, requestID, userID, models.JoinRequestDeclined, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifier.SendToUser(request.UserID, &models.WebSocketMessage{
+		Type: protocol.TypeGroupJoinRequestUpdated,
+		Data: *request,
+	})
+	return request, nil
+}
+
+// answer sets the status of a pending join request to the group, running
+// then in the same transaction. Only admins can answer requests.
+func (s *GroupService) answer(ctx context.Context, groupID, requestID, userID uuid.UUID, status string, then func(ctx context.Context, request *models.GroupJoinRequest) error) (*models.GroupJoinRequest, error) {
+	if err := s.requireAdmin(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	request, err := s.repo.GetJoinRequest(ctx, requestID)
+	if err != nil {
+		if !errors.Is(err, ErrJoinRequestNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to get join request", "error", err)
+		}
+		return nil, err
+	}
+
+	// Hide requests to other groups
+	if request.GroupID != groupID {
+		return nil, ErrJoinRequestNotFound
+	}
+
+	respondedAt := time.Now()
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateJoinRequestStatus(ctx, requestID, status, userID, respondedAt); err != nil {
+			return err
+		}
+		if then != nil {
+			return then(ctx, request)
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrJoinRequestNotPending) && !errors.Is(err, ErrNotInWorkspace) {
+			s.logger.WithContext(ctx).Error("Failed to answer join request", "error", err, "request_id", requestID)
+		}
+		return nil, err
+	}
+
+	request.Status = status
+	request.RespondedAt = &respondedAt
+	request.RespondedBy = &userID
+	return request, nil
+}
+
+// addMember adds a user to the group as a member and records it in the
+// timeline. It returns nil if the user already was a member. Callers run
+// it in a transaction.
+func (s *GroupService) addMember(ctx context.Context, groupID, memberID uuid.UUID, username string, actorID uuid.UUID) (*models.GroupMessage, error) {
+	if err := s.checkWorkspace(ctx, groupID, memberID); err != nil {
+		return nil, err
+	}
+
+	added, err := s.repo.AddMember(ctx, groupID, memberID, models.GroupRoleMember)
+	if err != nil || !added {
+		return nil, err
+	}
+	return s.recordMembership(ctx, models.GroupMessageMemberAdded, groupID, actorID, memberID, username)
+}
+
+// checkWorkspace returns ErrNotInWorkspace if the group belongs to a
+// workspace the user is not a member of
+func (s *GroupService) checkWorkspace(ctx context.Context, groupID, userID uuid.UUID) error {
+	group, err := s.repo.GetGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group.WorkspaceID == nil {
+		return nil
+	}
+
+	isMember, err := s.workspaces.IsMember(ctx, *group.WorkspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotInWorkspace
+	}
+	return nil
+}
+
+// removeMember removes a member from the group and records it in the
+// timeline as messageType. If no admins remain, the longest-standing
+// member becomes one.
+func (s *GroupService) removeMember(ctx context.Context, groupID, memberID, actorID uuid.UUID, messageType string) (*models.GroupMessage, error) {
+	username, err := s.repo.GetUsername(ctx, memberID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrNotMember
+		}
+		s.logger.WithContext(ctx).Error("Failed to get username", "error", err)
+		return nil, err
+	}
+
+	var message *models.GroupMessage
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		removed, err := s.repo.RemoveMember(ctx, groupID, memberID)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return ErrNotMember
+		}
+		if err := s.repo.EnsureAdmin(ctx, groupID); err != nil {
+			return err
+		}
+
+		message, err = s.recordMembership(ctx, messageType, groupID, actorID, memberID, username)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotMember) {
+			s.logger.WithContext(ctx).Error("Failed to remove group member", "error", err, "group_id", groupID)
+		}
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// recordMembership saves a system message recording a membership change
+// made by the actor
+func (s *GroupService) recordMembership(ctx context.Context, messageType string, groupID, actorID, memberID uuid.UUID, username string) (*models.GroupMessage, error) {
+	message := &models.GroupMessage{
+		ID:              uuid.New(),
+		GroupID:         groupID,
+		Type:            messageType,
+		SenderID:        actorID,
+		SubjectID:       &memberID,
+		SubjectUsername: &username,
+		Timestamp:       time.Now(),
+	}
+	if err := s.repo.CreateMessage(ctx, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// announce sends the membership event for a system message to the group's
+// members and to any former members passed as also
+func (s *GroupService) announce(ctx context.Context, message *models.GroupMessage, also ...uuid.UUID) {
+	data := models.GroupMembershipData{
+		MessageID: message.ID.String(),
+		GroupID:   message.GroupID.String(),
+		UserID:    message.SubjectID.String(),
+		Username:  *message.SubjectUsername,
+		Timestamp: message.Timestamp,
+	}
+	if message.Type != models.GroupMessageMemberLeft {
+		data.ActorID = message.SenderID.String()
+	}
+
+	// Membership events are sent with the type of the timeline message
+	event := &models.WebSocketMessage{
+		Type: protocol.MessageType(message.Type),
+		Data: data,
+	}
+	s.notifyMembers(ctx, message.GroupID, event)
+	for _, userID := range also {
+		s.notifier.SendToUser(userID, event)
+	}
+}
+
+// notifyStatusChanges tells the senders of messages a member just read
+// their messages' aggregated delivery status
+func (s *GroupService) notifyStatusChanges(ctx context.Context, groupID uuid.UUID, read []ReadMessage) {
+	if len(read) == 0 {
+		return
+	}
+
+	messageIDs := make([]uuid.UUID, len(read))
+	for i, message := range read {
+		messageIDs[i] = message.MessageID
+	}
+
+	statuses, err := s.repo.GetDeliveryStatuses(ctx, messageIDs)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group message statuses", "error", err, "group_id", groupID)
+		return
+	}
+
+	// One event per sender
+	bySender := make(map[uuid.UUID][]models.GroupMessageStatus)
+	for _, message := range read {
+		bySender[message.SenderID] = append(bySender[message.SenderID], models.GroupMessageStatus{
+			MessageID:      message.MessageID.String(),
+			DeliveryStatus: statuses[message.MessageID],
+		})
+	}
+	for senderID, messages := range bySender {
+		s.notifier.SendToUser(senderID, &models.WebSocketMessage{
+			Type: protocol.TypeGroupMessageStatus,
+			Data: models.GroupMessageStatusData{
+				GroupID:  groupID.String(),
+				Messages: messages,
+			},
+		})
+	}
+}
+
+// withMembers loads the group's members into group
+func (s *GroupService) withMembers(ctx context.Context, group *models.Group) (*models.Group, error) {
+	members, err := s.repo.GetMembers(ctx, group.ID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group members", "error", err, "group_id", group.ID)
+		return nil, err
+	}
+	if members == nil {
+		members = []models.GroupMember{}
+	}
+
+	group.Members = members
+	return group, nil
+}
+
+// memberRole returns the user's role in the group. Unexpected errors are
+// logged.
+func (s *GroupService) memberRole(ctx context.Context, groupID, userID uuid.UUID) (string, error) {
+	role, err := s.repo.GetMemberRole(ctx, groupID, userID)
+	if err != nil && !errors.Is(err, ErrNotMember) {
+		s.logger.WithContext(ctx).Error("Failed to check group membership", "error", err, "group_id", groupID)
+	}
+	return role, err
+}
+
+// requireAdmin returns an error unless the user is an admin of the group
+func (s *GroupService) requireAdmin(ctx context.Context, groupID, userID uuid.UUID) error {
+	role, err := s.memberRole(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if role != models.GroupRoleAdmin {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+// notifyMembers sends an event to every member of the group
+func (s *GroupService) notifyMembers(ctx context.Context, groupID uuid.UUID, message *models.WebSocketMessage) {
+	s.notify(ctx, groupID, message, "")
+}
+
+// notifyAdmins sends an event to the group's admins
+func (s *GroupService) notifyAdmins(ctx context.Context, groupID uuid.UUID, message *models.WebSocketMessage) {
+	s.notify(ctx, groupID, message, models.GroupRoleAdmin)
+}
+
+// notify sends an event to the group's members with the role, or to all
+// members if role is empty
+func (s *GroupService) notify(ctx context.Context, groupID uuid.UUID, message *models.WebSocketMessage, role string) {
+	members, err := s.repo.GetMembers(ctx, groupID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get group members", "error", err, "group_id", groupID)
+		return
+	}
+
+	for _, member := range members {
+		if role == "" || member.Role == role {
+			s.notifier.SendToUser(member.UserID, message)
+		}
+	}
+}
+
+// newInviteCode returns a random URL-safe invite code
+func newInviteCode() (string, error) {
+	b := make([]byte, inviteCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
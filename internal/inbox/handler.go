@@ -0,0 +1,156 @@
+package inbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+)
+
+// Handler handles inbox HTTP requests
+type Handler struct {
+	service   *Service
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new inbox handler. With a nil service, as when the
+// inbox is turned off, requests are refused.
+func NewHandler(service *Service, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		service:   service,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// GetEvents handles requests for the user's events after the seq in the
+// after query parameter. Devices pass their device_id so their place in
+// the inbox is kept from the first sync on.
+func (h *Handler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	if h.service == nil {
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "inbox.disabled"))
+		return
+	}
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var after int64
+	if value := r.URL.Query().Get("after"); value != "" {
+		var err error
+		after, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || after < 0 {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "inbox.invalid_seq"))
+			return
+		}
+	}
+	deviceID := r.URL.Query().Get("device_id")
+	if len(deviceID) > maxDeviceIDLength {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "inbox.invalid_device"))
+		return
+	}
+	limit := pagination.ParseLimit(r.URL.Query().Get("limit"), DefaultLimit, MaxLimit)
+
+	// Call service
+	resp, err := h.service.List(r.Context(), userID, deviceID, after, limit)
+	if err != nil {
+		sendError(w, r, errcode.Internal, i18n.T(r, "inbox.get_failed"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// Ack handles requests acknowledging a device received the user's events
+// up to a seq
+func (h *Handler) Ack(w http.ResponseWriter, r *http.Request) {
+	if h.service == nil {
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "inbox.disabled"))
+		return
+	}
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.AckInboxRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode inbox ack request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	if err := h.service.Ack(r.Context(), userID, req); err != nil {
+		sendError(w, r, errcode.Internal, i18n.T(r, "inbox.ack_failed"))
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing or malformed
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
@@ -0,0 +1,130 @@
+package inbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+)
+
+// Repository interface for inbox operations
+type Repository interface {
+	AppendEvent(ctx context.Context, userID uuid.UUID, eventType protocol.MessageType, data []byte) (int64, error)
+	ListEvents(ctx context.Context, userID uuid.UUID, after int64, limit int) ([]models.InboxEvent, error)
+	GetHead(ctx context.Context, userID uuid.UUID) (int64, error)
+	SaveOffset(ctx context.Context, userID uuid.UUID, deviceID string, seq int64) error
+	TrimAcknowledged(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteEventsBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// AppendEvent adds an event to a user's inbox and returns its seq. The
+// user's head row serializes concurrent appends, so seqs are gapless and
+// ordered as the events were committed.
+func (r *PostgresRepository) AppendEvent(ctx context.Context, userID uuid.UUID, eventType protocol.MessageType, data []byte) (int64, error) {
+	query := `
+		WITH head AS (
+			INSERT INTO user_event_heads (user_id, last_seq)
+			VALUES ($1, 1)
+			ON CONFLICT (user_id) DO UPDATE
+			SET last_seq = user_event_heads.last_seq + 1
+			RETURNING last_seq
+		)
+		INSERT INTO user_events (user_id, seq, type, data, created_at)
+		SELECT $1, last_seq, $2, $3, NOW() FROM head
+		RETURNING seq
+	`
+
+	var seq int64
+	err := r.conn(ctx).GetContext(ctx, &seq, query, userID, eventType, data)
+	return seq, err
+}
+
+// ListEvents returns up to limit of a user's events after a seq, oldest
+// first
+func (r *PostgresRepository) ListEvents(ctx context.Context, userID uuid.UUID, after int64, limit int) ([]models.InboxEvent, error) {
+	query := `
+		SELECT seq, type, data, created_at
+		FROM user_events
+		WHERE user_id = $1 AND seq > $2
+		ORDER BY seq
+		LIMIT $3
+	`
+
+	events := []models.InboxEvent{}
+	err := r.conn(ctx).SelectContext(ctx, &events, query, userID, after, limit)
+	return events, err
+}
+
+// GetHead returns the seq of a user's latest event, or 0 if they have none
+func (r *PostgresRepository) GetHead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := "SELECT COALESCE(MAX(last_seq), 0) FROM user_event_heads WHERE user_id = $1"
+
+	var head int64
+	err := r.conn(ctx).GetContext(ctx, &head, query, userID)
+	return head, err
+}
+
+// SaveOffset records that a device received a user's events up to a seq.
+// Offsets never move backwards or past the user's latest event.
+func (r *PostgresRepository) SaveOffset(ctx context.Context, userID uuid.UUID, deviceID string, seq int64) error {
+	query := `
+		INSERT INTO user_event_offsets (user_id, device_id, acked_seq, updated_at)
+		VALUES ($1, $2, LEAST($3, (SELECT COALESCE(MAX(last_seq), 0) FROM user_event_heads WHERE user_id = $1)), NOW())
+		ON CONFLICT (user_id, device_id) DO UPDATE
+		SET acked_seq = GREATEST(user_event_offsets.acked_seq, EXCLUDED.acked_seq),
+		    updated_at = NOW()
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, deviceID, seq)
+	return err
+}
+
+// TrimAcknowledged deletes a user's events that every device acknowledged.
+// Devices are registered with an offset when they first sync, so a device
+// that has not acknowledged anything yet holds back trimming.
+func (r *PostgresRepository) TrimAcknowledged(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := `
+		DELETE FROM user_events
+		WHERE user_id = $1
+		  AND seq <= (SELECT MIN(acked_seq) FROM user_event_offsets WHERE user_id = $1)
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteEventsBefore deletes events created before a cutoff whether or not
+// they were acknowledged, and forgets devices that have not acknowledged
+// anything since, so they no longer hold back trimming
+func (r *PostgresRepository) DeleteEventsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	if _, err := r.conn(ctx).ExecContext(ctx, "DELETE FROM user_event_offsets WHERE updated_at < $1", cutoff); err != nil {
+		return 0, err
+	}
+
+	result, err := r.conn(ctx).ExecContext(ctx, "DELETE FROM user_events WHERE created_at < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
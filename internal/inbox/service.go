@@ -0,0 +1,144 @@
+// Package inbox keeps the durable events sent to each user, such as
+// messages and read receipts, until all of the user's devices acknowledge
+// them, so reconnecting clients and new devices can catch up in order.
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Default and maximum number of events returned per page
+const (
+	DefaultLimit = 100
+	MaxLimit     = 500
+)
+
+// maxDeviceIDLength is the longest device ID kept with an offset
+const maxDeviceIDLength = 64
+
+// DeliveryTracker records that direct messages reached one of their
+// recipient's devices
+type DeliveryTracker interface {
+	MarkDelivered(ctx context.Context, recipientID uuid.UUID, messageIDs []uuid.UUID) map[uuid.UUID]bool
+}
+
+// Service handles inbox business logic
+type Service struct {
+	repo       Repository
+	deliveries DeliveryTracker // nil until TrackDeliveries is called
+	logger     logger.Logger
+}
+
+// NewService creates a new inbox service
+func NewService(repo Repository, logger logger.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// TrackDeliveries has direct messages marked delivered once a device syncs
+// them from the inbox. The tracker is created after the inbox, so it is set
+// separately.
+func (s *Service) TrackDeliveries(deliveries DeliveryTracker) {
+	s.deliveries = deliveries
+}
+
+// Append adds a WebSocket message sent to a user to their inbox and returns
+// its seq
+func (s *Service) Append(ctx context.Context, userID uuid.UUID, message *models.WebSocketMessage) (int64, error) {
+	data, err := json.Marshal(message.Data)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.AppendEvent(ctx, userID, message.Type, data)
+}
+
+// List returns a page of the user's events after a seq. A device that
+// names itself is registered at that seq, so events it has yet to
+// acknowledge are not trimmed once another device acknowledges them.
+func (s *Service) List(ctx context.Context, userID uuid.UUID, deviceID string, after int64, limit int) (*models.InboxResponse, error) {
+	if deviceID != "" {
+		if err := s.repo.SaveOffset(ctx, userID, deviceID, after); err != nil {
+			s.logger.WithContext(ctx).Error("Failed to register inbox device", "error", err)
+			return nil, err
+		}
+	}
+
+	head, err := s.repo.GetHead(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get inbox head", "error", err)
+		return nil, err
+	}
+
+	events, err := s.repo.ListEvents(ctx, userID, after, limit+1)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to list inbox events", "error", err)
+		return nil, err
+	}
+
+	resp := &models.InboxResponse{Head: head}
+	if len(events) > limit {
+		events = events[:limit]
+		resp.HasMore = true
+	}
+	resp.Events = events
+
+	// Events are numbered without gaps, so a missing successor was trimmed
+	if after < head && (len(events) == 0 || events[0].Seq > after+1) {
+		resp.Gap = true
+	}
+
+	s.markDelivered(ctx, userID, events)
+	return resp, nil
+}
+
+// markDelivered marks the direct messages the user received among synced
+// events as delivered
+func (s *Service) markDelivered(ctx context.Context, userID uuid.UUID, events []models.InboxEvent) {
+	if s.deliveries == nil {
+		return
+	}
+
+	var messageIDs []uuid.UUID
+	for _, event := range events {
+		if event.Type != protocol.TypeDirectMessage {
+			continue
+		}
+		var data models.DirectMessageData
+		if err := json.Unmarshal(event.Data, &data); err != nil || data.SenderID == userID.String() {
+			continue
+		}
+		if messageID, err := uuid.Parse(data.MessageID); err == nil {
+			messageIDs = append(messageIDs, messageID)
+		}
+	}
+	s.deliveries.MarkDelivered(ctx, userID, messageIDs)
+}
+
+// Ack records that a device received the user's events up to a seq and
+// deletes the events every device has received
+func (s *Service) Ack(ctx context.Context, userID uuid.UUID, req models.AckInboxRequest) error {
+	if err := s.repo.SaveOffset(ctx, userID, req.DeviceID, req.Seq); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save inbox offset", "error", err)
+		return err
+	}
+
+	if _, err := s.repo.TrimAcknowledged(ctx, userID); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to trim inbox", "error", err)
+	}
+	return nil
+}
+
+// DeleteExpired deletes events older than the retention period, and the
+// offsets of devices that have not acknowledged events in that time
+func (s *Service) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return s.repo.DeleteEventsBefore(ctx, before)
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Job metrics exported on the metrics endpoint
+var (
+	jobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_jobs_runs_total",
+		Help: "Number of background job runs by job name and outcome.",
+	}, []string{"job", "status"})
+
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chat_jobs_duration_seconds",
+		Help:    "Duration of background job runs.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	}, []string{"job"})
+
+	jobsRunning = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "chat_jobs_running",
+		Help: "Number of background jobs currently running.",
+	}, []string{"job"})
+
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_jobs_queue_depth",
+		Help: "Number of jobs waiting for a worker.",
+	})
+)
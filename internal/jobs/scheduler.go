@@ -0,0 +1,279 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+)
+
+// Scheduler errors
+var (
+	ErrQueueFull        = errors.New("job queue is full")
+	ErrSchedulerStopped = errors.New("scheduler is stopped")
+)
+
+// Func is the body of a job
+type Func func(ctx context.Context) error
+
+// Job is a unit of work executed by the worker pool
+type Job struct {
+	Name    string
+	Run     Func
+	Timeout time.Duration
+}
+
+// recurringJob is a job enqueued at a fixed interval
+type recurringJob struct {
+	job      Job
+	interval time.Duration
+}
+
+// Scheduler runs recurring and one-off jobs on a bounded worker pool
+type Scheduler struct {
+	workers   int
+	queue     chan Job
+	recurring []recurringJob
+	logger    logger.Logger
+
+	// running tracks recurring jobs queued or still executing so a job is
+	// not enqueued again before its last run finishes
+	mu      sync.Mutex
+	running map[string]bool
+	status  map[string]*models.JobStatus
+	stopped bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler with the given pool size
+func NewScheduler(workers, queueSize int, logger logger.Logger) *Scheduler {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+
+	return &Scheduler{
+		workers: workers,
+		queue:   make(chan Job, queueSize),
+		logger:  logger,
+		running: make(map[string]bool),
+		status:  make(map[string]*models.JobStatus),
+	}
+}
+
+// Every registers a job to run at a fixed interval. It must be called before Start.
+func (s *Scheduler) Every(interval time.Duration, job Job) {
+	if interval <= 0 {
+		s.logger.Info("Recurring job disabled", "job", job.Name)
+		return
+	}
+	s.recurring = append(s.recurring, recurringJob{job: job, interval: interval})
+}
+
+// Enqueue submits a one-off job to the worker pool without blocking
+func (s *Scheduler) Enqueue(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enqueueLocked(job)
+}
+
+// enqueueLocked submits a job to the worker pool. s.mu must be held.
+func (s *Scheduler) enqueueLocked(job Job) error {
+	if s.stopped {
+		return ErrSchedulerStopped
+	}
+
+	select {
+	case s.queue <- job:
+		queueDepth.Set(float64(len(s.queue)))
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Start launches the worker pool and the recurring job tickers
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.worker(ctx)
+	}
+
+	for _, r := range s.recurring {
+		s.wg.Add(1)
+		go s.tick(ctx, r)
+	}
+
+	s.logger.Info("Job scheduler started", "workers", s.workers, "recurring_jobs", len(s.recurring))
+}
+
+// Stop stops accepting jobs and waits for running jobs to finish or ctx to expire
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.logger.Info("Job scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Statuses returns a snapshot of the status of every job that has run,
+// sorted by name
+func (s *Scheduler) Statuses() []models.JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]models.JobStatus, 0, len(s.status))
+	for _, st := range s.status {
+		statuses = append(statuses, *st)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// tick enqueues a recurring job every interval
+func (s *Scheduler) tick(ctx context.Context, r recurringJob) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// The job counts as running from when it is queued, so a run
+			// still waiting for a worker is not queued twice
+			s.mu.Lock()
+			busy := s.running[r.job.Name]
+			var err error
+			if !busy {
+				if err = s.enqueueLocked(r.job); err == nil {
+					s.running[r.job.Name] = true
+				}
+			}
+			s.mu.Unlock()
+
+			if busy {
+				s.logger.Warn("Skipping recurring job, previous run still in progress", "job", r.job.Name)
+			} else if err != nil {
+				s.logger.Warn("Failed to enqueue recurring job", "job", r.job.Name, "error", err)
+			}
+		}
+	}
+}
+
+// worker executes jobs from the queue until ctx is cancelled
+func (s *Scheduler) worker(ctx context.Context) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			queueDepth.Set(float64(len(s.queue)))
+			s.execute(ctx, job)
+		}
+	}
+}
+
+// execute runs a single job and records its outcome
+func (s *Scheduler) execute(ctx context.Context, job Job) {
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	s.markStarted(job.Name, start)
+	jobsRunning.WithLabelValues(job.Name).Inc()
+
+	err := s.runSafely(ctx, job)
+
+	duration := time.Since(start)
+	jobsRunning.WithLabelValues(job.Name).Dec()
+	jobDuration.WithLabelValues(job.Name).Observe(duration.Seconds())
+	s.markFinished(job.Name, duration, err)
+
+	if err != nil {
+		jobRuns.WithLabelValues(job.Name, "failed").Inc()
+		s.logger.Error("Job failed", "job", job.Name, "duration", duration, "error", err)
+		return
+	}
+
+	jobRuns.WithLabelValues(job.Name, "succeeded").Inc()
+	s.logger.Debug("Job completed", "job", job.Name, "duration", duration)
+}
+
+// runSafely runs a job, converting panics into errors so a bad job cannot kill a worker
+func (s *Scheduler) runSafely(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = errors.New("job panicked")
+			s.logger.Error("Job panicked", "job", job.Name, "panic", p)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// markStarted records the start of a job run
+func (s *Scheduler) markStarted(name string, start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.status[name]
+	if !ok {
+		st = &models.JobStatus{Name: name}
+		s.status[name] = st
+	}
+	st.Running = true
+	st.LastStarted = start
+	s.running[name] = true
+}
+
+// markFinished records the outcome of a job run
+func (s *Scheduler) markFinished(name string, duration time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.status[name]
+	st.Running = false
+	st.Runs++
+	st.LastDuration = duration
+	st.LastError = ""
+	if err != nil {
+		st.Failures++
+		st.LastError = err.Error()
+	}
+	delete(s.running, name)
+}
@@ -0,0 +1,242 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+)
+
+// SessionCleaner removes expired sessions
+type SessionCleaner interface {
+	DeleteExpiredSessions(ctx context.Context) (int64, error)
+}
+
+// MessagePurger removes messages older than a cutoff
+type MessagePurger interface {
+	DeleteMessagesBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// MessageExpirer removes disappearing messages that expired
+type MessageExpirer interface {
+	DeleteExpiredMessages(ctx context.Context, now time.Time) (int64, error)
+}
+
+// AttachmentPurger removes attachments created before a cutoff
+type AttachmentPurger interface {
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+// ThumbnailGenerator makes thumbnails of uploaded images
+type ThumbnailGenerator interface {
+	GenerateThumbnails(ctx context.Context) (int, error)
+}
+
+// AccountEraser erases accounts whose deletion grace period has passed
+type AccountEraser interface {
+	EraseDue(ctx context.Context) (int, error)
+}
+
+// BufferFlusher saves messages buffered during a database outage
+type BufferFlusher interface {
+	FlushBuffer(ctx context.Context) (int, error)
+}
+
+// SMSNotifier texts users about messages they left unread
+type SMSNotifier interface {
+	SendUnreadNotifications(ctx context.Context) (int, error)
+}
+
+// DigestSender emails users summaries of their unread messages
+type DigestSender interface {
+	SendDigests(ctx context.Context) (int, error)
+}
+
+// InboxPurger removes inbox events created before a cutoff
+type InboxPurger interface {
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// FlagRefresher reloads feature flags from storage
+type FlagRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// SessionCleanup returns a job that deletes expired sessions
+func SessionCleanup(repo SessionCleaner, logger logger.Logger) Job {
+	return Job{
+		Name:    "session_cleanup",
+		Timeout: time.Minute,
+		Run: func(ctx context.Context) error {
+			deleted, err := repo.DeleteExpiredSessions(ctx)
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				logger.Info("Deleted expired sessions", "count", deleted)
+			}
+			return nil
+		},
+	}
+}
+
+// RetentionEnforcement returns a job that deletes messages older than the retention period
+func RetentionEnforcement(repo MessagePurger, retention time.Duration, logger logger.Logger) Job {
+	return Job{
+		Name:    "retention_enforcement",
+		Timeout: 10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			cutoff := time.Now().Add(-retention)
+			deleted, err := repo.DeleteMessagesBefore(ctx, cutoff)
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				logger.Info("Deleted messages past retention period", "count", deleted, "cutoff", cutoff)
+			}
+			return nil
+		},
+	}
+}
+
+// DisappearingMessages returns a job that deletes expired disappearing messages
+func DisappearingMessages(repo MessageExpirer, logger logger.Logger) Job {
+	return Job{
+		Name:    "disappearing_messages",
+		Timeout: time.Minute,
+		Run: func(ctx context.Context) error {
+			deleted, err := repo.DeleteExpiredMessages(ctx, time.Now())
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				logger.Info("Deleted expired disappearing messages", "count", deleted)
+			}
+			return nil
+		},
+	}
+}
+
+// AttachmentExpiry returns a job that deletes attachments older than the storage expiry
+func AttachmentExpiry(service AttachmentPurger, expiry time.Duration, logger logger.Logger) Job {
+	return Job{
+		Name:    "attachment_expiry",
+		Timeout: 10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			cutoff := time.Now().Add(-expiry)
+			deleted, err := service.DeleteExpired(ctx, cutoff)
+			if deleted > 0 {
+				logger.Info("Deleted expired attachments", "count", deleted, "cutoff", cutoff)
+			}
+			return err
+		},
+	}
+}
+
+// ThumbnailGeneration returns a job that makes thumbnails of the images
+// uploaded since its last run
+func ThumbnailGeneration(service ThumbnailGenerator, logger logger.Logger) Job {
+	return Job{
+		Name:    "thumbnail_generation",
+		Timeout: 10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			generated, err := service.GenerateThumbnails(ctx)
+			if generated > 0 {
+				logger.Info("Generated attachment thumbnails", "count", generated)
+			}
+			return err
+		},
+	}
+}
+
+// AccountErasure returns a job that erases accounts scheduled for deletion
+func AccountErasure(service AccountEraser, logger logger.Logger) Job {
+	return Job{
+		Name:    "account_erasure",
+		Timeout: 10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			erased, err := service.EraseDue(ctx)
+			if erased > 0 {
+				logger.Info("Erased accounts scheduled for deletion", "count", erased)
+			}
+			return err
+		},
+	}
+}
+
+// MessageBufferFlush returns a job that saves the messages buffered while
+// the database was unavailable
+func MessageBufferFlush(service BufferFlusher, logger logger.Logger) Job {
+	return Job{
+		Name:    "message_buffer_flush",
+		Timeout: time.Minute,
+		Run: func(ctx context.Context) error {
+			flushed, err := service.FlushBuffer(ctx)
+			if flushed > 0 {
+				logger.Info("Flushed buffered messages", "count", flushed)
+			}
+			return err
+		},
+	}
+}
+
+// SMSNotifications returns a job that texts users about direct messages
+// they have left unread
+func SMSNotifications(service SMSNotifier, logger logger.Logger) Job {
+	return Job{
+		Name:    "sms_notifications",
+		Timeout: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			sent, err := service.SendUnreadNotifications(ctx)
+			if sent > 0 {
+				logger.Info("Sent SMS notifications", "count", sent)
+			}
+			return err
+		},
+	}
+}
+
+// EmailDigests returns a job that emails users who opted in a summary of
+// the direct messages they have left unread
+func EmailDigests(digests DigestSender, logger logger.Logger) Job {
+	return Job{
+		Name:    "email_digests",
+		Timeout: 10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			sent, err := digests.SendDigests(ctx)
+			if sent > 0 {
+				logger.Info("Sent email digests", "count", sent)
+			}
+			return err
+		},
+	}
+}
+
+// FeatureFlagRefresh returns a job that reloads feature flag overrides
+func FeatureFlagRefresh(flags FlagRefresher) Job {
+	return Job{
+		Name:    "feature_flag_refresh",
+		Timeout: 10 * time.Second,
+		Run:     flags.Refresh,
+	}
+}
+
+// InboxCleanup returns a job that deletes inbox events older than the
+// retention period, acknowledged or not
+func InboxCleanup(inbox InboxPurger, retention time.Duration, logger logger.Logger) Job {
+	return Job{
+		Name:    "inbox_cleanup",
+		Timeout: 10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			cutoff := time.Now().Add(-retention)
+			deleted, err := inbox.DeleteExpired(ctx, cutoff)
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				logger.Info("Deleted expired inbox events", "count", deleted, "cutoff", cutoff)
+			}
+			return nil
+		},
+	}
+}
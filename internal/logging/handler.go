@@ -0,0 +1,121 @@
+// Package logging serves the admin API for changing log levels while the
+// server runs. Levels are per instance and reset on restart.
+package logging
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/gorilla/mux"
+)
+
+// Handler handles log level admin HTTP requests
+type Handler struct {
+	levels    *logger.Levels // nil if the logger's levels cannot be changed
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new log level handler
+func NewHandler(levels *logger.Levels, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		levels:    levels,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// GetLevels handles requests for the default and module log levels
+func (h *Handler) GetLevels(w http.ResponseWriter, r *http.Request) {
+	if h.levels == nil {
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "logging.disabled"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, h.list())
+}
+
+// SetLevel handles requests to change the level of a module, or the
+// default level for the default module
+func (h *Handler) SetLevel(w http.ResponseWriter, r *http.Request) {
+	if h.levels == nil {
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "logging.disabled"))
+		return
+	}
+
+	module := mux.Vars(r)["module"]
+
+	// Parse and validate request
+	var req models.LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode log level request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Change the level
+	if err := h.levels.Set(module, req.Level); err != nil {
+		if errors.Is(err, logger.ErrUnknownModule) {
+			sendError(w, r, errcode.NotFound, i18n.T(r, "logging.unknown_module"))
+			return
+		}
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "logging.invalid_level"))
+		return
+	}
+	h.logger.WithContext(r.Context()).Info("Log level changed", "module", module, "level", req.Level)
+
+	// Send response
+	sendJSON(w, http.StatusOK, h.list())
+}
+
+// list returns the current levels
+func (h *Handler) list() *models.LogLevelListResponse {
+	resp := &models.LogLevelListResponse{}
+	for _, level := range h.levels.List() {
+		resp.Levels = append(resp.Levels, models.LogLevel{
+			Module:    level.Module,
+			Level:     level.Level,
+			Inherited: level.Inherited,
+		})
+	}
+	return resp
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
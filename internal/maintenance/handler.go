@@ -0,0 +1,79 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+)
+
+// Handler handles maintenance mode admin HTTP requests
+type Handler struct {
+	mode      *Mode
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new maintenance mode handler
+func NewHandler(mode *Mode, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		mode:      mode,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// GetState handles requests for the maintenance mode setting
+func (h *Handler) GetState(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, http.StatusOK, h.mode.State())
+}
+
+// UpdateState handles requests to switch maintenance mode on or off
+func (h *Handler) UpdateState(w http.ResponseWriter, r *http.Request) {
+	// Parse and validate request
+	var req models.MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode maintenance request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, h.mode.Set(r.Context(), &req))
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
@@ -0,0 +1,174 @@
+// Package maintenance implements a maintenance mode that turns clients away
+// while the service is being deployed or migrated. It is held in memory, so
+// each instance is switched separately.
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/gorilla/websocket"
+)
+
+// maxCloseReason is the longest reason a WebSocket close frame can carry
+const maxCloseReason = 123
+
+// exemptPaths are served during maintenance so pages still load and
+// maintenance mode can be switched off. Metrics are served on their own
+// listener, outside maintenance mode.
+var exemptPaths = []string{"/static/", "/admin/maintenance", "/api/v1/admin/maintenance"}
+
+// adminLoginPaths are served while admins are let through, so admins can
+// get the access tokens they are let through with
+var adminLoginPaths = []string{"/auth/login", "/auth/refresh", "/api/v1/auth/login", "/api/v1/auth/refresh"}
+
+// AdminVerifier reports whether an access token belongs to an admin
+type AdminVerifier interface {
+	IsAdminToken(ctx context.Context, token string) bool
+}
+
+// Mode is the runtime maintenance mode switch
+type Mode struct {
+	logger logger.Logger
+
+	mu    sync.RWMutex
+	state models.MaintenanceState
+}
+
+// NewMode creates a maintenance mode switch in its configured state
+func NewMode(config configs.MaintenanceConfig, logger logger.Logger) *Mode {
+	return &Mode{
+		logger: logger,
+		state: models.MaintenanceState{
+			Enabled:     config.Enabled,
+			Message:     config.Message,
+			AllowAdmins: config.AllowAdmins,
+			UpdatedAt:   time.Now(),
+		},
+	}
+}
+
+// State returns the current maintenance mode setting
+func (m *Mode) State() models.MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Set changes the maintenance mode setting
+func (m *Mode) Set(ctx context.Context, req *models.MaintenanceRequest) models.MaintenanceState {
+	m.mu.Lock()
+	m.state.Enabled = req.Enabled
+	if req.Message != nil {
+		m.state.Message = *req.Message
+	}
+	if req.AllowAdmins != nil {
+		m.state.AllowAdmins = *req.AllowAdmins
+	}
+	m.state.UpdatedAt = time.Now()
+	state := m.state
+	m.mu.Unlock()
+
+	m.logger.WithContext(ctx).Info("Maintenance mode changed", "enabled", state.Enabled, "allow_admins", state.AllowAdmins)
+	return state
+}
+
+// Middleware turns requests away while maintenance mode is on. API requests
+// get a 503 with a JSON error, and WebSocket upgrades are accepted only to
+// be closed with the Try Again Later close code. Connections that are
+// already open are left alone.
+func (m *Mode) Middleware(admins AdminVerifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state := m.State()
+			if !state.Enabled || exempt(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if state.AllowAdmins {
+				if adminLogin(r.URL.Path) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				if token := requestToken(r); token != "" && admins.IsAdminToken(r.Context(), token) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			message := state.Message
+			if message == "" {
+				message = i18n.T(r, "maintenance.active")
+			}
+
+			if websocket.IsWebSocketUpgrade(r) {
+				m.refuseUpgrade(w, r, message)
+				return
+			}
+
+			sendError(w, r, errcode.Maintenance, message)
+		})
+	}
+}
+
+// refuseUpgrade completes a WebSocket handshake and closes the connection
+// straight away. Browsers do not expose the status of a failed handshake,
+// so this is the only way to tell clients why they cannot connect.
+func (m *Mode) refuseUpgrade(w http.ResponseWriter, r *http.Request, message string) {
+	upgrader := websocket.Upgrader{
+		// Nothing is read from the connection
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		m.logger.WithContext(r.Context()).Error("Failed to upgrade connection to refuse it", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	for len(message) > maxCloseReason {
+		_, size := utf8.DecodeLastRuneInString(message)
+		message = message[:len(message)-size]
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, message), time.Now().Add(time.Second))
+}
+
+// exempt reports whether a path is served during maintenance
+func exempt(path string) bool {
+	for _, prefix := range exemptPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// adminLogin reports whether a path is one admins log in through
+func adminLogin(path string) bool {
+	for _, loginPath := range adminLoginPaths {
+		if path == loginPath {
+			return true
+		}
+	}
+	return false
+}
+
+// requestToken returns the access token from the Authorization header or,
+// for WebSocket upgrades, the token query parameter
+func requestToken(r *http.Request) string {
+	fields := strings.Fields(r.Header.Get("Authorization"))
+	if len(fields) == 2 && fields[0] == "Bearer" {
+		return fields[1]
+	}
+	return r.URL.Query().Get("token")
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Account audit log actions
+const (
+	AuditExportRequested   = "export_requested"
+	AuditDeletionRequested = "deletion_requested"
+	AuditDeletionCancelled = "deletion_cancelled"
+	AuditAccountErased     = "account_erased"
+)
+
+// AccountProfile is the user's profile as included in a data export
+type AccountProfile struct {
+	ID                  uuid.UUID  `json:"user_id" db:"id"`
+	Username            string     `json:"username" db:"username"`
+	Email               string     `json:"email" db:"email"`
+	PhoneNumber         string     `json:"phone_number,omitempty" db:"phone_number"`
+	Role                string     `json:"role" db:"role"`
+	StatusText          string     `json:"status_text" db:"status_text"`
+	StatusEmoji         string     `json:"status_emoji" db:"status_emoji"`
+	MessagePrivacy      string     `json:"message_privacy" db:"message_privacy"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" db:"deletion_scheduled_at"`
+}
+
+// AccountSession is a session as included in a data export, without its
+// refresh token
+type AccountSession struct {
+	ID           uuid.UUID `json:"session_id" db:"id"`
+	UserAgent    string    `json:"user_agent" db:"user_agent"`
+	ClientIP     string    `json:"client_ip" db:"client_ip"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	LastActiveAt time.Time `json:"last_active_at" db:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// AccountContact is an accepted contact as included in a data export
+type AccountContact struct {
+	UserID    uuid.UUID `json:"user_id" db:"contact_id"`
+	Username  string    `json:"username" db:"username"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AccountGroupMessage is a group message the user sent, as included in a
+// data export
+type AccountGroupMessage struct {
+	ID        uuid.UUID `json:"message_id" db:"id"`
+	GroupID   uuid.UUID `json:"group_id" db:"group_id"`
+	Type      string    `json:"type" db:"type"`
+	Content   string    `json:"content" db:"content"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AccountAuditEntry records a data export or deletion request
+type AccountAuditEntry struct {
+	Action    string    `json:"action" db:"action"`
+	ClientIP  string    `json:"client_ip" db:"client_ip"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AccountExport is the personal data held about a user
+type AccountExport struct {
+	ExportedAt     time.Time             `json:"exported_at"`
+	Profile        AccountProfile        `json:"profile"`
+	Sessions       []AccountSession      `json:"sessions"`
+	Contacts       []AccountContact      `json:"contacts"`
+	DirectMessages []DirectMessage       `json:"direct_messages"` // sent and received
+	GroupMessages  []AccountGroupMessage `json:"group_messages"`  // sent only
+	Attachments    []Attachment          `json:"attachments"`     // uploaded, metadata only
+	AuditLog       []AccountAuditEntry   `json:"audit_log"`
+}
+
+// AccountDeletionResponse is the response for an account deletion request
+type AccountDeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
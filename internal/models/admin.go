@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+)
+
+// DailyCount is a count for a single day
+type DailyCount struct {
+	Day   time.Time `json:"day" db:"day"`
+	Count int       `json:"count" db:"count"`
+}
+
+// ConversationStat is the activity of a single conversation
+type ConversationStat struct {
+	ConversationID string `json:"conversation_id" db:"conversation_id"`
+	MessageCount   int    `json:"message_count" db:"message_count"`
+}
+
+// ConnectionStats are the hub's live connection counters
+type ConnectionStats struct {
+	ConnectedUsers int `json:"connected_users"`
+	Connections    int `json:"connections"`
+}
+
+// ConnectionInfo describes one open WebSocket connection for admins
+type ConnectionInfo struct {
+	UserID            string    `json:"user_id"`
+	Username          string    `json:"username"`
+	SessionID         string    `json:"session_id,omitempty"`
+	ClientIP          string    `json:"client_ip"`
+	Platform          string    `json:"platform,omitempty"`
+	AppVersion        string    `json:"app_version,omitempty"`
+	ConnectedAt       time.Time `json:"connected_at"`
+	LastActivityAt    time.Time `json:"last_activity_at"` // last message or pong from the client
+	QueueDepth        int       `json:"queue_depth"`      // messages waiting to be written
+	Quality           string    `json:"quality,omitempty"`
+	SmoothedRTTMillis int64     `json:"smoothed_rtt_ms,omitempty"`
+}
+
+// ConnectionListResponse is the response for the admin connection list,
+// oldest connections first
+type ConnectionListResponse struct {
+	Connections []ConnectionInfo `json:"connections"`
+	Total       int              `json:"total"` // open connections, including any past the limit
+}
+
+// UserConnectionsResponse is a user's connection state
+type UserConnectionsResponse struct {
+	UserID         string           `json:"user_id"`
+	Connected      bool             `json:"connected"`
+	Connections    []ConnectionInfo `json:"connections"`
+	PendingRetries int              `json:"pending_retries"` // messages waiting to be retried
+}
+
+// StatsResponse is the response for the admin statistics endpoint
+type StatsResponse struct {
+	GeneratedAt      time.Time          `json:"generated_at"`
+	Days             int                `json:"days"`
+	DailyActiveUsers []DailyCount       `json:"daily_active_users"`
+	MessagesPerDay   []DailyCount       `json:"messages_per_day"`
+	Connections      ConnectionStats    `json:"connections"`
+	TopConversations []ConversationStat `json:"top_conversations"`
+}
+
+// BanRequest is the request body for banning a user
+type BanRequest struct {
+	Reason string     `json:"reason" validate:"max=500"`
+	Until  *time.Time `json:"until"` // omit to ban indefinitely
+}
+
+// BanResponse is the response for the ban endpoint
+type BanResponse struct {
+	UserID            string `json:"user_id"`
+	Ban               Ban    `json:"ban"`
+	ConnectionsClosed int    `json:"connections_closed"`
+}
+
+// JobStatus describes the most recent executions of a background job
+type JobStatus struct {
+	Name         string        `json:"name"`
+	Runs         int64         `json:"runs"`
+	Failures     int64         `json:"failures"`
+	Running      bool          `json:"running"`
+	LastStarted  time.Time     `json:"last_started,omitempty"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+}
+
+// JobListResponse is the response for the admin job list, sorted by name.
+// It covers the jobs this server has run since it started.
+type JobListResponse struct {
+	Jobs []JobStatus `json:"jobs"`
+}
+
+// DisconnectResponse is the response for the force-disconnect endpoint
+type DisconnectResponse struct {
+	UserID            string `json:"user_id"`
+	ConnectionsClosed int    `json:"connections_closed"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Announcement is a system-wide notice from an admin, such as a maintenance
+// window
+type Announcement struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Message   string     `json:"message" db:"message"`
+	CreatedBy *uuid.UUID `json:"-" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+}
+
+// AnnouncementRequest is the request body for broadcasting an announcement
+type AnnouncementRequest struct {
+	Message   string     `json:"message" validate:"required,max=1000"`
+	ExpiresAt *time.Time `json:"expires_at"` // omit for the default lifetime
+}
+
+// AnnouncementResponse is the response for the announcement endpoint
+type AnnouncementResponse struct {
+	Announcement
+	Recipients int `json:"recipients"` // connections it was delivered to live
+}
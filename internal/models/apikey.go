@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// API key scopes
+const (
+	ScopeMessagesSend = "messages:send"
+	ScopeStatsRead    = "stats:read"
+)
+
+// APIKey lets a trusted backend integration call the API without a user
+// session. Only a hash of the key is stored.
+type APIKey struct {
+	ID         uuid.UUID  `json:"key_id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	UserID     *uuid.UUID `json:"user_id,omitempty" db:"user_id"` // the bot account messages are sent as
+	Username   string     `json:"username,omitempty" db:"username"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// HasScope reports whether the key grants a scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKeyRequest is the request body for issuing an API key. Keys with
+// the messages:send scope act as the user given by user_id.
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=messages:send stats:read"`
+	UserID string   `json:"user_id" validate:"omitempty,uuid"`
+}
+
+// CreateAPIKeyResponse is the response for issuing an API key. The key
+// itself is only ever returned here.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyListResponse is the response for the API key list endpoint
+type APIKeyListResponse struct {
+	Keys []APIKey `json:"keys"`
+}
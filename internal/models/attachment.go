@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a file uploaded to a conversation
+type Attachment struct {
+	ID             uuid.UUID `json:"attachment_id" db:"id"`
+	ConversationID string    `json:"conversation_id" db:"conversation_id"`
+	UploaderID     uuid.UUID `json:"uploader_id" db:"uploader_id"`
+	Filename       string    `json:"filename" db:"filename"`
+	ContentType    string    `json:"content_type" db:"content_type"`
+	Size           int64     `json:"size" db:"size"`
+	StorageKey     string    `json:"-" db:"storage_key"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+
+	// Images get a thumbnail from a background job after upload
+	ThumbnailKey  *string `json:"-" db:"thumbnail_key"`
+	ThumbnailSize *int64  `json:"-" db:"thumbnail_size"`
+}
+
+// AttachmentResponse is the API response for an attachment, with a
+// short-lived download URL
+type AttachmentResponse struct {
+	Attachment
+	DownloadURL  string    `json:"download_url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"` // once a thumbnail is made, expiring with the download URL
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ICEServer is a STUN or TURN server a WebRTC client may use, in the shape
+// of the browser's RTCIceServer
+type ICEServer struct {
+	URLs       []string `json:"urls"`
+	Username   string   `json:"username,omitempty"`
+	Credential string   `json:"credential,omitempty"`
+}
+
+// ICEServersResponse is the response for the ICE servers endpoint
+type ICEServersResponse struct {
+	ICEServers []ICEServer `json:"ice_servers"`
+	// ExpiresAt is when the TURN credentials stop working, if any were issued
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Contact request statuses
+const (
+	ContactRequestPending  = "pending"
+	ContactRequestAccepted = "accepted"
+	ContactRequestDeclined = "declined"
+)
+
+// ContactRequest is a request from one user to add another as a contact
+type ContactRequest struct {
+	ID                uuid.UUID  `json:"request_id" db:"id"`
+	RequesterID       uuid.UUID  `json:"requester_id" db:"requester_id"`
+	RequesterUsername string     `json:"requester_username" db:"requester_username"`
+	AddresseeID       uuid.UUID  `json:"addressee_id" db:"addressee_id"`
+	AddresseeUsername string     `json:"addressee_username" db:"addressee_username"`
+	Status            string     `json:"status" db:"status"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	RespondedAt       *time.Time `json:"responded_at,omitempty" db:"responded_at"`
+}
+
+// CreateContactRequest is the request body for sending a contact request
+type CreateContactRequest struct {
+	UserID string `json:"user_id" validate:"required,uuid"`
+}
+
+// ContactRequestListResponse is the response for the pending requests endpoint
+type ContactRequestListResponse struct {
+	Requests []ContactRequest `json:"requests"`
+}
+
+// ContactListResponse is the response for the contacts endpoint
+type ContactListResponse struct {
+	Contacts []UserInfo `json:"contacts"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// FeatureFlag controls the rollout of a capability
+type FeatureFlag struct {
+	Name       string    `json:"name" db:"name"`
+	Enabled    bool      `json:"enabled" db:"enabled"`
+	Percentage int       `json:"percentage" db:"percentage"`
+	UserIDs    []string  `json:"user_ids" db:"user_ids"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// FeatureFlagRequest is the request body for updating a feature flag
+type FeatureFlagRequest struct {
+	Enabled    bool     `json:"enabled"`
+	Percentage *int     `json:"percentage" validate:"omitempty,min=0,max=100"`
+	UserIDs    []string `json:"user_ids" validate:"omitempty,dive,uuid"`
+}
+
+// FeatureFlagListResponse is the response for the feature flag list endpoint
+type FeatureFlagListResponse struct {
+	Flags []FeatureFlag `json:"flags"`
+}
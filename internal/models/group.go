@@ -0,0 +1,193 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Group member roles
+const (
+	GroupRoleAdmin  = "admin"
+	GroupRoleMember = "member"
+)
+
+// Group join request statuses
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestDeclined = "declined"
+)
+
+// Group message types. Membership types mark system messages recording
+// who joined or left the group.
+const (
+	GroupMessageText          = "text"
+	GroupMessageMemberAdded   = "member_added"
+	GroupMessageMemberRemoved = "member_removed"
+	GroupMessageMemberLeft    = "member_left"
+)
+
+// Aggregated delivery states of a group text message, counting only
+// current members other than the sender
+const (
+	GroupDeliverySent      = "sent"
+	GroupDeliveryDelivered = "delivered" // delivered to every member
+	GroupDeliveryRead      = "read"      // read by every member
+)
+
+// Group is a group chat
+type Group struct {
+	ID          uuid.UUID     `json:"group_id" db:"id"`
+	Name        string        `json:"name" db:"name"`
+	CreatedBy   uuid.UUID     `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	WorkspaceID *uuid.UUID    `json:"workspace_id,omitempty" db:"workspace_id"` // nil outside workspaces
+	Members     []GroupMember `json:"members"`
+}
+
+// GroupMember is a member of a group
+type GroupMember struct {
+	UserID   uuid.UUID `json:"user_id" db:"user_id"`
+	Username string    `json:"username" db:"username"`
+	Role     string    `json:"role" db:"role"`
+	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
+}
+
+// CreateGroupRequest is the request body for creating a group
+type CreateGroupRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// AddGroupMemberRequest is the request body for adding a group member
+type AddGroupMemberRequest struct {
+	UserID string `json:"user_id" validate:"required,uuid"`
+}
+
+// GroupMessage is a message in a group's timeline. For system messages
+// the sender made the membership change and the subject is the member it
+// is about.
+type GroupMessage struct {
+	ID              uuid.UUID  `json:"message_id" db:"id"`
+	GroupID         uuid.UUID  `json:"group_id" db:"group_id"`
+	Type            string     `json:"type" db:"type"`
+	SenderID        uuid.UUID  `json:"sender_id" db:"sender_id"`
+	SenderUsername  string     `json:"sender_username" db:"sender_username"`
+	SubjectID       *uuid.UUID `json:"subject_id,omitempty" db:"subject_id"`
+	SubjectUsername *string    `json:"subject_username,omitempty" db:"subject_username"`
+	Content         string     `json:"content" db:"content"`
+	Timestamp       time.Time  `json:"timestamp" db:"created_at"`
+	DeliveryStatus  string     `json:"delivery_status,omitempty" db:"delivery_status"` // text messages only
+}
+
+// SendGroupMessageRequest is the request body for sending a group message
+type SendGroupMessageRequest struct {
+	Content string `json:"content" validate:"required,message"`
+}
+
+// GroupMessageReceipt is one member's delivery and read times for a
+// group message
+type GroupMessageReceipt struct {
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Username    string     `json:"username" db:"username"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	ReadAt      *time.Time `json:"read_at,omitempty" db:"read_at"`
+}
+
+// GroupMessageInfoResponse is the response for the group message info
+// endpoint
+type GroupMessageInfoResponse struct {
+	MessageID      string                `json:"message_id"`
+	GroupID        string                `json:"group_id"`
+	DeliveryStatus string                `json:"delivery_status"`
+	DeliveredCount int                   `json:"delivered_count"`
+	ReadCount      int                   `json:"read_count"`
+	Receipts       []GroupMessageReceipt `json:"receipts"`
+}
+
+// GroupMessageStatus is the aggregated delivery status of a group message
+type GroupMessageStatus struct {
+	MessageID      string `json:"message_id"`
+	DeliveryStatus string `json:"delivery_status"`
+}
+
+// GroupMessageStatusData is the data for the WebSocket event telling a
+// sender that the delivery status of their group messages changed
+type GroupMessageStatusData struct {
+	GroupID  string               `json:"group_id"`
+	Messages []GroupMessageStatus `json:"messages"`
+}
+
+// GroupMessageListResponse is the response for group message history
+type GroupMessageListResponse struct {
+	GroupID    string         `json:"group_id"`
+	Messages   []GroupMessage `json:"messages"`
+	HasMore    bool           `json:"has_more"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// GroupInvite is a revocable link that lets users ask to join a group
+type GroupInvite struct {
+	ID        uuid.UUID  `json:"invite_id" db:"id"`
+	GroupID   uuid.UUID  `json:"group_id" db:"group_id"`
+	Code      string     `json:"code" db:"code"`
+	CreatedBy uuid.UUID  `json:"created_by" db:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	MaxUses   *int       `json:"max_uses,omitempty" db:"max_uses"`
+	Uses      int        `json:"uses" db:"uses"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Usable reports whether the invite can still be used at now
+func (i *GroupInvite) Usable(now time.Time) bool {
+	if i.RevokedAt != nil {
+		return false
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == nil || i.Uses < *i.MaxUses
+}
+
+// CreateGroupInviteRequest is the request body for creating an invite link.
+// Both limits are optional.
+type CreateGroupInviteRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int       `json:"max_uses" validate:"omitempty,min=1"`
+}
+
+// GroupInviteListResponse is the response for the group invites endpoint
+type GroupInviteListResponse struct {
+	Invites []GroupInvite `json:"invites"`
+}
+
+// GroupJoinRequest is a user's request to join a group through an invite,
+// waiting for an admin to approve it
+type GroupJoinRequest struct {
+	ID          uuid.UUID  `json:"request_id" db:"id"`
+	GroupID     uuid.UUID  `json:"group_id" db:"group_id"`
+	GroupName   string     `json:"group_name" db:"group_name"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Username    string     `json:"username" db:"username"`
+	InviteID    *uuid.UUID `json:"invite_id,omitempty" db:"invite_id"`
+	Status      string     `json:"status" db:"status"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty" db:"responded_at"`
+	RespondedBy *uuid.UUID `json:"responded_by,omitempty" db:"responded_by"`
+}
+
+// GroupJoinRequestListResponse is the response for the join requests endpoint
+type GroupJoinRequestListResponse struct {
+	Requests []GroupJoinRequest `json:"requests"`
+}
+
+// GroupMembershipData is the data for group membership WebSocket events
+type GroupMembershipData struct {
+	MessageID string    `json:"message_id"` // system message in the timeline
+	GroupID   string    `json:"group_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	ActorID   string    `json:"actor_id,omitempty"` // admin who made the change, unset when members leave
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+)
+
+// InboxEvent is an event kept in a user's inbox until their devices
+// acknowledge it. Seq numbers a user's events in the order they were sent.
+type InboxEvent struct {
+	Seq       int64                `json:"seq" db:"seq"`
+	Type      protocol.MessageType `json:"type" db:"type"`
+	Data      json.RawMessage      `json:"data" db:"data"`
+	CreatedAt time.Time            `json:"created_at" db:"created_at"`
+}
+
+// InboxResponse is a page of a user's inbox. Head is the seq of the user's
+// latest event. Gap is set when events after the requested seq were
+// trimmed, and the client must resynchronize its state another way.
+type InboxResponse struct {
+	Events  []InboxEvent `json:"events"`
+	Head    int64        `json:"head"`
+	HasMore bool         `json:"has_more"`
+	Gap     bool         `json:"gap"`
+}
+
+// AckInboxRequest is the request body for acknowledging a device has
+// received every event up to and including Seq
+type AckInboxRequest struct {
+	DeviceID string `json:"device_id" validate:"required,max=64"`
+	Seq      int64  `json:"seq" validate:"min=0"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// LogLevel is the log level of a module of the server. Modules that
+// inherit follow the default level.
+type LogLevel struct {
+	Module    string `json:"module"`
+	Level     string `json:"level"`
+	Inherited bool   `json:"inherited"`
+}
+
+// LogLevelListResponse is the API response listing log levels
+type LogLevelListResponse struct {
+	Levels []LogLevel `json:"levels"`
+}
+
+// LogLevelRequest is the request body for changing a module's log level.
+// An empty level makes the module follow the default level again.
+type LogLevelRequest struct {
+	Level string `json:"level" validate:"omitempty,oneof=debug info warn error"`
+}
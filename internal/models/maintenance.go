@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// MaintenanceState is the current maintenance mode setting
+type MaintenanceState struct {
+	Enabled     bool      `json:"enabled"`
+	Message     string    `json:"message,omitempty"`
+	AllowAdmins bool      `json:"allow_admins"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MaintenanceRequest is the request body for changing maintenance mode.
+// Omitted fields keep their current values.
+type MaintenanceRequest struct {
+	Enabled     bool    `json:"enabled"`
+	Message     *string `json:"message" validate:"omitempty,max=500"`
+	AllowAdmins *bool   `json:"allow_admins"`
+}
@@ -1,36 +1,136 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
 	"github.com/google/uuid"
 )
 
+// Message formats
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// Direct message types. Other types than text mark system messages
+// recording events in the conversation, sent by the user who caused them.
+// They have no content; clients render them from the type and data.
+const (
+	DirectMessageText              = "text"
+	DirectMessageCallMissed        = "call_missed"
+	DirectMessageDisappearingTimer = "disappearing_timer"
+)
+
+// Delivery states of a direct message. A message is queued until it is
+// saved, then stored until it reaches one of the recipient's devices, and
+// read once the recipient's read cursor passes it. A queued message that
+// cannot be saved fails. Only the states after saving are persisted.
+const (
+	DeliveryQueued    = "queued"
+	DeliveryStored    = "stored"
+	DeliveryDelivered = "delivered"
+	DeliveryRead      = "read"
+	DeliveryFailed    = "failed"
+)
+
+// deliveryTransitions lists the states each delivery state can move to.
+// Read and failed are final.
+var deliveryTransitions = map[string][]string{
+	DeliveryQueued:    {DeliveryStored, DeliveryFailed},
+	DeliveryStored:    {DeliveryDelivered, DeliveryRead},
+	DeliveryDelivered: {DeliveryRead},
+}
+
+// CanTransition reports whether a message in one delivery state can move to another
+func CanTransition(from, to string) bool {
+	for _, next := range deliveryTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliverySources returns the states a message can move to the given state from
+func DeliverySources(to string) []string {
+	var sources []string
+	for from := range deliveryTransitions {
+		if CanTransition(from, to) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+// SystemData holds the details of a system message's event, stored as JSON
+type SystemData map[string]string
+
+// Value encodes the data as JSON, or NULL if there is none
+func (d SystemData) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+// Scan decodes the data from JSON
+func (d *SystemData) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, d)
+	case string:
+		return json.Unmarshal([]byte(v), d)
+	}
+	return fmt.Errorf("cannot scan %T into SystemData", value)
+}
+
 // DirectMessage represents a direct message in the database
 type DirectMessage struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	SenderID    uuid.UUID `json:"sender_id" db:"sender_id"`
-	RecipientID uuid.UUID `json:"recipient_id" db:"recipient_id"`
-	Content     string    `json:"content" db:"content"`
-	Delivered   bool      `json:"delivered" db:"delivered"`
-	Read        bool      `json:"read" db:"read"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	Type            string     `json:"type" db:"type"`
+	Data            SystemData `json:"data,omitempty" db:"data"` // system messages only
+	SenderID        uuid.UUID  `json:"sender_id" db:"sender_id"`
+	RecipientID     uuid.UUID  `json:"recipient_id" db:"recipient_id"`
+	Content         string     `json:"content" db:"content"`
+	Format          string     `json:"format" db:"format"`
+	RenderedContent string     `json:"rendered_content,omitempty" db:"rendered_content"`
+	Delivered       bool       `json:"delivered" db:"delivered"`
+	Read            bool       `json:"read" db:"read"` // from the recipient's read cursor
+	DeliveryState   string     `json:"delivery_state" db:"delivery_state"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty" db:"expires_at"` // nil unless disappearing
+	WorkspaceID     *uuid.UUID `json:"workspace_id,omitempty" db:"-"`        // where it was sent from, nil outside workspaces
 }
 
-// Message represents a message in the API
+// Message represents a message in the API. System messages carry their
+// data instead of content.
 type Message struct {
-	ID             uuid.UUID             `json:"message_id" db:"message_id"`
-	Content        string                `json:"content" db:"content"`
-	SenderID       string                `json:"sender_id" db:"sender_id"`
-	SenderUsername string                `json:"sender_username" db:"sender_username"`
-	Timestamp      time.Time             `json:"timestamp" db:"timestamp"`
-	DeliveryStatus MessageDeliveryStatus `json:"delivery_status"`
+	ID              uuid.UUID             `json:"message_id" db:"message_id"`
+	Type            string                `json:"type" db:"type"`
+	Data            SystemData            `json:"data,omitempty" db:"data"`
+	Content         string                `json:"content" db:"content"`
+	Format          string                `json:"format" db:"format"`
+	RenderedContent string                `json:"rendered_content,omitempty" db:"rendered_content"`
+	SenderID        string                `json:"sender_id" db:"sender_id"`
+	SenderUsername  string                `json:"sender_username" db:"sender_username"`
+	Timestamp       time.Time             `json:"timestamp" db:"timestamp"`
+	ExpiresAt       *time.Time            `json:"expires_at,omitempty" db:"expires_at"` // nil unless disappearing
+	DeliveryStatus  MessageDeliveryStatus `json:"delivery_status"`
 }
 
 // MessageDeliveryStatus represents the delivery status of a message
 type MessageDeliveryStatus struct {
-	Delivered bool `json:"delivered"`
-	Read      bool `json:"read"`
+	State     string `json:"state"` // one of the persisted delivery states
+	Delivered bool   `json:"delivered"`
+	Read      bool   `json:"read"`
 }
 
 // MessageListResponse is the response for message history
@@ -39,30 +139,97 @@ type MessageListResponse struct {
 	Messages       []Message `json:"messages"`
 	HasMore        bool      `json:"has_more"`
 	NextCursor     string    `json:"next_cursor,omitempty"`
+	HasMoreAfter   bool      `json:"has_more_after,omitempty"` // only set for pages around a date
 }
 
-// WebSocketMessage is the message format for WebSocket communication
-type WebSocketMessage struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data"`
+// BatchGetMessagesRequest is the request body for fetching the latest
+// messages of several conversations at once
+type BatchGetMessagesRequest struct {
+	ConversationIDs []string `json:"conversation_ids" validate:"required,min=1,max=50,dive,required"`
+	Limit           int      `json:"limit,omitempty" validate:"omitempty,min=1,max=50"` // per conversation
+}
+
+// BatchGetMessagesResponse is the response for a batch message fetch, one
+// page per conversation in the order requested. Conversations that do not
+// exist or that the user is not part of are listed as missing instead.
+type BatchGetMessagesResponse struct {
+	Conversations []MessageListResponse `json:"conversations"`
+	Missing       []string              `json:"missing,omitempty"`
 }
 
+// MessageContextResponse is the response for the message context endpoint
+type MessageContextResponse struct {
+	ConversationID string    `json:"conversation_id"`
+	MessageID      string    `json:"message_id"`
+	Messages       []Message `json:"messages"` // newest first, including the target message
+	HasMoreBefore  bool      `json:"has_more_before"`
+	HasMoreAfter   bool      `json:"has_more_after"`
+	NextCursor     string    `json:"next_cursor,omitempty"` // continues with older messages
+}
+
+// Conversation transcript export formats
+const (
+	ExportFormatJSON = "json"
+	ExportFormatText = "text"
+)
+
+// ConversationExport is a transcript of a conversation as the exporting user
+// sees it, oldest message first
+type ConversationExport struct {
+	ConversationID string       `json:"conversation_id"`
+	ExportedAt     time.Time    `json:"exported_at"`
+	Messages       []Message    `json:"messages"`
+	Attachments    []Attachment `json:"attachments,omitempty"` // only when media is requested
+}
+
+// WebSocketMessage is the message format for WebSocket communication
+type WebSocketMessage = protocol.Message
+
 // DirectMessageData is the data for a direct message WebSocket message
 type DirectMessageData struct {
-	MessageID      string    `json:"message_id"`
-	ConversationID string    `json:"conversation_id"`
-	SenderID       string    `json:"sender_id"`
-	SenderUsername string    `json:"sender_username"`
-	Content        string    `json:"content"`
-	Timestamp      time.Time `json:"timestamp"`
+	MessageID       string     `json:"message_id"`
+	ConversationID  string     `json:"conversation_id"`
+	SenderID        string     `json:"sender_id"`
+	SenderUsername  string     `json:"sender_username"`
+	Content         string     `json:"content"`
+	Format          string     `json:"format"`
+	RenderedContent string     `json:"rendered_content,omitempty"`
+	Timestamp       time.Time  `json:"timestamp"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`     // nil unless disappearing
+	DeliveryState   string     `json:"delivery_state,omitempty"` // only in the sender's copy
+}
+
+// SystemMessageData is the data for a system message WebSocket message
+type SystemMessageData struct {
+	MessageID      string     `json:"message_id"`
+	ConversationID string     `json:"conversation_id"`
+	Type           string     `json:"type"` // one of the direct message types other than text
+	SenderID       string     `json:"sender_id"`
+	Data           SystemData `json:"data,omitempty"`
+	Timestamp      time.Time  `json:"timestamp"`
+}
+
+// SendMessageRequest is the request body for sending a message over REST
+type SendMessageRequest struct {
+	ClientMessageID string `json:"client_message_id" validate:"max=100"`
+	Content         string `json:"content" validate:"required,message"`
+	Format          string `json:"format" validate:"omitempty,oneof=plain markdown"`
+}
+
+// SendMessageResponse is the API response for a message sent over REST. It
+// carries the same acknowledgment a WebSocket sender receives. Message is
+// omitted while the message is pending.
+type SendMessageResponse struct {
+	Ack     MessageAckData     `json:"ack"`
+	Message *DirectMessageData `json:"message,omitempty"`
 }
 
 // MessageAckData is the data for a message acknowledgment WebSocket message
 type MessageAckData struct {
 	ClientMessageID string    `json:"client_message_id"`
 	ServerMessageID string    `json:"server_message_id,omitempty"`
-	Status          string    `json:"status"`
-	Timestamp       time.Time `json:"timestamp,omitempty"`
+	Status          string    `json:"status"`              // the message's delivery state
+	Timestamp       time.Time `json:"timestamp,omitempty"` // the saved message's time once stored
 }
 
 // TypingIndicatorData is the data for a typing indicator WebSocket message
@@ -73,26 +240,222 @@ type TypingIndicatorData struct {
 	Status         string `json:"status"`
 }
 
+// Call media types
+const (
+	CallAudio = "audio"
+	CallVideo = "video"
+)
+
+// CallOfferData is the data for a call_offer WebSocket message sent to the
+// recipient's devices. Callers send a protocol.CallOffer.
+type CallOfferData struct {
+	CallID         string `json:"call_id"`
+	CallerID       string `json:"caller_id,omitempty"`
+	CallerUsername string `json:"caller_username,omitempty"`
+	Media          string `json:"media"`
+	SDP            string `json:"sdp"`
+}
+
+// CallAnswerData is the data for a call_answer WebSocket message
+type CallAnswerData struct {
+	CallID string `json:"call_id"`
+	SDP    string `json:"sdp"`
+}
+
+// CallEndData is the data for a call_end WebSocket message
+type CallEndData struct {
+	CallID string `json:"call_id"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// CallStateData is the data for a call_state WebSocket message, which tells
+// the caller how their call is progressing
+type CallStateData struct {
+	CallID string `json:"call_id"`
+	State  string `json:"state"`
+}
+
 // ReadReceiptData is the data for a read receipt WebSocket message
 type ReadReceiptData struct {
 	UserID            string    `json:"user_id"`
-	Username          string    `json:"username"`
+	Username          string    `json:"username,omitempty"`
 	ConversationID    string    `json:"conversation_id"`
 	LastReadMessageID string    `json:"last_read_message_id"`
 	Timestamp         time.Time `json:"timestamp,omitempty"`
 }
 
-// PresenceData is the data for a presence update WebSocket message
+// MarkReadRequest is the request body for marking a conversation read up
+// to a message
+type MarkReadRequest struct {
+	LastReadMessageID string `json:"last_read_message_id" validate:"required,uuid"`
+}
+
+// ReadSyncData is the data for a read_sync WebSocket message, sent to the
+// user's other sessions when they read a conversation on one device
+type ReadSyncData struct {
+	ConversationID    string    `json:"conversation_id"`
+	LastReadMessageID string    `json:"last_read_message_id"`
+	ReadAt            time.Time `json:"read_at"`
+}
+
+// MessageStatusData is the data for a message_status WebSocket message,
+// sent to the sender's sessions when their messages change delivery state
+type MessageStatusData struct {
+	ConversationID string    `json:"conversation_id"`
+	MessageIDs     []string  `json:"message_ids"`
+	State          string    `json:"state"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// PresenceData is the data for a presence update WebSocket message.
+// LastSeen and Platform describe the user's most recently active
+// connection, or the one that just closed when they go offline.
 type PresenceData struct {
-	UserID   string    `json:"user_id"`
-	Username string    `json:"username"`
-	Status   string    `json:"status"`
-	LastSeen time.Time `json:"last_seen,omitempty"`
+	UserID       string        `json:"user_id"`
+	Username     string        `json:"username"`
+	Status       string        `json:"status"`
+	CustomStatus *CustomStatus `json:"custom_status,omitempty"`
+	LastSeen     *time.Time    `json:"last_seen,omitempty"`
+	Platform     string        `json:"platform,omitempty"` // as declared by the client, such as ios or web
+}
+
+// Reasons for a conversation_updated WebSocket message
+const (
+	ConversationUpdateNewMessage = "new_message"
+	ConversationUpdateRead       = "read"
+	ConversationUpdatePresence   = "presence"
+	ConversationUpdateSettings   = "settings"
+)
+
+// ConversationUpdatedData is the data for a conversation_updated WebSocket
+// message, carrying the conversation as it appears in the recipient's list
+type ConversationUpdatedData struct {
+	Reason       string       `json:"reason"`
+	Version      int64        `json:"version"`
+	Conversation Conversation `json:"conversation"`
+}
+
+// ConversationClearedData is the data for a conversation_cleared WebSocket
+// message, sent to the user's own sessions. Deleted conversations are also
+// removed from the conversation list until a new message arrives.
+type ConversationClearedData struct {
+	ConversationID string    `json:"conversation_id"`
+	ClearedAt      time.Time `json:"cleared_at"`
+	Deleted        bool      `json:"deleted"`
 }
 
 // ErrorData is the data for an error WebSocket message
 type ErrorData struct {
-	Code                int    `json:"code"`
-	Message             string `json:"message"`
-	OriginalMessageType string `json:"original_message_type,omitempty"`
+	Code                errcode.Code         `json:"code"`
+	Error               string               `json:"error"`
+	Message             string               `json:"message"`
+	OriginalMessageType protocol.MessageType `json:"original_message_type,omitempty"`
+	RequestID           string               `json:"request_id,omitempty"`
+	Details             []errcode.FieldError `json:"details,omitempty"` // invalid payload fields
+}
+
+// Draft represents a partially typed message stored for a user
+type Draft struct {
+	ConversationID string    `json:"conversation_id" db:"conversation_id"`
+	Content        string    `json:"content" db:"content"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DraftRequest is the request body for saving a draft
+type DraftRequest struct {
+	Content string `json:"content" validate:"max=10000"`
+}
+
+// DraftUpdatedData is the data for a draft_updated WebSocket message
+type DraftUpdatedData struct {
+	ConversationID string    `json:"conversation_id"`
+	Content        string    `json:"content"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ConversationSettings are a user's own options for a conversation
+type ConversationSettings struct {
+	ConversationID    string     `json:"conversation_id" db:"conversation_id"`
+	Mute              string     `json:"mute" db:"mute"` // one of the mute levels
+	Pinned            bool       `json:"pinned" db:"pinned"`
+	NotificationSound string     `json:"notification_sound,omitempty" db:"notification_sound"`
+	DisappearingTimer int        `json:"disappearing_timer" db:"disappearing_timer"` // seconds, 0 when off
+	UpdatedAt         *time.Time `json:"updated_at,omitempty" db:"updated_at"`       // nil until first changed
+}
+
+// ConversationSettingsRequest is the request body for updating a
+// conversation's settings. Omitted fields are left unchanged.
+type ConversationSettingsRequest struct {
+	Mute              *string `json:"mute" validate:"omitempty,oneof=none mentions media all"`
+	Pinned            *bool   `json:"pinned"`
+	NotificationSound *string `json:"notification_sound" validate:"omitempty,max=64,printascii"`
+	DisappearingTimer *int    `json:"disappearing_timer" validate:"omitempty,oneof=0 86400 604800 7776000"` // off, a day, a week or 90 days
+}
+
+// Mention represents a message that mentions a user
+type Mention struct {
+	MessageID      uuid.UUID `json:"message_id" db:"message_id"`
+	ConversationID string    `json:"conversation_id" db:"conversation_id"`
+	SenderID       string    `json:"sender_id" db:"sender_id"`
+	SenderUsername string    `json:"sender_username" db:"sender_username"`
+	Content        string    `json:"content" db:"content"`
+	Timestamp      time.Time `json:"timestamp" db:"timestamp"`
+}
+
+// MentionListResponse is the response for the mentions endpoint
+type MentionListResponse struct {
+	Mentions   []Mention `json:"mentions"`
+	HasMore    bool      `json:"has_more"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// MentionData is the data for a mention WebSocket message
+type MentionData struct {
+	MessageID      string    `json:"message_id"`
+	ConversationID string    `json:"conversation_id"`
+	SenderID       string    `json:"sender_id"`
+	Content        string    `json:"content"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// Connection quality ratings
+const (
+	ConnectionGood = "good"
+	ConnectionFair = "fair"
+	ConnectionPoor = "poor"
+)
+
+// ConnectionQualityData is the data for a connection_quality WebSocket
+// message, sent to clients that connect with connection_quality=true or
+// declare the connection_quality feature when their connection's rating
+// changes, so they can switch to a degraded mode
+type ConnectionQualityData struct {
+	Quality           string `json:"quality"`
+	RTTMillis         int64  `json:"rtt_ms"`          // last ping round trip
+	SmoothedRTTMillis int64  `json:"smoothed_rtt_ms"` // weighted average
+}
+
+// AuthExpiryData is the data for reauth_required and auth_refreshed
+// WebSocket messages, giving when the connection's access token expires
+type AuthExpiryData struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ForceUpgradeData is the data for a force_upgrade WebSocket message, sent
+// before disconnecting a client whose app version is no longer supported
+type ForceUpgradeData struct {
+	Platform   string `json:"platform"`
+	AppVersion string `json:"app_version"`
+	MinVersion string `json:"min_version"`
+}
+
+// ReconnectHint is sent as the reason of a close frame when the server
+// disconnects a client it expects to come back, telling it how long to wait
+// before reconnecting. Epoch identifies the server process; a different
+// epoch after reconnecting means the server restarted and the client should
+// resynchronize its state.
+type ReconnectHint struct {
+	Reason       string `json:"reason"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+	Epoch        int64  `json:"epoch"`
 }
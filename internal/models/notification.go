@@ -0,0 +1,219 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification channels for users who are not connected
+const (
+	ChannelPush    = "push"
+	ChannelEmail   = "email"
+	ChannelWebPush = "web_push"
+	ChannelSMS     = "sms"
+	// ChannelEmailDigest is a periodic email summarizing unread messages
+	ChannelEmailDigest = "email_digest"
+)
+
+// Notification event types
+const (
+	NotificationDirectMessage  = "direct_message"
+	NotificationMention        = "mention"
+	NotificationContactRequest = "contact_request"
+	NotificationMedia          = "media"
+	NotificationSecurityAlert  = "security_alert" // cannot be turned off
+)
+
+// NotificationEvents lists the event types users can turn off
+var NotificationEvents = []string{
+	NotificationDirectMessage,
+	NotificationMention,
+	NotificationContactRequest,
+	NotificationMedia,
+}
+
+// Conversation mute levels
+const (
+	MuteNone     = "none"
+	MuteMentions = "mentions"
+	MuteMedia    = "media"
+	MuteAll      = "all"
+)
+
+// NotificationChannels holds which channels a user receives notifications on
+type NotificationChannels struct {
+	Push    bool `json:"push"`
+	Email   bool `json:"email"`
+	WebPush bool `json:"web_push"`
+	SMS     bool `json:"sms"` // opt-in, for messages left unread
+	// EmailDigest is opt-in, for a periodic summary of messages left unread
+	EmailDigest bool `json:"email_digest"`
+}
+
+// Enabled reports whether a channel is turned on
+func (c NotificationChannels) Enabled(channel string) bool {
+	switch channel {
+	case ChannelPush:
+		return c.Push
+	case ChannelEmail:
+		return c.Email
+	case ChannelWebPush:
+		return c.WebPush
+	case ChannelSMS:
+		return c.SMS
+	case ChannelEmailDigest:
+		return c.EmailDigest
+	default:
+		return false
+	}
+}
+
+// ConversationNotificationOverride changes notifications for one
+// conversation. With Mute set to none, Enabled turns every notification on
+// regardless of the user's event preferences; mentions and media mute only
+// that event type and leave the rest to the event preferences; all mutes
+// the conversation entirely, including its unread badge.
+type ConversationNotificationOverride struct {
+	ConversationID string `json:"conversation_id" db:"conversation_id"`
+	Enabled        bool   `json:"enabled" db:"enabled"`
+	Mute           string `json:"mute" db:"mute"`
+}
+
+// Allows reports whether the override lets through a notification of an
+// event type, and whether it decided that rather than the event preferences
+func (o ConversationNotificationOverride) Allows(event string) (allowed, decided bool) {
+	switch o.Mute {
+	case MuteAll:
+		return false, true
+	case MuteMentions:
+		if event == NotificationMention {
+			return false, true
+		}
+		return false, false
+	case MuteMedia:
+		if event == NotificationMedia {
+			return false, true
+		}
+		return false, false
+	default:
+		return o.Enabled, true
+	}
+}
+
+// NotificationPreferences holds how and about what a user is notified
+type NotificationPreferences struct {
+	Channels      NotificationChannels               `json:"channels"`
+	Events        map[string]bool                    `json:"events"`
+	Conversations []ConversationNotificationOverride `json:"conversations"`
+}
+
+// DefaultNotificationPreferences returns the preferences of users who have
+// not changed them
+func DefaultNotificationPreferences() *NotificationPreferences {
+	events := make(map[string]bool, len(NotificationEvents))
+	for _, event := range NotificationEvents {
+		events[event] = true
+	}
+	return &NotificationPreferences{
+		Channels:      NotificationChannels{Push: true, WebPush: true},
+		Events:        events,
+		Conversations: []ConversationNotificationOverride{},
+	}
+}
+
+// Allows reports whether a notification of an event type in a conversation
+// may be delivered on a channel. A conversation override takes precedence
+// over the event preference where it applies; the channel must always be
+// enabled.
+func (p *NotificationPreferences) Allows(channel, event, conversationID string) bool {
+	if !p.Channels.Enabled(channel) {
+		return false
+	}
+
+	if conversationID != "" {
+		for _, override := range p.Conversations {
+			if override.ConversationID == conversationID {
+				if allowed, decided := override.Allows(event); decided {
+					return allowed
+				}
+				break
+			}
+		}
+	}
+
+	enabled, ok := p.Events[event]
+	return !ok || enabled
+}
+
+// UpdateNotificationPreferencesRequest is the request body for updating
+// notification preferences. Event types that are left out keep their setting.
+type UpdateNotificationPreferencesRequest struct {
+	Channels NotificationChannels `json:"channels"`
+	Events   map[string]bool      `json:"events"`
+}
+
+// ConversationNotificationRequest is the request body for a conversation
+// override. Mute takes precedence over Enabled; enabled false alone mutes
+// the conversation entirely.
+type ConversationNotificationRequest struct {
+	Enabled *bool  `json:"enabled" validate:"required_without=Mute"`
+	Mute    string `json:"mute" validate:"omitempty,oneof=none mentions media all"`
+}
+
+// Override returns the conversation override the request asks for
+func (r ConversationNotificationRequest) Override(conversationID string) ConversationNotificationOverride {
+	override := ConversationNotificationOverride{ConversationID: conversationID, Mute: r.Mute}
+	if override.Mute == "" {
+		override.Mute = MuteNone
+		if r.Enabled != nil && !*r.Enabled {
+			override.Mute = MuteAll
+		}
+	}
+	override.Enabled = override.Mute != MuteAll
+	return override
+}
+
+// Notification is a notification for a user who is not connected. Senders
+// format it for their channel.
+type Notification struct {
+	UserID         uuid.UUID `json:"user_id"`
+	Type           string    `json:"type"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	SenderID       string    `json:"sender_id,omitempty"`
+	SenderUsername string    `json:"sender_username,omitempty"`
+	Body           string    `json:"body,omitempty"`
+}
+
+// PhoneNumber is a user's phone number for SMS notifications
+type PhoneNumber struct {
+	PhoneNumber string     `json:"phone_number,omitempty"`
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+	Pending     string     `json:"pending_phone_number,omitempty"` // awaiting verification
+}
+
+// PhoneNumberRequest is the request body for setting a phone number. The
+// number is saved once the user enters the code texted to it.
+type PhoneNumberRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required,e164"`
+}
+
+// VerifyPhoneRequest is the request body for confirming a phone number
+type VerifyPhoneRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// PhoneLoginRequest is the request body for logging in with a code texted
+// to the account's phone number
+type PhoneLoginRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required,e164"`
+	Code        string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// PhoneRegisterRequest is the request body for signing up with a code
+// texted to a phone number instead of an email and password
+type PhoneRegisterRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required,e164"`
+	Code        string `json:"code" validate:"required,len=6,numeric"`
+	Username    string `json:"username" validate:"required,min=3,max=50,username"`
+}
@@ -8,12 +8,43 @@ import (
 
 // Session represents a user session in the system
 type Session struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	UserID       uuid.UUID `json:"user_id" db:"user_id"`
-	RefreshToken string    `json:"refresh_token" db:"refresh_token"`
-	UserAgent    string    `json:"user_agent" db:"user_agent"`
-	ClientIP     string    `json:"client_ip" db:"client_ip"`
-	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	LastActiveAt time.Time `json:"last_active_at" db:"last_active_at"`
+	ID           uuid.UUID  `json:"id" db:"id"`
+	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
+	RefreshToken string     `json:"refresh_token" db:"refresh_token"`
+	UserAgent    string     `json:"user_agent" db:"user_agent"`
+	ClientIP     string     `json:"client_ip" db:"client_ip"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	LastActiveAt time.Time  `json:"last_active_at" db:"last_active_at"`
+	WorkspaceID  *uuid.UUID `json:"workspace_id,omitempty" db:"workspace_id"`
+}
+
+// LoginRecord is a successful login. The New fields mark what had not been
+// seen in the user's earlier logins.
+type LoginRecord struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	UserID     uuid.UUID `json:"-" db:"user_id"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	ClientIP   string    `json:"client_ip" db:"client_ip"`
+	Country    string    `json:"country,omitempty" db:"country"`
+	NewDevice  bool      `json:"new_device" db:"new_device"`
+	NewIP      bool      `json:"new_ip" db:"new_ip"`
+	NewCountry bool      `json:"new_country" db:"new_country"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Suspicious reports whether the login came from somewhere new
+func (l *LoginRecord) Suspicious() bool {
+	return l.NewDevice || l.NewIP || l.NewCountry
+}
+
+// LoginHistoryResponse is the API response for a user's recent logins
+type LoginHistoryResponse struct {
+	Logins []LoginRecord `json:"logins"`
+}
+
+// SecurityAlertData is the data for a security_alert WebSocket message
+type SecurityAlertData struct {
+	Reason string      `json:"reason"`
+	Login  LoginRecord `json:"login"`
 }
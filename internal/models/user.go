@@ -3,18 +3,50 @@ package models
 import (
 	"time"
 
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
 	"github.com/google/uuid"
 )
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // User represents a user in the system
 type User struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	Username     string    `json:"username" db:"username"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"`
-	Status       string    `json:"status" db:"status"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID                uuid.UUID `json:"id" db:"id"`
+	Username          string    `json:"username" db:"username"`
+	DisplayName       string    `json:"display_name,omitempty" db:"display_name"`
+	Email             string    `json:"email" db:"email"`
+	PasswordHash      string    `json:"-" db:"password_hash"`
+	PasswordAlgorithm string    `json:"-" db:"password_algorithm"`
+	PasswordPepperID  string    `json:"-" db:"password_pepper_id"`
+	Status            string    `json:"status" db:"status"`
+	Role              string    `json:"role" db:"role"`
+	Ban               `json:"-"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Ban is an admin's ban on a user. A banned user cannot log in, refresh
+// tokens or use the tokens they hold.
+type Ban struct {
+	BannedAt    *time.Time `json:"banned_at,omitempty" db:"banned_at"`
+	BannedUntil *time.Time `json:"banned_until,omitempty" db:"banned_until"` // nil bans indefinitely
+	BanReason   string     `json:"reason,omitempty" db:"ban_reason"`
+}
+
+// Active reports whether the ban is in force at now
+func (b Ban) Active(now time.Time) bool {
+	return b.BannedAt != nil && (b.BannedUntil == nil || now.Before(*b.BannedUntil))
+}
+
+// TokenStatus is the account state that decides whether a user's access
+// tokens are accepted
+type TokenStatus struct {
+	TokensRevokedAt *time.Time `db:"tokens_revoked_at"`
+	Ban
 }
 
 // UserResponse is the API response for a user
@@ -27,11 +59,39 @@ type UserResponse struct {
 
 // UserInfo represents user information with online status
 type UserInfo struct {
-	ID           uuid.UUID `json:"user_id" db:"id"`
-	Username     string    `json:"username" db:"username"`
-	Status       string    `json:"-" db:"status"`
-	OnlineStatus bool      `json:"online_status"`
-	LastSeen     time.Time `json:"last_seen" db:"updated_at"`
+	ID           uuid.UUID     `json:"user_id" db:"id"`
+	Username     string        `json:"username" db:"username"`
+	DisplayName  string        `json:"display_name,omitempty" db:"display_name"`
+	Status       string        `json:"-" db:"status"`
+	OnlineStatus bool          `json:"online_status"`
+	CustomStatus *CustomStatus `json:"custom_status,omitempty"`
+	LastSeen     time.Time     `json:"last_seen" db:"updated_at"`
+}
+
+// CustomStatus is a user-chosen status message such as "In a meeting"
+type CustomStatus struct {
+	Text      string     `json:"text,omitempty"`
+	Emoji     string     `json:"emoji,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewCustomStatus returns the custom status stored for a user, or nil if
+// none is set or it has expired
+func NewCustomStatus(text, emoji string, expiresAt *time.Time, now time.Time) *CustomStatus {
+	if text == "" && emoji == "" {
+		return nil
+	}
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil
+	}
+	return &CustomStatus{Text: text, Emoji: emoji, ExpiresAt: expiresAt}
+}
+
+// CustomStatusRequest is the request body for setting a custom status
+type CustomStatusRequest struct {
+	Text      string     `json:"text" validate:"required_without=Emoji,max=100"`
+	Emoji     string     `json:"emoji" validate:"max=32"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
 // UserListResponse is the response for the user list endpoint
@@ -40,12 +100,36 @@ type UserListResponse struct {
 	Pagination Pagination `json:"pagination"`
 }
 
+// Message privacy settings, controlling who may start a conversation
+const (
+	PrivacyEveryone = "everyone"
+	PrivacyContacts = "contacts"
+	PrivacyNobody   = "nobody"
+)
+
+// PrivacySettings holds a user's messaging privacy settings. An omitted
+// ShowOnlineStatus is left unchanged on update.
+type PrivacySettings struct {
+	WhoCanMessage    string `json:"who_can_message" db:"message_privacy" validate:"required,oneof=everyone contacts nobody"`
+	ShowOnlineStatus *bool  `json:"show_online_status" db:"show_online_status"`
+}
+
+// UserSearchResponse is the response for the user search endpoint
+type UserSearchResponse struct {
+	Users []UserInfo `json:"users"`
+}
+
+// OnlineUsersResponse is the response for the online users endpoint
+type OnlineUsersResponse struct {
+	Users []UserInfo `json:"users"`
+}
+
 // Pagination contains pagination information
 type Pagination struct {
-	Total    int `json:"total"`
-	Page     int `json:"page"`
-	Limit    int `json:"limit"`
-	NextPage int `json:"next_page"`
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // Conversation represents a conversation in the API
@@ -54,49 +138,80 @@ type Conversation struct {
 	OtherUser      UserInfo `json:"other_user"`
 	LastMessage    Message  `json:"last_message"`
 	UnreadCount    int      `json:"unread_count"`
+	Muted          bool     `json:"muted"`           // left out of the unread badge
+	Pinned         bool     `json:"pinned"`          // listed after the notes, before the rest
+	Notes          bool     `json:"notes,omitempty"` // the user's notes to self, pinned first
 }
 
-// ConversationListResponse is the response for the conversation list endpoint
+// ConversationListResponse is the response for the conversation list
+// endpoint. UnreadTotal is the badge count, leaving out muted conversations.
 type ConversationListResponse struct {
 	Conversations []Conversation `json:"conversations"`
+	UnreadTotal   int            `json:"unread_total"`
 }
 
 // RegisterRequest is the request body for user registration
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
-	Username string `json:"username" validate:"required,min=3,max=50"`
+	Password string `json:"password" validate:"required"`
+	Username string `json:"username" validate:"required,min=3,max=50,username"`
+}
+
+// ChangePasswordRequest is the request body for changing a password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
 }
 
-// LoginRequest is the request body for user login
+// LoginRequest is the request body for user login. Tokens are issued for
+// the workspace if one is given.
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Email       string     `json:"email" validate:"required,email"`
+	Password    string     `json:"password" validate:"required"`
+	WorkspaceID *uuid.UUID `json:"workspace_id"`
 }
 
 // LoginResponse is the API response for a successful login
 type LoginResponse struct {
-	UserID       uuid.UUID `json:"user_id"`
-	Username     string    `json:"username"`
-	AccessToken  string    `json:"access_token"`
-	RefreshToken string    `json:"refresh_token"`
-	ExpiresAt    time.Time `json:"expires_at"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Username     string     `json:"username"`
+	DisplayName  string     `json:"display_name,omitempty"`
+	AccessToken  string     `json:"access_token"`
+	RefreshToken string     `json:"refresh_token"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	WorkspaceID  *uuid.UUID `json:"workspace_id,omitempty"`
 }
 
-// RefreshRequest is the request body for token refresh
+// RefreshRequest is the request body for token refresh. Giving a workspace
+// switches the session to it; otherwise the session keeps its workspace.
 type RefreshRequest struct {
-	RefreshToken string `json:"refresh_token" validate:"required"`
+	RefreshToken string     `json:"refresh_token" validate:"required"`
+	WorkspaceID  *uuid.UUID `json:"workspace_id"`
 }
 
 // RefreshResponse is the API response for a successful token refresh
 type RefreshResponse struct {
-	AccessToken  string    `json:"access_token"`
-	RefreshToken string    `json:"refresh_token"`
-	ExpiresAt    time.Time `json:"expires_at"`
+	AccessToken  string     `json:"access_token"`
+	RefreshToken string     `json:"refresh_token"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	WorkspaceID  *uuid.UUID `json:"workspace_id,omitempty"`
 }
 
 // ErrorResponse is the API response for errors
 type ErrorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code      errcode.Code         `json:"code"`
+	Error     string               `json:"error"`
+	Message   string               `json:"message"`
+	Details   []errcode.FieldError `json:"details,omitempty"`
+	RequestID string               `json:"request_id,omitempty"`
+}
+
+// NewErrorResponse creates an error response for a code
+func NewErrorResponse(code errcode.Code, message string, details ...errcode.FieldError) ErrorResponse {
+	return ErrorResponse{
+		Code:    code,
+		Error:   code.Name(),
+		Message: message,
+		Details: details,
+	}
 }
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+)
+
+// StructRules are the request rules that span several fields
+var StructRules = []validator.StructRule{
+	{Type: ChangePasswordRequest{}, Func: validateChangePassword},
+	{Type: CustomStatusRequest{}, Func: validateCustomStatus},
+}
+
+// validateChangePassword requires a new password that differs from the
+// current one
+func validateChangePassword(sl validator.StructLevel) {
+	req := sl.Current().Interface().(ChangePasswordRequest)
+	if req.NewPassword != "" && req.NewPassword == req.CurrentPassword {
+		sl.ReportError(req.NewPassword, "new_password", "NewPassword", "nefield", "current_password")
+	}
+}
+
+// validateCustomStatus requires text or an emoji that is not only
+// whitespace
+func validateCustomStatus(sl validator.StructLevel) {
+	req := sl.Current().Interface().(CustomStatusRequest)
+	if strings.TrimSpace(req.Text) == "" && strings.TrimSpace(req.Emoji) == "" {
+		sl.ReportError(req.Text, "text", "Text", "required_without", "emoji")
+	}
+}
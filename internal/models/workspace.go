@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Workspace member roles
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+)
+
+// Workspace is an isolated community. Access tokens issued for a workspace
+// only reach its members.
+type Workspace struct {
+	ID        uuid.UUID  `json:"workspace_id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Slug      string     `json:"slug" db:"slug"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	Role      string     `json:"role,omitempty" db:"role"` // the requesting user's role
+}
+
+// WorkspaceMember is a user's membership of a workspace
+type WorkspaceMember struct {
+	UserID   uuid.UUID `json:"user_id" db:"user_id"`
+	Username string    `json:"username" db:"username"`
+	Role     string    `json:"role" db:"role"`
+	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
+}
+
+// CreateWorkspaceRequest is the request body for creating a workspace. The
+// slug is lowercase letters and digits separated by single hyphens.
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+	Slug string `json:"slug" validate:"required,min=3,max=50"`
+}
+
+// WorkspaceMemberRequest is the request body for adding a workspace member
+// or changing their role
+type WorkspaceMemberRequest struct {
+	Role string `json:"role" validate:"omitempty,oneof=admin member"`
+}
+
+// WorkspaceListResponse is the response for the workspace list endpoint
+type WorkspaceListResponse struct {
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// WorkspaceMemberListResponse is the response for the workspace member list
+// endpoint
+type WorkspaceMemberListResponse struct {
+	Members []WorkspaceMember `json:"members"`
+}
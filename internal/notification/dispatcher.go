@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Sender delivers notifications on one channel, such as push or email
+type Sender interface {
+	Channel() string
+	Send(ctx context.Context, notification *models.Notification) error
+}
+
+// PreferenceStore loads users' notification preferences
+type PreferenceStore interface {
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error)
+}
+
+// Dispatcher delivers notifications to users who are not connected,
+// on every channel their preferences allow
+type Dispatcher struct {
+	preferences PreferenceStore
+	senders     []Sender
+	logger      logger.Logger
+}
+
+// NewDispatcher creates a dispatcher delivering through senders
+func NewDispatcher(preferences PreferenceStore, logger logger.Logger, senders ...Sender) *Dispatcher {
+	return &Dispatcher{
+		preferences: preferences,
+		senders:     senders,
+		logger:      logger,
+	}
+}
+
+// Dispatch sends a notification on each channel the recipient allows for
+// its event type and conversation. Delivery failures are logged, not returned.
+func (d *Dispatcher) Dispatch(ctx context.Context, notification *models.Notification) {
+	if len(d.senders) == 0 {
+		return
+	}
+
+	preferences, err := d.preferences.GetPreferences(ctx, notification.UserID)
+	if err != nil {
+		d.logger.WithContext(ctx).Error("Failed to load notification preferences", "error", err, "user_id", notification.UserID)
+		return
+	}
+
+	for _, sender := range d.senders {
+		if !preferences.Allows(sender.Channel(), notification.Type, notification.ConversationID) {
+			continue
+		}
+		if err := sender.Send(ctx, notification); err != nil {
+			d.logger.WithContext(ctx).Error("Failed to send notification",
+				"channel", sender.Channel(),
+				"type", notification.Type,
+				"user_id", notification.UserID,
+				"error", err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/conversation"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Handler handles notification preference HTTP requests
+type Handler struct {
+	service   Service
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new notification preference handler
+func NewHandler(service Service, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		service:   service,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// GetPreferences handles requests for the user's notification preferences
+func (h *Handler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	preferences, err := h.service.GetPreferences(r.Context(), userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "notification.get_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, preferences)
+}
+
+// UpdatePreferences handles requests to change channel and event preferences
+func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode notification preferences request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	// Call service
+	preferences, err := h.service.UpdatePreferences(r.Context(), userID, req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "notification.update_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, preferences)
+}
+
+// SetConversationOverride handles requests to turn notifications for a
+// conversation on or off, or to mute some or all of them
+func (h *Handler) SetConversationOverride(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.ConversationNotificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode conversation notification request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	preferences, err := h.service.SetConversationOverride(r.Context(), userID, mux.Vars(r)["conversation_id"], req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "notification.update_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, preferences)
+}
+
+// DeleteConversationOverride handles requests to remove a conversation override
+func (h *Handler) DeleteConversationOverride(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	if err := h.service.DeleteConversationOverride(r.Context(), userID, mux.Vars(r)["conversation_id"]); err != nil {
+		h.sendServiceError(w, r, err, "notification.update_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing or malformed
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// sendServiceError maps a service error to an HTTP error response, using
+// the message key for unexpected errors
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error, key string) {
+	switch {
+	case errors.Is(err, conversation.ErrInvalidConversationID):
+		sendError(w, r, errcode.InvalidConversation, i18n.T(r, "conversation.invalid_id"))
+	case errors.Is(err, ErrUnauthorized):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "conversation.not_participant"))
+	case errors.Is(err, ErrUnknownEvent):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "notification.unknown_event"))
+	case errors.Is(err, ErrOverrideNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "notification.override_not_found"))
+	default:
+		h.logger.WithContext(r.Context()).Error(i18n.English.T(key), "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, key))
+	}
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
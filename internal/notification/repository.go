@@ -0,0 +1,137 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrOverrideNotFound is returned when a conversation has no override
+var ErrOverrideNotFound = errors.New("conversation notification override not found")
+
+// Repository interface for notification preference operations
+type Repository interface {
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error)
+	SavePreferences(ctx context.Context, userID uuid.UUID, channels models.NotificationChannels, mutedEvents []string) error
+	SetConversationOverride(ctx context.Context, userID uuid.UUID, override models.ConversationNotificationOverride) error
+	DeleteConversationOverride(ctx context.Context, userID uuid.UUID, conversationID string) error
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// GetPreferences retrieves a user's preferences, falling back to the
+// defaults for users who never saved any
+func (r *PostgresRepository) GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error) {
+	preferences := models.DefaultNotificationPreferences()
+
+	query := `
+		SELECT push_enabled, email_enabled, web_push_enabled, sms_enabled, email_digest_enabled, muted_events
+		FROM notification_preferences
+		WHERE user_id = $1
+	`
+
+	var mutedEvents []string
+	err := r.conn(ctx).QueryRowContext(ctx, query, userID).Scan(
+		&preferences.Channels.Push,
+		&preferences.Channels.Email,
+		&preferences.Channels.WebPush,
+		&preferences.Channels.SMS,
+		&preferences.Channels.EmailDigest,
+		pq.Array(&mutedEvents),
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for _, event := range mutedEvents {
+		preferences.Events[event] = false
+	}
+
+	overridesQuery := `
+		SELECT conversation_id, enabled, mute
+		FROM conversation_notification_overrides
+		WHERE user_id = $1
+		ORDER BY conversation_id
+	`
+
+	var overrides []models.ConversationNotificationOverride
+	if err := r.conn(ctx).SelectContext(ctx, &overrides, overridesQuery, userID); err != nil {
+		return nil, err
+	}
+	if overrides != nil {
+		preferences.Conversations = overrides
+	}
+
+	return preferences, nil
+}
+
+// SavePreferences saves a user's channel and event preferences
+func (r *PostgresRepository) SavePreferences(ctx context.Context, userID uuid.UUID, channels models.NotificationChannels, mutedEvents []string) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, push_enabled, email_enabled, web_push_enabled, sms_enabled, email_digest_enabled, muted_events, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET push_enabled = EXCLUDED.push_enabled,
+			email_enabled = EXCLUDED.email_enabled,
+			web_push_enabled = EXCLUDED.web_push_enabled,
+			sms_enabled = EXCLUDED.sms_enabled,
+			email_digest_enabled = EXCLUDED.email_digest_enabled,
+			muted_events = EXCLUDED.muted_events,
+			updated_at = NOW()
+	`
+
+	if mutedEvents == nil {
+		mutedEvents = []string{}
+	}
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, channels.Push, channels.Email, channels.WebPush, channels.SMS, channels.EmailDigest, pq.Array(mutedEvents))
+	return err
+}
+
+// SetConversationOverride creates or replaces a conversation override
+func (r *PostgresRepository) SetConversationOverride(ctx context.Context, userID uuid.UUID, override models.ConversationNotificationOverride) error {
+	query := `
+		INSERT INTO conversation_notification_overrides (user_id, conversation_id, enabled, mute, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id, conversation_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, mute = EXCLUDED.mute, updated_at = NOW()
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, override.ConversationID, override.Enabled, override.Mute)
+	return err
+}
+
+// DeleteConversationOverride removes a conversation override
+func (r *PostgresRepository) DeleteConversationOverride(ctx context.Context, userID uuid.UUID, conversationID string) error {
+	query := "DELETE FROM conversation_notification_overrides WHERE user_id = $1 AND conversation_id = $2"
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, userID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOverrideNotFound
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package notification
+
+import (
+	"context"
+	"errors"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Service errors
+var (
+	ErrUnauthorized = errors.New("user not authorized to access this conversation")
+	ErrUnknownEvent = errors.New("unknown notification event type")
+)
+
+// ParticipantChecker reports whether a user takes part in a conversation
+type ParticipantChecker interface {
+	IsUserInConversation(ctx context.Context, conversationID string, userID uuid.UUID) (bool, error)
+}
+
+// Service handles notification preference business logic
+type Service interface {
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, userID uuid.UUID, req models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error)
+	SetConversationOverride(ctx context.Context, userID uuid.UUID, conversationID string, req models.ConversationNotificationRequest) (*models.NotificationPreferences, error)
+	DeleteConversationOverride(ctx context.Context, userID uuid.UUID, conversationID string) error
+}
+
+// PreferenceService implements Service interface
+type PreferenceService struct {
+	repo         Repository
+	participants ParticipantChecker
+	logger       logger.Logger
+}
+
+// NewPreferenceService creates a new notification preference service
+func NewPreferenceService(repo Repository, participants ParticipantChecker, logger logger.Logger) *PreferenceService {
+	return &PreferenceService{
+		repo:         repo,
+		participants: participants,
+		logger:       logger,
+	}
+}
+
+// GetPreferences returns the user's notification preferences
+func (s *PreferenceService) GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error) {
+	preferences, err := s.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get notification preferences", "error", err)
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences saves the user's channel and event preferences
+func (s *PreferenceService) UpdatePreferences(ctx context.Context, userID uuid.UUID, req models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	preferences, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for event, enabled := range req.Events {
+		if _, ok := preferences.Events[event]; !ok {
+			return nil, ErrUnknownEvent
+		}
+		preferences.Events[event] = enabled
+	}
+
+	var mutedEvents []string
+	for _, event := range models.NotificationEvents {
+		if !preferences.Events[event] {
+			mutedEvents = append(mutedEvents, event)
+		}
+	}
+
+	if err := s.repo.SavePreferences(ctx, userID, req.Channels, mutedEvents); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save notification preferences", "error", err)
+		return nil, err
+	}
+
+	preferences.Channels = req.Channels
+	return preferences, nil
+}
+
+// SetConversationOverride turns notifications for a conversation on or off,
+// or mutes some or all of them
+func (s *PreferenceService) SetConversationOverride(ctx context.Context, userID uuid.UUID, conversationID string, req models.ConversationNotificationRequest) (*models.NotificationPreferences, error) {
+	isParticipant, err := s.participants.IsUserInConversation(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isParticipant {
+		return nil, ErrUnauthorized
+	}
+
+	override := req.Override(conversationID)
+	if err := s.repo.SetConversationOverride(ctx, userID, override); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save conversation notification override", "error", err)
+		return nil, err
+	}
+
+	return s.GetPreferences(ctx, userID)
+}
+
+// DeleteConversationOverride makes a conversation follow the user's
+// event preferences again
+func (s *PreferenceService) DeleteConversationOverride(ctx context.Context, userID uuid.UUID, conversationID string) error {
+	err := s.repo.DeleteConversationOverride(ctx, userID, conversationID)
+	if err != nil && !errors.Is(err, ErrOverrideNotFound) {
+		s.logger.WithContext(ctx).Error("Failed to delete conversation notification override", "error", err)
+	}
+	return err
+}
@@ -0,0 +1,188 @@
+// Package pages renders the web client's HTML pages. Each page is an
+// html/template given the client's bootstrap data, so the frontend learns
+// the API and WebSocket endpoints, its CSRF token, the feature flags and
+// the signed-in user from the server instead of hard-coding them.
+package pages
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sync"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/token"
+	"github.com/google/uuid"
+)
+
+// AuthCookie is the cookie holding the web client's access token, which
+// pages use to tell who is signed in
+const AuthCookie = "auth_token"
+
+// CSRFCookie is the cookie holding the CSRF token given to pages, for
+// double-submit checks
+const CSRFCookie = "csrf_token"
+
+// Features evaluates feature flags
+type Features interface {
+	List() []models.FeatureFlag
+	Enabled(name string, userID uuid.UUID) bool
+}
+
+// Bootstrap is the data pages are rendered with. Templates embed it in a
+// script as {{.}}, which encodes it as JSON.
+type Bootstrap struct {
+	APIBase   string          `json:"api_base"`
+	WSPath    string          `json:"ws_path"`
+	CSRFToken string          `json:"csrf_token"`
+	Features  map[string]bool `json:"features"`
+	User      *BootstrapUser  `json:"user,omitempty"`
+}
+
+// BootstrapUser is the signed-in user, or nil on pages rendered without one
+type BootstrapUser struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	WorkspaceID string `json:"workspace_id,omitempty"`
+}
+
+// Renderer renders the pages in a file system's templates directory
+type Renderer struct {
+	assets   fs.FS
+	reload   bool
+	apiBase  string
+	wsPath   string
+	tokens   token.Maker
+	features Features
+	logger   logger.Logger
+
+	mu        sync.Mutex
+	templates map[string]*template.Template
+}
+
+// NewRenderer creates a renderer for the templates in assets. With reload
+// set, templates are parsed again on every request, for development.
+func NewRenderer(assets fs.FS, reload bool, apiBase, wsPath string, tokens token.Maker, features Features, logger logger.Logger) *Renderer {
+	return &Renderer{
+		assets:    assets,
+		reload:    reload,
+		apiBase:   apiBase,
+		wsPath:    wsPath,
+		tokens:    tokens,
+		features:  features,
+		logger:    logger,
+		templates: make(map[string]*template.Template),
+	}
+}
+
+// Page serves a template. Pages that require a user redirect visitors who
+// are not signed in to the login page.
+func (p *Renderer) Page(name string, requireUser bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := p.currentUser(r)
+		if requireUser && user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		tmpl, err := p.template(name)
+		if err != nil {
+			p.logger.WithContext(r.Context()).Error("Failed to parse page template", "template", name, "error", err)
+			http.Error(w, "Error rendering page", http.StatusInternalServerError)
+			return
+		}
+
+		data := &Bootstrap{
+			APIBase:   p.apiBase,
+			WSPath:    p.wsPath,
+			CSRFToken: csrfToken(w, r),
+			Features:  p.enabledFeatures(user),
+			User:      user,
+		}
+
+		// Render to a buffer so a failed render does not send half a page
+		var body bytes.Buffer
+		if err := tmpl.Execute(&body, data); err != nil {
+			p.logger.WithContext(r.Context()).Error("Failed to render page", "template", name, "error", err)
+			http.Error(w, "Error rendering page", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(body.Bytes())
+	}
+}
+
+// template returns a parsed template, parsing it on first use
+func (p *Renderer) template(name string) (*template.Template, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if tmpl, ok := p.templates[name]; ok && !p.reload {
+		return tmpl, nil
+	}
+	tmpl, err := template.ParseFS(p.assets, path.Join("templates", name))
+	if err != nil {
+		return nil, err
+	}
+	p.templates[name] = tmpl
+	return tmpl, nil
+}
+
+// currentUser returns the user whose access token is in the request's auth
+// cookie, or nil if there is none or it is invalid
+func (p *Renderer) currentUser(r *http.Request) *BootstrapUser {
+	cookie, err := r.Cookie(AuthCookie)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	payload, err := p.tokens.VerifyToken(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	return &BootstrapUser{ID: payload.UserID, Username: payload.Username, WorkspaceID: payload.WorkspaceID}
+}
+
+// enabledFeatures evaluates every feature flag for a user. Visitors who are
+// not signed in get the flags enabled for everyone.
+func (p *Renderer) enabledFeatures(user *BootstrapUser) map[string]bool {
+	userID := uuid.Nil
+	if user != nil {
+		userID, _ = uuid.Parse(user.ID)
+	}
+
+	enabled := make(map[string]bool)
+	for _, flag := range p.features.List() {
+		enabled[flag.Name] = p.features.Enabled(flag.Name, userID)
+	}
+	return enabled
+}
+
+// csrfToken returns the request's CSRF token, issuing one in a cookie if it
+// has none
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(CSRFCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	value := base64.RawURLEncoding.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookie,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return value
+}
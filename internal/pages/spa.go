@@ -0,0 +1,77 @@
+package pages
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// spaIndex is the entry point of a single-page app
+const spaIndex = "index.html"
+
+// SPA serves a prebuilt single-page app. Paths that name a file serve the
+// file; other paths without an extension serve index.html, so the app's
+// client-side routes work when loaded directly. Files under the immutable
+// prefixes are fingerprinted by the bundler and cached for a year; the
+// index is always revalidated so deploys take effect.
+func SPA(files fs.FS, immutablePrefixes []string) http.Handler {
+	fileServer := http.FileServer(http.FS(files))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" {
+			name = spaIndex
+		}
+
+		info, err := fs.Stat(files, name)
+		switch {
+		case err == nil && !info.IsDir() && name != spaIndex:
+			if immutable(r.URL.Path, immutablePrefixes) {
+				w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			}
+			fileServer.ServeHTTP(w, r)
+			return
+		case err != nil && !errors.Is(err, fs.ErrNotExist):
+			http.Error(w, "Error reading file", http.StatusInternalServerError)
+			return
+		case path.Ext(name) != "" && name != spaIndex:
+			// A missing asset, not a client-side route
+			http.NotFound(w, r)
+			return
+		}
+
+		serveIndex(w, r, files)
+	})
+}
+
+// serveIndex serves the app's index.html
+func serveIndex(w http.ResponseWriter, r *http.Request, files fs.FS) {
+	file, err := files.Open(spaIndex)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	content, ok := file.(io.ReadSeeker)
+	if err != nil || !ok {
+		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, spaIndex, info.ModTime(), content)
+}
+
+// immutable reports whether a path is under one of the immutable prefixes
+func immutable(urlPath string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(urlPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
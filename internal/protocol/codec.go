@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrMissingType is returned when parsing a message without a type
+var ErrMissingType = errors.New("message has no type")
+
+// Marshal encodes a message for sending
+func Marshal(messageType MessageType, data interface{}, requestID string) ([]byte, error) {
+	return json.Marshal(&Message{Type: messageType, Data: data, RequestID: requestID})
+}
+
+// Unmarshal parses a received message. Its data is left as decoded JSON
+// for Decode to read into the payload for its type.
+func Unmarshal(raw []byte) (*Message, error) {
+	var message Message
+	if err := json.Unmarshal(raw, &message); err != nil {
+		return nil, err
+	}
+	if message.Type == "" {
+		return nil, ErrMissingType
+	}
+	return &message, nil
+}
+
+// Decode reads a message's data into a payload and, if the payload has
+// validate tags, checks it against them. Validation errors list the invalid
+// fields.
+func Decode(message *Message, payload interface{}) error {
+	data, err := json.Marshal(message.Data)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, payload); err != nil {
+		return err
+	}
+	return validate.Validate(payload)
+}
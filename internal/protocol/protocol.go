@@ -0,0 +1,122 @@
+// Package protocol defines the WebSocket protocol shared by the server and
+// Go clients: the message envelope, the message types and the schemas of
+// the messages clients send. Errors are sent as error messages carrying the
+// errcode.Code values shared with the HTTP API.
+package protocol
+
+// MessageType identifies the kind of a WebSocket message
+type MessageType string
+
+// Message types sent by clients
+const (
+	TypeDirectMessage   MessageType = "direct_message"
+	TypeTypingIndicator MessageType = "typing_indicator"
+	TypeReadReceipt     MessageType = "read_receipt"
+	TypePresence        MessageType = "presence"
+	TypeCallOffer       MessageType = "call_offer"
+	TypeCallAnswer      MessageType = "call_answer"
+	TypeICECandidate    MessageType = "ice_candidate"
+	TypeCallEnd         MessageType = "call_end"
+	TypeRefreshAuth     MessageType = "refresh_auth"
+	TypeHello           MessageType = "hello"
+
+	TypePresenceSubscribe MessageType = "presence_subscribe"
+)
+
+// Message types sent by the server. Typing indicators, read receipts, direct
+// messages and call signaling are also relayed with the client types above.
+const (
+	TypeError                   MessageType = "error"
+	TypeMessageAck              MessageType = "message_ack"
+	TypeMention                 MessageType = "mention"
+	TypeConversationUpdated     MessageType = "conversation_updated"
+	TypeConversationCleared     MessageType = "conversation_cleared"
+	TypeDraftUpdated            MessageType = "draft_updated"
+	TypeAttachmentUploaded      MessageType = "attachment_uploaded"
+	TypePresenceUpdate          MessageType = "presence_update"
+	TypeCallState               MessageType = "call_state"
+	TypeReauthRequired          MessageType = "reauth_required"
+	TypeAuthRefreshed           MessageType = "auth_refreshed"
+	TypeConnectionQuality       MessageType = "connection_quality"
+	TypeSecurityAlert           MessageType = "security_alert"
+	TypeSystemAnnouncement      MessageType = "system_announcement"
+	TypeContactRequest          MessageType = "contact_request"
+	TypeContactRequestUpdated   MessageType = "contact_request_updated"
+	TypeGroupMessage            MessageType = "group_message"
+	TypeGroupMessageStatus      MessageType = "group_message_status"
+	TypeGroupJoinRequest        MessageType = "group_join_request"
+	TypeGroupJoinRequestUpdated MessageType = "group_join_request_updated"
+	TypeMemberAdded             MessageType = "member_added"
+	TypeMemberRemoved           MessageType = "member_removed"
+	TypeMemberLeft              MessageType = "member_left"
+	TypeForceUpgrade            MessageType = "force_upgrade"
+	TypeSystemMessage           MessageType = "system_message"
+
+	TypeConversationSettingsUpdated MessageType = "conversation_settings_updated"
+	TypeReadSync                    MessageType = "read_sync"
+	TypeMessageStatus               MessageType = "message_status"
+)
+
+// Features clients declare support for when they connect. Clients that
+// declare features are only sent messages and formats they support;
+// clients that declare none are treated as supporting everything except
+// connection_quality messages, which are always opt-in.
+const (
+	FeatureCalls             = "calls"
+	FeatureMarkdown          = "markdown"
+	FeatureConnectionQuality = "connection_quality"
+)
+
+// requiredFeatures maps the server message types that need a feature to it
+var requiredFeatures = map[MessageType]string{
+	TypeCallOffer: FeatureCalls,
+}
+
+// RequiredFeature returns the feature a client must support to be sent
+// messages of a type, or "" if every client can be sent them
+func RequiredFeature(messageType MessageType) string {
+	return requiredFeatures[messageType]
+}
+
+// TypeUnknown is the original message type reported in errors about
+// messages that could not be parsed
+const TypeUnknown MessageType = "unknown"
+
+// durableTypes are the server message types kept in users' inboxes, so
+// devices that were offline can catch up on them. Other messages, such as
+// typing indicators and call signaling, only matter while they happen.
+var durableTypes = map[MessageType]bool{
+	TypeDirectMessage:         true,
+	TypeReadReceipt:           true,
+	TypeMention:               true,
+	TypeConversationUpdated:   true,
+	TypeConversationCleared:   true,
+	TypeContactRequest:        true,
+	TypeContactRequestUpdated: true,
+	TypeGroupMessage:          true,
+	TypeGroupMessageStatus:    true,
+	TypeMemberAdded:           true,
+	TypeMemberRemoved:         true,
+	TypeMemberLeft:            true,
+	TypeSystemMessage:         true,
+
+	TypeConversationSettingsUpdated: true,
+	TypeReadSync:                    true,
+	TypeMessageStatus:               true,
+}
+
+// Durable reports whether messages of a type are kept in users' inboxes
+func Durable(messageType MessageType) bool {
+	return durableTypes[messageType]
+}
+
+// Message is the envelope of every WebSocket message. Data is the payload
+// for the type; RequestID correlates responses and errors with requests.
+// Seq is the message's position in the recipient's inbox, set on durable
+// messages so clients can acknowledge them.
+type Message struct {
+	Type      MessageType `json:"type"`
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"request_id,omitempty"`
+	Seq       int64       `json:"seq,omitempty"`
+}
@@ -0,0 +1,117 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+)
+
+// validate checks payloads against their validate tags
+var validate = validator.NewCustomValidator()
+
+// DirectMessage is the payload of a direct_message sent by a client.
+// Content is checked by the server's message validator, and a malformed
+// recipient ID is reported as an invalid recipient.
+type DirectMessage struct {
+	RecipientID string `json:"recipient_id" validate:"required"`
+	Content     string `json:"content"`
+	MessageID   string `json:"message_id" validate:"required"` // chosen by the client
+	Format      string `json:"format,omitempty"`
+}
+
+// TypingIndicator is the payload of a typing_indicator sent by a client
+type TypingIndicator struct {
+	RecipientID string `json:"recipient_id" validate:"required"`
+	Status      string `json:"status" validate:"required"`
+}
+
+// ReadReceipt is the payload of a read_receipt sent by a client
+type ReadReceipt struct {
+	ConversationID    string `json:"conversation_id" validate:"required,conversation_id"`
+	LastReadMessageID string `json:"last_read_message_id" validate:"required"`
+}
+
+// Presence is the payload of a presence update sent by a client
+type Presence struct {
+	Status string `json:"status" validate:"required,oneof=online away offline"`
+}
+
+// PresenceSubscribe is the payload of a presence_subscribe sent by a client
+// to follow the presence of users, such as those of its open conversations
+// and contacts. It replaces the connection's earlier subscription.
+type PresenceSubscribe struct {
+	UserIDs []string `json:"user_ids" validate:"max=200,dive,uuid"`
+}
+
+// CallOffer is the payload of a call_offer sent by a caller. Media
+// defaults to audio.
+type CallOffer struct {
+	RecipientID string `json:"recipient_id" validate:"required"`
+	Media       string `json:"media,omitempty" validate:"omitempty,oneof=audio video"`
+	SDP         string `json:"sdp" validate:"required"`
+}
+
+// CallAnswer is the payload of a call_answer sent by a callee
+type CallAnswer struct {
+	CallID string `json:"call_id" validate:"required"`
+	SDP    string `json:"sdp" validate:"required"`
+}
+
+// ICECandidate is the payload of an ice_candidate sent by a participant.
+// The candidate is relayed to the other participant as is.
+type ICECandidate struct {
+	CallID    string          `json:"call_id" validate:"required"`
+	Candidate json.RawMessage `json:"candidate" validate:"required"`
+}
+
+// CallEnd is the payload of a call_end sent by a participant
+type CallEnd struct {
+	CallID string `json:"call_id" validate:"required"`
+}
+
+// RefreshAuth is the payload of a refresh_auth sent by a client to replace
+// the connection's access token before it expires
+type RefreshAuth struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// Hello is the payload of a hello a client may send as its first message,
+// instead of giving its platform, version and features when connecting
+type Hello struct {
+	Platform   string   `json:"platform" validate:"max=32"`
+	AppVersion string   `json:"app_version" validate:"max=32"`
+	Features   []string `json:"features" validate:"max=32,dive,max=64"`
+}
+
+// schemas returns an empty payload for each message type clients send
+var schemas = map[MessageType]func() interface{}{
+	TypeDirectMessage:   func() interface{} { return &DirectMessage{} },
+	TypeTypingIndicator: func() interface{} { return &TypingIndicator{} },
+	TypeReadReceipt:     func() interface{} { return &ReadReceipt{} },
+	TypePresence:        func() interface{} { return &Presence{} },
+	TypeCallOffer:       func() interface{} { return &CallOffer{} },
+	TypeCallAnswer:      func() interface{} { return &CallAnswer{} },
+	TypeICECandidate:    func() interface{} { return &ICECandidate{} },
+	TypeCallEnd:         func() interface{} { return &CallEnd{} },
+	TypeRefreshAuth:     func() interface{} { return &RefreshAuth{} },
+	TypeHello:           func() interface{} { return &Hello{} },
+
+	TypePresenceSubscribe: func() interface{} { return &PresenceSubscribe{} },
+}
+
+// ClientType reports whether clients may send messages of a type
+func ClientType(messageType MessageType) bool {
+	_, ok := schemas[messageType]
+	return ok
+}
+
+// Validate checks a message a client sends against the schema for its
+// type, so clients can catch mistakes before sending
+func Validate(message *Message) error {
+	schema, ok := schemas[message.Type]
+	if !ok {
+		return fmt.Errorf("clients cannot send %q messages", message.Type)
+	}
+	return Decode(message, schema())
+}
@@ -0,0 +1,309 @@
+package sms
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+)
+
+// Handler handles phone number HTTP requests
+type Handler struct {
+	service   *Service
+	login     *LoginService // nil when phone login is disabled
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new phone number handler
+func NewHandler(service *Service, login *LoginService, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		service:   service,
+		login:     login,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// GetPhone handles requests for the user's phone number
+func (h *Handler) GetPhone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	phone, err := h.service.GetPhone(r.Context(), userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "phone.get_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, phone)
+}
+
+// SetPhone handles requests to text a verification code to a new phone
+// number
+func (h *Handler) SetPhone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.PhoneNumberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode phone number request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	if err := h.service.StartVerification(r.Context(), userID, req.PhoneNumber); err != nil {
+		h.sendServiceError(w, r, err, "phone.send_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// VerifyPhone handles requests to confirm a phone number with the code
+// texted to it
+func (h *Handler) VerifyPhone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.VerifyPhoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode phone verification request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	phone, err := h.service.VerifyPhone(r.Context(), userID, req.Code)
+	if err != nil {
+		h.sendServiceError(w, r, err, "phone.verify_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, phone)
+}
+
+// RemovePhone handles requests to remove the user's phone number
+func (h *Handler) RemovePhone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	if err := h.service.RemovePhone(r.Context(), userID); err != nil {
+		h.sendServiceError(w, r, err, "phone.remove_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendLoginCode handles requests to text a code for signing up or logging
+// in to a phone number
+func (h *Handler) SendLoginCode(w http.ResponseWriter, r *http.Request) {
+	if h.login == nil {
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "phone.login_disabled"))
+		return
+	}
+
+	// Parse and validate request
+	var req models.PhoneNumberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode login code request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	if err := h.login.SendLoginCode(r.Context(), req.PhoneNumber); err != nil {
+		h.sendServiceError(w, r, err, "phone.send_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Register handles requests to sign up with a code texted to a phone number
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	if h.login == nil {
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "phone.login_disabled"))
+		return
+	}
+
+	// Parse and validate request
+	var req models.PhoneRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode phone register request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	userAgent, clientIP := clientInfo(r)
+	resp, err := h.login.Register(r.Context(), &req, userAgent, clientIP)
+	if err != nil {
+		h.sendServiceError(w, r, err, "auth.register_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusCreated, resp)
+}
+
+// Login handles requests to log in with a code texted to the account's
+// phone number
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	if h.login == nil {
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "phone.login_disabled"))
+		return
+	}
+
+	// Parse and validate request
+	var req models.PhoneLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode phone login request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	userAgent, clientIP := clientInfo(r)
+	resp, err := h.login.Login(r.Context(), &req, userAgent, clientIP)
+	if err != nil {
+		h.sendServiceError(w, r, err, "auth.login_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// clientInfo returns the request's user agent and client IP
+func clientInfo(r *http.Request) (string, string) {
+	return r.UserAgent(), auth.ClientIP(r)
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// sendServiceError maps a service error to an error response, falling back
+// to the message under key
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error, key string) {
+	switch {
+	case errors.Is(err, ErrDisabled):
+		sendError(w, r, errcode.FeatureDisabled, i18n.T(r, "phone.sms_disabled"))
+	case errors.Is(err, ErrCodeRecentlySent):
+		sendError(w, r, errcode.RateLimited, i18n.T(r, "phone.code_recently_sent"))
+	case errors.Is(err, ErrInvalidNumber):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "phone.invalid_number"))
+	case errors.Is(err, ErrNoVerification):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "phone.no_verification"))
+	case errors.Is(err, ErrInvalidCode):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "phone.invalid_code"))
+	case errors.Is(err, ErrTooManyAttempts):
+		sendError(w, r, errcode.RateLimited, i18n.T(r, "phone.too_many_attempts"))
+	case errors.Is(err, ErrPhoneInUse):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "phone.in_use"))
+	case errors.Is(err, ErrPhoneNotRegistered):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "phone.not_registered"))
+	case errors.Is(err, auth.ErrUsernameTaken):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "auth.username_taken"))
+	case errors.Is(err, auth.ErrDirectoryManaged):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.directory_managed"))
+	case errors.Is(err, auth.ErrUserBanned):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.banned"))
+	case errors.Is(err, auth.ErrTooManySessions):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "auth.too_many_sessions"))
+	default:
+		h.logger.WithContext(r.Context()).Error(i18n.English.T(key), "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, key))
+	}
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
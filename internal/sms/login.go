@@ -0,0 +1,177 @@
+package sms
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// ErrPhoneNotRegistered is returned when logging in with a phone number no
+// account has verified
+var ErrPhoneNotRegistered = errors.New("no account is registered with the phone number")
+
+// Accounts creates accounts and starts sessions for users who sign in with
+// a phone number
+type Accounts interface {
+	RegisterPhoneUser(ctx context.Context, username string) (*models.User, error)
+	LoginUser(ctx context.Context, userID uuid.UUID, userAgent, clientIP string) (*models.LoginResponse, error)
+}
+
+// LoginService signs users up and logs them in with codes texted to their
+// phone numbers, as an alternative to an email and password
+type LoginService struct {
+	repo     Repository
+	uow      database.UnitOfWork
+	provider Provider
+	accounts Accounts
+	config   configs.SMSConfig
+	logger   logger.Logger
+}
+
+// NewLoginService creates a phone number login service. The provider must
+// not be nil.
+func NewLoginService(repo Repository, uow database.UnitOfWork, provider Provider, accounts Accounts, config configs.SMSConfig, logger logger.Logger) *LoginService {
+	return &LoginService{
+		repo:     repo,
+		uow:      uow,
+		provider: provider,
+		accounts: accounts,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// SendLoginCode texts a code for signing up or logging in to a phone
+// number. The code is sent whether or not an account has the number, so
+// the response does not reveal which numbers are registered.
+func (s *LoginService) SendLoginCode(ctx context.Context, phoneNumber string) error {
+	existing, err := s.repo.GetLoginCode(ctx, phoneNumber)
+	if err != nil && !errors.Is(err, ErrLoginCodeNotFound) {
+		return err
+	}
+	now := time.Now()
+	if existing != nil && now.Sub(existing.CreatedAt) < resendInterval {
+		return ErrCodeRecentlySent
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return err
+	}
+	loginCode := &LoginCode{
+		PhoneNumber: phoneNumber,
+		CodeHash:    hashCode(phoneNumber, code),
+		ExpiresAt:   now.Add(s.config.CodeExpiry),
+		CreatedAt:   now,
+	}
+	if err := s.repo.SaveLoginCode(ctx, loginCode); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save login code", "error", err)
+		return err
+	}
+
+	body := fmt.Sprintf("Your Whatsapp-Lite login code is %s", code)
+	if err := s.provider.Send(ctx, phoneNumber, body); err != nil {
+		if !errors.Is(err, ErrInvalidNumber) {
+			s.logger.WithContext(ctx).Error("Failed to send login code", "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Login logs in the user who verified the phone number the code was texted
+// to. The code is kept if no account has the number, so it can be used to
+// sign up.
+func (s *LoginService) Login(ctx context.Context, req *models.PhoneLoginRequest, userAgent, clientIP string) (*models.LoginResponse, error) {
+	if err := s.checkLoginCode(ctx, req.PhoneNumber, req.Code); err != nil {
+		return nil, err
+	}
+
+	userID, err := s.repo.GetUserIDByPhone(ctx, req.PhoneNumber)
+	if err != nil {
+		if errors.Is(err, ErrPhoneNotFound) {
+			return nil, ErrPhoneNotRegistered
+		}
+		return nil, err
+	}
+	if err := s.repo.DeleteLoginCode(ctx, req.PhoneNumber); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to delete login code", "error", err)
+		return nil, err
+	}
+
+	return s.accounts.LoginUser(ctx, userID, userAgent, clientIP)
+}
+
+// Register creates an account with the phone number the code was texted
+// to, already verified, and logs the new user in
+func (s *LoginService) Register(ctx context.Context, req *models.PhoneRegisterRequest, userAgent, clientIP string) (*models.LoginResponse, error) {
+	if err := s.checkLoginCode(ctx, req.PhoneNumber, req.Code); err != nil {
+		return nil, err
+	}
+
+	var userID uuid.UUID
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		user, err := s.accounts.RegisterPhoneUser(ctx, req.Username)
+		if err != nil {
+			return err
+		}
+		userID = user.ID
+
+		now := time.Now()
+		if err := s.repo.SetPhone(ctx, user.ID, &req.PhoneNumber, &now); err != nil {
+			return err
+		}
+		return s.repo.DeleteLoginCode(ctx, req.PhoneNumber)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrPhoneInUse) && !errors.Is(err, auth.ErrUsernameTaken) && !errors.Is(err, auth.ErrDirectoryManaged) {
+			s.logger.WithContext(ctx).Error("Failed to register phone user", "error", err)
+		}
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("User registered with phone number", "user_id", userID)
+	return s.accounts.LoginUser(ctx, userID, userAgent, clientIP)
+}
+
+// checkLoginCode checks a code texted by SendLoginCode, counting every
+// check against the number's attempts
+func (s *LoginService) checkLoginCode(ctx context.Context, phoneNumber, code string) error {
+	codeHash, err := s.repo.TakeLoginCodeAttempt(ctx, phoneNumber, maxAttempts)
+	if err != nil {
+		if errors.Is(err, ErrLoginCodeNotFound) {
+			return s.noLoginCodeError(ctx, phoneNumber)
+		}
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashCode(phoneNumber, code)), []byte(codeHash)) != 1 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// noLoginCodeError tells why no attempt could be taken on a phone number's
+// login code
+func (s *LoginService) noLoginCodeError(ctx context.Context, phoneNumber string) error {
+	loginCode, err := s.repo.GetLoginCode(ctx, phoneNumber)
+	if err != nil {
+		if errors.Is(err, ErrLoginCodeNotFound) {
+			return ErrNoVerification
+		}
+		return err
+	}
+	if time.Now().Before(loginCode.ExpiresAt) && loginCode.Attempts >= maxAttempts {
+		return ErrTooManyAttempts
+	}
+	return ErrNoVerification
+}
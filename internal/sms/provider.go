@@ -0,0 +1,58 @@
+// Package sms verifies users' phone numbers, lets them sign up and log in
+// with codes texted to them, and texts them about direct messages they
+// leave unread
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+)
+
+// SMS providers
+const (
+	ProviderTwilio = "twilio"
+	ProviderLog    = "log"
+)
+
+// ErrInvalidNumber is returned when the provider cannot deliver to a number
+var ErrInvalidNumber = errors.New("phone number cannot receive SMS")
+
+// Provider sends text messages
+type Provider interface {
+	// Send texts body to a phone number in E.164 format
+	Send(ctx context.Context, to, body string) error
+}
+
+// NewProvider creates the configured SMS provider, or nil if SMS is disabled
+func NewProvider(config configs.SMSConfig, logger logger.Logger) (Provider, error) {
+	switch config.Provider {
+	case "":
+		return nil, nil
+	case ProviderTwilio:
+		provider, err := NewTwilioProvider(config.Twilio, config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	case ProviderLog:
+		return &LogProvider{logger: logger}, nil
+	default:
+		return nil, fmt.Errorf("unknown sms provider: %q", config.Provider)
+	}
+}
+
+// LogProvider writes text messages to the log instead of sending them, for
+// development
+type LogProvider struct {
+	logger logger.Logger
+}
+
+// Send logs the message
+func (p *LogProvider) Send(ctx context.Context, to, body string) error {
+	p.logger.WithContext(ctx).Info("SMS", "to", to, "body", body)
+	return nil
+}
@@ -0,0 +1,336 @@
+package sms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Repository errors
+var (
+	ErrVerificationNotFound = errors.New("phone verification not found")
+	ErrLoginCodeNotFound    = errors.New("login code not found")
+	ErrPhoneNotFound        = errors.New("no account has the phone number")
+	ErrPhoneInUse           = errors.New("phone number is used by another account")
+)
+
+// uniqueViolation is the PostgreSQL error code for unique constraint
+// violations
+const uniqueViolation = "23505"
+
+// Verification is a code texted to a phone number to confirm the user
+// owns it
+type Verification struct {
+	UserID      uuid.UUID `db:"user_id"`
+	PhoneNumber string    `db:"phone_number"`
+	CodeHash    string    `db:"code_hash"`
+	Attempts    int       `db:"attempts"`
+	ExpiresAt   time.Time `db:"expires_at"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// LoginCode is a code texted to a phone number for signing up or logging
+// in with it
+type LoginCode struct {
+	PhoneNumber string    `db:"phone_number"`
+	CodeHash    string    `db:"code_hash"`
+	Attempts    int       `db:"attempts"`
+	ExpiresAt   time.Time `db:"expires_at"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// UnreadMessages summarizes the direct messages a user has left unread in
+// one conversation
+type UnreadMessages struct {
+	UserID         uuid.UUID `db:"user_id"`
+	PhoneNumber    string    `db:"phone_number"`
+	ConversationID string    `db:"conversation_id"`
+	SenderUsername string    `db:"sender_username"`
+	Count          int       `db:"count"`
+	LastSentAt     time.Time `db:"last_sent_at"`
+}
+
+// Repository interface for phone numbers and SMS notification operations
+type Repository interface {
+	GetPhone(ctx context.Context, userID uuid.UUID) (*models.PhoneNumber, error)
+	SaveVerification(ctx context.Context, verification *Verification) error
+	GetVerification(ctx context.Context, userID uuid.UUID) (*Verification, error)
+	TakeAttempt(ctx context.Context, userID uuid.UUID, maxAttempts int) (*Verification, error)
+	DeleteVerification(ctx context.Context, userID uuid.UUID) error
+	SetPhone(ctx context.Context, userID uuid.UUID, phoneNumber *string, verifiedAt *time.Time) error
+	GetUserIDByPhone(ctx context.Context, phoneNumber string) (uuid.UUID, error)
+	SaveLoginCode(ctx context.Context, code *LoginCode) error
+	GetLoginCode(ctx context.Context, phoneNumber string) (*LoginCode, error)
+	TakeLoginCodeAttempt(ctx context.Context, phoneNumber string, maxAttempts int) (string, error)
+	DeleteLoginCode(ctx context.Context, phoneNumber string) error
+	GetUnreadMessages(ctx context.Context, sentAfter, sentBefore time.Time) ([]UnreadMessages, error)
+	CountSentSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	RecordSent(ctx context.Context, userID uuid.UUID, messageCount int, sentAt time.Time) error
+	SetNotifiedThrough(ctx context.Context, userID uuid.UUID, through time.Time) error
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// GetPhone retrieves a user's verified phone number and the number awaiting
+// verification, if any
+func (r *PostgresRepository) GetPhone(ctx context.Context, userID uuid.UUID) (*models.PhoneNumber, error) {
+	query := `
+		SELECT COALESCE(u.phone_number, ''), u.phone_verified_at, COALESCE(v.phone_number, '')
+		FROM users u
+		LEFT JOIN phone_verifications v ON v.user_id = u.id AND v.expires_at > NOW()
+		WHERE u.id = $1
+	`
+
+	var phone models.PhoneNumber
+	err := r.conn(ctx).QueryRowContext(ctx, query, userID).Scan(&phone.PhoneNumber, &phone.VerifiedAt, &phone.Pending)
+	if err != nil {
+		return nil, err
+	}
+	return &phone, nil
+}
+
+// SaveVerification creates or replaces a user's pending verification
+func (r *PostgresRepository) SaveVerification(ctx context.Context, verification *Verification) error {
+	query := `
+		INSERT INTO phone_verifications (user_id, phone_number, code_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, 0, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET phone_number = EXCLUDED.phone_number,
+			code_hash = EXCLUDED.code_hash,
+			attempts = 0,
+			expires_at = EXCLUDED.expires_at,
+			created_at = EXCLUDED.created_at
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		verification.UserID,
+		verification.PhoneNumber,
+		verification.CodeHash,
+		verification.ExpiresAt,
+		verification.CreatedAt,
+	)
+	return err
+}
+
+// GetVerification retrieves a user's pending verification
+func (r *PostgresRepository) GetVerification(ctx context.Context, userID uuid.UUID) (*Verification, error) {
+	query := `
+		SELECT user_id, phone_number, code_hash, attempts, expires_at, created_at
+		FROM phone_verifications
+		WHERE user_id = $1
+	`
+
+	var verification Verification
+	if err := r.conn(ctx).GetContext(ctx, &verification, query, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVerificationNotFound
+		}
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// TakeAttempt counts an attempt against a user's pending verification and
+// returns it. It returns ErrVerificationNotFound if there is no unexpired
+// verification with attempts left, so concurrent guesses cannot use more
+// than maxAttempts between them.
+func (r *PostgresRepository) TakeAttempt(ctx context.Context, userID uuid.UUID, maxAttempts int) (*Verification, error) {
+	query := `
+		UPDATE phone_verifications
+		SET attempts = attempts + 1
+		WHERE user_id = $1 AND attempts < $2 AND expires_at > NOW()
+		RETURNING user_id, phone_number, code_hash, attempts, expires_at, created_at
+	`
+
+	var verification Verification
+	if err := r.conn(ctx).GetContext(ctx, &verification, query, userID, maxAttempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVerificationNotFound
+		}
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// DeleteVerification removes a user's pending verification
+func (r *PostgresRepository) DeleteVerification(ctx context.Context, userID uuid.UUID) error {
+	query := "DELETE FROM phone_verifications WHERE user_id = $1"
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID)
+	return err
+}
+
+// SetPhone sets or, with nil values, clears a user's verified phone number.
+// It returns ErrPhoneInUse if another account has verified the number.
+func (r *PostgresRepository) SetPhone(ctx context.Context, userID uuid.UUID, phoneNumber *string, verifiedAt *time.Time) error {
+	query := `
+		UPDATE users
+		SET phone_number = $2, phone_verified_at = $3, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, phoneNumber, verifiedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrPhoneInUse
+	}
+	return err
+}
+
+// GetUserIDByPhone retrieves the account that verified a phone number
+func (r *PostgresRepository) GetUserIDByPhone(ctx context.Context, phoneNumber string) (uuid.UUID, error) {
+	query := "SELECT id FROM users WHERE phone_number = $1 AND phone_verified_at IS NOT NULL"
+
+	var userID uuid.UUID
+	if err := r.conn(ctx).GetContext(ctx, &userID, query, phoneNumber); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrPhoneNotFound
+		}
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// SaveLoginCode creates or replaces the login code for a phone number
+func (r *PostgresRepository) SaveLoginCode(ctx context.Context, code *LoginCode) error {
+	query := `
+		INSERT INTO phone_login_codes (phone_number, code_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, 0, $3, $4)
+		ON CONFLICT (phone_number) DO UPDATE
+		SET code_hash = EXCLUDED.code_hash,
+			attempts = 0,
+			expires_at = EXCLUDED.expires_at,
+			created_at = EXCLUDED.created_at
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, code.PhoneNumber, code.CodeHash, code.ExpiresAt, code.CreatedAt)
+	return err
+}
+
+// GetLoginCode retrieves the login code for a phone number
+func (r *PostgresRepository) GetLoginCode(ctx context.Context, phoneNumber string) (*LoginCode, error) {
+	query := `
+		SELECT phone_number, code_hash, attempts, expires_at, created_at
+		FROM phone_login_codes
+		WHERE phone_number = $1
+	`
+
+	var code LoginCode
+	if err := r.conn(ctx).GetContext(ctx, &code, query, phoneNumber); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLoginCodeNotFound
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// TakeLoginCodeAttempt counts an attempt against a phone number's login
+// code and returns the code's hash. It returns ErrLoginCodeNotFound if
+// there is no unexpired code with attempts left, so concurrent guesses
+// cannot use more than maxAttempts between them.
+func (r *PostgresRepository) TakeLoginCodeAttempt(ctx context.Context, phoneNumber string, maxAttempts int) (string, error) {
+	query := `
+		UPDATE phone_login_codes
+		SET attempts = attempts + 1
+		WHERE phone_number = $1 AND attempts < $2 AND expires_at > NOW()
+		RETURNING code_hash
+	`
+
+	var codeHash string
+	if err := r.conn(ctx).GetContext(ctx, &codeHash, query, phoneNumber, maxAttempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrLoginCodeNotFound
+		}
+		return "", err
+	}
+	return codeHash, nil
+}
+
+// DeleteLoginCode removes the login code for a phone number
+func (r *PostgresRepository) DeleteLoginCode(ctx context.Context, phoneNumber string) error {
+	query := "DELETE FROM phone_login_codes WHERE phone_number = $1"
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, phoneNumber)
+	return err
+}
+
+// GetUnreadMessages summarizes, per recipient and conversation, the unread
+// direct messages sent in a window to users who opted in to SMS
+// notifications and verified a phone number. Messages up to a recipient's
+// sms_notified_through are left out.
+func (r *PostgresRepository) GetUnreadMessages(ctx context.Context, sentAfter, sentBefore time.Time) ([]UnreadMessages, error) {
+	query := `
+		SELECT
+			dm.recipient_id AS user_id,
+			u.phone_number,
+			LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text AS conversation_id,
+			s.username AS sender_username,
+			COUNT(*) AS count,
+			MAX(dm.created_at) AS last_sent_at
+		FROM direct_messages dm
+		JOIN notification_preferences np ON np.user_id = dm.recipient_id AND np.sms_enabled
+		JOIN users u ON u.id = dm.recipient_id AND u.phone_verified_at IS NOT NULL
+		JOIN users s ON s.id = dm.sender_id
+		LEFT JOIN read_cursors rc
+			ON rc.user_id = dm.recipient_id
+			AND rc.conversation_id = LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text
+		WHERE (rc.user_id IS NULL OR (dm.created_at, dm.id) > (rc.last_read_message_at, rc.last_read_message_id))
+		  AND dm.created_at > $1
+		  AND dm.created_at <= $2
+		  AND (np.sms_notified_through IS NULL OR dm.created_at > np.sms_notified_through)
+		GROUP BY dm.recipient_id, u.phone_number, conversation_id, s.username
+		ORDER BY dm.recipient_id, last_sent_at DESC
+	`
+
+	var unread []UnreadMessages
+	if err := r.conn(ctx).SelectContext(ctx, &unread, query, sentAfter, sentBefore); err != nil {
+		return nil, err
+	}
+	return unread, nil
+}
+
+// CountSentSince counts the SMS notifications sent to a user since a time
+func (r *PostgresRepository) CountSentSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	query := "SELECT COUNT(*) FROM sms_notifications WHERE user_id = $1 AND sent_at > $2"
+
+	var count int
+	err := r.conn(ctx).GetContext(ctx, &count, query, userID, since)
+	return count, err
+}
+
+// RecordSent records an SMS notification sent to a user
+func (r *PostgresRepository) RecordSent(ctx context.Context, userID uuid.UUID, messageCount int, sentAt time.Time) error {
+	query := "INSERT INTO sms_notifications (user_id, message_count, sent_at) VALUES ($1, $2, $3)"
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, messageCount, sentAt)
+	return err
+}
+
+// SetNotifiedThrough records the newest unread message that was considered
+// for an SMS to a user, so later runs skip it
+func (r *PostgresRepository) SetNotifiedThrough(ctx context.Context, userID uuid.UUID, through time.Time) error {
+	query := "UPDATE notification_preferences SET sms_notified_through = $2 WHERE user_id = $1"
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, through)
+	return err
+}
@@ -0,0 +1,316 @@
+package sms
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Phone verification limits
+const (
+	codeDigits     = 6
+	maxAttempts    = 5
+	resendInterval = time.Minute
+)
+
+// maxUnreadAge bounds how old an unread message can be and still trigger an
+// SMS, so turning SMS on does not text users about their whole backlog
+const maxUnreadAge = 24 * time.Hour
+
+// capWindow is the period the daily cap counts SMS notifications over
+const capWindow = 24 * time.Hour
+
+// Service errors
+var (
+	ErrDisabled         = errors.New("SMS is not configured")
+	ErrCodeRecentlySent = errors.New("a verification code was sent recently")
+	ErrNoVerification   = errors.New("no phone number is awaiting verification")
+	ErrInvalidCode      = errors.New("invalid verification code")
+	ErrTooManyAttempts  = errors.New("too many verification attempts")
+)
+
+// PreferenceStore loads users' notification preferences
+type PreferenceStore interface {
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*models.NotificationPreferences, error)
+}
+
+// Service verifies phone numbers and sends SMS notifications. With no
+// provider configured, phone numbers cannot be verified and nothing is sent.
+type Service struct {
+	repo        Repository
+	uow         database.UnitOfWork
+	provider    Provider
+	preferences PreferenceStore
+	config      configs.SMSConfig
+	logger      logger.Logger
+}
+
+// NewService creates a new SMS service
+func NewService(repo Repository, uow database.UnitOfWork, provider Provider, preferences PreferenceStore, config configs.SMSConfig, logger logger.Logger) *Service {
+	return &Service{
+		repo:        repo,
+		uow:         uow,
+		provider:    provider,
+		preferences: preferences,
+		config:      config,
+		logger:      logger,
+	}
+}
+
+// GetPhone returns the user's phone number
+func (s *Service) GetPhone(ctx context.Context, userID uuid.UUID) (*models.PhoneNumber, error) {
+	phone, err := s.repo.GetPhone(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get phone number", "error", err)
+		return nil, err
+	}
+	return phone, nil
+}
+
+// StartVerification texts a code to a phone number. The number replaces
+// the user's current one once they confirm the code.
+func (s *Service) StartVerification(ctx context.Context, userID uuid.UUID, phoneNumber string) error {
+	if s.provider == nil {
+		return ErrDisabled
+	}
+
+	existing, err := s.repo.GetVerification(ctx, userID)
+	if err != nil && !errors.Is(err, ErrVerificationNotFound) {
+		return err
+	}
+	now := time.Now()
+	if existing != nil && now.Sub(existing.CreatedAt) < resendInterval {
+		return ErrCodeRecentlySent
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return err
+	}
+	verification := &Verification{
+		UserID:      userID,
+		PhoneNumber: phoneNumber,
+		CodeHash:    hashCode(userID.String(), code),
+		ExpiresAt:   now.Add(s.config.CodeExpiry),
+		CreatedAt:   now,
+	}
+	if err := s.repo.SaveVerification(ctx, verification); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to save phone verification", "error", err)
+		return err
+	}
+
+	body := fmt.Sprintf("Your Whatsapp-Lite verification code is %s", code)
+	if err := s.provider.Send(ctx, phoneNumber, body); err != nil {
+		if !errors.Is(err, ErrInvalidNumber) {
+			s.logger.WithContext(ctx).Error("Failed to send verification code", "error", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// VerifyPhone checks a code texted by StartVerification and saves the
+// number it was sent to
+func (s *Service) VerifyPhone(ctx context.Context, userID uuid.UUID, code string) (*models.PhoneNumber, error) {
+	verification, err := s.repo.TakeAttempt(ctx, userID, maxAttempts)
+	if err != nil {
+		if errors.Is(err, ErrVerificationNotFound) {
+			return nil, s.noVerificationError(ctx, userID)
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashCode(userID.String(), code)), []byte(verification.CodeHash)) != 1 {
+		return nil, ErrInvalidCode
+	}
+
+	now := time.Now()
+
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.SetPhone(ctx, userID, &verification.PhoneNumber, &now); err != nil {
+			return err
+		}
+		return s.repo.DeleteVerification(ctx, userID)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrPhoneInUse) {
+			s.logger.WithContext(ctx).Error("Failed to save phone number", "error", err)
+		}
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Phone number verified", "user_id", userID)
+	return &models.PhoneNumber{PhoneNumber: verification.PhoneNumber, VerifiedAt: &now}, nil
+}
+
+// noVerificationError tells why no attempt could be taken on a user's
+// pending verification
+func (s *Service) noVerificationError(ctx context.Context, userID uuid.UUID) error {
+	verification, err := s.repo.GetVerification(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrVerificationNotFound) {
+			return ErrNoVerification
+		}
+		return err
+	}
+	if time.Now().Before(verification.ExpiresAt) && verification.Attempts >= maxAttempts {
+		return ErrTooManyAttempts
+	}
+	return ErrNoVerification
+}
+
+// RemovePhone removes the user's phone number and any pending verification
+func (s *Service) RemovePhone(ctx context.Context, userID uuid.UUID) error {
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.SetPhone(ctx, userID, nil, nil); err != nil {
+			return err
+		}
+		return s.repo.DeleteVerification(ctx, userID)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to remove phone number", "error", err)
+	}
+	return err
+}
+
+// SendUnreadNotifications texts users who opted in about direct messages
+// they have left unread for the fallback delay, at most once per message
+// and up to the daily cap. It returns the number of texts sent.
+//
+// The SMS is a fallback for users who cannot be reached by push. No push
+// tokens are registered yet, so every opted-in user with a verified number
+// qualifies; once push delivery exists, users with tokens should be skipped
+// here.
+func (s *Service) SendUnreadNotifications(ctx context.Context) (int, error) {
+	if s.provider == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	unread, err := s.repo.GetUnreadMessages(ctx, now.Add(-maxUnreadAge), now.Add(-s.config.FallbackDelay))
+	if err != nil {
+		return 0, err
+	}
+
+	// Rows are grouped by recipient
+	sent := 0
+	for start := 0; start < len(unread); {
+		end := start + 1
+		for end < len(unread) && unread[end].UserID == unread[start].UserID {
+			end++
+		}
+		ok, err := s.notifyUser(ctx, unread[start:end], now)
+		if err != nil {
+			return sent, err
+		}
+		if ok {
+			sent++
+		}
+		start = end
+	}
+	return sent, nil
+}
+
+// notifyUser texts a user about their unread conversations that their
+// preferences allow SMS for, and reports whether a text was sent. Send
+// failures other than an undeliverable number leave the messages to be
+// retried on the next run.
+func (s *Service) notifyUser(ctx context.Context, unread []UnreadMessages, now time.Time) (bool, error) {
+	userID := unread[0].UserID
+	through := unread[0].LastSentAt
+	for _, u := range unread {
+		if u.LastSentAt.After(through) {
+			through = u.LastSentAt
+		}
+	}
+
+	preferences, err := s.preferences.GetPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	var allowed []UnreadMessages
+	for _, u := range unread {
+		if preferences.Allows(models.ChannelSMS, models.NotificationDirectMessage, u.ConversationID) {
+			allowed = append(allowed, u)
+		}
+	}
+
+	notified := false
+	if len(allowed) > 0 {
+		count, err := s.repo.CountSentSince(ctx, userID, now.Add(-capWindow))
+		if err != nil {
+			return false, err
+		}
+		if count >= s.config.DailyCap {
+			s.logger.WithContext(ctx).Info("SMS daily cap reached", "user_id", userID)
+		} else {
+			body, messages := unreadSummary(allowed)
+			if err := s.provider.Send(ctx, unread[0].PhoneNumber, body); err != nil {
+				if !errors.Is(err, ErrInvalidNumber) {
+					s.logger.WithContext(ctx).Error("Failed to send SMS notification", "error", err, "user_id", userID)
+					return false, nil
+				}
+				s.logger.WithContext(ctx).Info("Phone number cannot receive SMS", "error", err, "user_id", userID)
+			} else {
+				if err := s.repo.RecordSent(ctx, userID, messages, now); err != nil {
+					return false, err
+				}
+				notified = true
+			}
+		}
+	}
+
+	return notified, s.repo.SetNotifiedThrough(ctx, userID, through)
+}
+
+// unreadSummary describes unread conversations, newest first, without
+// their content, and returns the number of messages
+func unreadSummary(unread []UnreadMessages) (string, int) {
+	messages := 0
+	senders := make(map[string]bool)
+	for _, u := range unread {
+		messages += u.Count
+		senders[u.SenderUsername] = true
+	}
+
+	noun := "message"
+	if messages > 1 {
+		noun = "messages"
+	}
+	from := unread[0].SenderUsername
+	switch others := len(senders) - 1; {
+	case others == 1:
+		from += " and 1 other"
+	case others > 1:
+		from += fmt.Sprintf(" and %d others", others)
+	}
+	return fmt.Sprintf("You have %d unread %s from %s on Whatsapp-Lite", messages, noun, from), messages
+}
+
+// newCode generates a random numeric verification code
+func newCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", codeDigits, n.Int64()), nil
+}
+
+// hashCode hashes a verification code for storage, salted with the user ID
+// or phone number it was issued for
+func hashCode(salt, code string) string {
+	sum := sha256.Sum256([]byte(salt + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
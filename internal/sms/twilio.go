@@ -0,0 +1,89 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+)
+
+// twilioBaseURL is the Twilio REST API
+const twilioBaseURL = "https://api.twilio.com/2010-04-01"
+
+// Twilio error codes for numbers that cannot receive messages
+var twilioInvalidNumberCodes = map[int]bool{
+	21211: true, // invalid To number
+	21408: true, // region not enabled
+	21610: true, // recipient unsubscribed
+	21614: true, // not a mobile number
+}
+
+// TwilioProvider sends text messages through Twilio Programmable Messaging
+type TwilioProvider struct {
+	client  *http.Client
+	config  configs.TwilioConfig
+	baseURL string
+}
+
+// NewTwilioProvider creates a Twilio provider
+func NewTwilioProvider(config configs.TwilioConfig, timeout time.Duration) (*TwilioProvider, error) {
+	if config.AccountSID == "" || config.AuthToken == "" {
+		return nil, errors.New("twilio requires an account SID and auth token")
+	}
+	if config.From == "" && config.MessagingServiceSID == "" {
+		return nil, errors.New("twilio requires a from number or messaging service SID")
+	}
+
+	return &TwilioProvider{
+		client:  &http.Client{Timeout: timeout},
+		config:  config,
+		baseURL: twilioBaseURL,
+	}, nil
+}
+
+// Send creates a message resource
+func (p *TwilioProvider) Send(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if p.config.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", p.config.MessagingServiceSID)
+	} else {
+		form.Set("From", p.config.From)
+	}
+
+	endpoint := p.baseURL + "/Accounts/" + url.PathEscape(p.config.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.config.AccountSID, p.config.AuthToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	var apiErr struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(detail, &apiErr) == nil && apiErr.Code != 0 {
+		if twilioInvalidNumberCodes[apiErr.Code] {
+			return fmt.Errorf("%w: twilio %d: %s", ErrInvalidNumber, apiErr.Code, apiErr.Message)
+		}
+		return fmt.Errorf("twilio: %s: %d: %s", resp.Status, apiErr.Code, apiErr.Message)
+	}
+	return fmt.Errorf("twilio: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+}
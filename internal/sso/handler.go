@@ -0,0 +1,202 @@
+package sso
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/gorilla/mux"
+)
+
+// stateCookie holds the state, nonce and PKCE verifier of a sign-in in
+// progress, binding the provider's callback to the browser that started it
+const (
+	stateCookie       = "sso_state"
+	stateCookieMaxAge = 600
+)
+
+// Handler handles single sign-on HTTP requests
+type Handler struct {
+	service *Service
+	logger  logger.Logger
+}
+
+// NewHandler creates a new single sign-on handler
+func NewHandler(service *Service, logger logger.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetProviders handles requests to list the configured identity providers
+func (h *Handler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	// Send response
+	sendJSON(w, http.StatusOK, h.service.GetProviders())
+}
+
+// Login handles requests to start signing in with an OpenID Connect
+// provider, redirecting to it
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["provider"]
+
+	// Random state, nonce and PKCE verifier
+	var secrets [3]string
+	for i := range secrets {
+		token, err := randomToken()
+		if err != nil {
+			h.sendServiceError(w, r, err)
+			return
+		}
+		secrets[i] = token
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	url, err := h.service.AuthCodeURL(r.Context(), name, state, nonce, verifier)
+	if err != nil {
+		h.sendServiceError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    strings.Join(secrets[:], "."),
+		Path:     "/auth/sso/" + name,
+		MaxAge:   stateCookieMaxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// Callback handles an OpenID Connect provider redirecting back after the
+// user signed in
+func (h *Handler) Callback(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["provider"]
+	query := r.URL.Query()
+
+	// The state must match the one this browser was sent off with
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil {
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "sso.invalid_state"))
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/auth/sso/" + name, MaxAge: -1})
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(query.Get("state"))) != 1 {
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "sso.invalid_state"))
+		return
+	}
+
+	if query.Get("error") != "" {
+		h.logger.WithContext(r.Context()).Info("Identity provider returned an error", "provider", name, "error", query.Get("error"))
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "sso.sign_in_failed"))
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	// Call service
+	userAgent, clientIP := clientInfo(r)
+	resp, err := h.service.CompleteOIDC(r.Context(), name, code, parts[2], parts[1], userAgent, clientIP)
+	if err != nil {
+		h.sendServiceError(w, r, err)
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// ACS handles SAML responses posted by an identity provider to the
+// assertion consumer service
+func (h *Handler) ACS(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["provider"]
+
+	samlResponse := r.PostFormValue("SAMLResponse")
+	if samlResponse == "" {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	// Call service
+	userAgent, clientIP := clientInfo(r)
+	resp, err := h.service.CompleteSAML(r.Context(), name, samlResponse, userAgent, clientIP)
+	if err != nil {
+		h.sendServiceError(w, r, err)
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// sendServiceError maps a service error to an HTTP error response
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrProviderNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "sso.provider_not_found"))
+	case errors.Is(err, ErrProviderUnavailable):
+		h.logger.WithContext(r.Context()).Error("Identity provider is unavailable", "error", err)
+		sendError(w, r, errcode.Unavailable, i18n.T(r, "sso.provider_unavailable"))
+	case errors.Is(err, ErrInvalidIDToken), errors.Is(err, ErrExchangeFailed), errors.Is(err, ErrInvalidAssertion):
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "sso.sign_in_failed"))
+	case errors.Is(err, ErrNotProvisioned):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "sso.not_provisioned"))
+	case errors.Is(err, ErrEmailInUse):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "sso.email_in_use"))
+	case errors.Is(err, auth.ErrUserBanned):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "auth.banned"))
+	case errors.Is(err, auth.ErrTooManySessions):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "auth.too_many_sessions"))
+	default:
+		h.logger.WithContext(r.Context()).Error("Failed to sign in with identity provider", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "auth.login_failed"))
+	}
+}
+
+// clientInfo returns the request's user agent and client IP
+func clientInfo(r *http.Request) (string, string) {
+	return r.UserAgent(), auth.ClientIP(r)
+}
+
+// randomToken returns a random URL-safe token
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
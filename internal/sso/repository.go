@@ -0,0 +1,78 @@
+package sso
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+)
+
+// Repository errors
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+)
+
+// Repository interface for linked identity operations
+type Repository interface {
+	GetIdentityUser(ctx context.Context, provider, subject string) (uuid.UUID, error)
+	LinkIdentity(ctx context.Context, provider, subject string, userID uuid.UUID) error
+	SetUserRole(ctx context.Context, userID uuid.UUID, role string) error
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// GetIdentityUser returns the user linked to a provider's subject
+func (r *PostgresRepository) GetIdentityUser(ctx context.Context, provider, subject string) (uuid.UUID, error) {
+	query := `
+		SELECT user_id FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+
+	var userID uuid.UUID
+	err := r.conn(ctx).GetContext(ctx, &userID, query, provider, subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrIdentityNotFound
+		}
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// LinkIdentity links a provider's subject to a user
+func (r *PostgresRepository) LinkIdentity(ctx context.Context, provider, subject string, userID uuid.UUID) error {
+	query := `
+		INSERT INTO user_identities (provider, subject, user_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, provider, subject, userID)
+	return err
+}
+
+// SetUserRole sets a user's role
+func (r *PostgresRepository) SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	query := `
+		UPDATE users
+		SET role = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, userID, role)
+	return err
+}
@@ -0,0 +1,259 @@
+package sso
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+)
+
+// SAML namespaces and values
+const (
+	nsSAMLProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsSAMLAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	statusSuccess   = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	bearerMethod    = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// clockSkew is how far the identity provider's clock may be off from ours
+const clockSkew = 2 * time.Minute
+
+// ErrInvalidAssertion is returned when a SAML response is not accepted
+var ErrInvalidAssertion = errors.New("invalid SAML assertion")
+
+// SAMLProvider accepts SAML 2.0 responses posted by an identity provider.
+// Assertions must be signed, either directly or through the response, with
+// the provider's certificate. Encrypted assertions are not supported.
+type SAMLProvider struct {
+	name   string
+	config configs.SAMLConfig
+	groups string
+	cert   *x509.Certificate
+
+	// Assertion IDs already used, kept until the assertions expire so they
+	// cannot be replayed on this instance
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewSAMLProvider creates a SAML provider, loading the identity provider's
+// certificate
+func NewSAMLProvider(name string, config configs.SAMLConfig, groupsAttribute string) (*SAMLProvider, error) {
+	if config.IdPEntityID == "" || config.IdPCertificate == "" || config.EntityID == "" {
+		return nil, fmt.Errorf("provider %q: idp_entity_id, idp_certificate and entity_id are required", name)
+	}
+	data, err := os.ReadFile(config.IdPCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("provider %q: %w", name, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("provider %q: %s does not hold a PEM certificate", name, config.IdPCertificate)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("provider %q: %w", name, err)
+	}
+
+	return &SAMLProvider{
+		name:   name,
+		config: config,
+		groups: groupsAttribute,
+		cert:   cert,
+		seen:   make(map[string]time.Time),
+	}, nil
+}
+
+// ParseResponse verifies a base64 encoded SAML response and returns the
+// identity its assertion carries
+func (p *SAMLProvider) ParseResponse(encoded string, now time.Time) (*Identity, error) {
+	data, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed encoding", ErrInvalidAssertion)
+	}
+	response, err := parseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+	if !response.is(nsSAMLProtocol, "Response") {
+		return nil, fmt.Errorf("%w: not a SAML response", ErrInvalidAssertion)
+	}
+
+	status := response.child(nsSAMLProtocol, "Status")
+	if status == nil {
+		return nil, fmt.Errorf("%w: missing status", ErrInvalidAssertion)
+	}
+	if code := status.child(nsSAMLProtocol, "StatusCode"); code == nil || code.attr("Value") != statusSuccess {
+		return nil, fmt.Errorf("%w: sign-in was not successful", ErrInvalidAssertion)
+	}
+	if dest := response.attr("Destination"); dest != "" && p.config.ACSURL != "" && dest != p.config.ACSURL {
+		return nil, fmt.Errorf("%w: wrong destination", ErrInvalidAssertion)
+	}
+
+	// Data is only read from the one assertion the signature covers
+	assertions := response.all(nsSAMLAssertion, "Assertion")
+	if len(assertions) != 1 {
+		if response.child(nsSAMLAssertion, "EncryptedAssertion") != nil {
+			return nil, fmt.Errorf("%w: encrypted assertions are not supported", ErrInvalidAssertion)
+		}
+		return nil, fmt.Errorf("%w: expected one assertion", ErrInvalidAssertion)
+	}
+	assertion := assertions[0]
+	if response.child(nsDSig, "Signature") != nil {
+		err = verifySignature(response, p.cert)
+	} else {
+		err = verifySignature(assertion, p.cert)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+
+	if issuer := assertion.child(nsSAMLAssertion, "Issuer"); issuer == nil || issuer.text() != p.config.IdPEntityID {
+		return nil, fmt.Errorf("%w: wrong issuer", ErrInvalidAssertion)
+	}
+	expires, err := p.checkConditions(assertion, now)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := assertion.child(nsSAMLAssertion, "Subject")
+	if subject == nil {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidAssertion)
+	}
+	if err := p.checkSubjectConfirmation(subject, now); err != nil {
+		return nil, err
+	}
+	nameID := subject.child(nsSAMLAssertion, "NameID")
+	if nameID == nil || nameID.text() == "" {
+		return nil, fmt.Errorf("%w: missing name ID", ErrInvalidAssertion)
+	}
+
+	if err := p.markUsed(assertion.attr("ID"), expires, now); err != nil {
+		return nil, err
+	}
+
+	attributes := samlAttributes(assertion)
+	identity := &Identity{
+		Provider: p.name,
+		Subject:  nameID.text(),
+		Groups:   attributes[p.groups],
+	}
+	// SAML carries no standard verified flag; without a configured
+	// attribute the email is not trusted
+	if p.config.EmailVerifiedAttribute != "" {
+		identity.EmailVerified = strings.EqualFold(first(attributes[p.config.EmailVerifiedAttribute]), "true")
+	}
+	if p.config.EmailAttribute != "" {
+		identity.Email = first(attributes[p.config.EmailAttribute])
+	} else if strings.Contains(nameID.text(), "@") {
+		identity.Email = nameID.text()
+	}
+	if p.config.UsernameAttribute != "" {
+		identity.Username = first(attributes[p.config.UsernameAttribute])
+	}
+	return identity, nil
+}
+
+// checkConditions checks an assertion's validity period and audience, and
+// returns when it expires
+func (p *SAMLProvider) checkConditions(assertion *element, now time.Time) (time.Time, error) {
+	conditions := assertion.child(nsSAMLAssertion, "Conditions")
+	if conditions == nil {
+		return time.Time{}, fmt.Errorf("%w: missing conditions", ErrInvalidAssertion)
+	}
+	if notBefore := conditions.attr("NotBefore"); notBefore != "" {
+		t, err := time.Parse(time.RFC3339, notBefore)
+		if err != nil || now.Add(clockSkew).Before(t) {
+			return time.Time{}, fmt.Errorf("%w: not valid yet", ErrInvalidAssertion)
+		}
+	}
+	expires, err := time.Parse(time.RFC3339, conditions.attr("NotOnOrAfter"))
+	if err != nil || !now.Add(-clockSkew).Before(expires) {
+		return time.Time{}, fmt.Errorf("%w: expired", ErrInvalidAssertion)
+	}
+
+	// Every audience restriction must name this service
+	for _, restriction := range conditions.all(nsSAMLAssertion, "AudienceRestriction") {
+		found := false
+		for _, audience := range restriction.all(nsSAMLAssertion, "Audience") {
+			if audience.text() == p.config.EntityID {
+				found = true
+			}
+		}
+		if !found {
+			return time.Time{}, fmt.Errorf("%w: wrong audience", ErrInvalidAssertion)
+		}
+	}
+	return expires, nil
+}
+
+// checkSubjectConfirmation requires a bearer confirmation that has not
+// expired and, when an ACS URL is configured, names it as the recipient
+func (p *SAMLProvider) checkSubjectConfirmation(subject *element, now time.Time) error {
+	for _, confirmation := range subject.all(nsSAMLAssertion, "SubjectConfirmation") {
+		if confirmation.attr("Method") != bearerMethod {
+			continue
+		}
+		data := confirmation.child(nsSAMLAssertion, "SubjectConfirmationData")
+		if data == nil {
+			continue
+		}
+		expires, err := time.Parse(time.RFC3339, data.attr("NotOnOrAfter"))
+		if err != nil || !now.Add(-clockSkew).Before(expires) {
+			continue
+		}
+		if p.config.ACSURL != "" && data.attr("Recipient") != p.config.ACSURL {
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: no valid subject confirmation", ErrInvalidAssertion)
+}
+
+// markUsed records an assertion ID, failing if it was used before
+func (p *SAMLProvider) markUsed(id string, expires, now time.Time) error {
+	if id == "" {
+		return fmt.Errorf("%w: missing ID", ErrInvalidAssertion)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for seen, until := range p.seen {
+		if now.After(until) {
+			delete(p.seen, seen)
+		}
+	}
+	if _, ok := p.seen[id]; ok {
+		return fmt.Errorf("%w: already used", ErrInvalidAssertion)
+	}
+	p.seen[id] = expires.Add(clockSkew)
+	return nil
+}
+
+// samlAttributes returns an assertion's attribute values by attribute name
+func samlAttributes(assertion *element) map[string][]string {
+	attributes := make(map[string][]string)
+	for _, statement := range assertion.all(nsSAMLAssertion, "AttributeStatement") {
+		for _, attr := range statement.all(nsSAMLAssertion, "Attribute") {
+			name := attr.attr("Name")
+			for _, value := range attr.all(nsSAMLAssertion, "AttributeValue") {
+				attributes[name] = append(attributes[name], value.text())
+			}
+		}
+	}
+	return attributes
+}
+
+// first returns the first value, or "" if there are none
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,269 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Provider types
+const (
+	TypeOIDC = "oidc"
+	TypeSAML = "saml"
+)
+
+// Service errors
+var (
+	ErrProviderNotFound = errors.New("identity provider not found")
+	ErrNotProvisioned   = errors.New("no account is linked to the identity")
+	ErrEmailInUse       = errors.New("an account with the email exists but cannot be linked")
+)
+
+// Identity is a user as asserted by an identity provider
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Groups        []string
+}
+
+// ProviderInfo describes a configured identity provider to clients
+type ProviderInfo struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	LoginURL string `json:"login_url,omitempty"`
+}
+
+// ProviderListResponse is the response for the provider list endpoint
+type ProviderListResponse struct {
+	Providers []ProviderInfo `json:"providers"`
+}
+
+// Users looks up accounts
+type Users interface {
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// Authenticator creates accounts and starts sessions for users who signed
+// in through a provider
+type Authenticator interface {
+	ProvisionUser(ctx context.Context, email, username string) (*models.User, error)
+	LoginUser(ctx context.Context, userID uuid.UUID, userAgent, clientIP string) (*models.LoginResponse, error)
+}
+
+// provider is a configured identity provider
+type provider struct {
+	config configs.SSOProviderConfig
+	oidc   *OIDCProvider
+	saml   *SAMLProvider
+}
+
+// Service signs users in through external identity providers
+type Service struct {
+	providers map[string]*provider
+	names     []string
+	repo      Repository
+	uow       database.UnitOfWork
+	users     Users
+	auth      Authenticator
+	logger    logger.Logger
+}
+
+// NewService creates a single sign-on service for the configured providers
+func NewService(config configs.SSOConfig, repo Repository, uow database.UnitOfWork, users Users, authenticator Authenticator, logger logger.Logger) (*Service, error) {
+	s := &Service{
+		providers: make(map[string]*provider, len(config.Providers)),
+		repo:      repo,
+		uow:       uow,
+		users:     users,
+		auth:      authenticator,
+		logger:    logger,
+	}
+
+	for _, pc := range config.Providers {
+		if pc.Name == "" || strings.ContainsAny(pc.Name, "/?#") {
+			return nil, fmt.Errorf("invalid provider name %q", pc.Name)
+		}
+		if _, ok := s.providers[pc.Name]; ok {
+			return nil, fmt.Errorf("duplicate provider %q", pc.Name)
+		}
+		for group, role := range pc.RoleMapping {
+			if role != models.RoleUser && role != models.RoleAdmin {
+				return nil, fmt.Errorf("provider %q: group %q maps to unknown role %q", pc.Name, group, role)
+			}
+		}
+
+		p := &provider{config: pc}
+		var err error
+		switch pc.Type {
+		case TypeOIDC:
+			p.oidc, err = NewOIDCProvider(pc.Name, pc.OIDC, pc.GroupsClaim, config.Timeout)
+		case TypeSAML:
+			p.saml, err = NewSAMLProvider(pc.Name, pc.SAML, pc.GroupsClaim)
+		default:
+			err = fmt.Errorf("provider %q: unknown type %q", pc.Name, pc.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.providers[pc.Name] = p
+		s.names = append(s.names, pc.Name)
+	}
+	sort.Strings(s.names)
+
+	return s, nil
+}
+
+// GetProviders lists the configured providers
+func (s *Service) GetProviders() *ProviderListResponse {
+	providers := make([]ProviderInfo, 0, len(s.names))
+	for _, name := range s.names {
+		info := ProviderInfo{Name: name, Type: s.providers[name].config.Type}
+		if info.Type == TypeOIDC {
+			info.LoginURL = "/auth/sso/" + name + "/login"
+		}
+		providers = append(providers, info)
+	}
+	return &ProviderListResponse{Providers: providers}
+}
+
+// AuthCodeURL returns the URL that starts a sign-in at an OpenID Connect
+// provider
+func (s *Service) AuthCodeURL(ctx context.Context, name, state, nonce, verifier string) (string, error) {
+	p, ok := s.providers[name]
+	if !ok || p.oidc == nil {
+		return "", ErrProviderNotFound
+	}
+	return p.oidc.AuthCodeURL(ctx, state, nonce, verifier)
+}
+
+// CompleteOIDC redeems an authorization code from an OpenID Connect
+// provider and signs the user in
+func (s *Service) CompleteOIDC(ctx context.Context, name, code, verifier, nonce, userAgent, clientIP string) (*models.LoginResponse, error) {
+	p, ok := s.providers[name]
+	if !ok || p.oidc == nil {
+		return nil, ErrProviderNotFound
+	}
+
+	identity, err := p.oidc.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		s.logger.WithContext(ctx).Info("OIDC sign-in failed", "provider", name, "error", err)
+		return nil, err
+	}
+	return s.signIn(ctx, p, identity, userAgent, clientIP)
+}
+
+// CompleteSAML verifies a SAML response posted by a provider and signs the
+// user in
+func (s *Service) CompleteSAML(ctx context.Context, name, samlResponse, userAgent, clientIP string) (*models.LoginResponse, error) {
+	p, ok := s.providers[name]
+	if !ok || p.saml == nil {
+		return nil, ErrProviderNotFound
+	}
+
+	identity, err := p.saml.ParseResponse(samlResponse, time.Now())
+	if err != nil {
+		s.logger.WithContext(ctx).Info("SAML sign-in failed", "provider", name, "error", err)
+		return nil, err
+	}
+	return s.signIn(ctx, p, identity, userAgent, clientIP)
+}
+
+// signIn finds or provisions the account linked to an identity, applies
+// the provider's role mapping and starts a session
+func (s *Service) signIn(ctx context.Context, p *provider, identity *Identity, userAgent, clientIP string) (*models.LoginResponse, error) {
+	var userID uuid.UUID
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		userID, err = s.linkedUser(ctx, p, identity)
+		if err != nil {
+			return err
+		}
+		return s.syncRole(ctx, p, userID, identity.Groups)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotProvisioned) && !errors.Is(err, ErrEmailInUse) {
+			s.logger.WithContext(ctx).Error("Failed to link identity", "error", err, "provider", identity.Provider)
+		}
+		return nil, err
+	}
+
+	return s.auth.LoginUser(ctx, userID, userAgent, clientIP)
+}
+
+// linkedUser returns the account linked to an identity. An unlinked
+// identity is linked to the account with its email if the provider is
+// trusted to link accounts and the email is verified, or to a new account
+// if the provider provisions users.
+func (s *Service) linkedUser(ctx context.Context, p *provider, identity *Identity) (uuid.UUID, error) {
+	userID, err := s.repo.GetIdentityUser(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return userID, nil
+	}
+	if !errors.Is(err, ErrIdentityNotFound) {
+		return uuid.Nil, err
+	}
+
+	if identity.Email != "" {
+		user, err := s.users.GetUserByEmail(ctx, identity.Email)
+		switch {
+		case err == nil:
+			if !p.config.LinkByEmail || !identity.EmailVerified {
+				return uuid.Nil, ErrEmailInUse
+			}
+			s.logger.WithContext(ctx).Info("Linking identity to existing account", "provider", identity.Provider, "user_id", user.ID)
+			return user.ID, s.repo.LinkIdentity(ctx, identity.Provider, identity.Subject, user.ID)
+		case !errors.Is(err, auth.ErrUserNotFound):
+			return uuid.Nil, err
+		}
+	}
+
+	if !p.config.Provision || identity.Email == "" {
+		return uuid.Nil, ErrNotProvisioned
+	}
+	user, err := s.auth.ProvisionUser(ctx, identity.Email, identity.Username)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	s.logger.WithContext(ctx).Info("Provisioned account for identity", "provider", identity.Provider, "user_id", user.ID)
+	return user.ID, s.repo.LinkIdentity(ctx, identity.Provider, identity.Subject, user.ID)
+}
+
+// syncRole sets the role the user's groups map to, if the provider maps
+// roles
+func (s *Service) syncRole(ctx context.Context, p *provider, userID uuid.UUID, groups []string) error {
+	if len(p.config.RoleMapping) == 0 {
+		return nil
+	}
+
+	role := models.RoleUser
+	for _, group := range groups {
+		if p.config.RoleMapping[group] == models.RoleAdmin {
+			role = models.RoleAdmin
+			break
+		}
+	}
+
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+	s.logger.WithContext(ctx).Info("Updating role from identity provider groups", "user_id", userID, "role", role)
+	return s.repo.SetUserRole(ctx, userID, role)
+}
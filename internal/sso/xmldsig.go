@@ -0,0 +1,444 @@
+package sso
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// XML signature algorithms and namespaces
+const (
+	nsXML             = "http://www.w3.org/XML/1998/namespace"
+	nsDSig            = "http://www.w3.org/2000/09/xmldsig#"
+	algExcC14N        = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnveloped      = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algRSASHA256      = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algDigestSHA256   = "http://www.w3.org/2001/04/xmlenc#sha256"
+	maxDocumentDepth  = 64
+	maxDocumentLength = 1 << 20
+)
+
+// Signature errors
+var (
+	ErrUnsigned         = errors.New("element is not signed")
+	ErrInvalidSignature = errors.New("invalid XML signature")
+)
+
+// element is a parsed XML element. Prefixes and namespace declarations are
+// kept as written so the element can be canonicalized for signature checks.
+type element struct {
+	parent   *element
+	prefix   string
+	name     string
+	attrs    []xml.Attr        // Name.Space holds the prefix
+	ns       map[string]string // namespace declarations, by prefix
+	children []interface{}     // *element or string
+}
+
+// parseXML parses a document into elements. Documents with a DOCTYPE are
+// rejected.
+func parseXML(data []byte) (*element, error) {
+	if len(data) > maxDocumentLength {
+		return nil, errors.New("document is too large")
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *element
+	depth := 0
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root != nil && current == nil {
+				return nil, errors.New("document has more than one root element")
+			}
+			depth++
+			if depth > maxDocumentDepth {
+				return nil, errors.New("document is nested too deeply")
+			}
+			el := &element{parent: current, prefix: t.Name.Space, name: t.Name.Local}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					el.declare("", attr.Value)
+				case attr.Name.Space == "xmlns":
+					el.declare(attr.Name.Local, attr.Value)
+				default:
+					el.attrs = append(el.attrs, attr)
+				}
+			}
+			if current == nil {
+				root = el
+			} else {
+				current.children = append(current.children, el)
+			}
+			current = el
+		case xml.EndElement:
+			if current == nil || t.Name.Space != current.prefix || t.Name.Local != current.name {
+				return nil, errors.New("mismatched end element")
+			}
+			depth--
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, string(t))
+			}
+		case xml.Directive:
+			return nil, errors.New("document type declarations are not allowed")
+		}
+	}
+
+	if root == nil || current != nil {
+		return nil, errors.New("document is incomplete")
+	}
+	return root, nil
+}
+
+// declare records a namespace declaration
+func (e *element) declare(prefix, uri string) {
+	if e.ns == nil {
+		e.ns = make(map[string]string)
+	}
+	e.ns[prefix] = uri
+}
+
+// namespace resolves a prefix in the element's scope
+func (e *element) namespace(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for el := e; el != nil; el = el.parent {
+		if uri, ok := el.ns[prefix]; ok {
+			return uri, true
+		}
+	}
+	return "", prefix == ""
+}
+
+// is reports whether the element has a namespace and local name
+func (e *element) is(space, name string) bool {
+	uri, _ := e.namespace(e.prefix)
+	return e.name == name && uri == space
+}
+
+// child returns the element's first child with a namespace and local name
+func (e *element) child(space, name string) *element {
+	for _, c := range e.children {
+		if el, ok := c.(*element); ok && el.is(space, name) {
+			return el
+		}
+	}
+	return nil
+}
+
+// all returns the element's children with a namespace and local name
+func (e *element) all(space, name string) []*element {
+	var els []*element
+	for _, c := range e.children {
+		if el, ok := c.(*element); ok && el.is(space, name) {
+			els = append(els, el)
+		}
+	}
+	return els
+}
+
+// attr returns the value of an unqualified attribute
+func (e *element) attr(name string) string {
+	for _, a := range e.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// text returns the element's text content, trimmed
+func (e *element) text() string {
+	var sb strings.Builder
+	for _, c := range e.children {
+		if s, ok := c.(string); ok {
+			sb.WriteString(s)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// verifySignature checks the enveloped signature on an element against a
+// certificate. Only exclusive canonicalization with RSA-SHA256 and SHA-256
+// digests is accepted, and the signature must reference the element itself.
+func verifySignature(el *element, cert *x509.Certificate) error {
+	sig := el.child(nsDSig, "Signature")
+	if sig == nil {
+		return ErrUnsigned
+	}
+	signedInfo := sig.child(nsDSig, "SignedInfo")
+	if signedInfo == nil {
+		return fmt.Errorf("%w: missing SignedInfo", ErrInvalidSignature)
+	}
+
+	c14nMethod := signedInfo.child(nsDSig, "CanonicalizationMethod")
+	if c14nMethod == nil || c14nMethod.attr("Algorithm") != algExcC14N {
+		return fmt.Errorf("%w: unsupported canonicalization", ErrInvalidSignature)
+	}
+	method := signedInfo.child(nsDSig, "SignatureMethod")
+	if method == nil || method.attr("Algorithm") != algRSASHA256 {
+		return fmt.Errorf("%w: unsupported signature method", ErrInvalidSignature)
+	}
+
+	// The one reference must cover the signed element
+	refs := signedInfo.all(nsDSig, "Reference")
+	if len(refs) != 1 {
+		return fmt.Errorf("%w: expected one reference", ErrInvalidSignature)
+	}
+	ref := refs[0]
+	id := el.attr("ID")
+	if id == "" || ref.attr("URI") != "#"+id {
+		return fmt.Errorf("%w: reference does not match the element", ErrInvalidSignature)
+	}
+
+	var inclusive []string
+	if transforms := ref.child(nsDSig, "Transforms"); transforms != nil {
+		for _, t := range transforms.all(nsDSig, "Transform") {
+			switch t.attr("Algorithm") {
+			case algEnveloped:
+			case algExcC14N:
+				inclusive = inclusivePrefixes(t)
+			default:
+				return fmt.Errorf("%w: unsupported transform", ErrInvalidSignature)
+			}
+		}
+	}
+
+	// Check the digest of the element without its signature
+	digestMethod := ref.child(nsDSig, "DigestMethod")
+	if digestMethod == nil || digestMethod.attr("Algorithm") != algDigestSHA256 {
+		return fmt.Errorf("%w: unsupported digest method", ErrInvalidSignature)
+	}
+	digestValue := ref.child(nsDSig, "DigestValue")
+	if digestValue == nil {
+		return fmt.Errorf("%w: missing digest", ErrInvalidSignature)
+	}
+	want, err := decodeBase64(digestValue.text())
+	if err != nil {
+		return fmt.Errorf("%w: malformed digest", ErrInvalidSignature)
+	}
+	var buf bytes.Buffer
+	if err := canonicalize(&buf, el, sig, inclusive, nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	digest := sha256.Sum256(buf.Bytes())
+	if subtle.ConstantTimeCompare(digest[:], want) != 1 {
+		return fmt.Errorf("%w: digest mismatch", ErrInvalidSignature)
+	}
+
+	// Check the signature over SignedInfo
+	signatureValue := sig.child(nsDSig, "SignatureValue")
+	if signatureValue == nil {
+		return fmt.Errorf("%w: missing signature value", ErrInvalidSignature)
+	}
+	signature, err := decodeBase64(signatureValue.text())
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature value", ErrInvalidSignature)
+	}
+	buf.Reset()
+	if err := canonicalize(&buf, signedInfo, nil, inclusivePrefixes(c14nMethod), nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: certificate does not hold an RSA key", ErrInvalidSignature)
+	}
+	hashed := sha256.Sum256(buf.Bytes())
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// inclusivePrefixes returns the InclusiveNamespaces prefix list of an
+// exclusive canonicalization transform
+func inclusivePrefixes(transform *element) []string {
+	list := transform.child(algExcC14N, "InclusiveNamespaces")
+	if list == nil {
+		return nil
+	}
+	prefixes := strings.Fields(list.attr("PrefixList"))
+	for i, p := range prefixes {
+		if p == "#default" {
+			prefixes[i] = ""
+		}
+	}
+	return prefixes
+}
+
+// canonicalize writes an element in exclusive XML canonical form without
+// comments, leaving out the skip element. rendered holds the namespace
+// declarations already written by output ancestors.
+func canonicalize(buf *bytes.Buffer, el, skip *element, inclusive []string, rendered map[string]string) error {
+	// Declare the namespaces the element and its attributes use, and the
+	// inclusive ones in scope, unless an output ancestor already did
+	used := map[string]bool{el.prefix: true}
+	for _, a := range el.attrs {
+		if a.Name.Space != "" {
+			used[a.Name.Space] = true
+		}
+	}
+	for _, p := range inclusive {
+		if _, ok := el.namespace(p); ok {
+			used[p] = true
+		}
+	}
+	delete(used, "xml")
+
+	var decls []string
+	for p := range used {
+		uri, ok := el.namespace(p)
+		if !ok {
+			return fmt.Errorf("undeclared namespace prefix %q", p)
+		}
+		if current, ok := rendered[p]; ok && current == uri || !ok && p == "" && uri == "" {
+			continue
+		}
+		decls = append(decls, p)
+	}
+	sort.Strings(decls)
+
+	if len(decls) > 0 {
+		scope := make(map[string]string, len(rendered)+len(decls))
+		for p, uri := range rendered {
+			scope[p] = uri
+		}
+		for _, p := range decls {
+			scope[p], _ = el.namespace(p)
+		}
+		rendered = scope
+	}
+
+	buf.WriteByte('<')
+	writeQName(buf, el.prefix, el.name)
+	for _, p := range decls {
+		uri := rendered[p]
+		if p == "" {
+			buf.WriteString(` xmlns="`)
+		} else {
+			buf.WriteString(` xmlns:` + p + `="`)
+		}
+		escapeAttr(buf, uri)
+		buf.WriteByte('"')
+	}
+
+	// Attributes are sorted by namespace URI, then local name
+	attrs := make([]xml.Attr, len(el.attrs))
+	copy(attrs, el.attrs)
+	spaces := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		if a.Name.Space != "" {
+			spaces[a.Name.Space], _ = el.namespace(a.Name.Space)
+		}
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		si, sj := spaces[attrs[i].Name.Space], spaces[attrs[j].Name.Space]
+		if si != sj {
+			return si < sj
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+	for _, a := range attrs {
+		buf.WriteByte(' ')
+		writeQName(buf, a.Name.Space, a.Name.Local)
+		buf.WriteString(`="`)
+		escapeAttr(buf, a.Value)
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+
+	for _, c := range el.children {
+		switch c := c.(type) {
+		case string:
+			escapeText(buf, c)
+		case *element:
+			if c == skip {
+				continue
+			}
+			if err := canonicalize(buf, c, skip, inclusive, rendered); err != nil {
+				return err
+			}
+		}
+	}
+
+	buf.WriteString("</")
+	writeQName(buf, el.prefix, el.name)
+	buf.WriteByte('>')
+	return nil
+}
+
+// writeQName writes a prefixed name
+func writeQName(buf *bytes.Buffer, prefix, name string) {
+	if prefix != "" {
+		buf.WriteString(prefix)
+		buf.WriteByte(':')
+	}
+	buf.WriteString(name)
+}
+
+// escapeText escapes character data for canonical form
+func escapeText(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
+
+// escapeAttr escapes an attribute value for canonical form
+func escapeAttr(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '"':
+			buf.WriteString("&quot;")
+		case '\t':
+			buf.WriteString("&#x9;")
+		case '\n':
+			buf.WriteString("&#xA;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
+
+// decodeBase64 decodes base64 that may be wrapped over several lines
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+)
+
+// unsignedPayload is sent in place of the body hash so uploads can be
+// streamed; the body is protected by TLS
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// ObjectStorage stores objects in an S3-compatible bucket over its REST API.
+// It backs both the S3 and GCS drivers, which differ only in endpoint and
+// request signing.
+type ObjectStorage struct {
+	client       *http.Client
+	endpoint     *url.URL
+	bucket       string
+	pathStyle    bool
+	storageClass string
+	signer       *requestSigner
+}
+
+// NewS3Storage creates an object storage for an S3 or S3-compatible bucket
+func NewS3Storage(config configs.S3Config, storageClass string) (*ObjectStorage, error) {
+	if config.Bucket == "" || config.Region == "" {
+		return nil, errors.New("s3 storage requires a bucket and region")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+
+	return newObjectStorage(endpoint, config.Bucket, config.PathStyle, storageClass, &requestSigner{
+		algorithm:    "AWS4-HMAC-SHA256",
+		keyPrefix:    "AWS4",
+		region:       config.Region,
+		service:      "s3",
+		terminator:   "aws4_request",
+		headerPrefix: "x-amz-",
+		accessKeyID:  config.AccessKeyID,
+		secretKey:    config.SecretAccessKey,
+	})
+}
+
+// NewGCSStorage creates an object storage for a Google Cloud Storage bucket,
+// using its XML API with HMAC keys
+func NewGCSStorage(config configs.GCSConfig, storageClass string) (*ObjectStorage, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("gcs storage requires a bucket")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+
+	return newObjectStorage(endpoint, config.Bucket, true, storageClass, &requestSigner{
+		algorithm:    "GOOG4-HMAC-SHA256",
+		keyPrefix:    "GOOG4",
+		region:       "auto",
+		service:      "storage",
+		terminator:   "goog4_request",
+		headerPrefix: "x-goog-",
+		accessKeyID:  config.AccessKeyID,
+		secretKey:    config.SecretAccessKey,
+	})
+}
+
+// newObjectStorage creates an object storage for a bucket
+func newObjectStorage(endpoint, bucket string, pathStyle bool, storageClass string, signer *requestSigner) (*ObjectStorage, error) {
+	if signer.accessKeyID == "" || signer.secretKey == "" {
+		return nil, errors.New("object storage requires an access key ID and secret access key")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid storage endpoint: %q", endpoint)
+	}
+
+	return &ObjectStorage{
+		client:       &http.Client{Timeout: 5 * time.Minute},
+		endpoint:     u,
+		bucket:       bucket,
+		pathStyle:    pathStyle,
+		storageClass: storageClass,
+		signer:       signer,
+	}, nil
+}
+
+// Put uploads an object
+func (s *ObjectStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if s.storageClass != "" {
+		req.Header.Set(s.signer.headerPrefix+"storage-class", s.storageClass)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads an object
+func (s *ObjectStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes an object
+func (s *ObjectStorage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// newRequest creates a request for an object
+func (s *ObjectStorage) newRequest(ctx context.Context, method, key string, body io.ReadCloser) (*http.Request, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return nil, ErrInvalidKey
+	}
+
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = encodePath(u.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Body = body
+	}
+	return req, nil
+}
+
+// do signs and sends a request, turning error statuses into errors
+func (s *ObjectStorage) do(req *http.Request) (*http.Response, error) {
+	s.signer.sign(req, unsignedPayload, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("storage %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/codingminions/Whatsapp-Lite/configs"
+)
+
+// Storage errors
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Storage stores attachment contents by key
+type Storage interface {
+	// Put stores size bytes read from body under key
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key. The caller must close it.
+	// Backends that support it return an io.ReadSeekCloser.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Deleting a missing
+	// object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New creates the storage backend selected by the configured driver
+func New(config configs.StorageConfig) (Storage, error) {
+	switch config.Driver {
+	case "", "local":
+		return NewLocalStorage(config.Local.Dir)
+	case "s3":
+		return NewS3Storage(config.S3, config.StorageClass)
+	case "gcs":
+		return NewGCSStorage(config.GCS, config.StorageClass)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %q", config.Driver)
+	}
+}
@@ -2,74 +2,103 @@ package user
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/codingminions/Whatsapp-Lite/internal/auth"
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
 	"github.com/google/uuid"
 )
 
 // Handler handles user-related HTTP requests
 type Handler struct {
-	service Service
-	logger  logger.Logger
+	service   Service
+	logger    logger.Logger
+	validator validator.Validator
 }
 
 // NewHandler creates a new user handler
-func NewHandler(service Service, logger logger.Logger) *Handler {
+func NewHandler(service Service, logger logger.Logger, validator validator.Validator) *Handler {
 	return &Handler{
-		service: service,
-		logger:  logger,
+		service:   service,
+		logger:    logger,
+		validator: validator,
 	}
 }
 
 // GetUsers handles requests to get a list of users
 func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	// Get the authenticated user ID from context
-	userIDStr, err := auth.GetUserID(r.Context())
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse query parameters
+	query := r.URL.Query()
+	after, err := pagination.Decode(query.Get("cursor"))
 	if err != nil {
-		h.logger.Error("Failed to get user ID from context", "error", err)
-		sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-			Code:    1008,
-			Message: "Authentication required",
-		})
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "pagination.invalid_cursor"))
 		return
 	}
 
-	userID, err := uuid.Parse(userIDStr)
+	limit := pagination.ParseLimit(query.Get("limit"), pagination.DefaultLimit, pagination.MaxLimit)
+	search := query.Get("search")
+
+	// Call service
+	resp, err := h.service.GetUsers(r.Context(), userID, after, limit, search)
 	if err != nil {
-		h.logger.Error("Invalid user ID format", "error", err)
-		sendJSON(w, http.StatusBadRequest, models.ErrorResponse{
-			Code:    1000,
-			Message: "Invalid user ID format",
-		})
+		h.logger.WithContext(r.Context()).Error("Failed to get users", "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, "user.list_failed"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// SearchUsers handles type-ahead username search
+func (h *Handler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
 		return
 	}
 
 	// Parse query parameters
 	query := r.URL.Query()
-	page, _ := strconv.Atoi(query.Get("page"))
-	if page <= 0 {
-		page = 1
-	}
+	limit := pagination.ParseLimit(query.Get("limit"), DefaultSearchLimit, MaxSearchLimit)
 
-	limit, _ := strconv.Atoi(query.Get("limit"))
-	if limit <= 0 {
-		limit = 20
+	// Call service
+	resp, err := h.service.SearchUsers(r.Context(), userID, query.Get("q"), limit)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSearch) {
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_search"))
+			return
+		}
+		sendError(w, r, errcode.Internal, i18n.T(r, "user.list_failed"))
+		return
 	}
 
-	search := query.Get("search")
+	// Send response
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// GetOnlineUsers handles requests for the user's contacts who are online
+func (h *Handler) GetOnlineUsers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
 
 	// Call service
-	resp, err := h.service.GetUsers(r.Context(), userID, page, limit, search)
+	resp, err := h.service.GetOnlineContacts(r.Context(), userID)
 	if err != nil {
-		h.logger.Error("Failed to get users", "error", err)
-		sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
-			Code:    1009,
-			Message: "Failed to get users",
-		})
+		sendError(w, r, errcode.Internal, i18n.T(r, "user.online_failed"))
 		return
 	}
 
@@ -77,6 +106,141 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, http.StatusOK, resp)
 }
 
+// SetCustomStatus handles requests to set the user's custom status
+func (h *Handler) SetCustomStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.CustomStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode custom status request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	status, err := h.service.SetCustomStatus(r.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrStatusExpired):
+			sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.status_expired"))
+		case errors.Is(err, ErrUserNotFound):
+			sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+		default:
+			sendError(w, r, errcode.Internal, i18n.T(r, "user.status_failed"))
+		}
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, status)
+}
+
+// ClearCustomStatus handles requests to remove the user's custom status
+func (h *Handler) ClearCustomStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	if err := h.service.ClearCustomStatus(r.Context(), userID); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+			return
+		}
+		sendError(w, r, errcode.Internal, i18n.T(r, "user.status_failed"))
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPrivacySettings handles requests for the user's privacy settings
+func (h *Handler) GetPrivacySettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	settings, err := h.service.GetPrivacySettings(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+			return
+		}
+		sendError(w, r, errcode.Internal, i18n.T(r, "user.privacy_failed"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, settings)
+}
+
+// UpdatePrivacySettings handles requests to change the user's privacy settings
+func (h *Handler) UpdatePrivacySettings(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.PrivacySettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode privacy settings request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	if err := h.service.UpdatePrivacySettings(r.Context(), userID, &req); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+			return
+		}
+		sendError(w, r, errcode.Internal, i18n.T(r, "user.privacy_failed"))
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, req)
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing or malformed
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
 // sendJSON sends a JSON response
 func sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -87,3 +251,18 @@ func sendJSON(w http.ResponseWriter, status int, data interface{}) {
 		}
 	}
 }
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
@@ -2,40 +2,58 @@ package user
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 )
 
+// ErrUserNotFound is returned when a user does not exist
+var ErrUserNotFound = errors.New("user not found")
+
+// likeEscaper escapes LIKE wildcards in user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 // Repository interface for user operations
 type Repository interface {
-	GetUsers(ctx context.Context, currentUserID uuid.UUID, page, limit int, search string) ([]models.UserInfo, int, error)
+	GetUsers(ctx context.Context, currentUserID uuid.UUID, after *pagination.Cursor, limit int, search string) ([]models.UserInfo, int, error)
 	UpdateUserStatus(ctx context.Context, userID uuid.UUID, status string, lastSeen time.Time) error
+	UpdateCustomStatus(ctx context.Context, userID uuid.UUID, text, emoji string, expiresAt *time.Time) (string, error)
+	SearchUsers(ctx context.Context, currentUserID uuid.UUID, query string, limit int) ([]models.UserInfo, error)
+	GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*models.PrivacySettings, error)
+	UpdatePrivacySettings(ctx context.Context, userID uuid.UUID, settings *models.PrivacySettings) error
+	GetVisibleContacts(ctx context.Context, userID uuid.UUID) ([]models.UserInfo, error)
 }
 
 // PostgresRepository implements Repository interface with PostgreSQL
 type PostgresRepository struct {
-	db *sqlx.DB
+	db *database.DB
 }
 
 // NewPostgresRepository creates a new PostgreSQL repository
-func NewPostgresRepository(db *sqlx.DB) *PostgresRepository {
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
 	return &PostgresRepository{db: db}
 }
 
-// GetUsers retrieves a list of users with pagination
-// GetUsers retrieves a list of users with pagination
-func (r *PostgresRepository) GetUsers(ctx context.Context, currentUserID uuid.UUID, page, limit int, search string) ([]models.UserInfo, int, error) {
-	offset := (page - 1) * limit
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
 
+// GetUsers retrieves a page of users ordered by username. It fetches up to
+// limit+1 rows so callers can tell whether more follow.
+func (r *PostgresRepository) GetUsers(ctx context.Context, currentUserID uuid.UUID, after *pagination.Cursor, limit int, search string) ([]models.UserInfo, int, error) {
 	var params []interface{}
 	var whereClause string
 
-	// Base query to get all users except the current user
-	whereClause = "id != $1"
+	// Base query to get all users except the current user and erased accounts
+	whereClause = "id != $1 AND erased_at IS NULL"
 	params = append(params, currentUserID)
 
 	// Add search filter if provided
@@ -52,39 +70,48 @@ func (r *PostgresRepository) GetUsers(ctx context.Context, currentUserID uuid.UU
     `, whereClause)
 
 	var total int
-	err := r.db.GetContext(ctx, &total, countQuery, params...)
+	err := r.conn(ctx).GetContext(ctx, &total, countQuery, params...)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	// Get paginated user list
+	// Start after the cursor position if provided
+	if after != nil {
+		whereClause += fmt.Sprintf(" AND (username, id) > ($%d, $%d)", len(params)+1, len(params)+2)
+		params = append(params, after.Key, after.ID)
+	}
+
+	// Get the page of users
 	usersQuery := fmt.Sprintf(`
-        SELECT id, username, status, updated_at
+        SELECT id, username, display_name, status, status_text, status_emoji, status_expires_at, updated_at
         FROM users
         WHERE %s
-        ORDER BY username ASC
-        LIMIT $%d OFFSET $%d
-    `, whereClause, len(params)+1, len(params)+2)
+        ORDER BY username ASC, id ASC
+        LIMIT $%d
+    `, whereClause, len(params)+1)
 
-	params = append(params, limit, offset)
+	params = append(params, limit+1)
 
-	rows, err := r.db.QueryContext(ctx, usersQuery, params...)
+	rows, err := r.conn(ctx).QueryContext(ctx, usersQuery, params...)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 
+	now := time.Now()
 	var users []models.UserInfo
 	for rows.Next() {
 		var user models.UserInfo
-		err := rows.Scan(&user.ID, &user.Username, &user.Status, &user.LastSeen)
+		var statusText, statusEmoji string
+		var statusExpiresAt *time.Time
+		err := rows.Scan(&user.ID, &user.Username, &user.DisplayName, &user.Status, &statusText, &statusEmoji, &statusExpiresAt, &user.LastSeen)
 		if err != nil {
 			return nil, 0, err
 		}
 
 		// Set online status based on user's status field
 		user.OnlineStatus = user.Status == "online"
-
+		user.CustomStatus = models.NewCustomStatus(statusText, statusEmoji, statusExpiresAt, now)
 		users = append(users, user)
 	}
 
@@ -103,6 +130,143 @@ func (r *PostgresRepository) UpdateUserStatus(ctx context.Context, userID uuid.U
 		WHERE id = $3
 	`
 
-	_, err := r.db.ExecContext(ctx, query, status, lastSeen, userID)
+	_, err := r.conn(ctx).ExecContext(ctx, query, status, lastSeen, userID)
 	return err
 }
+
+// UpdateCustomStatus sets a user's custom status, returning their username.
+// Empty text and emoji clear it.
+func (r *PostgresRepository) UpdateCustomStatus(ctx context.Context, userID uuid.UUID, text, emoji string, expiresAt *time.Time) (string, error) {
+	query := `
+		UPDATE users
+		SET status_text = $1, status_emoji = $2, status_expires_at = $3
+		WHERE id = $4
+		RETURNING username
+	`
+
+	var username string
+	err := r.conn(ctx).QueryRowContext(ctx, query, text, emoji, expiresAt, userID).Scan(&username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	return username, nil
+}
+
+// SearchUsers finds users whose username starts with or resembles query.
+// Exact matches rank first, then prefix matches, then by similarity.
+func (r *PostgresRepository) SearchUsers(ctx context.Context, currentUserID uuid.UUID, query string, limit int) ([]models.UserInfo, error) {
+	searchQuery := `
+        SELECT id, username, display_name, status, status_text, status_emoji, status_expires_at, updated_at
+        FROM users
+        WHERE id != $1 AND erased_at IS NULL AND (username ILIKE $2 OR username % $3)
+        ORDER BY
+            lower(username) = lower($3) DESC,
+            username ILIKE $2 DESC,
+            similarity(username, $3) DESC,
+            username ASC
+        LIMIT $4
+    `
+
+	rows, err := r.conn(ctx).QueryContext(ctx, searchQuery, currentUserID, likeEscaper.Replace(query)+"%", query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	var users []models.UserInfo
+	for rows.Next() {
+		var user models.UserInfo
+		var statusText, statusEmoji string
+		var statusExpiresAt *time.Time
+		err := rows.Scan(&user.ID, &user.Username, &user.DisplayName, &user.Status, &statusText, &statusEmoji, &statusExpiresAt, &user.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+
+		user.OnlineStatus = user.Status == "online"
+		user.CustomStatus = models.NewCustomStatus(statusText, statusEmoji, statusExpiresAt, now)
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetPrivacySettings retrieves a user's privacy settings
+func (r *PostgresRepository) GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*models.PrivacySettings, error) {
+	var settings models.PrivacySettings
+	err := r.conn(ctx).GetContext(ctx, &settings, "SELECT message_privacy, show_online_status FROM users WHERE id = $1", userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdatePrivacySettings saves a user's privacy settings. A nil
+// ShowOnlineStatus keeps the stored value, which is written back to settings.
+func (r *PostgresRepository) UpdatePrivacySettings(ctx context.Context, userID uuid.UUID, settings *models.PrivacySettings) error {
+	query := `
+        UPDATE users
+        SET message_privacy = $1, show_online_status = COALESCE($2, show_online_status)
+        WHERE id = $3
+        RETURNING show_online_status
+    `
+
+	err := r.conn(ctx).GetContext(ctx, &settings.ShowOnlineStatus, query, settings.WhoCanMessage, settings.ShowOnlineStatus, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetVisibleContacts retrieves a user's contacts who let others see when
+// they are online, ordered by username
+func (r *PostgresRepository) GetVisibleContacts(ctx context.Context, userID uuid.UUID) ([]models.UserInfo, error) {
+	query := `
+        SELECT u.id, u.username, u.display_name, u.status, u.status_text, u.status_emoji, u.status_expires_at, u.updated_at
+        FROM contacts c
+        JOIN users u ON u.id = c.contact_id
+        WHERE c.user_id = $1 AND u.show_online_status AND u.erased_at IS NULL
+        ORDER BY u.username ASC
+    `
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	var users []models.UserInfo
+	for rows.Next() {
+		var user models.UserInfo
+		var statusText, statusEmoji string
+		var statusExpiresAt *time.Time
+		err := rows.Scan(&user.ID, &user.Username, &user.DisplayName, &user.Status, &statusText, &statusEmoji, &statusExpiresAt, &user.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+
+		user.CustomStatus = models.NewCustomStatus(statusText, statusEmoji, statusExpiresAt, now)
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
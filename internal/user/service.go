@@ -2,55 +2,182 @@ package user
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/pagination"
 	"github.com/google/uuid"
 )
 
+// Service errors
+var (
+	ErrStatusExpired = errors.New("custom status expiry is in the past")
+	ErrInvalidSearch = errors.New("search query must be between 1 and 50 characters")
+)
+
+// Search limits
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 20
+	maxSearchLength    = 50
+)
+
 // Service handles user business logic
 type Service interface {
-	GetUsers(ctx context.Context, userID uuid.UUID, page, limit int, search string) (*models.UserListResponse, error)
+	GetUsers(ctx context.Context, userID uuid.UUID, after *pagination.Cursor, limit int, search string) (*models.UserListResponse, error)
+	SearchUsers(ctx context.Context, userID uuid.UUID, query string, limit int) (*models.UserSearchResponse, error)
+	SetCustomStatus(ctx context.Context, userID uuid.UUID, req models.CustomStatusRequest) (*models.CustomStatus, error)
+	ClearCustomStatus(ctx context.Context, userID uuid.UUID) error
+	GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*models.PrivacySettings, error)
+	UpdatePrivacySettings(ctx context.Context, userID uuid.UUID, settings *models.PrivacySettings) error
+	GetOnlineContacts(ctx context.Context, userID uuid.UUID) (*models.OnlineUsersResponse, error)
+}
+
+// Notifier pushes custom status changes to connected users
+type Notifier interface {
+	BroadcastCustomStatus(userID uuid.UUID, username string, status *models.CustomStatus)
+}
+
+// Presence reports which users have live connections
+type Presence interface {
+	IsUserConnected(userID uuid.UUID) bool
 }
 
 // UserService implements Service interface
 type UserService struct {
-	repo   Repository
-	logger logger.Logger
+	repo     Repository
+	notifier Notifier
+	presence Presence
+	logger   logger.Logger
 }
 
 // NewUserService creates a new user service
-func NewUserService(repo Repository, logger logger.Logger) *UserService {
+func NewUserService(repo Repository, notifier Notifier, presence Presence, logger logger.Logger) *UserService {
 	return &UserService{
-		repo:   repo,
-		logger: logger,
+		repo:     repo,
+		notifier: notifier,
+		presence: presence,
+		logger:   logger,
 	}
 }
 
-// GetUsers returns a list of users with pagination
-func (s *UserService) GetUsers(ctx context.Context, userID uuid.UUID, page, limit int, search string) (*models.UserListResponse, error) {
+// GetUsers returns a page of users ordered by username
+func (s *UserService) GetUsers(ctx context.Context, userID uuid.UUID, after *pagination.Cursor, limit int, search string) (*models.UserListResponse, error) {
 	// Get users from repository
-	users, total, err := s.repo.GetUsers(ctx, userID, page, limit, search)
+	users, total, err := s.repo.GetUsers(ctx, userID, after, limit, search)
 	if err != nil {
-		s.logger.Error("Failed to get users", "error", err)
+		s.logger.WithContext(ctx).Error("Failed to get users", "error", err)
 		return nil, err
 	}
 
-	// Calculate next page
-	var nextPage int
-	if (page * limit) < total {
-		nextPage = page + 1
-	} else {
-		nextPage = 0
+	users, hasMore, nextCursor := pagination.Trim(users, limit, func(u models.UserInfo) pagination.Cursor {
+		return pagination.NewCursor(u.Username, u.ID)
+	})
+	if users == nil {
+		users = []models.UserInfo{}
 	}
 
 	return &models.UserListResponse{
 		Users: users,
 		Pagination: models.Pagination{
-			Total:    total,
-			Page:     page,
-			Limit:    limit,
-			NextPage: nextPage,
+			Total:      total,
+			Limit:      limit,
+			HasMore:    hasMore,
+			NextCursor: nextCursor,
 		},
 	}, nil
 }
+
+// SearchUsers returns the best matches for a username prefix or fragment
+func (s *UserService) SearchUsers(ctx context.Context, userID uuid.UUID, query string, limit int) (*models.UserSearchResponse, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxSearchLength {
+		return nil, ErrInvalidSearch
+	}
+
+	users, err := s.repo.SearchUsers(ctx, userID, query, limit)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to search users", "error", err)
+		return nil, err
+	}
+	if users == nil {
+		users = []models.UserInfo{}
+	}
+
+	return &models.UserSearchResponse{Users: users}, nil
+}
+
+// SetCustomStatus sets the user's custom status and notifies other users
+func (s *UserService) SetCustomStatus(ctx context.Context, userID uuid.UUID, req models.CustomStatusRequest) (*models.CustomStatus, error) {
+	text := strings.TrimSpace(req.Text)
+	emoji := strings.TrimSpace(req.Emoji)
+
+	status := models.NewCustomStatus(text, emoji, req.ExpiresAt, time.Now())
+	if status == nil {
+		return nil, ErrStatusExpired
+	}
+
+	username, err := s.repo.UpdateCustomStatus(ctx, userID, text, emoji, req.ExpiresAt)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to set custom status", "error", err)
+		return nil, err
+	}
+
+	s.notifier.BroadcastCustomStatus(userID, username, status)
+	return status, nil
+}
+
+// ClearCustomStatus removes the user's custom status and notifies other users
+func (s *UserService) ClearCustomStatus(ctx context.Context, userID uuid.UUID) error {
+	username, err := s.repo.UpdateCustomStatus(ctx, userID, "", "", nil)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to clear custom status", "error", err)
+		return err
+	}
+
+	// An empty status tells clients it was cleared
+	s.notifier.BroadcastCustomStatus(userID, username, &models.CustomStatus{})
+	return nil
+}
+
+// GetPrivacySettings returns the user's privacy settings
+func (s *UserService) GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*models.PrivacySettings, error) {
+	settings, err := s.repo.GetPrivacySettings(ctx, userID)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		s.logger.WithContext(ctx).Error("Failed to get privacy settings", "error", err)
+	}
+	return settings, err
+}
+
+// UpdatePrivacySettings saves the user's privacy settings
+func (s *UserService) UpdatePrivacySettings(ctx context.Context, userID uuid.UUID, settings *models.PrivacySettings) error {
+	err := s.repo.UpdatePrivacySettings(ctx, userID, settings)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		s.logger.WithContext(ctx).Error("Failed to update privacy settings", "error", err)
+	}
+	return err
+}
+
+// GetOnlineContacts returns the user's contacts who are connected right now,
+// leaving out those who hide their online status
+func (s *UserService) GetOnlineContacts(ctx context.Context, userID uuid.UUID) (*models.OnlineUsersResponse, error) {
+	contacts, err := s.repo.GetVisibleContacts(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get contacts", "error", err)
+		return nil, err
+	}
+
+	users := []models.UserInfo{}
+	for _, contact := range contacts {
+		if s.presence.IsUserConnected(contact.ID) {
+			contact.OnlineStatus = true
+			users = append(users, contact)
+		}
+	}
+
+	return &models.OnlineUsersResponse{Users: users}, nil
+}
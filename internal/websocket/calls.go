@@ -0,0 +1,391 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/conversation"
+	"github.com/codingminions/Whatsapp-Lite/internal/features"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// callRingTimeout is how long a call rings before it is ended as missed
+const callRingTimeout = 60 * time.Second
+
+// Call states
+const (
+	callRinging = "ringing"
+	callActive  = "active"
+)
+
+// Reasons a call ended
+const (
+	callEndHangup            = "hangup"
+	callEndDeclined          = "declined"
+	callEndMissed            = "missed"
+	callEndDisconnected      = "disconnected"
+	callEndAnsweredElsewhere = "answered_elsewhere"
+)
+
+// Call errors
+var (
+	errCallBusy     = errors.New("participant is already in a call")
+	errCallNotFound = errors.New("call not found")
+)
+
+// call is a 1:1 call. The caller's connection is bound when the call starts
+// and the callee's when they answer, and signaling is relayed only between
+// those connections. Until then the offer rings on all the callee's devices.
+type call struct {
+	id       string
+	media    string
+	caller   *Client
+	calleeID uuid.UUID
+	callee   *Client // nil until answered
+	state    string
+	timer    *time.Timer
+}
+
+// callRegistry tracks calls that are ringing or in progress. Each user takes
+// part in at most one call at a time. Like the hub's connections, calls are
+// held in memory.
+type callRegistry struct {
+	hub    *Hub
+	logger logger.Logger
+
+	mu     sync.Mutex
+	calls  map[string]*call
+	byUser map[uuid.UUID]*call
+}
+
+// newCallRegistry creates an empty call registry
+func newCallRegistry(hub *Hub, logger logger.Logger) *callRegistry {
+	return &callRegistry{
+		hub:    hub,
+		logger: logger,
+		calls:  make(map[string]*call),
+		byUser: make(map[uuid.UUID]*call),
+	}
+}
+
+// start registers a ringing call from the caller's connection
+func (r *callRegistry) start(caller *Client, calleeID uuid.UUID, media string) (*call, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byUser[caller.userID] != nil || r.byUser[calleeID] != nil {
+		return nil, errCallBusy
+	}
+
+	c := &call{
+		id:       uuid.New().String(),
+		media:    media,
+		caller:   caller,
+		calleeID: calleeID,
+		state:    callRinging,
+	}
+	c.timer = time.AfterFunc(callRingTimeout, func() {
+		r.end(c, nil, callEndMissed, true)
+	})
+
+	r.calls[c.id] = c
+	r.byUser[caller.userID] = c
+	r.byUser[calleeID] = c
+	activeCalls.Set(float64(len(r.calls)))
+
+	return c, nil
+}
+
+// answer binds the callee's answering connection to a ringing call
+func (r *callRegistry) answer(callID string, callee *Client) (*call, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := r.calls[callID]
+	if c == nil || c.calleeID != callee.userID || c.state != callRinging {
+		return nil, errCallNotFound
+	}
+
+	c.callee = callee
+	c.state = callActive
+	c.timer.Stop()
+
+	return c, nil
+}
+
+// relay forwards a signaling message from one participant to the other
+func (r *callRegistry) relay(callID string, from *Client, message *models.WebSocketMessage) error {
+	r.mu.Lock()
+	c := r.calls[callID]
+	if c == nil || !c.participant(from) {
+		r.mu.Unlock()
+		return errCallNotFound
+	}
+	caller, callee, calleeID := c.caller, c.callee, c.calleeID
+	r.mu.Unlock()
+
+	switch {
+	case from != caller:
+		r.hub.sendToClient(caller, message)
+	case callee != nil:
+		r.hub.sendToClient(callee, message)
+	default:
+		// The caller may trickle candidates before the call is answered
+		r.hub.SendToUser(calleeID, message)
+	}
+	return nil
+}
+
+// hangUp ends a call on behalf of a participant. A callee ending a ringing
+// call declines it.
+func (r *callRegistry) hangUp(callID string, from *Client) error {
+	r.mu.Lock()
+	c := r.calls[callID]
+	if c == nil || !c.participant(from) {
+		r.mu.Unlock()
+		return errCallNotFound
+	}
+	reason := callEndHangup
+	if c.state == callRinging && from != c.caller {
+		reason = callEndDeclined
+	}
+	r.mu.Unlock()
+
+	r.end(c, from, reason, false)
+	return nil
+}
+
+// clientGone ends the call a closed connection was bound to, or a call
+// ringing for a user whose last device disconnected
+func (r *callRegistry) clientGone(client *Client) {
+	r.mu.Lock()
+	c := r.byUser[client.userID]
+	if c == nil {
+		r.mu.Unlock()
+		return
+	}
+	bound := client == c.caller || client == c.callee
+	ringing := c.callee == nil && c.calleeID == client.userID
+	r.mu.Unlock()
+
+	switch {
+	case bound:
+		r.end(c, client, callEndDisconnected, false)
+	case ringing && !r.hub.IsUserConnected(client.userID):
+		r.end(c, client, callEndMissed, true)
+	}
+}
+
+// end removes a call and tells the participants, except the connection that
+// ended it, why it ended. With ringingOnly set the call is only ended if it
+// has not been answered. Calls already ended are left alone.
+func (r *callRegistry) end(c *call, except *Client, reason string, ringingOnly bool) {
+	r.mu.Lock()
+	if r.calls[c.id] != c || (ringingOnly && c.state != callRinging) {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.calls, c.id)
+	delete(r.byUser, c.caller.userID)
+	delete(r.byUser, c.calleeID)
+	c.timer.Stop()
+	callee := c.callee
+	activeCalls.Set(float64(len(r.calls)))
+	r.mu.Unlock()
+
+	callsEnded.WithLabelValues(reason).Inc()
+	r.logger.Info("Call ended", "call_id", c.id, "reason", reason)
+
+	message := &models.WebSocketMessage{
+		Type: protocol.TypeCallEnd,
+		Data: models.CallEndData{CallID: c.id, Reason: reason},
+	}
+	if c.caller != except {
+		r.hub.sendToClient(c.caller, message)
+	}
+	if callee == nil {
+		r.hub.sendToUserExcept(c.calleeID, except, message)
+	} else if callee != except {
+		r.hub.sendToClient(callee, message)
+	}
+
+	if reason == callEndMissed {
+		r.recordMissed(c)
+	}
+}
+
+// recordMissed adds the missed call to the conversation's timeline, off
+// the caller's goroutine
+func (r *callRegistry) recordMissed(c *call) {
+	if r.hub.messageService == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := r.hub.messageService.RecordSystemMessage(ctx, models.DirectMessageCallMissed, c.caller.userID, c.calleeID, models.SystemData{
+			"call_id": c.id,
+			"media":   c.media,
+		})
+		if err != nil {
+			r.logger.Error("Failed to record missed call", "error", err, "call_id", c.id)
+		}
+	}()
+}
+
+// participant reports whether a connection may signal on the call. Any of
+// the callee's connections may until one of them answers.
+func (c *call) participant(client *Client) bool {
+	if client == c.caller || client == c.callee {
+		return true
+	}
+	return c.callee == nil && client.userID == c.calleeID
+}
+
+// handleCallOffer starts a call by relaying the caller's SDP offer to all
+// of the recipient's connections
+func (r *Router) handleCallOffer(client *Client, message *models.WebSocketMessage) {
+	if !r.flags.Enabled(features.Calls, client.userID) {
+		client.sendError(errcode.FeatureDisabled, "Calls are disabled", message)
+		return
+	}
+
+	var data protocol.CallOffer
+	if !decodePayload(client, message, &data) {
+		return
+	}
+	if data.Media == "" {
+		data.Media = models.CallAudio
+	}
+
+	// Parse recipient ID
+	recipientID, err := uuid.Parse(data.RecipientID)
+	if err != nil || recipientID == client.userID {
+		client.sendError(errcode.InvalidRecipient, "Invalid recipient ID", message)
+		return
+	}
+
+	ctx, cancel := r.messageContext(client, message)
+	defer cancel()
+
+	if r.hub.messageService == nil {
+		r.logger.WithContext(ctx).Error("Message service is not available")
+		client.sendError(errcode.Internal, "Server error: repository unavailable", message)
+		return
+	}
+
+	// Calls follow the same rules as messages
+	err = r.hub.messageService.CanCall(ctx, client.userID, recipientID)
+	if errors.Is(err, conversation.ErrRecipientNotFound) {
+		client.sendError(errcode.RecipientNotFound, "Recipient does not exist", message)
+		return
+	}
+	if errors.Is(err, conversation.ErrNotContact) {
+		client.sendError(errcode.Forbidden, "Recipient has not accepted you as a contact", message)
+		return
+	}
+	if errors.Is(err, conversation.ErrNotAccepting) {
+		client.sendError(errcode.RecipientNotAccepting, "Recipient is not accepting calls", message)
+		return
+	}
+	if errors.Is(err, database.ErrCircuitOpen) {
+		client.sendError(errcode.Unavailable, "Calls are temporarily unavailable, try again shortly", message)
+		return
+	}
+	if err != nil {
+		r.logger.WithContext(ctx).Error("Failed to check call permission", "error", err)
+		client.sendError(errcode.Internal, "Failed to start call", message)
+		return
+	}
+
+	if !r.hub.IsUserConnected(recipientID) {
+		client.sendError(errcode.NotFound, "Recipient is not online", message)
+		return
+	}
+
+	c, err := r.calls.start(client, recipientID, data.Media)
+	if err != nil {
+		client.sendError(errcode.Conflict, "A participant is already in a call", message)
+		return
+	}
+	r.logger.WithContext(ctx).Info("Call started", "call_id", c.id, "caller_id", client.userID, "callee_id", recipientID, "media", c.media)
+
+	// Ring the recipient's devices
+	r.hub.SendToUser(recipientID, &models.WebSocketMessage{
+		Type: protocol.TypeCallOffer,
+		Data: models.CallOfferData{
+			CallID:         c.id,
+			CallerID:       client.userID.String(),
+			CallerUsername: client.username,
+			Media:          c.media,
+			SDP:            data.SDP,
+		},
+	})
+
+	client.SendMessage(&models.WebSocketMessage{
+		Type:      protocol.TypeCallState,
+		RequestID: message.RequestID,
+		Data:      models.CallStateData{CallID: c.id, State: callRinging},
+	})
+}
+
+// handleCallAnswer accepts a ringing call and relays the callee's SDP
+// answer to the caller
+func (r *Router) handleCallAnswer(client *Client, message *models.WebSocketMessage) {
+	var data protocol.CallAnswer
+	if !decodePayload(client, message, &data) {
+		return
+	}
+
+	c, err := r.calls.answer(data.CallID, client)
+	if err != nil {
+		client.sendError(errcode.NotFound, "Call not found", message)
+		return
+	}
+
+	r.hub.sendToClient(c.caller, &models.WebSocketMessage{
+		Type: protocol.TypeCallAnswer,
+		Data: models.CallAnswerData{CallID: c.id, SDP: data.SDP},
+	})
+
+	// Stop ringing on the callee's other devices
+	r.hub.sendToUserExcept(client.userID, client, &models.WebSocketMessage{
+		Type: protocol.TypeCallEnd,
+		Data: models.CallEndData{CallID: c.id, Reason: callEndAnsweredElsewhere},
+	})
+}
+
+// handleICECandidate relays an ICE candidate to the other participant
+func (r *Router) handleICECandidate(client *Client, message *models.WebSocketMessage) {
+	var data protocol.ICECandidate
+	if !decodePayload(client, message, &data) {
+		return
+	}
+
+	err := r.calls.relay(data.CallID, client, &models.WebSocketMessage{
+		Type: protocol.TypeICECandidate,
+		Data: data,
+	})
+	if err != nil {
+		client.sendError(errcode.NotFound, "Call not found", message)
+	}
+}
+
+// handleCallEnd ends or declines a call
+func (r *Router) handleCallEnd(client *Client, message *models.WebSocketMessage) {
+	var data protocol.CallEnd
+	if !decodePayload(client, message, &data) {
+		return
+	}
+
+	if err := r.calls.hangUp(data.CallID, client); err != nil {
+		client.sendError(errcode.NotFound, "Call not found", message)
+	}
+}
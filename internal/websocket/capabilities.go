@@ -0,0 +1,240 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/gorilla/websocket"
+)
+
+// Capabilities describes the app on the other end of a connection. Clients
+// give them as query parameters or headers when connecting, or in a hello
+// message.
+type Capabilities struct {
+	Platform   string
+	AppVersion string
+
+	// features is nil for clients that did not declare any
+	features map[string]bool
+}
+
+// NewCapabilities creates capabilities from a client's declared features.
+// A nil features slice declares none.
+func NewCapabilities(platform, appVersion string, features []string) *Capabilities {
+	caps := &Capabilities{
+		Platform:   strings.ToLower(strings.TrimSpace(platform)),
+		AppVersion: strings.TrimSpace(appVersion),
+	}
+	if features != nil {
+		caps.features = make(map[string]bool, len(features))
+		for _, feature := range features {
+			if feature = strings.TrimSpace(feature); feature != "" {
+				caps.features[feature] = true
+			}
+		}
+	}
+	return caps
+}
+
+// capabilitiesFromRequest reads the capabilities from a connection request's
+// platform, app_version and features query parameters, falling back to the
+// X-Client-Platform, X-Client-Version and X-Client-Features headers. Features
+// are comma-separated.
+func capabilitiesFromRequest(r *http.Request) *Capabilities {
+	param := func(name, header string) string {
+		if value := r.URL.Query().Get(name); value != "" {
+			return value
+		}
+		return r.Header.Get(header)
+	}
+
+	var features []string
+	if list := param("features", "X-Client-Features"); list != "" {
+		features = strings.Split(list, ",")
+	}
+	return NewCapabilities(param("platform", "X-Client-Platform"), param("app_version", "X-Client-Version"), features)
+}
+
+// Supports reports whether the client supports a feature. Clients that
+// declared no features support every feature.
+func (c *Capabilities) Supports(feature string) bool {
+	return c.features == nil || c.features[feature]
+}
+
+// Declared reports whether the client declared support for a feature
+func (c *Capabilities) Declared(feature string) bool {
+	return c.features[feature]
+}
+
+// VersionPolicy holds the oldest supported app version of each platform
+type VersionPolicy struct {
+	minVersions map[string]appVersion
+	raw         map[string]string
+}
+
+// NewVersionPolicy creates a policy from the minimum app version of each
+// platform. Versions are dotted numbers such as 2.14.0.
+func NewVersionPolicy(minVersions map[string]string) (*VersionPolicy, error) {
+	policy := &VersionPolicy{
+		minVersions: make(map[string]appVersion, len(minVersions)),
+		raw:         make(map[string]string, len(minVersions)),
+	}
+	for platform, version := range minVersions {
+		parsed, ok := parseAppVersion(version)
+		if !ok {
+			return nil, fmt.Errorf("invalid minimum app version %q for platform %q", version, platform)
+		}
+		platform = strings.ToLower(platform)
+		policy.minVersions[platform] = parsed
+		policy.raw[platform] = version
+	}
+	return policy, nil
+}
+
+// MinVersion returns the oldest version of a client's platform the server
+// supports and whether the client's version is older. Clients without a
+// platform or a parsable version are allowed.
+func (p *VersionPolicy) MinVersion(caps *Capabilities) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	min, ok := p.minVersions[caps.Platform]
+	if !ok {
+		return "", false
+	}
+	version, ok := parseAppVersion(caps.AppVersion)
+	if !ok {
+		return "", false
+	}
+	return p.raw[caps.Platform], version.less(min)
+}
+
+// appVersion is a parsed dotted version number
+type appVersion []int
+
+// parseAppVersion parses a version such as 2.14.0, ignoring a pre-release
+// or build suffix after a hyphen or plus sign
+func parseAppVersion(version string) (appVersion, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, false
+	}
+
+	parts := strings.Split(version, ".")
+	parsed := make(appVersion, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+// less reports whether v is older than other. Missing parts count as 0.
+func (v appVersion) less(other appVersion) bool {
+	for i := 0; i < len(v) || i < len(other); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(other) {
+			b = other[i]
+		}
+		if a != b {
+			return a < b
+		}
+	}
+	return false
+}
+
+// forceUpgradeMessage builds the force_upgrade message for a client
+func forceUpgradeMessage(caps *Capabilities, minVersion string) *models.WebSocketMessage {
+	return &models.WebSocketMessage{
+		Type: protocol.TypeForceUpgrade,
+		Data: models.ForceUpgradeData{
+			Platform:   caps.Platform,
+			AppVersion: caps.AppVersion,
+			MinVersion: minVersion,
+		},
+	}
+}
+
+// rejectOutdated tells a client connecting with an unsupported app version
+// to upgrade and closes the connection. It is called before the pumps
+// start, so it writes to the connection directly.
+func rejectOutdated(conn *websocket.Conn, caps *Capabilities, minVersion string) {
+	forcedUpgrades.WithLabelValues(caps.Platform).Inc()
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	conn.WriteJSON(forceUpgradeMessage(caps, minVersion))
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "upgrade required"), time.Now().Add(writeWait))
+	conn.Close()
+}
+
+// Capabilities returns the client's declared capabilities
+func (c *Client) Capabilities() *Capabilities {
+	return c.caps.Load()
+}
+
+// setCapabilities replaces the client's capabilities and turns
+// connection_quality messages on if the client declared support for them
+func (c *Client) setCapabilities(caps *Capabilities) {
+	c.caps.Store(caps)
+	if caps.Declared(protocol.FeatureConnectionQuality) {
+		c.qualityEvents = true
+	}
+}
+
+// adapt returns a message in a form the client supports, or nil if the
+// client does not support the message at all. Markdown messages are sent
+// to clients that do not render markdown as their plain source.
+func (c *Client) adapt(message *models.WebSocketMessage) *models.WebSocketMessage {
+	caps := c.Capabilities()
+	if caps == nil {
+		return message
+	}
+	if feature := protocol.RequiredFeature(message.Type); feature != "" && !caps.Supports(feature) {
+		return nil
+	}
+
+	if data, ok := message.Data.(models.DirectMessageData); ok && data.Format == models.FormatMarkdown && !caps.Supports(protocol.FeatureMarkdown) {
+		data.Format = models.FormatPlain
+		data.RenderedContent = ""
+		adapted := *message
+		adapted.Data = data
+		return &adapted
+	}
+	return message
+}
+
+// handleHello updates the client's capabilities from a hello message, and
+// tells it to upgrade and disconnects it if its app version is no longer
+// supported
+func (r *Router) handleHello(client *Client, message *models.WebSocketMessage) {
+	var data protocol.Hello
+	if !decodePayload(client, message, &data) {
+		return
+	}
+
+	caps := NewCapabilities(data.Platform, data.AppVersion, data.Features)
+	if minVersion, outdated := client.versions.MinVersion(caps); outdated {
+		forcedUpgrades.WithLabelValues(caps.Platform).Inc()
+		upgrade := forceUpgradeMessage(caps, minVersion)
+		upgrade.RequestID = message.RequestID
+		client.SendMessage(upgrade)
+
+		// Give the write pump time to send the message before closing
+		time.AfterFunc(time.Second, func() { client.disconnect("upgrade required") })
+		return
+	}
+	client.setCapabilities(caps)
+}
@@ -1,11 +1,19 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -14,7 +22,8 @@ const (
 	// Time allowed to write a message to the peer
 	writeWait = 10 * time.Second
 
-	// Time allowed to read the next pong message from the peer
+	// Time allowed to read the next pong or message from the peer before
+	// the connection is considered idle and closed
 	pongWait = 60 * time.Second
 
 	// Send pings to peer with this period (must be less than pongWait)
@@ -26,40 +35,108 @@ const (
 
 // Client represents a single websocket connection
 type Client struct {
+	// ctx carries the connection's request metadata and is cancelled when
+	// the connection closes, abandoning work done on the client's behalf
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	hub      *Hub
 	conn     *websocket.Conn
-	send     chan []byte
+	send     chan outbound
 	userID   uuid.UUID
 	username string
 	logger   logger.Logger
+
+	// workspaceID is the workspace the connection's token was issued for,
+	// nil outside workspaces
+	workspaceID *uuid.UUID
+
+	// sessionID is the session of the device the connection was opened
+	// with, empty for tokens that predate session claims
+	sessionID string
+
+	// connectedAt and clientIP are when and from where the connection was
+	// opened, shown to admins
+	connectedAt time.Time
+	clientIP    string
+
+	// hinted ensures a client is sent at most one reconnect hint
+	hinted sync.Once
+
+	// tokens verifies the access tokens the client refreshes with
+	tokens TokenVerifier
+	authMu sync.Mutex
+	auth   authState
+
+	// quality tracks the round trip time of pings. With qualityEvents set
+	// the client is sent connection_quality messages as it changes.
+	quality       qualityState
+	qualityEvents bool
+
+	// lastSeen is when the client last sent a message or pong, in Unix
+	// nanoseconds. The hub's timing wheel closes the connection once it has
+	// been silent for pongWait and otherwise signals ping to send a ping.
+	lastSeen atomic.Int64
+	ping     chan struct{}
+
+	// writeFailed is set once the write pump fails; later messages are not
+	// queued on the dead connection
+	writeFailed atomic.Bool
+
+	// typing is what was last forwarded of the client's typing indicators
+	// to each recipient. Only the read pump uses it.
+	typing map[uuid.UUID]*typingState
+
+	// caps is what the client declared about itself when connecting or in a
+	// hello message, and versions the app versions it must be at least
+	caps     atomic.Pointer[Capabilities]
+	versions *VersionPolicy
 }
 
-// NewClient creates a new websocket client
-func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID, username string, logger logger.Logger) *Client {
-	return &Client{
+// NewClient creates a new websocket client whose context derives from ctx.
+// The connection is closed unless the client replaces its access token
+// before tokenExpiresAt.
+func NewClient(ctx context.Context, hub *Hub, conn *websocket.Conn, userID uuid.UUID, username string, workspaceID *uuid.UUID, tokens TokenVerifier, tokenExpiresAt time.Time, logger logger.Logger) *Client {
+	ctx, cancel := context.WithCancel(ctx)
+	client := &Client{
+		ctx:      ctx,
+		cancel:   cancel,
 		hub:      hub,
 		conn:     conn,
-		send:     make(chan []byte, 256),
+		send:     make(chan outbound, 256),
+		ping:     make(chan struct{}, 1),
 		userID:   userID,
 		username: username,
 		logger:   logger,
+		tokens:   tokens,
+
+		workspaceID: workspaceID,
+		connectedAt: time.Now(),
 	}
+	client.setAuthExpiry(tokenExpiresAt)
+	client.lastSeen.Store(time.Now().UnixNano())
+	return client
 }
 
-// readPump pumps messages from the websocket connection to the hub
 // readPump pumps messages from the websocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
+		c.cancel()
+		c.hub.wheel.cancel(c)
+		c.stopAuthTimer()
+		c.clearQuality()
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
 
 	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(appData string) error {
+		now := time.Now()
+		c.lastSeen.Store(now.UnixNano())
+		c.handlePong(appData, now)
 		return nil
 	})
+	c.hub.wheel.schedule(c, pingPeriod)
 
 	for {
 		_, message, err := c.conn.ReadMessage()
@@ -69,33 +146,36 @@ func (c *Client) readPump() {
 			}
 			break
 		}
+		c.lastSeen.Store(time.Now().UnixNano())
 
-		// Log received message for debugging
+		// The raw message may carry content, which is redacted by default
 		c.logger.Debug("Received WebSocket message",
 			"user_id", c.userID.String(),
-			"username", c.username,
-			"message", string(message))
+			"size", len(message),
+			"message", logger.Sensitive(message))
 
 		// Parse the message
-		var wsMessage models.WebSocketMessage
-		if err := json.Unmarshal(message, &wsMessage); err != nil {
+		wsMessage, err := protocol.Unmarshal(message)
+		if err != nil {
 			c.logger.Error("Failed to parse websocket message", "error", err)
-			c.sendError(1000, "Invalid message format", "unknown")
+			c.sendError(errcode.InvalidRequest, "Invalid message format", &models.WebSocketMessage{Type: protocol.TypeUnknown, RequestID: requestid.New()})
 			continue
 		}
 
+		// Correlate the message with a request ID, keeping the client's if valid
+		wsMessage.RequestID = requestid.Sanitize(wsMessage.RequestID)
+		if wsMessage.RequestID == "" {
+			wsMessage.RequestID = requestid.New()
+		}
+
 		// Handle the message by its type
-		c.hub.router.RouteMessage(c, &wsMessage)
+		c.hub.router.RouteMessage(c, wsMessage)
 	}
 }
 
 // writePump pumps messages from the hub to the websocket connection
 func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
-	defer func() {
-		ticker.Stop()
-		c.conn.Close()
-	}()
+	defer c.conn.Close()
 
 	for {
 		select {
@@ -107,52 +187,185 @@ func (c *Client) writePump() {
 				return
 			}
 
+			batch := []outbound{message}
 			w, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
+				c.writeFailure(batch)
 				return
 			}
-			w.Write(message)
+			w.Write(message.data)
 
 			// Add queued messages to the current websocket message
 			n := len(c.send)
 			for i := 0; i < n; i++ {
+				next := <-c.send
+				batch = append(batch, next)
 				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+				w.Write(next.data)
 			}
 
 			if err := w.Close(); err != nil {
+				c.writeFailure(batch)
 				return
 			}
-		case <-ticker.C:
+		case <-c.ping:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			if err := c.conn.WriteMessage(websocket.PingMessage, pingPayload(time.Now())); err != nil {
 				return
 			}
 		}
 	}
 }
 
-// SendMessage sends a message to the client
-func (c *Client) SendMessage(message *models.WebSocketMessage) {
-	messageBytes, err := json.Marshal(message)
+// wheelCheck is called by the hub's timing wheel. It closes the connection
+// if the client has been silent for pongWait and otherwise asks the write
+// pump to ping it, returning when the client is next due or 0 if it was
+// closed.
+func (c *Client) wheelCheck(now time.Time) time.Duration {
+	if now.Sub(time.Unix(0, c.lastSeen.Load())) > pongWait {
+		idleDisconnects.Inc()
+		c.conn.Close()
+		return 0
+	}
+
+	select {
+	case c.ping <- struct{}{}:
+	default:
+		// The previous ping has not been written yet
+	}
+	return pingPeriod
+}
+
+// outbound is a message queued for the write pump. Messages the hub sent
+// to the user, rather than replies to this connection, are retried if
+// they cannot be written.
+type outbound struct {
+	message *models.WebSocketMessage
+	data    []byte
+	retry   bool
+	attempt int // earlier retries of the message
+}
+
+// SendMessage sends a message to the client. It reports whether the
+// message was queued; it is not if the connection is failing.
+func (c *Client) SendMessage(message *models.WebSocketMessage) bool {
+	return c.queue(outbound{message: message})
+}
+
+// queue adapts and encodes a message for the write pump. Messages the
+// client does not support are skipped and count as queued.
+func (c *Client) queue(out outbound) bool {
+	if c.writeFailed.Load() {
+		return false
+	}
+
+	adapted := c.adapt(out.message)
+	if adapted == nil {
+		return true
+	}
+	messageBytes, err := json.Marshal(adapted)
 	if err != nil {
 		c.logger.Error("Failed to marshal websocket message", "error", err)
-		return
+		encodeFailures.Inc()
+		return true
+	}
+	out.data = messageBytes
+
+	clientQueueDepth.Observe(float64(len(c.send)))
+	select {
+	case c.send <- out:
+		return true
+	default:
+		// The client is not keeping up. Rather than block the sender,
+		// drop the connection and let the client catch up on reconnect.
+		c.closeWithHint(reconnectOverloaded)
+		return false
+	}
+}
+
+// writeFailure stops queueing on a connection whose write failed and hands
+// the hub's messages in the failed write, and any still queued behind it,
+// to the user's retry queue
+func (c *Client) writeFailure(batch []outbound) {
+	c.writeFailed.Store(true)
+	for n := len(c.send); n > 0; n-- {
+		next, ok := <-c.send
+		if !ok {
+			break
+		}
+		batch = append(batch, next)
 	}
 
-	c.send <- messageBytes
+	for _, out := range batch {
+		if out.retry {
+			c.hub.retryFailedWrite(c, out)
+		}
+	}
 }
 
-// sendError sends an error message to the client
-func (c *Client) sendError(code int, message, originalType string) {
+// disconnect tells the client why it is being disconnected and closes the
+// connection, which ends the read and write pumps. Both calls are safe
+// alongside the write pump.
+func (c *Client) disconnect(reason string) {
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason), time.Now().Add(writeWait))
+	c.conn.Close()
+}
+
+// sendError sends an error in response to the original message
+func (c *Client) sendError(code errcode.Code, message string, original *models.WebSocketMessage) {
 	errorMsg := &models.WebSocketMessage{
-		Type: "error",
+		Type: protocol.TypeError,
 		Data: models.ErrorData{
 			Code:                code,
+			Error:               code.Name(),
 			Message:             message,
-			OriginalMessageType: originalType,
+			OriginalMessageType: original.Type,
+			RequestID:           original.RequestID,
 		},
+		RequestID: original.RequestID,
 	}
 
 	c.SendMessage(errorMsg)
 }
+
+// sendAck acknowledges a message the client sent with its delivery state
+func (c *Client) sendAck(original *models.WebSocketMessage, clientMessageID string, serverMessageID uuid.UUID, status string, timestamp time.Time) {
+	c.SendMessage(&models.WebSocketMessage{
+		Type:      protocol.TypeMessageAck,
+		RequestID: original.RequestID,
+		Data: models.MessageAckData{
+			ClientMessageID: clientMessageID,
+			ServerMessageID: serverMessageID.String(),
+			Status:          status,
+			Timestamp:       timestamp,
+		},
+	})
+}
+
+// presence returns when the client was last heard from and the platform
+// it declared
+func (c *Client) presence() (time.Time, string) {
+	lastSeen := time.Unix(0, c.lastSeen.Load()).UTC()
+	var platform string
+	if caps := c.Capabilities(); caps != nil {
+		platform = caps.Platform
+	}
+	return lastSeen, platform
+}
+
+// sendValidationError sends an error listing the invalid fields of a
+// message's payload
+func (c *Client) sendValidationError(errs validator.ValidationErrors, original *models.WebSocketMessage) {
+	c.SendMessage(&models.WebSocketMessage{
+		Type: protocol.TypeError,
+		Data: models.ErrorData{
+			Code:                errcode.InvalidRequest,
+			Error:               errcode.InvalidRequest.Name(),
+			Message:             errs.Error(),
+			OriginalMessageType: original.Type,
+			RequestID:           original.RequestID,
+			Details:             errs.Details(i18n.English),
+		},
+		RequestID: original.RequestID,
+	})
+}
@@ -1,37 +1,47 @@
 package websocket
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
 	"github.com/codingminions/Whatsapp-Lite/pkg/token"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// TokenVerifier checks access tokens, including whether they were revoked
+// and whether the user is banned
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (*token.Payload, error)
+}
+
 // Handler manages WebSocket connections
 type Handler struct {
-	hub        *Hub
-	upgrader   websocket.Upgrader
-	tokenMaker token.Maker
-	logger     logger.Logger
+	hub      *Hub
+	upgrader websocket.Upgrader
+	tokens   TokenVerifier
+	versions *VersionPolicy
+	logger   logger.Logger
 }
 
-// NewHandler creates a new WebSocket handler
-func NewHandler(hub *Hub, tokenMaker token.Maker, logger logger.Logger) *Handler {
+// NewHandler creates a new WebSocket handler that accepts upgrades from
+// the origins the policy allows and tells clients older than the versions
+// policy allows to upgrade
+func NewHandler(hub *Hub, tokens TokenVerifier, origins *OriginPolicy, versions *VersionPolicy, logger logger.Logger) *Handler {
 	return &Handler{
 		hub: hub,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin: func(r *http.Request) bool {
-				// Allow all origins for now
-				// In production, this should be more restrictive
-				return true
-			},
+			CheckOrigin:     origins.CheckOrigin,
 		},
-		tokenMaker: tokenMaker,
-		logger:     logger,
+		tokens:   tokens,
+		versions: versions,
+		logger:   logger,
 	}
 }
 
@@ -40,36 +50,82 @@ func (h *Handler) ServeWS(w http.ResponseWriter, r *http.Request) {
 	// Extract token from query string
 	tokenStr := r.URL.Query().Get("token")
 	if tokenStr == "" {
-		h.logger.Error("Missing token in WebSocket connection request")
+		h.logger.WithContext(r.Context()).Error("Missing token in WebSocket connection request")
 		http.Error(w, "Missing authentication token", http.StatusUnauthorized)
 		return
 	}
 
 	// Verify token
-	payload, err := h.tokenMaker.VerifyToken(tokenStr)
+	payload, err := h.tokens.VerifyToken(r.Context(), tokenStr)
+	if errors.Is(err, auth.ErrUserBanned) {
+		h.logger.WithContext(r.Context()).Info("Banned user tried to connect")
+		http.Error(w, "Account is banned", http.StatusForbidden)
+		return
+	}
 	if err != nil {
-		h.logger.Error("Invalid token in WebSocket connection request", "error", err)
+		h.logger.WithContext(r.Context()).Error("Invalid token in WebSocket connection request", "error", err)
 		http.Error(w, "Invalid authentication token", http.StatusUnauthorized)
 		return
 	}
 
+	if !payload.HasScope(token.ScopeWebSocket) {
+		h.logger.WithContext(r.Context()).Info("Token without the WebSocket scope used to connect", "user_id", payload.UserID)
+		http.Error(w, "Token does not grant WebSocket access", http.StatusForbidden)
+		return
+	}
+
 	// Parse user ID
 	userID, err := uuid.Parse(payload.UserID)
 	if err != nil {
-		h.logger.Error("Invalid user ID in token", "error", err)
+		h.logger.WithContext(r.Context()).Error("Invalid user ID in token", "error", err)
 		http.Error(w, "Invalid user ID", http.StatusUnauthorized)
 		return
 	}
 
+	// The token's workspace scopes the connection's messages
+	var workspaceID *uuid.UUID
+	if payload.WorkspaceID != "" {
+		id, err := uuid.Parse(payload.WorkspaceID)
+		if err != nil {
+			h.logger.WithContext(r.Context()).Error("Invalid workspace ID in token", "error", err)
+			http.Error(w, "Invalid authentication token", http.StatusUnauthorized)
+			return
+		}
+		workspaceID = &id
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		h.logger.Error("Failed to upgrade connection to WebSocket", "error", err)
+		h.logger.WithContext(r.Context()).Error("Failed to upgrade connection to WebSocket", "error", err)
+		return
+	}
+
+	// Turn the connection away if the server is shutting down
+	if h.hub.Draining() {
+		reconnectHints.WithLabelValues(reconnectShutdown).Inc()
+		conn.WriteControl(websocket.CloseMessage, h.hub.reconnectHint(reconnectShutdown), time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
+
+	// Tell clients whose app version is no longer supported to upgrade
+	caps := capabilitiesFromRequest(r)
+	if minVersion, outdated := h.versions.MinVersion(caps); outdated {
+		h.logger.WithContext(r.Context()).Info("Outdated client told to upgrade", "platform", caps.Platform, "app_version", caps.AppVersion)
+		rejectOutdated(conn, caps, minVersion)
 		return
 	}
 
-	// Create client
-	client := NewClient(h.hub, conn, userID, payload.Username, h.logger)
+	// Create client. The request context ends when ServeWS returns, so the
+	// client keeps its values but is cancelled when the connection closes.
+	client := NewClient(context.WithoutCancel(r.Context()), h.hub, conn, userID, payload.Username, workspaceID, h.tokens, payload.ExpiredAt, h.logger)
+	client.qualityEvents = r.URL.Query().Get("connection_quality") == "true"
+	client.sessionID = payload.SessionID
+	client.setAuthSession(payload.SessionID)
+	client.clientIP = auth.ClientIP(r)
+	client.versions = h.versions
+	client.setCapabilities(caps)
 
 	// Register client in hub
 	h.hub.register <- client
@@ -3,9 +3,13 @@ package websocket
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/codingminions/Whatsapp-Lite/internal/events"
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
 	"github.com/google/uuid"
 )
 
@@ -14,8 +18,8 @@ type Hub struct {
 	// Registered clients
 	clients map[*Client]bool
 
-	// User ID to client mapping
-	userClients map[string]*Client
+	// User ID to client mapping, one entry per open connection
+	userClients map[string]map[*Client]bool
 
 	// Register requests from the clients
 	register chan *Client
@@ -32,37 +36,96 @@ type Hub struct {
 	// Logger
 	logger logger.Logger
 
-	// Conversation repository for saving messages
-	conversationRepo ConversationRepository
+	// Message service for saving and forwarding messages
+	messageService MessageService
+
+	// Publisher for domain events
+	events events.Publisher
+
+	// Announcement service for delivering announcements missed offline
+	announcements AnnouncementService
+
+	// inbox keeps durable messages for devices that are offline, nil if
+	// messages are only sent to connected clients
+	inbox EventInbox
+
+	// epoch identifies this server process in reconnect hints
+	epoch int64
+
+	// wheel schedules the pings and idle checks of every connection
+	wheel *timingWheel
+
+	// presence tracks which connections follow which users' presence
+	presence *presenceSubscriptions
+
+	// retries holds the messages that could not be written to a user's
+	// connections
+	retries *retryQueues
+
+	// draining is set while the server shuts down; new connections are
+	// turned away
+	draining bool
 }
 
-// ConversationRepository defines the methods needed by the websocket hub
-type ConversationRepository interface {
-	SaveMessage(ctx context.Context, message *models.DirectMessage) error
+// MessageService defines the methods needed by the websocket hub
+type MessageService interface {
+	SendMessage(ctx context.Context, message *models.DirectMessage, senderUsername string) (*models.DirectMessageData, error)
+	CanCall(ctx context.Context, callerID, calleeID uuid.UUID) error
+	MarkRead(ctx context.Context, conversationID string, userID uuid.UUID, username, sessionID, lastReadMessageID string) error
+	RecordSystemMessage(ctx context.Context, messageType string, senderID, recipientID uuid.UUID, data models.SystemData) error
+	CanType(ctx context.Context, senderID, recipientID uuid.UUID) (string, error)
+	NotifyPresenceChanged(ctx context.Context, userID uuid.UUID)
+	VisiblePresence(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error)
+	DeliverOffline(ctx context.Context, userID uuid.UUID, message *models.WebSocketMessage)
 }
 
-// NewHub creates a new Hub
-func NewHub(logger logger.Logger, conversationRepo ConversationRepository) *Hub {
+// AnnouncementService tracks which system announcements users have received
+type AnnouncementService interface {
+	DeliverPending(ctx context.Context, userID uuid.UUID)
+	MarkSeen(ctx context.Context, userID uuid.UUID)
+}
+
+// EventInbox keeps the durable messages sent to users until their devices
+// acknowledge them
+type EventInbox interface {
+	Append(ctx context.Context, userID uuid.UUID, message *models.WebSocketMessage) (int64, error)
+}
+
+// NewHub creates a new Hub. Durable messages sent to users are kept in the
+// inbox unless it is nil.
+func NewHub(logger logger.Logger, publisher events.Publisher, inbox EventInbox) *Hub {
 	hub := &Hub{
-		register:         make(chan *Client),
-		unregister:       make(chan *Client),
-		clients:          make(map[*Client]bool),
-		userClients:      make(map[string]*Client),
-		logger:           logger,
-		conversationRepo: conversationRepo,
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
+		clients:     make(map[*Client]bool),
+		userClients: make(map[string]map[*Client]bool),
+		logger:      logger,
+		events:      publisher,
+		inbox:       inbox,
+		epoch:       time.Now().UnixMilli(),
+		wheel:       newTimingWheel(wheelTick, wheelSlots),
+		presence:    newPresenceSubscriptions(),
+		retries:     newRetryQueues(),
 	}
 	// We'll wait to initialize the router until after the hub is created
 	// to avoid circular references
 	return hub
 }
 
-// InitRouter initializes the message router
-func (h *Hub) InitRouter() {
-	h.router = NewRouter(h, h.logger)
+// InitRouter initializes the message router with the service used to send
+// messages and the service that delivers announcements missed offline.
+// Services that push events through the hub are created between NewHub and
+// InitRouter.
+func (h *Hub) InitRouter(messageService MessageService, announcements AnnouncementService, messageValidator *validator.MessageValidator, flags FeatureFlags, messageTimeout time.Duration) {
+	h.messageService = messageService
+	h.announcements = announcements
+	h.router = NewRouter(h, messageValidator, flags, messageTimeout, h.logger)
 }
 
 // Run starts the hub's event loop
 func (h *Hub) Run() {
+	go h.wheel.run()
+
 	for {
 		select {
 		case client := <-h.register:
@@ -73,71 +136,356 @@ func (h *Hub) Run() {
 	}
 }
 
-// registerClient registers a new client
+// registerClient registers a new client, or turns it away while draining
 func (h *Hub) registerClient(client *Client) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
-
+	if h.draining {
+		h.mu.Unlock()
+		client.closeWithHint(reconnectShutdown)
+		return
+	}
 	h.logger.Info("Client connected",
 		"user_id", client.userID.String(),
-		"username", client.username)
+		"username", client.username,
+		"session_id", client.sessionID)
 
 	h.clients[client] = true
-	h.userClients[client.userID.String()] = client
+	connections, ok := h.userClients[client.userID.String()]
+	if !ok {
+		connections = make(map[*Client]bool)
+		h.userClients[client.userID.String()] = connections
+	}
+	connections[client] = true
+	firstConnection := len(connections) == 1
+	openConnections.Set(float64(len(h.clients)))
+	connectedUsers.Set(float64(len(h.userClients)))
+	h.mu.Unlock()
+	registrations.Inc()
 
 	// Notify other users that this user is online
-	h.broadcastPresenceUpdate(client.userID, client.username, "online")
+	if firstConnection {
+		h.broadcastPresenceUpdate(client, "online")
+		h.announcementsAsync(client.userID, AnnouncementService.DeliverPending)
+	}
 }
 
 // unregisterClient unregisters a client
 func (h *Hub) unregisterClient(client *Client) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	// Stop presence updates before the connection's queue is closed
+	h.presence.remove(client)
 
-	if _, ok := h.clients[client]; ok {
+	h.mu.Lock()
+	_, ok := h.clients[client]
+	lastConnection := false
+	if ok {
 		delete(h.clients, client)
-		delete(h.userClients, client.userID.String())
+		connections := h.userClients[client.userID.String()]
+		delete(connections, client)
+		if len(connections) == 0 {
+			delete(h.userClients, client.userID.String())
+			lastConnection = true
+		}
 		close(client.send)
+	}
+	openConnections.Set(float64(len(h.clients)))
+	connectedUsers.Set(float64(len(h.userClients)))
+	h.mu.Unlock()
+	unregistrations.Inc()
 
+	// End any call the connection was taking part in
+	if h.router != nil {
+		h.router.calls.clientGone(client)
+	}
+
+	if lastConnection {
 		// Notify other users that this user is offline
-		h.broadcastPresenceUpdate(client.userID, client.username, "offline")
+		h.broadcastPresenceUpdate(client, "offline")
+		h.announcementsAsync(client.userID, AnnouncementService.MarkSeen)
 	}
 }
 
-// SendToUser sends a message to a specific user
+// announcementsAsync calls the announcement service off the hub's event loop
+func (h *Hub) announcementsAsync(userID uuid.UUID, fn func(AnnouncementService, context.Context, uuid.UUID)) {
+	if h.announcements == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		fn(h.announcements, ctx, userID)
+	}()
+}
+
+// SendToUser sends a message to every connection of a specific user,
+// keeping durable messages in the user's inbox whether or not they are
+// connected. It reports whether the user is connected; connections that
+// fail to take the message have it retried, falling back to offline
+// delivery once the user's connections are gone.
 func (h *Hub) SendToUser(userID uuid.UUID, message *models.WebSocketMessage) bool {
+	return h.SendToOtherSessions(userID, "", message)
+}
+
+// SendToOtherSessions sends a message to a user's connections except those
+// opened by one session, or to all of them if sessionID is empty. It
+// reports whether any connection was sent the message.
+func (h *Hub) SendToOtherSessions(userID uuid.UUID, sessionID string, message *models.WebSocketMessage) bool {
+	message = h.keep(userID, message)
+
+	h.mu.RLock()
+	sent, failed := false, false
+	received := make(map[*Client]bool)
+	for client := range h.userClients[userID.String()] {
+		received[client] = true
+		if sessionID != "" && client.sessionID == sessionID {
+			continue
+		}
+		if !client.queue(outbound{message: message, retry: true}) {
+			delete(received, client)
+			failed = true
+		}
+		sent = true
+	}
+	h.mu.RUnlock()
+
+	status := sendStatusDelivered
+	if !sent {
+		status = sendStatusOffline
+	}
+	sendsToUser.WithLabelValues(string(message.Type), status).Inc()
+
+	if failed {
+		h.queueRetry(userID, retryEntry{message: message, received: received})
+	}
+	return sent
+}
+
+// keep adds a durable message to the user's inbox and returns it with its
+// seq. The message is still sent live if it cannot be kept.
+func (h *Hub) keep(userID uuid.UUID, message *models.WebSocketMessage) *models.WebSocketMessage {
+	if h.inbox == nil || !protocol.Durable(message.Type) {
+		return message
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	seq, err := h.inbox.Append(ctx, userID, message)
+	if err != nil {
+		h.logger.Error("Failed to keep message in inbox", "user_id", userID, "type", message.Type, "error", err)
+		return message
+	}
+
+	kept := *message
+	kept.Seq = seq
+	return &kept
+}
+
+// Broadcast sends a message to every connection and returns how many it
+// was sent to
+func (h *Hub) Broadcast(message *models.WebSocketMessage) int {
+	start := time.Now()
+	h.mu.RLock()
+	for client := range h.clients {
+		client.SendMessage(message)
+	}
+	sent := len(h.clients)
+	h.mu.RUnlock()
+	broadcastDuration.WithLabelValues(string(message.Type)).Observe(time.Since(start).Seconds())
+
+	return sent
+}
+
+// sendToClient sends a message to one connection if it is still registered
+func (h *Hub) sendToClient(client *Client, message *models.WebSocketMessage) bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	client, ok := h.userClients[userID.String()]
-	if !ok {
+	if !h.clients[client] {
 		return false
 	}
-
 	client.SendMessage(message)
 	return true
 }
 
-// broadcastPresenceUpdate notifies all clients about a user's presence update
-func (h *Hub) broadcastPresenceUpdate(userID uuid.UUID, username, status string) {
+// sendToUserExcept sends a message to every connection of a user but one
+func (h *Hub) sendToUserExcept(userID uuid.UUID, except *Client, message *models.WebSocketMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.userClients[userID.String()] {
+		if client != except {
+			client.SendMessage(message)
+		}
+	}
+}
+
+// Drain stops the hub accepting connections and closes the open ones,
+// telling clients to reconnect after a spread-out delay, by which time
+// another instance or the restarted server can take them. It returns the
+// number of connections closed.
+func (h *Hub) Drain() int {
+	h.mu.Lock()
+	h.draining = true
+	connections := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		connections = append(connections, client)
+	}
+	h.mu.Unlock()
+
+	for _, client := range connections {
+		client.closeWithHint(reconnectShutdown)
+	}
+	h.logger.Info("Hub drained", "connections_closed", len(connections))
+	return len(connections)
+}
+
+// Draining reports whether the hub has stopped accepting connections
+func (h *Hub) Draining() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.draining
+}
+
+// DisconnectUser closes every connection of a user, for example after they
+// log out of all devices or are banned, telling each client the reason. The
+// connections unregister as their read pumps exit. It returns the number of
+// connections closed.
+func (h *Hub) DisconnectUser(userID uuid.UUID, reason string) int {
+	h.mu.RLock()
+	connections := make([]*Client, 0, len(h.userClients[userID.String()]))
+	for client := range h.userClients[userID.String()] {
+		connections = append(connections, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range connections {
+		client.disconnect(reason)
+	}
+	forcedDisconnects.Add(float64(len(connections)))
+	return len(connections)
+}
+
+// DisconnectSession closes the connections a user opened with one of
+// their sessions, or whose current token was issued for it, sending them
+// the reason, and returns how many were closed
+func (h *Hub) DisconnectSession(userID uuid.UUID, sessionID, reason string) int {
+	h.mu.RLock()
+	var connections []*Client
+	for client := range h.userClients[userID.String()] {
+		if client.sessionID == sessionID || client.authSession() == sessionID {
+			connections = append(connections, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range connections {
+		client.disconnect(reason)
+	}
+	forcedDisconnects.Add(float64(len(connections)))
+	return len(connections)
+}
+
+// broadcastPresenceUpdate notifies the clients following a user about a
+// presence update. The client is the connection that changed it, or the
+// last one to close.
+func (h *Hub) broadcastPresenceUpdate(client *Client, status string) {
+	userID, username := client.userID, client.username
+	lastSeen, platform := client.presence()
 	message := &models.WebSocketMessage{
-		Type: "presence_update",
+		Type: protocol.TypePresenceUpdate,
 		Data: models.PresenceData{
 			UserID:   userID.String(),
 			Username: username,
 			Status:   status,
+			LastSeen: &lastSeen,
+			Platform: platform,
 		},
 	}
 
+	start := time.Now()
+	h.sendToWatchers(userID, message)
+	broadcastDuration.WithLabelValues(string(message.Type)).Observe(time.Since(start).Seconds())
+
+	// Publish domain event
+	err := h.events.Publish(context.Background(), events.New(events.TypePresenceChanged, events.PresenceChangedData{
+		UserID:   userID.String(),
+		Username: username,
+		Status:   status,
+	}))
+	if err != nil {
+		h.logger.Error("Failed to publish presence changed event", "error", err)
+	}
+
+	// Refresh conversation lists off the hub's event loop
+	if h.messageService != nil {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			h.messageService.NotifyPresenceChanged(ctx, userID)
+		}()
+	}
+}
+
+// BroadcastCustomStatus notifies the clients following a user, and the
+// user's own connections, about a custom status change. An empty status means it was
+// cleared; presence updates without one leave it unchanged.
+func (h *Hub) BroadcastCustomStatus(userID uuid.UUID, username string, status *models.CustomStatus) {
+	data := models.PresenceData{
+		UserID:       userID.String(),
+		Username:     username,
+		Status:       "offline",
+		CustomStatus: status,
+	}
+	if client := h.mostRecentClient(userID); client != nil {
+		lastSeen, platform := client.presence()
+		data.Status = "online"
+		data.LastSeen = &lastSeen
+		data.Platform = platform
+	}
+
+	message := &models.WebSocketMessage{
+		Type: protocol.TypePresenceUpdate,
+		Data: data,
+	}
+
+	start := time.Now()
+	h.sendToWatchers(userID, message)
+	h.mu.RLock()
+	for client := range h.userClients[userID.String()] {
+		client.SendMessage(message)
+	}
+	h.mu.RUnlock()
+	broadcastDuration.WithLabelValues(string(message.Type)).Observe(time.Since(start).Seconds())
+}
+
+// sendToWatchers sends a message to the connections following a user's
+// presence, other than the user's own. Holding the hub's lock keeps the
+// connections from being closed while they are sent to, and connections
+// already unregistered are skipped.
+func (h *Hub) sendToWatchers(userID uuid.UUID, message *models.WebSocketMessage) {
+	watchers := h.presence.watchersOf(userID)
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
+	for _, watcher := range watchers {
+		if watcher.userID != userID && h.clients[watcher] {
+			watcher.SendMessage(message)
+		}
+	}
+}
 
-	for client := range h.clients {
-		// Don't send presence update to the user themselves
-		if client.userID != userID {
-			client.SendMessage(message)
+// mostRecentClient returns the user's most recently active connection, or
+// nil if the user is not connected
+func (h *Hub) mostRecentClient(userID uuid.UUID) *Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var recent *Client
+	for client := range h.userClients[userID.String()] {
+		if recent == nil || client.lastSeen.Load() > recent.lastSeen.Load() {
+			recent = client
 		}
 	}
+	return recent
 }
 
 // GetConnectedUserCount returns the number of connected users
@@ -147,6 +495,13 @@ func (h *Hub) GetConnectedUserCount() int {
 	return len(h.userClients)
 }
 
+// GetConnectionCount returns the number of open connections across all users
+func (h *Hub) GetConnectionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
 // IsUserConnected checks if a user is connected
 func (h *Hub) IsUserConnected(userID uuid.UUID) bool {
 	h.mu.RLock()
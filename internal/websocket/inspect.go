@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"sort"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/google/uuid"
+)
+
+// Connections returns a snapshot of every open connection, oldest first
+func (h *Hub) Connections() []models.ConnectionInfo {
+	h.mu.RLock()
+	connections := make([]models.ConnectionInfo, 0, len(h.clients))
+	for client := range h.clients {
+		connections = append(connections, client.info())
+	}
+	h.mu.RUnlock()
+
+	sortConnections(connections)
+	return connections
+}
+
+// UserConnections returns a snapshot of a user's open connections, oldest
+// first, and the number of messages waiting to be retried for them
+func (h *Hub) UserConnections(userID uuid.UUID) ([]models.ConnectionInfo, int) {
+	h.mu.RLock()
+	connections := make([]models.ConnectionInfo, 0, len(h.userClients[userID.String()]))
+	for client := range h.userClients[userID.String()] {
+		connections = append(connections, client.info())
+	}
+	h.mu.RUnlock()
+
+	h.retries.mu.Lock()
+	pending := len(h.retries.queues[userID])
+	h.retries.mu.Unlock()
+
+	sortConnections(connections)
+	return connections, pending
+}
+
+// sortConnections orders connections by when they were opened
+func sortConnections(connections []models.ConnectionInfo) {
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].ConnectedAt.Before(connections[j].ConnectedAt)
+	})
+}
+
+// info describes the connection for admins
+func (c *Client) info() models.ConnectionInfo {
+	info := models.ConnectionInfo{
+		UserID:         c.userID.String(),
+		Username:       c.username,
+		SessionID:      c.sessionID,
+		ClientIP:       c.clientIP,
+		ConnectedAt:    c.connectedAt,
+		LastActivityAt: time.Unix(0, c.lastSeen.Load()).UTC(),
+		QueueDepth:     len(c.send),
+	}
+	if caps := c.Capabilities(); caps != nil {
+		info.Platform = caps.Platform
+		info.AppVersion = caps.AppVersion
+	}
+
+	c.quality.mu.Lock()
+	info.Quality = c.quality.quality
+	info.SmoothedRTTMillis = c.quality.smoothed.Milliseconds()
+	c.quality.mu.Unlock()
+
+	return info
+}
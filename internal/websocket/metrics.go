@@ -0,0 +1,113 @@
+package websocket
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// SendToUser outcomes
+const (
+	sendStatusDelivered = "delivered"
+	sendStatusOffline   = "offline"
+)
+
+// Hub metrics exported on the metrics endpoint
+var (
+	openConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_ws_connections",
+		Help: "Number of open WebSocket connections.",
+	})
+
+	connectedUsers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_ws_connected_users",
+		Help: "Number of users with at least one open WebSocket connection.",
+	})
+
+	registrations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chat_ws_registrations_total",
+		Help: "Number of WebSocket connections registered with the hub.",
+	})
+
+	unregistrations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chat_ws_unregistrations_total",
+		Help: "Number of WebSocket connections unregistered from the hub.",
+	})
+
+	forcedDisconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chat_ws_forced_disconnects_total",
+		Help: "Number of WebSocket connections closed by the server, such as after logging out of all devices.",
+	})
+
+	authExpiries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chat_ws_auth_expiries_total",
+		Help: "Number of WebSocket connections closed because their access token expired without being refreshed.",
+	})
+
+	reconnectHints = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_ws_reconnect_hints_total",
+		Help: "Number of WebSocket connections closed with a reconnect hint, by reason.",
+	}, []string{"reason"})
+
+	sendsToUser = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_ws_send_to_user_total",
+		Help: "Number of messages sent to a user's connections, by message type and outcome.",
+	}, []string{"type", "status"})
+
+	messageRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_ws_message_retries_total",
+		Help: "Number of messages retried after a user's connection failed to take them, by outcome.",
+	}, []string{"outcome"})
+
+	encodeFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chat_ws_encode_failures_total",
+		Help: "Number of outgoing WebSocket messages that could not be encoded.",
+	})
+
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_ws_messages_received_total",
+		Help: "Number of WebSocket messages received from clients, by message type.",
+	}, []string{"type"})
+
+	clientQueueDepth = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "chat_ws_client_queue_depth",
+		Help:    "Number of messages already waiting in a client's send queue when another is queued.",
+		Buckets: []float64{0, 1, 4, 16, 64, 128, 192, 256},
+	})
+
+	activeCalls = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_ws_active_calls",
+		Help: "Number of calls ringing or in progress.",
+	})
+
+	callsEnded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_ws_calls_ended_total",
+		Help: "Number of calls ended, by reason.",
+	}, []string{"reason"})
+
+	roundTripTime = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "chat_ws_rtt_seconds",
+		Help:    "Round trip time of pings to WebSocket connections.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 10),
+	})
+
+	connectionsByQuality = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "chat_ws_connections_by_quality",
+		Help: "Number of open WebSocket connections, by quality rating from their smoothed round trip time.",
+	}, []string{"quality"})
+
+	idleDisconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chat_ws_idle_disconnects_total",
+		Help: "Total number of WebSocket connections closed after going silent for longer than the pong wait.",
+	})
+
+	forcedUpgrades = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_ws_forced_upgrades_total",
+		Help: "Total number of WebSocket clients told to upgrade their app, by platform.",
+	}, []string{"platform"})
+
+	broadcastDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chat_ws_broadcast_duration_seconds",
+		Help:    "Time taken to queue a broadcast for every connected client, by message type.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+	}, []string{"type"})
+)
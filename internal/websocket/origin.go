@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OriginPolicy decides which web origins may open WebSocket connections.
+// Browsers attach cookies and other ambient credentials to cross-site
+// upgrades, so accepting any origin would let other sites open connections
+// as the user.
+type OriginPolicy struct {
+	any      bool
+	exact    map[string]bool // scheme://host[:port]
+	suffixes []originSuffix  // from *.example.com patterns
+}
+
+// originSuffix matches the subdomains of a host for one scheme
+type originSuffix struct {
+	scheme string
+	suffix string // ".example.com[:port]"
+}
+
+// NewOriginPolicy creates a policy allowing the given origins in addition
+// to the server's own. A pattern is an origin such as https://example.com,
+// an origin whose host starts with "*." such as https://*.example.com to
+// allow every subdomain, or "*" to allow any origin.
+func NewOriginPolicy(patterns []string) (*OriginPolicy, error) {
+	p := &OriginPolicy{exact: make(map[string]bool)}
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			p.any = true
+			continue
+		}
+
+		u, err := url.Parse(strings.ToLower(pattern))
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("invalid allowed origin %q", pattern)
+		}
+
+		if strings.HasPrefix(u.Host, "*.") {
+			p.suffixes = append(p.suffixes, originSuffix{scheme: u.Scheme, suffix: u.Host[1:]})
+			continue
+		}
+		if strings.Contains(u.Host, "*") {
+			return nil, fmt.Errorf("invalid allowed origin %q: only a leading *. wildcard is supported", pattern)
+		}
+		p.exact[u.Scheme+"://"+u.Host] = true
+	}
+	return p, nil
+}
+
+// CheckOrigin reports whether an upgrade request's origin is allowed.
+// Requests without an Origin header come from clients other than browsers
+// and are allowed, as are requests from the server's own origin.
+func (p *OriginPolicy) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || p.any {
+		return true
+	}
+
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Host == strings.ToLower(r.Host) || p.exact[u.Scheme+"://"+u.Host] {
+		return true
+	}
+	for _, s := range p.suffixes {
+		if u.Scheme == s.scheme && strings.HasSuffix(u.Host, s.suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package websocket
+
+import (
+	"sync"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/google/uuid"
+)
+
+// presenceSubscriptions tracks which connections follow the presence of
+// which users, so presence updates are only sent to the connections that
+// subscribed to them instead of to everyone
+type presenceSubscriptions struct {
+	mu       sync.RWMutex
+	watchers map[uuid.UUID]map[*Client]bool // followed user to connections
+	watching map[*Client][]uuid.UUID
+}
+
+// newPresenceSubscriptions creates an empty set of subscriptions
+func newPresenceSubscriptions() *presenceSubscriptions {
+	return &presenceSubscriptions{
+		watchers: make(map[uuid.UUID]map[*Client]bool),
+		watching: make(map[*Client][]uuid.UUID),
+	}
+}
+
+// set replaces the users a connection follows
+func (s *presenceSubscriptions) set(client *Client, userIDs []uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeLocked(client)
+	if len(userIDs) == 0 {
+		return
+	}
+	for _, userID := range userIDs {
+		watchers, ok := s.watchers[userID]
+		if !ok {
+			watchers = make(map[*Client]bool)
+			s.watchers[userID] = watchers
+		}
+		watchers[client] = true
+	}
+	s.watching[client] = userIDs
+}
+
+// remove drops a connection's subscriptions
+func (s *presenceSubscriptions) remove(client *Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(client)
+}
+
+func (s *presenceSubscriptions) removeLocked(client *Client) {
+	for _, userID := range s.watching[client] {
+		watchers := s.watchers[userID]
+		delete(watchers, client)
+		if len(watchers) == 0 {
+			delete(s.watchers, userID)
+		}
+	}
+	delete(s.watching, client)
+}
+
+// watchersOf returns the connections following a user
+func (s *presenceSubscriptions) watchersOf(userID uuid.UUID) []*Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(s.watchers[userID]))
+	for client := range s.watchers[userID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// handlePresenceSubscribe replaces the users a client follows and sends it
+// the presence of those who are online. Users it does not hear about are
+// offline, or do not let the client see their presence.
+func (r *Router) handlePresenceSubscribe(client *Client, message *models.WebSocketMessage) {
+	var data protocol.PresenceSubscribe
+	if !decodePayload(client, message, &data) {
+		return
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(data.UserIDs))
+	seen := make(map[uuid.UUID]bool, len(data.UserIDs))
+	for _, id := range data.UserIDs {
+		userID, err := uuid.Parse(id)
+		if err != nil {
+			client.sendError(errcode.InvalidRequest, "Invalid user ID", message)
+			return
+		}
+		if !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	ctx, cancel := r.messageContext(client, message)
+	defer cancel()
+
+	if r.hub.messageService == nil {
+		r.logger.WithContext(ctx).Error("Message service is not available")
+		client.sendError(errcode.Internal, "Server error: repository unavailable", message)
+		return
+	}
+	userIDs, err := r.hub.messageService.VisiblePresence(ctx, client.userID, userIDs)
+	if err != nil {
+		client.sendError(errcode.Internal, "Failed to subscribe to presence", message)
+		return
+	}
+	r.hub.presence.set(client, userIDs)
+
+	for _, userID := range userIDs {
+		followed := r.hub.mostRecentClient(userID)
+		if followed == nil {
+			continue
+		}
+		lastSeen, platform := followed.presence()
+		client.SendMessage(&models.WebSocketMessage{
+			Type:      protocol.TypePresenceUpdate,
+			RequestID: message.RequestID,
+			Data: models.PresenceData{
+				UserID:   userID.String(),
+				Username: followed.username,
+				Status:   "online",
+				LastSeen: &lastSeen,
+				Platform: platform,
+			},
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package websocket
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+)
+
+// rttSmoothing is the weight of each new round trip time in a connection's
+// smoothed round trip time, as in TCP's estimator
+const rttSmoothing = 0.125
+
+// Smoothed round trip times above which a connection is rated fair and poor
+const (
+	fairRTT = 300 * time.Millisecond
+	poorRTT = time.Second
+)
+
+// qualityState tracks a connection's round trip time, measured from the
+// server's pings
+type qualityState struct {
+	mu       sync.Mutex
+	rtt      time.Duration // last measurement
+	smoothed time.Duration
+	quality  string // empty until the first pong
+}
+
+// pingPayload returns the payload of a ping sent at a time. Clients echo
+// it in their pong, so the round trip can be timed without keeping state.
+func pingPayload(sentAt time.Time) []byte {
+	return strconv.AppendInt(nil, sentAt.UnixNano(), 10)
+}
+
+// handlePong measures the round trip time of the ping a pong answers and
+// tells clients that asked for connection_quality messages when their
+// connection's rating changes. Pongs not answering a ping are ignored.
+func (c *Client) handlePong(appData string, receivedAt time.Time) {
+	sentAt, err := strconv.ParseInt(appData, 10, 64)
+	if err != nil {
+		return
+	}
+	rtt := receivedAt.Sub(time.Unix(0, sentAt))
+	if rtt < 0 || rtt > pongWait {
+		return
+	}
+	roundTripTime.Observe(rtt.Seconds())
+
+	c.quality.mu.Lock()
+	if c.quality.quality == "" {
+		c.quality.smoothed = rtt
+	} else {
+		c.quality.smoothed += time.Duration(rttSmoothing * float64(rtt-c.quality.smoothed))
+	}
+	c.quality.rtt = rtt
+	previous := c.quality.quality
+	c.quality.quality = rateRTT(c.quality.smoothed)
+	data := models.ConnectionQualityData{
+		Quality:           c.quality.quality,
+		RTTMillis:         rtt.Milliseconds(),
+		SmoothedRTTMillis: c.quality.smoothed.Milliseconds(),
+	}
+	if data.Quality != previous {
+		if previous != "" {
+			connectionsByQuality.WithLabelValues(previous).Dec()
+		}
+		connectionsByQuality.WithLabelValues(data.Quality).Inc()
+	}
+	c.quality.mu.Unlock()
+
+	if data.Quality != previous && c.qualityEvents {
+		c.SendMessage(&models.WebSocketMessage{
+			Type: protocol.TypeConnectionQuality,
+			Data: data,
+		})
+	}
+}
+
+// RTT returns the connection's last and smoothed round trip times, or
+// zero before the first pong
+func (c *Client) RTT() (last, smoothed time.Duration) {
+	c.quality.mu.Lock()
+	defer c.quality.mu.Unlock()
+	return c.quality.rtt, c.quality.smoothed
+}
+
+// clearQuality removes a closed connection from the quality metrics
+func (c *Client) clearQuality() {
+	c.quality.mu.Lock()
+	defer c.quality.mu.Unlock()
+
+	if c.quality.quality != "" {
+		connectionsByQuality.WithLabelValues(c.quality.quality).Dec()
+		c.quality.quality = ""
+	}
+}
+
+// rateRTT rates a connection by its smoothed round trip time
+func rateRTT(smoothed time.Duration) string {
+	switch {
+	case smoothed > poorRTT:
+		return models.ConnectionPoor
+	case smoothed > fairRTT:
+		return models.ConnectionFair
+	default:
+		return models.ConnectionGood
+	}
+}
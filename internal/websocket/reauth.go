@@ -0,0 +1,170 @@
+package websocket
+
+import (
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/token"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// How long before the access token expires the client is asked for a
+	// new one
+	reauthWarning = time.Minute
+
+	// How long after the access token expires the connection stays open
+	// waiting for a new one
+	reauthGrace = 30 * time.Second
+)
+
+// authState tracks when a connection's access token expires. Each new
+// token starts a new generation, so timers set for an older token do
+// nothing when they fire.
+type authState struct {
+	expiresAt  time.Time
+	generation int
+	timer      *time.Timer
+
+	// session is the session the current token was issued for, which
+	// changes from the connection's sessionID once the device refreshes
+	session string
+}
+
+// setAuthSession records the session the connection's current access
+// token was issued for
+func (c *Client) setAuthSession(sessionID string) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.auth.session = sessionID
+}
+
+// authSession returns the session the connection's current access token
+// was issued for
+func (c *Client) authSession() string {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return c.auth.session
+}
+
+// setAuthExpiry records the expiry of the connection's access token and
+// schedules the reauth_required warning
+func (c *Client) setAuthExpiry(expiresAt time.Time) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if c.auth.timer != nil {
+		c.auth.timer.Stop()
+	}
+	c.auth.expiresAt = expiresAt
+	c.auth.generation++
+	generation := c.auth.generation
+	c.auth.timer = time.AfterFunc(time.Until(expiresAt.Add(-reauthWarning)), func() {
+		c.requireReauth(generation)
+	})
+}
+
+// requireReauth asks the client for a new access token and closes the
+// connection if none arrives before the grace period ends
+func (c *Client) requireReauth(generation int) {
+	c.authMu.Lock()
+	if c.auth.generation != generation {
+		c.authMu.Unlock()
+		return
+	}
+	expiresAt := c.auth.expiresAt
+	c.auth.timer = time.AfterFunc(time.Until(expiresAt.Add(reauthGrace)), func() {
+		c.expireAuth(generation)
+	})
+	c.authMu.Unlock()
+
+	c.hub.sendToClient(c, &models.WebSocketMessage{
+		Type: protocol.TypeReauthRequired,
+		Data: models.AuthExpiryData{ExpiresAt: expiresAt},
+	})
+}
+
+// expireAuth closes a connection whose access token expired without being
+// replaced
+func (c *Client) expireAuth(generation int) {
+	c.authMu.Lock()
+	current := c.auth.generation == generation
+	c.authMu.Unlock()
+	if !current {
+		return
+	}
+
+	c.logger.Info("Closing connection with expired token", "user_id", c.userID.String())
+	authExpiries.Inc()
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "token expired"), time.Now().Add(writeWait))
+	c.conn.Close()
+}
+
+// stopAuthTimer cancels the pending reauthentication timer when the
+// connection closes
+func (c *Client) stopAuthTimer() {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	c.auth.generation++
+	if c.auth.timer != nil {
+		c.auth.timer.Stop()
+	}
+}
+
+// handleRefreshAuth replaces the connection's access token. The new token
+// must belong to the same user and workspace.
+func (r *Router) handleRefreshAuth(client *Client, message *models.WebSocketMessage) {
+	var data protocol.RefreshAuth
+	if !decodePayload(client, message, &data) {
+		return
+	}
+
+	ctx, cancel := r.messageContext(client, message)
+	defer cancel()
+
+	payload, err := client.tokens.VerifyToken(ctx, data.Token)
+	if errors.Is(err, auth.ErrUserBanned) {
+		client.sendError(errcode.Forbidden, "Account is banned", message)
+		return
+	}
+	if err != nil {
+		r.logger.WithContext(ctx).Info("Rejected token refresh", "error", err)
+		client.sendError(errcode.Unauthenticated, "Invalid authentication token", message)
+		return
+	}
+	if !payload.HasScope(token.ScopeWebSocket) {
+		client.sendError(errcode.Forbidden, "Token does not grant WebSocket access", message)
+		return
+	}
+	if userID, err := uuid.Parse(payload.UserID); err != nil || userID != client.userID {
+		client.sendError(errcode.Unauthenticated, "Token belongs to another user", message)
+		return
+	}
+	if payload.WorkspaceID != workspaceClaim(client.workspaceID) {
+		client.sendError(errcode.Unauthenticated, "Token is for another workspace", message)
+		return
+	}
+
+	client.setAuthSession(payload.SessionID)
+	client.setAuthExpiry(payload.ExpiredAt)
+	client.SendMessage(&models.WebSocketMessage{
+		Type:      protocol.TypeAuthRefreshed,
+		RequestID: message.RequestID,
+		Data:      models.AuthExpiryData{ExpiresAt: payload.ExpiredAt},
+	})
+}
+
+// workspaceClaim returns the token workspace claim matching a connection's
+// workspace, empty outside workspaces
+func workspaceClaim(workspaceID *uuid.UUID) string {
+	if workspaceID == nil {
+		return ""
+	}
+	return workspaceID.String()
+}
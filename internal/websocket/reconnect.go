@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/gorilla/websocket"
+)
+
+// Reasons the server closes a connection with a reconnect hint
+const (
+	reconnectShutdown    = "shutdown"
+	reconnectOverloaded  = "overloaded"
+	reconnectRateLimited = "rate_limited"
+)
+
+// reconnectPolicy is the close code and reconnect delay for a reason. The
+// delay is spread over [delay, delay+jitter) so clients disconnected
+// together do not all come back at once.
+type reconnectPolicy struct {
+	code   int
+	delay  time.Duration
+	jitter time.Duration
+}
+
+// reconnectPolicies maps each reason to its policy
+var reconnectPolicies = map[string]reconnectPolicy{
+	reconnectShutdown:    {code: websocket.CloseServiceRestart, delay: time.Second, jitter: 10 * time.Second},
+	reconnectOverloaded:  {code: websocket.CloseTryAgainLater, delay: 5 * time.Second, jitter: 10 * time.Second},
+	reconnectRateLimited: {code: websocket.ClosePolicyViolation, delay: 30 * time.Second, jitter: 30 * time.Second},
+}
+
+// reconnectHint builds the close frame for a reason, with a reconnect hint
+// as its JSON reason
+func (h *Hub) reconnectHint(reason string) []byte {
+	policy := reconnectPolicies[reason]
+	delay := policy.delay + time.Duration(rand.Int63n(int64(policy.jitter)))
+
+	hint, _ := json.Marshal(models.ReconnectHint{
+		Reason:       reason,
+		RetryAfterMs: delay.Milliseconds(),
+		Epoch:        h.epoch,
+	})
+	return websocket.FormatCloseMessage(policy.code, string(hint))
+}
+
+// closeWithHint sends a close frame telling the client when to reconnect and
+// closes the connection, which ends the read and write pumps. Only the
+// first call on a client has any effect.
+func (c *Client) closeWithHint(reason string) {
+	c.hinted.Do(func() {
+		reconnectHints.WithLabelValues(reason).Inc()
+		c.conn.WriteControl(websocket.CloseMessage, c.hub.reconnectHint(reason), time.Now().Add(writeWait))
+		c.conn.Close()
+	})
+}
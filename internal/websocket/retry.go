@@ -0,0 +1,187 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// retryBaseDelay is the wait before a message's first retry, doubling
+	// with each later attempt
+	retryBaseDelay = 250 * time.Millisecond
+
+	// retryMaxAttempts is how often a message is retried before it is
+	// delivered offline even though the user still has connections
+	retryMaxAttempts = 5
+
+	// retryQueueLimit bounds the messages waiting to be retried per user;
+	// further messages go straight to offline delivery
+	retryQueueLimit = 256
+)
+
+// Retry outcomes
+const (
+	retryRedelivered = "redelivered"
+	retryOffline     = "offline"
+	retryDropped     = "dropped"
+)
+
+// retryEntry is a message that could not be written to one of a user's
+// connections
+type retryEntry struct {
+	message *models.WebSocketMessage
+	attempt int
+
+	// received holds the connections assumed to have the message: those
+	// that were sent it, or were open elsewhere when it failed
+	received map[*Client]bool
+}
+
+// retryQueues holds each user's messages waiting to be retried. A user
+// with pending messages has one goroutine working through their queue in
+// order; the map holds an entry for exactly those users.
+type retryQueues struct {
+	mu     sync.Mutex
+	queues map[uuid.UUID][]retryEntry
+}
+
+func newRetryQueues() *retryQueues {
+	return &retryQueues{queues: make(map[uuid.UUID][]retryEntry)}
+}
+
+// retryDelay returns how long to wait before a message's next attempt
+func retryDelay(attempt int) time.Duration {
+	return retryBaseDelay << attempt
+}
+
+// retryFailedWrite queues a message the hub sent to a user that the
+// client's write pump could not write. The user's other connections were
+// sent it too, so only connections opened since then are retried.
+func (h *Hub) retryFailedWrite(client *Client, out outbound) {
+	received := make(map[*Client]bool)
+	h.mu.RLock()
+	for other := range h.userClients[client.userID.String()] {
+		if other != client {
+			received[other] = true
+		}
+	}
+	h.mu.RUnlock()
+
+	if out.attempt+1 >= retryMaxAttempts {
+		h.deliverOffline(client.userID, out.message)
+		return
+	}
+	h.queueRetry(client.userID, retryEntry{message: out.message, attempt: out.attempt + 1, received: received})
+}
+
+// queueRetry adds a message to the user's retry queue, starting the
+// goroutine that works through it if there is none
+func (h *Hub) queueRetry(userID uuid.UUID, entry retryEntry) {
+	h.retries.mu.Lock()
+	queue, running := h.retries.queues[userID]
+	if len(queue) >= retryQueueLimit {
+		h.retries.mu.Unlock()
+		h.deliverOffline(userID, entry.message)
+		return
+	}
+	h.retries.queues[userID] = append(queue, entry)
+	h.retries.mu.Unlock()
+
+	if !running {
+		go h.runRetries(userID, entry.attempt)
+	}
+}
+
+// runRetries retries the user's queued messages with exponential backoff
+// until the queue is empty
+func (h *Hub) runRetries(userID uuid.UUID, attempt int) {
+	for {
+		time.Sleep(retryDelay(attempt))
+
+		h.retries.mu.Lock()
+		batch := h.retries.queues[userID]
+		h.retries.queues[userID] = nil
+		h.retries.mu.Unlock()
+
+		var failed []retryEntry
+		for _, entry := range batch {
+			if !h.redeliver(userID, entry) {
+				entry.attempt++
+				failed = append(failed, entry)
+			}
+		}
+
+		h.retries.mu.Lock()
+		queue := append(failed, h.retries.queues[userID]...)
+		if len(queue) == 0 {
+			delete(h.retries.queues, userID)
+			h.retries.mu.Unlock()
+			return
+		}
+		h.retries.queues[userID] = queue
+		attempt = queue[0].attempt
+		h.retries.mu.Unlock()
+	}
+}
+
+// redeliver sends a queued message to the user's connections that do not
+// have it yet, reporting whether it is done with. It is kept while any
+// connection still could not take it. Once the user has no connections,
+// or the message has run out of attempts, it is delivered offline instead.
+func (h *Hub) redeliver(userID uuid.UUID, entry retryEntry) bool {
+	h.mu.RLock()
+	connections := h.userClients[userID.String()]
+	if len(connections) == 0 {
+		h.mu.RUnlock()
+		h.deliverOffline(userID, entry.message)
+		return true
+	}
+
+	sent, pending := false, false
+	for client := range connections {
+		if entry.received[client] {
+			continue
+		}
+		if client.queue(outbound{message: entry.message, retry: true, attempt: entry.attempt}) {
+			entry.received[client] = true
+			sent = true
+		} else {
+			pending = true
+		}
+	}
+	h.mu.RUnlock()
+
+	switch {
+	case pending && entry.attempt+1 >= retryMaxAttempts:
+		h.deliverOffline(userID, entry.message)
+		return true
+	case pending:
+		return false
+	case sent:
+		messageRetries.WithLabelValues(retryRedelivered).Inc()
+		return true
+	default:
+		// Every open connection already has the message
+		messageRetries.WithLabelValues(retryDropped).Inc()
+		return true
+	}
+}
+
+// deliverOffline hands a message the user's connections could not be sent
+// to the message service's offline delivery, off the caller's goroutine
+func (h *Hub) deliverOffline(userID uuid.UUID, message *models.WebSocketMessage) {
+	messageRetries.WithLabelValues(retryOffline).Inc()
+	if h.messageService == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		h.messageService.DeliverOffline(ctx, userID, message)
+	}()
+}
@@ -2,37 +2,64 @@ package websocket
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"time"
 
+	"github.com/codingminions/Whatsapp-Lite/internal/conversation"
 	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
 	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
 	"github.com/google/uuid"
 )
 
 // MessageHandler defines a function that handles a specific type of message
 type MessageHandler func(client *Client, message *models.WebSocketMessage)
 
+// FeatureFlags reports whether a feature is enabled for a user
+type FeatureFlags interface {
+	Enabled(name string, userID uuid.UUID) bool
+}
+
 // Router routes WebSocket messages to appropriate handlers
 type Router struct {
-	handlers map[string]MessageHandler
-	hub      *Hub
-	logger   logger.Logger
+	handlers         map[protocol.MessageType]MessageHandler
+	hub              *Hub
+	calls            *callRegistry
+	messageValidator *validator.MessageValidator
+	flags            FeatureFlags
+	messageTimeout   time.Duration
+	logger           logger.Logger
 }
 
-// NewRouter creates a new router
-func NewRouter(hub *Hub, logger logger.Logger) *Router {
+// NewRouter creates a new router. Each message is handled within
+// messageTimeout, or for as long as the connection lasts if it is 0.
+func NewRouter(hub *Hub, messageValidator *validator.MessageValidator, flags FeatureFlags, messageTimeout time.Duration, logger logger.Logger) *Router {
 	r := &Router{
-		handlers: make(map[string]MessageHandler),
-		hub:      hub,
-		logger:   logger,
+		handlers:         make(map[protocol.MessageType]MessageHandler),
+		hub:              hub,
+		calls:            newCallRegistry(hub, logger),
+		messageValidator: messageValidator,
+		flags:            flags,
+		messageTimeout:   messageTimeout,
+		logger:           logger,
 	}
 
 	// Register the message handlers
-	r.handlers["direct_message"] = r.handleDirectMessage
-	r.handlers["typing_indicator"] = r.handleTypingIndicator
-	r.handlers["read_receipt"] = r.handleReadReceipt
-	r.handlers["presence"] = r.handlePresenceUpdate
+	r.handlers[protocol.TypeDirectMessage] = r.handleDirectMessage
+	r.handlers[protocol.TypeTypingIndicator] = r.handleTypingIndicator
+	r.handlers[protocol.TypeReadReceipt] = r.handleReadReceipt
+	r.handlers[protocol.TypePresence] = r.handlePresenceUpdate
+	r.handlers[protocol.TypeCallOffer] = r.handleCallOffer
+	r.handlers[protocol.TypeCallAnswer] = r.handleCallAnswer
+	r.handlers[protocol.TypeICECandidate] = r.handleICECandidate
+	r.handlers[protocol.TypeCallEnd] = r.handleCallEnd
+	r.handlers[protocol.TypeRefreshAuth] = r.handleRefreshAuth
+	r.handlers[protocol.TypeHello] = r.handleHello
+	r.handlers[protocol.TypePresenceSubscribe] = r.handlePresenceSubscribe
 
 	return r
 }
@@ -41,14 +68,43 @@ func NewRouter(hub *Hub, logger logger.Logger) *Router {
 func (r *Router) RouteMessage(client *Client, message *models.WebSocketMessage) {
 	handler, ok := r.handlers[message.Type]
 	if !ok {
-		r.logger.Error("Unknown message type received", "type", message.Type)
-		client.sendError(1001, "Invalid message type", message.Type)
+		messagesReceived.WithLabelValues("unknown").Inc()
+		r.logger.Error("Unknown message type received", "type", message.Type, "request_id", message.RequestID)
+		client.sendError(errcode.UnknownMessageType, "Invalid message type", message)
 		return
 	}
 
+	messagesReceived.WithLabelValues(string(message.Type)).Inc()
 	handler(client, message)
 }
 
+// messageContext returns the context for handling a message: the client's
+// connection context, correlated with the message's request ID and bounded
+// by the per-message timeout
+func (r *Router) messageContext(client *Client, message *models.WebSocketMessage) (context.Context, context.CancelFunc) {
+	ctx := requestid.NewContext(client.ctx, message.RequestID)
+	if r.messageTimeout > 0 {
+		return context.WithTimeout(ctx, r.messageTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// decodePayload decodes a message's data into its payload, sending an
+// error listing the invalid fields if it does not match the schema
+func decodePayload(client *Client, message *models.WebSocketMessage, payload interface{}) bool {
+	err := protocol.Decode(message, payload)
+	if err == nil {
+		return true
+	}
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		client.sendValidationError(validationErrors, message)
+	} else {
+		client.sendError(errcode.InvalidRequest, "Invalid message format", message)
+	}
+	return false
+}
+
 // Helper min function for string truncation
 func min(a, b int) int {
 	if a < b {
@@ -59,243 +115,148 @@ func min(a, b int) int {
 
 // handleDirectMessage handles a direct message
 func (r *Router) handleDirectMessage(client *Client, message *models.WebSocketMessage) {
-	// Convert to a proper map if needed
-	data, ok := message.Data.(map[string]interface{})
-	if !ok {
-		// If data is not a map, try to marshal and unmarshal to convert to the right format
-		dataBytes, err := json.Marshal(message.Data)
-		if err != nil {
-			client.sendError(1000, "Invalid message format", message.Type)
-			return
-		}
-
-		err = json.Unmarshal(dataBytes, &data)
-		if err != nil {
-			client.sendError(1000, "Invalid message format", message.Type)
-			return
-		}
-	}
-
-	// Extract recipient ID and content
-	recipientIDStr, ok := data["recipient_id"].(string)
-	if !ok {
-		client.sendError(1000, "Missing recipient_id", message.Type)
+	var data protocol.DirectMessage
+	if !decodePayload(client, message, &data) {
 		return
 	}
+	clientMsgID := data.MessageID
 
-	content, ok := data["content"].(string)
-	if !ok {
-		client.sendError(1000, "Missing message content", message.Type)
+	// Validate and normalize content
+	content, err := r.messageValidator.Normalize(data.Content)
+	if err != nil {
+		client.sendError(errcode.InvalidContent, err.Error(), message)
 		return
 	}
 
-	clientMsgID, ok := data["message_id"].(string)
-	if !ok {
-		client.sendError(1000, "Missing client message_id", message.Type)
+	// Optional message format, plain text by default
+	format, err := r.messageValidator.Format(data.Format)
+	if err != nil {
+		client.sendError(errcode.InvalidContent, err.Error(), message)
 		return
 	}
 
 	// Parse recipient ID
-	recipientID, err := uuid.Parse(recipientIDStr)
+	recipientID, err := uuid.Parse(data.RecipientID)
 	if err != nil {
-		client.sendError(1002, "Invalid recipient ID", message.Type)
+		client.sendError(errcode.InvalidRecipient, "Invalid recipient ID", message)
 		return
 	}
 
 	// Generate a server message ID
 	serverMsgID := uuid.New()
 
-	// Create conversation ID (smaller UUID first)
-	conversationID := ""
-	if client.userID.String() < recipientIDStr {
-		conversationID = client.userID.String() + "-" + recipientIDStr
-	} else {
-		conversationID = recipientIDStr + "-" + client.userID.String()
-	}
-
-	// Send acknowledgment to sender with sent status
-	ack := &models.WebSocketMessage{
-		Type: "message_ack",
-		Data: models.MessageAckData{
-			ClientMessageID: clientMsgID,
-			ServerMessageID: serverMsgID.String(),
-			Status:          "sent",
-			Timestamp:       time.Now(),
-		},
-	}
-	client.SendMessage(ack)
+	// Acknowledge the message as queued until it is saved
+	client.sendAck(message, clientMsgID, serverMsgID, models.DeliveryQueued, time.Now())
 
 	// Create message
-	now := time.Now()
 	msg := &models.DirectMessage{
 		ID:          serverMsgID,
 		SenderID:    client.userID,
 		RecipientID: recipientID,
 		Content:     content,
+		Format:      format,
 		Delivered:   false,
 		Read:        false,
-		CreatedAt:   now,
+		CreatedAt:   time.Now(),
+		WorkspaceID: client.workspaceID,
 	}
 
-	// Log message details for debugging
-	r.logger.Info("Attempting to save direct message",
+	// Save within the client's connection context, so the save is abandoned
+	// if the client disconnects
+	ctx, cancel := r.messageContext(client, message)
+	defer cancel()
+
+	r.logger.WithContext(ctx).Debug("Sending direct message",
 		"message_id", serverMsgID,
 		"sender_id", client.userID,
 		"recipient_id", recipientID,
-		"content_preview", content[:min(20, len(content))])
+		"content", logger.Sensitive(content))
 
-	// Save to database
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if r.hub.conversationRepo == nil {
-		r.logger.Error("Conversation repository is not available")
-		client.sendError(1009, "Server error: repository unavailable", message.Type)
+	// Save to database and forward to the recipient
+	if r.hub.messageService == nil {
+		r.logger.WithContext(ctx).Error("Message service is not available")
+		client.sendError(errcode.Internal, "Server error: repository unavailable", message)
 		return
 	}
 
-	err = r.hub.conversationRepo.SaveMessage(ctx, msg)
+	saved, err := r.hub.messageService.SendMessage(ctx, msg, client.username)
+	if errors.Is(err, conversation.ErrMessagePending) {
+		// Buffered; the flush acknowledges the outcome
+		return
+	}
 	if err != nil {
-		r.logger.Error("Failed to save message to database", "error", err)
-		client.sendError(1009, "Failed to save message: "+err.Error(), message.Type)
+		client.sendAck(message, clientMsgID, serverMsgID, models.DeliveryFailed, time.Now())
+	}
+	if errors.Is(err, conversation.ErrRecipientNotFound) {
+		client.sendError(errcode.RecipientNotFound, "Recipient does not exist", message)
 		return
 	}
-
-	r.logger.Info("Message saved successfully", "message_id", serverMsgID)
-
-	// Send delivered acknowledgment
-	deliveredAck := &models.WebSocketMessage{
-		Type: "message_ack",
-		Data: models.MessageAckData{
-			ClientMessageID: clientMsgID,
-			ServerMessageID: serverMsgID.String(),
-			Status:          "delivered",
-			Timestamp:       time.Now(),
-		},
+	if errors.Is(err, conversation.ErrInvalidData) {
+		client.sendError(errcode.InvalidContent, "Message contains a value that is not allowed", message)
+		return
 	}
-	client.SendMessage(deliveredAck)
-
-	// Forward the message to the recipient if they're online
-	recipientConnected := r.hub.IsUserConnected(recipientID)
-	if recipientConnected {
-		forwardMsg := &models.WebSocketMessage{
-			Type: "direct_message",
-			Data: models.DirectMessageData{
-				MessageID:      serverMsgID.String(),
-				ConversationID: conversationID,
-				SenderID:       client.userID.String(),
-				SenderUsername: client.username,
-				Content:        content,
-				Timestamp:      now,
-			},
-		}
-		r.hub.SendToUser(recipientID, forwardMsg)
+	if errors.Is(err, conversation.ErrNotContact) {
+		client.sendError(errcode.Forbidden, "Recipient has not accepted you as a contact", message)
+		return
 	}
-}
-
-// handleTypingIndicator handles a typing indicator
-func (r *Router) handleTypingIndicator(client *Client, message *models.WebSocketMessage) {
-	data, ok := message.Data.(map[string]interface{})
-	if !ok {
-		client.sendError(1000, "Invalid message format", message.Type)
+	if errors.Is(err, conversation.ErrNotAccepting) {
+		client.sendError(errcode.RecipientNotAccepting, "Recipient is not accepting messages", message)
 		return
 	}
-
-	// Extract recipient ID and status
-	recipientIDStr, ok := data["recipient_id"].(string)
-	if !ok {
-		client.sendError(1000, "Missing recipient_id", message.Type)
+	if errors.Is(err, conversation.ErrNotInWorkspace) {
+		client.sendError(errcode.Forbidden, "Recipient is not a member of the workspace", message)
 		return
 	}
-
-	status, ok := data["status"].(string)
-	if !ok {
-		client.sendError(1000, "Missing status", message.Type)
+	if errors.Is(err, database.ErrCircuitOpen) {
+		client.sendError(errcode.Unavailable, "Messaging is temporarily unavailable, try again shortly", message)
 		return
 	}
-
-	// Parse recipient ID
-	recipientID, err := uuid.Parse(recipientIDStr)
 	if err != nil {
-		client.sendError(1002, "Invalid recipient ID", message.Type)
+		r.logger.WithContext(ctx).Error("Failed to save message to database", "error", err)
+		client.sendError(errcode.Internal, "Failed to save message: "+err.Error(), message)
 		return
 	}
 
-	// Forward typing indicator to recipient
-	msg := &models.WebSocketMessage{
-		Type: "typing_indicator",
-		Data: models.TypingIndicatorData{
-			UserID:   client.userID.String(),
-			Username: client.username,
-			Status:   status,
-		},
-	}
-	r.hub.SendToUser(recipientID, msg)
+	r.logger.WithContext(ctx).Debug("Direct message sent", "message_id", serverMsgID)
+
+	// Acknowledge the saved message's state with its canonical timestamp.
+	// Later transitions arrive as message_status events.
+	client.sendAck(message, clientMsgID, serverMsgID, saved.DeliveryState, saved.Timestamp)
 }
 
 // handleReadReceipt handles a read receipt
 func (r *Router) handleReadReceipt(client *Client, message *models.WebSocketMessage) {
-	data, ok := message.Data.(map[string]interface{})
-	if !ok {
-		client.sendError(1000, "Invalid message format", message.Type)
-		return
-	}
-
-	// Extract conversation ID and last read message ID
-	conversationIDStr, ok := data["conversation_id"].(string)
-	if !ok {
-		client.sendError(1000, "Missing conversation_id", message.Type)
+	var data protocol.ReadReceipt
+	if !decodePayload(client, message, &data) {
 		return
 	}
 
-	lastReadMsgIDStr, ok := data["last_read_message_id"].(string)
-	if !ok {
-		client.sendError(1000, "Missing last_read_message_id", message.Type)
-		return
-	}
-
-	// TODO: Update read status in database
-	// This should be done through a service call
+	ctx, cancel := r.messageContext(client, message)
+	defer cancel()
 
-	// Forward read receipt to the other user in the conversation
-	// For direct messages, the conversation ID is a combination of the two user IDs
-	// TODO: Get the other user ID from the conversation ID
-	otherUserID, err := uuid.Parse("00000000-0000-0000-0000-000000000000") // Placeholder
-	if err != nil {
-		client.sendError(1003, "Invalid conversation ID", message.Type)
+	if r.hub.messageService == nil {
+		r.logger.WithContext(ctx).Error("Message service is not available")
+		client.sendError(errcode.Internal, "Server error: repository unavailable", message)
 		return
 	}
 
-	msg := &models.WebSocketMessage{
-		Type: "read_receipt",
-		Data: models.ReadReceiptData{
-			UserID:            client.userID.String(),
-			Username:          client.username,
-			ConversationID:    conversationIDStr,
-			LastReadMessageID: lastReadMsgIDStr,
-		},
+	// Move the shared read cursor; the service sends the receipt to the
+	// other participant and syncs the user's other devices
+	err := r.hub.messageService.MarkRead(ctx, data.ConversationID, client.userID, client.username, client.sessionID, data.LastReadMessageID)
+	switch {
+	case errors.Is(err, conversation.ErrUnauthorized):
+		client.sendError(errcode.Forbidden, "Not a participant in this conversation", message)
+	case errors.Is(err, conversation.ErrMessageNotFound):
+		client.sendError(errcode.NotFound, "Message not found", message)
+	case err != nil:
+		client.sendError(errcode.Internal, "Failed to mark messages as read", message)
 	}
-	r.hub.SendToUser(otherUserID, msg)
 }
 
 // handlePresenceUpdate handles a presence update
 func (r *Router) handlePresenceUpdate(client *Client, message *models.WebSocketMessage) {
-	data, ok := message.Data.(map[string]interface{})
-	if !ok {
-		client.sendError(1000, "Invalid message format", message.Type)
-		return
-	}
-
-	// Extract status
-	status, ok := data["status"].(string)
-	if !ok {
-		client.sendError(1000, "Missing status", message.Type)
-		return
-	}
-
-	// Validate status
-	if status != "online" && status != "away" && status != "offline" {
-		client.sendError(1000, "Invalid status value", message.Type)
+	var data protocol.Presence
+	if !decodePayload(client, message, &data) {
 		return
 	}
 
@@ -303,5 +264,5 @@ func (r *Router) handlePresenceUpdate(client *Client, message *models.WebSocketM
 	// This should be done through a service call
 
 	// Broadcast presence update to all connected clients
-	r.hub.broadcastPresenceUpdate(client.userID, client.username, status)
+	r.hub.broadcastPresenceUpdate(client, data.Status)
 }
@@ -0,0 +1,113 @@
+package websocket
+
+import (
+	"errors"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/conversation"
+	"github.com/codingminions/Whatsapp-Lite/internal/features"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/internal/protocol"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/google/uuid"
+)
+
+const (
+	// How long the result of checking that a client may send typing
+	// indicators to a recipient is reused
+	typingCheckTTL = time.Minute
+
+	// Repeats of the status last forwarded to a recipient are dropped for
+	// this long, so clients sending one per keystroke cost one message
+	typingRepeatInterval = 3 * time.Second
+)
+
+// typingState is what was last forwarded of a client's typing indicators
+// to one recipient
+type typingState struct {
+	conversationID string // empty if the client may not send indicators
+	checkedAt      time.Time
+	status         string
+	sentAt         time.Time
+}
+
+// handleTypingIndicator forwards a typing indicator to the other
+// participant of an existing conversation the client may message. Repeated
+// statuses are coalesced, and indicators the client may not send are
+// dropped silently, since they are best effort.
+func (r *Router) handleTypingIndicator(client *Client, message *models.WebSocketMessage) {
+	// Typing indicators are best effort, drop them silently when disabled
+	if !r.flags.Enabled(features.TypingIndicators, client.userID) {
+		return
+	}
+
+	var data protocol.TypingIndicator
+	if !decodePayload(client, message, &data) {
+		return
+	}
+
+	// Parse recipient ID
+	recipientID, err := uuid.Parse(data.RecipientID)
+	if err != nil {
+		client.sendError(errcode.InvalidRecipient, "Invalid recipient ID", message)
+		return
+	}
+
+	now := time.Now()
+	state := r.typingState(client, message, recipientID, now)
+	if state == nil || state.conversationID == "" {
+		return
+	}
+	if state.status == data.Status && now.Sub(state.sentAt) < typingRepeatInterval {
+		return
+	}
+	state.status = data.Status
+	state.sentAt = now
+
+	// Forward typing indicator to recipient
+	msg := &models.WebSocketMessage{
+		Type: protocol.TypeTypingIndicator,
+		Data: models.TypingIndicatorData{
+			UserID:         client.userID.String(),
+			Username:       client.username,
+			ConversationID: state.conversationID,
+			Status:         data.Status,
+		},
+	}
+	r.hub.SendToUser(recipientID, msg)
+}
+
+// typingState returns what was last forwarded of the client's typing
+// indicators to a recipient, checking that the client may send them if it
+// has not recently. It returns nil if the check failed.
+func (r *Router) typingState(client *Client, message *models.WebSocketMessage, recipientID uuid.UUID, now time.Time) *typingState {
+	if client.typing == nil {
+		client.typing = make(map[uuid.UUID]*typingState)
+	}
+	state, ok := client.typing[recipientID]
+	if ok && now.Sub(state.checkedAt) < typingCheckTTL {
+		return state
+	}
+	if r.hub.messageService == nil {
+		return nil
+	}
+
+	ctx, cancel := r.messageContext(client, message)
+	defer cancel()
+
+	// Denials are remembered like permissions, so a client typing to
+	// someone it may not is not checked on every keystroke
+	conversationID, err := r.hub.messageService.CanType(ctx, client.userID, recipientID)
+	denied := errors.Is(err, conversation.ErrConversationNotFound) || errors.Is(err, conversation.ErrNotContact)
+	if err != nil && !denied {
+		r.logger.WithContext(ctx).Error("Failed to check typing indicator recipient", "error", err)
+		return nil
+	}
+	if !ok {
+		state = &typingState{}
+		client.typing[recipientID] = state
+	}
+	state.conversationID = conversationID
+	state.checkedAt = now
+	return state
+}
@@ -0,0 +1,113 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+// wheelTick is the resolution of the timing wheel. Pings and idle checks
+// run up to a tick late.
+const wheelTick = time.Second
+
+// wheelSlots is the number of slots in the timing wheel. Delays longer than
+// a full turn wait out whole turns in their slot.
+const wheelSlots = 64
+
+// wheelEntry is a client's place on the timing wheel
+type wheelEntry struct {
+	slot   int
+	rounds int // full turns of the wheel left before the entry is due
+}
+
+// timingWheel schedules the pings and idle checks of every connection from
+// a single goroutine, instead of a ticker and read deadline per
+// connection. Each client has at most one entry; when it comes due the
+// client is checked and rescheduled.
+type timingWheel struct {
+	tick time.Duration
+
+	mu      sync.Mutex
+	slots   []map[*Client]*wheelEntry
+	entries map[*Client]*wheelEntry
+	pos     int
+}
+
+// newTimingWheel creates a timing wheel with a resolution of tick
+func newTimingWheel(tick time.Duration, slots int) *timingWheel {
+	w := &timingWheel{
+		tick:    tick,
+		slots:   make([]map[*Client]*wheelEntry, slots),
+		entries: make(map[*Client]*wheelEntry),
+	}
+	for i := range w.slots {
+		w.slots[i] = make(map[*Client]*wheelEntry)
+	}
+	return w
+}
+
+// run advances the wheel every tick
+func (w *timingWheel) run() {
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, client := range w.advance() {
+			if delay := client.wheelCheck(now); delay > 0 {
+				w.schedule(client, delay)
+			}
+		}
+	}
+}
+
+// schedule checks a client after a delay, replacing any check already
+// scheduled for it
+func (w *timingWheel) schedule(client *Client, delay time.Duration) {
+	ticks := int((delay + w.tick - 1) / w.tick)
+	if ticks < 1 {
+		ticks = 1
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if entry, ok := w.entries[client]; ok {
+		delete(w.slots[entry.slot], client)
+	}
+	entry := &wheelEntry{
+		slot:   (w.pos + ticks) % len(w.slots),
+		rounds: (ticks - 1) / len(w.slots),
+	}
+	w.slots[entry.slot][client] = entry
+	w.entries[client] = entry
+}
+
+// cancel removes a client from the wheel
+func (w *timingWheel) cancel(client *Client) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if entry, ok := w.entries[client]; ok {
+		delete(w.slots[entry.slot], client)
+		delete(w.entries, client)
+	}
+}
+
+// advance moves the wheel on a tick and removes and returns the clients
+// that are due
+func (w *timingWheel) advance() []*Client {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pos = (w.pos + 1) % len(w.slots)
+	var due []*Client
+	for client, entry := range w.slots[w.pos] {
+		if entry.rounds > 0 {
+			entry.rounds--
+			continue
+		}
+		delete(w.slots[w.pos], client)
+		delete(w.entries, client)
+		due = append(due, client)
+	}
+	return due
+}
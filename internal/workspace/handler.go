@@ -0,0 +1,270 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/auth"
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
+	"github.com/codingminions/Whatsapp-Lite/pkg/validator"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Handler handles workspace HTTP requests
+type Handler struct {
+	service   Service
+	logger    logger.Logger
+	validator validator.Validator
+}
+
+// NewHandler creates a new workspace handler
+func NewHandler(service Service, logger logger.Logger, validator validator.Validator) *Handler {
+	return &Handler{
+		service:   service,
+		logger:    logger,
+		validator: validator,
+	}
+}
+
+// CreateWorkspace handles requests to create a workspace
+func (h *Handler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse and validate request
+	var req models.CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to decode create workspace request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	workspace, err := h.service.CreateWorkspace(r.Context(), userID, &req)
+	if err != nil {
+		h.sendServiceError(w, r, err, "workspace.create_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusCreated, workspace)
+}
+
+// GetWorkspaces handles requests to list the user's workspaces
+func (h *Handler) GetWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	workspaces, err := h.service.GetWorkspaces(r.Context(), userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "workspace.get_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, workspaces)
+}
+
+// GetWorkspace handles requests to get a workspace
+func (h *Handler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := h.workspaceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	workspace, err := h.service.GetWorkspace(r.Context(), workspaceID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "workspace.get_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, workspace)
+}
+
+// GetMembers handles requests to list a workspace's members
+func (h *Handler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := h.workspaceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Call service
+	members, err := h.service.GetMembers(r.Context(), workspaceID, userID)
+	if err != nil {
+		h.sendServiceError(w, r, err, "workspace.get_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, members)
+}
+
+// SetMember handles requests to add a member to a workspace or change
+// their role
+func (h *Handler) SetMember(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := h.workspaceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Parse and validate request; the body is optional
+	var req models.WorkspaceMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.WithContext(r.Context()).Error("Failed to decode workspace member request", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "request.invalid_format"))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		sendValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	members, err := h.service.SetMember(r.Context(), workspaceID, userID, memberID, req.Role)
+	if err != nil {
+		h.sendServiceError(w, r, err, "workspace.update_failed")
+		return
+	}
+
+	// Send response
+	sendJSON(w, http.StatusOK, members)
+}
+
+// RemoveMember handles requests to remove a member from a workspace or to
+// leave it
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, workspaceID, ok := h.workspaceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return
+	}
+
+	// Call service
+	if err := h.service.RemoveMember(r.Context(), workspaceID, userID, memberID); err != nil {
+		h.sendServiceError(w, r, err, "workspace.update_failed")
+		return
+	}
+
+	// Send response
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// workspaceRequest returns the authenticated user's ID and the workspace ID
+// from the path, sending an error response if either is invalid
+func (h *Handler) workspaceRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := h.currentUserID(w, r)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	workspaceID, err := uuid.Parse(mux.Vars(r)["workspace_id"])
+	if err != nil {
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "workspace.invalid_id"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, workspaceID, true
+}
+
+// currentUserID returns the authenticated user's ID, sending an error
+// response if it is missing or malformed
+func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, err := auth.GetUserID(r.Context())
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Failed to get user ID from context", "error", err)
+		sendError(w, r, errcode.Unauthenticated, i18n.T(r, "auth.required"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		h.logger.WithContext(r.Context()).Error("Invalid user ID format", "error", err)
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "user.invalid_id"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// sendServiceError maps a service error to an HTTP error response, using
+// the message key for unexpected errors
+func (h *Handler) sendServiceError(w http.ResponseWriter, r *http.Request, err error, key string) {
+	switch {
+	case errors.Is(err, ErrWorkspaceNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "workspace.not_found"))
+	case errors.Is(err, ErrNotMember):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "workspace.not_member"))
+	case errors.Is(err, ErrNotAdmin):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "workspace.admin_required"))
+	case errors.Is(err, ErrOwner):
+		sendError(w, r, errcode.Forbidden, i18n.T(r, "workspace.owner"))
+	case errors.Is(err, ErrMemberNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "workspace.member_not_found"))
+	case errors.Is(err, ErrUserNotFound):
+		sendError(w, r, errcode.NotFound, i18n.T(r, "user.not_found"))
+	case errors.Is(err, ErrInvalidSlug):
+		sendError(w, r, errcode.InvalidRequest, i18n.T(r, "workspace.invalid_slug"))
+	case errors.Is(err, ErrSlugTaken):
+		sendError(w, r, errcode.Conflict, i18n.T(r, "workspace.slug_taken"))
+	default:
+		h.logger.WithContext(r.Context()).Error(i18n.English.T(key), "error", err)
+		sendError(w, r, errcode.Internal, i18n.T(r, key))
+	}
+}
+
+// sendJSON sends a JSON response
+func sendJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if data != nil {
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// sendError sends an error response with the code's HTTP status
+func sendError(w http.ResponseWriter, r *http.Request, code errcode.Code, message string) {
+	resp := models.NewErrorResponse(code, message)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, code.HTTPStatus(), resp)
+}
+
+// sendValidationError sends an invalid request response listing the invalid fields
+func sendValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	l := i18n.FromRequest(r)
+	resp := models.NewErrorResponse(errcode.InvalidRequest, l.Error(err), errcode.Details(l, err)...)
+	resp.RequestID = requestid.FromContext(r.Context())
+	sendJSON(w, errcode.InvalidRequest.HTTPStatus(), resp)
+}
@@ -0,0 +1,192 @@
+package workspace
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Repository errors
+var (
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrNotMember         = errors.New("user is not a member of the workspace")
+	ErrSlugTaken         = errors.New("workspace slug is already taken")
+	ErrUserNotFound      = errors.New("user not found")
+)
+
+// PostgreSQL error codes
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// Repository interface for workspace operations
+type Repository interface {
+	CreateWorkspace(ctx context.Context, workspace *models.Workspace) error
+	GetWorkspace(ctx context.Context, workspaceID uuid.UUID) (*models.Workspace, error)
+	GetUserWorkspaces(ctx context.Context, userID uuid.UUID) ([]models.Workspace, error)
+	GetMembers(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceMember, error)
+	GetMemberRole(ctx context.Context, workspaceID, userID uuid.UUID) (string, error)
+	IsMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error)
+	SetMember(ctx context.Context, workspaceID, userID uuid.UUID, role string) error
+	RemoveMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error)
+	DeleteMemberSessions(ctx context.Context, workspaceID, userID uuid.UUID) error
+}
+
+// PostgresRepository implements Repository interface with PostgreSQL
+type PostgresRepository struct {
+	db *database.DB
+}
+
+// NewPostgresRepository creates a new PostgreSQL repository
+func NewPostgresRepository(db *database.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// conn returns the transaction bound to ctx, falling back to the pool
+func (r *PostgresRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// CreateWorkspace saves a new workspace
+func (r *PostgresRepository) CreateWorkspace(ctx context.Context, workspace *models.Workspace) error {
+	query := `
+		INSERT INTO workspaces (id, name, slug, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		workspace.ID,
+		workspace.Name,
+		workspace.Slug,
+		workspace.CreatedBy,
+		workspace.CreatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrSlugTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// GetWorkspace retrieves a workspace by ID
+func (r *PostgresRepository) GetWorkspace(ctx context.Context, workspaceID uuid.UUID) (*models.Workspace, error) {
+	var workspace models.Workspace
+	query := "SELECT id, name, slug, created_by, created_at FROM workspaces WHERE id = $1"
+	if err := r.conn(ctx).GetContext(ctx, &workspace, query, workspaceID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// GetUserWorkspaces retrieves the workspaces a user belongs to, with their
+// role in each
+func (r *PostgresRepository) GetUserWorkspaces(ctx context.Context, userID uuid.UUID) ([]models.Workspace, error) {
+	query := `
+		SELECT w.id, w.name, w.slug, w.created_by, w.created_at, m.role
+		FROM workspace_members m
+		JOIN workspaces w ON w.id = m.workspace_id
+		WHERE m.user_id = $1
+		ORDER BY w.name
+	`
+
+	workspaces := []models.Workspace{}
+	if err := r.conn(ctx).SelectContext(ctx, &workspaces, query, userID); err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+// GetMembers retrieves the members of a workspace, oldest first
+func (r *PostgresRepository) GetMembers(ctx context.Context, workspaceID uuid.UUID) ([]models.WorkspaceMember, error) {
+	query := `
+		SELECT m.user_id, u.username, m.role, m.joined_at
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY m.joined_at, u.username
+	`
+
+	members := []models.WorkspaceMember{}
+	if err := r.conn(ctx).SelectContext(ctx, &members, query, workspaceID); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetMemberRole retrieves a member's role in a workspace
+func (r *PostgresRepository) GetMemberRole(ctx context.Context, workspaceID, userID uuid.UUID) (string, error) {
+	query := "SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2"
+
+	var role string
+	if err := r.conn(ctx).GetContext(ctx, &role, query, workspaceID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotMember
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// IsMember reports whether a user belongs to a workspace
+func (r *PostgresRepository) IsMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error) {
+	query := "SELECT EXISTS(SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id = $2)"
+
+	var isMember bool
+	if err := r.conn(ctx).GetContext(ctx, &isMember, query, workspaceID, userID); err != nil {
+		return false, err
+	}
+	return isMember, nil
+}
+
+// SetMember adds a user to a workspace or changes their role
+func (r *PostgresRepository) SetMember(ctx context.Context, workspaceID, userID uuid.UUID, role string) error {
+	query := `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, workspaceID, userID, role)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// RemoveMember removes a user from a workspace, reporting whether they were
+// a member
+func (r *PostgresRepository) RemoveMember(ctx context.Context, workspaceID, userID uuid.UUID) (bool, error) {
+	result, err := r.conn(ctx).ExecContext(ctx, "DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2", workspaceID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// DeleteMemberSessions deletes a user's sessions for a workspace, so their
+// refresh tokens can no longer be used in it
+func (r *PostgresRepository) DeleteMemberSessions(ctx context.Context, workspaceID, userID uuid.UUID) error {
+	_, err := r.conn(ctx).ExecContext(ctx, "DELETE FROM sessions WHERE workspace_id = $1 AND user_id = $2", workspaceID, userID)
+	return err
+}
@@ -0,0 +1,217 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/internal/models"
+	"github.com/codingminions/Whatsapp-Lite/pkg/database"
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// Service errors
+var (
+	ErrInvalidSlug    = errors.New("invalid workspace slug")
+	ErrNotAdmin       = errors.New("workspace admin access required")
+	ErrOwner          = errors.New("the workspace owner cannot be changed or removed")
+	ErrMemberNotFound = errors.New("workspace member not found")
+)
+
+// slugPattern matches lowercase letters and digits separated by single hyphens
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Service handles workspace business logic
+type Service interface {
+	CreateWorkspace(ctx context.Context, userID uuid.UUID, req *models.CreateWorkspaceRequest) (*models.Workspace, error)
+	GetWorkspaces(ctx context.Context, userID uuid.UUID) (*models.WorkspaceListResponse, error)
+	GetWorkspace(ctx context.Context, workspaceID, userID uuid.UUID) (*models.Workspace, error)
+	GetMembers(ctx context.Context, workspaceID, userID uuid.UUID) (*models.WorkspaceMemberListResponse, error)
+	SetMember(ctx context.Context, workspaceID, userID, memberID uuid.UUID, role string) (*models.WorkspaceMemberListResponse, error)
+	RemoveMember(ctx context.Context, workspaceID, userID, memberID uuid.UUID) error
+}
+
+// WorkspaceService implements Service interface
+type WorkspaceService struct {
+	repo   Repository
+	uow    database.UnitOfWork
+	logger logger.Logger
+}
+
+// NewWorkspaceService creates a new workspace service
+func NewWorkspaceService(repo Repository, uow database.UnitOfWork, logger logger.Logger) *WorkspaceService {
+	return &WorkspaceService{
+		repo:   repo,
+		uow:    uow,
+		logger: logger,
+	}
+}
+
+// CreateWorkspace creates a workspace with the user as its owner
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, userID uuid.UUID, req *models.CreateWorkspaceRequest) (*models.Workspace, error) {
+	if !slugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	workspace := &models.Workspace{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Slug:      req.Slug,
+		CreatedBy: &userID,
+		CreatedAt: time.Now(),
+		Role:      models.WorkspaceRoleOwner,
+	}
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.CreateWorkspace(ctx, workspace); err != nil {
+			return err
+		}
+		return s.repo.SetMember(ctx, workspace.ID, userID, models.WorkspaceRoleOwner)
+	})
+	if err != nil {
+		if !errors.Is(err, ErrSlugTaken) {
+			s.logger.WithContext(ctx).Error("Failed to create workspace", "error", err)
+		}
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).Info("Workspace created", "workspace_id", workspace.ID, "user_id", userID)
+	return workspace, nil
+}
+
+// GetWorkspaces returns the workspaces a user belongs to
+func (s *WorkspaceService) GetWorkspaces(ctx context.Context, userID uuid.UUID) (*models.WorkspaceListResponse, error) {
+	workspaces, err := s.repo.GetUserWorkspaces(ctx, userID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get workspaces", "error", err)
+		return nil, err
+	}
+
+	return &models.WorkspaceListResponse{Workspaces: workspaces}, nil
+}
+
+// GetWorkspace returns a workspace to one of its members
+func (s *WorkspaceService) GetWorkspace(ctx context.Context, workspaceID, userID uuid.UUID) (*models.Workspace, error) {
+	role, err := s.memberRole(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.repo.GetWorkspace(ctx, workspaceID)
+	if err != nil {
+		if !errors.Is(err, ErrWorkspaceNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to get workspace", "error", err, "workspace_id", workspaceID)
+		}
+		return nil, err
+	}
+	workspace.Role = role
+
+	return workspace, nil
+}
+
+// GetMembers returns the members of a workspace to one of its members
+func (s *WorkspaceService) GetMembers(ctx context.Context, workspaceID, userID uuid.UUID) (*models.WorkspaceMemberListResponse, error) {
+	if _, err := s.memberRole(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+	return s.members(ctx, workspaceID)
+}
+
+// SetMember adds a user to a workspace or changes their role. Only owners
+// and admins can manage members, and the owner's role cannot be changed.
+func (s *WorkspaceService) SetMember(ctx context.Context, workspaceID, userID, memberID uuid.UUID, role string) (*models.WorkspaceMemberListResponse, error) {
+	if err := s.requireAdmin(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+	if role == "" {
+		role = models.WorkspaceRoleMember
+	}
+
+	current, err := s.memberRole(ctx, workspaceID, memberID)
+	if err != nil && !errors.Is(err, ErrNotMember) {
+		return nil, err
+	}
+	if current == models.WorkspaceRoleOwner {
+		return nil, ErrOwner
+	}
+
+	if err := s.repo.SetMember(ctx, workspaceID, memberID, role); err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			s.logger.WithContext(ctx).Error("Failed to set workspace member", "error", err, "workspace_id", workspaceID)
+		}
+		return nil, err
+	}
+
+	return s.members(ctx, workspaceID)
+}
+
+// RemoveMember removes a user from a workspace and ends their sessions in
+// it. Members can remove themselves; removing others takes an owner or
+// admin. The owner cannot be removed.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, workspaceID, userID, memberID uuid.UUID) error {
+	if memberID != userID {
+		if err := s.requireAdmin(ctx, workspaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	role, err := s.memberRole(ctx, workspaceID, memberID)
+	if err != nil {
+		if errors.Is(err, ErrNotMember) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	if role == models.WorkspaceRoleOwner {
+		return ErrOwner
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		removed, err := s.repo.RemoveMember(ctx, workspaceID, memberID)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return ErrMemberNotFound
+		}
+		return s.repo.DeleteMemberSessions(ctx, workspaceID, memberID)
+	})
+	if err != nil && !errors.Is(err, ErrMemberNotFound) {
+		s.logger.WithContext(ctx).Error("Failed to remove workspace member", "error", err, "workspace_id", workspaceID)
+	}
+	return err
+}
+
+// members returns the members of a workspace
+func (s *WorkspaceService) members(ctx context.Context, workspaceID uuid.UUID) (*models.WorkspaceMemberListResponse, error) {
+	members, err := s.repo.GetMembers(ctx, workspaceID)
+	if err != nil {
+		s.logger.WithContext(ctx).Error("Failed to get workspace members", "error", err, "workspace_id", workspaceID)
+		return nil, err
+	}
+	return &models.WorkspaceMemberListResponse{Members: members}, nil
+}
+
+// memberRole returns the user's role in the workspace, or ErrNotMember
+func (s *WorkspaceService) memberRole(ctx context.Context, workspaceID, userID uuid.UUID) (string, error) {
+	role, err := s.repo.GetMemberRole(ctx, workspaceID, userID)
+	if err != nil && !errors.Is(err, ErrNotMember) {
+		s.logger.WithContext(ctx).Error("Failed to check workspace membership", "error", err, "workspace_id", workspaceID)
+	}
+	return role, err
+}
+
+// requireAdmin returns an error unless the user is an owner or admin of the
+// workspace
+func (s *WorkspaceService) requireAdmin(ctx context.Context, workspaceID, userID uuid.UUID) error {
+	role, err := s.memberRole(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if role != models.WorkspaceRoleOwner && role != models.WorkspaceRoleAdmin {
+		return ErrNotAdmin
+	}
+	return nil
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_conversation_summaries_last_message_at;
+DROP TABLE IF EXISTS conversation_summaries;
\ No newline at end of file
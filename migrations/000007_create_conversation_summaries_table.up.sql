@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS conversation_summaries (
+    conversation_id VARCHAR(73) PRIMARY KEY,
+    last_message_id UUID REFERENCES direct_messages(id) ON DELETE SET NULL,
+    last_message_at TIMESTAMP WITH TIME ZONE,
+    message_count BIGINT NOT NULL DEFAULT 0,
+    -- Incremented on every change so readers can detect stale copies
+    version BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Index for ordering conversations by most recent activity
+CREATE INDEX idx_conversation_summaries_last_message_at ON conversation_summaries(last_message_at DESC);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS drafts (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id VARCHAR(73) NOT NULL,
+    content TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    -- Each user has at most one draft per conversation
+    PRIMARY KEY (user_id, conversation_id)
+);
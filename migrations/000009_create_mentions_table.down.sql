@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_mentions_user_id_created_at;
+DROP TABLE IF EXISTS mentions;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS mentions (
+    message_id UUID NOT NULL REFERENCES direct_messages(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    -- A user is mentioned at most once per message
+    PRIMARY KEY (message_id, user_id)
+);
+
+-- Index for listing the messages that mention a user, newest first
+CREATE INDEX idx_mentions_user_id_created_at ON mentions(user_id, created_at DESC);
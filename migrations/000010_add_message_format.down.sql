@@ -0,0 +1,2 @@
+ALTER TABLE direct_messages DROP COLUMN IF EXISTS rendered_content;
+ALTER TABLE direct_messages DROP COLUMN IF EXISTS format;
\ No newline at end of file
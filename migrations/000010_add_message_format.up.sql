@@ -0,0 +1,4 @@
+-- Message format: 'plain' or 'markdown'
+ALTER TABLE direct_messages ADD COLUMN IF NOT EXISTS format VARCHAR(16) NOT NULL DEFAULT 'plain';
+-- Sanitized HTML rendering of formatted messages, NULL for plain text
+ALTER TABLE direct_messages ADD COLUMN IF NOT EXISTS rendered_content TEXT;
@@ -0,0 +1,2 @@
+-- User role: 'user' or 'admin'
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user';
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS feature_flags (
+    name VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Percentage of users (0-100) the flag is rolled out to when enabled
+    percentage SMALLINT NOT NULL DEFAULT 100 CHECK (percentage BETWEEN 0 AND 100),
+    -- Users that always get the feature regardless of percentage
+    user_ids UUID[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
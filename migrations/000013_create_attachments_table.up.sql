@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY,
+    conversation_id VARCHAR(73) NOT NULL,
+    uploader_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    -- Location of the file in attachment storage, never derived from user input
+    storage_key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Index for listing a conversation's attachments
+CREATE INDEX idx_attachments_conversation_id ON attachments(conversation_id, created_at DESC);
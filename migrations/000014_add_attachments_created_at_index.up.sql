@@ -0,0 +1,2 @@
+-- Index for finding attachments past the storage expiry
+CREATE INDEX idx_attachments_created_at ON attachments(created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS status_expires_at,
+    DROP COLUMN IF EXISTS status_emoji,
+    DROP COLUMN IF EXISTS status_text;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN status_text VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN status_emoji VARCHAR(32) NOT NULL DEFAULT '',
+    -- Custom status is hidden once this passes; NULL keeps it until cleared
+    ADD COLUMN status_expires_at TIMESTAMP WITH TIME ZONE;
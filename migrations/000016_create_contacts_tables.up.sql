@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS contact_requests (
+    id UUID PRIMARY KEY,
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    addressee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- pending, accepted or declined
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    responded_at TIMESTAMP WITH TIME ZONE,
+    CHECK (requester_id <> addressee_id)
+);
+
+-- Only one pending request from a user to another at a time
+CREATE UNIQUE INDEX idx_contact_requests_pending ON contact_requests(requester_id, addressee_id) WHERE status = 'pending';
+-- Index for listing a user's incoming requests
+CREATE INDEX idx_contact_requests_addressee_id ON contact_requests(addressee_id, status);
+
+-- Accepted contacts, stored once in each direction
+CREATE TABLE IF NOT EXISTS contacts (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    contact_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, contact_id)
+);
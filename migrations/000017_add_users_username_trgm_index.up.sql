@@ -0,0 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Trigram index for type-ahead username search, serving both prefix
+-- matches (ILIKE 'abc%') and fuzzy similarity matches
+CREATE INDEX idx_users_username_trgm ON users USING GIN (username gin_trgm_ops);
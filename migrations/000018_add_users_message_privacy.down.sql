@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS message_privacy;
\ No newline at end of file
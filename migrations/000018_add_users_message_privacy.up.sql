@@ -0,0 +1,4 @@
+-- Who may start a conversation with the user: everyone, contacts or nobody
+ALTER TABLE users
+    ADD COLUMN message_privacy VARCHAR(20) NOT NULL DEFAULT 'everyone'
+    CHECK (message_privacy IN ('everyone', 'contacts', 'nobody'));
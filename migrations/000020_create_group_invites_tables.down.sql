@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_group_join_requests_group_id;
+DROP INDEX IF EXISTS idx_group_join_requests_pending;
+DROP TABLE IF EXISTS group_join_requests;
+DROP INDEX IF EXISTS idx_group_invites_group_id;
+DROP TABLE IF EXISTS group_invites;
\ No newline at end of file
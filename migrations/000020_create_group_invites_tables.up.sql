@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS group_invites (
+    id UUID PRIMARY KEY,
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- NULL never expires
+    expires_at TIMESTAMP WITH TIME ZONE,
+    -- NULL allows unlimited uses
+    max_uses INTEGER CHECK (max_uses > 0),
+    uses INTEGER NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Index for listing a group's invites
+CREATE INDEX idx_group_invites_group_id ON group_invites(group_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS group_join_requests (
+    id UUID PRIMARY KEY,
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invite_id UUID REFERENCES group_invites(id) ON DELETE SET NULL,
+    -- pending, approved or declined
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    responded_at TIMESTAMP WITH TIME ZONE,
+    responded_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Only one pending request per user and group at a time
+CREATE UNIQUE INDEX idx_group_join_requests_pending ON group_join_requests(group_id, user_id) WHERE status = 'pending';
+-- Index for listing a group's join request queue
+CREATE INDEX idx_group_join_requests_group_id ON group_join_requests(group_id, status, created_at);
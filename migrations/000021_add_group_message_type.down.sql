@@ -0,0 +1,3 @@
+ALTER TABLE group_messages
+    DROP COLUMN IF EXISTS subject_id,
+    DROP COLUMN IF EXISTS type;
\ No newline at end of file
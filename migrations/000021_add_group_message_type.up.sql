@@ -0,0 +1,7 @@
+ALTER TABLE group_messages
+    -- text for user messages, or member_added, member_removed or member_left
+    -- for system messages recording membership changes
+    ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'text',
+    -- Member a system message is about; the sender is the member or admin
+    -- who made the change
+    ADD COLUMN subject_id UUID REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS conversation_visibility;
\ No newline at end of file
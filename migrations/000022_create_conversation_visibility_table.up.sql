@@ -0,0 +1,8 @@
+-- Per-user visibility of direct conversations. Messages sent up to
+-- cleared_at are hidden from the user but not from the other participant.
+CREATE TABLE IF NOT EXISTS conversation_visibility (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id VARCHAR(73) NOT NULL,
+    cleared_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, conversation_id)
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_account_audit_log_user_id;
+DROP TABLE IF EXISTS account_audit_log;
+DROP INDEX IF EXISTS idx_users_deletion_scheduled_at;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS erased_at,
+    DROP COLUMN IF EXISTS deletion_scheduled_at;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- Accounts are erased once deletion_scheduled_at passes. Erased accounts
+-- keep their row, anonymized, so messages to others still have a sender.
+ALTER TABLE users
+    ADD COLUMN deletion_scheduled_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN erased_at TIMESTAMP WITH TIME ZONE;
+
+-- Index for finding accounts due for erasure
+CREATE INDEX idx_users_deletion_scheduled_at ON users(deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+-- Record of data exports and deletion requests for each account
+CREATE TABLE IF NOT EXISTS account_audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- export_requested, deletion_requested, deletion_cancelled or account_erased
+    action VARCHAR(32) NOT NULL,
+    client_ip VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Index for listing an account's audit entries
+CREATE INDEX idx_account_audit_log_user_id ON account_audit_log(user_id, created_at DESC);
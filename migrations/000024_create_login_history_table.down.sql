@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_login_history_user_id;
+DROP TABLE IF EXISTS login_history;
\ No newline at end of file
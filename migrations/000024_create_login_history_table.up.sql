@@ -0,0 +1,17 @@
+-- Successful logins, used to spot logins from a new device, IP address or
+-- country and to let users review recent activity on their account
+CREATE TABLE IF NOT EXISTS login_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    client_ip VARCHAR(50) NOT NULL DEFAULT '',
+    -- ISO 3166-1 alpha-2 code, empty when unknown
+    country VARCHAR(2) NOT NULL DEFAULT '',
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    new_ip BOOLEAN NOT NULL DEFAULT FALSE,
+    new_country BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Index for listing a user's recent logins
+CREATE INDEX idx_login_history_user_id ON login_history(user_id, created_at DESC);
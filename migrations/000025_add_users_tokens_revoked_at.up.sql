@@ -0,0 +1,4 @@
+-- Access tokens issued at or before this time are rejected, so logging out
+-- of every device also ends the sessions' outstanding access tokens
+ALTER TABLE users
+    ADD COLUMN tokens_revoked_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,5 @@
+-- The algorithm each password hash was made with, so bcrypt hashes can be
+-- replaced with argon2id ones as users log in
+ALTER TABLE users
+    ADD COLUMN password_algorithm VARCHAR(20) NOT NULL DEFAULT 'bcrypt'
+    CHECK (password_algorithm IN ('bcrypt', 'argon2id'));
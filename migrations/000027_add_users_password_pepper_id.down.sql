@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS password_pepper_id;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- The pepper each password hash was made with, empty for unpeppered hashes,
+-- so the pepper can be rotated as users log in
+ALTER TABLE users
+    ADD COLUMN password_pepper_id VARCHAR(50) NOT NULL DEFAULT '';
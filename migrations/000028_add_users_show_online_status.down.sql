@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS show_online_status;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Whether a user's contacts can see when they are connected
+ALTER TABLE users
+    ADD COLUMN show_online_status BOOLEAN NOT NULL DEFAULT TRUE;
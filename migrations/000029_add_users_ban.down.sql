@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS ban_reason,
+    DROP COLUMN IF EXISTS banned_until,
+    DROP COLUMN IF EXISTS banned_at;
\ No newline at end of file
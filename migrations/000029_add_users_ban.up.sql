@@ -0,0 +1,6 @@
+-- Admin bans. A banned user's tokens are rejected until banned_until, or
+-- indefinitely when it is NULL.
+ALTER TABLE users
+    ADD COLUMN banned_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN banned_until TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN ban_reason TEXT NOT NULL DEFAULT '';
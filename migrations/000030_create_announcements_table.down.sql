@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS announcements_seen_at;
+DROP TABLE IF EXISTS announcements;
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS announcements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    message TEXT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Index for finding announcements that have not expired
+CREATE INDEX idx_announcements_expires_at ON announcements(expires_at);
+
+-- When each user last had announcements delivered, either live or on
+-- reconnecting, so those sent while they were offline can be delivered once
+ALTER TABLE users
+    ADD COLUMN announcements_seen_at TIMESTAMP WITH TIME ZONE;
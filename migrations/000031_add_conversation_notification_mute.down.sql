@@ -0,0 +1 @@
+ALTER TABLE conversation_notification_overrides DROP COLUMN IF EXISTS mute;
\ No newline at end of file
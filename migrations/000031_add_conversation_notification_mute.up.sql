@@ -0,0 +1,6 @@
+-- What a conversation override mutes: nothing, mentions, media or everything
+ALTER TABLE conversation_notification_overrides
+    ADD COLUMN IF NOT EXISTS mute VARCHAR(20) NOT NULL DEFAULT 'none'
+    CHECK (mute IN ('none', 'mentions', 'media', 'all'));
+
+UPDATE conversation_notification_overrides SET mute = 'all' WHERE enabled = FALSE;
@@ -0,0 +1,4 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE groups DROP COLUMN IF EXISTS workspace_id;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
\ No newline at end of file
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- owner, admin or member
+    role VARCHAR(20) NOT NULL DEFAULT 'member',
+    joined_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+-- Index for listing the workspaces a user belongs to
+CREATE INDEX idx_workspace_members_user_id ON workspace_members(user_id);
+
+-- Groups created in a workspace only admit its members
+ALTER TABLE groups
+    ADD COLUMN workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
+CREATE INDEX idx_groups_workspace_id ON groups(workspace_id);
+
+-- The workspace a session's access tokens are issued for
+ALTER TABLE sessions
+    ADD COLUMN workspace_id UUID REFERENCES workspaces(id) ON DELETE CASCADE;
@@ -0,0 +1,11 @@
+-- Accounts at external identity providers, linked to users on single sign-on
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (provider, subject)
+);
+
+-- Index for finding a user's linked identities
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
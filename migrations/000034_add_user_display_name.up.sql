@@ -0,0 +1,2 @@
+-- Display names, kept in sync with the directory when one manages accounts
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS sms_notifications;
+DROP TABLE IF EXISTS phone_verifications;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS sms_notified_through;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS sms_enabled;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified_at;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_number;
\ No newline at end of file
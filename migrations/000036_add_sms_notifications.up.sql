@@ -0,0 +1,29 @@
+-- Phone numbers for SMS notifications, set once verified
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_number VARCHAR(20);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- SMS notifications are opt-in. sms_notified_through is the send time of
+-- the newest unread message already considered for an SMS.
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS sms_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS sms_notified_through TIMESTAMP WITH TIME ZONE;
+
+-- Pending phone number verifications, one per user
+CREATE TABLE IF NOT EXISTS phone_verifications (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    phone_number VARCHAR(20) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- SMS notifications sent, counted against the daily cap
+CREATE TABLE IF NOT EXISTS sms_notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message_count INT NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Index for counting a user's recent SMS notifications
+CREATE INDEX idx_sms_notifications_user_id_sent_at ON sms_notifications(user_id, sent_at);
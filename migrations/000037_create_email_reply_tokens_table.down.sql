@@ -0,0 +1 @@
+DROP TABLE IF EXISTS email_reply_tokens;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- Reply addresses in notification emails, one per user and conversation
+CREATE TABLE IF NOT EXISTS email_reply_tokens (
+    token VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id VARCHAR(73) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, conversation_id)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS phone_login_codes;
+DROP INDEX IF EXISTS idx_users_verified_phone_number;
+UPDATE users SET email = id::text || '@phone.invalid' WHERE email IS NULL;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
\ No newline at end of file
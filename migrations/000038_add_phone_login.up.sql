@@ -0,0 +1,14 @@
+-- Accounts created with a phone number have no email
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+
+-- A verified phone number belongs to one account, so it can be used to log in
+CREATE UNIQUE INDEX idx_users_verified_phone_number ON users(phone_number) WHERE phone_verified_at IS NOT NULL;
+
+-- Codes texted to phone numbers for signing up or logging in, one per number
+CREATE TABLE IF NOT EXISTS phone_login_codes (
+    phone_number VARCHAR(20) PRIMARY KEY,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- Durable events sent to each user, numbered per user so every device can
+-- catch up from the last event it acknowledged
+CREATE TABLE IF NOT EXISTS user_event_heads (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    last_seq BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS user_events (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_events_created_at ON user_events(created_at);
+
+-- The last event each of a user's devices acknowledged
+CREATE TABLE IF NOT EXISTS user_event_offsets (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_id VARCHAR(64) NOT NULL,
+    acked_seq BIGINT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, device_id)
+);
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE direct_messages ADD COLUMN IF NOT EXISTS read BOOLEAN DEFAULT FALSE;
+
+UPDATE direct_messages dm
+SET read = TRUE
+FROM read_cursors rc
+WHERE rc.user_id = dm.recipient_id
+  AND rc.conversation_id = LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text
+  AND (dm.created_at, dm.id) <= (rc.last_read_message_at, rc.last_read_message_id);
+
+DROP TABLE IF EXISTS read_cursors;
\ No newline at end of file
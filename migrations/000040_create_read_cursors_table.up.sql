@@ -0,0 +1,25 @@
+-- Each user's position in their direct conversations. Messages up to and
+-- including the cursor's message, ordered by (created_at, id), are read.
+CREATE TABLE IF NOT EXISTS read_cursors (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id VARCHAR(73) NOT NULL,
+    last_read_message_id UUID NOT NULL,
+    last_read_message_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    read_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, conversation_id)
+);
+
+-- Start each cursor at the newest message the user had read
+INSERT INTO read_cursors (user_id, conversation_id, last_read_message_id, last_read_message_at, read_at)
+SELECT DISTINCT ON (recipient_id, conversation_id)
+    recipient_id, conversation_id, id, created_at, NOW()
+FROM (
+    SELECT recipient_id, id, created_at,
+           LEAST(sender_id, recipient_id)::text || '-' || GREATEST(sender_id, recipient_id)::text AS conversation_id
+    FROM direct_messages
+    WHERE read
+) AS read_messages
+ORDER BY recipient_id, conversation_id, created_at DESC, id DESC
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE direct_messages DROP COLUMN IF EXISTS read;
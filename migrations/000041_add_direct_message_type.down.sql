@@ -0,0 +1,6 @@
+-- System messages have no content to fall back to
+DELETE FROM direct_messages WHERE type <> 'text';
+
+ALTER TABLE direct_messages
+    DROP COLUMN IF EXISTS data,
+    DROP COLUMN IF EXISTS type;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE direct_messages
+    -- text for user messages, or an event such as call_missed for system
+    -- messages recording what happened in the conversation
+    ADD COLUMN type VARCHAR(20) NOT NULL DEFAULT 'text',
+    -- Details of a system message's event, such as the media of a missed
+    -- call
+    ADD COLUMN data JSONB;
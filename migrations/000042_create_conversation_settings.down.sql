@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_direct_messages_expires_at;
+
+ALTER TABLE direct_messages DROP COLUMN IF EXISTS expires_at;
+
+DROP TABLE IF EXISTS conversation_settings;
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- Each user's own options for their direct conversations. Mute levels are
+-- kept with the conversation notification overrides.
+CREATE TABLE IF NOT EXISTS conversation_settings (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    conversation_id VARCHAR(73) NOT NULL,
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Client-defined sound played for the conversation's notifications,
+    -- empty for the default
+    notification_sound VARCHAR(64) NOT NULL DEFAULT '',
+    -- Seconds after which the messages the user sends disappear, 0 when off
+    disappearing_timer INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, conversation_id)
+);
+
+-- When a disappearing message is deleted
+ALTER TABLE direct_messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_direct_messages_expires_at ON direct_messages(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE direct_messages
+    DROP COLUMN IF EXISTS delivery_updated_at,
+    DROP COLUMN IF EXISTS delivery_state;
\ No newline at end of file
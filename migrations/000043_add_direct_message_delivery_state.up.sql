@@ -0,0 +1,16 @@
+-- Where each direct message is in its delivery to the recipient: stored,
+-- delivered to one of their devices, or read. Messages are only saved once
+-- stored, so the queued and failed states are never persisted.
+ALTER TABLE direct_messages
+    ADD COLUMN delivery_state VARCHAR(16) NOT NULL DEFAULT 'stored'
+    CHECK (delivery_state IN ('stored', 'delivered', 'read')),
+    ADD COLUMN delivery_updated_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE direct_messages SET delivery_state = 'delivered' WHERE delivered;
+
+UPDATE direct_messages dm
+SET delivery_state = 'read'
+FROM read_cursors rc
+WHERE rc.user_id = dm.recipient_id
+  AND rc.conversation_id = LEAST(dm.sender_id, dm.recipient_id)::text || '-' || GREATEST(dm.sender_id, dm.recipient_id)::text
+  AND (dm.created_at, dm.id) <= (rc.last_read_message_at, rc.last_read_message_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_attachments_thumbnail_pending;
+
+ALTER TABLE attachments
+    DROP COLUMN IF EXISTS thumbnailed_at,
+    DROP COLUMN IF EXISTS thumbnail_size,
+    DROP COLUMN IF EXISTS thumbnail_key;
@@ -0,0 +1,11 @@
+-- Thumbnails of image attachments, made by a background job. thumbnailed_at
+-- is set once the job has handled an image, whether or not it could make a
+-- thumbnail of it.
+ALTER TABLE attachments
+    ADD COLUMN thumbnail_key VARCHAR(255),
+    ADD COLUMN thumbnail_size BIGINT,
+    ADD COLUMN thumbnailed_at TIMESTAMP WITH TIME ZONE;
+
+-- Index for finding images still waiting for a thumbnail
+CREATE INDEX idx_attachments_thumbnail_pending ON attachments(created_at)
+    WHERE thumbnailed_at IS NULL AND content_type LIKE 'image/%';
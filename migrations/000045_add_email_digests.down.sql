@@ -0,0 +1,3 @@
+ALTER TABLE notification_preferences
+    DROP COLUMN IF EXISTS email_digest_through,
+    DROP COLUMN IF EXISTS email_digest_enabled;
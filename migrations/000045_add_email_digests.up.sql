@@ -0,0 +1,4 @@
+-- Email digests of unread messages are opt-in. email_digest_through is the
+-- send time of the newest unread message already included in a digest.
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS email_digest_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS email_digest_through TIMESTAMP WITH TIME ZONE;
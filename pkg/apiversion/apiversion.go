@@ -0,0 +1,79 @@
+// Package apiversion identifies the REST API version a request was made
+// for. Handlers are shared between versions and read the version from the
+// request context when a version changes the shape of a response.
+package apiversion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Version is a major version of the REST API
+type Version int
+
+// API versions
+const (
+	V1 Version = 1
+
+	// Latest is the newest version the server supports
+	Latest = V1
+)
+
+// Header is the HTTP header naming the version of the API a client wants
+// on unversioned paths, and the version a response was made for
+const Header = "API-Version"
+
+// ErrUnsupported is returned for versions the server does not support
+var ErrUnsupported = errors.New("unsupported API version")
+
+// supported lists the versions the server serves
+var supported = map[Version]bool{V1: true}
+
+type contextKey struct{}
+
+// Parse parses a version such as 1 or v1, returning ErrUnsupported for
+// versions the server does not serve
+func Parse(value string) (Version, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "v"))
+	if err != nil || !supported[Version(n)] {
+		return 0, ErrUnsupported
+	}
+	return Version(n), nil
+}
+
+// String returns the version's path segment, such as v1
+func (v Version) String() string {
+	return "v" + strconv.Itoa(int(v))
+}
+
+// Prefix returns the path prefix the version is served under
+func (v Version) Prefix() string {
+	return "/api/" + v.String()
+}
+
+// NewContext returns a copy of ctx carrying the API version
+func NewContext(ctx context.Context, v Version) context.Context {
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FromContext returns the API version carried by ctx, or V1 if none
+func FromContext(ctx context.Context) Version {
+	if v, ok := ctx.Value(contextKey{}).(Version); ok {
+		return v
+	}
+	return V1
+}
+
+// Middleware marks the requests it handles as made for a version and
+// tells clients the version in the API-Version response header
+func Middleware(v Version) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(Header, strconv.Itoa(int(v)))
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), v)))
+		})
+	}
+}
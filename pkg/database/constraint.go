@@ -0,0 +1,35 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// PostgreSQL error codes for statements rejected by an integrity constraint
+const (
+	NotNullViolation    pq.ErrorCode = "23502"
+	ForeignKeyViolation pq.ErrorCode = "23503"
+	UniqueViolation     pq.ErrorCode = "23505"
+	CheckViolation      pq.ErrorCode = "23514"
+)
+
+// ConstraintViolation returns the error of a statement rejected by an
+// integrity constraint, or nil if err is anything else. Repositories use
+// the error's code and constraint name to map it to a domain error.
+func ConstraintViolation(err error) *pq.Error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Class() == "23" {
+		return pqErr
+	}
+	return nil
+}
+
+// ConstraintField names what a constraint violation rejected: the column
+// for NOT NULL violations, otherwise the constraint
+func ConstraintField(pqErr *pq.Error) string {
+	if pqErr.Column != "" {
+		return pqErr.Column
+	}
+	return pqErr.Constraint
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// Config controls how DB runs queries
+type Config struct {
+	QueryTimeout       time.Duration // upper bound on each query; 0 leaves it to the caller's context
+	SlowQueryThreshold time.Duration // queries at least this slow are logged; 0 disables the log
+	Resilience         ResilienceConfig
+}
+
+// DB is a connection pool whose queries are bounded by a timeout, logged
+// when slow, retried on transient errors and guarded by a circuit breaker,
+// so a brief database hiccup neither fails every request nor piles load
+// onto a database that is down.
+//
+// QueryRowContext and QueryRowxContext defer their errors to Scan, so they
+// are passed through unguarded; repositories read single rows with
+// GetContext where possible.
+type DB struct {
+	*sqlx.DB
+	config  Config
+	breaker *Breaker
+	logger  logger.Logger
+}
+
+// NewDB wraps a connection pool with the query limits, retry policy and
+// circuit breaker described by config
+func NewDB(db *sqlx.DB, config Config, logger logger.Logger) *DB {
+	return &DB{
+		DB:      db,
+		config:  config,
+		breaker: NewBreaker(config.Resilience.BreakerThreshold, config.Resilience.BreakerCooldown),
+		logger:  logger,
+	}
+}
+
+// Breaker returns the pool's circuit breaker
+func (db *DB) Breaker() *Breaker {
+	return db.breaker
+}
+
+// ExecContext executes a statement
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := retry(ctx, db.config.Resilience, db.breaker, "exec", false, func() error {
+		return db.observe(ctx, "exec", query, func(ctx context.Context) (err error) {
+			result, err = db.DB.ExecContext(ctx, query, args...)
+			return err
+		})
+	})
+	return result, err
+}
+
+// QueryContext runs a query returning rows
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := retry(ctx, db.config.Resilience, db.breaker, "query", isReadQuery(query), func() error {
+		return db.observeRows(ctx, query, func(ctx context.Context) (err error) {
+			rows, err = db.DB.QueryContext(ctx, query, args...)
+			return err
+		})
+	})
+	return rows, err
+}
+
+// QueryxContext runs a query returning rows that scan into structs
+func (db *DB) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+	err := retry(ctx, db.config.Resilience, db.breaker, "query", isReadQuery(query), func() error {
+		return db.observeRows(ctx, query, func(ctx context.Context) (err error) {
+			rows, err = db.DB.QueryxContext(ctx, query, args...)
+			return err
+		})
+	})
+	return rows, err
+}
+
+// GetContext scans a single row into dest
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return retry(ctx, db.config.Resilience, db.breaker, "get", isReadQuery(query), func() error {
+		return db.observe(ctx, "get", query, func(ctx context.Context) error {
+			return db.DB.GetContext(ctx, dest, query, args...)
+		})
+	})
+}
+
+// SelectContext scans all rows into dest
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return retry(ctx, db.config.Resilience, db.breaker, "select", isReadQuery(query), func() error {
+		return db.observe(ctx, "select", query, func(ctx context.Context) error {
+			return db.DB.SelectContext(ctx, dest, query, args...)
+		})
+	})
+}
+
+// observe runs a query under the query timeout, recording its duration and
+// logging it if it was slow
+func (db *DB) observe(ctx context.Context, op, query string, call func(ctx context.Context) error) error {
+	if db.config.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, db.config.QueryTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := call(ctx)
+	db.record(ctx, op, query, time.Since(start), err)
+	return err
+}
+
+// observeRows is observe for queries whose rows are read after the call
+// returns. The timeout then covers reading the rows too, so its context is
+// only cancelled early if the query fails; the duration recorded is the
+// time to the first row.
+func (db *DB) observeRows(ctx context.Context, query string, call func(ctx context.Context) error) error {
+	var cancel context.CancelFunc
+	if db.config.QueryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, db.config.QueryTimeout)
+	}
+
+	start := time.Now()
+	err := call(ctx)
+	db.record(ctx, "query", query, time.Since(start), err)
+
+	if cancel != nil {
+		if err != nil {
+			cancel()
+		} else {
+			time.AfterFunc(db.config.QueryTimeout, cancel)
+		}
+	}
+	return err
+}
+
+// record updates the query metrics and writes the slow query log
+func (db *DB) record(ctx context.Context, op, query string, elapsed time.Duration, err error) {
+	queryDuration.WithLabelValues(op).Observe(elapsed.Seconds())
+
+	threshold := db.config.SlowQueryThreshold
+	if threshold <= 0 || elapsed < threshold {
+		return
+	}
+	slowQueries.WithLabelValues(op).Inc()
+
+	// Arguments are left out since they hold user data
+	fields := []interface{}{"operation", op, "duration", elapsed, "query", compactQuery(query)}
+	if err != nil {
+		fields = append(fields, "error", err)
+	}
+	db.logger.WithContext(ctx).Warn("Slow database query", fields...)
+}
+
+// compactQuery collapses a query's whitespace and truncates it for logging
+func compactQuery(query string) string {
+	const maxLength = 300
+
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLength {
+		query = query[:maxLength] + "..."
+	}
+	return query
+}
+
+// isReadQuery reports whether a query only reads, so it can be retried
+// after a lost connection
+func isReadQuery(query string) bool {
+	query = strings.TrimSpace(query)
+	return len(query) >= 6 && strings.EqualFold(query[:6], "SELECT")
+}
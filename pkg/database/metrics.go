@@ -0,0 +1,40 @@
+package database
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Database metrics exported on the metrics endpoint
+var (
+	breakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_db_breaker_state",
+		Help: "State of the database circuit breaker: 0 closed, 1 half-open, 2 open.",
+	})
+
+	breakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_db_breaker_transitions_total",
+		Help: "Number of database circuit breaker state changes by new state.",
+	}, []string{"state"})
+
+	breakerRejections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "chat_db_breaker_rejections_total",
+		Help: "Number of database calls rejected while the circuit breaker was open.",
+	})
+
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chat_db_query_duration_seconds",
+		Help:    "Duration of database queries by operation.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"operation"})
+
+	slowQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_db_slow_queries_total",
+		Help: "Number of database queries slower than the slow query threshold, by operation.",
+	}, []string{"operation"})
+
+	retries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_db_retries_total",
+		Help: "Number of database calls retried after a transient error, by operation.",
+	}, []string{"operation"})
+)
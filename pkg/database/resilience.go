@@ -0,0 +1,226 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrCircuitOpen is returned without contacting the database while the
+// circuit breaker is open
+var ErrCircuitOpen = errors.New("database unavailable: circuit breaker is open")
+
+// ResilienceConfig controls retries and the circuit breaker
+type ResilienceConfig struct {
+	MaxAttempts      int           // attempts per call, including the first; 1 disables retries
+	RetryBaseDelay   time.Duration // delay before the first retry, doubled for each retry
+	RetryMaxDelay    time.Duration // upper bound on the retry delay
+	BreakerThreshold int           // consecutive failed calls that open the breaker; 0 disables it
+	BreakerCooldown  time.Duration // how long the breaker stays open before a trial call
+}
+
+// Breaker states, also the values of the breaker state metric
+const (
+	BreakerClosed   = 0
+	BreakerHalfOpen = 1
+	BreakerOpen     = 2
+)
+
+// Breaker is a circuit breaker that stops calls to the database after
+// repeated transient failures, then lets a single trial call through once
+// the cooldown has passed
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker creates a closed circuit breaker
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	breakerState.Set(BreakerClosed)
+	return &Breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may proceed
+func (b *Breaker) Allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			breakerRejections.Inc()
+			return ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		// Only the trial call goes through until it succeeds
+		if b.probing {
+			breakerRejections.Inc()
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// Record updates the breaker with the outcome of a call. Only transient
+// errors count as failures; a missing row or constraint violation means the
+// database is healthy.
+func (b *Breaker) Record(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && IsTransient(err) {
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+			b.openedAt = time.Now()
+			b.setState(BreakerOpen)
+		}
+		b.probing = false
+		return
+	}
+
+	b.failures = 0
+	b.probing = false
+	if b.state != BreakerClosed {
+		b.setState(BreakerClosed)
+	}
+}
+
+// State returns the breaker's current state
+func (b *Breaker) State() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// setState changes the state and updates the metric. Callers hold b.mu.
+func (b *Breaker) setState(state int) {
+	b.state = state
+	breakerState.Set(float64(state))
+	breakerTransitions.WithLabelValues(stateNames[state]).Inc()
+}
+
+var stateNames = map[int]string{
+	BreakerClosed:   "closed",
+	BreakerHalfOpen: "half_open",
+	BreakerOpen:     "open",
+}
+
+// PostgreSQL error codes that are safe to retry because the statement was
+// rolled back or never ran
+var retryableCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53300": true, // too_many_connections
+	"57P03": true, // cannot_connect_now
+	"08001": true, // sqlclient_unable_to_establish_sqlconnection
+	"08004": true, // sqlserver_rejected_establishment_of_sqlconnection
+}
+
+// IsTransient reports whether err is a database failure that may go away on
+// its own, such as a serialization failure or a lost connection
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 is connection exceptions, 57P01-57P03 are shutdowns
+		return retryableCodes[pqErr.Code] || pqErr.Code.Class() == "08" ||
+			pqErr.Code == "57P01" || pqErr.Code == "57P02"
+	}
+
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &netErr)
+}
+
+// isRetryable reports whether a failed call can be repeated without risk of
+// applying it twice. A connection lost part way through a write may have
+// committed it, so writes are only retried when the server rejected them,
+// and failed commits never are.
+func isRetryable(err error, readOnly bool) bool {
+	if !IsTransient(err) || errors.Is(err, errCommitFailed) {
+		return false
+	}
+	if readOnly || errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && retryableCodes[pqErr.Code]
+}
+
+// retry runs call under the breaker, repeating it with exponential backoff
+// while it fails with a retryable error
+func retry(ctx context.Context, config ResilienceConfig, breaker *Breaker, op string, readOnly bool, call func() error) error {
+	attempts := config.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := config.RetryBaseDelay
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = breaker.Allow(); err != nil {
+			return err
+		}
+		err = call()
+		breaker.Record(err)
+
+		if err == nil || attempt >= attempts || !isRetryable(err, readOnly) {
+			return err
+		}
+		retries.WithLabelValues(op).Inc()
+
+		// Jitter spreads out retries from concurrent callers
+		wait := delay
+		if wait > 0 {
+			wait = wait/2 + time.Duration(rand.Int63n(int64(wait)/2+1))
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		delay *= 2
+		if config.RetryMaxDelay > 0 && delay > config.RetryMaxDelay {
+			delay = config.RetryMaxDelay
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Querier is the set of query methods shared by *sqlx.DB and *sqlx.Tx
+type Querier interface {
+	sqlx.ExtContext
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// UnitOfWork runs a set of repository operations atomically
+type UnitOfWork interface {
+	// Do executes fn inside a transaction. Repositories called with the
+	// context passed to fn take part in that transaction. The transaction
+	// is committed when fn returns nil and rolled back otherwise.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// errCommitFailed marks a failed commit. The transaction may have been
+// committed anyway, so it is never retried.
+var errCommitFailed = errors.New("failed to commit transaction")
+
+// txContextKey is the context key under which the active transaction is stored
+type txContextKey struct{}
+
+// TxManager implements UnitOfWork on top of a sqlx database
+type TxManager struct {
+	db *DB
+}
+
+// NewUnitOfWork creates a new transaction manager
+func NewUnitOfWork(db *DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// Do executes fn inside a transaction. A transaction that fails with a
+// serialization failure or deadlock is run again from the start, so fn must
+// only do database work; notify clients after Do returns. A failed commit
+// is returned as is, since it may have taken effect.
+func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Nested units of work join the outer transaction
+	if _, ok := ctx.Value(txContextKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	return retry(ctx, m.db.config.Resilience, m.db.breaker, "transaction", false, func() error {
+		return m.run(ctx, fn)
+	})
+}
+
+// run executes fn inside a single transaction
+func (m *TxManager) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Ensure transaction is rolled back on error or panic. Once a commit
+	// has been attempted the transaction is done either way.
+	committing := false
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil && !committing {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+			}
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txContextKey{}, tx)); err != nil {
+		return err
+	}
+
+	committing = true
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", errCommitFailed, err)
+	}
+
+	return nil
+}
+
+// Conn returns the transaction bound to ctx, or db when there is none
+func Conn(ctx context.Context, db *DB) Querier {
+	if tx, ok := ctx.Value(txContextKey{}).(*sqlx.Tx); ok {
+		return &Tx{Tx: tx, db: db}
+	}
+	return db
+}
+
+// Tx is a transaction whose queries are bounded by the query timeout and
+// logged when slow, like DB's. Its queries are not retried one by one; the
+// unit of work retries the whole transaction.
+type Tx struct {
+	*sqlx.Tx
+	db *DB
+}
+
+// ExecContext executes a statement
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := tx.db.observe(ctx, "exec", query, func(ctx context.Context) (err error) {
+		result, err = tx.Tx.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryContext runs a query returning rows
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := tx.db.observeRows(ctx, query, func(ctx context.Context) (err error) {
+		rows, err = tx.Tx.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryxContext runs a query returning rows that scan into structs
+func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+	err := tx.db.observeRows(ctx, query, func(ctx context.Context) (err error) {
+		rows, err = tx.Tx.QueryxContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// GetContext scans a single row into dest
+func (tx *Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.db.observe(ctx, "get", query, func(ctx context.Context) error {
+		return tx.Tx.GetContext(ctx, dest, query, args...)
+	})
+}
+
+// SelectContext scans all rows into dest
+func (tx *Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.db.observe(ctx, "select", query, func(ctx context.Context) error {
+		return tx.Tx.SelectContext(ctx, dest, query, args...)
+	})
+}
@@ -0,0 +1,101 @@
+// Package errcode defines the error codes returned to HTTP and WebSocket
+// clients, along with their machine-readable names and HTTP statuses
+package errcode
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+)
+
+// Code identifies a class of client-facing error
+type Code int
+
+// Error codes. Values are part of the public API and must not change.
+const (
+	InvalidRequest        Code = 1000 // malformed or invalid request
+	UnknownMessageType    Code = 1001 // unsupported WebSocket message type
+	InvalidRecipient      Code = 1002 // recipient ID is missing or malformed
+	InvalidConversation   Code = 1003 // conversation ID is malformed
+	Forbidden             Code = 1004 // caller may not access the resource
+	InvalidContent        Code = 1005 // message content failed validation
+	NotFound              Code = 1006 // resource does not exist
+	FeatureDisabled       Code = 1007 // feature is turned off for the caller
+	Unauthenticated       Code = 1008 // missing, invalid or expired credentials
+	Internal              Code = 1009 // unexpected server error
+	Conflict              Code = 1010 // resource already exists
+	PayloadTooLarge       Code = 1011 // upload or export exceeds the size limit
+	RecipientNotAccepting Code = 1012 // recipient's privacy settings block the sender
+	RateLimited           Code = 1013 // caller made too many requests
+	Unavailable           Code = 1014 // a dependency is temporarily down; retry later
+	Maintenance           Code = 1015 // the service is down for maintenance; retry later
+	RecipientNotFound     Code = 1016 // recipient ID is well formed but names no user
+)
+
+// registry maps each code to its name and HTTP status
+var registry = map[Code]struct {
+	name   string
+	status int
+}{
+	InvalidRequest:        {"invalid_request", http.StatusBadRequest},
+	UnknownMessageType:    {"unknown_message_type", http.StatusBadRequest},
+	InvalidRecipient:      {"invalid_recipient", http.StatusBadRequest},
+	InvalidConversation:   {"invalid_conversation", http.StatusBadRequest},
+	Forbidden:             {"forbidden", http.StatusForbidden},
+	InvalidContent:        {"invalid_content", http.StatusBadRequest},
+	NotFound:              {"not_found", http.StatusNotFound},
+	FeatureDisabled:       {"feature_disabled", http.StatusForbidden},
+	Unauthenticated:       {"unauthenticated", http.StatusUnauthorized},
+	Internal:              {"internal", http.StatusInternalServerError},
+	Conflict:              {"conflict", http.StatusConflict},
+	PayloadTooLarge:       {"payload_too_large", http.StatusRequestEntityTooLarge},
+	RecipientNotAccepting: {"recipient_not_accepting", http.StatusForbidden},
+	RateLimited:           {"rate_limited", http.StatusTooManyRequests},
+	Unavailable:           {"unavailable", http.StatusServiceUnavailable},
+	Maintenance:           {"maintenance", http.StatusServiceUnavailable},
+	RecipientNotFound:     {"recipient_not_found", http.StatusNotFound},
+}
+
+// Name returns the machine-readable name of the code
+func (c Code) Name() string {
+	if entry, ok := registry[c]; ok {
+		return entry.name
+	}
+	return "unknown"
+}
+
+// HTTPStatus returns the HTTP status code used for the code
+func (c Code) HTTPStatus() int {
+	if entry, ok := registry[c]; ok {
+		return entry.status
+	}
+	return http.StatusInternalServerError
+}
+
+// String returns the code's name
+func (c Code) String() string {
+	return c.Name()
+}
+
+// FieldError describes a single invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"` // the rule's parameter, such as a maximum length
+	Message string `json:"message"`
+}
+
+// Detailer is implemented by errors that carry field-level details
+type Detailer interface {
+	Details(l i18n.Localizer) []FieldError
+}
+
+// Details returns the field-level details carried by err, if any
+func Details(l i18n.Localizer, err error) []FieldError {
+	var d Detailer
+	if errors.As(err, &d) {
+		return d.Details(l)
+	}
+	return nil
+}
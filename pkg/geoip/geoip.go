@@ -0,0 +1,106 @@
+// Package geoip looks up the country of an IP address in a table of
+// address ranges loaded from a CSV file. Each line holds the first and last
+// address of a range and its ISO 3166-1 alpha-2 country code, the layout of
+// the freely available IP-to-country databases:
+//
+//	1.0.0.0,1.0.0.255,AU
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ipRange is a range of addresses in one country
+type ipRange struct {
+	first   netip.Addr
+	last    netip.Addr
+	country string
+}
+
+// Table maps address ranges to countries
+type Table struct {
+	ranges []ipRange
+}
+
+// Load reads a table from a CSV file
+func Load(path string) (*Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Read reads a table in CSV form
+func Read(r io.Reader) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	var ranges []ipRange
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected first address, last address and country", line)
+		}
+
+		first, err := netip.ParseAddr(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		last, err := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranges = append(ranges, ipRange{
+			first:   first.Unmap(),
+			last:    last.Unmap(),
+			country: strings.ToUpper(strings.TrimSpace(record[2])),
+		})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].first.Less(ranges[j].first)
+	})
+
+	return &Table{ranges: ranges}, nil
+}
+
+// Country returns the country code of an address, or an empty string if
+// the address is invalid or not in the table
+func (t *Table) Country(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	// Find the last range starting at or before the address
+	i := sort.Search(len(t.ranges), func(i int) bool {
+		return addr.Less(t.ranges[i].first)
+	}) - 1
+	if i < 0 {
+		return ""
+	}
+
+	r := t.ranges[i]
+	if addr.BitLen() != r.last.BitLen() || r.last.Less(addr) {
+		return ""
+	}
+	return r.country
+}
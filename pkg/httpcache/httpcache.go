@@ -0,0 +1,77 @@
+// Package httpcache implements conditional GET requests with entity tags,
+// so clients polling a resource that has not changed get a 304 Not
+// Modified instead of the full response.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ETag returns a weak entity tag for a version of a resource, made of
+// parts such as version numbers and the request's query
+func ETag(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// Matches reports whether a request's If-None-Match header lists the tag.
+// Tags are compared weakly, ignoring the W/ prefix.
+func Matches(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModified sets the response's ETag and, if the request already has
+// that version, sends 304 Not Modified and returns true. The response must
+// not have been started.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	// Responses are per user, and clients must revalidate before reusing them
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if !Matches(r, etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// WriteJSON sends data as a JSON response tagged with a hash of its
+// encoding, or 304 Not Modified if the request already has it. It saves
+// bandwidth for responses that have no cheaper version to check.
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		http.Error(w, "Error encoding JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	if NotModified(w, r, ETag(body.String())) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body.Bytes())
+}
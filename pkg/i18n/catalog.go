@@ -0,0 +1,718 @@
+package i18n
+
+import "golang.org/x/text/language"
+
+// catalogs maps each supported language to its translated messages
+var catalogs = map[language.Tag]map[string]string{
+	language.English: {
+		// Requests
+		"request.invalid_format":    "Invalid request format",
+		"pagination.invalid_cursor": "Invalid pagination cursor",
+		"request.invalid_date":      "Invalid date, use YYYY-MM-DD or RFC 3339",
+		"request.invalid_value":     "Request contains a value that is not allowed",
+
+		// Authentication
+		"auth.required":               "Authentication required",
+		"auth.invalid_header":         "Invalid authorization header format",
+		"auth.invalid_token":          "Invalid token",
+		"auth.token_expired":          "Token has expired",
+		"auth.token_revoked":          "Token has been revoked, please log in again",
+		"auth.banned":                 "This account is banned",
+		"auth.verify_failed":          "Failed to verify token",
+		"auth.invalid_credentials":    "Invalid email or password",
+		"auth.user_exists":            "Email or username already exists",
+		"auth.email_taken":            "An account with this email already exists",
+		"auth.username_taken":         "This username is already taken",
+		"auth.admin_required":         "Admin access required",
+		"auth.insufficient_scope":     "Token does not grant access to this resource",
+		"auth.register_failed":        "Failed to register user",
+		"auth.login_failed":           "Failed to login user",
+		"auth.refresh_failed":         "Failed to refresh token",
+		"auth.logout_failed":          "Failed to logout user",
+		"auth.logout_all_failed":      "Failed to log out of all devices",
+		"auth.logins_failed":          "Failed to get login history",
+		"auth.wrong_password":         "Current password is incorrect",
+		"auth.password_change_failed": "Failed to change password",
+		"auth.too_many_sessions":      "Too many active sessions; log out on another device and try again",
+		"auth.directory_managed":      "Accounts are managed by your organization's directory",
+
+		// Single sign-on
+		"sso.provider_not_found":   "Identity provider not found",
+		"sso.provider_unavailable": "The identity provider is unavailable, try again later",
+		"sso.invalid_state":        "Sign-in session expired or is invalid, start again",
+		"sso.sign_in_failed":       "Sign-in with the identity provider failed",
+		"sso.not_provisioned":      "No account is linked to this identity",
+		"sso.email_in_use":         "An account with this email already exists; sign in with your password",
+
+		// Users
+		"user.invalid_id":     "Invalid user ID format",
+		"user.list_failed":    "Failed to get users",
+		"user.invalid_search": "Search query must be between 1 and 50 characters",
+		"user.not_found":      "User not found",
+		"user.status_expired": "Custom status expiry must be in the future",
+		"user.status_failed":  "Failed to update custom status",
+		"user.privacy_failed": "Failed to update privacy settings",
+		"user.online_failed":  "Failed to get online contacts",
+
+		// Account
+		"account.export_failed":          "Failed to export account data",
+		"account.deletion_failed":        "Failed to schedule account deletion",
+		"account.cancel_deletion_failed": "Failed to cancel account deletion",
+		"account.deletion_not_scheduled": "Account deletion is not scheduled",
+
+		// Contacts
+		"contact.invalid_request_id":  "Invalid contact request ID",
+		"contact.invalid_direction":   "Direction must be incoming or outgoing",
+		"contact.self_request":        "You cannot add yourself as a contact",
+		"contact.already_contacts":    "You are already contacts",
+		"contact.request_exists":      "A contact request is already pending",
+		"contact.request_not_found":   "Contact request not found",
+		"contact.request_not_pending": "Contact request has already been answered",
+		"contact.not_found":           "Contact not found",
+		"contact.list_failed":         "Failed to get contacts",
+		"contact.request_failed":      "Failed to send contact request",
+		"contact.answer_failed":       "Failed to answer contact request",
+		"contact.remove_failed":       "Failed to remove contact",
+
+		// Groups
+		"group.invalid_id":                 "Invalid group ID",
+		"group.invalid_invite_id":          "Invalid invite ID",
+		"group.invalid_join_request_id":    "Invalid join request ID",
+		"group.not_found":                  "Group not found",
+		"group.not_member":                 "You are not a member of this group",
+		"group.admin_required":             "Group admin access required",
+		"group.already_member":             "You are already a member of this group",
+		"group.invalid_invite_expiry":      "Invite expiry must be in the future",
+		"group.invite_not_found":           "Invite not found",
+		"group.invite_unusable":            "This invite link has been revoked, has expired or has reached its usage limit",
+		"group.join_request_exists":        "A join request is already pending",
+		"group.join_request_not_found":     "Join request not found",
+		"group.join_request_not_pending":   "Join request has already been answered",
+		"group.member_not_found":           "User is not a member of this group",
+		"group.not_sender":                 "Only the sender can view message info",
+		"group.not_in_workspace":           "User is not a member of this group's workspace",
+		"group.create_failed":              "Failed to create group",
+		"group.get_failed":                 "Failed to get group",
+		"group.add_member_failed":          "Failed to add group member",
+		"group.remove_member_failed":       "Failed to remove group member",
+		"group.leave_failed":               "Failed to leave group",
+		"group.messages_failed":            "Failed to get group messages",
+		"group.message_info_failed":        "Failed to get message info",
+		"group.invite_failed":              "Failed to create invite",
+		"group.invite_list_failed":         "Failed to get invites",
+		"group.invite_revoke_failed":       "Failed to revoke invite",
+		"group.join_failed":                "Failed to join group",
+		"group.join_request_list_failed":   "Failed to get join requests",
+		"group.join_request_answer_failed": "Failed to answer join request",
+
+		// Notifications
+		"notification.get_failed":         "Failed to get notification preferences",
+		"notification.update_failed":      "Failed to update notification preferences",
+		"notification.unknown_event":      "Unknown notification event type",
+		"notification.override_not_found": "This conversation has no notification override",
+
+		// Inbox
+		"inbox.invalid_seq":    "Invalid event sequence number",
+		"inbox.invalid_device": "Invalid device ID",
+		"inbox.get_failed":     "Failed to get events",
+		"inbox.ack_failed":     "Failed to acknowledge events",
+		"inbox.disabled":       "The event inbox is not available on this server",
+
+		// API versions
+		"api.unsupported_version": "Unsupported API version",
+
+		// Log levels
+		"logging.disabled":       "Log levels cannot be changed on this server",
+		"logging.unknown_module": "Unknown log module",
+		"logging.invalid_level":  "Invalid log level",
+
+		// Phone numbers
+		"phone.sms_disabled":       "SMS is not available on this server",
+		"phone.code_recently_sent": "A code was sent recently, wait a minute before requesting another",
+		"phone.invalid_number":     "This phone number cannot receive text messages",
+		"phone.no_verification":    "No phone number is awaiting verification, or the code expired",
+		"phone.invalid_code":       "Invalid verification code",
+		"phone.too_many_attempts":  "Too many attempts, request a new code",
+		"phone.get_failed":         "Failed to get phone number",
+		"phone.send_failed":        "Failed to send verification code",
+		"phone.verify_failed":      "Failed to verify phone number",
+		"phone.remove_failed":      "Failed to remove phone number",
+		"phone.login_disabled":     "Logging in with a phone number is not enabled",
+		"phone.in_use":             "This phone number is already used by another account",
+		"phone.not_registered":     "No account is registered with this phone number",
+
+		// Email replies
+		"email.replies_disabled": "Replying by email is not enabled",
+		"email.invalid_secret":   "Invalid webhook secret",
+		"email.unknown_address":  "Unknown reply address",
+		"email.sender_mismatch":  "Replies must come from the email address of the account",
+		"email.empty_reply":      "The reply has no text",
+
+		// Conversations
+		"conversation.missing_id":              "Missing conversation ID",
+		"conversation.invalid_id":              "Invalid conversation ID",
+		"conversation.not_participant":         "Not a participant of this conversation",
+		"conversation.not_contact":             "You can only message users who accepted your contact request",
+		"conversation.not_in_workspace":        "Recipient is not a member of this workspace",
+		"conversation.recipient_not_accepting": "Recipient is not accepting messages from you",
+		"conversation.recipient_not_found":     "Recipient does not exist",
+		"conversation.list_failed":             "Failed to get conversations",
+		"conversation.messages_failed":         "Failed to get messages",
+		"conversation.read_failed":             "Failed to mark messages as read",
+		"conversation.clear_failed":            "Failed to clear conversation history",
+		"conversation.delete_failed":           "Failed to delete conversation",
+		"message.send_failed":                  "Failed to send message",
+		"message.not_found":                    "Message not found",
+		"message.invalid_id":                   "Invalid message ID",
+		"draft.get_failed":                     "Failed to get draft",
+		"draft.save_failed":                    "Failed to save draft",
+		"settings.get_failed":                  "Failed to get conversation settings",
+		"settings.update_failed":               "Failed to update conversation settings",
+		"mention.list_failed":                  "Failed to get mentions",
+		"export.invalid_format":                "Export format must be json or text",
+		"export.too_large":                     "Conversation is too large to export",
+		"export.rate_limited":                  "Please wait before exporting another conversation",
+		"export.failed":                        "Failed to export conversation",
+
+		// Message content
+		"message.empty":            "message content is empty",
+		"message.too_long":         "message content is too long",
+		"message.too_long_detail":  "message content is too long: %d characters, maximum is %d",
+		"message.invalid_encoding": "message content is not valid UTF-8",
+		"message.invalid_format":   "message format must be plain or markdown",
+
+		// Field validation
+		"validation.required":        "%s is required",
+		"validation.email":           "%s must be a valid email address",
+		"validation.min":             "%s must be at least %s characters long",
+		"validation.max":             "%s must not be longer than %s characters",
+		"validation.username":        "%s may only contain letters, digits, underscores, dots and hyphens",
+		"validation.e164":            "%s must be a phone number in international format, such as +14155552671",
+		"validation.uuid":            "%s must be a valid UUID",
+		"validation.conversation_id": "%s must be a valid conversation ID",
+		"validation.message":         "%s must not be empty or longer than the maximum message length",
+		"validation.nefield":         "%s must be different from %s",
+		"validation.failed":          "%s failed validation: %s",
+
+		// Passwords
+		"password.min_length": "Password must be at least %d characters long",
+		"password.uppercase":  "Password must contain an uppercase letter",
+		"password.lowercase":  "Password must contain a lowercase letter",
+		"password.digit":      "Password must contain a digit",
+		"password.symbol":     "Password must contain a symbol",
+		"password.breached":   "This password has appeared in a data breach, choose a different one",
+
+		// Attachments
+		"attachment.invalid_id":        "Invalid attachment ID",
+		"attachment.not_found":         "Attachment not found",
+		"attachment.missing_file":      "Missing file in upload",
+		"attachment.empty":             "Attachment is empty",
+		"attachment.too_large":         "Attachment is too large, maximum is %d bytes",
+		"attachment.image_too_large":   "Image is too large, maximum is %d pixels",
+		"attachment.unsupported_image": "This image format is not supported",
+		"attachment.invalid_signature": "Invalid download link",
+		"attachment.url_expired":       "Download link has expired",
+		"attachment.upload_failed":     "Failed to upload attachment",
+		"attachment.download_failed":   "Failed to download attachment",
+
+		// Feature flags
+		"feature.disabled":      "Feature is not enabled",
+		"feature.unknown":       "Unknown feature flag",
+		"feature.update_failed": "Failed to update feature flag",
+
+		// Calls
+		"call.ice_servers_failed": "Failed to get call servers",
+
+		// Admin
+		"admin.stats_failed":         "Failed to get stats",
+		"admin.ban_self":             "Admins cannot ban themselves",
+		"admin.ban_ended":            "Ban must end in the future",
+		"admin.ban_failed":           "Failed to update ban",
+		"announcement.expired":       "Announcement must expire in the future",
+		"announcement.failed":        "Failed to send announcement",
+		"maintenance.active":         "The service is down for maintenance, please try again later",
+		"workspace.invalid_id":       "Invalid workspace ID",
+		"workspace.not_found":        "Workspace not found",
+		"workspace.not_member":       "You are not a member of this workspace",
+		"workspace.admin_required":   "Workspace admin access required",
+		"workspace.owner":            "The workspace owner cannot be changed or removed",
+		"workspace.member_not_found": "User is not a member of this workspace",
+		"workspace.invalid_slug":     "Workspace slug must be lowercase letters and digits separated by hyphens",
+		"workspace.slug_taken":       "Workspace slug is already taken",
+		"workspace.create_failed":    "Failed to create workspace",
+		"workspace.get_failed":       "Failed to get workspace",
+		"workspace.update_failed":    "Failed to update workspace members",
+
+		// API keys
+		"apikey.required":      "API key required",
+		"apikey.invalid":       "Invalid or revoked API key",
+		"apikey.verify_failed": "Failed to verify API key",
+		"apikey.scope_denied":  "API key does not have the %s scope",
+		"apikey.invalid_id":    "Invalid API key ID",
+		"apikey.not_found":     "API key not found",
+		"apikey.user_required": "Keys that send messages must act as a user",
+		"apikey.create_failed": "Failed to create API key",
+		"apikey.list_failed":   "Failed to get API keys",
+		"apikey.revoke_failed": "Failed to revoke API key",
+	},
+
+	language.Spanish: {
+		"request.invalid_format":    "Formato de solicitud no válido",
+		"pagination.invalid_cursor": "Cursor de paginación no válido",
+		"request.invalid_date":      "Fecha no válida, usa AAAA-MM-DD o RFC 3339",
+		"request.invalid_value":     "La solicitud contiene un valor no permitido",
+
+		"auth.required":               "Se requiere autenticación",
+		"auth.invalid_header":         "Formato de cabecera de autorización no válido",
+		"auth.invalid_token":          "Token no válido",
+		"auth.token_expired":          "El token ha caducado",
+		"auth.token_revoked":          "El token ha sido revocado, inicia sesión de nuevo",
+		"auth.banned":                 "Esta cuenta está suspendida",
+		"auth.verify_failed":          "No se pudo verificar el token",
+		"auth.invalid_credentials":    "Correo electrónico o contraseña incorrectos",
+		"auth.user_exists":            "El correo electrónico o el nombre de usuario ya existe",
+		"auth.email_taken":            "Ya existe una cuenta con este correo electrónico",
+		"auth.username_taken":         "Este nombre de usuario ya está en uso",
+		"auth.admin_required":         "Se requiere acceso de administrador",
+		"auth.insufficient_scope":     "El token no concede acceso a este recurso",
+		"auth.register_failed":        "No se pudo registrar el usuario",
+		"auth.login_failed":           "No se pudo iniciar sesión",
+		"auth.refresh_failed":         "No se pudo renovar el token",
+		"auth.logout_failed":          "No se pudo cerrar la sesión",
+		"auth.logout_all_failed":      "No se pudo cerrar la sesión en todos los dispositivos",
+		"auth.logins_failed":          "No se pudo obtener el historial de inicios de sesión",
+		"auth.wrong_password":         "La contraseña actual es incorrecta",
+		"auth.password_change_failed": "No se pudo cambiar la contraseña",
+		"auth.too_many_sessions":      "Demasiadas sesiones activas; cierra sesión en otro dispositivo e inténtalo de nuevo",
+		"auth.directory_managed":      "Las cuentas las gestiona el directorio de tu organización",
+
+		"sso.provider_not_found":   "Proveedor de identidad no encontrado",
+		"sso.provider_unavailable": "El proveedor de identidad no está disponible, inténtalo más tarde",
+		"sso.invalid_state":        "La sesión de inicio caducó o no es válida, vuelve a empezar",
+		"sso.sign_in_failed":       "Falló el inicio de sesión con el proveedor de identidad",
+		"sso.not_provisioned":      "No hay ninguna cuenta vinculada a esta identidad",
+		"sso.email_in_use":         "Ya existe una cuenta con este correo; inicia sesión con tu contraseña",
+
+		"user.invalid_id":     "Formato de ID de usuario no válido",
+		"user.list_failed":    "No se pudieron obtener los usuarios",
+		"user.invalid_search": "La búsqueda debe tener entre 1 y 50 caracteres",
+		"user.not_found":      "Usuario no encontrado",
+		"user.status_expired": "La caducidad del estado debe ser en el futuro",
+		"user.status_failed":  "No se pudo actualizar el estado personalizado",
+		"user.privacy_failed": "No se pudo actualizar la configuración de privacidad",
+		"user.online_failed":  "No se pudieron obtener los contactos en línea",
+
+		"account.export_failed":          "No se pudieron exportar los datos de la cuenta",
+		"account.deletion_failed":        "No se pudo programar la eliminación de la cuenta",
+		"account.cancel_deletion_failed": "No se pudo cancelar la eliminación de la cuenta",
+		"account.deletion_not_scheduled": "La eliminación de la cuenta no está programada",
+
+		"contact.invalid_request_id":  "ID de solicitud de contacto no válido",
+		"contact.invalid_direction":   "La dirección debe ser incoming u outgoing",
+		"contact.self_request":        "No puedes agregarte a ti mismo como contacto",
+		"contact.already_contacts":    "Ya son contactos",
+		"contact.request_exists":      "Ya hay una solicitud de contacto pendiente",
+		"contact.request_not_found":   "Solicitud de contacto no encontrada",
+		"contact.request_not_pending": "La solicitud de contacto ya fue respondida",
+		"contact.not_found":           "Contacto no encontrado",
+		"contact.list_failed":         "No se pudieron obtener los contactos",
+		"contact.request_failed":      "No se pudo enviar la solicitud de contacto",
+		"contact.answer_failed":       "No se pudo responder la solicitud de contacto",
+		"contact.remove_failed":       "No se pudo eliminar el contacto",
+
+		"group.invalid_id":                 "ID de grupo no válido",
+		"group.invalid_invite_id":          "ID de invitación no válido",
+		"group.invalid_join_request_id":    "ID de solicitud de unión no válido",
+		"group.not_found":                  "Grupo no encontrado",
+		"group.not_member":                 "No eres miembro de este grupo",
+		"group.admin_required":             "Se requiere acceso de administrador del grupo",
+		"group.already_member":             "Ya eres miembro de este grupo",
+		"group.invalid_invite_expiry":      "La caducidad de la invitación debe estar en el futuro",
+		"group.invite_not_found":           "Invitación no encontrada",
+		"group.invite_unusable":            "Este enlace de invitación fue revocado, caducó o alcanzó su límite de usos",
+		"group.join_request_exists":        "Ya hay una solicitud de unión pendiente",
+		"group.join_request_not_found":     "Solicitud de unión no encontrada",
+		"group.join_request_not_pending":   "La solicitud de unión ya fue respondida",
+		"group.member_not_found":           "El usuario no es miembro de este grupo",
+		"group.not_sender":                 "Solo el remitente puede ver la información del mensaje",
+		"group.not_in_workspace":           "El usuario no es miembro del espacio de trabajo de este grupo",
+		"group.create_failed":              "No se pudo crear el grupo",
+		"group.get_failed":                 "No se pudo obtener el grupo",
+		"group.add_member_failed":          "No se pudo agregar el miembro al grupo",
+		"group.remove_member_failed":       "No se pudo eliminar el miembro del grupo",
+		"group.leave_failed":               "No se pudo salir del grupo",
+		"group.messages_failed":            "No se pudieron obtener los mensajes del grupo",
+		"group.message_info_failed":        "No se pudo obtener la información del mensaje",
+		"group.invite_failed":              "No se pudo crear la invitación",
+		"group.invite_list_failed":         "No se pudieron obtener las invitaciones",
+		"group.invite_revoke_failed":       "No se pudo revocar la invitación",
+		"group.join_failed":                "No se pudo unir al grupo",
+		"group.join_request_list_failed":   "No se pudieron obtener las solicitudes de unión",
+		"group.join_request_answer_failed": "No se pudo responder la solicitud de unión",
+
+		"notification.get_failed":         "No se pudieron obtener las preferencias de notificación",
+		"notification.update_failed":      "No se pudieron actualizar las preferencias de notificación",
+		"notification.unknown_event":      "Tipo de evento de notificación desconocido",
+		"notification.override_not_found": "Esta conversación no tiene una configuración de notificaciones propia",
+
+		"inbox.invalid_seq":    "Número de secuencia de evento no válido",
+		"inbox.invalid_device": "ID de dispositivo no válido",
+		"inbox.get_failed":     "No se pudieron obtener los eventos",
+		"inbox.ack_failed":     "No se pudieron confirmar los eventos",
+		"inbox.disabled":       "La bandeja de eventos no está disponible en este servidor",
+
+		"api.unsupported_version": "Versión de la API no admitida",
+
+		"logging.disabled":       "Los niveles de registro no se pueden cambiar en este servidor",
+		"logging.unknown_module": "Módulo de registro desconocido",
+		"logging.invalid_level":  "Nivel de registro no válido",
+
+		"phone.sms_disabled":       "Los SMS no están disponibles en este servidor",
+		"phone.code_recently_sent": "Se envió un código hace poco, espera un minuto antes de pedir otro",
+		"phone.invalid_number":     "Este número de teléfono no puede recibir mensajes de texto",
+		"phone.no_verification":    "Ningún número de teléfono está pendiente de verificación, o el código caducó",
+		"phone.invalid_code":       "Código de verificación no válido",
+		"phone.too_many_attempts":  "Demasiados intentos, solicita un código nuevo",
+		"phone.get_failed":         "No se pudo obtener el número de teléfono",
+		"phone.send_failed":        "No se pudo enviar el código de verificación",
+		"phone.verify_failed":      "No se pudo verificar el número de teléfono",
+		"phone.remove_failed":      "No se pudo eliminar el número de teléfono",
+		"phone.login_disabled":     "Iniciar sesión con un número de teléfono no está activado",
+		"phone.in_use":             "Este número de teléfono ya lo usa otra cuenta",
+		"phone.not_registered":     "No hay ninguna cuenta registrada con este número de teléfono",
+
+		"email.replies_disabled": "Responder por correo no está activado",
+		"email.invalid_secret":   "Secreto de webhook no válido",
+		"email.unknown_address":  "Dirección de respuesta desconocida",
+		"email.sender_mismatch":  "Las respuestas deben venir de la dirección de correo de la cuenta",
+		"email.empty_reply":      "La respuesta no tiene texto",
+
+		"conversation.missing_id":              "Falta el ID de la conversación",
+		"conversation.invalid_id":              "ID de conversación no válido",
+		"conversation.not_participant":         "No participas en esta conversación",
+		"conversation.not_contact":             "Solo puedes enviar mensajes a usuarios que aceptaron tu solicitud de contacto",
+		"conversation.not_in_workspace":        "El destinatario no es miembro de este espacio de trabajo",
+		"conversation.recipient_not_accepting": "El destinatario no acepta mensajes tuyos",
+		"conversation.recipient_not_found":     "El destinatario no existe",
+		"conversation.list_failed":             "No se pudieron obtener las conversaciones",
+		"conversation.messages_failed":         "No se pudieron obtener los mensajes",
+		"conversation.read_failed":             "No se pudieron marcar los mensajes como leídos",
+		"conversation.clear_failed":            "No se pudo vaciar el historial de la conversación",
+		"conversation.delete_failed":           "No se pudo eliminar la conversación",
+		"message.send_failed":                  "No se pudo enviar el mensaje",
+		"message.not_found":                    "Mensaje no encontrado",
+		"message.invalid_id":                   "ID de mensaje no válido",
+		"draft.get_failed":                     "No se pudo obtener el borrador",
+		"draft.save_failed":                    "No se pudo guardar el borrador",
+		"settings.get_failed":                  "No se pudo obtener la configuración de la conversación",
+		"settings.update_failed":               "No se pudo actualizar la configuración de la conversación",
+		"mention.list_failed":                  "No se pudieron obtener las menciones",
+		"export.invalid_format":                "El formato de exportación debe ser json o text",
+		"export.too_large":                     "La conversación es demasiado grande para exportarla",
+		"export.rate_limited":                  "Espera antes de exportar otra conversación",
+		"export.failed":                        "No se pudo exportar la conversación",
+
+		"message.empty":            "el contenido del mensaje está vacío",
+		"message.too_long":         "el contenido del mensaje es demasiado largo",
+		"message.too_long_detail":  "el contenido del mensaje es demasiado largo: %d caracteres, el máximo es %d",
+		"message.invalid_encoding": "el contenido del mensaje no es UTF-8 válido",
+		"message.invalid_format":   "el formato del mensaje debe ser plain o markdown",
+
+		"validation.required":        "%s es obligatorio",
+		"validation.email":           "%s debe ser una dirección de correo electrónico válida",
+		"validation.min":             "%s debe tener al menos %s caracteres",
+		"validation.max":             "%s no debe tener más de %s caracteres",
+		"validation.username":        "%s solo puede contener letras, dígitos, guiones bajos, puntos y guiones",
+		"validation.e164":            "%s debe ser un número de teléfono en formato internacional, como +14155552671",
+		"validation.uuid":            "%s debe ser un UUID válido",
+		"validation.conversation_id": "%s debe ser un ID de conversación válido",
+		"validation.message":         "%s no debe estar vacío ni superar la longitud máxima de un mensaje",
+		"validation.nefield":         "%s debe ser distinto de %s",
+		"validation.failed":          "%s no superó la validación: %s",
+
+		"password.min_length": "La contraseña debe tener al menos %d caracteres",
+		"password.uppercase":  "La contraseña debe contener una letra mayúscula",
+		"password.lowercase":  "La contraseña debe contener una letra minúscula",
+		"password.digit":      "La contraseña debe contener un dígito",
+		"password.symbol":     "La contraseña debe contener un símbolo",
+		"password.breached":   "Esta contraseña ha aparecido en una filtración de datos, elige otra",
+
+		"attachment.invalid_id":        "ID de adjunto no válido",
+		"attachment.not_found":         "Adjunto no encontrado",
+		"attachment.missing_file":      "Falta el archivo en la subida",
+		"attachment.empty":             "El adjunto está vacío",
+		"attachment.too_large":         "El adjunto es demasiado grande, el máximo es %d bytes",
+		"attachment.image_too_large":   "La imagen es demasiado grande, el máximo es %d píxeles",
+		"attachment.unsupported_image": "Este formato de imagen no es compatible",
+		"attachment.invalid_signature": "Enlace de descarga no válido",
+		"attachment.url_expired":       "El enlace de descarga ha caducado",
+		"attachment.upload_failed":     "No se pudo subir el adjunto",
+		"attachment.download_failed":   "No se pudo descargar el adjunto",
+
+		"feature.disabled":      "La función no está habilitada",
+		"feature.unknown":       "Indicador de función desconocido",
+		"feature.update_failed": "No se pudo actualizar el indicador de función",
+
+		"call.ice_servers_failed": "No se pudieron obtener los servidores de llamadas",
+
+		"admin.stats_failed":         "No se pudieron obtener las estadísticas",
+		"admin.ban_self":             "Los administradores no pueden suspenderse a sí mismos",
+		"admin.ban_ended":            "La suspensión debe terminar en el futuro",
+		"admin.ban_failed":           "No se pudo actualizar la suspensión",
+		"announcement.expired":       "El anuncio debe caducar en el futuro",
+		"announcement.failed":        "No se pudo enviar el anuncio",
+		"maintenance.active":         "El servicio está en mantenimiento, inténtalo de nuevo más tarde",
+		"workspace.invalid_id":       "ID de espacio de trabajo no válido",
+		"workspace.not_found":        "Espacio de trabajo no encontrado",
+		"workspace.not_member":       "No eres miembro de este espacio de trabajo",
+		"workspace.admin_required":   "Se requiere acceso de administrador del espacio de trabajo",
+		"workspace.owner":            "El propietario del espacio de trabajo no se puede cambiar ni eliminar",
+		"workspace.member_not_found": "El usuario no es miembro de este espacio de trabajo",
+		"workspace.invalid_slug":     "El identificador del espacio de trabajo debe tener letras minúsculas y dígitos separados por guiones",
+		"workspace.slug_taken":       "El identificador del espacio de trabajo ya está en uso",
+		"workspace.create_failed":    "Error al crear el espacio de trabajo",
+		"workspace.get_failed":       "Error al obtener el espacio de trabajo",
+		"workspace.update_failed":    "Error al actualizar los miembros del espacio de trabajo",
+
+		"apikey.required":      "Se requiere una clave de API",
+		"apikey.invalid":       "Clave de API no válida o revocada",
+		"apikey.verify_failed": "No se pudo verificar la clave de API",
+		"apikey.scope_denied":  "La clave de API no tiene el permiso %s",
+		"apikey.invalid_id":    "ID de clave de API no válido",
+		"apikey.not_found":     "Clave de API no encontrada",
+		"apikey.user_required": "Las claves que envían mensajes deben actuar como un usuario",
+		"apikey.create_failed": "No se pudo crear la clave de API",
+		"apikey.list_failed":   "No se pudieron obtener las claves de API",
+		"apikey.revoke_failed": "No se pudo revocar la clave de API",
+	},
+
+	language.Portuguese: {
+		"request.invalid_format":    "Formato de requisição inválido",
+		"pagination.invalid_cursor": "Cursor de paginação inválido",
+		"request.invalid_date":      "Data inválida, use AAAA-MM-DD ou RFC 3339",
+		"request.invalid_value":     "A requisição contém um valor não permitido",
+
+		"auth.required":               "Autenticação necessária",
+		"auth.invalid_header":         "Formato do cabeçalho de autorização inválido",
+		"auth.invalid_token":          "Token inválido",
+		"auth.token_expired":          "O token expirou",
+		"auth.token_revoked":          "O token foi revogado, faça login novamente",
+		"auth.banned":                 "Esta conta está banida",
+		"auth.verify_failed":          "Falha ao verificar o token",
+		"auth.invalid_credentials":    "E-mail ou senha inválidos",
+		"auth.user_exists":            "E-mail ou nome de usuário já existe",
+		"auth.email_taken":            "Já existe uma conta com este e-mail",
+		"auth.username_taken":         "Este nome de usuário já está em uso",
+		"auth.admin_required":         "Acesso de administrador necessário",
+		"auth.insufficient_scope":     "O token não concede acesso a este recurso",
+		"auth.register_failed":        "Falha ao registrar o usuário",
+		"auth.login_failed":           "Falha ao fazer login",
+		"auth.refresh_failed":         "Falha ao renovar o token",
+		"auth.logout_failed":          "Falha ao encerrar a sessão",
+		"auth.logout_all_failed":      "Falha ao encerrar a sessão em todos os dispositivos",
+		"auth.logins_failed":          "Falha ao obter o histórico de logins",
+		"auth.wrong_password":         "A senha atual está incorreta",
+		"auth.password_change_failed": "Falha ao alterar a senha",
+		"auth.too_many_sessions":      "Muitas sessões ativas; saia em outro dispositivo e tente novamente",
+		"auth.directory_managed":      "As contas são gerenciadas pelo diretório da sua organização",
+
+		"sso.provider_not_found":   "Provedor de identidade não encontrado",
+		"sso.provider_unavailable": "O provedor de identidade está indisponível, tente mais tarde",
+		"sso.invalid_state":        "A sessão de login expirou ou é inválida, comece novamente",
+		"sso.sign_in_failed":       "Falha ao entrar com o provedor de identidade",
+		"sso.not_provisioned":      "Nenhuma conta está vinculada a esta identidade",
+		"sso.email_in_use":         "Já existe uma conta com este e-mail; entre com sua senha",
+
+		"user.invalid_id":     "Formato de ID de usuário inválido",
+		"user.list_failed":    "Falha ao obter os usuários",
+		"user.invalid_search": "A busca deve ter entre 1 e 50 caracteres",
+		"user.not_found":      "Usuário não encontrado",
+		"user.status_expired": "A expiração do status deve estar no futuro",
+		"user.status_failed":  "Falha ao atualizar o status personalizado",
+		"user.privacy_failed": "Falha ao atualizar as configurações de privacidade",
+		"user.online_failed":  "Falha ao obter os contatos online",
+
+		"account.export_failed":          "Falha ao exportar os dados da conta",
+		"account.deletion_failed":        "Falha ao agendar a exclusão da conta",
+		"account.cancel_deletion_failed": "Falha ao cancelar a exclusão da conta",
+		"account.deletion_not_scheduled": "A exclusão da conta não está agendada",
+
+		"contact.invalid_request_id":  "ID da solicitação de contato inválido",
+		"contact.invalid_direction":   "A direção deve ser incoming ou outgoing",
+		"contact.self_request":        "Você não pode adicionar a si mesmo como contato",
+		"contact.already_contacts":    "Vocês já são contatos",
+		"contact.request_exists":      "Já existe uma solicitação de contato pendente",
+		"contact.request_not_found":   "Solicitação de contato não encontrada",
+		"contact.request_not_pending": "A solicitação de contato já foi respondida",
+		"contact.not_found":           "Contato não encontrado",
+		"contact.list_failed":         "Falha ao obter os contatos",
+		"contact.request_failed":      "Falha ao enviar a solicitação de contato",
+		"contact.answer_failed":       "Falha ao responder a solicitação de contato",
+		"contact.remove_failed":       "Falha ao remover o contato",
+
+		"group.invalid_id":                 "ID de grupo inválido",
+		"group.invalid_invite_id":          "ID de convite inválido",
+		"group.invalid_join_request_id":    "ID de pedido de entrada inválido",
+		"group.not_found":                  "Grupo não encontrado",
+		"group.not_member":                 "Você não é membro deste grupo",
+		"group.admin_required":             "Acesso de administrador do grupo necessário",
+		"group.already_member":             "Você já é membro deste grupo",
+		"group.invalid_invite_expiry":      "A expiração do convite deve estar no futuro",
+		"group.invite_not_found":           "Convite não encontrado",
+		"group.invite_unusable":            "Este link de convite foi revogado, expirou ou atingiu o limite de usos",
+		"group.join_request_exists":        "Já existe um pedido de entrada pendente",
+		"group.join_request_not_found":     "Pedido de entrada não encontrado",
+		"group.join_request_not_pending":   "O pedido de entrada já foi respondido",
+		"group.member_not_found":           "O usuário não é membro deste grupo",
+		"group.not_sender":                 "Somente o remetente pode ver as informações da mensagem",
+		"group.not_in_workspace":           "O usuário não é membro do espaço de trabalho deste grupo",
+		"group.create_failed":              "Falha ao criar o grupo",
+		"group.get_failed":                 "Falha ao obter o grupo",
+		"group.add_member_failed":          "Falha ao adicionar o membro ao grupo",
+		"group.remove_member_failed":       "Falha ao remover o membro do grupo",
+		"group.leave_failed":               "Falha ao sair do grupo",
+		"group.messages_failed":            "Falha ao obter as mensagens do grupo",
+		"group.message_info_failed":        "Falha ao obter as informações da mensagem",
+		"group.invite_failed":              "Falha ao criar o convite",
+		"group.invite_list_failed":         "Falha ao obter os convites",
+		"group.invite_revoke_failed":       "Falha ao revogar o convite",
+		"group.join_failed":                "Falha ao entrar no grupo",
+		"group.join_request_list_failed":   "Falha ao obter os pedidos de entrada",
+		"group.join_request_answer_failed": "Falha ao responder o pedido de entrada",
+
+		"notification.get_failed":         "Falha ao obter as preferências de notificação",
+		"notification.update_failed":      "Falha ao atualizar as preferências de notificação",
+		"notification.unknown_event":      "Tipo de evento de notificação desconhecido",
+		"notification.override_not_found": "Esta conversa não tem uma configuração de notificações própria",
+
+		"inbox.invalid_seq":    "Número de sequência de evento inválido",
+		"inbox.invalid_device": "ID de dispositivo inválido",
+		"inbox.get_failed":     "Falha ao obter os eventos",
+		"inbox.ack_failed":     "Falha ao confirmar os eventos",
+		"inbox.disabled":       "A caixa de eventos não está disponível neste servidor",
+
+		"api.unsupported_version": "Versão da API não suportada",
+
+		"logging.disabled":       "Os níveis de log não podem ser alterados neste servidor",
+		"logging.unknown_module": "Módulo de log desconhecido",
+		"logging.invalid_level":  "Nível de log inválido",
+
+		"phone.sms_disabled":       "SMS não está disponível neste servidor",
+		"phone.code_recently_sent": "Um código foi enviado recentemente, aguarde um minuto antes de pedir outro",
+		"phone.invalid_number":     "Este número de telefone não pode receber mensagens de texto",
+		"phone.no_verification":    "Nenhum número de telefone aguarda verificação, ou o código expirou",
+		"phone.invalid_code":       "Código de verificação inválido",
+		"phone.too_many_attempts":  "Muitas tentativas, solicite um novo código",
+		"phone.get_failed":         "Falha ao obter o número de telefone",
+		"phone.send_failed":        "Falha ao enviar o código de verificação",
+		"phone.verify_failed":      "Falha ao verificar o número de telefone",
+		"phone.remove_failed":      "Falha ao remover o número de telefone",
+		"phone.login_disabled":     "Entrar com um número de telefone não está ativado",
+		"phone.in_use":             "Este número de telefone já é usado por outra conta",
+		"phone.not_registered":     "Nenhuma conta está registrada com este número de telefone",
+
+		"email.replies_disabled": "Responder por e-mail não está ativado",
+		"email.invalid_secret":   "Segredo de webhook inválido",
+		"email.unknown_address":  "Endereço de resposta desconhecido",
+		"email.sender_mismatch":  "As respostas devem vir do endereço de e-mail da conta",
+		"email.empty_reply":      "A resposta não tem texto",
+
+		"conversation.missing_id":              "ID da conversa ausente",
+		"conversation.invalid_id":              "ID da conversa inválido",
+		"conversation.not_participant":         "Você não participa desta conversa",
+		"conversation.not_contact":             "Você só pode enviar mensagens a usuários que aceitaram sua solicitação de contato",
+		"conversation.not_in_workspace":        "O destinatário não é membro deste espaço de trabalho",
+		"conversation.recipient_not_accepting": "O destinatário não está aceitando suas mensagens",
+		"conversation.recipient_not_found":     "O destinatário não existe",
+		"conversation.list_failed":             "Falha ao obter as conversas",
+		"conversation.messages_failed":         "Falha ao obter as mensagens",
+		"conversation.read_failed":             "Falha ao marcar as mensagens como lidas",
+		"conversation.clear_failed":            "Falha ao limpar o histórico da conversa",
+		"conversation.delete_failed":           "Falha ao excluir a conversa",
+		"message.send_failed":                  "Falha ao enviar a mensagem",
+		"message.not_found":                    "Mensagem não encontrada",
+		"message.invalid_id":                   "ID da mensagem inválido",
+		"draft.get_failed":                     "Falha ao obter o rascunho",
+		"draft.save_failed":                    "Falha ao salvar o rascunho",
+		"settings.get_failed":                  "Falha ao obter as configurações da conversa",
+		"settings.update_failed":               "Falha ao atualizar as configurações da conversa",
+		"mention.list_failed":                  "Falha ao obter as menções",
+		"export.invalid_format":                "O formato de exportação deve ser json ou text",
+		"export.too_large":                     "A conversa é grande demais para ser exportada",
+		"export.rate_limited":                  "Aguarde antes de exportar outra conversa",
+		"export.failed":                        "Falha ao exportar a conversa",
+
+		"message.empty":            "o conteúdo da mensagem está vazio",
+		"message.too_long":         "o conteúdo da mensagem é longo demais",
+		"message.too_long_detail":  "o conteúdo da mensagem é longo demais: %d caracteres, o máximo é %d",
+		"message.invalid_encoding": "o conteúdo da mensagem não é UTF-8 válido",
+		"message.invalid_format":   "o formato da mensagem deve ser plain ou markdown",
+
+		"validation.required":        "%s é obrigatório",
+		"validation.email":           "%s deve ser um endereço de e-mail válido",
+		"validation.min":             "%s deve ter pelo menos %s caracteres",
+		"validation.max":             "%s não deve ter mais de %s caracteres",
+		"validation.username":        "%s só pode conter letras, dígitos, sublinhados, pontos e hífens",
+		"validation.e164":            "%s deve ser um número de telefone no formato internacional, como +14155552671",
+		"validation.uuid":            "%s deve ser um UUID válido",
+		"validation.conversation_id": "%s deve ser um ID de conversa válido",
+		"validation.message":         "%s não deve estar vazio nem ultrapassar o tamanho máximo de uma mensagem",
+		"validation.nefield":         "%s deve ser diferente de %s",
+		"validation.failed":          "%s falhou na validação: %s",
+
+		"password.min_length": "A senha deve ter pelo menos %d caracteres",
+		"password.uppercase":  "A senha deve conter uma letra maiúscula",
+		"password.lowercase":  "A senha deve conter uma letra minúscula",
+		"password.digit":      "A senha deve conter um dígito",
+		"password.symbol":     "A senha deve conter um símbolo",
+		"password.breached":   "Esta senha apareceu em um vazamento de dados, escolha outra",
+
+		"attachment.invalid_id":        "ID do anexo inválido",
+		"attachment.not_found":         "Anexo não encontrado",
+		"attachment.missing_file":      "Arquivo ausente no envio",
+		"attachment.empty":             "O anexo está vazio",
+		"attachment.too_large":         "O anexo é grande demais, o máximo é %d bytes",
+		"attachment.image_too_large":   "A imagem é grande demais, o máximo é %d pixels",
+		"attachment.unsupported_image": "Este formato de imagem não é suportado",
+		"attachment.invalid_signature": "Link de download inválido",
+		"attachment.url_expired":       "O link de download expirou",
+		"attachment.upload_failed":     "Falha ao enviar o anexo",
+		"attachment.download_failed":   "Falha ao baixar o anexo",
+
+		"feature.disabled":      "O recurso não está habilitado",
+		"feature.unknown":       "Flag de recurso desconhecida",
+		"feature.update_failed": "Falha ao atualizar a flag de recurso",
+
+		"call.ice_servers_failed": "Falha ao obter os servidores de chamadas",
+
+		"admin.stats_failed":         "Falha ao obter as estatísticas",
+		"admin.ban_self":             "Administradores não podem banir a si mesmos",
+		"admin.ban_ended":            "O banimento deve terminar no futuro",
+		"admin.ban_failed":           "Falha ao atualizar o banimento",
+		"announcement.expired":       "O anúncio deve expirar no futuro",
+		"announcement.failed":        "Falha ao enviar o anúncio",
+		"maintenance.active":         "O serviço está em manutenção, tente novamente mais tarde",
+		"workspace.invalid_id":       "ID de espaço de trabalho inválido",
+		"workspace.not_found":        "Espaço de trabalho não encontrado",
+		"workspace.not_member":       "Você não é membro deste espaço de trabalho",
+		"workspace.admin_required":   "Acesso de administrador do espaço de trabalho necessário",
+		"workspace.owner":            "O proprietário do espaço de trabalho não pode ser alterado nem removido",
+		"workspace.member_not_found": "O usuário não é membro deste espaço de trabalho",
+		"workspace.invalid_slug":     "O identificador do espaço de trabalho deve ter letras minúsculas e dígitos separados por hífens",
+		"workspace.slug_taken":       "O identificador do espaço de trabalho já está em uso",
+		"workspace.create_failed":    "Falha ao criar o espaço de trabalho",
+		"workspace.get_failed":       "Falha ao obter o espaço de trabalho",
+		"workspace.update_failed":    "Falha ao atualizar os membros do espaço de trabalho",
+
+		"apikey.required":      "Chave de API obrigatória",
+		"apikey.invalid":       "Chave de API inválida ou revogada",
+		"apikey.verify_failed": "Falha ao verificar a chave de API",
+		"apikey.scope_denied":  "A chave de API não tem a permissão %s",
+		"apikey.invalid_id":    "ID de chave de API inválido",
+		"apikey.not_found":     "Chave de API não encontrada",
+		"apikey.user_required": "Chaves que enviam mensagens devem agir como um usuário",
+		"apikey.create_failed": "Falha ao criar a chave de API",
+		"apikey.list_failed":   "Falha ao obter as chaves de API",
+		"apikey.revoke_failed": "Falha ao revogar a chave de API",
+	},
+}
@@ -0,0 +1,90 @@
+// Package i18n translates user-facing messages into the language requested
+// by the client
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+// supported lists the languages with a catalog. The first entry is the
+// fallback for clients that don't ask for a supported language.
+var supported = []language.Tag{
+	language.English,
+	language.Spanish,
+	language.Portuguese,
+}
+
+var matcher = language.NewMatcher(supported)
+
+// English is the localizer for the default language, also used for logs
+var English = Localizer{lang: language.English}
+
+// Localizer translates message keys into a single language
+type Localizer struct {
+	lang language.Tag
+}
+
+// New returns a localizer for the supported language closest to the given
+// Accept-Language header value
+func New(acceptLanguage string) Localizer {
+	_, index := language.MatchStrings(matcher, acceptLanguage)
+	return Localizer{lang: supported[index]}
+}
+
+// FromRequest returns a localizer for the request's Accept-Language header
+func FromRequest(r *http.Request) Localizer {
+	return New(r.Header.Get("Accept-Language"))
+}
+
+// Language returns the localizer's language
+func (l Localizer) Language() language.Tag {
+	return l.lang
+}
+
+// T translates a message key, formatting it with args if any are given.
+// Keys missing from the language's catalog fall back to English, and
+// unknown keys are returned as is.
+func (l Localizer) T(key string, args ...interface{}) string {
+	msg, ok := catalogs[l.lang][key]
+	if !ok {
+		msg, ok = catalogs[language.English][key]
+	}
+	if !ok {
+		msg = key
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Localizable is implemented by errors that can describe themselves in
+// the client's language
+type Localizable interface {
+	Localize(l Localizer) string
+}
+
+// Error returns the translated message for err, falling back to its
+// English text for errors that aren't Localizable
+func (l Localizer) Error(err error) string {
+	var le Localizable
+	if errors.As(err, &le) {
+		return le.Localize(l)
+	}
+	return err.Error()
+}
+
+// T translates a message key into the request's language
+func T(r *http.Request, key string, args ...interface{}) string {
+	return FromRequest(r).T(key, args...)
+}
+
+// Error translates err into the request's language
+func Error(r *http.Request, err error) string {
+	return FromRequest(r).Error(err)
+}
@@ -0,0 +1,180 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER tags used by the LDAP messages this package sends and reads
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+
+	tagBindRequest       = 0x60
+	tagBindResponse      = 0x61
+	tagUnbindRequest     = 0x42
+	tagSearchRequest     = 0x63
+	tagSearchResultEntry = 0x64
+	tagSearchResultDone  = 0x65
+	tagSearchResultRef   = 0x73
+	tagExtendedRequest   = 0x77
+	tagExtendedResponse  = 0x78
+
+	tagSimpleAuth    = 0x80 // [0] in BindRequest
+	tagExtendedName  = 0x80 // [0] in ExtendedRequest
+	tagFilterAnd     = 0xa0
+	tagFilterOr      = 0xa1
+	tagFilterNot     = 0xa2
+	tagFilterEqual   = 0xa3
+	tagFilterGreater = 0xa5
+	tagFilterLess    = 0xa6
+	tagFilterPresent = 0x87
+	tagFilterApprox  = 0xa8
+)
+
+// maxMessageLength bounds the messages read from a server
+const maxMessageLength = 8 << 20
+
+var errMalformed = errors.New("ldap: malformed message")
+
+// element is a decoded BER element
+type element struct {
+	tag     byte
+	content []byte
+}
+
+// encode encodes an element from its content, which for constructed
+// elements is the encoded children
+func encode(tag byte, content ...[]byte) []byte {
+	n := 0
+	for _, c := range content {
+		n += len(c)
+	}
+	out := append([]byte{tag}, encodeLength(n)...)
+	for _, c := range content {
+		out = append(out, c...)
+	}
+	return out
+}
+
+// encodeLength encodes a length in short or long form
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for v := n; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// encodeInt encodes an integer or enumerated value
+func encodeInt(tag byte, v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 0x7f || v < -0x80 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return encode(tag, b)
+}
+
+// encodeString encodes an octet string
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+// encodeBool encodes a boolean
+func encodeBool(v bool) []byte {
+	if v {
+		return encode(tagBoolean, []byte{0xff})
+	}
+	return encode(tagBoolean, []byte{0x00})
+}
+
+// readElement reads one element from r
+func readElement(r *bufio.Reader) (element, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+
+	length := int(first)
+	if first&0x80 != 0 {
+		count := int(first & 0x7f)
+		if count == 0 || count > 4 {
+			return element{}, errMalformed
+		}
+		length = 0
+		for i := 0; i < count; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return element{}, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxMessageLength {
+		return element{}, fmt.Errorf("ldap: message of %d bytes is too large", length)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return element{}, err
+	}
+	return element{tag: tag, content: content}, nil
+}
+
+// children decodes the elements in a constructed element's content
+func (e element) children() ([]element, error) {
+	var els []element
+	data := e.content
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errMalformed
+		}
+		tag, first := data[0], data[1]
+		data = data[2:]
+
+		length := int(first)
+		if first&0x80 != 0 {
+			count := int(first & 0x7f)
+			if count == 0 || count > 4 || len(data) < count {
+				return nil, errMalformed
+			}
+			length = 0
+			for _, b := range data[:count] {
+				length = length<<8 | int(b)
+			}
+			data = data[count:]
+		}
+		if length < 0 || length > len(data) {
+			return nil, errMalformed
+		}
+
+		els = append(els, element{tag: tag, content: data[:length]})
+		data = data[length:]
+	}
+	return els, nil
+}
+
+// int decodes an integer or enumerated value
+func (e element) int() int64 {
+	var v int64
+	for i, b := range e.content {
+		if i == 0 && b&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(b)
+	}
+	return v
+}
@@ -0,0 +1,139 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// EscapeFilter escapes a value for use in a search filter
+func EscapeFilter(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&sb, "\\%02x", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// compileFilter encodes a search filter in string form, such as
+// (&(objectClass=person)(mail=jane@example.com)). And, or, not, equality,
+// ordering, approximate and presence filters are supported; substring and
+// extensible match filters are not.
+func compileFilter(filter string) ([]byte, error) {
+	encoded, rest, err := parseFilter(strings.TrimSpace(filter))
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("ldap: unexpected %q after filter", rest)
+	}
+	return encoded, nil
+}
+
+// parseFilter encodes the filter at the start of s and returns the rest
+func parseFilter(s string) ([]byte, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("ldap: filter %q must start with (", s)
+	}
+	s = s[1:]
+
+	switch {
+	case strings.HasPrefix(s, "&"), strings.HasPrefix(s, "|"):
+		tag := byte(tagFilterAnd)
+		if s[0] == '|' {
+			tag = tagFilterOr
+		}
+		s = s[1:]
+		var filters [][]byte
+		for strings.HasPrefix(s, "(") {
+			f, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			filters = append(filters, f)
+			s = rest
+		}
+		if len(filters) == 0 || !strings.HasPrefix(s, ")") {
+			return nil, "", fmt.Errorf("ldap: malformed filter list")
+		}
+		return encode(tag, filters...), s[1:], nil
+
+	case strings.HasPrefix(s, "!"):
+		f, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("ldap: malformed not filter")
+		}
+		return encode(tagFilterNot, f), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("ldap: unterminated filter")
+	}
+	item, rest := s[:end], s[end+1:]
+
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, "", fmt.Errorf("ldap: malformed filter item %q", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+
+	tag := byte(tagFilterEqual)
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = tagFilterGreater, attr[:len(attr)-1]
+	case '<':
+		tag, attr = tagFilterLess, attr[:len(attr)-1]
+	case '~':
+		tag, attr = tagFilterApprox, attr[:len(attr)-1]
+	case ':':
+		return nil, "", fmt.Errorf("ldap: extensible match filters are not supported")
+	}
+	if attr == "" {
+		return nil, "", fmt.Errorf("ldap: malformed filter item %q", item)
+	}
+
+	if value == "*" && tag == tagFilterEqual {
+		return encodeString(tagFilterPresent, attr), rest, nil
+	}
+	if strings.Contains(value, "*") {
+		return nil, "", fmt.Errorf("ldap: substring filters are not supported")
+	}
+	unescaped, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, "", err
+	}
+	return encode(tag, encodeString(tagOctetString, attr), encodeString(tagOctetString, unescaped)), rest, nil
+}
+
+// unescapeFilterValue decodes the \XX escapes in a filter value
+func unescapeFilterValue(value string) (string, error) {
+	if !strings.Contains(value, "\\") {
+		return value, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			sb.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("ldap: malformed escape in %q", value)
+		}
+		b, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("ldap: malformed escape in %q", value)
+		}
+		sb.Write(b)
+		i += 2
+	}
+	return sb.String(), nil
+}
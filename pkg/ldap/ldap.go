@@ -0,0 +1,327 @@
+// Package ldap is a minimal LDAPv3 client for authenticating users against
+// a directory such as OpenLDAP or Active Directory. It supports simple
+// binds, searches and StartTLS over a single connection, one operation at a
+// time.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// LDAP result codes
+const (
+	resultSuccess            = 0
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+)
+
+// startTLSOID names the StartTLS extended operation
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// Errors
+var (
+	ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+	ErrEmptyPassword      = errors.New("ldap: empty password")
+)
+
+// ResultError is an operation that the server rejected
+type ResultError struct {
+	Code    int64
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// Entry is a directory entry returned by a search
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get returns the first value of an attribute, matching its name without
+// regard to case, or "" if the entry has none
+func (e *Entry) Get(name string) string {
+	for attr, values := range e.Attributes {
+		if strings.EqualFold(attr, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// Conn is a connection to an LDAP server
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	nextID  int64
+}
+
+// Dial connects to the server at an ldap:// or ldaps:// URL. With startTLS
+// an ldap:// connection is upgraded to TLS before use.
+func Dial(ctx context.Context, rawURL string, startTLS bool, tlsConfig *tls.Config, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid URL: %w", err)
+	}
+
+	host := u.Host
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: withServerName(tlsConfig, u.Hostname())}).DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("ldap: unsupported URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+	if startTLS && u.Scheme == "ldap" {
+		if err := c.startTLS(withServerName(tlsConfig, u.Hostname())); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// withServerName returns a TLS configuration that verifies the host name
+func withServerName(config *tls.Config, host string) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
+
+// startTLS upgrades the connection to TLS
+func (c *Conn) startTLS(config *tls.Config) error {
+	resp, err := c.roundTrip(encode(tagExtendedRequest, encodeString(tagExtendedName, startTLSOID)), tagExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := checkResult(resp); err != nil {
+		return err
+	}
+
+	tlsConn := tls.Client(c.conn, config)
+	tlsConn.SetDeadline(time.Now().Add(c.timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.conn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// Bind authenticates the connection with a DN and password. An empty
+// password is refused, since servers treat it as an anonymous bind that
+// succeeds for any DN.
+func (c *Conn) Bind(dn, password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+
+	req := encode(tagBindRequest,
+		encodeInt(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(tagSimpleAuth, password),
+	)
+	resp, err := c.roundTrip(req, tagBindResponse)
+	if err != nil {
+		return err
+	}
+	err = checkResult(resp)
+	var result *ResultError
+	if errors.As(err, &result) && result.Code == resultInvalidCredentials {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+// Search returns up to sizeLimit entries under baseDN that match a filter,
+// with the named attributes
+func (c *Conn) Search(baseDN, filter string, attributes []string, sizeLimit int) ([]Entry, error) {
+	compiled, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make([][]byte, len(attributes))
+	for i, a := range attributes {
+		attrs[i] = encodeString(tagOctetString, a)
+	}
+	req := encode(tagSearchRequest,
+		encodeString(tagOctetString, baseDN),
+		encodeInt(tagEnumerated, 2), // whole subtree
+		encodeInt(tagEnumerated, 0), // never dereference aliases
+		encodeInt(tagInteger, int64(sizeLimit)),
+		encodeInt(tagInteger, int64(c.timeout/time.Second)),
+		encodeBool(false),
+		compiled,
+		encode(tagSequence, attrs...),
+	)
+
+	id, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case tagSearchResultEntry:
+			entry, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case tagSearchResultRef:
+			// Referrals to other servers are not followed
+		case tagSearchResultDone:
+			err := checkResult(op)
+			var result *ResultError
+			if errors.As(err, &result) && result.Code == resultSizeLimitExceeded {
+				err = nil
+			}
+			return entries, err
+		default:
+			return nil, errMalformed
+		}
+	}
+}
+
+// Close tells the server the client is done and closes the connection
+func (c *Conn) Close() error {
+	c.nextID++
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	c.conn.Write(encode(tagSequence, encodeInt(tagInteger, c.nextID), encode(tagUnbindRequest)))
+	return c.conn.Close()
+}
+
+// roundTrip sends a request and returns its one response, which must have
+// the tag
+func (c *Conn) roundTrip(req []byte, tag byte) (element, error) {
+	id, err := c.send(req)
+	if err != nil {
+		return element{}, err
+	}
+	resp, err := c.receive(id)
+	if err != nil {
+		return element{}, err
+	}
+	if resp.tag != tag {
+		return element{}, errMalformed
+	}
+	return resp, nil
+}
+
+// send writes a request in an LDAP message and returns its message ID
+func (c *Conn) send(op []byte) (int64, error) {
+	c.nextID++
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	_, err := c.conn.Write(encode(tagSequence, encodeInt(tagInteger, c.nextID), op))
+	return c.nextID, err
+}
+
+// receive reads the next message, which must answer the request with the
+// message ID, and returns its protocol operation
+func (c *Conn) receive(id int64) (element, error) {
+	msg, err := readElement(c.reader)
+	if err != nil {
+		return element{}, err
+	}
+	if msg.tag != tagSequence {
+		return element{}, errMalformed
+	}
+	parts, err := msg.children()
+	if err != nil {
+		return element{}, err
+	}
+	if len(parts) < 2 || parts[0].tag != tagInteger {
+		return element{}, errMalformed
+	}
+	if parts[0].int() != id {
+		return element{}, fmt.Errorf("ldap: unexpected message ID %d", parts[0].int())
+	}
+	return parts[1], nil
+}
+
+// checkResult returns a ResultError unless an LDAPResult reports success
+func checkResult(op element) error {
+	parts, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(parts) < 3 || parts[0].tag != tagEnumerated {
+		return errMalformed
+	}
+	if code := parts[0].int(); code != resultSuccess {
+		return &ResultError{Code: code, Message: string(parts[2].content)}
+	}
+	return nil
+}
+
+// parseEntry decodes a SearchResultEntry
+func parseEntry(op element) (Entry, error) {
+	parts, err := op.children()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(parts) < 2 {
+		return Entry{}, errMalformed
+	}
+
+	entry := Entry{DN: string(parts[0].content), Attributes: make(map[string][]string)}
+	attrs, err := parts[1].children()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, attr := range attrs {
+		pair, err := attr.children()
+		if err != nil {
+			return Entry{}, err
+		}
+		if len(pair) != 2 {
+			return Entry{}, errMalformed
+		}
+		values, err := pair[1].children()
+		if err != nil {
+			return Entry{}, err
+		}
+		name := string(pair[0].content)
+		for _, v := range values {
+			entry.Attributes[name] = append(entry.Attributes[name], string(v.content))
+		}
+	}
+	return entry, nil
+}
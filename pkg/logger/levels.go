@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultModule names the level of loggers that are not a module's
+const DefaultModule = "default"
+
+// Modules are the parts of the server whose loggers have their own level
+var Modules = []string{"auth", "conversation", "database", "websocket"}
+
+// ErrUnknownModule is returned for levels of modules not in Modules
+var ErrUnknownModule = errors.New("unknown log module")
+
+// Levels holds the default log level and the levels of modules, which can
+// be changed while the server runs. Modules without a level of their own
+// follow the default.
+type Levels struct {
+	global zap.AtomicLevel
+
+	mu      sync.Mutex
+	modules map[string]*moduleLevel
+}
+
+// ModuleLevel is a module's current level
+type ModuleLevel struct {
+	Module    string
+	Level     string
+	Inherited bool // the module follows the default level
+}
+
+// moduleLevel is a module's level, or the default while override is nil
+type moduleLevel struct {
+	global   zap.AtomicLevel
+	override atomic.Pointer[zapcore.Level]
+}
+
+// Enabled reports whether entries at a level are logged
+func (m *moduleLevel) Enabled(level zapcore.Level) bool {
+	if override := m.override.Load(); override != nil {
+		return level >= *override
+	}
+	return m.global.Enabled(level)
+}
+
+func newLevels(global zap.AtomicLevel) *Levels {
+	levels := &Levels{global: global, modules: make(map[string]*moduleLevel)}
+	for _, module := range Modules {
+		levels.modules[module] = &moduleLevel{global: global}
+	}
+	return levels
+}
+
+// enabler returns the level enabler of a module, or the default level for
+// names that are not modules
+func (l *Levels) enabler(module string) zapcore.LevelEnabler {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level, ok := l.modules[module]; ok {
+		return level
+	}
+	return l.global
+}
+
+// List returns the default level and every module's level
+func (l *Levels) List() []ModuleLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list := []ModuleLevel{{Module: DefaultModule, Level: l.global.Level().String()}}
+	for name, level := range l.modules {
+		entry := ModuleLevel{Module: name, Level: l.global.Level().String(), Inherited: true}
+		if override := level.override.Load(); override != nil {
+			entry.Level = override.String()
+			entry.Inherited = false
+		}
+		list = append(list, entry)
+	}
+	sort.Slice(list[1:], func(i, j int) bool { return list[i+1].Module < list[j+1].Module })
+	return list
+}
+
+// Set changes the level of a module, or the default level for
+// DefaultModule. An empty level makes a module follow the default again.
+func (l *Levels) Set(module, level string) error {
+	var parsed *zapcore.Level
+	if level != "" {
+		p, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("invalid log level %q", level)
+		}
+		parsed = &p
+	}
+
+	if module == DefaultModule {
+		if parsed == nil {
+			return fmt.Errorf("the default log level cannot be reset")
+		}
+		l.global.SetLevel(*parsed)
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	moduleLevel, ok := l.modules[module]
+	if !ok {
+		return ErrUnknownModule
+	}
+	moduleLevel.override.Store(parsed)
+	return nil
+}
+
+// levelCore filters a core's entries by a level that can change at run
+// time. The cores it wraps accept every level.
+type levelCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.level.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
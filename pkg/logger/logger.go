@@ -1,8 +1,11 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"os"
 
+	"github.com/codingminions/Whatsapp-Lite/pkg/requestid"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -14,18 +17,57 @@ type Logger interface {
 	Warn(msg string, keysAndValues ...interface{})
 	Error(msg string, keysAndValues ...interface{})
 	Fatal(msg string, keysAndValues ...interface{})
+	// With returns a logger that adds the given key/value pairs to every entry
+	With(keysAndValues ...interface{}) Logger
+	// WithContext returns a logger that adds the request ID carried by ctx
+	WithContext(ctx context.Context) Logger
+	// Module returns a logger for one of the Modules, whose level can be
+	// set apart from the default level
+	Module(name string) Logger
 }
 
 // ZapLogger implements Logger using zap
 type ZapLogger struct {
 	logger *zap.SugaredLogger
+	levels *Levels // nil for the fallback logger
 }
 
-// NewZapLogger creates a new logger
+// Config selects what a logger writes and where. Empty fields keep the
+// defaults of the development or production configuration.
+type Config struct {
+	Development bool
+	Level       string     // debug, info, warn or error
+	Encoding    string     // json or console
+	Outputs     []string   // stdout, stderr or file paths
+	File        FileConfig // rotating file every entry is also written to
+	ErrorFile   FileConfig // rotating file errors are also written to
+
+	// IncludeContent logs Sensitive values, such as message content,
+	// instead of redacting them
+	IncludeContent bool
+	Redaction      Redaction
+}
+
+// NewZapLogger creates a new logger with the default configuration
 func NewZapLogger(development bool) *ZapLogger {
+	logger, err := New(Config{Development: development})
+	if err != nil {
+		// If the logger can't be created, just use a simple fallback
+		return &ZapLogger{logger: zap.New(zapcore.NewCore(
+			zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
+			zapcore.AddSync(os.Stdout),
+			zapcore.InfoLevel,
+		)).Sugar()}
+	}
+	return logger
+}
+
+// New creates a logger from a configuration. It fails for unknown levels
+// or encodings and for output files that cannot be opened.
+func New(cfg Config) (*ZapLogger, error) {
 	var config zap.Config
 
-	if development {
+	if cfg.Development {
 		// Development logger configuration
 		config = zap.NewDevelopmentConfig()
 		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
@@ -34,24 +76,89 @@ func NewZapLogger(development bool) *ZapLogger {
 		config = zap.NewProductionConfig()
 	}
 
-	// Set output to stdout
+	if cfg.Level != "" {
+		level, err := zap.ParseAtomicLevel(cfg.Level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level %q", cfg.Level)
+		}
+		config.Level = level
+	}
+
+	switch cfg.Encoding {
+	case "":
+	case "json", "console":
+		if cfg.Encoding != config.Encoding {
+			// Level colors only suit the development console encoder
+			config.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		}
+		config.Encoding = cfg.Encoding
+	default:
+		return nil, fmt.Errorf("invalid log encoding %q", cfg.Encoding)
+	}
+
+	// Write to stdout unless configured otherwise
 	config.OutputPaths = []string{"stdout"}
+	if len(cfg.Outputs) > 0 {
+		config.OutputPaths = cfg.Outputs
+	}
 	config.ErrorOutputPaths = []string{"stdout"}
 
+	// The cores accept every level, and levelCore filters entries by the
+	// default or module level, which can be changed at run time
+	levels := newLevels(config.Level)
+	config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+	// Tee entries to the rotating files
+	var files []zapcore.Core
+	fileEncoder := config.EncoderConfig
+	fileEncoder.EncodeLevel = zapcore.CapitalLevelEncoder
+	newEncoder := func() zapcore.Encoder {
+		if config.Encoding == "console" {
+			return zapcore.NewConsoleEncoder(fileEncoder)
+		}
+		return zapcore.NewJSONEncoder(fileEncoder)
+	}
+	if cfg.File.Path != "" {
+		file, err := OpenRotatingFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, zapcore.NewCore(newEncoder(), zapcore.AddSync(file), config.Level))
+	}
+	if cfg.ErrorFile.Path != "" {
+		file, err := OpenRotatingFile(cfg.ErrorFile)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, zapcore.NewCore(newEncoder(), zapcore.AddSync(file), zapcore.ErrorLevel))
+	}
+
+	redactor, err := newRedactor(cfg.IncludeContent, cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build logger
-	logger, err := config.Build()
+	logger, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		core = zapcore.NewTee(append([]zapcore.Core{core}, files...)...)
+		if redactor != nil {
+			core = &redactCore{Core: core, redactor: redactor}
+		}
+		return &levelCore{Core: core, level: levels.global}
+	}))
 	if err != nil {
-		// If the logger can't be created, just use a simple fallback
-		logger = zap.New(zapcore.NewCore(
-			zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
-			zapcore.AddSync(os.Stdout),
-			zapcore.InfoLevel,
-		))
+		return nil, fmt.Errorf("failed to build logger: %w", err)
 	}
 
 	return &ZapLogger{
 		logger: logger.Sugar(),
-	}
+		levels: levels,
+	}, nil
+}
+
+// Levels returns the logger's levels, or nil for the fallback logger
+func (l *ZapLogger) Levels() *Levels {
+	return l.levels
 }
 
 // Debug logs a debug message
@@ -78,3 +185,31 @@ func (l *ZapLogger) Error(msg string, keysAndValues ...interface{}) {
 func (l *ZapLogger) Fatal(msg string, keysAndValues ...interface{}) {
 	l.logger.Fatalw(msg, keysAndValues...)
 }
+
+// With returns a logger that adds the given key/value pairs to every entry
+func (l *ZapLogger) With(keysAndValues ...interface{}) Logger {
+	return &ZapLogger{logger: l.logger.With(keysAndValues...), levels: l.levels}
+}
+
+// Module returns a logger named after a module and filtered by its level
+func (l *ZapLogger) Module(name string) Logger {
+	logger := l.logger.Desugar().Named(name)
+	if l.levels != nil {
+		level := l.levels.enabler(name)
+		logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			if filtered, ok := core.(*levelCore); ok {
+				core = filtered.Core
+			}
+			return &levelCore{Core: core, level: level}
+		}))
+	}
+	return &ZapLogger{logger: logger.Sugar(), levels: l.levels}
+}
+
+// WithContext returns a logger that adds the request ID carried by ctx
+func (l *ZapLogger) WithContext(ctx context.Context) Logger {
+	if id := requestid.FromContext(ctx); id != "" {
+		return l.With("request_id", id)
+	}
+	return l
+}
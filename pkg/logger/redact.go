@@ -0,0 +1,229 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces sensitive values in log entries
+const Redacted = "[redacted]"
+
+// Sensitive marks a value, such as message content, that is only logged by
+// loggers configured to include content. Other loggers write Redacted in
+// its place.
+type Sensitive string
+
+// String returns the value
+func (s Sensitive) String() string {
+	return string(s)
+}
+
+// Redaction configures the filter that keeps personal data out of logs
+// shipped to third parties. Fields are recognized by their keys: tokens,
+// secrets and message bodies are replaced with Redacted, emails are masked
+// and IP addresses are hashed, so entries about the same address can still
+// be correlated. Emails and IP addresses are also recognized in the values
+// of other fields.
+type Redaction struct {
+	Enabled bool
+	Allow   []string // field keys logged as is
+	HashKey string   // key of the HMAC that hashes IP addresses; random if empty
+}
+
+// hashKeyBytes is the size of the hash key generated when none is set
+const hashKeyBytes = 32
+
+// redactor rewrites the fields of log entries
+type redactor struct {
+	includeContent bool
+	pii            bool
+	allow          map[string]bool
+	hashKey        []byte
+}
+
+// newRedactor creates a redactor, or returns nil if nothing needs
+// redacting. Without a hash key, IP addresses are hashed with a random key,
+// so their hashes cannot be reversed by hashing guesses, but change when
+// the process restarts.
+func newRedactor(includeContent bool, redaction Redaction) (*redactor, error) {
+	if includeContent && !redaction.Enabled {
+		return nil, nil
+	}
+	r := &redactor{
+		includeContent: includeContent,
+		pii:            redaction.Enabled,
+		allow:          make(map[string]bool, len(redaction.Allow)),
+		hashKey:        []byte(redaction.HashKey),
+	}
+	if r.pii && len(r.hashKey) == 0 {
+		r.hashKey = make([]byte, hashKeyBytes)
+		if _, err := rand.Read(r.hashKey); err != nil {
+			return nil, fmt.Errorf("failed to generate log hash key: %w", err)
+		}
+	}
+	for _, key := range redaction.Allow {
+		r.allow[strings.ToLower(key)] = true
+	}
+	return r, nil
+}
+
+// fieldKind is the kind of personal data a field holds
+type fieldKind int
+
+const (
+	kindOther fieldKind = iota
+	kindSecret
+	kindBody
+	kindEmail
+	kindIP
+)
+
+// secretKeys are substrings of the keys of fields holding credentials
+var secretKeys = []string{"token", "secret", "password", "authorization", "cookie", "api_key", "apikey", "otp"}
+
+// kindOf recognizes the kind of a field by its key
+func kindOf(key string) fieldKind {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return kindSecret
+		}
+	}
+	switch {
+	case key == "content" || key == "body" || key == "text",
+		strings.HasSuffix(key, "_content"), strings.HasSuffix(key, "_body"), strings.HasSuffix(key, "_text"):
+		return kindBody
+	case strings.Contains(key, "email"):
+		return kindEmail
+	case key == "ip" || key == "remote_addr" || strings.HasSuffix(key, "_ip") || strings.HasPrefix(key, "ip_"):
+		return kindIP
+	}
+	return kindOther
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// maskEmail keeps the first character and the domain of an email
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return Redacted
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// hash returns a short keyed hash of a value
+func (r *redactor) hash(value string) string {
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(value))
+	return "hash:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// isIP reports whether a value is an IP address, with or without a port
+func isIP(value string) bool {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	return net.ParseIP(value) != nil
+}
+
+// fieldString returns the text of a field that holds a string, a Stringer
+// or an error
+func fieldString(field zapcore.Field) (string, bool) {
+	switch field.Type {
+	case zapcore.StringType:
+		return field.String, true
+	case zapcore.StringerType:
+		if s, ok := field.Interface.(fmt.Stringer); ok {
+			return s.String(), true
+		}
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok {
+			return err.Error(), true
+		}
+	}
+	return "", false
+}
+
+// field returns a field with its value redacted, and whether it was changed
+func (r *redactor) field(field zapcore.Field) (zapcore.Field, bool) {
+	if _, ok := field.Interface.(Sensitive); ok && field.Type == zapcore.StringerType && !r.includeContent {
+		return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: Redacted}, true
+	}
+	if !r.pii || r.allow[strings.ToLower(field.Key)] {
+		return field, false
+	}
+
+	kind := kindOf(field.Key)
+	value, ok := fieldString(field)
+	if !ok {
+		if kind == kindOther {
+			return field, false
+		}
+		// A value of another type under a key holding personal data
+		return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: Redacted}, true
+	}
+
+	var redacted string
+	switch {
+	case kind == kindSecret || kind == kindBody:
+		redacted = Redacted
+	case kind == kindIP || kind == kindOther && isIP(value):
+		redacted = r.hash(value)
+	default:
+		redacted = emailPattern.ReplaceAllStringFunc(value, maskEmail)
+	}
+	if redacted == value {
+		return field, false
+	}
+	return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: redacted}, true
+}
+
+// fields returns the fields with their values redacted
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+	for i, field := range fields {
+		f, changed := r.field(field)
+		if !changed {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]zapcore.Field(nil), fields...)
+		}
+		redacted[i] = f
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+// redactCore redacts the fields of entries before they reach the wrapped
+// core
+type redactCore struct {
+	zapcore.Core
+	redactor *redactor
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redactor.fields(fields)), redactor: c.redactor}
+}
+
+func (c *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, c.redactor.fields(fields))
+}
@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FileConfig configures a log file that is rotated as it grows. A zero
+// MaxSizeMB rotates at lumberjack's default of 100 MB; zero MaxAge and
+// MaxBackups keep rotated files forever.
+type FileConfig struct {
+	Path       string
+	MaxSizeMB  int           // size at which the file is rotated
+	MaxAge     time.Duration // how long rotated files are kept, rounded up to days
+	MaxBackups int           // how many rotated files are kept
+}
+
+// OpenRotatingFile opens a log file for appending, creating it and its
+// directory if needed. Rotated files are renamed with a timestamp, and
+// those past their maximum age or count are deleted when the file is
+// opened or rotated.
+func OpenRotatingFile(config FileConfig) (*lumberjack.Logger, error) {
+	file := &lumberjack.Logger{
+		Filename:   config.Path,
+		MaxSize:    config.MaxSizeMB,
+		MaxAge:     maxAgeDays(config.MaxAge),
+		MaxBackups: config.MaxBackups,
+	}
+
+	// Open the file now, so a bad path fails at startup rather than on the
+	// first entry
+	if _, err := file.Write(nil); err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return file, nil
+}
+
+// maxAgeDays converts a maximum age to the whole days lumberjack counts in
+func maxAgeDays(age time.Duration) int {
+	if age <= 0 {
+		return 0
+	}
+	const day = 24 * time.Hour
+	return int((age + day - 1) / day)
+}
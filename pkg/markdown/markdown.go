@@ -0,0 +1,74 @@
+// Package markdown renders a small, safe subset of Markdown to HTML.
+//
+// Supported syntax is **bold**, *italic* or _italic_, `code` and
+// [links](https://example.com). All other input is HTML-escaped, so the
+// output is safe to insert into a page without further sanitization.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	codePattern   = regexp.MustCompile("`([^`\n]+)`")
+	linkPattern   = regexp.MustCompile(`\[([^\]\n]+)\]\(([^)\s]+)\)`)
+	boldPattern   = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+	italicPattern = regexp.MustCompile(`(^|[^\w*])[*_]([^*_\n]+)[*_]([^\w*]|$)`)
+)
+
+// allowedSchemes lists the URL schemes permitted in links
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// placeholder marks a protected span in the output while other rules run.
+// NUL bytes are removed from the source before rendering.
+const placeholder = "\x00"
+
+// Render converts Markdown source to sanitized HTML
+func Render(source string) string {
+	escaped := html.EscapeString(strings.ReplaceAll(source, placeholder, ""))
+
+	// Code spans are extracted first so their contents are not formatted
+	var spans []string
+	escaped = codePattern.ReplaceAllStringFunc(escaped, func(m string) string {
+		inner := codePattern.FindStringSubmatch(m)[1]
+		spans = append(spans, "<code>"+inner+"</code>")
+		return placeholder + strconv.Itoa(len(spans)-1) + placeholder
+	})
+
+	escaped = linkPattern.ReplaceAllStringFunc(escaped, func(m string) string {
+		parts := linkPattern.FindStringSubmatch(m)
+		text, href := parts[1], html.UnescapeString(parts[2])
+		if !isSafeURL(href) {
+			return m
+		}
+		return `<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer" target="_blank">` + text + "</a>"
+	})
+
+	escaped = boldPattern.ReplaceAllString(escaped, "<strong>$1</strong>")
+	escaped = italicPattern.ReplaceAllString(escaped, "$1<em>$2</em>$3")
+	escaped = strings.ReplaceAll(escaped, "\n", "<br>")
+
+	// Restore code spans
+	for i, span := range spans {
+		escaped = strings.Replace(escaped, placeholder+strconv.Itoa(i)+placeholder, span, 1)
+	}
+
+	return escaped
+}
+
+// isSafeURL reports whether href is an absolute URL with an allowed scheme
+func isSafeURL(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	return allowedSchemes[strings.ToLower(u.Scheme)]
+}
@@ -0,0 +1,248 @@
+// Package migrate applies the SQL migrations in a directory. It keeps its
+// state in the schema_migrations table used by the golang-migrate CLI, so
+// the two can be used on the same database.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NilVersion is the version of a database with no migrations applied
+const NilVersion int64 = -1
+
+// Migration errors
+var (
+	ErrDirty  = errors.New("database is dirty after a failed migration; fix it and force the version")
+	ErrNoDown = errors.New("migration has no down file")
+)
+
+// fileName matches migration files such as 000001_create_users_table.up.sql
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a numbered schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	UpFile   string
+	DownFile string // empty if the migration cannot be reverted
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db  *sqlx.DB
+	dir string
+}
+
+// New creates a migrator for the migrations in dir
+func New(db *sqlx.DB, dir string) *Migrator {
+	return &Migrator{db: db, dir: dir}
+}
+
+// Load returns the migrations in the directory, oldest first
+func (m *Migrator) Load() ([]Migration, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", match[1], err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		path := filepath.Join(m.dir, entry.Name())
+		if match[3] == "up" {
+			migration.UpFile = path
+		} else {
+			migration.DownFile = path
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.UpFile == "" {
+			return nil, fmt.Errorf("migration %d has no up file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Version returns the database's current migration version and whether the
+// last migration failed part way
+func (m *Migrator) Version(ctx context.Context) (int64, bool, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return NilVersion, false, err
+	}
+
+	var version int64
+	var dirty bool
+	err := m.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return NilVersion, false, nil
+	}
+	return version, dirty, err
+}
+
+// Up applies every pending migration and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	current, err := m.cleanVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+		if err := m.apply(ctx, migration.UpFile, migration.Version); err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// Down reverts up to steps applied migrations, newest first, and returns
+// how many were reverted
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	current, err := m.cleanVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+	for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+		migration := migrations[i]
+		if migration.Version > current {
+			continue
+		}
+		if migration.DownFile == "" {
+			return reverted, fmt.Errorf("%w: %d_%s", ErrNoDown, migration.Version, migration.Name)
+		}
+
+		previous := NilVersion
+		if i > 0 {
+			previous = migrations[i-1].Version
+		}
+		if err := m.apply(ctx, migration.DownFile, previous); err != nil {
+			return reverted, fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		reverted++
+	}
+
+	return reverted, nil
+}
+
+// apply runs a migration file and records the resulting version in the
+// same transaction, marking the database dirty if it fails
+func (m *Migrator) apply(ctx context.Context, file string, version int64) error {
+	statements, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(statements)); err != nil {
+		m.markDirty(ctx, version)
+		return err
+	}
+	if err := setVersion(ctx, tx, version, false); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// markDirty records that the migration to version failed part way, as
+// golang-migrate does, so neither tool runs further migrations
+func (m *Migrator) markDirty(ctx context.Context, version int64) {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	if setVersion(ctx, tx, version, true) == nil {
+		tx.Commit()
+	}
+}
+
+// cleanVersion returns the current version, failing if the database is dirty
+func (m *Migrator) cleanVersion(ctx context.Context) (int64, error) {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return NilVersion, err
+	}
+	if dirty {
+		return NilVersion, fmt.Errorf("%w (version %d)", ErrDirty, version)
+	}
+	return version, nil
+}
+
+// ensureTable creates the schema_migrations table if it does not exist
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version BIGINT NOT NULL PRIMARY KEY,
+            dirty BOOLEAN NOT NULL
+        )
+    `)
+	return err
+}
+
+// setVersion replaces the recorded version. NilVersion clears it.
+func setVersion(ctx context.Context, tx *sqlx.Tx, version int64, dirty bool) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if version == NilVersion {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)", version, dirty)
+	return err
+}
@@ -0,0 +1,253 @@
+// Package openapi generates an OpenAPI 3 document describing the REST API
+// from the metadata routes are registered with, so the document always
+// matches the routes the server actually serves.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.0.3"
+
+// Security schemes operations can require
+const (
+	BearerAuth = "bearerAuth"
+	APIKeyAuth = "apiKeyAuth"
+)
+
+// Param describes a query parameter
+type Param struct {
+	Name        string
+	Type        string // string, integer or boolean
+	Description string
+}
+
+// Operation describes what a route accepts and returns. Request and
+// Response are zero values of the types encoded in the request and
+// response bodies; a nil Response means the route answers with no content.
+type Operation struct {
+	Summary     string
+	Tag         string
+	Query       []Param
+	Request     interface{}
+	FileField   string // multipart field of an uploaded file, instead of Request
+	Status      int    // success status, 200 by default
+	Response    interface{}
+	ContentType string // response media type, JSON by default
+	Security    string // security scheme, none if empty
+}
+
+// Route is an operation served at a method and path. Path parameters are
+// written in braces, as with gorilla/mux.
+type Route struct {
+	Method    string
+	Path      string
+	Operation Operation
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       Info                                   `json:"info"`
+	Servers    []server                               `json:"servers,omitempty"`
+	Tags       []tag                                  `json:"tags,omitempty"`
+	Paths      map[string]map[string]*operationObject `json:"paths"`
+	Components components                             `json:"components"`
+}
+
+type server struct {
+	URL string `json:"url"`
+}
+
+type tag struct {
+	Name string `json:"name"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes,omitempty"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+type operationObject struct {
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *body                 `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type body struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+// pathParam matches the parameters of a route path
+var pathParam = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// Build generates the document for routes served under a base path.
+// Error responses of every operation are described by errorResponse.
+func Build(info Info, basePath string, routes []Route, errorResponse interface{}) *Document {
+	schemas := newRegistry()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: []server{{URL: basePath}},
+		Paths:   make(map[string]map[string]*operationObject),
+		Components: components{
+			SecuritySchemes: map[string]securityScheme{
+				BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				APIKeyAuth: {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+	}
+
+	errorSchema := schemas.schemaFor(errorResponse)
+	tags := make(map[string]bool)
+	for _, route := range routes {
+		op := route.Operation
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		object := &operationObject{
+			Summary:     op.Summary,
+			OperationID: operationID(route.Method, route.Path),
+			Responses: map[string]response{
+				"default": {Description: "Error", Content: jsonContent(errorSchema)},
+			},
+		}
+		if op.Tag != "" {
+			object.Tags = []string{op.Tag}
+			tags[op.Tag] = true
+		}
+		if op.Security != "" {
+			object.Security = []map[string][]string{{op.Security: {}}}
+		}
+
+		for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+			object.Parameters = append(object.Parameters, parameter{
+				Name:     match[1],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+		for _, param := range op.Query {
+			paramType := param.Type
+			if paramType == "" {
+				paramType = "string"
+			}
+			object.Parameters = append(object.Parameters, parameter{
+				Name:        param.Name,
+				In:          "query",
+				Description: param.Description,
+				Schema:      &Schema{Type: paramType},
+			})
+		}
+
+		switch {
+		case op.Request != nil:
+			object.RequestBody = &body{Required: true, Content: jsonContent(schemas.schemaFor(op.Request))}
+		case op.FileField != "":
+			object.RequestBody = &body{Required: true, Content: map[string]mediaType{"multipart/form-data": {Schema: &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{op.FileField: {Type: "string", Format: "binary"}},
+				Required:   []string{op.FileField},
+			}}}}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := response{Description: http.StatusText(status)}
+		switch {
+		case op.ContentType != "":
+			success.Content = map[string]mediaType{op.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+		case op.Response != nil:
+			success.Content = jsonContent(schemas.schemaFor(op.Response))
+		}
+		object.Responses[strconv.Itoa(status)] = success
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*operationObject)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = object
+	}
+
+	for name := range tags {
+		doc.Tags = append(doc.Tags, tag{Name: name})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	doc.Components.Schemas = schemas.schemas
+	return doc
+}
+
+// Handler serves the document as JSON
+func (d *Document) Handler() http.HandlerFunc {
+	data, err := json.Marshal(d)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, "Error encoding OpenAPI document", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+// jsonContent describes a JSON body
+func jsonContent(schema *Schema) map[string]mediaType {
+	return map[string]mediaType{"application/json": {Schema: schema}}
+}
+
+// operationID derives a stable operation ID from a method and path, such
+// as get_conversations_conversation_id_messages
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.Trim(pathParam.ReplaceAllString(segment, "$1"), "{}")
+		if segment == "" {
+			continue
+		}
+		id += "_" + strings.NewReplacer("-", "_", ".", "_").Replace(segment)
+	}
+	return id
+}
@@ -0,0 +1,37 @@
+package openapi
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// uiTemplate is the Swagger UI page. The UI's assets are loaded from a CDN,
+// so the explorer needs the browser to have internet access.
+var uiTemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <title>{{.Title}}</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+    <div id="swagger-ui"></div>
+    <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+    <script>
+        window.ui = SwaggerUIBundle({
+            url: {{.SpecURL}},
+            dom_id: '#swagger-ui',
+            persistAuthorization: true
+        });
+    </script>
+</body>
+</html>
+`))
+
+// UIHandler serves an interactive explorer for the document at specURL
+func UIHandler(title, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		uiTemplate.Execute(w, struct{ Title, SpecURL string }{title, specURL})
+	}
+}
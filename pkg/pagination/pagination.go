@@ -0,0 +1,101 @@
+// Package pagination implements keyset pagination with opaque cursors.
+//
+// A cursor records the sort key and ID of the last row on a page. The next
+// page starts strictly after that position, so rows inserted concurrently
+// never cause rows to be skipped or repeated the way offsets do.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for cursors that cannot be decoded
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// Default and maximum page sizes
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Cursor is a position in a result set ordered by (Key, ID)
+type Cursor struct {
+	Key string    `json:"k"`
+	ID  uuid.UUID `json:"id"`
+}
+
+// NewCursor creates a cursor for a row with a string sort key
+func NewCursor(key string, id uuid.UUID) Cursor {
+	return Cursor{Key: key, ID: id}
+}
+
+// TimeCursor creates a cursor for a row sorted by timestamp
+func TimeCursor(t time.Time, id uuid.UUID) Cursor {
+	return Cursor{Key: t.UTC().Format(time.RFC3339Nano), ID: id}
+}
+
+// Time returns the cursor's key as a timestamp
+func (c Cursor) Time() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, c.Key)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// Encode returns the cursor in its opaque, URL-safe form
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses an opaque cursor. An empty string decodes to nil, meaning
+// the first page.
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// ParseLimit parses a page size, using def for missing or invalid values
+// and capping it at max
+func ParseLimit(value string, def, max int) int {
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return def
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+// Trim cuts items fetched with a limit of limit+1 down to a page. It
+// reports whether more items follow and returns the encoded cursor of the
+// last item kept, or an empty string on the last page.
+func Trim[T any](items []T, limit int, cursor func(T) Cursor) ([]T, bool, string) {
+	if len(items) <= limit {
+		return items, false, ""
+	}
+
+	items = items[:limit]
+	return items, true, cursor(items[limit-1]).Encode()
+}
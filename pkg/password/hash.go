@@ -0,0 +1,148 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hash algorithms
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// ErrMalformedHash is returned when a stored hash cannot be parsed
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// Argon2Params are the argon2id cost parameters
+type Argon2Params struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Hasher hashes new passwords with one algorithm and verifies hashes made
+// with any supported algorithm, so users can be moved to a new algorithm as
+// they log in
+type Hasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     Argon2Params
+}
+
+// NewHasher creates a hasher that hashes new passwords with algorithm
+func NewHasher(algorithm string, bcryptCost int, argon2Params Argon2Params) (*Hasher, error) {
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	switch algorithm {
+	case AlgorithmBcrypt:
+	case AlgorithmArgon2id:
+		if argon2Params.Memory == 0 || argon2Params.Iterations == 0 || argon2Params.Parallelism == 0 ||
+			argon2Params.SaltLength == 0 || argon2Params.KeyLength == 0 {
+			return nil, errors.New("argon2id parameters must be greater than zero")
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm: %q", algorithm)
+	}
+
+	return &Hasher{algorithm: algorithm, bcryptCost: bcryptCost, argon2: argon2Params}, nil
+}
+
+// Algorithm returns the algorithm new passwords are hashed with
+func (h *Hasher) Algorithm() string {
+	return h.algorithm
+}
+
+// Hash hashes a password with the hasher's algorithm
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, h.argon2.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2.Iterations, h.argon2.Memory, h.argon2.Parallelism, h.argon2.KeyLength)
+
+	// PHC string format, as used by the reference implementation
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.argon2.Memory, h.argon2.Iterations, h.argon2.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches a hash made with algorithm and,
+// if it does, whether the hash should be replaced because it was made with
+// another algorithm or other cost parameters than the hasher's
+func (h *Hasher) Verify(password, hash, algorithm string) (match, rehash bool, err error) {
+	switch algorithm {
+	case AlgorithmBcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return false, false, err
+		}
+		return true, h.algorithm != AlgorithmBcrypt || cost != h.bcryptCost, nil
+	case AlgorithmArgon2id:
+		params, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, false, err
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return false, false, nil
+		}
+		return true, h.algorithm != AlgorithmArgon2id || params != h.argon2, nil
+	default:
+		return false, false, fmt.Errorf("unknown password hash algorithm: %q", algorithm)
+	}
+}
+
+// decodeArgon2 parses an argon2id hash in PHC string format
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrMalformedHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
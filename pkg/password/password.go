@@ -0,0 +1,136 @@
+// Package password checks new passwords against a configurable policy of
+// length and character class rules and, optionally, a list of passwords
+// exposed in data breaches, and hashes and verifies stored passwords.
+package password
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+)
+
+// Rules a password can break
+const (
+	RuleMinLength = "min_length"
+	RuleUppercase = "uppercase"
+	RuleLowercase = "lowercase"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleBreached  = "breached"
+)
+
+// BreachChecker reports whether a password appears in known data breaches
+type BreachChecker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// Policy describes the passwords users may choose
+type Policy struct {
+	MinLength        int // in characters, not bytes
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	Breaches         BreachChecker // nil skips the breach check
+}
+
+// Validate checks a password against the policy. A password that breaks any
+// rule yields a *PolicyError listing every rule it breaks. The breach check
+// runs only when the other rules pass; if it cannot be completed the error
+// returned is not a *PolicyError, so callers may choose to accept the
+// password.
+func (p *Policy) Validate(ctx context.Context, field, password string) error {
+	var broken []string
+	if utf8.RuneCountInString(password) < p.MinLength {
+		broken = append(broken, RuleMinLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUppercase && !upper {
+		broken = append(broken, RuleUppercase)
+	}
+	if p.RequireLowercase && !lower {
+		broken = append(broken, RuleLowercase)
+	}
+	if p.RequireDigit && !digit {
+		broken = append(broken, RuleDigit)
+	}
+	if p.RequireSymbol && !symbol {
+		broken = append(broken, RuleSymbol)
+	}
+	if len(broken) > 0 {
+		return &PolicyError{Field: field, Rules: broken, MinLength: p.MinLength}
+	}
+
+	if p.Breaches == nil {
+		return nil
+	}
+	breached, err := p.Breaches.Breached(ctx, password)
+	if err != nil {
+		return fmt.Errorf("breached password check failed: %w", err)
+	}
+	if breached {
+		return &PolicyError{Field: field, Rules: []string{RuleBreached}, MinLength: p.MinLength}
+	}
+
+	return nil
+}
+
+// PolicyError lists the rules a password breaks
+type PolicyError struct {
+	Field     string
+	Rules     []string
+	MinLength int
+}
+
+// Error returns the broken rules in English
+func (e *PolicyError) Error() string {
+	return e.Localize(i18n.English)
+}
+
+// Localize returns the broken rules in the localizer's language
+func (e *PolicyError) Localize(l i18n.Localizer) string {
+	messages := make([]string, 0, len(e.Rules))
+	for _, rule := range e.Rules {
+		messages = append(messages, e.message(l, rule))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Details returns one entry per broken rule in the localizer's language
+func (e *PolicyError) Details(l i18n.Localizer) []errcode.FieldError {
+	details := make([]errcode.FieldError, 0, len(e.Rules))
+	for _, rule := range e.Rules {
+		details = append(details, errcode.FieldError{
+			Field:   e.Field,
+			Rule:    rule,
+			Message: e.message(l, rule),
+		})
+	}
+	return details
+}
+
+// message describes a broken rule
+func (e *PolicyError) message(l i18n.Localizer, rule string) string {
+	if rule == RuleMinLength {
+		return l.T("password.min_length", e.MinLength)
+	}
+	return l.T("password." + rule)
+}
@@ -0,0 +1,84 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrUnknownPepper is returned for a hash made with a pepper that is no
+// longer configured
+var ErrUnknownPepper = errors.New("unknown password pepper")
+
+// Peppers holds the secret peppers mixed into passwords before they are
+// hashed. Unlike salts, peppers are not stored with the hashes, so a leaked
+// users table cannot be cracked without them.
+//
+// Each pepper has an ID that is recorded with every hash made with it. To
+// rotate the pepper, add a new one and make it current: hashes made with the
+// old pepper are still verified, and are replaced when their users next log
+// in. Once no hashes use the old pepper it can be removed; users who have
+// not logged in by then must reset their password.
+type Peppers struct {
+	current string
+	secrets map[string][]byte
+}
+
+// NewPeppers creates a set of peppers keyed by ID. New hashes use the
+// current pepper; an empty current ID leaves new hashes unpeppered.
+func NewPeppers(current string, secrets map[string][]byte) (*Peppers, error) {
+	if current != "" {
+		if _, ok := secrets[current]; !ok {
+			return nil, fmt.Errorf("current password pepper %q is not configured", current)
+		}
+	}
+	for id, secret := range secrets {
+		if len(secret) < 16 {
+			return nil, fmt.Errorf("password pepper %q must be at least 16 bytes", id)
+		}
+	}
+
+	return &Peppers{current: current, secrets: secrets}, nil
+}
+
+// LoadPeppers reads each pepper from a file, such as a mounted secret,
+// keyed by pepper ID
+func LoadPeppers(current string, files map[string]string) (*Peppers, error) {
+	secrets := make(map[string][]byte, len(files))
+	for id, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password pepper %q: %w", id, err)
+		}
+		secrets[id] = []byte(strings.TrimSpace(string(data)))
+	}
+
+	return NewPeppers(current, secrets)
+}
+
+// Current returns the ID of the pepper used for new hashes
+func (p *Peppers) Current() string {
+	return p.current
+}
+
+// Apply mixes the pepper with the given ID into a password. An empty ID
+// returns the password unchanged. The result is a fixed-length HMAC, which
+// also keeps long passwords within bcrypt's 72-byte limit.
+func (p *Peppers) Apply(id, password string) (string, error) {
+	if id == "" {
+		return password, nil
+	}
+
+	secret, ok := p.secrets[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownPepper, id)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,77 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPwnedURL is the Pwned Passwords range API
+const DefaultPwnedURL = "https://api.pwnedpasswords.com/range/"
+
+// PwnedChecker looks passwords up in the Pwned Passwords range API. Only the
+// first five characters of the password's SHA-1 hash are sent; the API
+// returns every breached hash with that prefix and the rest of the hash is
+// matched locally, so neither the password nor its full hash leaves the
+// server.
+type PwnedChecker struct {
+	url    string
+	client *http.Client
+}
+
+// NewPwnedChecker creates a checker for the range API at url, which ends
+// where the hash prefix is appended
+func NewPwnedChecker(url string, timeout time.Duration) *PwnedChecker {
+	if url == "" {
+		url = DefaultPwnedURL
+	}
+	return &PwnedChecker{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Breached reports whether the password appears in a known breach
+func (c *PwnedChecker) Breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides the number of real matches from anyone watching the
+	// response sizes
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	// Each line is a hash suffix and the number of times it was seen.
+	// Padding entries have a count of zero.
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		seen, err := strconv.Atoi(count)
+		return err == nil && seen > 0, nil
+	}
+
+	return false, scanner.Err()
+}
@@ -0,0 +1,63 @@
+// Package requestid generates and propagates request IDs used to correlate
+// log entries and error responses for a single request
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header carrying the request ID
+const Header = "X-Request-ID"
+
+// maxLength bounds the length of client-supplied request IDs
+const maxLength = 128
+
+type contextKey struct{}
+
+// New generates a new request ID
+func New() string {
+	return uuid.NewString()
+}
+
+// NewContext returns a copy of ctx carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or an empty string
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Sanitize returns id if it is safe to log and echo back to clients,
+// otherwise an empty string
+func Sanitize(id string) string {
+	if id == "" || len(id) > maxLength {
+		return ""
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if c < '!' || c > '~' {
+			return ""
+		}
+	}
+	return id
+}
+
+// Middleware propagates the client's X-Request-ID, or generates one, adding
+// it to the request context and the response headers
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := Sanitize(r.Header.Get(Header))
+		if id == "" {
+			id = New()
+		}
+
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
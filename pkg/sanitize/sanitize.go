@@ -0,0 +1,131 @@
+// Package sanitize removes HTML and script content from user-supplied text
+package sanitize
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// Policy controls how markup in user content is handled
+type Policy string
+
+// Sanitization policies
+const (
+	// PolicyStrip removes HTML tags, comments and the contents of script
+	// and style elements, keeping the surrounding text as typed
+	PolicyStrip Policy = "strip"
+	// PolicyEscape HTML-escapes the whole content
+	PolicyEscape Policy = "escape"
+	// PolicyNone leaves content untouched
+	PolicyNone Policy = "none"
+)
+
+// ParsePolicy parses a policy name, defaulting to PolicyStrip
+func ParsePolicy(name string) (Policy, error) {
+	switch Policy(name) {
+	case "", PolicyStrip:
+		return PolicyStrip, nil
+	case PolicyEscape, PolicyNone:
+		return Policy(name), nil
+	default:
+		return "", fmt.Errorf("unknown sanitize policy: %q", name)
+	}
+}
+
+// Sanitizer applies a sanitization policy to text
+type Sanitizer struct {
+	policy Policy
+}
+
+// New creates a new sanitizer with the given policy
+func New(policy Policy) *Sanitizer {
+	return &Sanitizer{policy: policy}
+}
+
+// Clean applies the sanitizer's policy to content
+func (s *Sanitizer) Clean(content string) string {
+	switch s.policy {
+	case PolicyNone:
+		return content
+	case PolicyEscape:
+		return html.EscapeString(content)
+	default:
+		return StripTags(content)
+	}
+}
+
+// CleanStored sanitizes content read back from storage, which may predate
+// the current policy. Tags are stripped rather than escaped so content that
+// was already escaped on write is not escaped twice.
+func (s *Sanitizer) CleanStored(content string) string {
+	if s.policy == PolicyNone {
+		return content
+	}
+	return StripTags(content)
+}
+
+// maxStripPasses bounds the number of passes StripTags makes over content
+const maxStripPasses = 8
+
+// StripTags removes HTML markup from content. Text outside of tags is kept
+// exactly as written, including any entities the user typed. Removing a tag
+// can splice its neighbours into new markup ("<<b>script>"), so passes are
+// repeated until the content is stable.
+func StripTags(content string) string {
+	for i := 0; i < maxStripPasses; i++ {
+		// Fast path for the common case of plain text
+		if !strings.Contains(content, "<") {
+			return content
+		}
+
+		stripped := stripPass(content)
+		if stripped == content {
+			return content
+		}
+		content = stripped
+	}
+
+	// Pathologically nested input, neutralize whatever is left
+	return strings.ReplaceAll(content, "<", "&lt;")
+}
+
+// stripPass removes the markup recognized in a single tokenizer pass
+func stripPass(content string) string {
+	var b strings.Builder
+	b.Grow(len(content))
+
+	z := nethtml.NewTokenizer(strings.NewReader(content))
+	skipDepth := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case nethtml.ErrorToken:
+			return b.String()
+		case nethtml.TextToken:
+			if skipDepth == 0 {
+				b.Write(z.Raw())
+			}
+		case nethtml.StartTagToken:
+			if isRawTextElement(z) {
+				skipDepth++
+			}
+		case nethtml.EndTagToken:
+			if skipDepth > 0 && isRawTextElement(z) {
+				skipDepth--
+			}
+		}
+	}
+}
+
+// isRawTextElement reports whether the current tag's contents must be dropped entirely
+func isRawTextElement(z *nethtml.Tokenizer) bool {
+	name, _ := z.TagName()
+	switch string(name) {
+	case "script", "style", "iframe", "noscript", "object", "template":
+		return true
+	}
+	return false
+}
@@ -8,12 +8,15 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 // Errors
 var (
-	ErrInvalidToken = errors.New("token is invalid")
-	ErrExpiredToken = errors.New("token has expired")
+	ErrInvalidToken    = errors.New("token is invalid")
+	ErrExpiredToken    = errors.New("token has expired")
+	ErrInvalidIssuer   = errors.New("token was issued by another issuer")
+	ErrInvalidAudience = errors.New("token is intended for another audience")
 )
 
 // ValidationError is returned when token validation fails
@@ -25,50 +28,143 @@ func (e ValidationError) Error() string {
 	return fmt.Sprintf("token validation failed: %v", e.Err)
 }
 
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Scopes granted by access tokens
+const (
+	ScopeAPI       = "api" // the REST API
+	ScopeWebSocket = "ws"  // WebSocket connections
+)
+
+// Claims describe the holder of a token
+type Claims struct {
+	UserID      string   `json:"user_id"`
+	Username    string   `json:"username"`
+	WorkspaceID string   `json:"workspace_id,omitempty"` // empty outside workspaces
+	Roles       []string `json:"roles,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`     // empty for tokens that predate scopes, which grant every scope
+	SessionID   string   `json:"session_id,omitempty"` // the session of the device the token was issued to
+}
+
+// HasRole reports whether the claims include a role
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the claims grant a scope
+func (c *Claims) HasScope(scope string) bool {
+	if len(c.Scopes) == 0 {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 // Payload contains the payload data of the token
 type Payload struct {
-	UserID    string    `json:"user_id"`
-	Username  string    `json:"username"`
+	Claims
+	ID        string    `json:"id,omitempty"` // empty for legacy tokens
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken creates a new token for a specific user
-	CreateToken(userID, username string, duration time.Duration) (string, *Payload, error)
+	// CreateToken creates a new token carrying claims
+	CreateToken(claims Claims, duration time.Duration) (string, *Payload, error)
 
 	// VerifyToken checks if the token is valid
 	VerifyToken(token string) (*Payload, error)
 }
 
+// Options configures the tokens a JWTMaker issues and accepts
+type Options struct {
+	// Issuer and Audience are set in the iss and aud claims of issued
+	// tokens and required in verified ones, so tokens minted by other
+	// deployments are rejected. Empty values are neither set nor checked.
+	Issuer   string
+	Audience string
+
+	// Leeway tolerates clock drift between servers when checking expiry
+	Leeway time.Duration
+
+	// AcceptLegacy accepts tokens with the custom user_id, issued_at and
+	// expired_at claims used before the registered sub, iat and exp claims.
+	// It can be turned off once every legacy token has expired.
+	AcceptLegacy bool
+}
+
 // JWTMaker is a JSON Web Token maker
 type JWTMaker struct {
 	secretKey string
+	options   Options
 }
 
 // NewJWTMaker creates a new JWTMaker
-func NewJWTMaker(secretKey string) (Maker, error) {
+func NewJWTMaker(secretKey string, options Options) (Maker, error) {
 	if len(secretKey) < 32 {
 		return nil, errors.New("secret key must be at least 32 characters")
 	}
-	return &JWTMaker{secretKey: secretKey}, nil
+	if options.Leeway < 0 {
+		return nil, errors.New("leeway must not be negative")
+	}
+	return &JWTMaker{secretKey: secretKey, options: options}, nil
 }
 
-// CreateToken creates a new token for a specific user
-func (maker *JWTMaker) CreateToken(userID, username string, duration time.Duration) (string, *Payload, error) {
+// jwtClaims are the claims encoded in tokens. The user ID is the subject.
+// Legacy tokens, issued before the registered claims were adopted, carry
+// the user ID and times in custom claims instead.
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Username    string   `json:"username"`
+	WorkspaceID string   `json:"workspace_id,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	SessionID   string   `json:"session_id,omitempty"`
+
+	LegacyUserID    string `json:"user_id,omitempty"`
+	LegacyIssuedAt  int64  `json:"issued_at,omitempty"`
+	LegacyExpiredAt int64  `json:"expired_at,omitempty"`
+}
+
+// CreateToken creates a new token carrying claims
+func (maker *JWTMaker) CreateToken(claims Claims, duration time.Duration) (string, *Payload, error) {
+	now := time.Now()
 	payload := &Payload{
-		UserID:    userID,
-		Username:  username,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
+		Claims:    claims,
+		ID:        uuid.NewString(),
+		IssuedAt:  now,
+		ExpiredAt: now.Add(duration),
 	}
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":    payload.UserID,
-		"username":   payload.Username,
-		"issued_at":  payload.IssuedAt.Unix(),
-		"expired_at": payload.ExpiredAt.Unix(),
+	registered := jwt.RegisteredClaims{
+		ID:        payload.ID,
+		Subject:   claims.UserID,
+		Issuer:    maker.options.Issuer,
+		IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+		ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
+	}
+	if maker.options.Audience != "" {
+		registered.Audience = jwt.ClaimStrings{maker.options.Audience}
+	}
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwtClaims{
+		RegisteredClaims: registered,
+		Username:         claims.Username,
+		WorkspaceID:      claims.WorkspaceID,
+		Roles:            claims.Roles,
+		Scopes:           claims.Scopes,
+		SessionID:        claims.SessionID,
 	})
 
 	tokenString, err := jwtToken.SignedString([]byte(maker.secretKey))
@@ -89,7 +185,10 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return []byte(maker.secretKey), nil
 	}
 
-	jwtToken, err := jwt.Parse(token, keyFunc)
+	// Claims are checked below, so expiry allows for the leeway and legacy
+	// tokens are read
+	var claims jwtClaims
+	jwtToken, err := jwt.ParseWithClaims(token, &claims, keyFunc, jwt.WithoutClaimsValidation())
 	if err != nil {
 		if errors.Is(err, jwt.ErrSignatureInvalid) {
 			return nil, ValidationError{Err: ErrInvalidToken}
@@ -100,44 +199,53 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	if !jwtToken.Valid {
 		return nil, ValidationError{Err: ErrInvalidToken}
 	}
-
-	claims, ok := jwtToken.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, ValidationError{Err: ErrInvalidToken}
+	if maker.options.Issuer != "" && !claims.VerifyIssuer(maker.options.Issuer, true) {
+		return nil, ValidationError{Err: ErrInvalidIssuer}
 	}
-
-	// Extract claims
-	userID, ok := claims["user_id"].(string)
-	if !ok {
-		return nil, ValidationError{Err: ErrInvalidToken}
+	if maker.options.Audience != "" && !claims.VerifyAudience(maker.options.Audience, true) {
+		return nil, ValidationError{Err: ErrInvalidAudience}
 	}
 
-	username, ok := claims["username"].(string)
-	if !ok {
-		return nil, ValidationError{Err: ErrInvalidToken}
-	}
-
-	issuedAtFloat, ok := claims["issued_at"].(float64)
-	if !ok {
+	var id, userID string
+	var issuedAt, expiredAt time.Time
+	switch {
+	case claims.Subject != "":
+		if claims.IssuedAt == nil || claims.ExpiresAt == nil {
+			return nil, ValidationError{Err: ErrInvalidToken}
+		}
+		id = claims.ID
+		userID = claims.Subject
+		issuedAt = claims.IssuedAt.Time
+		expiredAt = claims.ExpiresAt.Time
+	case maker.options.AcceptLegacy && claims.LegacyUserID != "":
+		if claims.LegacyIssuedAt == 0 || claims.LegacyExpiredAt == 0 {
+			return nil, ValidationError{Err: ErrInvalidToken}
+		}
+		userID = claims.LegacyUserID
+		issuedAt = time.Unix(claims.LegacyIssuedAt, 0)
+		expiredAt = time.Unix(claims.LegacyExpiredAt, 0)
+	default:
 		return nil, ValidationError{Err: ErrInvalidToken}
 	}
-
-	expiredAtFloat, ok := claims["expired_at"].(float64)
-	if !ok {
+	if claims.Username == "" {
 		return nil, ValidationError{Err: ErrInvalidToken}
 	}
 
-	issuedAt := time.Unix(int64(issuedAtFloat), 0)
-	expiredAt := time.Unix(int64(expiredAtFloat), 0)
-
-	// Check if the token has expired
-	if time.Now().After(expiredAt) {
+	// Check if the token has expired, allowing for clock drift
+	if time.Now().After(expiredAt.Add(maker.options.Leeway)) {
 		return nil, ValidationError{Err: ErrExpiredToken}
 	}
 
 	payload := &Payload{
-		UserID:    userID,
-		Username:  username,
+		Claims: Claims{
+			UserID:      userID,
+			Username:    claims.Username,
+			WorkspaceID: claims.WorkspaceID,
+			Roles:       claims.Roles,
+			Scopes:      claims.Scopes,
+			SessionID:   claims.SessionID,
+		},
+		ID:        id,
 		IssuedAt:  issuedAt,
 		ExpiredAt: expiredAt,
 	}
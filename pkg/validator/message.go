@@ -0,0 +1,125 @@
+package validator
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/sanitize"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Message content errors
+var (
+	ErrEmptyMessage    error = &messageError{key: "message.empty"}
+	ErrMessageTooLong  error = &messageError{key: "message.too_long"}
+	ErrInvalidEncoding error = &messageError{key: "message.invalid_encoding"}
+	ErrInvalidFormat   error = &messageError{key: "message.invalid_format"}
+)
+
+// messageError is a message content error identified by its message key
+type messageError struct {
+	key string
+}
+
+func (e *messageError) Error() string {
+	return i18n.English.T(e.key)
+}
+
+// Localize returns the error message in the localizer's language
+func (e *messageError) Localize(l i18n.Localizer) string {
+	return l.T(e.key)
+}
+
+// lengthError reports content over the maximum length. It matches
+// ErrMessageTooLong with errors.Is.
+type lengthError struct {
+	length    int
+	maxLength int
+}
+
+func (e *lengthError) Error() string {
+	return e.Localize(i18n.English)
+}
+
+// Localize returns the error message in the localizer's language
+func (e *lengthError) Localize(l i18n.Localizer) string {
+	return l.T("message.too_long_detail", e.length, e.maxLength)
+}
+
+func (e *lengthError) Unwrap() error {
+	return ErrMessageTooLong
+}
+
+// DefaultMaxMessageLength is used when no maximum length is configured
+const DefaultMaxMessageLength = 4096
+
+// MessageValidator validates and normalizes chat message content
+type MessageValidator struct {
+	maxLength int
+	sanitizer *sanitize.Sanitizer
+}
+
+// NewMessageValidator creates a message validator allowing at most maxLength
+// characters after markup has been removed by sanitizer
+func NewMessageValidator(maxLength int, sanitizer *sanitize.Sanitizer) *MessageValidator {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxMessageLength
+	}
+	return &MessageValidator{
+		maxLength: maxLength,
+		sanitizer: sanitizer,
+	}
+}
+
+// MaxLength returns the maximum number of characters allowed in a message
+func (v *MessageValidator) MaxLength() int {
+	return v.maxLength
+}
+
+// Normalize validates message content and returns its normalized form.
+// Content is NFC-normalized, stripped of control characters other than
+// newlines and tabs, sanitized of markup and trimmed of surrounding
+// whitespace before the length check, which counts characters rather
+// than bytes.
+func (v *MessageValidator) Normalize(content string) (string, error) {
+	if !utf8.ValidString(content) {
+		return "", ErrInvalidEncoding
+	}
+
+	content = norm.NFC.String(content)
+	content = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if r == '\r' || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, content)
+	content = v.sanitizer.Clean(content)
+	content = strings.TrimSpace(content)
+
+	if content == "" {
+		return "", ErrEmptyMessage
+	}
+
+	if n := utf8.RuneCountInString(content); n > v.maxLength {
+		return "", &lengthError{length: n, maxLength: v.maxLength}
+	}
+
+	return content, nil
+}
+
+// Format validates a message format, defaulting to plain text
+func (v *MessageValidator) Format(format string) (string, error) {
+	switch format {
+	case "", "plain":
+		return "plain", nil
+	case "markdown":
+		return "markdown", nil
+	default:
+		return "", ErrInvalidFormat
+	}
+}
@@ -2,11 +2,15 @@ package validator
 
 import (
 	"errors"
-	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
+	"github.com/codingminions/Whatsapp-Lite/pkg/errcode"
+	"github.com/codingminions/Whatsapp-Lite/pkg/i18n"
+	"github.com/codingminions/Whatsapp-Lite/pkg/sanitize"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 )
 
 // Validator is an interface for validating structs
@@ -15,14 +19,40 @@ type Validator interface {
 	Validate(i interface{}) error
 }
 
-// CustomValidator implements Validator using the go-playground/validator package
+// CustomValidator implements Validator using the go-playground/validator package.
+// Besides the package's rules, it checks the chat domain's rules:
+//
+//   - username: letters, digits, underscores, dots and hyphens, as mentions allow
+//   - conversation_id: the ID of a direct conversation, two UUIDs joined by a hyphen
+//   - message: message content that is not empty and not too long
 type CustomValidator struct {
 	validator *validator.Validate
+	messages  *MessageValidator
 }
 
-// NewCustomValidator creates a new validator
+// StructLevel gives struct rules the struct being validated and reports its
+// invalid fields
+type StructLevel = validator.StructLevel
+
+// StructRule checks rules that span several fields of a struct. Its
+// function reports invalid fields with StructLevel.ReportError, giving the
+// field's JSON name and the rule that failed.
+type StructRule struct {
+	Type interface{} // a value of the struct type
+	Func func(sl StructLevel)
+}
+
+// usernamePattern matches the characters allowed in usernames
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// NewCustomValidator creates a new validator. Message content is checked
+// against the default maximum length until SetMessageValidator is called.
 func NewCustomValidator() *CustomValidator {
 	v := validator.New()
+	cv := &CustomValidator{
+		validator: v,
+		messages:  NewMessageValidator(0, sanitize.New(sanitize.PolicyNone)),
+	}
 
 	// Register a custom function to get JSON tag names
 	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -33,41 +63,109 @@ func NewCustomValidator() *CustomValidator {
 		return name
 	})
 
-	return &CustomValidator{
-		validator: v,
+	v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("conversation_id", func(fl validator.FieldLevel) bool {
+		return validConversationID(fl.Field().String())
+	})
+	v.RegisterValidation("message", func(fl validator.FieldLevel) bool {
+		_, err := cv.messages.Normalize(fl.Field().String())
+		return err == nil
+	})
+
+	return cv
+}
+
+// SetMessageValidator sets the validator that checks message content. It
+// must be called before validating.
+func (cv *CustomValidator) SetMessageValidator(messages *MessageValidator) {
+	cv.messages = messages
+}
+
+// RegisterStructRules adds rules spanning several fields of structs. It
+// must be called before validating.
+func (cv *CustomValidator) RegisterStructRules(rules ...StructRule) {
+	for _, rule := range rules {
+		cv.validator.RegisterStructValidation(rule.Func, rule.Type)
 	}
 }
 
+// validConversationID reports whether id is the ID of a direct
+// conversation, made of the two participants' UUIDs
+func validConversationID(id string) bool {
+	if len(id) != 73 || id[36] != '-' {
+		return false
+	}
+	_, err := uuid.Parse(id[:36])
+	if err != nil {
+		return false
+	}
+	_, err = uuid.Parse(id[37:])
+	return err == nil
+}
+
 // Validate validates a struct
 func (cv *CustomValidator) Validate(i interface{}) error {
 	if err := cv.validator.Struct(i); err != nil {
 		var validationErrors validator.ValidationErrors
 		if errors.As(err, &validationErrors) {
-			messages := make([]string, 0, len(validationErrors))
-			for _, e := range validationErrors {
-				messages = append(messages, formatValidationError(e))
-			}
-			return errors.New(strings.Join(messages, "; "))
+			return ValidationErrors(validationErrors)
 		}
 		return err
 	}
 	return nil
 }
 
+// ValidationErrors describes the fields of a struct that failed validation
+type ValidationErrors []validator.FieldError
+
+// Error returns the validation errors in English
+func (ve ValidationErrors) Error() string {
+	return ve.Localize(i18n.English)
+}
+
+// Localize returns the validation errors in the localizer's language
+func (ve ValidationErrors) Localize(l i18n.Localizer) string {
+	messages := make([]string, 0, len(ve))
+	for _, e := range ve {
+		messages = append(messages, formatValidationError(l, e))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Details returns one entry per invalid field in the localizer's language
+func (ve ValidationErrors) Details(l i18n.Localizer) []errcode.FieldError {
+	details := make([]errcode.FieldError, 0, len(ve))
+	for _, e := range ve {
+		details = append(details, errcode.FieldError{
+			Field:   e.Field(),
+			Rule:    e.Tag(),
+			Param:   e.Param(),
+			Message: formatValidationError(l, e),
+		})
+	}
+	return details
+}
+
 // formatValidationError formats a validation error
-func formatValidationError(e validator.FieldError) string {
+func formatValidationError(l i18n.Localizer, e validator.FieldError) string {
 	field := e.Field()
 
 	switch e.Tag() {
-	case "required":
-		return fmt.Sprintf("%s is required", field)
+	case "required", "required_without":
+		return l.T("validation.required", field)
 	case "email":
-		return fmt.Sprintf("%s must be a valid email address", field)
+		return l.T("validation.email", field)
 	case "min":
-		return fmt.Sprintf("%s must be at least %s characters long", field, e.Param())
+		return l.T("validation.min", field, e.Param())
 	case "max":
-		return fmt.Sprintf("%s must not be longer than %s characters", field, e.Param())
+		return l.T("validation.max", field, e.Param())
+	case "username", "e164", "uuid", "conversation_id", "message":
+		return l.T("validation."+e.Tag(), field)
+	case "nefield":
+		return l.T("validation.nefield", field, e.Param())
 	default:
-		return fmt.Sprintf("%s failed validation: %s", field, e.Tag())
+		return l.T("validation.failed", field, e.Tag())
 	}
 }
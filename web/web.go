@@ -0,0 +1,22 @@
+// Package web holds the web client's templates and static assets. They are
+// embedded in the server binary, so the server runs from any directory.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+)
+
+//go:embed templates static
+var files embed.FS
+
+// Assets returns the web assets. If dir is set, they are read from that
+// directory on every request instead, so changes show up without
+// rebuilding during development.
+func Assets(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	return files
+}